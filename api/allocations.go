@@ -106,6 +106,7 @@ type Allocation struct {
 	ClientStatus       string
 	ClientDescription  string
 	TaskStates         map[string]*TaskState
+	NetworkStatus      *AllocNetworkStatus
 	PreviousAllocation string
 	CreateIndex        uint64
 	ModifyIndex        uint64
@@ -113,6 +114,12 @@ type Allocation struct {
 	CreateTime         int64
 }
 
+// AllocNetworkStatus is the status of the network namespace of an allocation.
+type AllocNetworkStatus struct {
+	InterfaceName string
+	Address       string
+}
+
 // AllocationMetric is used to deserialize allocation metrics.
 type AllocationMetric struct {
 	NodesEvaluated     int
@@ -22,7 +22,7 @@ func TestCompose(t *testing.T) {
 				&NetworkResource{
 					CIDR:          "0.0.0.0/0",
 					MBits:         helper.IntToPtr(100),
-					ReservedPorts: []Port{{"", 80}, {"", 443}},
+					ReservedPorts: []Port{{"", 80, 0}, {"", 443, 0}},
 				},
 			},
 		})
@@ -85,8 +85,8 @@ func TestCompose(t *testing.T) {
 									CIDR:  "0.0.0.0/0",
 									MBits: helper.IntToPtr(100),
 									ReservedPorts: []Port{
-										{"", 80},
-										{"", 443},
+										{"", 80, 0},
+										{"", 443, 0},
 									},
 								},
 							},
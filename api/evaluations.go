@@ -52,6 +52,16 @@ func (e *Evaluations) Allocations(evalID string, q *QueryOptions) ([]*Allocation
 	return resp, qm, nil
 }
 
+// Plans is used to retrieve the plans applied for an evaluation.
+func (e *Evaluations) Plans(evalID string, q *QueryOptions) (*EvalPlans, *QueryMeta, error) {
+	var resp EvalPlans
+	qm, err := e.client.query("/v1/evaluation/"+evalID+"/plan", &resp, q)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &resp, qm, nil
+}
+
 // Evaluation is used to serialize an evaluation.
 type Evaluation struct {
 	ID                   string
@@ -78,6 +88,30 @@ type Evaluation struct {
 	ModifyIndex          uint64
 }
 
+// EvalPlans is the record of the plans applied for an evaluation.
+type EvalPlans struct {
+	EvalID      string
+	JobID       string
+	Plans       []*AppliedPlan
+	CreateIndex uint64
+	ModifyIndex uint64
+}
+
+// AppliedPlan is the record of a plan applied for an evaluation.
+type AppliedPlan struct {
+	EvalID         string
+	JobID          string
+	Priority       int
+	AllAtOnce      bool
+	NodeUpdate     map[string][]*AllocationListStub
+	NodeAllocation map[string][]*AllocationListStub
+	RejectedNodes  []string
+	Annotations    *PlanAnnotations
+	RefreshIndex   uint64
+	AllocIndex     uint64
+	ApplyTime      int64
+}
+
 // EvalIndexSort is a wrapper to sort evaluations by CreateIndex.
 // We reverse the test so that we get the highest index first.
 type EvalIndexSort []*Evaluation
@@ -138,6 +138,18 @@ func TestEvaluations_Allocations(t *testing.T) {
 	}
 }
 
+func TestEvaluations_Plans(t *testing.T) {
+	c, s := makeClient(t, nil, nil)
+	defer s.Stop()
+	e := c.Evaluations()
+
+	// Querying the plans of a non-existent evaluation returns error
+	_, _, err := e.Plans("8E231CF4-CA48-43FF-B694-5801E69E22FA", nil)
+	if err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Fatalf("expected not found error, got: %s", err)
+	}
+}
+
 func TestEvaluations_Sort(t *testing.T) {
 	evals := []*Evaluation{
 		&Evaluation{CreateIndex: 2},
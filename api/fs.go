@@ -245,6 +245,18 @@ func (a *AllocFS) Stream(alloc *Allocation, path, origin string, offset int64,
 	return frames, nil
 }
 
+// LogFilter selects the lines of a task's logs to stream.
+type LogFilter struct {
+	// Since and Until select the output written in a time range, with a
+	// resolution of a second. They are ignored if zero. Since can't be used
+	// with an offset and Until can't be used when following the logs.
+	Since time.Time
+	Until time.Time
+
+	// Grep selects the lines matching the regular expression if set.
+	Grep string
+}
+
 // Logs streams the content of a tasks logs blocking on EOF.
 // The parameters are:
 // * allocation: the allocation to stream from.
@@ -258,6 +270,14 @@ func (a *AllocFS) Stream(alloc *Allocation, path, origin string, offset int64,
 // The return value is a channel that will emit StreamFrames as they are read.
 func (a *AllocFS) Logs(alloc *Allocation, follow bool, task, logType, origin string,
 	offset int64, cancel <-chan struct{}, q *QueryOptions) (<-chan *StreamFrame, error) {
+	return a.FilteredLogs(alloc, follow, task, logType, origin, offset, nil, cancel, q)
+}
+
+// FilteredLogs streams the lines of a tasks logs selected by the filter,
+// blocking on EOF. The parameters are the same as for Logs. If the filter is
+// nil all lines are streamed.
+func (a *AllocFS) FilteredLogs(alloc *Allocation, follow bool, task, logType, origin string,
+	offset int64, filter *LogFilter, cancel <-chan struct{}, q *QueryOptions) (<-chan *StreamFrame, error) {
 
 	node, _, err := a.client.Nodes().Info(alloc.NodeID, q)
 	if err != nil {
@@ -273,6 +293,17 @@ func (a *AllocFS) Logs(alloc *Allocation, follow bool, task, logType, origin str
 	q.Params["type"] = logType
 	q.Params["origin"] = origin
 	q.Params["offset"] = strconv.FormatInt(offset, 10)
+	if filter != nil {
+		if !filter.Since.IsZero() {
+			q.Params["since"] = filter.Since.Format(time.RFC3339Nano)
+		}
+		if !filter.Until.IsZero() {
+			q.Params["until"] = filter.Until.Format(time.RFC3339Nano)
+		}
+		if filter.Grep != "" {
+			q.Params["grep"] = filter.Grep
+		}
+	}
 
 	r, err := nodeClient.rawQuery(fmt.Sprintf("/v1/client/fs/logs/%s", alloc.ID), q)
 	if err != nil {
@@ -197,19 +197,60 @@ func (j *Jobs) Summary(jobID string, q *QueryOptions) (*JobSummary, *QueryMeta,
 
 func (j *Jobs) Dispatch(jobID string, meta map[string]string,
 	payload []byte, q *WriteOptions) (*JobDispatchResponse, *WriteMeta, error) {
-	var resp JobDispatchResponse
 	req := &JobDispatchRequest{
 		JobID:   jobID,
 		Meta:    meta,
 		Payload: payload,
 	}
-	wm, err := j.client.write("/v1/job/"+jobID+"/dispatch", req, &resp, q)
+	return j.DispatchOpts(req, q)
+}
+
+// DispatchOpts is used to dispatch a parameterized job with all the options
+// of a dispatch request, such as a payload URL or an idempotency token.
+func (j *Jobs) DispatchOpts(req *JobDispatchRequest, q *WriteOptions) (*JobDispatchResponse, *WriteMeta, error) {
+	var resp JobDispatchResponse
+	wm, err := j.client.write("/v1/job/"+req.JobID+"/dispatch", req, &resp, q)
 	if err != nil {
 		return nil, nil, err
 	}
 	return &resp, wm, nil
 }
 
+// Scale is used to change the count of a task group of a job. If count is
+// nil, only a scaling event with the message is recorded in the scaling
+// history of the job.
+func (j *Jobs) Scale(jobID, group string, count *int, message string, isError bool,
+	meta map[string]interface{}, q *WriteOptions) (*JobScaleResponse, *WriteMeta, error) {
+	var resp JobScaleResponse
+	req := &ScalingRequest{
+		JobID:     jobID,
+		TaskGroup: group,
+		Message:   message,
+		Error:     isError,
+		Meta:      meta,
+	}
+	if count != nil {
+		c := int64(*count)
+		req.Count = &c
+	}
+	wm, err := j.client.write("/v1/job/"+jobID+"/scale", req, &resp, q)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &resp, wm, nil
+}
+
+// ScaleStatus is used to retrieve the count, allocations and scaling history
+// of the task groups of a job
+func (j *Jobs) ScaleStatus(jobID string, q *QueryOptions) (*JobScaleStatus, *QueryMeta, error) {
+	var resp JobScaleStatus
+	qm, err := j.client.query("/v1/job/"+jobID+"/scale", &resp, q)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &resp, qm, nil
+}
+
 // periodicForceResponse is used to deserialize a force response
 type periodicForceResponse struct {
 	EvalID string
@@ -608,9 +649,11 @@ type DesiredUpdates struct {
 }
 
 type JobDispatchRequest struct {
-	JobID   string
-	Payload []byte
-	Meta    map[string]string
+	JobID            string
+	Payload          []byte
+	Meta             map[string]string
+	PayloadURL       string
+	IdempotencyToken string
 }
 
 type JobDispatchResponse struct {
@@ -620,3 +663,51 @@ type JobDispatchResponse struct {
 	JobCreateIndex  uint64
 	WriteMeta
 }
+
+// ScalingRequest is used to change the count of a task group of a job
+type ScalingRequest struct {
+	JobID     string
+	TaskGroup string
+	Count     *int64
+	Message   string
+	Error     bool
+	Meta      map[string]interface{}
+}
+
+// JobScaleResponse is used to respond to a scaling request
+type JobScaleResponse struct {
+	EvalID          string
+	EvalCreateIndex uint64
+	JobModifyIndex  uint64
+	WriteMeta
+}
+
+// JobScaleStatus is the scaling status of the task groups of a job
+type JobScaleStatus struct {
+	JobID          string
+	JobCreateIndex uint64
+	JobModifyIndex uint64
+	TaskGroups     map[string]TaskGroupScaleStatus
+}
+
+// TaskGroupScaleStatus is the scaling status of a task group
+type TaskGroupScaleStatus struct {
+	Desired int
+	Placed  int
+	Running int
+	Queued  int
+	Events  []ScalingEvent
+}
+
+// ScalingEvent records a change of the count of a task group, or an attempt
+// to change it
+type ScalingEvent struct {
+	Time          int64
+	Count         *int64
+	PreviousCount int64
+	Message       string
+	Error         bool
+	Meta          map[string]interface{}
+	EvalID        string
+	CreateIndex   uint64
+}
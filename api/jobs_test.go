@@ -355,6 +355,7 @@ func TestJobs_Canonicalize(t *testing.T) {
 										Perms:        helper.StringToPtr("0644"),
 										LeftDelim:    helper.StringToPtr("{{"),
 										RightDelim:   helper.StringToPtr("}}"),
+										Envvars:      helper.BoolToPtr(false),
 									},
 								},
 							},
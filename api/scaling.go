@@ -0,0 +1,71 @@
+package api
+
+import "github.com/hashicorp/nomad/helper"
+
+// Scaling is used to query the scaling policies of task groups.
+type Scaling struct {
+	client *Client
+}
+
+// Scaling returns a new handle on the scaling policies.
+func (c *Client) Scaling() *Scaling {
+	return &Scaling{client: c}
+}
+
+// ListPolicies is used to list the scaling policies. The policies of a single
+// job can be listed by setting the "job" query parameter to its ID.
+func (s *Scaling) ListPolicies(q *QueryOptions) ([]*ScalingPolicyListStub, *QueryMeta, error) {
+	var resp []*ScalingPolicyListStub
+	qm, err := s.client.query("/v1/scaling/policies", &resp, q)
+	if err != nil {
+		return nil, nil, err
+	}
+	return resp, qm, nil
+}
+
+// GetPolicy is used to query a scaling policy by its ID.
+func (s *Scaling) GetPolicy(id string, q *QueryOptions) (*ScalingPolicy, *QueryMeta, error) {
+	var resp ScalingPolicy
+	qm, err := s.client.query("/v1/scaling/policy/"+id, &resp, q)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &resp, qm, nil
+}
+
+// ScalingPolicy bounds the count of a task group and holds the policy used by
+// autoscalers to change it.
+type ScalingPolicy struct {
+	ID          string
+	JobID       string
+	TaskGroup   string
+	Min         *int64
+	Max         *int64
+	Policy      map[string]interface{}
+	Enabled     *bool
+	CreateIndex uint64
+	ModifyIndex uint64
+}
+
+// Canonicalize sets the defaults of the scaling policy of a task group with
+// the given count.
+func (p *ScalingPolicy) Canonicalize(count int) {
+	if p.Min == nil {
+		p.Min = helper.Int64ToPtr(int64(count))
+	}
+	if p.Enabled == nil {
+		p.Enabled = helper.BoolToPtr(true)
+	}
+}
+
+// ScalingPolicyListStub is a summary of a scaling policy.
+type ScalingPolicyListStub struct {
+	ID          string
+	JobID       string
+	TaskGroup   string
+	Min         int64
+	Max         int64
+	Enabled     bool
+	CreateIndex uint64
+	ModifyIndex uint64
+}
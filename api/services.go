@@ -0,0 +1,57 @@
+package api
+
+// Services is used to query the services registered with the Nomad
+// service registry.
+type Services struct {
+	client *Client
+}
+
+// Services returns a new handle on the services.
+func (c *Client) Services() *Services {
+	return &Services{client: c}
+}
+
+// List is used to dump the names and tags of all registered services.
+func (s *Services) List(q *QueryOptions) ([]*ServiceRegistrationListStub, *QueryMeta, error) {
+	var resp []*ServiceRegistrationListStub
+	qm, err := s.client.query("/v1/services", &resp, q)
+	if err != nil {
+		return nil, nil, err
+	}
+	return resp, qm, nil
+}
+
+// Get is used to query the registered instances of a service by name.
+func (s *Services) Get(name string, q *QueryOptions) ([]*ServiceRegistration, *QueryMeta, error) {
+	var resp []*ServiceRegistration
+	qm, err := s.client.query("/v1/service/"+name, &resp, q)
+	if err != nil {
+		return nil, nil, err
+	}
+	return resp, qm, nil
+}
+
+// ServiceRegistration is an instance of a service registered by the client
+// running the task that provides it.
+type ServiceRegistration struct {
+	ID          string
+	ServiceName string
+	NodeID      string
+	JobID       string
+	AllocID     string
+	TaskName    string
+	Datacenter  string
+	Tags        []string
+	Address     string
+	Port        int
+	Status      string
+	CreateIndex uint64
+	ModifyIndex uint64
+}
+
+// ServiceRegistrationListStub is the name of a registered service and the
+// union of the tags of its instances.
+type ServiceRegistrationListStub struct {
+	ServiceName string
+	Tags        []string
+}
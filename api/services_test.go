@@ -0,0 +1,37 @@
+package api
+
+import (
+	"testing"
+)
+
+func TestServices_List(t *testing.T) {
+	c, s := makeClient(t, nil, nil)
+	defer s.Stop()
+	services := c.Services()
+
+	// Listing when nothing exists returns empty
+	result, qm, err := services.List(nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	assertQueryMeta(t, qm)
+	if n := len(result); n != 0 {
+		t.Fatalf("expected 0 services, got: %d", n)
+	}
+}
+
+func TestServices_Get(t *testing.T) {
+	c, s := makeClient(t, nil, nil)
+	defer s.Stop()
+	services := c.Services()
+
+	// Querying an unknown service returns no instances
+	result, qm, err := services.Get("web", nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	assertQueryMeta(t, qm)
+	if n := len(result); n != 0 {
+		t.Fatalf("expected 0 instances, got: %d", n)
+	}
+}
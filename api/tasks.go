@@ -90,6 +90,21 @@ type ServiceCheck struct {
 	Interval      time.Duration
 	Timeout       time.Duration
 	InitialStatus string `mapstructure:"initial_status"`
+	Method        string
+	Header        map[string][]string
+	Body          string
+	TLSSkipVerify bool          `mapstructure:"tls_skip_verify"`
+	GRPCService   string        `mapstructure:"grpc_service"`
+	GRPCUseTLS    bool          `mapstructure:"grpc_use_tls"`
+	CheckRestart  *CheckRestart `mapstructure:"check_restart"`
+}
+
+// CheckRestart describes if and when a task should be restarted based on the
+// health of a service check
+type CheckRestart struct {
+	Limit          int
+	Grace          time.Duration
+	IgnoreWarnings bool `mapstructure:"ignore_warnings"`
 }
 
 // The Service model represents a Consul service definition
@@ -99,6 +114,8 @@ type Service struct {
 	Tags      []string
 	PortLabel string `mapstructure:"port"`
 	Checks    []ServiceCheck
+	Connect   *ConsulConnect
+	Provider  string
 }
 
 func (s *Service) Canonicalize(t *Task, tg *TaskGroup, job *Job) {
@@ -107,6 +124,29 @@ func (s *Service) Canonicalize(t *Task, tg *TaskGroup, job *Job) {
 	}
 }
 
+// ConsulConnect represents a Consul Connect configuration of a service
+type ConsulConnect struct {
+	SidecarService *ConsulSidecarService `mapstructure:"sidecar_service"`
+}
+
+// ConsulSidecarService is the sidecar proxy service of a Connect enabled
+// service
+type ConsulSidecarService struct {
+	Port  string
+	Proxy *ConsulProxy
+}
+
+// ConsulProxy is the configuration of a Connect sidecar proxy
+type ConsulProxy struct {
+	Upstreams []*ConsulUpstream
+}
+
+// ConsulUpstream is a service exposed on a local port by the sidecar proxy
+type ConsulUpstream struct {
+	DestinationName string `mapstructure:"destination_name"`
+	LocalBindPort   int    `mapstructure:"local_bind_port"`
+}
+
 // EphemeralDisk is an ephemeral disk object
 type EphemeralDisk struct {
 	Sticky  *bool
@@ -142,7 +182,9 @@ type TaskGroup struct {
 	Tasks         []*Task
 	RestartPolicy *RestartPolicy
 	EphemeralDisk *EphemeralDisk
+	Networks      []*NetworkResource
 	Meta          map[string]string
+	Scaling       *ScalingPolicy
 }
 
 // NewTaskGroup creates a new TaskGroup.
@@ -168,6 +210,12 @@ func (g *TaskGroup) Canonicalize(job *Job) {
 	} else {
 		g.EphemeralDisk.Canonicalize()
 	}
+	for _, n := range g.Networks {
+		n.Canonicalize()
+	}
+	if g.Scaling != nil {
+		g.Scaling.Canonicalize(*g.Count)
+	}
 
 	var defaultRestartPolicy *RestartPolicy
 	switch *job.Type {
@@ -208,6 +256,12 @@ func (g *TaskGroup) SetMeta(key, val string) *TaskGroup {
 	return g
 }
 
+// AddNetwork is used to add a network shared by the tasks of the task group.
+func (g *TaskGroup) AddNetwork(n *NetworkResource) *TaskGroup {
+	g.Networks = append(g.Networks, n)
+	return g
+}
+
 // AddTask is used to add a new task to a task group.
 func (g *TaskGroup) AddTask(t *Task) *TaskGroup {
 	g.Tasks = append(g.Tasks, t)
@@ -222,14 +276,19 @@ func (g *TaskGroup) RequireDisk(disk *EphemeralDisk) *TaskGroup {
 
 // LogConfig provides configuration for log rotation
 type LogConfig struct {
-	MaxFiles      *int `mapstructure:"max_files"`
-	MaxFileSizeMB *int `mapstructure:"max_file_size"`
+	MaxFiles      *int           `mapstructure:"max_files"`
+	MaxFileSizeMB *int           `mapstructure:"max_file_size"`
+	Compress      *bool          `mapstructure:"compress"`
+	MaxAge        *time.Duration `mapstructure:"max_age"`
+	Sinks         []*LogSink
 }
 
 func DefaultLogConfig() *LogConfig {
 	return &LogConfig{
 		MaxFiles:      helper.IntToPtr(10),
 		MaxFileSizeMB: helper.IntToPtr(10),
+		Compress:      helper.BoolToPtr(false),
+		MaxAge:        helper.TimeToPtr(0),
 	}
 }
 
@@ -240,6 +299,54 @@ func (l *LogConfig) Canonicalize() {
 	if l.MaxFileSizeMB == nil {
 		l.MaxFileSizeMB = helper.IntToPtr(10)
 	}
+	if l.Compress == nil {
+		l.Compress = helper.BoolToPtr(false)
+	}
+	if l.MaxAge == nil {
+		l.MaxAge = helper.TimeToPtr(0)
+	}
+	for _, sink := range l.Sinks {
+		sink.Canonicalize()
+	}
+}
+
+// LogSink is a destination the output of a task is forwarded to.
+type LogSink struct {
+	Type       *string
+	Address    *string
+	BatchSize  *int           `mapstructure:"batch_size"`
+	BatchWait  *time.Duration `mapstructure:"batch_wait"`
+	BufferSize *int           `mapstructure:"buffer_size"`
+	Headers    map[string]string
+}
+
+func (s *LogSink) Canonicalize() {
+	if s.Type == nil {
+		s.Type = helper.StringToPtr("")
+	}
+	if s.Address == nil {
+		s.Address = helper.StringToPtr("")
+	}
+	if s.BufferSize == nil {
+		s.BufferSize = helper.IntToPtr(10000)
+	}
+
+	// Only HTTP sinks batch log lines
+	if *s.Type == "http" {
+		if s.BatchSize == nil {
+			s.BatchSize = helper.IntToPtr(100)
+		}
+		if s.BatchWait == nil {
+			s.BatchWait = helper.TimeToPtr(1 * time.Second)
+		}
+	} else {
+		if s.BatchSize == nil {
+			s.BatchSize = helper.IntToPtr(0)
+		}
+		if s.BatchWait == nil {
+			s.BatchWait = helper.TimeToPtr(0)
+		}
+	}
 }
 
 // DispatchPayloadConfig configures how a task gets its input from a job dispatch
@@ -262,9 +369,11 @@ type Task struct {
 	LogConfig       *LogConfig     `mapstructure:"logs"`
 	Artifacts       []*TaskArtifact
 	Vault           *Vault
+	Identity        *WorkloadIdentity
 	Templates       []*Template
 	DispatchPayload *DispatchPayloadConfig
 	Leader          bool
+	Kind            string
 }
 
 func (t *Task) Canonicalize(tg *TaskGroup, job *Job) {
@@ -287,6 +396,9 @@ func (t *Task) Canonicalize(tg *TaskGroup, job *Job) {
 	if t.Vault != nil {
 		t.Vault.Canonicalize()
 	}
+	if t.Identity != nil {
+		t.Identity.Canonicalize()
+	}
 	for _, tmpl := range t.Templates {
 		tmpl.Canonicalize()
 	}
@@ -297,15 +409,19 @@ func (t *Task) Canonicalize(tg *TaskGroup, job *Job) {
 
 // TaskArtifact is used to download artifacts before running a task.
 type TaskArtifact struct {
-	GetterSource  *string           `mapstructure:"source"`
-	GetterOptions map[string]string `mapstructure:"options"`
-	RelativeDest  *string           `mapstructure:"destination"`
+	GetterSource    *string           `mapstructure:"source"`
+	GetterOptions   map[string]string `mapstructure:"options"`
+	RelativeDest    *string           `mapstructure:"destination"`
+	GetterSignature *string           `mapstructure:"signature"`
 }
 
 func (a *TaskArtifact) Canonicalize() {
 	if a.RelativeDest == nil {
 		a.RelativeDest = helper.StringToPtr("local/")
 	}
+	if a.GetterSignature == nil {
+		a.GetterSignature = helper.StringToPtr("")
+	}
 }
 
 type Template struct {
@@ -318,6 +434,7 @@ type Template struct {
 	Perms        *string        `mapstructure:"perms"`
 	LeftDelim    *string        `mapstructure:"left_delimiter"`
 	RightDelim   *string        `mapstructure:"right_delimiter"`
+	Envvars      *bool          `mapstructure:"env"`
 }
 
 func (tmpl *Template) Canonicalize() {
@@ -355,6 +472,9 @@ func (tmpl *Template) Canonicalize() {
 	if tmpl.RightDelim == nil {
 		tmpl.RightDelim = helper.StringToPtr("}}")
 	}
+	if tmpl.Envvars == nil {
+		tmpl.Envvars = helper.BoolToPtr(false)
+	}
 }
 
 type Vault struct {
@@ -362,6 +482,8 @@ type Vault struct {
 	Env          *bool
 	ChangeMode   *string `mapstructure:"change_mode"`
 	ChangeSignal *string `mapstructure:"change_signal"`
+	Role         *string
+	EntityAlias  *string `mapstructure:"entity_alias"`
 }
 
 func (v *Vault) Canonicalize() {
@@ -374,6 +496,28 @@ func (v *Vault) Canonicalize() {
 	if v.ChangeSignal == nil {
 		v.ChangeSignal = helper.StringToPtr("SIGHUP")
 	}
+	if v.Role == nil {
+		v.Role = helper.StringToPtr("")
+	}
+	if v.EntityAlias == nil {
+		v.EntityAlias = helper.StringToPtr("")
+	}
+}
+
+// WorkloadIdentity configures how the signed identity of a task is exposed to
+// it.
+type WorkloadIdentity struct {
+	Env  *bool
+	File *bool
+}
+
+func (w *WorkloadIdentity) Canonicalize() {
+	if w.Env == nil {
+		w.Env = helper.BoolToPtr(false)
+	}
+	if w.File == nil {
+		w.File = helper.BoolToPtr(false)
+	}
 }
 
 // NewTask creates and initializes a new Task.
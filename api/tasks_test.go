@@ -3,6 +3,7 @@ package api
 import (
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/hashicorp/nomad/helper"
 )
@@ -172,7 +173,7 @@ func TestTask_Require(t *testing.T) {
 			&NetworkResource{
 				CIDR:          "0.0.0.0/0",
 				MBits:         helper.IntToPtr(100),
-				ReservedPorts: []Port{{"", 80}, {"", 443}},
+				ReservedPorts: []Port{{"", 80, 0}, {"", 443, 0}},
 			},
 		},
 	}
@@ -219,3 +220,60 @@ func TestTask_Constrain(t *testing.T) {
 		t.Fatalf("expect: %#v, got: %#v", expect, task.Constraints)
 	}
 }
+
+func TestLogConfig_Canonicalize_Sinks(t *testing.T) {
+	l := &LogConfig{
+		Sinks: []*LogSink{
+			{Type: helper.StringToPtr("syslog"), Address: helper.StringToPtr("udp://127.0.0.1:514")},
+			{Type: helper.StringToPtr("http"), Address: helper.StringToPtr("https://logs.example.com")},
+		},
+	}
+	l.Canonicalize()
+
+	expected := []*LogSink{
+		{
+			Type:       helper.StringToPtr("syslog"),
+			Address:    helper.StringToPtr("udp://127.0.0.1:514"),
+			BatchSize:  helper.IntToPtr(0),
+			BatchWait:  helper.TimeToPtr(0),
+			BufferSize: helper.IntToPtr(10000),
+		},
+		{
+			Type:       helper.StringToPtr("http"),
+			Address:    helper.StringToPtr("https://logs.example.com"),
+			BatchSize:  helper.IntToPtr(100),
+			BatchWait:  helper.TimeToPtr(time.Second),
+			BufferSize: helper.IntToPtr(10000),
+		},
+	}
+	if !reflect.DeepEqual(l.Sinks, expected) {
+		t.Fatalf("bad sinks: %#v", l.Sinks)
+	}
+}
+
+func TestWorkloadIdentity_Canonicalize(t *testing.T) {
+	w := &WorkloadIdentity{Env: helper.BoolToPtr(true)}
+	w.Canonicalize()
+
+	expected := &WorkloadIdentity{
+		Env:  helper.BoolToPtr(true),
+		File: helper.BoolToPtr(false),
+	}
+	if !reflect.DeepEqual(w, expected) {
+		t.Fatalf("bad: %#v", w)
+	}
+}
+
+func TestScalingPolicy_Canonicalize(t *testing.T) {
+	p := &ScalingPolicy{Max: helper.Int64ToPtr(10)}
+	p.Canonicalize(3)
+
+	expected := &ScalingPolicy{
+		Min:     helper.Int64ToPtr(3),
+		Max:     helper.Int64ToPtr(10),
+		Enabled: helper.BoolToPtr(true),
+	}
+	if !reflect.DeepEqual(p, expected) {
+		t.Fatalf("bad: %#v", p)
+	}
+}
@@ -0,0 +1,215 @@
+package client
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/nomad/client/cni"
+	"github.com/hashicorp/nomad/client/driver"
+	"github.com/hashicorp/nomad/client/netns"
+	"github.com/hashicorp/nomad/nomad/structs"
+
+	dstructs "github.com/hashicorp/nomad/client/driver/structs"
+)
+
+const (
+	// allocNetworkIfName is the name of the interface created by the
+	// network's plugins inside the network namespace of an allocation.
+	allocNetworkIfName = "eth0"
+)
+
+// isolatedNetwork returns the network of the allocation's task group if it
+// runs in its own network namespace.
+func isolatedNetwork(alloc *structs.Allocation) *structs.NetworkResource {
+	if alloc.SharedResources == nil {
+		return nil
+	}
+	for _, network := range alloc.SharedResources.Networks {
+		if network.Isolated() {
+			return network
+		}
+	}
+	return nil
+}
+
+// networkConfigList returns the CNI configuration attaching the namespace to
+// the network. Bridge networks use a built-in configuration while CNI
+// networks are loaded from the client's config directory.
+func (r *AllocRunner) networkConfigList(network *structs.NetworkResource) (*cni.NetworkConfigList, error) {
+	if name := network.CNIName(); name != "" {
+		return cni.LoadConfigList(r.config.CNIConfigDir, name)
+	}
+	return cni.BridgeConfig(r.config.BridgeNetworkName, r.config.BridgeNetworkAllocSubnet), nil
+}
+
+// cniPortMappings returns the port mappings used to forward the host ports
+// of the network into the allocation's network namespace.
+func cniPortMappings(network *structs.NetworkResource) []cni.PortMapping {
+	var mappings []cni.PortMapping
+	for _, ports := range [][]structs.Port{network.ReservedPorts, network.DynamicPorts} {
+		for _, port := range ports {
+			to := port.To
+			if to == 0 {
+				to = port.Value
+			}
+			for _, proto := range []string{"tcp", "udp"} {
+				mappings = append(mappings, cni.PortMapping{
+					HostPort:      port.Value,
+					ContainerPort: to,
+					Protocol:      proto,
+					HostIP:        network.IP,
+				})
+			}
+		}
+	}
+	return mappings
+}
+
+// networkManager returns the name of the first driver of the task group that
+// creates network namespaces itself, along with the driver. If no driver
+// does, the client creates the namespace.
+func (r *AllocRunner) networkManager(tg *structs.TaskGroup) (string, driver.DriverNetworkManager, error) {
+	for _, task := range tg.Tasks {
+		d, err := r.newNetworkDriver(task.Driver)
+		if err != nil {
+			return "", nil, err
+		}
+		if nm, ok := d.(driver.DriverNetworkManager); ok {
+			return task.Driver, nm, nil
+		}
+	}
+	return "", nil, nil
+}
+
+// newNetworkDriver instantiates a driver to manage the network namespace.
+func (r *AllocRunner) newNetworkDriver(name string) (driver.Driver, error) {
+	ctx := driver.NewDriverContext("", r.alloc.ID, r.config, r.config.Node, r.logger, nil, nil)
+	return driver.NewDriver(name, ctx)
+}
+
+// cniRuntimeConf returns the CNI parameters for the allocation's namespace.
+func (r *AllocRunner) cniRuntimeConf(ifName string, spec *dstructs.NetworkIsolationSpec) *cni.RuntimeConf {
+	return &cni.RuntimeConf{
+		ContainerID: r.alloc.ID,
+		NetNS:       spec.Path,
+		IfName:      ifName,
+	}
+}
+
+// setupNetwork creates the network namespace shared by the tasks of the
+// allocation and attaches it to the task group's network. The address
+// assigned to the allocation is recorded in its network status. It is a
+// no-op if the task group uses the host's network or the namespace was
+// restored.
+func (r *AllocRunner) setupNetwork(tg *structs.TaskGroup) error {
+	network := isolatedNetwork(r.alloc)
+	if network == nil || r.networkIsolation != nil {
+		return nil
+	}
+
+	list, err := r.networkConfigList(network)
+	if err != nil {
+		return err
+	}
+
+	driverName, nm, err := r.networkManager(tg)
+	if err != nil {
+		return err
+	}
+
+	var spec *dstructs.NetworkIsolationSpec
+	if nm != nil {
+		spec, err = nm.CreateNetwork(r.alloc.ID)
+	} else {
+		var path string
+		path, err = netns.Create(r.alloc.ID)
+		spec = &dstructs.NetworkIsolationSpec{Path: path}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create network namespace: %v", err)
+	}
+
+	r.networkLock.Lock()
+	r.networkIsolation = spec
+	r.networkDriver = driverName
+	r.networkLock.Unlock()
+
+	c := cni.New(filepath.SplitList(r.config.CNIPath))
+	if _, err := c.AddNetworkList(cni.LoopbackConfig(), r.cniRuntimeConf("lo", spec)); err != nil {
+		r.teardownNetwork()
+		return err
+	}
+
+	rt := r.cniRuntimeConf(allocNetworkIfName, spec)
+	rt.PortMappings = cniPortMappings(network)
+	res, err := c.AddNetworkList(list, rt)
+	if err != nil {
+		r.teardownNetwork()
+		return err
+	}
+
+	status := &structs.AllocNetworkStatus{
+		InterfaceName: allocNetworkIfName,
+		Address:       res.Address(allocNetworkIfName),
+	}
+	r.allocLock.Lock()
+	r.alloc.NetworkStatus = status
+	r.allocLock.Unlock()
+
+	r.logger.Printf("[DEBUG] client: created network namespace %q with address %q for alloc %q",
+		spec.Path, status.Address, r.alloc.ID)
+	return r.saveAllocRunnerState()
+}
+
+// teardownNetwork detaches and destroys the network namespace of the
+// allocation if one was created.
+func (r *AllocRunner) teardownNetwork() {
+	r.networkLock.Lock()
+	spec, driverName := r.networkIsolation, r.networkDriver
+	r.networkLock.Unlock()
+	if spec == nil {
+		return
+	}
+
+	var mErr multierror.Error
+	c := cni.New(filepath.SplitList(r.config.CNIPath))
+	if network := isolatedNetwork(r.alloc); network != nil {
+		rt := r.cniRuntimeConf(allocNetworkIfName, spec)
+		rt.PortMappings = cniPortMappings(network)
+		if list, err := r.networkConfigList(network); err != nil {
+			mErr.Errors = append(mErr.Errors, err)
+		} else if err := c.DelNetworkList(list, rt); err != nil {
+			mErr.Errors = append(mErr.Errors, err)
+		}
+	}
+	if err := c.DelNetworkList(cni.LoopbackConfig(), r.cniRuntimeConf("lo", spec)); err != nil {
+		mErr.Errors = append(mErr.Errors, err)
+	}
+
+	if driverName != "" {
+		d, err := r.newNetworkDriver(driverName)
+		if err != nil {
+			mErr.Errors = append(mErr.Errors, err)
+		} else if nm, ok := d.(driver.DriverNetworkManager); ok {
+			if err := nm.DestroyNetwork(r.alloc.ID, spec); err != nil {
+				mErr.Errors = append(mErr.Errors, err)
+			}
+		}
+	} else if err := netns.Destroy(spec.Path); err != nil {
+		mErr.Errors = append(mErr.Errors, err)
+	}
+
+	if err := mErr.ErrorOrNil(); err != nil {
+		r.logger.Printf("[ERR] client: failed to destroy network of alloc %q: %v", r.alloc.ID, err)
+	}
+
+	r.networkLock.Lock()
+	r.networkIsolation = nil
+	r.networkDriver = ""
+	r.networkLock.Unlock()
+
+	if err := r.saveAllocRunnerState(); err != nil {
+		r.logger.Printf("[ERR] client: failed to save state of alloc %q: %v", r.alloc.ID, err)
+	}
+}
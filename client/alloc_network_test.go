@@ -0,0 +1,78 @@
+package client
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/nomad/client/cni"
+	"github.com/hashicorp/nomad/nomad/mock"
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+func TestAllocNetwork_IsolatedNetwork(t *testing.T) {
+	alloc := mock.Alloc()
+	if network := isolatedNetwork(alloc); network != nil {
+		t.Fatalf("unexpected isolated network: %v", network)
+	}
+
+	bridge := &structs.NetworkResource{Mode: structs.NetworkModeBridge, MBits: 10}
+	alloc.SharedResources.Networks = []*structs.NetworkResource{
+		{Mode: structs.NetworkModeHost, MBits: 10},
+		bridge,
+	}
+	if network := isolatedNetwork(alloc); network != bridge {
+		t.Fatalf("bad bridge network: %v", network)
+	}
+}
+
+func TestAllocNetwork_CNIPortMappings(t *testing.T) {
+	network := &structs.NetworkResource{
+		IP:            "10.0.0.1",
+		ReservedPorts: []structs.Port{{Label: "db", Value: 6379}},
+		DynamicPorts:  []structs.Port{{Label: "http", Value: 23456, To: 8080}},
+	}
+
+	expected := []cni.PortMapping{
+		{HostPort: 6379, ContainerPort: 6379, Protocol: "tcp", HostIP: "10.0.0.1"},
+		{HostPort: 6379, ContainerPort: 6379, Protocol: "udp", HostIP: "10.0.0.1"},
+		{HostPort: 23456, ContainerPort: 8080, Protocol: "tcp", HostIP: "10.0.0.1"},
+		{HostPort: 23456, ContainerPort: 8080, Protocol: "udp", HostIP: "10.0.0.1"},
+	}
+	if act := cniPortMappings(network); !reflect.DeepEqual(act, expected) {
+		t.Fatalf("bad port mappings: %#v", act)
+	}
+}
+
+func TestAllocNetwork_TaskResources(t *testing.T) {
+	alloc := mock.Alloc()
+	if act := taskResources(alloc, "web"); act != alloc.TaskResources["web"] {
+		t.Fatalf("resources without a group network should be unchanged")
+	}
+
+	network := &structs.NetworkResource{
+		Mode:         structs.NetworkModeBridge,
+		MBits:        10,
+		DynamicPorts: []structs.Port{{Label: "http", Value: 23456, To: 8080}},
+	}
+	alloc.SharedResources.Networks = []*structs.NetworkResource{network}
+
+	act := taskResources(alloc, "web")
+	if n := len(alloc.TaskResources["web"].Networks); n != 1 {
+		t.Fatalf("task resources of the alloc were modified: %d networks", n)
+	}
+	if len(act.Networks) != 2 || !reflect.DeepEqual(act.Networks[1], network) {
+		t.Fatalf("bad networks: %#v", act.Networks)
+	}
+
+	// Tasks on a CNI network use the address recorded on the alloc
+	network.Mode = "cni/macvlan"
+	network.IP = "192.168.0.100"
+	alloc.NetworkStatus = &structs.AllocNetworkStatus{InterfaceName: "eth0", Address: "10.0.0.5"}
+	act = taskResources(alloc, "web")
+	if ip := act.Networks[1].IP; ip != "10.0.0.5" {
+		t.Fatalf("bad ip: %s", ip)
+	}
+	if network.IP != "192.168.0.100" {
+		t.Fatalf("shared network of the alloc was modified")
+	}
+}
@@ -14,6 +14,7 @@ import (
 	"github.com/hashicorp/nomad/client/vaultclient"
 	"github.com/hashicorp/nomad/nomad/structs"
 
+	dstructs "github.com/hashicorp/nomad/client/driver/structs"
 	cstructs "github.com/hashicorp/nomad/client/structs"
 )
 
@@ -61,8 +62,18 @@ type AllocRunner struct {
 
 	vaultClient vaultclient.VaultClient
 
+	// rpc is used by the tasks to query the servers
+	rpc config.RPCHandler
+
 	otherAllocDir *allocdir.AllocDir
 
+	// networkIsolation is the network namespace shared by the tasks when
+	// the task group uses bridge networking. networkDriver is the name of
+	// the driver that created it or empty if the client created it.
+	networkIsolation *dstructs.NetworkIsolationSpec
+	networkDriver    string
+	networkLock      sync.Mutex
+
 	destroy     bool
 	destroyCh   chan struct{}
 	destroyLock sync.Mutex
@@ -79,6 +90,8 @@ type allocRunnerState struct {
 	AllocDir               *allocdir.AllocDir
 	AllocClientStatus      string
 	AllocClientDescription string
+	NetworkIsolation       *dstructs.NetworkIsolationSpec
+	NetworkDriver          string
 
 	// COMPAT: Remove in 0.7.0: removing will break upgrading directly from
 	//         0.5.2, so don't remove in the 0.6 series.
@@ -96,7 +109,7 @@ type allocRunnerState struct {
 
 // NewAllocRunner is used to create a new allocation context
 func NewAllocRunner(logger *log.Logger, config *config.Config, updater AllocStateUpdater,
-	alloc *structs.Allocation, vaultClient vaultclient.VaultClient) *AllocRunner {
+	alloc *structs.Allocation, vaultClient vaultclient.VaultClient, rpc config.RPCHandler) *AllocRunner {
 	ar := &AllocRunner{
 		config:      config,
 		updater:     updater,
@@ -110,6 +123,7 @@ func NewAllocRunner(logger *log.Logger, config *config.Config, updater AllocStat
 		destroyCh:   make(chan struct{}),
 		waitCh:      make(chan struct{}),
 		vaultClient: vaultClient,
+		rpc:         rpc,
 	}
 	return ar
 }
@@ -145,6 +159,8 @@ func (r *AllocRunner) RestoreState() error {
 	r.allocDir = snap.AllocDir
 	r.allocClientStatus = snap.AllocClientStatus
 	r.allocClientDescription = snap.AllocClientDescription
+	r.networkIsolation = snap.NetworkIsolation
+	r.networkDriver = snap.NetworkDriver
 
 	var snapshotErrors multierror.Error
 	if r.alloc == nil {
@@ -174,7 +190,8 @@ func (r *AllocRunner) RestoreState() error {
 		}
 
 		task := &structs.Task{Name: name}
-		tr := NewTaskRunner(r.logger, r.config, r.setTaskState, td, r.Alloc(), task, r.vaultClient)
+		tr := NewTaskRunner(r.logger, r.config, r.setTaskState, td, r.Alloc(), task, r.vaultClient, r.rpc)
+		tr.SetNetworkIsolation(r.networkIsolation)
 		r.tasks[name] = tr
 
 		// Skip tasks in terminal states.
@@ -235,12 +252,19 @@ func (r *AllocRunner) saveAllocRunnerState() error {
 	allocDir := r.allocDir
 	r.allocDirLock.Unlock()
 
+	r.networkLock.Lock()
+	networkIsolation := r.networkIsolation
+	networkDriver := r.networkDriver
+	r.networkLock.Unlock()
+
 	snap := allocRunnerState{
 		Version:                r.config.Version,
 		Alloc:                  alloc,
 		AllocDir:               allocDir,
 		AllocClientStatus:      allocClientStatus,
 		AllocClientDescription: allocClientDescription,
+		NetworkIsolation:       networkIsolation,
+		NetworkDriver:          networkDriver,
 	}
 	return persistState(r.stateFilePath(), &snap)
 }
@@ -495,11 +519,19 @@ func (r *AllocRunner) Run() {
 	// clean up the allocation.
 	if alloc.TerminalStatus() {
 		r.logger.Printf("[DEBUG] client: alloc %q in terminal status, waiting for destroy", r.alloc.ID)
+		r.teardownNetwork()
 		r.handleDestroy()
 		r.logger.Printf("[DEBUG] client: terminating runner for alloc '%s'", r.alloc.ID)
 		return
 	}
 
+	// Create the network namespace shared by the tasks
+	if err := r.setupNetwork(tg); err != nil {
+		r.logger.Printf("[ERR] client: failed to setup network for alloc %q: %v", r.alloc.ID, err)
+		r.setStatus(structs.AllocClientStatusFailed, fmt.Sprintf("failed to setup network: %v", err))
+		return
+	}
+
 	// Start the task runners
 	r.logger.Printf("[DEBUG] client: starting task runners for alloc '%s'", r.alloc.ID)
 	r.taskLock.Lock()
@@ -512,7 +544,8 @@ func (r *AllocRunner) Run() {
 		taskdir := r.allocDir.NewTaskDir(task.Name)
 		r.allocDirLock.Unlock()
 
-		tr := NewTaskRunner(r.logger, r.config, r.setTaskState, taskdir, r.Alloc(), task.Copy(), r.vaultClient)
+		tr := NewTaskRunner(r.logger, r.config, r.setTaskState, taskdir, r.Alloc(), task.Copy(), r.vaultClient, r.rpc)
+		tr.SetNetworkIsolation(r.networkIsolation)
 		r.tasks[task.Name] = tr
 		tr.MarkReceived()
 
@@ -529,8 +562,10 @@ OUTER:
 	for {
 		select {
 		case update := <-r.updateCh:
-			// Store the updated allocation.
+			// Store the updated allocation. The client is the authority on
+			// the network status so it is carried over.
 			r.allocLock.Lock()
+			update.NetworkStatus = r.alloc.NetworkStatus
 			r.alloc = update
 			r.allocLock.Unlock()
 
@@ -554,6 +589,9 @@ OUTER:
 	// Kill the task runners
 	r.destroyTaskRunners(taskDestroyEvent)
 
+	// Destroy the network namespace now that no task uses it
+	r.teardownNetwork()
+
 	// Block until we should destroy the state of the alloc
 	r.handleDestroy()
 	r.logger.Printf("[DEBUG] client: terminating runner for alloc '%s'", r.alloc.ID)
@@ -0,0 +1,147 @@
+package client
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	consulapi "github.com/hashicorp/consul/api"
+	"github.com/hashicorp/nomad/command/agent/consul"
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+// consulChecksGetter returns the checks registered with the local Consul
+// agent. It is implemented by the Consul API's Agent.
+type consulChecksGetter interface {
+	Checks() (map[string]*consulapi.AgentCheck, error)
+}
+
+// watchedCheck is a service check of the task that restarts the task when it
+// is unhealthy.
+type watchedCheck struct {
+	name    string
+	restart *structs.CheckRestart
+
+	// unhealthy is the number of consecutive unhealthy results
+	unhealthy int
+}
+
+// apply records the status of the check and returns whether the task should
+// be restarted.
+func (w *watchedCheck) apply(status string) bool {
+	switch status {
+	case consulapi.HealthCritical:
+		w.unhealthy++
+	case consulapi.HealthWarning:
+		if w.restart.IgnoreWarnings {
+			w.unhealthy = 0
+		} else {
+			w.unhealthy++
+		}
+	default:
+		w.unhealthy = 0
+	}
+	return w.unhealthy >= w.restart.Limit
+}
+
+// watchedChecks returns the service checks of the task with check_restart
+// behavior and the interval they should be polled at.
+func (r *TaskRunner) watchedChecks() ([]*watchedCheck, time.Duration) {
+	env := r.getTaskEnv()
+
+	var checks []*watchedCheck
+	var interval time.Duration
+	for _, service := range r.task.Services {
+		for _, check := range service.Checks {
+			if check.CheckRestart == nil || check.CheckRestart.Limit == 0 {
+				continue
+			}
+
+			// Check names are interpolated by the executor before they
+			// are registered
+			name := check.Name
+			if env != nil {
+				name = env.ReplaceEnv(name)
+			}
+			checks = append(checks, &watchedCheck{
+				name:    name,
+				restart: check.CheckRestart,
+			})
+
+			if interval == 0 || check.Interval < interval {
+				interval = check.Interval
+			}
+		}
+	}
+	return checks, interval
+}
+
+// watchChecks polls the health of the task's service checks with
+// check_restart behavior and restarts the task once a check has been
+// unhealthy for the configured number of consecutive results. Results are
+// ignored until the grace period of the check has passed since the watcher
+// was started. The watcher exits when stopCh is closed.
+func (r *TaskRunner) watchChecks(stopCh chan struct{}) {
+	checks, interval := r.watchedChecks()
+	if len(checks) == 0 {
+		return
+	}
+
+	if r.consulChecks == nil {
+		apiConf, err := r.config.ConsulConfig.ApiConfig()
+		if err != nil {
+			r.logger.Printf("[ERR] client: failed to create Consul config to watch checks of task %q for alloc %q: %v",
+				r.task.Name, r.alloc.ID, err)
+			return
+		}
+		client, err := consulapi.NewClient(apiConf)
+		if err != nil {
+			r.logger.Printf("[ERR] client: failed to create Consul client to watch checks of task %q for alloc %q: %v",
+				r.task.Name, r.alloc.ID, err)
+			return
+		}
+		r.consulChecks = client.Agent()
+	}
+
+	prefix := consul.ServiceIDPrefix(consul.NewExecutorDomain(r.alloc.ID, r.task.Name))
+	started := time.Now()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stopCh:
+			return
+		case <-ticker.C:
+		}
+
+		registered, err := r.consulChecks.Checks()
+		if err != nil {
+			r.logger.Printf("[DEBUG] client: failed to query checks of task %q for alloc %q: %v",
+				r.task.Name, r.alloc.ID, err)
+			continue
+		}
+
+		// Index the status of the task's checks by name
+		statuses := make(map[string]string)
+		for _, check := range registered {
+			if strings.HasPrefix(check.ServiceID, prefix) {
+				statuses[check.Name] = check.Status
+			}
+		}
+
+		for _, check := range checks {
+			status, ok := statuses[check.name]
+			if !ok || time.Since(started) < check.restart.Grace {
+				continue
+			}
+
+			if check.apply(status) {
+				r.logger.Printf("[INFO] client: restarting task %q for alloc %q: check %q unhealthy",
+					r.task.Name, r.alloc.ID, check.name)
+				r.Restart("healthcheck", fmt.Sprintf("check %q unhealthy", check.name))
+				return
+			}
+		}
+	}
+}
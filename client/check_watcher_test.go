@@ -0,0 +1,140 @@
+package client
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	consulapi "github.com/hashicorp/consul/api"
+	"github.com/hashicorp/nomad/command/agent/consul"
+	"github.com/hashicorp/nomad/nomad/mock"
+	"github.com/hashicorp/nomad/nomad/structs"
+	"github.com/hashicorp/nomad/testutil"
+)
+
+// fakeConsulChecks returns a check with a settable status for every
+// registered name.
+type fakeConsulChecks struct {
+	serviceID string
+	status    string
+	names     []string
+	sync.Mutex
+}
+
+func (f *fakeConsulChecks) setStatus(status string) {
+	f.Lock()
+	defer f.Unlock()
+	f.status = status
+}
+
+func (f *fakeConsulChecks) Checks() (map[string]*consulapi.AgentCheck, error) {
+	f.Lock()
+	defer f.Unlock()
+	out := make(map[string]*consulapi.AgentCheck, len(f.names))
+	for i, name := range f.names {
+		id := fmt.Sprintf("check-%d", i)
+		out[id] = &consulapi.AgentCheck{
+			CheckID:   id,
+			Name:      name,
+			ServiceID: f.serviceID,
+			Status:    f.status,
+		}
+	}
+	return out, nil
+}
+
+func TestWatchedCheck_Apply(t *testing.T) {
+	check := &watchedCheck{
+		name:    "foo",
+		restart: &structs.CheckRestart{Limit: 2},
+	}
+	if check.apply(consulapi.HealthCritical) {
+		t.Fatalf("should not restart after one failure")
+	}
+	if check.apply(consulapi.HealthPassing) || check.unhealthy != 0 {
+		t.Fatalf("passing result should reset the failures")
+	}
+	if check.apply(consulapi.HealthWarning) || !check.apply(consulapi.HealthCritical) {
+		t.Fatalf("should restart after two consecutive failures")
+	}
+
+	check = &watchedCheck{
+		name:    "foo",
+		restart: &structs.CheckRestart{Limit: 1, IgnoreWarnings: true},
+	}
+	if check.apply(consulapi.HealthWarning) {
+		t.Fatalf("warnings should be ignored")
+	}
+	if !check.apply(consulapi.HealthCritical) {
+		t.Fatalf("should restart after a failure")
+	}
+}
+
+func TestTaskRunner_CheckRestart(t *testing.T) {
+	alloc := mock.Alloc()
+	task := alloc.Job.TaskGroups[0].Tasks[0]
+	task.Driver = "mock_driver"
+	task.Config = map[string]interface{}{
+		"exit_code": "0",
+		"run_for":   "100s",
+	}
+	task.Services = []*structs.Service{
+		{
+			Name:      "web",
+			PortLabel: "http",
+			Checks: []*structs.ServiceCheck{
+				{
+					Name:     "web-alive",
+					Type:     structs.ServiceCheckHTTP,
+					Path:     "/health",
+					Interval: 50 * time.Millisecond,
+					Timeout:  time.Second,
+					CheckRestart: &structs.CheckRestart{
+						Limit: 3,
+						Grace: 100 * time.Millisecond,
+					},
+				},
+			},
+		},
+	}
+
+	ctx := testTaskRunnerFromAlloc(t, true, alloc)
+	checks := &fakeConsulChecks{
+		serviceID: consul.ServiceIDPrefix(consul.NewExecutorDomain(alloc.ID, task.Name)) + "web",
+		names:     []string{"web-alive"},
+		status:    consulapi.HealthPassing,
+	}
+	ctx.tr.consulChecks = checks
+	ctx.tr.MarkReceived()
+	go ctx.tr.Run()
+	defer ctx.Cleanup()
+
+	testWaitForTaskToStart(t, ctx)
+	checks.setStatus(consulapi.HealthCritical)
+
+	testutil.WaitForResult(func() (bool, error) {
+		for _, e := range ctx.upd.events {
+			if e.Type != structs.TaskRestartSignal {
+				continue
+			}
+			if !strings.Contains(e.RestartReason, `check "web-alive" unhealthy`) {
+				return false, fmt.Errorf("unexpected restart reason: %q", e.RestartReason)
+			}
+			return true, nil
+		}
+		return false, fmt.Errorf("task not restarted: %#v", ctx.upd.events)
+	}, func(err error) {
+		t.Fatalf("err: %v", err)
+	})
+
+	// Once healthy the task keeps running
+	checks.setStatus(consulapi.HealthPassing)
+	ctx.tr.Kill("test", "done", false)
+	select {
+	case <-ctx.tr.WaitCh():
+	case <-time.After(time.Duration(testutil.TestMultiplier()*15) * time.Second):
+		t.Fatalf("timeout")
+	}
+}
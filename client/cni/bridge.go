@@ -0,0 +1,56 @@
+package cni
+
+// LoopbackConfig returns the network configuration bringing up the loopback
+// interface of a network namespace.
+func LoopbackConfig() *NetworkConfigList {
+	return &NetworkConfigList{
+		CNIVersion: Version,
+		Name:       "nomad-loopback",
+		Plugins: []map[string]interface{}{
+			{"type": "loopback"},
+		},
+	}
+}
+
+// BridgeConfig returns the network configuration used for allocations with
+// bridge networking. Each allocation is attached to the bridge and assigned
+// an address from the subnet. Traffic leaving the subnet is masqueraded and
+// the host ports of the allocation are forwarded into its namespace.
+func BridgeConfig(bridge, subnet string) *NetworkConfigList {
+	return &NetworkConfigList{
+		CNIVersion: Version,
+		Name:       "nomad",
+		Plugins: []map[string]interface{}{
+			{
+				"type":         "bridge",
+				"bridge":       bridge,
+				"ipMasq":       true,
+				"isGateway":    true,
+				"forceAddress": true,
+				"hairpinMode":  true,
+				"ipam": map[string]interface{}{
+					"type": "host-local",
+					"ranges": [][]map[string]interface{}{
+						{
+							{"subnet": subnet},
+						},
+					},
+					"routes": []map[string]interface{}{
+						{"dst": "0.0.0.0/0"},
+					},
+				},
+			},
+			{
+				"type":    "firewall",
+				"backend": "iptables",
+			},
+			{
+				"type": "portmap",
+				"snat": true,
+				"capabilities": map[string]interface{}{
+					"portMappings": true,
+				},
+			},
+		},
+	}
+}
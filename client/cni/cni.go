@@ -0,0 +1,254 @@
+// Package cni invokes Container Network Interface plugins to configure the
+// network namespaces of allocations. Plugins are executed directly following
+// the CNI specification: the network configuration is written to the
+// plugin's stdin and the runtime parameters are passed as environment
+// variables.
+package cni
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// Version is the CNI specification version used for the configurations
+	// generated by Nomad.
+	Version = "0.4.0"
+
+	commandAdd = "ADD"
+	commandDel = "DEL"
+)
+
+// NetworkConfigList is a list of plugins that are invoked in order to
+// configure a network.
+type NetworkConfigList struct {
+	CNIVersion string                   `json:"cniVersion"`
+	Name       string                   `json:"name"`
+	Plugins    []map[string]interface{} `json:"plugins"`
+}
+
+// PortMapping maps a port on the host to a port inside the network namespace.
+// It is passed to plugins that declare the portMappings capability.
+type PortMapping struct {
+	HostPort      int    `json:"hostPort"`
+	ContainerPort int    `json:"containerPort"`
+	Protocol      string `json:"protocol"`
+	HostIP        string `json:"hostIP,omitempty"`
+}
+
+// RuntimeConf holds the parameters of a single plugin invocation.
+type RuntimeConf struct {
+	// ContainerID is a unique ID for the network namespace, Nomad uses the
+	// allocation ID.
+	ContainerID string
+
+	// NetNS is the path to the network namespace.
+	NetNS string
+
+	// IfName is the name of the interface to create inside the namespace.
+	IfName string
+
+	// PortMappings are the ports to forward into the namespace.
+	PortMappings []PortMapping
+}
+
+// Interface is an interface created by a plugin.
+type Interface struct {
+	Name    string `json:"name"`
+	Mac     string `json:"mac,omitempty"`
+	Sandbox string `json:"sandbox,omitempty"`
+}
+
+// IPConfig is an address assigned by a plugin.
+type IPConfig struct {
+	Version   string `json:"version"`
+	Interface *int   `json:"interface,omitempty"`
+	Address   string `json:"address"`
+	Gateway   string `json:"gateway,omitempty"`
+}
+
+// Result is the result returned by the last plugin of a list.
+type Result struct {
+	CNIVersion string       `json:"cniVersion,omitempty"`
+	Interfaces []*Interface `json:"interfaces,omitempty"`
+	IPs        []*IPConfig  `json:"ips,omitempty"`
+
+	// raw is the unparsed result which is passed to the next plugin of the
+	// list as its prevResult.
+	raw map[string]interface{}
+}
+
+// Address returns the address assigned to the interface inside the network
+// namespace without its prefix length. If the plugins didn't report the
+// interface of the addresses, the first address is returned.
+func (r *Result) Address(ifName string) string {
+	for _, ip := range r.IPs {
+		if ip.Interface == nil || *ip.Interface < 0 || *ip.Interface >= len(r.Interfaces) {
+			continue
+		}
+		iface := r.Interfaces[*ip.Interface]
+		if iface.Name == ifName && iface.Sandbox != "" {
+			return addressIP(ip.Address)
+		}
+	}
+	for _, ip := range r.IPs {
+		if ip.Interface == nil {
+			return addressIP(ip.Address)
+		}
+	}
+	return ""
+}
+
+// addressIP returns the IP of an address in CIDR notation.
+func addressIP(addr string) string {
+	ip, _, err := net.ParseCIDR(addr)
+	if err != nil {
+		return addr
+	}
+	return ip.String()
+}
+
+// pluginError is the error format plugins write to stdout on failure.
+type pluginError struct {
+	Code    int    `json:"code"`
+	Msg     string `json:"msg"`
+	Details string `json:"details,omitempty"`
+}
+
+// CNI invokes plugins found in a set of directories.
+type CNI struct {
+	paths []string
+}
+
+// New returns a CNI that looks up plugins in the given directories.
+func New(paths []string) *CNI {
+	return &CNI{paths: paths}
+}
+
+// AddNetworkList invokes the ADD command of every plugin of the list in order
+// and returns the result of the last plugin.
+func (c *CNI) AddNetworkList(list *NetworkConfigList, rt *RuntimeConf) (*Result, error) {
+	var prev *Result
+	for _, plugin := range list.Plugins {
+		res, err := c.exec(commandAdd, list, plugin, prev, rt)
+		if err != nil {
+			return nil, err
+		}
+		prev = res
+	}
+
+	if prev == nil {
+		return &Result{}, nil
+	}
+	return prev, nil
+}
+
+// DelNetworkList invokes the DEL command of every plugin of the list in
+// reverse order. All plugins are invoked even if one of them fails.
+func (c *CNI) DelNetworkList(list *NetworkConfigList, rt *RuntimeConf) error {
+	var errs []string
+	for i := len(list.Plugins) - 1; i >= 0; i-- {
+		if _, err := c.exec(commandDel, list, list.Plugins[i], nil, rt); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) != 0 {
+		return fmt.Errorf("failed to delete network %q: %s", list.Name, strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// findPlugin returns the path of the plugin binary.
+func (c *CNI) findPlugin(name string) (string, error) {
+	for _, dir := range c.paths {
+		path := filepath.Join(dir, name)
+		if fi, err := os.Stat(path); err == nil && !fi.IsDir() {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("failed to find CNI plugin %q in %v", name, c.paths)
+}
+
+// pluginConfig builds the configuration written to a plugin's stdin.
+func pluginConfig(list *NetworkConfigList, plugin map[string]interface{}, prev *Result, rt *RuntimeConf) map[string]interface{} {
+	conf := make(map[string]interface{}, len(plugin)+4)
+	for k, v := range plugin {
+		conf[k] = v
+	}
+	conf["name"] = list.Name
+	conf["cniVersion"] = list.CNIVersion
+	if prev != nil && prev.raw != nil {
+		conf["prevResult"] = prev.raw
+	}
+
+	// Only pass the runtime arguments the plugin has declared support for
+	if caps, ok := plugin["capabilities"].(map[string]interface{}); ok {
+		if enabled, _ := caps["portMappings"].(bool); enabled && len(rt.PortMappings) != 0 {
+			conf["runtimeConfig"] = map[string]interface{}{
+				"portMappings": rt.PortMappings,
+			}
+		}
+	}
+	return conf
+}
+
+// exec invokes a single plugin.
+func (c *CNI) exec(command string, list *NetworkConfigList, plugin map[string]interface{}, prev *Result, rt *RuntimeConf) (*Result, error) {
+	pluginType, _ := plugin["type"].(string)
+	if pluginType == "" {
+		return nil, fmt.Errorf("network %q has a plugin without a type", list.Name)
+	}
+
+	path, err := c.findPlugin(pluginType)
+	if err != nil {
+		return nil, err
+	}
+
+	stdin, err := json.Marshal(pluginConfig(list, plugin, prev, rt))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode configuration of plugin %q: %v", pluginType, err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(path)
+	cmd.Stdin = bytes.NewReader(stdin)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	cmd.Env = append(os.Environ(),
+		"CNI_COMMAND="+command,
+		"CNI_CONTAINERID="+rt.ContainerID,
+		"CNI_NETNS="+rt.NetNS,
+		"CNI_IFNAME="+rt.IfName,
+		"CNI_PATH="+strings.Join(c.paths, string(os.PathListSeparator)),
+	)
+
+	if err := cmd.Run(); err != nil {
+		var perr pluginError
+		if jerr := json.Unmarshal(stdout.Bytes(), &perr); jerr == nil && perr.Msg != "" {
+			if perr.Details != "" {
+				return nil, fmt.Errorf("plugin %q failed: %s: %s", pluginType, perr.Msg, perr.Details)
+			}
+			return nil, fmt.Errorf("plugin %q failed: %s", pluginType, perr.Msg)
+		}
+		return nil, fmt.Errorf("plugin %q failed: %v: %s", pluginType, err, strings.TrimSpace(stderr.String()))
+	}
+
+	if command != commandAdd || stdout.Len() == 0 {
+		return nil, nil
+	}
+
+	var res Result
+	if err := json.Unmarshal(stdout.Bytes(), &res); err != nil {
+		return nil, fmt.Errorf("failed to decode result of plugin %q: %v", pluginType, err)
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &res.raw); err != nil {
+		return nil, fmt.Errorf("failed to decode result of plugin %q: %v", pluginType, err)
+	}
+	return &res, nil
+}
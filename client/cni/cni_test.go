@@ -0,0 +1,149 @@
+package cni
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// fakePlugin writes a plugin script that records its invocation into the
+// directory and prints the given result.
+func fakePlugin(t *testing.T, dir, name, result string, exitCode int) {
+	script := `#!/bin/sh
+cat > "` + dir + `/` + name + `.$CNI_COMMAND.stdin"
+echo "$CNI_COMMAND $CNI_CONTAINERID $CNI_NETNS $CNI_IFNAME" >> "` + dir + `/calls"
+echo '` + result + `'
+exit ` + strconv.Itoa(exitCode) + `
+`
+	if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(script), 0755); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+}
+
+func TestCNI_AddDelNetworkList(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test requires a shell")
+	}
+
+	dir, err := ioutil.TempDir("", "cni")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	fakePlugin(t, dir, "bridge", `{"cniVersion":"0.4.0","ips":[{"version":"4","address":"172.26.64.2/20","gateway":"172.26.64.1"}]}`, 0)
+	fakePlugin(t, dir, "portmap", `{"cniVersion":"0.4.0","ips":[{"version":"4","address":"172.26.64.2/20","gateway":"172.26.64.1"}]}`, 0)
+
+	list := &NetworkConfigList{
+		CNIVersion: Version,
+		Name:       "test",
+		Plugins: []map[string]interface{}{
+			{"type": "bridge"},
+			{"type": "portmap", "capabilities": map[string]interface{}{"portMappings": true}},
+		},
+	}
+	rt := &RuntimeConf{
+		ContainerID: "alloc",
+		NetNS:       "/var/run/netns/alloc",
+		IfName:      "eth0",
+		PortMappings: []PortMapping{
+			{HostPort: 20000, ContainerPort: 8080, Protocol: "tcp"},
+		},
+	}
+
+	c := New([]string{filepath.Join(dir, "missing"), dir})
+	res, err := c.AddNetworkList(list, rt)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(res.IPs) != 1 || res.IPs[0].Address != "172.26.64.2/20" {
+		t.Fatalf("bad result: %#v", res)
+	}
+
+	// The bridge plugin doesn't declare the capability so it must not get
+	// the port mappings
+	var bridgeConf map[string]interface{}
+	data, _ := ioutil.ReadFile(filepath.Join(dir, "bridge.ADD.stdin"))
+	if err := json.Unmarshal(data, &bridgeConf); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if _, ok := bridgeConf["runtimeConfig"]; ok {
+		t.Fatalf("bridge got runtime config: %v", bridgeConf)
+	}
+	if _, ok := bridgeConf["prevResult"]; ok {
+		t.Fatalf("bridge got a previous result: %v", bridgeConf)
+	}
+	if bridgeConf["name"] != "test" || bridgeConf["cniVersion"] != Version {
+		t.Fatalf("bad bridge config: %v", bridgeConf)
+	}
+
+	var portmapConf map[string]interface{}
+	data, _ = ioutil.ReadFile(filepath.Join(dir, "portmap.ADD.stdin"))
+	if err := json.Unmarshal(data, &portmapConf); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if _, ok := portmapConf["prevResult"]; !ok {
+		t.Fatalf("portmap missing previous result: %v", portmapConf)
+	}
+	mappings := portmapConf["runtimeConfig"].(map[string]interface{})["portMappings"]
+	expected := []interface{}{
+		map[string]interface{}{"hostPort": 20000.0, "containerPort": 8080.0, "protocol": "tcp"},
+	}
+	if !reflect.DeepEqual(mappings, expected) {
+		t.Fatalf("bad port mappings: %#v", mappings)
+	}
+
+	if err := c.DelNetworkList(list, rt); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	calls, _ := ioutil.ReadFile(filepath.Join(dir, "calls"))
+	expectedCalls := []string{
+		"ADD alloc /var/run/netns/alloc eth0",
+		"ADD alloc /var/run/netns/alloc eth0",
+		"DEL alloc /var/run/netns/alloc eth0",
+		"DEL alloc /var/run/netns/alloc eth0",
+	}
+	if got := strings.Split(strings.TrimSpace(string(calls)), "\n"); !reflect.DeepEqual(got, expectedCalls) {
+		t.Fatalf("bad calls: %v", got)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "portmap.DEL.stdin")); err != nil {
+		t.Fatalf("portmap not deleted: %v", err)
+	}
+}
+
+func TestCNI_PluginError(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test requires a shell")
+	}
+
+	dir, err := ioutil.TempDir("", "cni")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	fakePlugin(t, dir, "bridge", `{"code":11,"msg":"no addresses left"}`, 1)
+
+	list := &NetworkConfigList{
+		CNIVersion: Version,
+		Name:       "test",
+		Plugins:    []map[string]interface{}{{"type": "bridge"}},
+	}
+	c := New([]string{dir})
+	_, err = c.AddNetworkList(list, &RuntimeConf{ContainerID: "alloc"})
+	if err == nil || !strings.Contains(err.Error(), "no addresses left") {
+		t.Fatalf("expected plugin error; got %v", err)
+	}
+
+	list.Plugins = []map[string]interface{}{{"type": "macvlan"}}
+	if _, err := c.AddNetworkList(list, &RuntimeConf{ContainerID: "alloc"}); err == nil {
+		t.Fatalf("expected missing plugin error")
+	}
+}
@@ -0,0 +1,78 @@
+package cni
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+)
+
+// configExtensions are the extensions of the files loaded from a CNI config
+// directory. Files ending in .conflist hold a list of plugins while the
+// others hold the configuration of a single plugin.
+var configExtensions = map[string]bool{
+	".conflist": true,
+	".conf":     true,
+	".json":     true,
+}
+
+// LoadConfigList returns the network configuration with the given name from
+// the directory. Files are read in lexical order and the first configuration
+// with a matching name is returned.
+func LoadConfigList(dir, name string) (*NetworkConfigList, error) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CNI config directory %q: %v", dir, err)
+	}
+
+	var paths []string
+	for _, f := range files {
+		if !f.IsDir() && configExtensions[filepath.Ext(f.Name())] {
+			paths = append(paths, filepath.Join(dir, f.Name()))
+		}
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		list, err := readConfigList(path)
+		if err != nil {
+			return nil, err
+		}
+		if list.Name == name {
+			return list, nil
+		}
+	}
+	return nil, fmt.Errorf("CNI network %q not found in %q", name, dir)
+}
+
+// readConfigList parses a configuration file. A single plugin configuration
+// is converted into a list holding only that plugin.
+func readConfigList(path string) (*NetworkConfigList, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CNI config %q: %v", path, err)
+	}
+
+	if filepath.Ext(path) == ".conflist" {
+		var list NetworkConfigList
+		if err := json.Unmarshal(data, &list); err != nil {
+			return nil, fmt.Errorf("failed to parse CNI config %q: %v", path, err)
+		}
+		if len(list.Plugins) == 0 {
+			return nil, fmt.Errorf("CNI config %q has no plugins", path)
+		}
+		return &list, nil
+	}
+
+	var plugin map[string]interface{}
+	if err := json.Unmarshal(data, &plugin); err != nil {
+		return nil, fmt.Errorf("failed to parse CNI config %q: %v", path, err)
+	}
+	list := &NetworkConfigList{Plugins: []map[string]interface{}{plugin}}
+	list.Name, _ = plugin["name"].(string)
+	list.CNIVersion, _ = plugin["cniVersion"].(string)
+	delete(plugin, "name")
+	delete(plugin, "cniVersion")
+	return list, nil
+}
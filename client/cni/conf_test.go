@@ -0,0 +1,86 @@
+package cni
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadConfigList(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cni")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"10-macvlan.conflist": `{"cniVersion":"0.4.0","name":"macvlan","plugins":[{"type":"macvlan","master":"eth1"},{"type":"portmap","capabilities":{"portMappings":true}}]}`,
+		"20-calico.conf":      `{"cniVersion":"0.3.1","name":"calico","type":"calico","etcd_endpoints":"http://127.0.0.1:2379"}`,
+		"30-other.conflist":   `{"cniVersion":"0.4.0","name":"macvlan","plugins":[{"type":"bridge"}]}`,
+		"README":              `not a config`,
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+
+	// The first file in lexical order wins
+	list, err := LoadConfigList(dir, "macvlan")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if list.CNIVersion != "0.4.0" || len(list.Plugins) != 2 || list.Plugins[0]["type"] != "macvlan" {
+		t.Fatalf("bad list: %#v", list)
+	}
+
+	// Single plugin configurations are converted to a list
+	list, err = LoadConfigList(dir, "calico")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	expected := &NetworkConfigList{
+		CNIVersion: "0.3.1",
+		Name:       "calico",
+		Plugins: []map[string]interface{}{
+			{"type": "calico", "etcd_endpoints": "http://127.0.0.1:2379"},
+		},
+	}
+	if !reflect.DeepEqual(list, expected) {
+		t.Fatalf("bad list: %#v", list)
+	}
+
+	if _, err := LoadConfigList(dir, "weave"); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Fatalf("expected not found error; got %v", err)
+	}
+}
+
+func TestResult_Address(t *testing.T) {
+	host, sandbox := 0, 1
+	res := &Result{
+		Interfaces: []*Interface{
+			{Name: "cni0"},
+			{Name: "eth0", Sandbox: "/var/run/netns/alloc"},
+		},
+		IPs: []*IPConfig{
+			{Version: "4", Interface: &host, Address: "172.26.64.1/20"},
+			{Version: "4", Interface: &sandbox, Address: "172.26.64.2/20"},
+		},
+	}
+	if addr := res.Address("eth0"); addr != "172.26.64.2" {
+		t.Fatalf("bad address: %q", addr)
+	}
+
+	// Plugins may not report the interface of an address
+	res = &Result{IPs: []*IPConfig{{Version: "6", Address: "fd00::2/64"}}}
+	if addr := res.Address("eth0"); addr != "fd00::2" {
+		t.Fatalf("bad address: %q", addr)
+	}
+
+	if addr := (&Result{}).Address("eth0"); addr != "" {
+		t.Fatalf("bad address: %q", addr)
+	}
+}
@@ -87,6 +87,22 @@ type Config struct {
 	// be determined dynamically.
 	NetworkSpeed int
 
+	// CNIPath is the list of directories, separated like PATH, in which the
+	// CNI plugins used for allocation networking are searched for.
+	CNIPath string
+
+	// CNIConfigDir is the directory containing the CNI network configuration
+	// lists that allocations can select with the "cni/<name>" network mode.
+	CNIConfigDir string
+
+	// BridgeNetworkName is the name of the bridge that allocations using
+	// bridge networking are attached to.
+	BridgeNetworkName string
+
+	// BridgeNetworkAllocSubnet is the subnet from which allocations using
+	// bridge networking are assigned addresses.
+	BridgeNetworkAllocSubnet string
+
 	// CpuCompute is the default total CPU compute if they can not be determined
 	// dynamically. It should be given as Cores * MHz (2 Cores * 2 Ghz = 4000)
 	CpuCompute int
@@ -177,6 +193,9 @@ type Config struct {
 	// NoHostUUID disables using the host's UUID and will force generation of a
 	// random UUID.
 	NoHostUUID bool
+
+	// Artifact configures the caching and verification of task artifacts
+	Artifact *config.ArtifactConfig
 }
 
 func (c *Config) Copy() *Config {
@@ -188,23 +207,29 @@ func (c *Config) Copy() *Config {
 	nc.GloballyReservedPorts = helper.CopySliceInt(c.GloballyReservedPorts)
 	nc.ConsulConfig = c.ConsulConfig.Copy()
 	nc.VaultConfig = c.VaultConfig.Copy()
+	nc.Artifact = c.Artifact.Copy()
 	return nc
 }
 
 // DefaultConfig returns the default configuration
 func DefaultConfig() *Config {
 	return &Config{
-		VaultConfig:             config.DefaultVaultConfig(),
-		ConsulConfig:            config.DefaultConsulConfig(),
-		LogOutput:               os.Stderr,
-		Region:                  "global",
-		StatsCollectionInterval: 1 * time.Second,
-		TLSConfig:               &config.TLSConfig{},
-		LogLevel:                "DEBUG",
-		GCInterval:              1 * time.Minute,
-		GCParallelDestroys:      2,
-		GCDiskUsageThreshold:    80,
-		GCInodeUsageThreshold:   70,
+		VaultConfig:              config.DefaultVaultConfig(),
+		ConsulConfig:             config.DefaultConsulConfig(),
+		Artifact:                 config.DefaultArtifactConfig(),
+		LogOutput:                os.Stderr,
+		Region:                   "global",
+		StatsCollectionInterval:  1 * time.Second,
+		TLSConfig:                &config.TLSConfig{},
+		LogLevel:                 "DEBUG",
+		GCInterval:               1 * time.Minute,
+		GCParallelDestroys:       2,
+		GCDiskUsageThreshold:     80,
+		GCInodeUsageThreshold:    70,
+		CNIPath:                  "/opt/cni/bin",
+		CNIConfigDir:             "/opt/cni/config",
+		BridgeNetworkName:        "nomad",
+		BridgeNetworkAllocSubnet: "172.26.64.0/20",
 	}
 }
 
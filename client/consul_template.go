@@ -1,7 +1,9 @@
 package client
 
 import (
+	"bufio"
 	"fmt"
+	"io"
 	"math/rand"
 	"os"
 	"path/filepath"
@@ -46,6 +48,11 @@ type TaskHooks interface {
 	// Kill is used to kill the task because of the passed error. If fail is set
 	// to true, the task is marked as failed
 	Kill(source, reason string, fail bool)
+
+	// SetTemplateEnv is used to set the environment variables rendered by
+	// templates with env enabled. They take effect the next time the task is
+	// started.
+	SetTemplateEnv(env map[string]string)
 }
 
 // TaskTemplateManager is used to run a set of templates for a given task
@@ -66,6 +73,10 @@ type TaskTemplateManager struct {
 	// actual signal
 	signals map[string]os.Signal
 
+	// taskDir and taskEnv are used to find the rendered templates
+	taskDir string
+	taskEnv *env.TaskEnvironment
+
 	// shutdownCh is used to signal and started goroutine to shutdown
 	shutdownCh chan struct{}
 
@@ -76,7 +87,7 @@ type TaskTemplateManager struct {
 
 func NewTaskTemplateManager(hook TaskHooks, tmpls []*structs.Template,
 	config *config.Config, vaultToken, taskDir string,
-	taskEnv *env.TaskEnvironment) (*TaskTemplateManager, error) {
+	taskEnv *env.TaskEnvironment, nomadFuncs *nomadTemplateFuncs) (*TaskTemplateManager, error) {
 
 	// Check pre-conditions
 	if hook == nil {
@@ -92,6 +103,8 @@ func NewTaskTemplateManager(hook TaskHooks, tmpls []*structs.Template,
 	tm := &TaskTemplateManager{
 		templates:  tmpls,
 		hook:       hook,
+		taskDir:    taskDir,
+		taskEnv:    taskEnv,
 		shutdownCh: make(chan struct{}),
 	}
 
@@ -114,7 +127,7 @@ func NewTaskTemplateManager(hook TaskHooks, tmpls []*structs.Template,
 	}
 
 	// Build the consul-template runner
-	runner, lookup, err := templateRunner(tmpls, config, vaultToken, taskDir, taskEnv)
+	runner, lookup, err := templateRunner(tmpls, config, vaultToken, taskDir, taskEnv, nomadFuncs)
 	if err != nil {
 		return nil, err
 	}
@@ -192,6 +205,12 @@ WAIT:
 		}
 	}
 
+	// Read the environment variables before the task is started
+	if err := tm.loadTemplateEnv(); err != nil {
+		tm.hook.Kill("consul-template", err.Error(), true)
+		return
+	}
+
 	allRenderedTime = time.Now()
 	tm.hook.UnblockStart("consul-template")
 
@@ -219,6 +238,7 @@ WAIT:
 			var handling []string
 			signals := make(map[string]struct{})
 			restart := false
+			envChanged := false
 			var splay time.Duration
 
 			events := tm.runner.RenderEvents()
@@ -243,6 +263,10 @@ WAIT:
 				}
 
 				for _, tmpl := range tmpls {
+					if tmpl.Envvars {
+						envChanged = true
+					}
+
 					switch tmpl.ChangeMode {
 					case structs.TemplateChangeModeSignal:
 						signals[tmpl.ChangeSignal] = struct{}{}
@@ -260,6 +284,14 @@ WAIT:
 				handling = append(handling, id)
 			}
 
+			// Update the environment variables so a restart picks them up
+			if envChanged {
+				if err := tm.loadTemplateEnv(); err != nil {
+					tm.hook.Kill("consul-template", err.Error(), true)
+					return
+				}
+			}
+
 			if restart || len(signals) != 0 {
 				if splay != 0 {
 					ns := splay.Nanoseconds()
@@ -302,6 +334,71 @@ WAIT:
 	}
 }
 
+// loadTemplateEnv reads the environment variables rendered by the templates
+// with env enabled and sets them on the task.
+func (tm *TaskTemplateManager) loadTemplateEnv() error {
+	all := make(map[string]string)
+	found := false
+	for _, tmpl := range tm.templates {
+		if !tmpl.Envvars {
+			continue
+		}
+		found = true
+
+		dest := filepath.Join(tm.taskDir, tm.taskEnv.ReplaceEnv(tmpl.DestPath))
+		f, err := os.Open(dest)
+		if err != nil {
+			return fmt.Errorf("failed to open env template %q: %v", tmpl.DestPath, err)
+		}
+		vars, err := parseEnvFile(f)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("failed to parse env template %q: %v", tmpl.DestPath, err)
+		}
+
+		for k, v := range vars {
+			all[k] = v
+		}
+	}
+
+	if found {
+		tm.hook.SetTemplateEnv(all)
+	}
+	return nil
+}
+
+// parseEnvFile parses the KEY=VALUE lines of a rendered env template. Lines
+// are split on the first "=". Empty lines and lines starting with # are
+// ignored, but to avoid escaping issues #s within lines are not treated as
+// comments.
+func parseEnvFile(r io.Reader) (map[string]string, error) {
+	vars := make(map[string]string)
+	lines := 0
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		lines++
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		idx := strings.Index(line, "=")
+		if idx == -1 {
+			return nil, fmt.Errorf("line %d: missing '=' in %q", lines, line)
+		}
+		key := strings.TrimSpace(line[:idx])
+		if key == "" {
+			return nil, fmt.Errorf("line %d: missing variable name", lines)
+		}
+		vars[key] = line[idx+1:]
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return vars, nil
+}
+
 // allTemplatesNoop returns whether all the managed templates have change mode noop.
 func (tm *TaskTemplateManager) allTemplatesNoop() bool {
 	for _, tmpl := range tm.templates {
@@ -315,9 +412,11 @@ func (tm *TaskTemplateManager) allTemplatesNoop() bool {
 
 // templateRunner returns a consul-template runner for the given templates and a
 // lookup by destination to the template. If no templates are given, a nil
-// template runner and lookup is returned.
+// template runner and lookup is returned. The Nomad template functions are
+// made available to the templates if given.
 func templateRunner(tmpls []*structs.Template, config *config.Config,
-	vaultToken, taskDir string, taskEnv *env.TaskEnvironment) (
+	vaultToken, taskDir string, taskEnv *env.TaskEnvironment,
+	nomadFuncs *nomadTemplateFuncs) (
 	*manager.Runner, map[string][]*structs.Template, error) {
 
 	if len(tmpls) == 0 {
@@ -352,6 +451,11 @@ func templateRunner(tmpls []*structs.Template, config *config.Config,
 	// Set Nomad's environment variables
 	runner.Env = taskEnv.Build().EnvMapAll()
 
+	// Set the template functions that query Nomad
+	if nomadFuncs != nil {
+		runner.Funcs = nomadFuncs.FuncMap
+	}
+
 	// Build the lookup
 	idMap := runner.TemplateConfigMapping()
 	lookup := make(map[string][]*structs.Template, len(idMap))
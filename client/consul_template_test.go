@@ -6,6 +6,7 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
@@ -41,6 +42,9 @@ type MockTaskHooks struct {
 
 	KillReason string
 	KillCh     chan struct{}
+
+	// Env is the environment variables of the templates with env enabled
+	Env map[string]string
 }
 
 func NewMockTaskHooks() *MockTaskHooks {
@@ -77,6 +81,10 @@ func (m *MockTaskHooks) Kill(source, reason string, fail bool) {
 	}
 }
 
+func (m *MockTaskHooks) SetTemplateEnv(env map[string]string) {
+	m.Env = env
+}
+
 func (m *MockTaskHooks) UnblockStart(source string) {
 	if !m.Unblocked {
 		close(m.UnblockCh)
@@ -98,6 +106,7 @@ type testHarness struct {
 	taskDir    string
 	vault      *testutil.TestVault
 	consul     *ctestutil.TestServer
+	nomadFuncs *nomadTemplateFuncs
 }
 
 // newTestHarness returns a harness starting a dev consul and vault server,
@@ -138,7 +147,7 @@ func newTestHarness(t *testing.T, templates []*structs.Template, consul, vault b
 
 func (h *testHarness) start(t *testing.T) {
 	manager, err := NewTaskTemplateManager(h.mockHooks, h.templates,
-		h.config, h.vaultToken, h.taskDir, h.taskEnv)
+		h.config, h.vaultToken, h.taskDir, h.taskEnv, h.nomadFuncs)
 	if err != nil {
 		t.Fatalf("failed to build task template manager: %v", err)
 	}
@@ -148,7 +157,7 @@ func (h *testHarness) start(t *testing.T) {
 
 func (h *testHarness) startWithErr() error {
 	manager, err := NewTaskTemplateManager(h.mockHooks, h.templates,
-		h.config, h.vaultToken, h.taskDir, h.taskEnv)
+		h.config, h.vaultToken, h.taskDir, h.taskEnv, h.nomadFuncs)
 	h.manager = manager
 	return err
 }
@@ -177,32 +186,32 @@ func TestTaskTemplateManager_Invalid(t *testing.T) {
 	vaultToken := ""
 	taskEnv := env.NewTaskEnvironment(mock.Node())
 
-	_, err := NewTaskTemplateManager(nil, nil, nil, "", "", nil)
+	_, err := NewTaskTemplateManager(nil, nil, nil, "", "", nil, nil)
 	if err == nil {
 		t.Fatalf("Expected error")
 	}
 
-	_, err = NewTaskTemplateManager(nil, tmpls, config, vaultToken, taskDir, taskEnv)
+	_, err = NewTaskTemplateManager(nil, tmpls, config, vaultToken, taskDir, taskEnv, nil)
 	if err == nil || !strings.Contains(err.Error(), "task hook") {
 		t.Fatalf("Expected invalid task hook error: %v", err)
 	}
 
-	_, err = NewTaskTemplateManager(hooks, tmpls, nil, vaultToken, taskDir, taskEnv)
+	_, err = NewTaskTemplateManager(hooks, tmpls, nil, vaultToken, taskDir, taskEnv, nil)
 	if err == nil || !strings.Contains(err.Error(), "config") {
 		t.Fatalf("Expected invalid config error: %v", err)
 	}
 
-	_, err = NewTaskTemplateManager(hooks, tmpls, config, vaultToken, "", taskEnv)
+	_, err = NewTaskTemplateManager(hooks, tmpls, config, vaultToken, "", taskEnv, nil)
 	if err == nil || !strings.Contains(err.Error(), "task directory") {
 		t.Fatalf("Expected invalid task dir error: %v", err)
 	}
 
-	_, err = NewTaskTemplateManager(hooks, tmpls, config, vaultToken, taskDir, nil)
+	_, err = NewTaskTemplateManager(hooks, tmpls, config, vaultToken, taskDir, nil, nil)
 	if err == nil || !strings.Contains(err.Error(), "task environment") {
 		t.Fatalf("Expected invalid task environment error: %v", err)
 	}
 
-	tm, err := NewTaskTemplateManager(hooks, tmpls, config, vaultToken, taskDir, taskEnv)
+	tm, err := NewTaskTemplateManager(hooks, tmpls, config, vaultToken, taskDir, taskEnv, nil)
 	if err != nil {
 		t.Fatalf("Unexpected error: %v", err)
 	} else if tm == nil {
@@ -218,7 +227,7 @@ func TestTaskTemplateManager_Invalid(t *testing.T) {
 	}
 
 	tmpls = append(tmpls, tmpl)
-	tm, err = NewTaskTemplateManager(hooks, tmpls, config, vaultToken, taskDir, taskEnv)
+	tm, err = NewTaskTemplateManager(hooks, tmpls, config, vaultToken, taskDir, taskEnv, nil)
 	if err == nil || !strings.Contains(err.Error(), "Failed to parse signal") {
 		t.Fatalf("Expected signal parsing error: %v", err)
 	}
@@ -902,3 +911,149 @@ func TestTaskTemplateManager_Signal_Error(t *testing.T) {
 		t.Fatalf("Unexpected error: %v", harness.mockHooks.KillReason)
 	}
 }
+
+func TestTaskTemplateManager_Env(t *testing.T) {
+	// Make a template that renders environment variables
+	template := &structs.Template{
+		EmbeddedTmpl: `
+# Comments and empty lines are ignored
+FOO=bar
+QUERY=a=b#c
+TASK={{env "NOMAD_TASK_NAME"}}
+`,
+		DestPath:   "local/test.env",
+		ChangeMode: structs.TemplateChangeModeNoop,
+		Envvars:    true,
+	}
+
+	harness := newTestHarness(t, []*structs.Template{template}, false, false)
+	harness.start(t)
+	defer harness.stop()
+
+	// Wait for the unblock
+	select {
+	case <-harness.mockHooks.UnblockCh:
+	case <-time.After(time.Duration(5*testutil.TestMultiplier()) * time.Second):
+		t.Fatalf("Task unblock should have been called")
+	}
+
+	expected := map[string]string{
+		"FOO":   "bar",
+		"QUERY": "a=b#c",
+		"TASK":  TestTaskName,
+	}
+	if !reflect.DeepEqual(harness.mockHooks.Env, expected) {
+		t.Fatalf("Unexpected environment variables; got %v, want %v", harness.mockHooks.Env, expected)
+	}
+}
+
+func TestTaskTemplateManager_Env_Invalid(t *testing.T) {
+	// Make a template that renders an invalid environment variable
+	template := &structs.Template{
+		EmbeddedTmpl: "FOO",
+		DestPath:     "local/test.env",
+		ChangeMode:   structs.TemplateChangeModeNoop,
+		Envvars:      true,
+	}
+
+	harness := newTestHarness(t, []*structs.Template{template}, false, false)
+	harness.start(t)
+	defer harness.stop()
+
+	// Wait for the kill
+	select {
+	case <-harness.mockHooks.KillCh:
+		if !strings.Contains(harness.mockHooks.KillReason, "missing '='") {
+			t.Fatalf("Unexpected kill reason: %q", harness.mockHooks.KillReason)
+		}
+	case <-time.After(time.Duration(5*testutil.TestMultiplier()) * time.Second):
+		t.Fatalf("Task should have been killed")
+	}
+
+	if harness.mockHooks.Unblocked {
+		t.Fatalf("Task unblock should not have been called")
+	}
+}
+
+func TestTaskTemplateManager_Env_Rerender(t *testing.T) {
+	job := mock.Job()
+	job.Meta = map[string]string{"version": "1"}
+	rpc := newMockNomadRPC(job)
+
+	// Make an env template that restarts the task when the job meta changes
+	template := &structs.Template{
+		EmbeddedTmpl: `VERSION={{nomadVar "version"}}`,
+		DestPath:     "local/test.env",
+		ChangeMode:   structs.TemplateChangeModeRestart,
+		Envvars:      true,
+	}
+
+	harness := newTestHarness(t, []*structs.Template{template}, false, false)
+	harness.nomadFuncs = newNomadTemplateFuncs(rpc, "global", job.ID)
+	harness.start(t)
+	defer harness.stop()
+
+	select {
+	case <-harness.mockHooks.UnblockCh:
+	case <-time.After(time.Duration(5*testutil.TestMultiplier()) * time.Second):
+		t.Fatalf("Task unblock should have been called")
+	}
+	if v := harness.mockHooks.Env["VERSION"]; v != "1" {
+		t.Fatalf("Unexpected VERSION %q", v)
+	}
+
+	rpc.update(func() {
+		rpc.job.Meta["version"] = "2"
+	})
+
+	// The environment must be updated by the time of the restart
+	select {
+	case <-harness.mockHooks.RestartCh:
+	case <-time.After(time.Duration(5*testutil.TestMultiplier()) * time.Second):
+		t.Fatalf("Should have received a restart: %+v", harness.mockHooks)
+	}
+	if v := harness.mockHooks.Env["VERSION"]; v != "2" {
+		t.Fatalf("Unexpected VERSION %q", v)
+	}
+}
+
+func TestParseEnvFile(t *testing.T) {
+	cases := []struct {
+		Input    string
+		Expected map[string]string
+		Err      bool
+	}{
+		{
+			Input:    "",
+			Expected: map[string]string{},
+		},
+		{
+			Input:    "A=1\n\n#B=2\nC= spaced value \nD=\n",
+			Expected: map[string]string{"A": "1", "C": " spaced value ", "D": ""},
+		},
+		{
+			Input: "A=1\nB\n",
+			Err:   true,
+		},
+		{
+			Input: "=1\n",
+			Err:   true,
+		},
+	}
+
+	for i, c := range cases {
+		act, err := parseEnvFile(strings.NewReader(c.Input))
+		if c.Err {
+			if err == nil {
+				t.Fatalf("case %d: expected error", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("case %d: err: %v", i, err)
+		}
+		if !reflect.DeepEqual(act, c.Expected) {
+			t.Fatalf("case %d: got %v; want %v", i, act, c.Expected)
+		}
+	}
+}
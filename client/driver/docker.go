@@ -839,8 +839,27 @@ func (d *DockerDriver) createContainerConfig(ctx *ExecContext, task *structs.Tas
 		hostConfig.NetworkMode = defaultNetworkMode
 	}
 
+	// Join the network namespace shared by the allocation. Ports are
+	// forwarded into the namespace by the client so none are published.
+	sharedNetwork := ctx.NetworkIsolation != nil
+	if sharedNetwork {
+		id, ok := ctx.NetworkIsolation.Labels[dockerNetSandboxLabel]
+		if !ok {
+			return c, fmt.Errorf("allocation network namespace was not created by docker")
+		}
+		if driverConfig.NetworkMode != "" {
+			return c, fmt.Errorf("network_mode can't be set when the task group uses bridge networking")
+		}
+		hostConfig.NetworkMode = fmt.Sprintf("container:%s", id)
+		d.logger.Printf("[DEBUG] driver.docker: joining network namespace of container %s", id)
+	}
+
 	// Setup port mapping and exposed ports
-	if len(task.Resources.Networks) == 0 {
+	if sharedNetwork {
+		if len(driverConfig.PortMap) > 0 {
+			return c, fmt.Errorf("port_map can't be used when the task group uses bridge networking")
+		}
+	} else if len(task.Resources.Networks) == 0 {
 		d.logger.Println("[DEBUG] driver.docker: No network interfaces are available")
 		if len(driverConfig.PortMap) > 0 {
 			return c, fmt.Errorf("Trying to map ports but no network interface is available")
@@ -0,0 +1,103 @@
+package driver
+
+import (
+	"fmt"
+
+	docker "github.com/fsouza/go-dockerclient"
+
+	dstructs "github.com/hashicorp/nomad/client/driver/structs"
+)
+
+const (
+	// dockerInfraImageConfigOption is the key for setting the image of the
+	// container holding the network namespace of an allocation.
+	dockerInfraImageConfigOption  = "docker.network.infra_image"
+	dockerInfraImageConfigDefault = "gcr.io/google_containers/pause-amd64:3.0"
+
+	// dockerNetSandboxLabel is the NetworkIsolationSpec label holding the ID
+	// of the container that owns the network namespace.
+	dockerNetSandboxLabel = "docker_sandbox_container_id"
+)
+
+// CreateNetwork creates a container that holds the network namespace shared
+// by the tasks of the allocation. Docker tasks join it using the container
+// network mode while other tasks enter it through its path in /proc.
+func (d *DockerDriver) CreateNetwork(allocID string) (*dstructs.NetworkIsolationSpec, error) {
+	client, _, err := d.dockerClients()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to connect to docker daemon: %s", err)
+	}
+
+	image := d.config.ReadDefault(dockerInfraImageConfigOption, dockerInfraImageConfigDefault)
+	if _, err := client.InspectImage(image); err == docker.ErrNoSuchImage {
+		repo, tag := docker.ParseRepositoryTag(image)
+		if tag == "" {
+			tag = "latest"
+		}
+		opts := docker.PullImageOptions{Repository: repo, Tag: tag}
+		if err := client.PullImage(opts, docker.AuthConfiguration{}); err != nil {
+			return nil, fmt.Errorf("failed to pull infra image %q: %v", image, err)
+		}
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to inspect infra image %q: %v", image, err)
+	}
+
+	container, err := client.CreateContainer(docker.CreateContainerOptions{
+		Name: fmt.Sprintf("nomad_init_%s", allocID),
+		Config: &docker.Config{
+			Image: image,
+		},
+		HostConfig: &docker.HostConfig{
+			NetworkMode: "none",
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create infra container: %v", err)
+	}
+
+	if err := client.StartContainer(container.ID, nil); err != nil {
+		d.removeInfraContainer(client, container.ID)
+		return nil, fmt.Errorf("failed to start infra container: %v", err)
+	}
+
+	c, err := client.InspectContainer(container.ID)
+	if err != nil {
+		d.removeInfraContainer(client, container.ID)
+		return nil, fmt.Errorf("failed to inspect infra container: %v", err)
+	}
+
+	d.logger.Printf("[DEBUG] driver.docker: created infra container %s for alloc %q", container.ID, allocID)
+	return &dstructs.NetworkIsolationSpec{
+		Path: fmt.Sprintf("/proc/%d/ns/net", c.State.Pid),
+		Labels: map[string]string{
+			dockerNetSandboxLabel: container.ID,
+		},
+	}, nil
+}
+
+// DestroyNetwork removes the container created by CreateNetwork.
+func (d *DockerDriver) DestroyNetwork(allocID string, spec *dstructs.NetworkIsolationSpec) error {
+	id := spec.Labels[dockerNetSandboxLabel]
+	if id == "" {
+		return fmt.Errorf("network of alloc %q is not owned by a docker container", allocID)
+	}
+
+	client, _, err := d.dockerClients()
+	if err != nil {
+		return fmt.Errorf("Failed to connect to docker daemon: %s", err)
+	}
+	return d.removeInfraContainer(client, id)
+}
+
+// removeInfraContainer force removes the container holding a network
+// namespace.
+func (d *DockerDriver) removeInfraContainer(client *docker.Client, id string) error {
+	err := client.RemoveContainer(docker.RemoveContainerOptions{
+		ID:    id,
+		Force: true,
+	})
+	if _, ok := err.(*docker.NoSuchContainer); ok {
+		return nil
+	}
+	return err
+}
@@ -27,6 +27,8 @@ var (
 		"java":     NewJavaDriver,
 		"qemu":     NewQemuDriver,
 		"rkt":      NewRktDriver,
+		"podman":   NewPodmanDriver,
+		"wasm":     NewWasmDriver,
 	}
 
 	// DriverStatsNotImplemented is the error to be returned if a driver doesn't
@@ -187,6 +189,17 @@ type Driver interface {
 	FSIsolation() cstructs.FSIsolation
 }
 
+// DriverNetworkManager is implemented by drivers that need to create the
+// network namespace shared by the tasks of an allocation themselves, such as
+// when the namespace has to be owned by a container.
+type DriverNetworkManager interface {
+	// CreateNetwork creates the network namespace for the allocation.
+	CreateNetwork(allocID string) (*dstructs.NetworkIsolationSpec, error)
+
+	// DestroyNetwork destroys a network namespace created by CreateNetwork.
+	DestroyNetwork(allocID string, spec *dstructs.NetworkIsolationSpec) error
+}
+
 // DriverAbilities marks the abilities the driver has.
 type DriverAbilities struct {
 	// SendSignals marks the driver as being able to send signals
@@ -260,6 +273,19 @@ type DriverHandle interface {
 type ExecContext struct {
 	// TaskDir contains information about the task directory structure.
 	TaskDir *allocdir.TaskDir
+
+	// NetworkIsolation is the network namespace shared by the tasks of the
+	// allocation. It is nil when the tasks use the host's network.
+	NetworkIsolation *dstructs.NetworkIsolationSpec
+}
+
+// NetworkNamespace returns the path to the network namespace the task should
+// be started in or an empty string if the host network should be used.
+func (ctx *ExecContext) NetworkNamespace() string {
+	if ctx.NetworkIsolation == nil {
+		return ""
+	}
+	return ctx.NetworkIsolation.Path
 }
 
 // NewExecContext is used to create a new execution context
@@ -307,6 +333,16 @@ func GetTaskEnv(taskDir *allocdir.TaskDir, node *structs.Node,
 
 	if alloc != nil {
 		env.SetAlloc(alloc)
+
+		// Ports of a bridge or CNI network are exposed to the task on the
+		// port they are mapped to inside the network namespace
+		if alloc.SharedResources != nil {
+			for _, network := range alloc.SharedResources.Networks {
+				if network.Isolated() {
+					env.SetPortMap(network.PortMap())
+				}
+			}
+		}
 	}
 
 	if task.Vault != nil {
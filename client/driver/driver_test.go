@@ -297,6 +297,45 @@ func TestDriver_GetTaskEnv_Image(t *testing.T) {
 	}
 }
 
+// TestDriver_GetTaskEnv_BridgeNetwork ensures ports of a bridge network are
+// exposed on the port they are mapped to inside the namespace.
+func TestDriver_GetTaskEnv_BridgeNetwork(t *testing.T) {
+	network := &structs.NetworkResource{
+		Mode:          structs.NetworkModeBridge,
+		IP:            "1.2.3.4",
+		MBits:         10,
+		ReservedPorts: []structs.Port{{Label: "db", Value: 6379}},
+		DynamicPorts:  []structs.Port{{Label: "http", Value: 23456, To: 8080}},
+	}
+
+	alloc := mock.Alloc()
+	alloc.SharedResources.Networks = []*structs.NetworkResource{network}
+	task := alloc.Job.TaskGroups[0].Tasks[0]
+	task.Resources = task.Resources.Copy()
+	task.Resources.Networks = append(task.Resources.Networks, network)
+
+	conf := testConfig()
+	allocDir := allocdir.NewAllocDir(testLogger(), filepath.Join(conf.AllocDir, alloc.ID))
+	taskDir := allocDir.NewTaskDir(task.Name)
+	env, err := GetTaskEnv(taskDir, nil, task, alloc, conf, "")
+	if err != nil {
+		t.Fatalf("GetTaskEnv() failed: %v", err)
+	}
+
+	exp := map[string]string{
+		"NOMAD_PORT_http":      "8080",
+		"NOMAD_HOST_PORT_http": "23456",
+		"NOMAD_PORT_db":        "6379",
+		"NOMAD_HOST_PORT_db":   "6379",
+	}
+	act := env.EnvMap()
+	for k, v := range exp {
+		if act[k] != v {
+			t.Errorf("Expected %s=%q but found %q", k, v, act[k])
+		}
+	}
+}
+
 func TestMapMergeStrInt(t *testing.T) {
 	a := map[string]int{
 		"cakes":   5,
@@ -66,8 +66,16 @@ const (
 	// MetaPrefix is the prefix for passing task meta data.
 	MetaPrefix = "NOMAD_META_"
 
+	// UpstreamPrefix is the prefix for passing the local address of the
+	// Connect upstreams of the task group.
+	UpstreamPrefix = "NOMAD_UPSTREAM_ADDR_"
+
 	// VaultToken is the environment variable for passing the Vault token
 	VaultToken = "VAULT_TOKEN"
+
+	// WorkloadToken is the environment variable for passing the signed
+	// workload identity of the task
+	WorkloadToken = "NOMAD_TOKEN"
 )
 
 // The node values that can be interpreted.
@@ -85,24 +93,30 @@ const (
 // TaskEnvironment is used to expose information to a task via environment
 // variables and provide interpolation of Nomad variables.
 type TaskEnvironment struct {
-	Env              map[string]string
-	TaskMeta         map[string]string
-	AllocDir         string
-	TaskDir          string
-	SecretsDir       string
-	CpuLimit         int
-	MemLimit         int
-	TaskName         string
-	AllocIndex       int
-	AllocId          string
-	AllocName        string
-	Node             *structs.Node
-	Networks         []*structs.NetworkResource
-	PortMap          map[string]int
-	VaultToken       string
-	InjectVaultToken bool
-	JobName          string
-	Alloc            *structs.Allocation
+	Env                 map[string]string
+	TaskMeta            map[string]string
+	AllocDir            string
+	TaskDir             string
+	SecretsDir          string
+	CpuLimit            int
+	MemLimit            int
+	TaskName            string
+	AllocIndex          int
+	AllocId             string
+	AllocName           string
+	Node                *structs.Node
+	Networks            []*structs.NetworkResource
+	PortMap             map[string]int
+	VaultToken          string
+	InjectVaultToken    bool
+	WorkloadToken       string
+	InjectWorkloadToken bool
+	JobName             string
+	Alloc               *structs.Allocation
+
+	// TemplateEnv is the variables rendered by the task's templates. They
+	// override the task's environment variables.
+	TemplateEnv map[string]string
 
 	// taskEnv is the variables that will be set in the tasks environment
 	TaskEnv map[string]string
@@ -222,6 +236,23 @@ func (t *TaskEnvironment) Build() *TaskEnvironment {
 		}
 	}
 
+	// Build the addr of the Connect upstreams of the task group
+	if t.Alloc != nil && t.Alloc.Job != nil {
+		if tg := t.Alloc.Job.LookupTaskGroup(t.Alloc.TaskGroup); tg != nil {
+			for _, task := range tg.Tasks {
+				for _, service := range task.Services {
+					if !service.Connect.HasSidecar() || service.Connect.SidecarService.Proxy == nil {
+						continue
+					}
+					for _, upstream := range service.Connect.SidecarService.Proxy.Upstreams {
+						key := fmt.Sprintf("%s%s", UpstreamPrefix, upstream.DestinationName)
+						t.TaskEnv[key] = net.JoinHostPort("127.0.0.1", strconv.Itoa(upstream.LocalBindPort))
+					}
+				}
+			}
+		}
+	}
+
 	// Build the node
 	if t.Node != nil {
 		// Set up the node values.
@@ -246,6 +277,11 @@ func (t *TaskEnvironment) Build() *TaskEnvironment {
 		t.TaskEnv[VaultToken] = t.VaultToken
 	}
 
+	// Build the workload identity token
+	if t.InjectWorkloadToken && t.WorkloadToken != "" {
+		t.TaskEnv[WorkloadToken] = t.WorkloadToken
+	}
+
 	// Interpret the environment variables
 	interpreted := make(map[string]string, len(t.Env))
 	for k, v := range t.Env {
@@ -256,6 +292,12 @@ func (t *TaskEnvironment) Build() *TaskEnvironment {
 		t.TaskEnv[k] = v
 	}
 
+	// Copy the template environment variables last as they override the
+	// task's environment variables
+	for k, v := range t.TemplateEnv {
+		t.TaskEnv[k] = v
+	}
+
 	// Clean keys (see #2405)
 	cleanedEnv := make(map[string]string, len(t.TaskEnv))
 	for k, v := range t.TaskEnv {
@@ -440,6 +482,16 @@ func (t *TaskEnvironment) ClearEnvvars() *TaskEnvironment {
 }
 
 // Helper method for setting all fields from an allocation.
+func (t *TaskEnvironment) SetTemplateEnv(m map[string]string) *TaskEnvironment {
+	t.TemplateEnv = m
+	return t
+}
+
+func (t *TaskEnvironment) ClearTemplateEnv() *TaskEnvironment {
+	t.TemplateEnv = nil
+	return t
+}
+
 func (t *TaskEnvironment) SetAlloc(alloc *structs.Allocation) *TaskEnvironment {
 	t.AllocId = alloc.ID
 	t.AllocName = alloc.Name
@@ -514,3 +566,15 @@ func (t *TaskEnvironment) ClearVaultToken() *TaskEnvironment {
 	t.InjectVaultToken = false
 	return t
 }
+
+func (t *TaskEnvironment) SetWorkloadToken(token string, inject bool) *TaskEnvironment {
+	t.WorkloadToken = token
+	t.InjectWorkloadToken = inject
+	return t
+}
+
+func (t *TaskEnvironment) ClearWorkloadToken() *TaskEnvironment {
+	t.WorkloadToken = ""
+	t.InjectWorkloadToken = false
+	return t
+}
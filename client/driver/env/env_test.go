@@ -219,6 +219,43 @@ func TestEnvironment_VaultToken(t *testing.T) {
 	}
 }
 
+func TestEnvironment_WorkloadToken(t *testing.T) {
+	n := mock.Node()
+	env := NewTaskEnvironment(n).SetWorkloadToken("a.b.c", false).Build()
+
+	act := env.EnvList()
+	if len(act) != 0 {
+		t.Fatalf("Unexpected environment variables: %v", act)
+	}
+
+	env = env.SetWorkloadToken("a.b.c", true).Build()
+	act = env.EnvList()
+	exp := []string{"NOMAD_TOKEN=a.b.c"}
+	if !reflect.DeepEqual(act, exp) {
+		t.Fatalf("env.List() returned %v; want %v", act, exp)
+	}
+}
+
+func TestEnvironment_ConnectUpstreams(t *testing.T) {
+	n := mock.Node()
+	a := mock.Alloc()
+	a.Job.TaskGroups[0].Tasks[0].Services[0].Connect = &structs.ConsulConnect{
+		SidecarService: &structs.ConsulSidecarService{
+			Proxy: &structs.ConsulProxy{
+				Upstreams: []*structs.ConsulUpstream{
+					{DestinationName: "db", LocalBindPort: 9191},
+				},
+			},
+		},
+	}
+	env := NewTaskEnvironment(n).SetAlloc(a).SetTaskName("web").Build()
+
+	act := env.EnvMap()
+	if addr := act["NOMAD_UPSTREAM_ADDR_db"]; addr != "127.0.0.1:9191" {
+		t.Fatalf("bad upstream addr: %q", addr)
+	}
+}
+
 func TestEnvironment_ClearEnvvars(t *testing.T) {
 	n := mock.Node()
 	env := NewTaskEnvironment(n).
@@ -280,6 +317,31 @@ func TestEnvironment_Interpolate(t *testing.T) {
 	}
 }
 
+func TestEnvironment_TemplateEnv(t *testing.T) {
+	n := mock.Node()
+	env := NewTaskEnvironment(n).
+		SetEnvvars(map[string]string{"foo": "baz", "bar": "bang"}).
+		SetTemplateEnv(map[string]string{"foo": "secret", "DB_PASSWORD": "hunter2"}).
+		Build()
+
+	act := env.EnvList()
+	exp := []string{"DB_PASSWORD=hunter2", "bar=bang", "foo=secret"}
+	sort.Strings(act)
+	if !reflect.DeepEqual(act, exp) {
+		t.Fatalf("env.List() returned %v; want %v", act, exp)
+	}
+
+	// Clear the template environment variables
+	env.ClearTemplateEnv().Build()
+
+	act = env.EnvList()
+	exp = []string{"bar=bang", "foo=baz"}
+	sort.Strings(act)
+	if !reflect.DeepEqual(act, exp) {
+		t.Fatalf("env.List() returned %v; want %v", act, exp)
+	}
+}
+
 func TestEnvironment_AppendHostEnvvars(t *testing.T) {
 	host := os.Environ()
 	if len(host) < 2 {
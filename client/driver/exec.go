@@ -135,11 +135,12 @@ func (d *ExecDriver) Start(ctx *ExecContext, task *structs.Task) (DriverHandle,
 	}
 
 	execCmd := &executor.ExecCommand{
-		Cmd:            command,
-		Args:           driverConfig.Args,
-		FSIsolation:    true,
-		ResourceLimits: true,
-		User:           getExecutorUser(task),
+		Cmd:              command,
+		Args:             driverConfig.Args,
+		FSIsolation:      true,
+		ResourceLimits:   true,
+		User:             getExecutorUser(task),
+		NetworkNamespace: ctx.NetworkNamespace(),
 	}
 
 	ps, err := exec.LaunchCmd(execCmd)
@@ -2,6 +2,7 @@ package executor
 
 import (
 	"fmt"
+	"io"
 	"io/ioutil"
 	"log"
 	"net"
@@ -136,6 +137,10 @@ type ExecCommand struct {
 	// ResourceLimits determines whether resource limits are enforced by the
 	// executor.
 	ResourceLimits bool
+
+	// NetworkNamespace is the path to the network namespace the command is
+	// started in. The host's network is used if it is empty.
+	NetworkNamespace string
 }
 
 // ProcessState holds information about the state of a user process.
@@ -189,6 +194,10 @@ type UniversalExecutor struct {
 	lro         *logging.FileRotator
 	rotatorLock sync.Mutex
 
+	// logForwarder forwards the task's output to the sinks of its log
+	// config. It is nil if the task doesn't configure any sinks.
+	logForwarder *logging.LogForwarder
+
 	shutdownCh chan struct{}
 
 	syslogServer *logging.SyslogServer
@@ -277,6 +286,10 @@ func (e *UniversalExecutor) LaunchCmd(command *ExecCommand) (*ProcessState, erro
 	}
 	e.cmd.Stdout = e.lro
 	e.cmd.Stderr = e.lre
+	if e.logForwarder != nil {
+		e.cmd.Stdout = io.MultiWriter(e.lro, e.logForwarder.Writer(logging.StreamStdout))
+		e.cmd.Stderr = io.MultiWriter(e.lre, e.logForwarder.Writer(logging.StreamStderr))
+	}
 
 	// Look up the binary path and make it executable
 	absPath, err := e.lookupBin(e.ctx.TaskEnv.ReplaceEnv(command.Cmd))
@@ -305,7 +318,7 @@ func (e *UniversalExecutor) LaunchCmd(command *ExecCommand) (*ProcessState, erro
 	e.cmd.Env = e.ctx.TaskEnv.EnvList()
 
 	// Start the process
-	if err := e.cmd.Start(); err != nil {
+	if err := e.startCmd(); err != nil {
 		return nil, fmt.Errorf("failed to start command path=%q --- args=%q: %v", path, e.cmd.Args, err)
 	}
 	go e.collectPids()
@@ -326,6 +339,8 @@ func (e *UniversalExecutor) configureLoggers() error {
 		if err != nil {
 			return fmt.Errorf("error creating new stdout log file for %q: %v", e.ctx.Task.Name, err)
 		}
+		lro.Compress = e.ctx.Task.LogConfig.Compress
+		lro.MaxAge = e.ctx.Task.LogConfig.MaxAge
 		e.lro = lro
 	}
 
@@ -335,8 +350,28 @@ func (e *UniversalExecutor) configureLoggers() error {
 		if err != nil {
 			return fmt.Errorf("error creating new stderr log file for %q: %v", e.ctx.Task.Name, err)
 		}
+		lre.Compress = e.ctx.Task.LogConfig.Compress
+		lre.MaxAge = e.ctx.Task.LogConfig.MaxAge
 		e.lre = lre
 	}
+
+	if e.logForwarder == nil && len(e.ctx.Task.LogConfig.Sinks) != 0 {
+		tags := logging.SinkTags{
+			AllocID: e.ctx.AllocID,
+			Task:    e.ctx.Task.Name,
+		}
+		if e.ctx.TaskEnv != nil {
+			tags.Job = e.ctx.TaskEnv.JobName
+			if e.ctx.TaskEnv.Alloc != nil {
+				tags.Group = e.ctx.TaskEnv.Alloc.TaskGroup
+			}
+		}
+		forwarder, err := logging.NewLogForwarder(e.ctx.Task.LogConfig.Sinks, tags, e.logger)
+		if err != nil {
+			return fmt.Errorf("error creating log sinks for %q: %v", e.ctx.Task.Name, err)
+		}
+		e.logForwarder = forwarder
+	}
 	return nil
 }
 
@@ -355,12 +390,16 @@ func (e *UniversalExecutor) UpdateLogConfig(logConfig *structs.LogConfig) error
 	}
 	e.lro.MaxFiles = logConfig.MaxFiles
 	e.lro.FileSize = int64(logConfig.MaxFileSizeMB * 1024 * 1024)
+	e.lro.Compress = logConfig.Compress
+	e.lro.MaxAge = logConfig.MaxAge
 
 	if e.lre == nil {
 		return fmt.Errorf("log rotator for stderr doesn't exist")
 	}
 	e.lre.MaxFiles = logConfig.MaxFiles
 	e.lre.FileSize = int64(logConfig.MaxFileSizeMB * 1024 * 1024)
+	e.lre.Compress = logConfig.Compress
+	e.lre.MaxAge = logConfig.MaxAge
 	return nil
 }
 
@@ -373,8 +412,12 @@ func (e *UniversalExecutor) UpdateTask(task *structs.Task) error {
 		fileSize := int64(task.LogConfig.MaxFileSizeMB * 1024 * 1024)
 		e.lro.MaxFiles = task.LogConfig.MaxFiles
 		e.lro.FileSize = fileSize
+		e.lro.Compress = task.LogConfig.Compress
+		e.lro.MaxAge = task.LogConfig.MaxAge
 		e.lre.MaxFiles = task.LogConfig.MaxFiles
 		e.lre.FileSize = fileSize
+		e.lre.Compress = task.LogConfig.Compress
+		e.lre.MaxAge = task.LogConfig.MaxAge
 	}
 	e.rotatorLock.Unlock()
 
@@ -389,10 +432,14 @@ func (e *UniversalExecutor) UpdateTask(task *structs.Task) error {
 }
 
 // generateServiceKeys takes a list of interpolated Nomad Services and returns a map
-// of ServiceKeys to Nomad Services.
+// of ServiceKeys to Nomad Services. Services registered with Nomad's service
+// registry are registered by the client and are skipped.
 func generateServiceKeys(allocID string, services []*structs.Service) map[consul.ServiceKey]*structs.Service {
 	keys := make(map[consul.ServiceKey]*structs.Service, len(services))
 	for _, service := range services {
+		if service.Provider == structs.ServiceProviderNomad {
+			continue
+		}
 		key := consul.GenerateServiceKey(service)
 		keys[key] = service
 	}
@@ -464,6 +511,10 @@ func (e *UniversalExecutor) Exit() error {
 		e.lro.Close()
 	}
 
+	if e.logForwarder != nil {
+		e.logForwarder.Close()
+	}
+
 	if e.consulSyncer != nil {
 		e.consulSyncer.Shutdown()
 	}
@@ -730,6 +781,12 @@ func (e *UniversalExecutor) interpolateServices(task *structs.Task) {
 			check.Protocol = e.ctx.TaskEnv.ReplaceEnv(check.Protocol)
 			check.PortLabel = e.ctx.TaskEnv.ReplaceEnv(check.PortLabel)
 			check.InitialStatus = e.ctx.TaskEnv.ReplaceEnv(check.InitialStatus)
+			check.Method = e.ctx.TaskEnv.ReplaceEnv(check.Method)
+			check.Body = e.ctx.TaskEnv.ReplaceEnv(check.Body)
+			check.GRPCService = e.ctx.TaskEnv.ReplaceEnv(check.GRPCService)
+			for k, v := range check.Header {
+				check.Header[k] = e.ctx.TaskEnv.ParseAndReplace(v)
+			}
 		}
 		service.Name = e.ctx.TaskEnv.ReplaceEnv(service.Name)
 		service.PortLabel = e.ctx.TaskEnv.ReplaceEnv(service.PortLabel)
@@ -3,6 +3,7 @@
 package executor
 
 import (
+	"fmt"
 	"os"
 
 	cstructs "github.com/hashicorp/nomad/client/structs"
@@ -29,6 +30,15 @@ func (e *UniversalExecutor) configureIsolation() error {
 	return nil
 }
 
+// startCmd starts the user command. Network namespaces are not supported on
+// this platform.
+func (e *UniversalExecutor) startCmd() error {
+	if e.command.NetworkNamespace != "" {
+		return fmt.Errorf("network namespaces are not supported on this platform")
+	}
+	return e.cmd.Start()
+}
+
 func (e *UniversalExecutor) Stats() (*cstructs.TaskResourceUsage, error) {
 	pidStats, err := e.pidStats()
 	if err != nil {
@@ -16,6 +16,7 @@ import (
 	cgroupFs "github.com/opencontainers/runc/libcontainer/cgroups/fs"
 	cgroupConfig "github.com/opencontainers/runc/libcontainer/configs"
 
+	"github.com/hashicorp/nomad/client/netns"
 	"github.com/hashicorp/nomad/client/stats"
 	cstructs "github.com/hashicorp/nomad/client/structs"
 	"github.com/hashicorp/nomad/nomad/structs"
@@ -333,3 +334,12 @@ func DestroyCgroup(groups *cgroupConfig.Cgroup, cgPaths map[string]string, execu
 func getCgroupManager(groups *cgroupConfig.Cgroup, paths map[string]string) cgroups.Manager {
 	return &cgroupFs.Manager{Cgroups: groups, Paths: paths}
 }
+
+// startCmd starts the user command, entering the allocation's network
+// namespace first if one has been set.
+func (e *UniversalExecutor) startCmd() error {
+	if e.command.NetworkNamespace == "" {
+		return e.cmd.Start()
+	}
+	return netns.Do(e.command.NetworkNamespace, e.cmd.Start)
+}
@@ -41,9 +41,15 @@ func (e *UniversalExecutor) collectLogs(we io.Writer, wo io.Writer) {
 		if logParts.Severity == syslog.LOG_ERR {
 			e.lre.Write(logParts.Message)
 			e.lre.Write([]byte{'\n'})
+			if e.logForwarder != nil {
+				e.logForwarder.Forward(logging.StreamStderr, logParts.Message)
+			}
 		} else {
 			e.lro.Write(logParts.Message)
 			e.lro.Write([]byte{'\n'})
+			if e.logForwarder != nil {
+				e.logForwarder.Forward(logging.StreamStdout, logParts.Message)
+			}
 		}
 	}
 }
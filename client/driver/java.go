@@ -264,11 +264,12 @@ func (d *JavaDriver) Start(ctx *ExecContext, task *structs.Task) (DriverHandle,
 	}
 
 	execCmd := &executor.ExecCommand{
-		Cmd:            absPath,
-		Args:           args,
-		FSIsolation:    true,
-		ResourceLimits: true,
-		User:           getExecutorUser(task),
+		Cmd:              absPath,
+		Args:             args,
+		FSIsolation:      true,
+		ResourceLimits:   true,
+		User:             getExecutorUser(task),
+		NetworkNamespace: ctx.NetworkNamespace(),
 	}
 	ps, err := execIntf.LaunchCmd(execCmd)
 	if err != nil {
@@ -0,0 +1,72 @@
+package logging
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LogIndexEntry maps the time output was written to a log file to the offset
+// it was written at. The rotator records an entry for the first write of every
+// second, so log files can be searched by time with a resolution of a second.
+type LogIndexEntry struct {
+	Time   time.Time
+	Offset int64
+}
+
+// LogIndexFileName returns the name of the index of a rotated log file. The
+// index is a hidden file next to the log file.
+func LogIndexFileName(logFile string) string {
+	return fmt.Sprintf(".%s.idx", logFile)
+}
+
+// ParseLogIndex parses the entries of a log index. Each line of the index
+// holds the time in nanoseconds since the epoch and the offset of an entry.
+// A trailing partially written line is ignored.
+func ParseLogIndex(r io.Reader) ([]*LogIndexEntry, error) {
+	var entries []*LogIndexEntry
+	br := bufio.NewReader(r)
+	for {
+		line, err := br.ReadString('\n')
+		if err == io.EOF {
+			return entries, nil
+		} else if err != nil {
+			return nil, err
+		}
+
+		parts := strings.Fields(line)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid log index entry %q", line)
+		}
+		nanos, err := strconv.ParseInt(parts[0], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid log index time %q: %v", parts[0], err)
+		}
+		offset, err := strconv.ParseInt(parts[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid log index offset %q: %v", parts[1], err)
+		}
+		entries = append(entries, &LogIndexEntry{Time: time.Unix(0, nanos), Offset: offset})
+	}
+}
+
+// LogIndexOffset returns the offset of the first output written during or
+// after the second of the given time. It returns false if all the indexed
+// output was written before.
+func LogIndexOffset(entries []*LogIndexEntry, t time.Time) (int64, bool) {
+	t = t.Truncate(time.Second)
+	for _, entry := range entries {
+		if !entry.Time.Before(t) {
+			return entry.Offset, true
+		}
+	}
+	return 0, false
+}
+
+// formatLogIndexEntry returns the line of an index entry.
+func formatLogIndexEntry(t time.Time, offset int64) string {
+	return fmt.Sprintf("%d %d\n", t.UnixNano(), offset)
+}
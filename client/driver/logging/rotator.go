@@ -2,7 +2,9 @@ package logging
 
 import (
 	"bufio"
+	"compress/gzip"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"log"
 	"os"
@@ -17,6 +19,13 @@ import (
 const (
 	bufSize  = 32768
 	flushDur = 100 * time.Millisecond
+
+	// maxAgeCheckInterval is the interval at which rotated files are checked
+	// for exceeding their max age
+	maxAgeCheckInterval = 1 * time.Minute
+
+	// CompressedLogSuffix is the suffix of compressed rotated files
+	CompressedLogSuffix = ".gz"
 )
 
 // FileRotator writes bytes to a rotated set of files
@@ -24,6 +33,15 @@ type FileRotator struct {
 	MaxFiles int   // MaxFiles is the maximum number of rotated files allowed in a path
 	FileSize int64 // FileSize is the size a rotated file is allowed to grow
 
+	// Compress enables gzip compression of rotated files. The storage of
+	// MaxFiles * FileSize is then accounted by the size of the compressed
+	// files instead of the number of files.
+	Compress bool
+
+	// MaxAge is the age after which rotated files are purged. It is ignored
+	// if zero.
+	MaxAge time.Duration
+
 	path             string // path is the path on the file system where the rotated set of files are opened
 	baseFileName     string // baseFileName is the base file name of the rotated files
 	logFileIdx       int    // logFileIdx is the current index of the rotated files
@@ -34,9 +52,12 @@ type FileRotator struct {
 	bufw        *bufio.Writer
 	bufLock     sync.Mutex
 
+	indexFile    *os.File // indexFile is the index of the current file
+	lastIndexSec int64    // lastIndexSec is the second of the last index entry
+
 	flushTicker *time.Ticker
 	logger      *log.Logger
-	purgeCh     chan struct{}
+	purgeCh     chan int
 	doneCh      chan struct{}
 
 	closed     bool
@@ -55,13 +76,13 @@ func NewFileRotator(path string, baseFile string, maxFiles int,
 
 		flushTicker: time.NewTicker(flushDur),
 		logger:      logger,
-		purgeCh:     make(chan struct{}, 1),
+		purgeCh:     make(chan int, 1),
 		doneCh:      make(chan struct{}, 1),
 	}
 	if err := rotator.lastFile(); err != nil {
 		return nil, err
 	}
-	go rotator.purgeOldFiles()
+	go rotator.purgeOldFiles(rotator.logFileIdx)
 	go rotator.flushPeriodically()
 	return rotator, nil
 }
@@ -71,6 +92,7 @@ func NewFileRotator(path string, baseFile string, maxFiles int,
 func (f *FileRotator) Write(p []byte) (n int, err error) {
 	n = 0
 	var nw int
+	now := time.Now()
 
 	for n < len(p) {
 		// Check if we still have space in the current file, otherwise close and
@@ -83,6 +105,9 @@ func (f *FileRotator) Write(p []byte) (n int, err error) {
 				return 0, err
 			}
 		}
+		// Record the offset of the first write of every second
+		f.writeIndex(now)
+
 		// Calculate the remaining size on this file
 		remainingSize := f.FileSize - f.currentWr
 
@@ -102,7 +127,7 @@ func (f *FileRotator) Write(p []byte) (n int, err error) {
 		n += nw
 
 		// Increment the total number of bytes in the file
-		f.currentWr += int64(n)
+		f.currentWr += int64(nw)
 		if err != nil {
 			f.logger.Printf("[ERROR] driver.rotator: error writing to file: %v", err)
 			return
@@ -123,18 +148,27 @@ func (f *FileRotator) nextFile() error {
 				continue
 			}
 		}
+		if _, err := os.Stat(logFileName + CompressedLogSuffix); err == nil {
+			continue
+		}
 		f.logFileIdx = nextFileIdx
 		if err := f.createFile(); err != nil {
 			return err
 		}
 		break
 	}
-	// Purge old files if we have more files than MaxFiles
+	// Purge old files if we have more files than MaxFiles. Compressed files
+	// and files with a max age are handled on every rotation.
 	f.closedLock.Lock()
 	defer f.closedLock.Unlock()
-	if f.logFileIdx-f.oldestLogFileIdx >= f.MaxFiles && !f.closed {
+	if (f.logFileIdx-f.oldestLogFileIdx >= f.MaxFiles || f.Compress || f.MaxAge > 0) && !f.closed {
+		// Replace a pending purge for an older file
+		select {
+		case <-f.purgeCh:
+		default:
+		}
 		select {
-		case f.purgeCh <- struct{}{}:
+		case f.purgeCh <- f.logFileIdx:
 		default:
 		}
 	}
@@ -155,10 +189,16 @@ func (f *FileRotator) lastFile() error {
 		}
 		if strings.HasPrefix(fi.Name(), prefix) {
 			fileIdx := strings.TrimPrefix(fi.Name(), prefix)
-			n, err := strconv.Atoi(fileIdx)
+			compressed := strings.HasSuffix(fileIdx, CompressedLogSuffix)
+			n, err := strconv.Atoi(strings.TrimSuffix(fileIdx, CompressedLogSuffix))
 			if err != nil {
 				continue
 			}
+
+			// Compressed files are never written to again
+			if compressed {
+				n++
+			}
 			if n > f.logFileIdx {
 				f.logFileIdx = n
 			}
@@ -184,9 +224,41 @@ func (f *FileRotator) createFile() error {
 	}
 	f.currentWr = fi.Size()
 	f.createOrResetBuffer()
+	f.createIndex(filepath.Base(logFileName))
 	return nil
 }
 
+// createIndex opens the index of the current file. Writing to the log file
+// continues without an index if it can't be opened.
+func (f *FileRotator) createIndex(logFileName string) {
+	if f.indexFile != nil {
+		f.indexFile.Close()
+		f.indexFile = nil
+	}
+	f.lastIndexSec = 0
+
+	indexFileName := filepath.Join(f.path, LogIndexFileName(logFileName))
+	indexFile, err := os.OpenFile(indexFileName, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0666)
+	if err != nil {
+		f.logger.Printf("[WARN] driver.rotator: error creating log index file: %v", err)
+		return
+	}
+	f.indexFile = indexFile
+}
+
+// writeIndex records the current offset in the index if nothing was written
+// yet in the second of the given time
+func (f *FileRotator) writeIndex(now time.Time) {
+	if f.indexFile == nil || now.Unix() == f.lastIndexSec {
+		return
+	}
+	if _, err := f.indexFile.WriteString(formatLogIndexEntry(now, f.currentWr)); err != nil {
+		f.logger.Printf("[WARN] driver.rotator: error writing to log index file: %v", err)
+		return
+	}
+	f.lastIndexSec = now.Unix()
+}
+
 // flushPeriodically flushes the buffered writer every 100ms to the underlying
 // file
 func (f *FileRotator) flushPeriodically() {
@@ -211,48 +283,206 @@ func (f *FileRotator) Close() {
 	}
 }
 
-// purgeOldFiles removes older files and keeps only the last N files rotated for
-// a file
-func (f *FileRotator) purgeOldFiles() {
+// purgeOldFiles compresses and removes the files rotated before the current
+// file whenever a file is rotated, and periodically if files have a max age.
+func (f *FileRotator) purgeOldFiles(current int) {
+	ageTicker := time.NewTicker(maxAgeCheckInterval)
+	defer ageTicker.Stop()
 	for {
 		select {
-		case <-f.purgeCh:
-			var fIndexes []int
-			files, err := ioutil.ReadDir(f.path)
-			if err != nil {
+		case idx, ok := <-f.purgeCh:
+			if !ok {
 				return
 			}
-			// Inserting all the rotated files in a slice
-			for _, fi := range files {
-				if strings.HasPrefix(fi.Name(), f.baseFileName) {
-					fileIdx := strings.TrimPrefix(fi.Name(), fmt.Sprintf("%s.", f.baseFileName))
-					n, err := strconv.Atoi(fileIdx)
-					if err != nil {
-						continue
-					}
-					fIndexes = append(fIndexes, n)
-				}
+			current = idx
+		case <-ageTicker.C:
+			if f.MaxAge == 0 {
+				continue
+			}
+		case <-f.doneCh:
+			return
+		}
+		f.purge(current)
+	}
+}
+
+// rotatedFile is a file of the rotated set
+type rotatedFile struct {
+	idx        int
+	name       string
+	compressed bool
+	size       int64
+	modTime    time.Time
+}
+
+// rotatedFilesByIdx sorts rotated files by their index
+type rotatedFilesByIdx []*rotatedFile
+
+func (r rotatedFilesByIdx) Len() int           { return len(r) }
+func (r rotatedFilesByIdx) Less(i, j int) bool { return r[i].idx < r[j].idx }
+func (r rotatedFilesByIdx) Swap(i, j int)      { r[i], r[j] = r[j], r[i] }
+
+// rotatedFiles returns the files of the rotated set sorted by their index. If
+// a file exists both compressed and uncompressed, the uncompressed file is
+// returned as its compression didn't complete.
+func (f *FileRotator) rotatedFiles() ([]*rotatedFile, error) {
+	fis, err := ioutil.ReadDir(f.path)
+	if err != nil {
+		return nil, err
+	}
+
+	byIdx := make(map[int]*rotatedFile)
+	prefix := fmt.Sprintf("%s.", f.baseFileName)
+	for _, fi := range fis {
+		if fi.IsDir() || !strings.HasPrefix(fi.Name(), prefix) {
+			continue
+		}
+		fileIdx := strings.TrimPrefix(fi.Name(), prefix)
+		compressed := strings.HasSuffix(fileIdx, CompressedLogSuffix)
+		n, err := strconv.Atoi(strings.TrimSuffix(fileIdx, CompressedLogSuffix))
+		if err != nil {
+			continue
+		}
+		if existing, ok := byIdx[n]; ok && !existing.compressed {
+			continue
+		}
+		byIdx[n] = &rotatedFile{
+			idx:        n,
+			name:       fmt.Sprintf("%s.%d", f.baseFileName, n),
+			compressed: compressed,
+			size:       fi.Size(),
+			modTime:    fi.ModTime(),
+		}
+	}
+
+	files := make([]*rotatedFile, 0, len(byIdx))
+	for _, file := range byIdx {
+		files = append(files, file)
+	}
+	sort.Sort(rotatedFilesByIdx(files))
+	return files, nil
+}
+
+// purge compresses the files rotated before the current file if compression
+// is enabled and removes the files exceeding the retention.
+func (f *FileRotator) purge(current int) {
+	files, err := f.rotatedFiles()
+	if err != nil {
+		return
+	}
+
+	if f.Compress {
+		for _, file := range files {
+			if file.compressed || file.idx >= current {
+				continue
+			}
+			if err := f.compressFile(file); err != nil {
+				f.logger.Printf("[ERROR] driver.rotator: error compressing file %q: %v", file.name, err)
+			}
+		}
+	}
+
+	toDelete := make(map[int]struct{})
+	if f.MaxAge > 0 {
+		cutoff := time.Now().Add(-f.MaxAge)
+		for _, file := range files {
+			if file.idx < current && file.modTime.Before(cutoff) {
+				toDelete[file.idx] = struct{}{}
 			}
+		}
+	}
 
-			// Not continuing to delete files if the number of files is not more
-			// than MaxFiles
-			if len(fIndexes) <= f.MaxFiles {
+	if f.Compress {
+		// Keep the newest files fitting in the storage of MaxFiles * FileSize,
+		// reserving the full size for the file being written
+		budget := int64(f.MaxFiles) * f.FileSize
+		var used int64
+		for i := len(files) - 1; i >= 0; i-- {
+			file := files[i]
+			if file.idx >= current {
+				used += f.FileSize
 				continue
 			}
+			used += file.size
+			if used > budget {
+				toDelete[file.idx] = struct{}{}
+			}
+		}
+	} else if len(files) > f.MaxFiles {
+		// Keep only the number of files as configured by the user
+		for _, file := range files[:len(files)-f.MaxFiles] {
+			toDelete[file.idx] = struct{}{}
+		}
+	}
 
-			// Sorting the file indexes so that we can purge the older files and keep
-			// only the number of files as configured by the user
-			sort.Sort(sort.IntSlice(fIndexes))
-			toDelete := fIndexes[0 : len(fIndexes)-f.MaxFiles]
-			for _, fIndex := range toDelete {
-				fname := filepath.Join(f.path, fmt.Sprintf("%s.%d", f.baseFileName, fIndex))
-				os.RemoveAll(fname)
+	oldest := current
+	for _, file := range files {
+		if _, ok := toDelete[file.idx]; !ok {
+			if file.idx < oldest {
+				oldest = file.idx
 			}
-			f.oldestLogFileIdx = fIndexes[0]
-		case <-f.doneCh:
-			return
+			continue
 		}
+		os.RemoveAll(filepath.Join(f.path, file.name))
+		os.RemoveAll(filepath.Join(f.path, file.name+CompressedLogSuffix))
+		os.RemoveAll(filepath.Join(f.path, LogIndexFileName(file.name)))
 	}
+	f.oldestLogFileIdx = oldest
+}
+
+// compressFile replaces a rotated file with its gzip compressed version. The
+// compressed file keeps the modification time of the file so its age is
+// retained.
+func (f *FileRotator) compressFile(file *rotatedFile) error {
+	src := filepath.Join(f.path, file.name)
+	dst := src + CompressedLogSuffix
+	tmp := filepath.Join(f.path, fmt.Sprintf(".%s%s.tmp", file.name, CompressedLogSuffix))
+
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0666)
+	if err != nil {
+		return err
+	}
+	gz := gzip.NewWriter(out)
+	if _, err := io.Copy(gz, in); err != nil {
+		out.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		out.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	if err := os.Chtimes(tmp, file.modTime, file.modTime); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, dst); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Remove(src); err != nil {
+		return err
+	}
+
+	fi, err := os.Stat(dst)
+	if err != nil {
+		return err
+	}
+	file.compressed = true
+	file.size = fi.Size()
+	return nil
 }
 
 // flushBuffer flushes the buffer
@@ -1,12 +1,16 @@
 package logging
 
 import (
+	"compress/gzip"
+	"crypto/rand"
 	"fmt"
 	"io/ioutil"
 	"log"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/hashicorp/nomad/testutil"
 )
@@ -272,11 +276,261 @@ func TestFileRotator_PurgeOldFiles(t *testing.T) {
 			return false, nil
 		}
 
-		if len(f) != 2 {
-			lastErr = fmt.Errorf("expected number of files: %v, got: %v", 2, len(f))
+		// The indexes of purged files are removed as well
+		var logs, indexes int
+		for _, fi := range f {
+			if strings.HasSuffix(fi.Name(), ".idx") {
+				indexes++
+			} else {
+				logs++
+			}
+		}
+		if logs != 2 {
+			lastErr = fmt.Errorf("expected number of files: %v, got: %v", 2, logs)
+			return false, nil
+		}
+		if indexes != 2 {
+			lastErr = fmt.Errorf("expected number of index files: %v, got: %v", 2, indexes)
+			return false, nil
+		}
+
+		return true, nil
+	}, func(err error) {
+		t.Fatalf("%v", lastErr)
+	})
+}
+
+func TestFileRotator_Index(t *testing.T) {
+	var path string
+	var err error
+	if path, err = ioutil.TempDir("", pathPrefix); err != nil {
+		t.Fatalf("test setup err: %v", err)
+	}
+	defer os.RemoveAll(path)
+
+	fr, err := NewFileRotator(path, baseFileName, 10, 6, logger)
+	if err != nil {
+		t.Fatalf("test setup err: %v", err)
+	}
+
+	// The first write spans two files, each getting an index entry
+	start := time.Now()
+	fr.Write([]byte("abcdefgh"))
+	time.Sleep(time.Until(start.Truncate(time.Second).Add(time.Second)))
+	fr.Write([]byte("ij"))
+
+	readIndex := func(name string) []*LogIndexEntry {
+		f, err := os.Open(filepath.Join(path, LogIndexFileName(name)))
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		defer f.Close()
+		entries, err := ParseLogIndex(f)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		return entries
+	}
+
+	if entries := readIndex("redis.stdout.0"); len(entries) != 1 || entries[0].Offset != 0 {
+		t.Fatalf("bad index of first file: %v", entries)
+	}
+	entries := readIndex("redis.stdout.1")
+	if len(entries) != 2 || entries[0].Offset != 0 || entries[1].Offset != 2 {
+		t.Fatalf("bad index of second file: %v", entries)
+	}
+
+	// The output of the second write is found by its time
+	if offset, ok := LogIndexOffset(entries, entries[1].Time); !ok || offset != 2 {
+		t.Fatalf("bad offset: %d %v", offset, ok)
+	}
+	if offset, ok := LogIndexOffset(entries, start); !ok || offset != 0 {
+		t.Fatalf("bad offset: %d %v", offset, ok)
+	}
+	if _, ok := LogIndexOffset(entries, entries[1].Time.Add(time.Second)); ok {
+		t.Fatalf("expected no offset after the last entry")
+	}
+}
+
+func TestParseLogIndex(t *testing.T) {
+	index := "1493632800000000000 0\n1493632801500000000 120\n14936328025"
+	entries, err := ParseLogIndex(strings.NewReader(index))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("bad entries: %v", entries)
+	}
+	if entries[1].Offset != 120 || !entries[1].Time.Equal(time.Unix(1493632801, 500000000)) {
+		t.Fatalf("bad entry: %#v", entries[1])
+	}
+
+	if _, err := ParseLogIndex(strings.NewReader("foo 0\n")); err == nil {
+		t.Fatalf("expected error")
+	}
+}
+
+func TestFileRotator_Compress(t *testing.T) {
+	var path string
+	var err error
+	if path, err = ioutil.TempDir("", pathPrefix); err != nil {
+		t.Fatalf("test setup err: %v", err)
+	}
+	defer os.RemoveAll(path)
+
+	fr, err := NewFileRotator(path, baseFileName, 2, 1024, logger)
+	if err != nil {
+		t.Fatalf("test setup err: %v", err)
+	}
+	fr.Compress = true
+
+	// Compressed files take less space, retaining more than MaxFiles files
+	data := []byte(strings.Repeat("a", 6*1024))
+	if _, err := fr.Write(data); err != nil {
+		t.Fatalf("got error while writing: %v", err)
+	}
+
+	var lastErr error
+	testutil.WaitForResult(func() (bool, error) {
+		for i := 0; i < 5; i++ {
+			fname := filepath.Join(path, fmt.Sprintf("redis.stdout.%d", i))
+			if _, err := os.Stat(fname); err == nil {
+				lastErr = fmt.Errorf("expected file %v to be compressed", fname)
+				return false, nil
+			}
+			if _, err := os.Stat(fname + CompressedLogSuffix); err != nil {
+				lastErr = fmt.Errorf("expected file %v to exist", fname+CompressedLogSuffix)
+				return false, nil
+			}
+		}
+		if _, err := os.Stat(filepath.Join(path, "redis.stdout.5")); err != nil {
+			lastErr = fmt.Errorf("expected current file to be uncompressed: %v", err)
+			return false, nil
+		}
+		return true, nil
+	}, func(err error) {
+		t.Fatalf("%v", lastErr)
+	})
+
+	f, err := os.Open(filepath.Join(path, "redis.stdout.0"+CompressedLogSuffix))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	out, err := ioutil.ReadAll(gz)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if string(out) != string(data[:1024]) {
+		t.Fatalf("bad decompressed data: %q", out)
+	}
+
+	// A restarted rotator continues after the compressed files
+	fr.Close()
+	fr, err = NewFileRotator(path, baseFileName, 2, 1024, logger)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer fr.Close()
+	if fr.logFileIdx != 5 {
+		t.Fatalf("bad file index: %d", fr.logFileIdx)
+	}
+}
+
+func TestFileRotator_Compress_Budget(t *testing.T) {
+	var path string
+	var err error
+	if path, err = ioutil.TempDir("", pathPrefix); err != nil {
+		t.Fatalf("test setup err: %v", err)
+	}
+	defer os.RemoveAll(path)
+
+	fr, err := NewFileRotator(path, baseFileName, 3, 1024, logger)
+	if err != nil {
+		t.Fatalf("test setup err: %v", err)
+	}
+	fr.Compress = true
+
+	// Random data doesn't compress, so the compressed files exceed the
+	// storage before MaxFiles is reached
+	data := make([]byte, 6*1024)
+	rand.Read(data)
+	if _, err := fr.Write(data); err != nil {
+		t.Fatalf("got error while writing: %v", err)
+	}
+
+	var lastErr error
+	testutil.WaitForResult(func() (bool, error) {
+		files, err := fr.rotatedFiles()
+		if err != nil {
+			return false, err
+		}
+		var size int64
+		for _, file := range files {
+			if file.idx != 5 {
+				size += file.size
+			}
+		}
+		if size+1024 > 3*1024 {
+			lastErr = fmt.Errorf("log storage exceeded: %d", size+1024)
 			return false, nil
 		}
+		if len(files) != 2 || files[0].idx != 4 || !files[0].compressed {
+			lastErr = fmt.Errorf("bad files: %d", len(files))
+			return false, nil
+		}
+		return true, nil
+	}, func(err error) {
+		t.Fatalf("%v", lastErr)
+	})
+}
 
+func TestFileRotator_MaxAge(t *testing.T) {
+	var path string
+	var err error
+	if path, err = ioutil.TempDir("", pathPrefix); err != nil {
+		t.Fatalf("test setup err: %v", err)
+	}
+	defer os.RemoveAll(path)
+
+	// Create an old rotated file
+	old := time.Now().Add(-2 * time.Hour)
+	for _, name := range []string{"redis.stdout.0", LogIndexFileName("redis.stdout.0")} {
+		fname := filepath.Join(path, name)
+		if err := ioutil.WriteFile(fname, []byte("abc"), 0666); err != nil {
+			t.Fatalf("test setup err: %v", err)
+		}
+		if err := os.Chtimes(fname, old, old); err != nil {
+			t.Fatalf("test setup err: %v", err)
+		}
+	}
+
+	fr, err := NewFileRotator(path, baseFileName, 10, 3, logger)
+	if err != nil {
+		t.Fatalf("test setup err: %v", err)
+	}
+	defer fr.Close()
+	fr.MaxAge = time.Hour
+
+	// Rotating purges the old file and its index
+	fr.Write([]byte("def"))
+
+	var lastErr error
+	testutil.WaitForResult(func() (bool, error) {
+		for _, name := range []string{"redis.stdout.0", LogIndexFileName("redis.stdout.0")} {
+			if _, err := os.Stat(filepath.Join(path, name)); err == nil {
+				lastErr = fmt.Errorf("expected file %v to be purged", name)
+				return false, nil
+			}
+		}
+		if _, err := os.Stat(filepath.Join(path, "redis.stdout.1")); err != nil {
+			lastErr = fmt.Errorf("expected current file to exist: %v", err)
+			return false, nil
+		}
 		return true, nil
 	}, func(err error) {
 		t.Fatalf("%v", lastErr)
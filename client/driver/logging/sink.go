@@ -0,0 +1,625 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+const (
+	// StreamStdout and StreamStderr are the streams a log line is read from.
+	StreamStdout = "stdout"
+	StreamStderr = "stderr"
+
+	// The defaults of sinks that didn't configure buffering and batching.
+	defaultSinkBufferSize = 10000
+	defaultSinkBatchSize  = 100
+	defaultSinkBatchWait  = 1 * time.Second
+
+	// sinkMaxLineSize is the size at which lines without a newline are
+	// forwarded anyway.
+	sinkMaxLineSize = 64 * 1024
+
+	// sinkRetryMin and sinkRetryMax bound the time waited before retrying to
+	// send log lines to a sink that failed.
+	sinkRetryMin = 250 * time.Millisecond
+	sinkRetryMax = 30 * time.Second
+
+	// sinkTimeout is the timeout for connecting and writing to a sink.
+	sinkTimeout = 10 * time.Second
+
+	// sinkCloseTimeout is how long closing a sink waits for buffered lines
+	// to be sent.
+	sinkCloseTimeout = 5 * time.Second
+
+	// syslogFacilityUser is the syslog facility log lines are sent with.
+	syslogFacilityUser = 1
+
+	// syslogSeverityErr and syslogSeverityInfo are the syslog severities of
+	// lines read from stderr and stdout.
+	syslogSeverityErr  = 3
+	syslogSeverityInfo = 6
+)
+
+// SinkTags identify the task a log line was read from.
+type SinkTags struct {
+	AllocID string
+	Job     string
+	Group   string
+	Task    string
+}
+
+// LogLine is a line of a task's output forwarded to sinks. Sinks using JSON
+// send it as a single JSON object per line.
+type LogLine struct {
+	Time    time.Time `json:"time"`
+	Stream  string    `json:"stream"`
+	Message string    `json:"message"`
+	AllocID string    `json:"alloc_id"`
+	Job     string    `json:"job"`
+	Group   string    `json:"group"`
+	Task    string    `json:"task"`
+}
+
+// LogForwarder forwards the output of a task to the sinks of its log config.
+// Each sink buffers lines independently so a slow sink never blocks the task
+// or the other sinks.
+type LogForwarder struct {
+	tags    SinkTags
+	sinks   []*sink
+	writers []*lineWriter
+	lock    sync.Mutex
+	logger  *log.Logger
+}
+
+// NewLogForwarder returns a forwarder sending log lines tagged with the given
+// tags to the configured sinks.
+func NewLogForwarder(configs []*structs.LogSink, tags SinkTags, logger *log.Logger) (*LogForwarder, error) {
+	f := &LogForwarder{
+		tags:   tags,
+		logger: logger,
+	}
+	for _, config := range configs {
+		s, err := newSink(config, tags, logger)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		f.sinks = append(f.sinks, s)
+	}
+	return f, nil
+}
+
+// Forward sends a single line read from the given stream to the sinks.
+func (f *LogForwarder) Forward(stream string, message []byte) {
+	line := &LogLine{
+		Time:    time.Now(),
+		Stream:  stream,
+		Message: string(bytes.TrimRight(message, "\r\n")),
+		AllocID: f.tags.AllocID,
+		Job:     f.tags.Job,
+		Group:   f.tags.Group,
+		Task:    f.tags.Task,
+	}
+	for _, s := range f.sinks {
+		s.enqueue(line)
+	}
+}
+
+// Writer returns a writer splitting the output written to it into lines that
+// are forwarded as read from the given stream.
+func (f *LogForwarder) Writer(stream string) io.WriteCloser {
+	w := &lineWriter{forwarder: f, stream: stream}
+	f.lock.Lock()
+	f.writers = append(f.writers, w)
+	f.lock.Unlock()
+	return w
+}
+
+// Close forwards the unterminated lines of its writers, sends the buffered
+// lines and closes the sinks.
+func (f *LogForwarder) Close() error {
+	f.lock.Lock()
+	writers := f.writers
+	f.writers = nil
+	f.lock.Unlock()
+	for _, w := range writers {
+		w.Close()
+	}
+
+	var wg sync.WaitGroup
+	for _, s := range f.sinks {
+		wg.Add(1)
+		go func(s *sink) {
+			defer wg.Done()
+			s.close()
+		}(s)
+	}
+	wg.Wait()
+	return nil
+}
+
+// lineWriter splits the output of a stream into lines.
+type lineWriter struct {
+	forwarder *LogForwarder
+	stream    string
+	buf       []byte
+	lock      sync.Mutex
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		w.forwarder.Forward(w.stream, w.buf[:i])
+		w.buf = w.buf[i+1:]
+	}
+
+	// Forward overly long lines in pieces
+	for len(w.buf) >= sinkMaxLineSize {
+		w.forwarder.Forward(w.stream, w.buf[:sinkMaxLineSize])
+		w.buf = w.buf[sinkMaxLineSize:]
+	}
+
+	// Don't hold on to the memory of forwarded lines
+	if len(w.buf) == 0 {
+		w.buf = nil
+	}
+	return len(p), nil
+}
+
+// Close forwards the last line if it wasn't terminated by a newline.
+func (w *lineWriter) Close() error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if len(w.buf) != 0 {
+		w.forwarder.Forward(w.stream, w.buf)
+		w.buf = nil
+	}
+	return nil
+}
+
+// sinkSender sends log lines to the destination of a sink.
+type sinkSender interface {
+	// Send sends the lines and returns how many were sent before an error
+	// occurred.
+	Send(lines []*LogLine) (int, error)
+
+	// Close releases the connection to the sink.
+	Close() error
+}
+
+// sinkRetryAfterError is returned by senders when the sink asked for lines to
+// be sent again after some time.
+type sinkRetryAfterError struct {
+	wait time.Duration
+	err  error
+}
+
+func (e *sinkRetryAfterError) Error() string {
+	return e.err.Error()
+}
+
+// sinkPermanentError is returned by senders when sending the lines again
+// won't succeed.
+type sinkPermanentError struct {
+	err error
+}
+
+func (e *sinkPermanentError) Error() string {
+	return e.err.Error()
+}
+
+// sink buffers lines for a sender and sends them in batches, retrying when
+// sending fails.
+type sink struct {
+	name       string
+	sender     sinkSender
+	batchSize  int
+	batchWait  time.Duration
+	bufferSize int
+
+	buf      []*LogLine
+	dropped  int
+	closed   bool
+	lock     sync.Mutex
+	notifyCh chan struct{}
+	closeCh  chan struct{}
+	doneCh   chan struct{}
+
+	logger *log.Logger
+}
+
+func newSink(config *structs.LogSink, tags SinkTags, logger *log.Logger) (*sink, error) {
+	s := &sink{
+		name:       fmt.Sprintf("%s sink %q", config.Type, config.Address),
+		batchSize:  defaultSinkBatchSize,
+		bufferSize: config.BufferSize,
+		notifyCh:   make(chan struct{}, 1),
+		closeCh:    make(chan struct{}),
+		doneCh:     make(chan struct{}),
+		logger:     logger,
+	}
+	if s.bufferSize <= 0 {
+		s.bufferSize = defaultSinkBufferSize
+	}
+
+	switch config.Type {
+	case structs.LogSinkTypeSyslog:
+		u, err := url.Parse(config.Address)
+		if err != nil {
+			return nil, fmt.Errorf("invalid syslog address %q: %v", config.Address, err)
+		}
+		s.sender = newSyslogSender(u.Scheme, u.Host, tags.Task)
+	case structs.LogSinkTypeUnix:
+		s.sender = &unixSender{path: strings.TrimPrefix(config.Address, "unix://")}
+	case structs.LogSinkTypeHTTP:
+		s.sender = &httpSender{
+			url:     config.Address,
+			headers: config.Headers,
+			client:  &http.Client{Timeout: sinkTimeout},
+		}
+		if config.BatchSize > 0 {
+			s.batchSize = config.BatchSize
+		}
+		s.batchWait = defaultSinkBatchWait
+		if config.BatchWait > 0 {
+			s.batchWait = config.BatchWait
+		}
+	default:
+		return nil, fmt.Errorf("unknown sink type %q", config.Type)
+	}
+
+	go s.run()
+	return s, nil
+}
+
+// enqueue buffers a line, dropping the oldest line if the buffer is full.
+func (s *sink) enqueue(line *LogLine) {
+	s.lock.Lock()
+	if s.closed {
+		s.lock.Unlock()
+		return
+	}
+	if len(s.buf) >= s.bufferSize {
+		s.buf[0] = nil
+		s.buf = s.buf[1:]
+		s.dropped++
+	}
+	s.buf = append(s.buf, line)
+	full := len(s.buf) >= s.batchSize || s.batchWait == 0
+	s.lock.Unlock()
+
+	if full {
+		select {
+		case s.notifyCh <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// nextBatch waits for a batch of lines to send. It returns nil once the sink
+// is closed and all lines were taken.
+func (s *sink) nextBatch() []*LogLine {
+	var timer <-chan time.Time
+	waited := s.batchWait == 0
+	for {
+		s.lock.Lock()
+		n := len(s.buf)
+		if n >= s.batchSize || (n > 0 && (waited || s.closed)) {
+			if n > s.batchSize {
+				n = s.batchSize
+			}
+			batch := make([]*LogLine, n)
+			copy(batch, s.buf)
+			s.buf = s.buf[n:]
+			dropped := s.dropped
+			s.dropped = 0
+			s.lock.Unlock()
+
+			if dropped > 0 {
+				s.logger.Printf("[WARN] logging.sink: dropped %d log lines for %s as it couldn't keep up", dropped, s.name)
+			}
+			return batch
+		}
+		closed := s.closed
+		s.lock.Unlock()
+
+		if closed {
+			return nil
+		}
+		if n > 0 && timer == nil {
+			timer = time.After(s.batchWait)
+		}
+
+		select {
+		case <-s.notifyCh:
+		case <-timer:
+			waited = true
+		case <-s.closeCh:
+		}
+	}
+}
+
+func (s *sink) run() {
+	defer close(s.doneCh)
+	defer s.sender.Close()
+	for {
+		batch := s.nextBatch()
+		if batch == nil {
+			return
+		}
+		s.send(batch)
+	}
+}
+
+// send sends a batch, retrying with a backoff until it succeeds. Once the sink
+// is closed, lines that fail to be sent are dropped.
+func (s *sink) send(batch []*LogLine) {
+	backoff := sinkRetryMin
+	for {
+		n, err := s.sender.Send(batch)
+		batch = batch[n:]
+		if err == nil {
+			return
+		}
+		if perr, ok := err.(*sinkPermanentError); ok {
+			s.logger.Printf("[ERR] logging.sink: dropped %d log lines rejected by %s: %v", len(batch), s.name, perr)
+			return
+		}
+
+		wait := backoff
+		if rerr, ok := err.(*sinkRetryAfterError); ok && rerr.wait > 0 {
+			wait = rerr.wait
+		}
+		s.logger.Printf("[WARN] logging.sink: failed to send %d log lines to %s, retrying in %v: %v", len(batch), s.name, wait, err)
+
+		select {
+		case <-time.After(wait):
+		case <-s.closeCh:
+			s.logger.Printf("[ERR] logging.sink: dropped %d log lines for %s while closing", len(batch), s.name)
+			return
+		}
+
+		backoff *= 2
+		if backoff > sinkRetryMax {
+			backoff = sinkRetryMax
+		}
+	}
+}
+
+// close stops accepting lines and waits for the buffered lines to be sent.
+func (s *sink) close() {
+	s.lock.Lock()
+	if s.closed {
+		s.lock.Unlock()
+		return
+	}
+	s.closed = true
+	s.lock.Unlock()
+	close(s.closeCh)
+
+	select {
+	case <-s.doneCh:
+	case <-time.After(sinkCloseTimeout):
+		s.logger.Printf("[WARN] logging.sink: timed out sending buffered log lines to %s", s.name)
+	}
+}
+
+// syslogSender sends lines to a syslog server in the RFC5424 format. Messages
+// sent over TCP are framed using octet counting as described in RFC6587.
+type syslogSender struct {
+	network  string
+	addr     string
+	hostname string
+	appName  string
+	conn     net.Conn
+}
+
+func newSyslogSender(network, addr, appName string) *syslogSender {
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "-"
+	}
+	if appName == "" {
+		appName = "-"
+	}
+	return &syslogSender{
+		network:  network,
+		addr:     addr,
+		hostname: syslogHeaderField(hostname, 255),
+		appName:  syslogHeaderField(appName, 48),
+	}
+}
+
+func (s *syslogSender) Send(lines []*LogLine) (int, error) {
+	if s.conn == nil {
+		conn, err := net.DialTimeout(s.network, s.addr, sinkTimeout)
+		if err != nil {
+			return 0, err
+		}
+		s.conn = conn
+	}
+
+	for i, line := range lines {
+		msg := s.format(line)
+		if s.network == "tcp" {
+			msg = strconv.Itoa(len(msg)) + " " + msg
+		}
+		s.conn.SetWriteDeadline(time.Now().Add(sinkTimeout))
+		if _, err := io.WriteString(s.conn, msg); err != nil {
+			s.Close()
+			return i, err
+		}
+	}
+	return len(lines), nil
+}
+
+// format returns the RFC5424 syslog message of a line. The tags of the line
+// are sent as structured data.
+func (s *syslogSender) format(line *LogLine) string {
+	severity := syslogSeverityInfo
+	if line.Stream == StreamStderr {
+		severity = syslogSeverityErr
+	}
+	return fmt.Sprintf("<%d>1 %s %s %s - %s [nomad alloc_id=\"%s\" job=\"%s\" group=\"%s\" task=\"%s\"] %s",
+		syslogFacilityUser*8+severity,
+		line.Time.UTC().Format("2006-01-02T15:04:05.000000Z07:00"),
+		s.hostname, s.appName, line.Stream,
+		syslogParamValue(line.AllocID), syslogParamValue(line.Job),
+		syslogParamValue(line.Group), syslogParamValue(line.Task),
+		line.Message)
+}
+
+func (s *syslogSender) Close() error {
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
+
+// syslogHeaderField returns a value usable as a header field of a syslog
+// message, which may only contain printable ASCII characters.
+func syslogHeaderField(value string, maxLen int) string {
+	field := strings.Map(func(r rune) rune {
+		if r < 33 || r > 126 {
+			return '_'
+		}
+		return r
+	}, value)
+	if len(field) > maxLen {
+		field = field[:maxLen]
+	}
+	return field
+}
+
+// syslogParamValue escapes the characters RFC5424 requires to be escaped in
+// structured data parameter values.
+var syslogParamValue = strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`).Replace
+
+// unixSender sends lines as JSON lines to a unix socket.
+type unixSender struct {
+	path string
+	conn net.Conn
+}
+
+func (s *unixSender) Send(lines []*LogLine) (int, error) {
+	if s.conn == nil {
+		conn, err := net.DialTimeout("unix", s.path, sinkTimeout)
+		if err != nil {
+			return 0, err
+		}
+		s.conn = conn
+	}
+
+	for i, line := range lines {
+		data, err := json.Marshal(line)
+		if err != nil {
+			return i, &sinkPermanentError{err}
+		}
+		s.conn.SetWriteDeadline(time.Now().Add(sinkTimeout))
+		if _, err := s.conn.Write(append(data, '\n')); err != nil {
+			s.Close()
+			return i, err
+		}
+	}
+	return len(lines), nil
+}
+
+func (s *unixSender) Close() error {
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
+
+// httpSender posts batches of lines as JSON lines to an HTTP endpoint. Lines
+// are sent again when the endpoint fails or asks to slow down, honoring the
+// Retry-After header of the response.
+type httpSender struct {
+	url     string
+	headers map[string]string
+	client  *http.Client
+}
+
+func (s *httpSender) Send(lines []*LogLine) (int, error) {
+	var body bytes.Buffer
+	enc := json.NewEncoder(&body)
+	for _, line := range lines {
+		if err := enc.Encode(line); err != nil {
+			return 0, &sinkPermanentError{err}
+		}
+	}
+
+	req, err := http.NewRequest("POST", s.url, &body)
+	if err != nil {
+		return 0, &sinkPermanentError{err}
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	for k, v := range s.headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	io.Copy(ioutil.Discard, resp.Body)
+	resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return len(lines), nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable:
+		err := fmt.Errorf("unexpected response code: %d", resp.StatusCode)
+		return 0, &sinkRetryAfterError{wait: retryAfter(resp.Header.Get("Retry-After")), err: err}
+	case resp.StatusCode >= 400 && resp.StatusCode < 500 && resp.StatusCode != http.StatusRequestTimeout:
+		return 0, &sinkPermanentError{fmt.Errorf("unexpected response code: %d", resp.StatusCode)}
+	default:
+		return 0, fmt.Errorf("unexpected response code: %d", resp.StatusCode)
+	}
+}
+
+func (s *httpSender) Close() error {
+	return nil
+}
+
+// retryAfter parses the value of a Retry-After header, which is either a
+// number of seconds or an HTTP date.
+func retryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(value); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		if d := t.Sub(time.Now()); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
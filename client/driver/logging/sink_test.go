@@ -0,0 +1,254 @@
+package logging
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+var testSinkTags = SinkTags{
+	AllocID: "5d2d0b5c-0b4a-4a2e-9e4a-3f6a2d5c1f20",
+	Job:     "example",
+	Group:   "cache",
+	Task:    "redis",
+}
+
+func TestLogForwarder_Unix(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logsink")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "logs.sock")
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer l.Close()
+
+	linesCh := make(chan *LogLine, 10)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		scanner := bufio.NewScanner(conn)
+		for scanner.Scan() {
+			var line LogLine
+			if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+				t.Errorf("bad line %q: %v", scanner.Text(), err)
+				return
+			}
+			linesCh <- &line
+		}
+	}()
+
+	sinks := []*structs.LogSink{{Type: structs.LogSinkTypeUnix, Address: path}}
+	f, err := NewLogForwarder(sinks, testSinkTags, logger)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	w := f.Writer(StreamStderr)
+	w.Write([]byte("hello\nwor"))
+	w.Write([]byte("ld\nunterminated"))
+	f.Close()
+
+	expected := []string{"hello", "world", "unterminated"}
+	for _, msg := range expected {
+		select {
+		case line := <-linesCh:
+			if line.Message != msg || line.Stream != StreamStderr {
+				t.Fatalf("bad line: %#v; want message %q", line, msg)
+			}
+			if line.AllocID != testSinkTags.AllocID || line.Job != testSinkTags.Job ||
+				line.Group != testSinkTags.Group || line.Task != testSinkTags.Task {
+				t.Fatalf("bad tags: %#v", line)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timeout waiting for %q", msg)
+		}
+	}
+}
+
+func TestLogForwarder_Syslog(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer conn.Close()
+
+	sinks := []*structs.LogSink{{Type: structs.LogSinkTypeSyslog, Address: "udp://" + conn.LocalAddr().String()}}
+	f, err := NewLogForwarder(sinks, testSinkTags, logger)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer f.Close()
+
+	f.Forward(StreamStderr, []byte("failed to bind"))
+
+	buf := make([]byte, 2048)
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	msg := string(buf[:n])
+	if !strings.HasPrefix(msg, "<11>1 ") {
+		t.Fatalf("bad priority and version: %q", msg)
+	}
+	parts := strings.SplitN(msg, " ", 7)
+	if len(parts) != 7 {
+		t.Fatalf("bad message: %q", msg)
+	}
+	if _, err := time.Parse(time.RFC3339Nano, parts[1]); err != nil {
+		t.Fatalf("bad timestamp %q: %v", parts[1], err)
+	}
+	if parts[3] != "redis" || parts[4] != "-" || parts[5] != "stderr" {
+		t.Fatalf("bad header: %q", msg)
+	}
+	sd := `[nomad alloc_id="5d2d0b5c-0b4a-4a2e-9e4a-3f6a2d5c1f20" job="example" group="cache" task="redis"] failed to bind`
+	if parts[6] != sd {
+		t.Fatalf("bad structured data and message: %q", parts[6])
+	}
+}
+
+func TestSyslogSender_Format(t *testing.T) {
+	s := newSyslogSender("tcp", "127.0.0.1:514", "my task")
+	line := &LogLine{
+		Time:    time.Date(2017, 5, 1, 10, 0, 0, 0, time.UTC),
+		Stream:  StreamStdout,
+		Message: "ready",
+		Job:     `a"b]c\d`,
+	}
+	s.hostname = "node1"
+
+	expected := `<14>1 2017-05-01T10:00:00.000000Z node1 my_task - stdout [nomad alloc_id="" job="a\"b\]c\\d" group="" task=""] ready`
+	if act := s.format(line); act != expected {
+		t.Fatalf("bad message:\n%s\nwant:\n%s", act, expected)
+	}
+}
+
+func TestLogForwarder_HTTP(t *testing.T) {
+	var lock sync.Mutex
+	var requests int
+	var lines []*LogLine
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+
+		// Ask for the first batch to be sent again
+		requests++
+		if requests == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+
+		if ct := r.Header.Get("Content-Type"); ct != "application/x-ndjson" {
+			t.Errorf("bad content type: %q", ct)
+		}
+		if token := r.Header.Get("Authorization"); token != "Bearer foo" {
+			t.Errorf("bad authorization header: %q", token)
+		}
+		dec := json.NewDecoder(r.Body)
+		for dec.More() {
+			var line LogLine
+			if err := dec.Decode(&line); err != nil {
+				t.Errorf("err: %v", err)
+				return
+			}
+			lines = append(lines, &line)
+		}
+	}))
+	defer ts.Close()
+
+	sinks := []*structs.LogSink{{
+		Type:      structs.LogSinkTypeHTTP,
+		Address:   ts.URL,
+		BatchSize: 2,
+		BatchWait: 50 * time.Millisecond,
+		Headers:   map[string]string{"Authorization": "Bearer foo"},
+	}}
+	f, err := NewLogForwarder(sinks, testSinkTags, logger)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	w := f.Writer(StreamStdout)
+	w.Write([]byte("a\nb\nc\n"))
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		lock.Lock()
+		n := len(lines)
+		lock.Unlock()
+		if n == 3 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("timeout waiting for lines; got %d", n)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	f.Close()
+
+	lock.Lock()
+	defer lock.Unlock()
+	for i, msg := range []string{"a", "b", "c"} {
+		if lines[i].Message != msg || lines[i].Task != "redis" {
+			t.Fatalf("bad line %d: %#v", i, lines[i])
+		}
+	}
+
+	// The rejected batch, the resent batch and the partial batch sent after
+	// the batch wait
+	if requests != 3 {
+		t.Fatalf("bad requests: %d", requests)
+	}
+}
+
+func TestSink_DropsOldestLines(t *testing.T) {
+	s := &sink{
+		batchSize:  10,
+		batchWait:  time.Hour,
+		bufferSize: 2,
+		notifyCh:   make(chan struct{}, 1),
+	}
+	for _, msg := range []string{"a", "b", "c"} {
+		s.enqueue(&LogLine{Message: msg})
+	}
+	if len(s.buf) != 2 || s.buf[0].Message != "b" || s.buf[1].Message != "c" {
+		t.Fatalf("bad buffer: %v", s.buf)
+	}
+	if s.dropped != 1 {
+		t.Fatalf("bad dropped: %d", s.dropped)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	if d := retryAfter("3"); d != 3*time.Second {
+		t.Fatalf("bad duration: %v", d)
+	}
+	if d := retryAfter(""); d != 0 {
+		t.Fatalf("bad duration: %v", d)
+	}
+	date := time.Now().Add(time.Minute).UTC().Format(http.TimeFormat)
+	if d := retryAfter(date); d <= 0 || d > time.Minute {
+		t.Fatalf("bad duration: %v", d)
+	}
+}
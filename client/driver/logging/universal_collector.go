@@ -100,6 +100,8 @@ func (s *SyslogCollector) LaunchCollector(ctx *LogCollectorContext) (*SyslogColl
 	if err != nil {
 		return nil, err
 	}
+	lro.Compress = ctx.LogConfig.Compress
+	lro.MaxAge = ctx.LogConfig.MaxAge
 	s.lro = lro
 
 	lre, err := NewFileRotator(logdir, fmt.Sprintf("%v.stderr", ctx.TaskName),
@@ -107,6 +109,8 @@ func (s *SyslogCollector) LaunchCollector(ctx *LogCollectorContext) (*SyslogColl
 	if err != nil {
 		return nil, err
 	}
+	lre.Compress = ctx.LogConfig.Compress
+	lre.MaxAge = ctx.LogConfig.MaxAge
 	s.lre = lre
 
 	go s.collectLogs(lre, lro)
@@ -144,12 +148,16 @@ func (s *SyslogCollector) UpdateLogConfig(logConfig *structs.LogConfig) error {
 	}
 	s.lro.MaxFiles = logConfig.MaxFiles
 	s.lro.FileSize = int64(logConfig.MaxFileSizeMB * 1024 * 1024)
+	s.lro.Compress = logConfig.Compress
+	s.lro.MaxAge = logConfig.MaxAge
 
 	if s.lre == nil {
 		return fmt.Errorf("log rotator for stderr doesn't exist")
 	}
 	s.lre.MaxFiles = logConfig.MaxFiles
 	s.lre.FileSize = int64(logConfig.MaxFileSizeMB * 1024 * 1024)
+	s.lre.Compress = logConfig.Compress
+	s.lre.MaxAge = logConfig.MaxAge
 	return nil
 }
 
@@ -0,0 +1,791 @@
+package driver
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/hashicorp/go-plugin"
+	"github.com/hashicorp/nomad/client/allocdir"
+	"github.com/hashicorp/nomad/client/config"
+	"github.com/hashicorp/nomad/client/driver/env"
+	"github.com/hashicorp/nomad/client/driver/executor"
+	"github.com/hashicorp/nomad/client/driver/logging"
+	dstructs "github.com/hashicorp/nomad/client/driver/structs"
+	cstructs "github.com/hashicorp/nomad/client/structs"
+	"github.com/hashicorp/nomad/helper"
+	"github.com/hashicorp/nomad/helper/fields"
+	shelpers "github.com/hashicorp/nomad/helper/stats"
+	"github.com/hashicorp/nomad/nomad/structs"
+	"github.com/mitchellh/mapstructure"
+)
+
+const (
+	// The key populated in Node Attributes to indicate presence of the Podman
+	// driver
+	podmanDriverAttr = "driver.podman"
+
+	// podmanSocketConfigOption is the key for setting the path to the Podman
+	// API socket.
+	podmanSocketConfigOption = "podman.socket"
+
+	// podmanVolumesConfigOption is the key for enabling the use of custom
+	// bind volumes to arbitrary host paths.
+	podmanVolumesConfigOption  = "podman.volumes.enabled"
+	podmanVolumesConfigDefault = true
+
+	// podmanStatsInterval is the interval at which the resource usage of
+	// containers is collected.
+	podmanStatsInterval = 1 * time.Second
+
+	// podmanLogsRetryInterval is the time waited before following the logs of
+	// a container again after the request failed.
+	podmanLogsRetryInterval = 1 * time.Second
+)
+
+var (
+	// The statistics the Podman driver exposes
+	PodmanMeasuredMemStats = []string{"RSS"}
+	PodmanMeasuredCpuStats = []string{"Percent"}
+)
+
+// PodmanDriver runs tasks as containers using the Podman API. Podman doesn't
+// require a daemon so containers can be run rootless on hosts where Docker
+// isn't allowed.
+type PodmanDriver struct {
+	DriverContext
+
+	driverConfig *PodmanDriverConfig
+
+	// A tri-state boolean to know if the fingerprinting has happened and
+	// whether it has been successful
+	fingerprintSuccess *bool
+}
+
+type PodmanDriverAuth struct {
+	Username string `mapstructure:"username"` // username for the registry
+	Password string `mapstructure:"password"` // password to access the registry
+}
+
+type PodmanDriverConfig struct {
+	ImageName  string              `mapstructure:"image"`      // Container's Image Name
+	Command    string              `mapstructure:"command"`    // The Command to run when the container starts up
+	Args       []string            `mapstructure:"args"`       // The arguments to the Command
+	PortMapRaw []map[string]int    `mapstructure:"port_map"`   //
+	PortMap    map[string]int      `mapstructure:"-"`          // A map of host port labels and the ports exposed on the container
+	Hostname   string              `mapstructure:"hostname"`   // Hostname for containers
+	LabelsRaw  []map[string]string `mapstructure:"labels"`     //
+	Labels     map[string]string   `mapstructure:"-"`          // Labels to set when the container starts up
+	Auth       []PodmanDriverAuth  `mapstructure:"auth"`       // Authentication credentials for a private registry
+	WorkDir    string              `mapstructure:"work_dir"`   // Working directory inside the container
+	Volumes    []string            `mapstructure:"volumes"`    // Host-Volumes to mount in, syntax: /path/to/host/directory:/destination/path/in/container
+	ForcePull  bool                `mapstructure:"force_pull"` // Always force pull before running image, useful if your tags are mutable
+}
+
+// Validate validates a podman driver config
+func (c *PodmanDriverConfig) Validate() error {
+	if c.ImageName == "" {
+		return fmt.Errorf("Podman Driver needs an image name")
+	}
+	if len(c.Auth) > 1 {
+		return fmt.Errorf("Only one auth block is allowed")
+	}
+
+	c.PortMap = mapMergeStrInt(c.PortMapRaw...)
+	c.Labels = mapMergeStrStr(c.LabelsRaw...)
+	return nil
+}
+
+// NewPodmanDriverConfig returns a podman driver config by parsing the HCL
+// config
+func NewPodmanDriverConfig(task *structs.Task, env *env.TaskEnvironment) (*PodmanDriverConfig, error) {
+	var pconf PodmanDriverConfig
+	if err := mapstructure.WeakDecode(task.Config, &pconf); err != nil {
+		return nil, err
+	}
+
+	// Interpolate everthing that is a string
+	pconf.ImageName = env.ReplaceEnv(pconf.ImageName)
+	pconf.Command = env.ReplaceEnv(pconf.Command)
+	pconf.Hostname = env.ReplaceEnv(pconf.Hostname)
+	pconf.WorkDir = env.ReplaceEnv(pconf.WorkDir)
+	pconf.Volumes = env.ParseAndReplace(pconf.Volumes)
+
+	for _, m := range pconf.LabelsRaw {
+		for k, v := range m {
+			delete(m, k)
+			m[env.ReplaceEnv(k)] = env.ReplaceEnv(v)
+		}
+	}
+
+	for i, a := range pconf.Auth {
+		pconf.Auth[i].Username = env.ReplaceEnv(a.Username)
+		pconf.Auth[i].Password = env.ReplaceEnv(a.Password)
+	}
+
+	for _, m := range pconf.PortMapRaw {
+		for k, v := range m {
+			delete(m, k)
+			m[env.ReplaceEnv(k)] = v
+		}
+	}
+
+	if err := pconf.Validate(); err != nil {
+		return nil, err
+	}
+	return &pconf, nil
+}
+
+type podmanPID struct {
+	Version        string
+	Image          string
+	ContainerID    string
+	KillTimeout    time.Duration
+	MaxKillTimeout time.Duration
+	LogConfig      *structs.LogConfig
+	PluginConfig   *PluginReattachConfig
+}
+
+type PodmanHandle struct {
+	pluginClient      *plugin.Client
+	executor          executor.Executor
+	client            *podmanClient
+	logger            *log.Logger
+	image             string
+	containerID       string
+	version           string
+	killTimeout       time.Duration
+	maxKillTimeout    time.Duration
+	logConfig         *structs.LogConfig
+	resourceUsageLock sync.RWMutex
+	resourceUsage     *cstructs.TaskResourceUsage
+	stdout            *logging.FileRotator
+	stderr            *logging.FileRotator
+	waitCh            chan *dstructs.WaitResult
+	doneCh            chan struct{}
+}
+
+func NewPodmanDriver(ctx *DriverContext) Driver {
+	return &PodmanDriver{DriverContext: *ctx}
+}
+
+// podmanClient returns a client for the configured socket. Without a
+// configured socket the default socket of root or of the rootless user the
+// client runs as is used.
+func (d *PodmanDriver) podmanClient() *podmanClient {
+	socket := d.config.Read(podmanSocketConfigOption)
+	if socket == "" {
+		socket = "/run/podman/podman.sock"
+		if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" && os.Getuid() != 0 {
+			socket = filepath.Join(dir, "podman", "podman.sock")
+		}
+	}
+	return newPodmanClient(socket)
+}
+
+func (d *PodmanDriver) Fingerprint(cfg *config.Config, node *structs.Node) (bool, error) {
+	client := d.podmanClient()
+	info, err := client.Info()
+	if err != nil {
+		if d.fingerprintSuccess == nil || *d.fingerprintSuccess {
+			d.logger.Printf("[DEBUG] driver.podman: could not connect to podman socket at %s: %s", client.socket, err)
+		}
+		delete(node.Attributes, podmanDriverAttr)
+		d.fingerprintSuccess = helper.BoolToPtr(false)
+		return false, nil
+	}
+
+	node.Attributes[podmanDriverAttr] = "1"
+	node.Attributes["driver.podman.version"] = info.Version.Version
+	node.Attributes["driver.podman.rootless"] = strconv.FormatBool(info.Host.Security.Rootless)
+
+	// Advertise if this node supports Podman volumes
+	if d.config.ReadBoolDefault(podmanVolumesConfigOption, podmanVolumesConfigDefault) {
+		node.Attributes["driver."+podmanVolumesConfigOption] = "1"
+	} else {
+		delete(node.Attributes, "driver."+podmanVolumesConfigOption)
+	}
+
+	d.fingerprintSuccess = helper.BoolToPtr(true)
+	return true, nil
+}
+
+func (d *PodmanDriver) Periodic() (bool, time.Duration) {
+	return true, 15 * time.Second
+}
+
+// Validate is used to validate the driver configuration
+func (d *PodmanDriver) Validate(config map[string]interface{}) error {
+	fd := &fields.FieldData{
+		Raw: config,
+		Schema: map[string]*fields.FieldSchema{
+			"image": &fields.FieldSchema{
+				Type:     fields.TypeString,
+				Required: true,
+			},
+			"command": &fields.FieldSchema{
+				Type: fields.TypeString,
+			},
+			"args": &fields.FieldSchema{
+				Type: fields.TypeArray,
+			},
+			"port_map": &fields.FieldSchema{
+				Type: fields.TypeArray,
+			},
+			"hostname": &fields.FieldSchema{
+				Type: fields.TypeString,
+			},
+			"labels": &fields.FieldSchema{
+				Type: fields.TypeArray,
+			},
+			"auth": &fields.FieldSchema{
+				Type: fields.TypeArray,
+			},
+			"work_dir": &fields.FieldSchema{
+				Type: fields.TypeString,
+			},
+			"volumes": &fields.FieldSchema{
+				Type: fields.TypeArray,
+			},
+			"force_pull": &fields.FieldSchema{
+				Type: fields.TypeBool,
+			},
+		},
+	}
+
+	if err := fd.Validate(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (d *PodmanDriver) Abilities() DriverAbilities {
+	return DriverAbilities{
+		SendSignals: true,
+	}
+}
+
+func (d *PodmanDriver) FSIsolation() cstructs.FSIsolation {
+	return cstructs.FSIsolationImage
+}
+
+func (d *PodmanDriver) Prestart(ctx *ExecContext, task *structs.Task) (*CreatedResources, error) {
+	driverConfig, err := NewPodmanDriverConfig(task, d.taskEnv)
+	if err != nil {
+		return nil, err
+	}
+
+	// Set state needed by Start()
+	d.driverConfig = driverConfig
+
+	// Ensure the image is available
+	client := d.podmanClient()
+	if !driverConfig.ForcePull {
+		exists, err := client.ImageExists(driverConfig.ImageName)
+		if err != nil {
+			return nil, structs.NewRecoverableError(fmt.Errorf("Failed to check for image %q: %v", driverConfig.ImageName, err), true)
+		}
+		if exists {
+			d.logger.Printf("[DEBUG] driver.podman: image %q is already present", driverConfig.ImageName)
+			return nil, nil
+		}
+	}
+
+	d.emitEvent("Downloading image %s", driverConfig.ImageName)
+	var auth *podmanAuth
+	if len(driverConfig.Auth) != 0 {
+		auth = &podmanAuth{
+			Username: driverConfig.Auth[0].Username,
+			Password: driverConfig.Auth[0].Password,
+		}
+	}
+	if err := client.PullImage(driverConfig.ImageName, auth); err != nil {
+		d.logger.Printf("[ERR] driver.podman: failed pulling image %q: %v", driverConfig.ImageName, err)
+		return nil, structs.NewRecoverableError(fmt.Errorf("Failed to pull %q: %v", driverConfig.ImageName, err), true)
+	}
+	d.logger.Printf("[DEBUG] driver.podman: image %q successfully pulled", driverConfig.ImageName)
+	return nil, nil
+}
+
+func (d *PodmanDriver) Start(ctx *ExecContext, task *structs.Task) (DriverHandle, error) {
+	spec, err := d.createContainerSpec(ctx, task, d.driverConfig)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create container configuration for image %q: %v", d.driverConfig.ImageName, err)
+	}
+
+	pluginLogFile := filepath.Join(ctx.TaskDir.Dir, "executor.out")
+	executorConfig := &dstructs.ExecutorConfig{
+		LogFile:  pluginLogFile,
+		LogLevel: d.config.LogLevel,
+	}
+	exec, pluginClient, err := createExecutor(d.config.LogOutput, d.config, executorConfig)
+	if err != nil {
+		return nil, err
+	}
+	executorCtx := &executor.ExecutorContext{
+		TaskEnv:        d.taskEnv,
+		Task:           task,
+		Driver:         "podman",
+		AllocID:        d.DriverContext.allocID,
+		LogDir:         ctx.TaskDir.LogDir,
+		TaskDir:        ctx.TaskDir.Dir,
+		PortLowerBound: d.config.ClientMinPort,
+		PortUpperBound: d.config.ClientMaxPort,
+	}
+	if err := exec.SetContext(executorCtx); err != nil {
+		pluginClient.Kill()
+		return nil, fmt.Errorf("failed to set executor context: %v", err)
+	}
+
+	client := d.podmanClient()
+	id, err := client.CreateContainer(spec)
+	if err != nil {
+		wrapped := fmt.Sprintf("Failed to create container: %v", err)
+		d.logger.Printf("[ERR] driver.podman: %s", wrapped)
+		pluginClient.Kill()
+		return nil, structs.WrapRecoverable(wrapped, err)
+	}
+	d.logger.Printf("[INFO] driver.podman: created container %s", id)
+
+	if err := client.StartContainer(id); err != nil {
+		d.logger.Printf("[ERR] driver.podman: failed to start container %s: %s", id, err)
+		pluginClient.Kill()
+		client.RemoveContainer(id)
+		return nil, fmt.Errorf("Failed to start container %s: %s", id, err)
+	}
+	d.logger.Printf("[INFO] driver.podman: started container %s", id)
+
+	maxKill := d.DriverContext.config.MaxKillTimeout
+	h := &PodmanHandle{
+		client:         client,
+		executor:       exec,
+		pluginClient:   pluginClient,
+		logger:         d.logger,
+		image:          d.driverConfig.ImageName,
+		containerID:    id,
+		version:        d.config.Version,
+		killTimeout:    GetKillTimeout(task.KillTimeout, maxKill),
+		maxKillTimeout: maxKill,
+		logConfig:      task.LogConfig,
+		doneCh:         make(chan struct{}),
+		waitCh:         make(chan *dstructs.WaitResult, 1),
+	}
+	if err := h.openLogs(ctx.TaskDir.LogDir, task.Name); err != nil {
+		d.logger.Printf("[ERR] driver.podman: %v", err)
+	}
+	if err := exec.SyncServices(consulContext(d.config, "")); err != nil {
+		d.logger.Printf("[ERR] driver.podman: error registering services with consul for task: %q: %v", task.Name, err)
+	}
+	go h.collectLogs(time.Time{})
+	go h.collectStats()
+	go h.run()
+	return h, nil
+}
+
+func (d *PodmanDriver) Cleanup(*ExecContext, *CreatedResources) error { return nil }
+
+// createContainerSpec returns the specification of the task's container.
+func (d *PodmanDriver) createContainerSpec(ctx *ExecContext, task *structs.Task,
+	driverConfig *PodmanDriverConfig) (*podmanContainerSpec, error) {
+	if task.Resources == nil {
+		// Guard against missing resources. We should never have been able to
+		// schedule a job without specifying this.
+		return nil, fmt.Errorf("task.Resources is empty")
+	}
+
+	mounts, err := d.containerMounts(driverConfig, ctx.TaskDir)
+	if err != nil {
+		return nil, err
+	}
+
+	spec := &podmanContainerSpec{
+		Name:     fmt.Sprintf("%s-%s", task.Name, d.DriverContext.allocID),
+		Image:    driverConfig.ImageName,
+		WorkDir:  driverConfig.WorkDir,
+		Hostname: driverConfig.Hostname,
+		Labels:   driverConfig.Labels,
+		Mounts:   mounts,
+		ResourceLimits: &podmanResourceLimits{
+			// Convert MB to bytes. This is an absolute value.
+			Memory: &podmanMemoryLimits{Limit: int64(task.Resources.MemoryMB) * 1024 * 1024},
+			// Convert Mhz to shares. This is a relative value.
+			CPU: &podmanCPULimits{Shares: uint64(task.Resources.CPU)},
+		},
+	}
+
+	// Join the network namespace shared by the allocation. Ports are
+	// forwarded into the namespace by the client so none are published.
+	if ctx.NetworkIsolation != nil {
+		if len(driverConfig.PortMap) > 0 {
+			return nil, fmt.Errorf("port_map can't be used when the task group network has its own namespace")
+		}
+		spec.NetNS = &podmanNamespace{NSMode: "path", Value: ctx.NetworkIsolation.Path}
+	} else if len(task.Resources.Networks) == 0 {
+		if len(driverConfig.PortMap) > 0 {
+			return nil, fmt.Errorf("Trying to map ports but no network interface is available")
+		}
+	} else {
+		network := task.Resources.Networks[0]
+		for _, ports := range [][]structs.Port{network.ReservedPorts, network.DynamicPorts} {
+			for _, port := range ports {
+				// By default we will map the allocated port 1:1 to the container
+				containerPort := port.Value
+				if mapped, ok := driverConfig.PortMap[port.Label]; ok {
+					containerPort = mapped
+				}
+				for _, proto := range []string{"tcp", "udp"} {
+					spec.PortMappings = append(spec.PortMappings, podmanPortMapping{
+						HostIP:        network.IP,
+						HostPort:      port.Value,
+						ContainerPort: containerPort,
+						Protocol:      proto,
+					})
+				}
+				d.logger.Printf("[DEBUG] driver.podman: allocated port %s:%d -> %d", network.IP, port.Value, containerPort)
+			}
+		}
+		d.taskEnv.SetPortMap(driverConfig.PortMap)
+	}
+
+	d.taskEnv.Build()
+	if driverConfig.Command != "" {
+		// Validate command
+		if err := validateCommand(driverConfig.Command, "args"); err != nil {
+			return nil, err
+		}
+		spec.Command = append([]string{driverConfig.Command}, d.taskEnv.ParseAndReplace(driverConfig.Args)...)
+	} else if len(driverConfig.Args) != 0 {
+		spec.Command = d.taskEnv.ParseAndReplace(driverConfig.Args)
+	}
+	spec.Env = d.taskEnv.EnvMap()
+	return spec, nil
+}
+
+// containerMounts returns the bind mounts of the task's directories and of
+// the volumes of the task.
+func (d *PodmanDriver) containerMounts(driverConfig *PodmanDriverConfig, taskDir *allocdir.TaskDir) ([]podmanMount, error) {
+	bind := func(src, dst string, options ...string) podmanMount {
+		return podmanMount{
+			Type:        "bind",
+			Source:      src,
+			Destination: dst,
+			Options:     append([]string{"rbind"}, options...),
+		}
+	}
+
+	mounts := []podmanMount{
+		bind(taskDir.SharedAllocDir, allocdir.SharedAllocContainerPath),
+		bind(taskDir.LocalDir, allocdir.TaskLocalContainerPath),
+		bind(taskDir.SecretsDir, allocdir.TaskSecretsContainerPath),
+	}
+
+	volumesEnabled := d.config.ReadBoolDefault(podmanVolumesConfigOption, podmanVolumesConfigDefault)
+	for _, volume := range driverConfig.Volumes {
+		parts := strings.Split(volume, ":")
+		if len(parts) < 2 || len(parts) > 3 {
+			return nil, fmt.Errorf("invalid podman volume: %q", volume)
+		}
+
+		var options []string
+		if len(parts) == 3 {
+			options = strings.Split(parts[2], ",")
+		}
+
+		// Resolve dotted path segments
+		src := filepath.Clean(parts[0])
+
+		// Relative paths are always allowed as they mount within the task
+		// directory
+		if filepath.IsAbs(src) {
+			if !volumesEnabled {
+				return nil, fmt.Errorf("%s is false; cannot mount host paths: %+q", podmanVolumesConfigOption, volume)
+			}
+		} else {
+			src = filepath.Join(taskDir.Dir, src)
+		}
+		mounts = append(mounts, bind(src, parts[1], options...))
+	}
+	return mounts, nil
+}
+
+func (d *PodmanDriver) Open(ctx *ExecContext, handleID string) (DriverHandle, error) {
+	pid := &podmanPID{}
+	if err := json.Unmarshal([]byte(strings.TrimPrefix(handleID, "PODMAN:")), pid); err != nil {
+		return nil, fmt.Errorf("Failed to parse handle '%s': %v", handleID, err)
+	}
+	d.logger.Printf("[INFO] driver.podman: re-attaching to container: %s", pid.ContainerID)
+
+	client := d.podmanClient()
+	container, err := client.InspectContainer(pid.ContainerID)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to find container %s: %v", pid.ContainerID, err)
+	}
+
+	pluginConfig := &plugin.ClientConfig{
+		Reattach: pid.PluginConfig.PluginConfig(),
+	}
+	exec, pluginClient, err := createExecutorWithConfig(pluginConfig, d.config.LogOutput)
+	if err != nil {
+		d.logger.Printf("[INFO] driver.podman: couldn't re-attach to the plugin process: %v", err)
+		if e := client.StopContainer(pid.ContainerID, pid.KillTimeout); e != nil {
+			d.logger.Printf("[DEBUG] driver.podman: couldn't stop container: %v", e)
+		}
+		return nil, err
+	}
+
+	h := &PodmanHandle{
+		client:         client,
+		executor:       exec,
+		pluginClient:   pluginClient,
+		logger:         d.logger,
+		image:          pid.Image,
+		containerID:    pid.ContainerID,
+		version:        pid.Version,
+		killTimeout:    pid.KillTimeout,
+		maxKillTimeout: pid.MaxKillTimeout,
+		logConfig:      pid.LogConfig,
+		doneCh:         make(chan struct{}),
+		waitCh:         make(chan *dstructs.WaitResult, 1),
+	}
+	if h.logConfig == nil {
+		h.logConfig = structs.DefaultLogConfig()
+	}
+	if err := h.openLogs(ctx.TaskDir.LogDir, d.DriverContext.taskName); err != nil {
+		d.logger.Printf("[ERR] driver.podman: %v", err)
+	}
+	if err := exec.SyncServices(consulContext(d.config, "")); err != nil {
+		h.logger.Printf("[ERR] driver.podman: error registering services with consul: %v", err)
+	}
+
+	// Output written while the client wasn't running is not collected
+	since := time.Time{}
+	if container.State.Running {
+		since = time.Now()
+	}
+	go h.collectLogs(since)
+	go h.collectStats()
+	go h.run()
+	return h, nil
+}
+
+func (h *PodmanHandle) ID() string {
+	pid := podmanPID{
+		Version:        h.version,
+		Image:          h.image,
+		ContainerID:    h.containerID,
+		KillTimeout:    h.killTimeout,
+		MaxKillTimeout: h.maxKillTimeout,
+		LogConfig:      h.logConfig,
+		PluginConfig:   NewPluginReattachConfig(h.pluginClient.ReattachConfig()),
+	}
+	data, err := json.Marshal(pid)
+	if err != nil {
+		h.logger.Printf("[ERR] driver.podman: failed to marshal podman PID to JSON: %s", err)
+	}
+	return fmt.Sprintf("PODMAN:%s", string(data))
+}
+
+func (h *PodmanHandle) WaitCh() chan *dstructs.WaitResult {
+	return h.waitCh
+}
+
+func (h *PodmanHandle) Update(task *structs.Task) error {
+	// Store the updated kill timeout.
+	h.killTimeout = GetKillTimeout(task.KillTimeout, h.maxKillTimeout)
+	if err := h.executor.UpdateTask(task); err != nil {
+		h.logger.Printf("[DEBUG] driver.podman: failed to update services: %v", err)
+	}
+
+	// Apply the new log configuration to the collected output
+	h.logConfig = task.LogConfig
+	fileSize := int64(task.LogConfig.MaxFileSizeMB * 1024 * 1024)
+	for _, r := range []*logging.FileRotator{h.stdout, h.stderr} {
+		if r != nil {
+			r.MaxFiles = task.LogConfig.MaxFiles
+			r.FileSize = fileSize
+			r.Compress = task.LogConfig.Compress
+			r.MaxAge = task.LogConfig.MaxAge
+		}
+	}
+
+	// Update is not possible
+	return nil
+}
+
+func (h *PodmanHandle) Signal(s os.Signal) error {
+	// Convert types
+	sysSig, ok := s.(syscall.Signal)
+	if !ok {
+		return fmt.Errorf("Failed to determine signal number")
+	}
+	return h.client.KillContainer(h.containerID, int(sysSig))
+}
+
+// Kill is used to terminate the task. The container is sent SIGTERM and is
+// killed if it hasn't exited after the kill timeout.
+func (h *PodmanHandle) Kill() error {
+	if err := h.client.StopContainer(h.containerID, h.killTimeout); err != nil {
+		// Container has already been removed.
+		if isPodmanNotFound(err) {
+			h.logger.Printf("[DEBUG] driver.podman: attempted to stop non-existent container %s", h.containerID)
+			return nil
+		}
+		h.logger.Printf("[ERR] driver.podman: failed to stop container %s: %v", h.containerID, err)
+		return fmt.Errorf("Failed to stop container %s: %s", h.containerID, err)
+	}
+	h.logger.Printf("[INFO] driver.podman: stopped container %s", h.containerID)
+	return nil
+}
+
+func (h *PodmanHandle) Stats() (*cstructs.TaskResourceUsage, error) {
+	h.resourceUsageLock.RLock()
+	defer h.resourceUsageLock.RUnlock()
+	var err error
+	if h.resourceUsage == nil {
+		err = fmt.Errorf("stats collection hasn't started yet")
+	}
+	return h.resourceUsage, err
+}
+
+func (h *PodmanHandle) run() {
+	// Wait for it...
+	exitCode, werr := h.client.WaitContainer(h.containerID)
+	if werr != nil {
+		h.logger.Printf("[ERR] driver.podman: failed to wait for %s: %v", h.containerID, werr)
+
+		// Fall back to the state of the container if it already exited
+		if container, err := h.client.InspectContainer(h.containerID); err == nil && !container.State.Running {
+			exitCode, werr = container.State.ExitCode, nil
+		}
+	}
+
+	if werr == nil && exitCode != 0 {
+		werr = fmt.Errorf("Podman container exited with non-zero exit code: %d", exitCode)
+	}
+
+	close(h.doneCh)
+
+	// Remove services
+	if err := h.executor.DeregisterServices(); err != nil {
+		h.logger.Printf("[ERR] driver.podman: error deregistering services: %v", err)
+	}
+	if err := h.executor.Exit(); err != nil {
+		h.logger.Printf("[ERR] driver.podman: failed to kill the executor: %v", err)
+	}
+	h.pluginClient.Kill()
+
+	// Remove the container
+	if err := h.client.RemoveContainer(h.containerID); err != nil {
+		h.logger.Printf("[ERR] driver.podman: error removing container: %v", err)
+	}
+
+	// Send the results
+	h.waitCh <- dstructs.NewWaitResult(exitCode, 0, werr)
+	close(h.waitCh)
+}
+
+// openLogs opens the files the output of the container is written to.
+func (h *PodmanHandle) openLogs(logDir, taskName string) error {
+	fileSize := int64(h.logConfig.MaxFileSizeMB * 1024 * 1024)
+	stdout, err := logging.NewFileRotator(logDir, fmt.Sprintf("%v.stdout", taskName),
+		h.logConfig.MaxFiles, fileSize, h.logger)
+	if err != nil {
+		return fmt.Errorf("error creating new stdout log file for %q: %v", taskName, err)
+	}
+	stderr, err := logging.NewFileRotator(logDir, fmt.Sprintf("%v.stderr", taskName),
+		h.logConfig.MaxFiles, fileSize, h.logger)
+	if err != nil {
+		stdout.Close()
+		return fmt.Errorf("error creating new stderr log file for %q: %v", taskName, err)
+	}
+	for _, r := range []*logging.FileRotator{stdout, stderr} {
+		r.Compress = h.logConfig.Compress
+		r.MaxAge = h.logConfig.MaxAge
+	}
+	h.stdout, h.stderr = stdout, stderr
+	return nil
+}
+
+// collectLogs copies the output of the container into the task's log files
+// until the container exits.
+func (h *PodmanHandle) collectLogs(since time.Time) {
+	if h.stdout == nil || h.stderr == nil {
+		return
+	}
+	defer h.stdout.Close()
+	defer h.stderr.Close()
+
+	for {
+		err := h.client.FollowLogs(h.containerID, since, h.stdout, h.stderr)
+
+		// The request ends when the container exits
+		select {
+		case <-h.doneCh:
+			return
+		default:
+		}
+
+		if err != nil {
+			h.logger.Printf("[DEBUG] driver.podman: error following logs of container %s: %v", h.containerID, err)
+		}
+		since = time.Now()
+
+		select {
+		case <-h.doneCh:
+			return
+		case <-time.After(podmanLogsRetryInterval):
+		}
+	}
+}
+
+// collectStats periodically collects the resource usage of the container.
+func (h *PodmanHandle) collectStats() {
+	numCores := runtime.NumCPU()
+	ticker := time.NewTicker(podmanStatsInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s, err := h.client.ContainerStats(h.containerID)
+			if err != nil {
+				h.logger.Printf("[DEBUG] driver.podman: error collecting stats from container %s: %v", h.containerID, err)
+				continue
+			}
+			h.resourceUsageLock.Lock()
+			h.resourceUsage = podmanResourceUsage(s, numCores)
+			h.resourceUsageLock.Unlock()
+		case <-h.doneCh:
+			return
+		}
+	}
+}
+
+// podmanResourceUsage converts the stats returned by Podman.
+func podmanResourceUsage(s *podmanStats, numCores int) *cstructs.TaskResourceUsage {
+	ms := &cstructs.MemoryStats{
+		RSS:      s.MemUsage,
+		Measured: PodmanMeasuredMemStats,
+	}
+	cs := &cstructs.CpuStats{
+		Percent:  s.CPU,
+		Measured: PodmanMeasuredCpuStats,
+	}
+	cs.TotalTicks = (cs.Percent / 100) * shelpers.TotalTicksAvailable() / float64(numCores)
+
+	return &cstructs.TaskResourceUsage{
+		ResourceUsage: &cstructs.ResourceUsage{
+			MemoryStats: ms,
+			CpuStats:    cs,
+		},
+		Timestamp: time.Now().UTC().UnixNano(),
+	}
+}
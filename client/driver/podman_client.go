@@ -0,0 +1,416 @@
+package driver
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// podmanAPIVersion is the version of the libpod REST API used by the
+	// driver.
+	podmanAPIVersion = "v1.0.0"
+
+	// podmanTimeout is the length of time a request that isn't long running
+	// can be outstanding before it is timed out.
+	podmanTimeout = 5 * time.Minute
+)
+
+// podmanAPIError is an error returned by the Podman API.
+type podmanAPIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *podmanAPIError) Error() string {
+	return fmt.Sprintf("podman API returned %d: %s", e.StatusCode, e.Message)
+}
+
+// isPodmanNotFound returns whether the error was returned because the
+// container or image doesn't exist.
+func isPodmanNotFound(err error) bool {
+	apiErr, ok := err.(*podmanAPIError)
+	return ok && apiErr.StatusCode == http.StatusNotFound
+}
+
+// podmanInfo is the subset of the system information used for fingerprinting.
+type podmanInfo struct {
+	Host struct {
+		Security struct {
+			Rootless bool `json:"rootless"`
+		} `json:"security"`
+	} `json:"host"`
+	Version struct {
+		APIVersion string `json:"APIVersion"`
+		Version    string `json:"Version"`
+	} `json:"version"`
+}
+
+// podmanAuth are the credentials used to pull an image from a private
+// registry.
+type podmanAuth struct {
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+}
+
+// podmanContainerSpec is the subset of the libpod container specification used
+// to create containers.
+type podmanContainerSpec struct {
+	Name           string                `json:"name"`
+	Image          string                `json:"image"`
+	Command        []string              `json:"command,omitempty"`
+	Env            map[string]string     `json:"env,omitempty"`
+	WorkDir        string                `json:"work_dir,omitempty"`
+	Hostname       string                `json:"hostname,omitempty"`
+	Labels         map[string]string     `json:"labels,omitempty"`
+	Mounts         []podmanMount         `json:"mounts,omitempty"`
+	PortMappings   []podmanPortMapping   `json:"portmappings,omitempty"`
+	NetNS          *podmanNamespace      `json:"netns,omitempty"`
+	ResourceLimits *podmanResourceLimits `json:"resource_limits,omitempty"`
+}
+
+type podmanMount struct {
+	Destination string   `json:"destination"`
+	Type        string   `json:"type"`
+	Source      string   `json:"source"`
+	Options     []string `json:"options,omitempty"`
+}
+
+type podmanPortMapping struct {
+	HostIP        string `json:"host_ip,omitempty"`
+	ContainerPort int    `json:"container_port"`
+	HostPort      int    `json:"host_port"`
+	Protocol      string `json:"protocol,omitempty"`
+}
+
+type podmanNamespace struct {
+	NSMode string `json:"nsmode"`
+	Value  string `json:"value,omitempty"`
+}
+
+type podmanResourceLimits struct {
+	Memory *podmanMemoryLimits `json:"memory,omitempty"`
+	CPU    *podmanCPULimits    `json:"cpu,omitempty"`
+}
+
+type podmanMemoryLimits struct {
+	Limit int64 `json:"limit"`
+}
+
+type podmanCPULimits struct {
+	Shares uint64 `json:"shares"`
+}
+
+// podmanContainer is the subset of the inspected state of a container used by
+// the driver.
+type podmanContainer struct {
+	ID    string `json:"Id"`
+	State struct {
+		Status    string
+		Running   bool
+		Pid       int
+		ExitCode  int
+		OOMKilled bool
+	}
+}
+
+// podmanStats are the resource usage statistics of a container.
+type podmanStats struct {
+	ContainerID   string
+	CPU           float64
+	CPUNano       uint64
+	CPUSystemNano uint64
+	MemUsage      uint64
+	MemLimit      uint64
+}
+
+// podmanClient talks to the Podman REST API over its unix socket.
+type podmanClient struct {
+	socket string
+
+	// client times out requests while streamClient is used for long running
+	// requests such as waiting on containers and following logs.
+	client       *http.Client
+	streamClient *http.Client
+}
+
+// newPodmanClient returns a client for the socket. The socket may be given as
+// a path or a unix:// URL.
+func newPodmanClient(socket string) *podmanClient {
+	socket = strings.TrimPrefix(socket, "unix://")
+	dial := func(_, _ string) (net.Conn, error) {
+		return net.Dial("unix", socket)
+	}
+	return &podmanClient{
+		socket: socket,
+		client: &http.Client{
+			Transport: &http.Transport{Dial: dial},
+			Timeout:   podmanTimeout,
+		},
+		streamClient: &http.Client{
+			Transport: &http.Transport{Dial: dial},
+		},
+	}
+}
+
+// do performs a request against the API and decodes the response into out
+// if it isn't nil.
+func (c *podmanClient) do(client *http.Client, method, path string, query url.Values,
+	header http.Header, in, out interface{}) (*http.Response, error) {
+
+	u := url.URL{
+		Scheme:   "http",
+		Host:     "podman",
+		Path:     fmt.Sprintf("/%s/libpod%s", podmanAPIVersion, path),
+		RawQuery: query.Encode(),
+	}
+
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode >= 400 {
+		defer resp.Body.Close()
+		var apiErr struct {
+			Message string `json:"message"`
+		}
+		data, _ := ioutil.ReadAll(resp.Body)
+		if err := json.Unmarshal(data, &apiErr); err != nil || apiErr.Message == "" {
+			apiErr.Message = strings.TrimSpace(string(data))
+		}
+		return nil, &podmanAPIError{StatusCode: resp.StatusCode, Message: apiErr.Message}
+	}
+
+	if out != nil {
+		defer resp.Body.Close()
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return nil, fmt.Errorf("failed to decode response of %s %s: %v", method, path, err)
+		}
+	}
+	return resp, nil
+}
+
+// doNoContent performs a request whose response body is ignored.
+func (c *podmanClient) doNoContent(method, path string, query url.Values) error {
+	resp, err := c.do(c.client, method, path, query, nil, nil, nil)
+	if err != nil {
+		return err
+	}
+	io.Copy(ioutil.Discard, resp.Body)
+	return resp.Body.Close()
+}
+
+// Info returns information about the Podman host.
+func (c *podmanClient) Info() (*podmanInfo, error) {
+	var info podmanInfo
+	if _, err := c.do(c.client, "GET", "/info", nil, nil, nil, &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+// ImageExists returns whether the image is present locally.
+func (c *podmanClient) ImageExists(image string) (bool, error) {
+	err := c.doNoContent("GET", fmt.Sprintf("/images/%s/exists", image), nil)
+	if isPodmanNotFound(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// PullImage pulls the image using the optional credentials.
+func (c *podmanClient) PullImage(image string, auth *podmanAuth) error {
+	header := make(http.Header)
+	if auth != nil {
+		data, err := json.Marshal(auth)
+		if err != nil {
+			return err
+		}
+		header.Set("X-Registry-Auth", base64.URLEncoding.EncodeToString(data))
+	}
+
+	query := url.Values{"reference": []string{image}}
+	resp, err := c.do(c.streamClient, "POST", "/images/pull", query, header, nil, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// The progress of the pull is streamed as JSON objects. Failures after
+	// the pull has started are reported in the stream.
+	dec := json.NewDecoder(resp.Body)
+	for {
+		var msg struct {
+			Error string `json:"error"`
+		}
+		if err := dec.Decode(&msg); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("failed to decode pull progress: %v", err)
+		}
+		if msg.Error != "" {
+			return fmt.Errorf("failed to pull image %q: %s", image, msg.Error)
+		}
+	}
+}
+
+// CreateContainer creates a container and returns its ID.
+func (c *podmanClient) CreateContainer(spec *podmanContainerSpec) (string, error) {
+	var out struct {
+		ID string `json:"Id"`
+	}
+	if _, err := c.do(c.client, "POST", "/containers/create", nil, nil, spec, &out); err != nil {
+		return "", err
+	}
+	return out.ID, nil
+}
+
+// StartContainer starts a container. Starting a running container is not an
+// error.
+func (c *podmanClient) StartContainer(id string) error {
+	return c.doNoContent("POST", fmt.Sprintf("/containers/%s/start", id), nil)
+}
+
+// InspectContainer returns the state of a container.
+func (c *podmanClient) InspectContainer(id string) (*podmanContainer, error) {
+	var container podmanContainer
+	if _, err := c.do(c.client, "GET", fmt.Sprintf("/containers/%s/json", id), nil, nil, nil, &container); err != nil {
+		return nil, err
+	}
+	return &container, nil
+}
+
+// WaitContainer blocks until the container stops and returns its exit code.
+func (c *podmanClient) WaitContainer(id string) (int, error) {
+	var exitCode int
+	query := url.Values{"condition": []string{"stopped"}}
+	if _, err := c.do(c.streamClient, "POST", fmt.Sprintf("/containers/%s/wait", id), query, nil, nil, &exitCode); err != nil {
+		return 0, err
+	}
+	return exitCode, nil
+}
+
+// StopContainer stops a container, killing it if it hasn't exited after the
+// timeout.
+func (c *podmanClient) StopContainer(id string, timeout time.Duration) error {
+	query := url.Values{"timeout": []string{strconv.Itoa(int(timeout.Seconds()))}}
+	return c.doNoContent("POST", fmt.Sprintf("/containers/%s/stop", id), query)
+}
+
+// KillContainer sends a signal to a container.
+func (c *podmanClient) KillContainer(id string, signal int) error {
+	query := url.Values{"signal": []string{strconv.Itoa(signal)}}
+	return c.doNoContent("POST", fmt.Sprintf("/containers/%s/kill", id), query)
+}
+
+// RemoveContainer force removes a container and its anonymous volumes.
+// Removing a container that doesn't exist is not an error.
+func (c *podmanClient) RemoveContainer(id string) error {
+	query := url.Values{"force": []string{"true"}, "v": []string{"true"}}
+	err := c.doNoContent("DELETE", fmt.Sprintf("/containers/%s", id), query)
+	if isPodmanNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// ContainerStats returns the current resource usage of a container.
+func (c *podmanClient) ContainerStats(id string) (*podmanStats, error) {
+	var out struct {
+		Error interface{}
+		Stats []*podmanStats
+	}
+	query := url.Values{"containers": []string{id}, "stream": []string{"false"}}
+	if _, err := c.do(c.client, "GET", "/containers/stats", query, nil, nil, &out); err != nil {
+		return nil, err
+	}
+	if out.Error != nil {
+		return nil, fmt.Errorf("failed to collect stats of container %s: %v", id, out.Error)
+	}
+	for _, s := range out.Stats {
+		if s.ContainerID == id {
+			return s, nil
+		}
+	}
+	return nil, fmt.Errorf("no stats returned for container %s", id)
+}
+
+// FollowLogs copies the output of the container written since the given time
+// to stdout and stderr until the container exits or the request fails.
+func (c *podmanClient) FollowLogs(id string, since time.Time, stdout, stderr io.Writer) error {
+	query := url.Values{
+		"follow": []string{"true"},
+		"stdout": []string{"true"},
+		"stderr": []string{"true"},
+	}
+	if !since.IsZero() {
+		query.Set("since", strconv.FormatInt(since.Unix(), 10))
+	}
+	resp, err := c.do(c.streamClient, "GET", fmt.Sprintf("/containers/%s/logs", id), query, nil, nil, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return demuxPodmanLogs(bufio.NewReader(resp.Body), stdout, stderr)
+}
+
+// demuxPodmanLogs splits the multiplexed log stream into stdout and stderr.
+// Each frame starts with an 8 byte header holding the stream in the first
+// byte and the big endian length of the frame in the last four.
+func demuxPodmanLogs(r io.Reader, stdout, stderr io.Writer) error {
+	var header [8]byte
+	for {
+		if _, err := io.ReadFull(r, header[:]); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		var w io.Writer
+		switch header[0] {
+		case 0, 1:
+			w = stdout
+		case 2:
+			w = stderr
+		default:
+			return fmt.Errorf("unexpected log stream %d", header[0])
+		}
+
+		size := int64(binary.BigEndian.Uint32(header[4:]))
+		if _, err := io.CopyN(w, r, size); err != nil {
+			return err
+		}
+	}
+}
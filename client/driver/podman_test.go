@@ -0,0 +1,307 @@
+package driver
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/nomad/client/allocdir"
+	"github.com/hashicorp/nomad/client/config"
+	dstructs "github.com/hashicorp/nomad/client/driver/structs"
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+// newFakePodman starts a server answering Podman API requests on a unix
+// socket. It returns the path of the socket and a function stopping the
+// server.
+func newFakePodman(t *testing.T, handler http.Handler) (string, func()) {
+	dir, err := ioutil.TempDir("", "podman")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	socket := filepath.Join(dir, "podman.sock")
+	l, err := net.Listen("unix", socket)
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatalf("err: %v", err)
+	}
+
+	srv := httptest.NewUnstartedServer(handler)
+	srv.Listener = l
+	srv.Start()
+	return socket, func() {
+		srv.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func podmanTestTask() *structs.Task {
+	return &structs.Task{
+		Name:   "redis-demo",
+		Driver: "podman",
+		Config: map[string]interface{}{
+			"image": "docker.io/library/redis:3.2",
+		},
+		LogConfig: &structs.LogConfig{
+			MaxFiles:      10,
+			MaxFileSizeMB: 10,
+		},
+		Resources: &structs.Resources{
+			MemoryMB: 256,
+			CPU:      512,
+			Networks: []*structs.NetworkResource{
+				&structs.NetworkResource{
+					IP:            "127.0.0.1",
+					ReservedPorts: []structs.Port{{Label: "main", Value: 6379}},
+					DynamicPorts:  []structs.Port{{Label: "REDIS", Value: 43330}},
+				},
+			},
+		},
+	}
+}
+
+func TestPodmanDriver_Fingerprint(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1.0.0/libpod/info", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"host":{"security":{"rootless":true}},"version":{"APIVersion":"1","Version":"2.0.6"}}`))
+	})
+	socket, stop := newFakePodman(t, mux)
+	defer stop()
+
+	ctx := testDriverContexts(t, &structs.Task{Name: "foo", Driver: "podman", Resources: structs.DefaultResources()})
+	defer ctx.AllocDir.Destroy()
+	ctx.DriverCtx.config.Options = map[string]string{podmanSocketConfigOption: socket}
+	d := NewPodmanDriver(ctx.DriverCtx)
+
+	node := &structs.Node{Attributes: make(map[string]string)}
+	apply, err := d.Fingerprint(&config.Config{}, node)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !apply {
+		t.Fatalf("should apply")
+	}
+	if node.Attributes[podmanDriverAttr] != "1" ||
+		node.Attributes["driver.podman.version"] != "2.0.6" ||
+		node.Attributes["driver.podman.rootless"] != "true" ||
+		node.Attributes["driver.podman.volumes.enabled"] != "1" {
+		t.Fatalf("bad attributes: %v", node.Attributes)
+	}
+
+	// Without a reachable socket the driver is disabled
+	ctx.DriverCtx.config.Options[podmanSocketConfigOption] = socket + ".missing"
+	d = NewPodmanDriver(ctx.DriverCtx)
+	apply, err = d.Fingerprint(&config.Config{}, node)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if apply {
+		t.Fatalf("should not apply")
+	}
+	if _, ok := node.Attributes[podmanDriverAttr]; ok {
+		t.Fatalf("driver attribute not removed: %v", node.Attributes)
+	}
+}
+
+func TestPodmanDriver_Prestart_PullAuth(t *testing.T) {
+	var pulled string
+	var auth podmanAuth
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1.0.0/libpod/images/docker.io/library/redis:3.2/exists", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"cause":"failed to find image","message":"no such image","response":404}`))
+	})
+	mux.HandleFunc("/v1.0.0/libpod/images/pull", func(w http.ResponseWriter, r *http.Request) {
+		pulled = r.URL.Query().Get("reference")
+		data, _ := base64.URLEncoding.DecodeString(r.Header.Get("X-Registry-Auth"))
+		json.Unmarshal(data, &auth)
+		w.Write([]byte(`{"stream":"Trying to pull docker.io/library/redis:3.2...\n"}` + "\n"))
+		w.Write([]byte(`{"images":["abc"],"id":"abc"}` + "\n"))
+	})
+	socket, stop := newFakePodman(t, mux)
+	defer stop()
+
+	task := podmanTestTask()
+	task.Config["auth"] = []map[string]interface{}{
+		{"username": "user", "password": "secret"},
+	}
+	ctx := testDriverContexts(t, task)
+	defer ctx.AllocDir.Destroy()
+	ctx.DriverCtx.config.Options = map[string]string{podmanSocketConfigOption: socket}
+	d := NewPodmanDriver(ctx.DriverCtx)
+
+	if _, err := d.Prestart(ctx.ExecCtx, task); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if pulled != "docker.io/library/redis:3.2" {
+		t.Fatalf("bad pulled image: %q", pulled)
+	}
+	if auth.Username != "user" || auth.Password != "secret" {
+		t.Fatalf("bad auth: %#v", auth)
+	}
+}
+
+func TestPodmanDriver_Prestart_PullError(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1.0.0/libpod/images/pull", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"error":"unauthorized: authentication required"}` + "\n"))
+	})
+	socket, stop := newFakePodman(t, mux)
+	defer stop()
+
+	task := podmanTestTask()
+	task.Config["force_pull"] = true
+	ctx := testDriverContexts(t, task)
+	defer ctx.AllocDir.Destroy()
+	ctx.DriverCtx.config.Options = map[string]string{podmanSocketConfigOption: socket}
+	d := NewPodmanDriver(ctx.DriverCtx)
+
+	_, err := d.Prestart(ctx.ExecCtx, task)
+	if err == nil {
+		t.Fatalf("expected error")
+	}
+	if rerr, ok := err.(*structs.RecoverableError); !ok || !rerr.Recoverable {
+		t.Fatalf("expected a recoverable error; got %#v", err)
+	}
+}
+
+func TestPodmanDriver_CreateContainerSpec(t *testing.T) {
+	task := podmanTestTask()
+	task.Config["port_map"] = []map[string]interface{}{{"REDIS": 6380}}
+	task.Config["command"] = "redis-server"
+	task.Config["args"] = []string{"--port", "${NOMAD_PORT_REDIS}"}
+	task.Config["volumes"] = []string{"/etc/ssl:/etc/ssl:ro", "data:/data"}
+	ctx := testDriverContexts(t, task)
+	defer ctx.AllocDir.Destroy()
+	d := NewPodmanDriver(ctx.DriverCtx).(*PodmanDriver)
+
+	driverConfig, err := NewPodmanDriverConfig(task, d.taskEnv)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	spec, err := d.createContainerSpec(ctx.ExecCtx, task, driverConfig)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	if spec.Image != "docker.io/library/redis:3.2" {
+		t.Fatalf("bad image: %q", spec.Image)
+	}
+	if exp := []string{"redis-server", "--port", "6380"}; !reflect.DeepEqual(spec.Command, exp) {
+		t.Fatalf("bad command: %v", spec.Command)
+	}
+	if spec.Env["NOMAD_PORT_REDIS"] != "6380" || spec.Env["NOMAD_HOST_PORT_REDIS"] != "43330" {
+		t.Fatalf("bad env: %v", spec.Env)
+	}
+	if spec.ResourceLimits.Memory.Limit != 256*1024*1024 || spec.ResourceLimits.CPU.Shares != 512 {
+		t.Fatalf("bad limits: %#v", spec.ResourceLimits)
+	}
+
+	expPorts := []podmanPortMapping{
+		{HostIP: "127.0.0.1", HostPort: 6379, ContainerPort: 6379, Protocol: "tcp"},
+		{HostIP: "127.0.0.1", HostPort: 6379, ContainerPort: 6379, Protocol: "udp"},
+		{HostIP: "127.0.0.1", HostPort: 43330, ContainerPort: 6380, Protocol: "tcp"},
+		{HostIP: "127.0.0.1", HostPort: 43330, ContainerPort: 6380, Protocol: "udp"},
+	}
+	if !reflect.DeepEqual(spec.PortMappings, expPorts) {
+		t.Fatalf("bad port mappings: %#v", spec.PortMappings)
+	}
+
+	taskDir := ctx.ExecCtx.TaskDir
+	expMounts := []podmanMount{
+		{Type: "bind", Source: taskDir.SharedAllocDir, Destination: allocdir.SharedAllocContainerPath, Options: []string{"rbind"}},
+		{Type: "bind", Source: taskDir.LocalDir, Destination: allocdir.TaskLocalContainerPath, Options: []string{"rbind"}},
+		{Type: "bind", Source: taskDir.SecretsDir, Destination: allocdir.TaskSecretsContainerPath, Options: []string{"rbind"}},
+		{Type: "bind", Source: "/etc/ssl", Destination: "/etc/ssl", Options: []string{"rbind", "ro"}},
+		{Type: "bind", Source: filepath.Join(taskDir.Dir, "data"), Destination: "/data", Options: []string{"rbind"}},
+	}
+	if !reflect.DeepEqual(spec.Mounts, expMounts) {
+		t.Fatalf("bad mounts: %#v", spec.Mounts)
+	}
+
+	// Host volumes can be disabled
+	d.config.Options = map[string]string{podmanVolumesConfigOption: "false"}
+	if _, err := d.createContainerSpec(ctx.ExecCtx, task, driverConfig); err == nil {
+		t.Fatalf("expected error mounting host path")
+	}
+}
+
+func TestPodmanDriver_CreateContainerSpec_NetworkIsolation(t *testing.T) {
+	task := podmanTestTask()
+	ctx := testDriverContexts(t, task)
+	defer ctx.AllocDir.Destroy()
+	ctx.ExecCtx.NetworkIsolation = &dstructs.NetworkIsolationSpec{Path: "/var/run/netns/alloc"}
+	d := NewPodmanDriver(ctx.DriverCtx).(*PodmanDriver)
+
+	driverConfig, err := NewPodmanDriverConfig(task, d.taskEnv)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	spec, err := d.createContainerSpec(ctx.ExecCtx, task, driverConfig)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if exp := (&podmanNamespace{NSMode: "path", Value: "/var/run/netns/alloc"}); !reflect.DeepEqual(spec.NetNS, exp) {
+		t.Fatalf("bad netns: %#v", spec.NetNS)
+	}
+	if len(spec.PortMappings) != 0 {
+		t.Fatalf("ports should not be published: %#v", spec.PortMappings)
+	}
+
+	driverConfig.PortMap = map[string]int{"REDIS": 6380}
+	if _, err := d.createContainerSpec(ctx.ExecCtx, task, driverConfig); err == nil {
+		t.Fatalf("expected error using port_map")
+	}
+}
+
+func TestPodmanDriver_Validate(t *testing.T) {
+	d := NewPodmanDriver(NewEmptyDriverContext())
+	if err := d.Validate(map[string]interface{}{"image": "redis"}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := d.Validate(map[string]interface{}{"command": "redis-server"}); err == nil {
+		t.Fatalf("expected error without image")
+	}
+	if err := d.Validate(map[string]interface{}{"image": "redis", "network_mode": "host"}); err == nil {
+		t.Fatalf("expected error with unknown key")
+	}
+}
+
+func TestDemuxPodmanLogs(t *testing.T) {
+	var stream bytes.Buffer
+	frame := func(fd byte, data string) {
+		header := make([]byte, 8)
+		header[0] = fd
+		binary.BigEndian.PutUint32(header[4:], uint32(len(data)))
+		stream.Write(header)
+		stream.WriteString(data)
+	}
+	frame(1, "hello\n")
+	frame(2, "oops\n")
+	frame(1, "world\n")
+
+	var stdout, stderr bytes.Buffer
+	if err := demuxPodmanLogs(&stream, &stdout, &stderr); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if stdout.String() != "hello\nworld\n" || stderr.String() != "oops\n" {
+		t.Fatalf("bad output: %q %q", stdout.String(), stderr.String())
+	}
+
+	// Truncated frames are an error
+	stream.Reset()
+	frame(1, "hello\n")
+	stream.Truncate(stream.Len() - 2)
+	if err := demuxPodmanLogs(&stream, &stdout, &stderr); err == nil {
+		t.Fatalf("expected error")
+	}
+}
@@ -249,9 +249,10 @@ func (d *QemuDriver) Start(ctx *ExecContext, task *structs.Task) (DriverHandle,
 	}
 
 	execCmd := &executor.ExecCommand{
-		Cmd:  args[0],
-		Args: args[1:],
-		User: task.User,
+		Cmd:              args[0],
+		Args:             args[1:],
+		User:             task.User,
+		NetworkNamespace: ctx.NetworkNamespace(),
 	}
 	ps, err := exec.LaunchCmd(execCmd)
 	if err != nil {
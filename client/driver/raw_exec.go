@@ -140,9 +140,10 @@ func (d *RawExecDriver) Start(ctx *ExecContext, task *structs.Task) (DriverHandl
 	}
 
 	execCmd := &executor.ExecCommand{
-		Cmd:  command,
-		Args: driverConfig.Args,
-		User: task.User,
+		Cmd:              command,
+		Args:             driverConfig.Args,
+		User:             task.User,
+		NetworkNamespace: ctx.NetworkNamespace(),
 	}
 	ps, err := exec.LaunchCmd(execCmd)
 	if err != nil {
@@ -429,9 +429,10 @@ func (d *RktDriver) Start(ctx *ExecContext, task *structs.Task) (DriverHandle, e
 	}
 
 	execCmd := &executor.ExecCommand{
-		Cmd:  absPath,
-		Args: cmdArgs,
-		User: task.User,
+		Cmd:              absPath,
+		Args:             cmdArgs,
+		User:             task.User,
+		NetworkNamespace: ctx.NetworkNamespace(),
 	}
 	ps, err := execIntf.LaunchCmd(execCmd)
 	if err != nil {
@@ -65,3 +65,14 @@ type ExecutorConfig struct {
 	// LogLevel is the level of the logs to putout
 	LogLevel string
 }
+
+// NetworkIsolationSpec describes the network namespace that is shared by all
+// the tasks of an allocation.
+type NetworkIsolationSpec struct {
+	// Path is the path to the network namespace
+	Path string
+
+	// Labels holds driver specific metadata about how the namespace was
+	// created, such as the ID of the container holding it.
+	Labels map[string]string
+}
@@ -0,0 +1,390 @@
+package driver
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/hashicorp/nomad/client/allocdir"
+	"github.com/hashicorp/nomad/client/config"
+	"github.com/hashicorp/nomad/client/driver/logging"
+	dstructs "github.com/hashicorp/nomad/client/driver/structs"
+	"github.com/hashicorp/nomad/client/fingerprint"
+	cstructs "github.com/hashicorp/nomad/client/structs"
+	"github.com/hashicorp/nomad/helper/fields"
+	"github.com/hashicorp/nomad/nomad/structs"
+	"github.com/mitchellh/mapstructure"
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+	"github.com/tetratelabs/wazero/sys"
+)
+
+const (
+	// The key populated in Node Attributes to indicate presence of the wasm
+	// driver
+	wasmDriverAttr = "driver.wasm"
+
+	// wasmPageSize is the size of a page of WebAssembly linear memory.
+	wasmPageSize = 64 * 1024
+
+	// wasmMaxPages is the maximum number of pages a 32-bit WebAssembly memory
+	// can address.
+	wasmMaxPages = 65536
+
+	// wasmStartFunction is the function WASI commands export as their entry
+	// point.
+	wasmStartFunction = "_start"
+)
+
+// WasmDriver runs WebAssembly modules using an embedded WASI runtime. Modules
+// are run inside the client, which avoids the cost of starting a process or
+// container for small tasks.
+type WasmDriver struct {
+	DriverContext
+	fingerprint.StaticFingerprinter
+}
+
+type WasmDriverConfig struct {
+	Module string   `mapstructure:"module"` // Path of the module relative to the task directory
+	Args   []string `mapstructure:"args"`   // The arguments passed to the module
+}
+
+// wasmHandle is returned from Start as a handle to the running module
+type wasmHandle struct {
+	version        string
+	module         string
+	killTimeout    time.Duration
+	maxKillTimeout time.Duration
+	logger         *log.Logger
+	runtime        wazero.Runtime
+	cancel         context.CancelFunc
+	stdout         *logging.FileRotator
+	stderr         *logging.FileRotator
+	waitCh         chan *dstructs.WaitResult
+	doneCh         chan struct{}
+}
+
+// wasmID is the handle ID of a wasm task.
+type wasmID struct {
+	Version        string
+	Module         string
+	KillTimeout    time.Duration
+	MaxKillTimeout time.Duration
+}
+
+// NewWasmDriver is used to create a new wasm driver
+func NewWasmDriver(ctx *DriverContext) Driver {
+	return &WasmDriver{DriverContext: *ctx}
+}
+
+// Validate is used to validate the driver configuration
+func (d *WasmDriver) Validate(config map[string]interface{}) error {
+	fd := &fields.FieldData{
+		Raw: config,
+		Schema: map[string]*fields.FieldSchema{
+			"module": &fields.FieldSchema{
+				Type:     fields.TypeString,
+				Required: true,
+			},
+			"args": &fields.FieldSchema{
+				Type: fields.TypeArray,
+			},
+		},
+	}
+
+	if err := fd.Validate(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (d *WasmDriver) Abilities() DriverAbilities {
+	return DriverAbilities{
+		SendSignals: false,
+	}
+}
+
+// FSIsolation is image based as modules only see the directories mounted into
+// their filesystem.
+func (d *WasmDriver) FSIsolation() cstructs.FSIsolation {
+	return cstructs.FSIsolationImage
+}
+
+func (d *WasmDriver) Fingerprint(cfg *config.Config, node *structs.Node) (bool, error) {
+	// The runtime is embedded so the driver is always available
+	node.Attributes[wasmDriverAttr] = "1"
+	return true, nil
+}
+
+func (d *WasmDriver) Prestart(*ExecContext, *structs.Task) (*CreatedResources, error) {
+	return nil, nil
+}
+
+func (d *WasmDriver) Start(ctx *ExecContext, task *structs.Task) (DriverHandle, error) {
+	var driverConfig WasmDriverConfig
+	if err := mapstructure.WeakDecode(task.Config, &driverConfig); err != nil {
+		return nil, err
+	}
+
+	// Modules can't be reached by Consul health checks or the executor
+	// registering services, so reject tasks relying on them
+	if len(task.Services) > 0 {
+		return nil, fmt.Errorf("wasm driver does not support services")
+	}
+
+	modulePath, err := wasmModulePath(ctx.TaskDir.Dir, d.taskEnv.ReplaceEnv(driverConfig.Module))
+	if err != nil {
+		return nil, err
+	}
+	bin, err := ioutil.ReadFile(modulePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read module: %v", err)
+	}
+
+	rcfg := wazero.NewRuntimeConfig().
+		WithCloseOnContextDone(true).
+		WithMemoryLimitPages(wasmMemoryLimitPages(task.Resources.MemoryMB))
+	if d.config.StateDir != "" {
+		cache, err := wazero.NewCompilationCacheWithDir(filepath.Join(d.config.StateDir, "wasm"))
+		if err != nil {
+			d.logger.Printf("[WARN] driver.wasm: failed to create compilation cache: %v", err)
+		} else {
+			rcfg = rcfg.WithCompilationCache(cache)
+		}
+	}
+
+	runCtx, cancel := context.WithCancel(context.Background())
+	runtime := wazero.NewRuntimeWithConfig(runCtx, rcfg)
+	if _, err := wasi_snapshot_preview1.Instantiate(runCtx, runtime); err != nil {
+		cancel()
+		runtime.Close(context.Background())
+		return nil, fmt.Errorf("failed to instantiate WASI: %v", err)
+	}
+	compiled, err := runtime.CompileModule(runCtx, bin)
+	if err != nil {
+		cancel()
+		runtime.Close(context.Background())
+		return nil, fmt.Errorf("failed to compile module: %v", err)
+	}
+
+	h := &wasmHandle{
+		version:        d.config.Version,
+		module:         driverConfig.Module,
+		killTimeout:    GetKillTimeout(task.KillTimeout, d.DriverContext.config.MaxKillTimeout),
+		maxKillTimeout: d.DriverContext.config.MaxKillTimeout,
+		logger:         d.logger,
+		runtime:        runtime,
+		cancel:         cancel,
+		doneCh:         make(chan struct{}),
+		waitCh:         make(chan *dstructs.WaitResult, 1),
+	}
+	if err := h.openLogs(ctx.TaskDir.LogDir, task.Name, task.LogConfig); err != nil {
+		cancel()
+		runtime.Close(context.Background())
+		return nil, err
+	}
+
+	// The task directory is the root of the module's filesystem with the
+	// shared alloc directory mounted at the same path as in containers
+	fsConfig := wazero.NewFSConfig().
+		WithDirMount(ctx.TaskDir.Dir, "/").
+		WithDirMount(ctx.TaskDir.SharedAllocDir, allocdir.SharedAllocContainerPath)
+
+	mcfg := wazero.NewModuleConfig().
+		WithName(task.Name).
+		WithArgs(append([]string{filepath.Base(modulePath)}, d.taskEnv.ParseAndReplace(driverConfig.Args)...)...).
+		WithStdout(h.stdout).
+		WithStderr(h.stderr).
+		WithFSConfig(fsConfig).
+		WithSysWalltime().
+		WithSysNanotime().
+		WithSysNanosleep().
+		WithRandSource(rand.Reader).
+		WithStartFunctions()
+	for k, v := range d.taskEnv.EnvMap() {
+		mcfg = mcfg.WithEnv(k, v)
+	}
+
+	module, err := runtime.InstantiateModule(runCtx, compiled, mcfg)
+	if err != nil {
+		h.closeLogs()
+		cancel()
+		runtime.Close(context.Background())
+		return nil, fmt.Errorf("failed to instantiate module: %v", err)
+	}
+	start := module.ExportedFunction(wasmStartFunction)
+	if start == nil {
+		h.closeLogs()
+		cancel()
+		runtime.Close(context.Background())
+		return nil, fmt.Errorf("module doesn't export a %q function", wasmStartFunction)
+	}
+
+	d.logger.Printf("[DEBUG] driver.wasm: started module %q for task %q", driverConfig.Module, task.Name)
+	go h.run(runCtx, start.Call)
+	return h, nil
+}
+
+func (d *WasmDriver) Cleanup(*ExecContext, *CreatedResources) error { return nil }
+
+// Open fails as modules run inside the client and exit with it. The task is
+// started again by the task runner.
+func (d *WasmDriver) Open(ctx *ExecContext, handleID string) (DriverHandle, error) {
+	id := &wasmID{}
+	if err := json.Unmarshal([]byte(strings.TrimPrefix(handleID, "WASM:")), id); err != nil {
+		return nil, fmt.Errorf("Failed to parse handle '%s': %v", handleID, err)
+	}
+	return nil, fmt.Errorf("wasm module %q did not survive the client restart", id.Module)
+}
+
+// wasmModulePath returns the path of the module on the host. The module must
+// be inside the task directory, usually downloaded there using an artifact.
+func wasmModulePath(taskDir, module string) (string, error) {
+	if module == "" {
+		return "", fmt.Errorf("missing module")
+	}
+	path := filepath.Join(taskDir, module)
+	rel, err := filepath.Rel(taskDir, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("module %q escapes the task directory", module)
+	}
+	return path, nil
+}
+
+// wasmMemoryLimitPages returns the number of pages of linear memory a module
+// may use given the memory resources of the task in megabytes.
+func wasmMemoryLimitPages(memoryMB int) uint32 {
+	pages := memoryMB * (1024 * 1024 / wasmPageSize)
+	if pages <= 0 || pages > wasmMaxPages {
+		return wasmMaxPages
+	}
+	return uint32(pages)
+}
+
+func (h *wasmHandle) ID() string {
+	id := wasmID{
+		Version:        h.version,
+		Module:         h.module,
+		KillTimeout:    h.killTimeout,
+		MaxKillTimeout: h.maxKillTimeout,
+	}
+	data, err := json.Marshal(id)
+	if err != nil {
+		h.logger.Printf("[ERR] driver.wasm: failed to marshal ID to JSON: %s", err)
+	}
+	return fmt.Sprintf("WASM:%s", string(data))
+}
+
+func (h *wasmHandle) WaitCh() chan *dstructs.WaitResult {
+	return h.waitCh
+}
+
+func (h *wasmHandle) Update(task *structs.Task) error {
+	// Store the updated kill timeout.
+	h.killTimeout = GetKillTimeout(task.KillTimeout, h.maxKillTimeout)
+
+	// Apply the new log configuration to the module's output
+	fileSize := int64(task.LogConfig.MaxFileSizeMB * 1024 * 1024)
+	for _, r := range []*logging.FileRotator{h.stdout, h.stderr} {
+		r.MaxFiles = task.LogConfig.MaxFiles
+		r.FileSize = fileSize
+		r.Compress = task.LogConfig.Compress
+		r.MaxAge = task.LogConfig.MaxAge
+	}
+
+	// Update is not possible
+	return nil
+}
+
+func (h *wasmHandle) Signal(s os.Signal) error {
+	return fmt.Errorf("wasm driver does not support signals")
+}
+
+// Kill is used to terminate the task. Modules can't be signalled so they are
+// given the kill timeout to exit before they are cancelled.
+func (h *wasmHandle) Kill() error {
+	select {
+	case <-h.doneCh:
+		return nil
+	case <-time.After(h.killTimeout):
+		h.cancel()
+	}
+
+	select {
+	case <-h.doneCh:
+		return nil
+	case <-time.After(5 * time.Second):
+		return fmt.Errorf("wasm module %q did not exit after being cancelled", h.module)
+	}
+}
+
+func (h *wasmHandle) Stats() (*cstructs.TaskResourceUsage, error) {
+	return nil, DriverStatsNotImplemented
+}
+
+func (h *wasmHandle) run(ctx context.Context, start func(context.Context, ...uint64) ([]uint64, error)) {
+	_, err := start(ctx)
+	killed := ctx.Err() != nil
+	h.cancel()
+	close(h.doneCh)
+
+	exitCode, signal := 0, 0
+	if exitErr, ok := err.(*sys.ExitError); ok {
+		switch code := exitErr.ExitCode(); {
+		case killed:
+			signal, err = int(syscall.SIGKILL), nil
+		case code != 0:
+			exitCode = int(code)
+			err = fmt.Errorf("wasm module exited with non-zero exit code: %d", exitCode)
+		default:
+			err = nil
+		}
+	} else if killed {
+		signal, err = int(syscall.SIGKILL), nil
+	}
+
+	if err := h.runtime.Close(context.Background()); err != nil {
+		h.logger.Printf("[ERR] driver.wasm: failed to close runtime: %v", err)
+	}
+	h.closeLogs()
+
+	// Send the results
+	h.waitCh <- dstructs.NewWaitResult(exitCode, signal, err)
+	close(h.waitCh)
+}
+
+// openLogs opens the files the output of the module is written to.
+func (h *wasmHandle) openLogs(logDir, taskName string, logConfig *structs.LogConfig) error {
+	fileSize := int64(logConfig.MaxFileSizeMB * 1024 * 1024)
+	stdout, err := logging.NewFileRotator(logDir, fmt.Sprintf("%v.stdout", taskName),
+		logConfig.MaxFiles, fileSize, h.logger)
+	if err != nil {
+		return fmt.Errorf("error creating new stdout log file for %q: %v", taskName, err)
+	}
+	stderr, err := logging.NewFileRotator(logDir, fmt.Sprintf("%v.stderr", taskName),
+		logConfig.MaxFiles, fileSize, h.logger)
+	if err != nil {
+		stdout.Close()
+		return fmt.Errorf("error creating new stderr log file for %q: %v", taskName, err)
+	}
+	for _, r := range []*logging.FileRotator{stdout, stderr} {
+		r.Compress = logConfig.Compress
+		r.MaxAge = logConfig.MaxAge
+	}
+	h.stdout, h.stderr = stdout, stderr
+	return nil
+}
+
+func (h *wasmHandle) closeLogs() {
+	h.stdout.Close()
+	h.stderr.Close()
+}
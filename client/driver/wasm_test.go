@@ -0,0 +1,229 @@
+package driver
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/nomad/client/config"
+	"github.com/hashicorp/nomad/nomad/structs"
+	"github.com/hashicorp/nomad/testutil"
+)
+
+// wasmTestTask returns a task running the given module from the wasm test
+// resources.
+func wasmTestTask(module string) *structs.Task {
+	return &structs.Task{
+		Name:   "transform",
+		Driver: "wasm",
+		Config: map[string]interface{}{
+			"module": filepath.Join("local", module),
+		},
+		LogConfig: &structs.LogConfig{
+			MaxFiles:      10,
+			MaxFileSizeMB: 10,
+		},
+		Resources: basicResources,
+	}
+}
+
+// wasmTestStart starts the task after copying its module into the task
+// directory.
+func wasmTestStart(t *testing.T, ctx *testContext, task *structs.Task) (DriverHandle, error) {
+	module := filepath.Base(task.Config["module"].(string))
+	dst := filepath.Join(ctx.ExecCtx.TaskDir.LocalDir, module)
+	copyFile(filepath.Join("./test-resources/wasm", module), dst, t)
+
+	d := NewWasmDriver(ctx.DriverCtx)
+	if _, err := d.Prestart(ctx.ExecCtx, task); err != nil {
+		t.Fatalf("prestart err: %v", err)
+	}
+	return d.Start(ctx.ExecCtx, task)
+}
+
+func TestWasmDriver_Fingerprint(t *testing.T) {
+	task := &structs.Task{Name: "foo", Driver: "wasm", Resources: structs.DefaultResources()}
+	ctx := testDriverContexts(t, task)
+	defer ctx.AllocDir.Destroy()
+	d := NewWasmDriver(ctx.DriverCtx)
+	node := &structs.Node{
+		Attributes: make(map[string]string),
+	}
+	apply, err := d.Fingerprint(&config.Config{}, node)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !apply {
+		t.Fatalf("should apply")
+	}
+	if node.Attributes["driver.wasm"] == "" {
+		t.Fatalf("missing driver")
+	}
+}
+
+func TestWasmDriver_Start_Wait(t *testing.T) {
+	task := wasmTestTask("hello.wasm")
+	ctx := testDriverContexts(t, task)
+	defer ctx.AllocDir.Destroy()
+
+	handle, err := wasmTestStart(t, ctx, task)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	select {
+	case res := <-handle.WaitCh():
+		if !res.Successful() {
+			t.Fatalf("err: %v", res)
+		}
+	case <-time.After(time.Duration(testutil.TestMultiplier()*5) * time.Second):
+		t.Fatalf("timeout")
+	}
+
+	// The output of the module is written to the task's logs
+	stdout, err := ioutil.ReadFile(filepath.Join(ctx.ExecCtx.TaskDir.LogDir, "transform.stdout.0"))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if string(stdout) != "hello world\n" {
+		t.Fatalf("bad stdout: %q", stdout)
+	}
+
+	if !strings.HasPrefix(handle.ID(), "WASM:") {
+		t.Fatalf("bad ID: %s", handle.ID())
+	}
+}
+
+func TestWasmDriver_Start_ExitCode(t *testing.T) {
+	task := wasmTestTask("exit.wasm")
+	ctx := testDriverContexts(t, task)
+	defer ctx.AllocDir.Destroy()
+
+	handle, err := wasmTestStart(t, ctx, task)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	select {
+	case res := <-handle.WaitCh():
+		if res.ExitCode != 3 || res.Err == nil {
+			t.Fatalf("bad result: %v", res)
+		}
+	case <-time.After(time.Duration(testutil.TestMultiplier()*5) * time.Second):
+		t.Fatalf("timeout")
+	}
+
+	stderr, err := ioutil.ReadFile(filepath.Join(ctx.ExecCtx.TaskDir.LogDir, "transform.stderr.0"))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if string(stderr) != "oops\n" {
+		t.Fatalf("bad stderr: %q", stderr)
+	}
+}
+
+func TestWasmDriver_Start_Kill_Wait(t *testing.T) {
+	task := wasmTestTask("loop.wasm")
+	task.KillTimeout = 100 * time.Millisecond
+	ctx := testDriverContexts(t, task)
+	defer ctx.AllocDir.Destroy()
+
+	handle, err := wasmTestStart(t, ctx, task)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// The module must keep running until it is killed
+	select {
+	case res := <-handle.WaitCh():
+		t.Fatalf("module exited: %v", res)
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	start := time.Now()
+	if err := handle.Kill(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < task.KillTimeout {
+		t.Fatalf("module cancelled before the kill timeout: %v", elapsed)
+	}
+
+	select {
+	case res := <-handle.WaitCh():
+		if res.Successful() || res.Signal != int(syscall.SIGKILL) {
+			t.Fatalf("bad result: %v", res)
+		}
+	case <-time.After(time.Duration(testutil.TestMultiplier()*5) * time.Second):
+		t.Fatalf("timeout")
+	}
+}
+
+func TestWasmDriver_Start_MemoryLimit(t *testing.T) {
+	// The module requires 64MB of memory
+	task := wasmTestTask("memory.wasm")
+	task.Resources = &structs.Resources{MemoryMB: 32}
+	ctx := testDriverContexts(t, task)
+	defer ctx.AllocDir.Destroy()
+
+	if _, err := wasmTestStart(t, ctx, task); err == nil {
+		t.Fatalf("expected error exceeding the memory limit")
+	}
+
+	task.Resources.MemoryMB = 64
+	handle, err := wasmTestStart(t, ctx, task)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	select {
+	case res := <-handle.WaitCh():
+		if !res.Successful() {
+			t.Fatalf("err: %v", res)
+		}
+	case <-time.After(time.Duration(testutil.TestMultiplier()*5) * time.Second):
+		t.Fatalf("timeout")
+	}
+}
+
+func TestWasmDriver_Validate(t *testing.T) {
+	d := NewWasmDriver(NewEmptyDriverContext())
+	if err := d.Validate(map[string]interface{}{"module": "local/transform.wasm"}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := d.Validate(map[string]interface{}{"args": []string{"foo"}}); err == nil {
+		t.Fatalf("expected error without module")
+	}
+}
+
+func TestWasmModulePath(t *testing.T) {
+	path, err := wasmModulePath("/alloc/task", "local/transform.wasm")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if path != "/alloc/task/local/transform.wasm" {
+		t.Fatalf("bad path: %s", path)
+	}
+
+	for _, module := range []string{"", "../other/local/transform.wasm", "local/../../transform.wasm"} {
+		if _, err := wasmModulePath("/alloc/task", module); err == nil {
+			t.Fatalf("expected error for %q", module)
+		}
+	}
+}
+
+func TestWasmMemoryLimitPages(t *testing.T) {
+	cases := map[int]uint32{
+		0:      65536,
+		1:      16,
+		256:    4096,
+		4096:   65536,
+		100000: 65536,
+	}
+	for mb, pages := range cases {
+		if act := wasmMemoryLimitPages(mb); act != pages {
+			t.Fatalf("bad pages for %dMB: %d; want %d", mb, act, pages)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"strconv"
+
+	"github.com/hashicorp/nomad/command/agent/consul"
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+const (
+	// envoyBootstrapFile is the name of the file holding the Envoy bootstrap
+	// configuration inside the sidecar task's secret directory
+	envoyBootstrapFile = "envoy_bootstrap.json"
+
+	// envoyLocalAgentCluster is the name of the Envoy cluster pointing at the
+	// local Consul agent's gRPC endpoint
+	envoyLocalAgentCluster = "local_agent"
+)
+
+// buildEnvoyBootstrap returns the Envoy bootstrap configuration of the Connect
+// sidecar proxy of the given service in the allocation. The proxy retrieves
+// its listeners and clusters from the local Consul agent at grpcAddr.
+func buildEnvoyBootstrap(alloc *structs.Allocation, service, grpcAddr, token string) ([]byte, error) {
+	tg := alloc.Job.LookupTaskGroup(alloc.TaskGroup)
+	if tg == nil {
+		return nil, fmt.Errorf("task group %q not found", alloc.TaskGroup)
+	}
+
+	// Find the task registering the service to derive the proxy's service ID
+	var proxyID string
+	for _, task := range tg.Tasks {
+		for _, s := range task.Services {
+			if s.Name == service && s.Connect.HasSidecar() {
+				proxyID = consul.ConnectProxyServiceID(consul.NewExecutorDomain(alloc.ID, task.Name), s)
+			}
+		}
+	}
+	if proxyID == "" {
+		return nil, fmt.Errorf("no Connect enabled service %q in task group %q", service, tg.Name)
+	}
+
+	host, portStr, err := net.SplitHostPort(grpcAddr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Consul gRPC address %q: %v", grpcAddr, err)
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Consul gRPC address %q: %v", grpcAddr, err)
+	}
+
+	grpcService := map[string]interface{}{
+		"envoy_grpc": map[string]interface{}{
+			"cluster_name": envoyLocalAgentCluster,
+		},
+	}
+	if token != "" {
+		grpcService["initial_metadata"] = []map[string]string{
+			{"key": "x-consul-token", "value": token},
+		}
+	}
+
+	bootstrap := map[string]interface{}{
+		"admin": map[string]interface{}{
+			"access_log_path": "/dev/null",
+			"address": map[string]interface{}{
+				"socket_address": map[string]interface{}{
+					"address":    "127.0.0.1",
+					"port_value": 0,
+				},
+			},
+		},
+		"node": map[string]interface{}{
+			"cluster": service,
+			"id":      proxyID,
+		},
+		"static_resources": map[string]interface{}{
+			"clusters": []map[string]interface{}{
+				{
+					"name":                   envoyLocalAgentCluster,
+					"connect_timeout":        "1s",
+					"type":                   "STATIC",
+					"http2_protocol_options": map[string]interface{}{},
+					"hosts": []map[string]interface{}{
+						{
+							"socket_address": map[string]interface{}{
+								"address":    host,
+								"port_value": port,
+							},
+						},
+					},
+				},
+			},
+		},
+		"dynamic_resources": map[string]interface{}{
+			"lds_config": map[string]interface{}{"ads": map[string]interface{}{}},
+			"cds_config": map[string]interface{}{"ads": map[string]interface{}{}},
+			"ads_config": map[string]interface{}{
+				"api_type":      "GRPC",
+				"grpc_services": grpcService,
+			},
+		},
+	}
+
+	return json.MarshalIndent(bootstrap, "", "  ")
+}
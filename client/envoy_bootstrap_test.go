@@ -0,0 +1,74 @@
+package client
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/hashicorp/nomad/command/agent/consul"
+	"github.com/hashicorp/nomad/nomad/mock"
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+func TestEnvoyBootstrap(t *testing.T) {
+	alloc := mock.Alloc()
+	task := alloc.Job.TaskGroups[0].Tasks[0]
+	service := task.Services[0]
+	service.Name = "web-frontend"
+	service.Connect = &structs.ConsulConnect{
+		SidecarService: &structs.ConsulSidecarService{},
+	}
+
+	out, err := buildEnvoyBootstrap(alloc, "web-frontend", "127.0.0.1:8502", "secret")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	var bootstrap struct {
+		Node struct {
+			Cluster string
+			ID      string
+		}
+		StaticResources struct {
+			Clusters []struct {
+				Name  string
+				Hosts []struct {
+					SocketAddress struct {
+						Address   string
+						PortValue int `json:"port_value"`
+					} `json:"socket_address"`
+				}
+			}
+		} `json:"static_resources"`
+		DynamicResources struct {
+			AdsConfig struct {
+				GrpcServices struct {
+					InitialMetadata []map[string]string `json:"initial_metadata"`
+				} `json:"grpc_services"`
+			} `json:"ads_config"`
+		} `json:"dynamic_resources"`
+	}
+	if err := json.Unmarshal(out, &bootstrap); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	expID := consul.ConnectProxyServiceID(consul.NewExecutorDomain(alloc.ID, task.Name), service)
+	if bootstrap.Node.ID != expID || bootstrap.Node.Cluster != "web-frontend" {
+		t.Fatalf("bad node: %#v", bootstrap.Node)
+	}
+	clusters := bootstrap.StaticResources.Clusters
+	if len(clusters) != 1 || len(clusters[0].Hosts) != 1 {
+		t.Fatalf("bad clusters: %#v", clusters)
+	}
+	if addr := clusters[0].Hosts[0].SocketAddress; addr.Address != "127.0.0.1" || addr.PortValue != 8502 {
+		t.Fatalf("bad local agent address: %#v", addr)
+	}
+	md := bootstrap.DynamicResources.AdsConfig.GrpcServices.InitialMetadata
+	if len(md) != 1 || md[0]["value"] != "secret" {
+		t.Fatalf("bad token metadata: %#v", md)
+	}
+
+	// Unknown services are an error
+	if _, err := buildEnvoyBootstrap(alloc, "unknown", "127.0.0.1:8502", ""); err == nil {
+		t.Fatalf("expected error for unknown service")
+	}
+}
@@ -0,0 +1,262 @@
+package getter
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// caches is the set of artifact caches keyed by their directory. It is
+	// shared by all the task runners of a client so that an artifact is only
+	// downloaded once.
+	caches    = make(map[string]*artifactCache)
+	cachesMtx sync.Mutex
+)
+
+const (
+	// cacheTempPrefix is the prefix of the directories artifacts are
+	// downloaded into before being added to the cache
+	cacheTempPrefix = ".fetch-"
+)
+
+// artifactCache is a content addressable cache of downloaded artifacts. Each
+// entry is stored in its own directory named after the key of the artifact.
+// Once the cache grows beyond its maximum size, the least recently used
+// entries that are not in use are evicted.
+type artifactCache struct {
+	dir     string
+	maxSize int64
+	entries map[string]*cacheEntry
+	lock    sync.Mutex
+}
+
+// cacheEntry is a single cached artifact.
+type cacheEntry struct {
+	key      string
+	path     string
+	size     int64
+	lastUsed time.Time
+
+	// refs is the number of downloads using the entry. Entries in use are
+	// never evicted.
+	refs int
+
+	// ready is closed once the artifact is downloaded and err is set if the
+	// download failed.
+	ready chan struct{}
+	err   error
+}
+
+// getCache returns the artifact cache stored in the given directory, loading
+// the entries left by previous runs of the client.
+func getCache(dir string, maxSizeMB int) (*artifactCache, error) {
+	cachesMtx.Lock()
+	defer cachesMtx.Unlock()
+
+	maxSize := int64(maxSizeMB) * 1024 * 1024
+	if c, ok := caches[dir]; ok {
+		c.lock.Lock()
+		c.maxSize = maxSize
+		c.lock.Unlock()
+		return c, nil
+	}
+
+	c, err := newArtifactCache(dir, maxSize)
+	if err != nil {
+		return nil, err
+	}
+	caches[dir] = c
+	return c, nil
+}
+
+func newArtifactCache(dir string, maxSize int64) (*artifactCache, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create artifact cache directory: %v", err)
+	}
+
+	c := &artifactCache{
+		dir:     dir,
+		maxSize: maxSize,
+		entries: make(map[string]*cacheEntry),
+	}
+
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read artifact cache directory: %v", err)
+	}
+	for _, info := range infos {
+		entryDir := filepath.Join(dir, info.Name())
+
+		// Remove interrupted downloads and anything that isn't an entry
+		if !info.IsDir() || strings.HasPrefix(info.Name(), cacheTempPrefix) {
+			os.RemoveAll(entryDir)
+			continue
+		}
+
+		files, err := ioutil.ReadDir(entryDir)
+		if err != nil || len(files) != 1 || !files[0].Mode().IsRegular() {
+			os.RemoveAll(entryDir)
+			continue
+		}
+
+		ready := make(chan struct{})
+		close(ready)
+		c.entries[info.Name()] = &cacheEntry{
+			key:      info.Name(),
+			path:     filepath.Join(entryDir, files[0].Name()),
+			size:     files[0].Size(),
+			lastUsed: files[0].ModTime(),
+			ready:    ready,
+		}
+	}
+
+	c.lock.Lock()
+	c.evict()
+	c.lock.Unlock()
+	return c, nil
+}
+
+// acquire returns the cache entry of the artifact with the given key and file
+// name, using fetch to download it into the given path if it isn't cached.
+// Concurrent acquires of the same artifact share a single download. The
+// returned entry must be released once it is no longer used.
+func (c *artifactCache) acquire(key, name string, fetch func(dst string) error) (*cacheEntry, error) {
+	c.lock.Lock()
+	e, ok := c.entries[key]
+	if !ok {
+		e = &cacheEntry{
+			key:   key,
+			path:  filepath.Join(c.dir, key, name),
+			ready: make(chan struct{}),
+		}
+		c.entries[key] = e
+	}
+	e.refs++
+	c.lock.Unlock()
+
+	if !ok {
+		c.fill(e, fetch)
+	}
+	<-e.ready
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if e.err != nil {
+		e.refs--
+		return nil, e.err
+	}
+
+	// Record the use on disk so that it is kept across restarts
+	e.lastUsed = time.Now()
+	os.Chtimes(e.path, e.lastUsed, e.lastUsed)
+	return e, nil
+}
+
+// fill downloads the artifact of the entry and moves it into the cache.
+func (c *artifactCache) fill(e *cacheEntry, fetch func(dst string) error) {
+	defer close(e.ready)
+
+	size, err := c.download(e, fetch)
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if err != nil {
+		// Remove the entry so the download is retried
+		e.err = err
+		delete(c.entries, e.key)
+		return
+	}
+	e.size = size
+	c.evict()
+}
+
+func (c *artifactCache) download(e *cacheEntry, fetch func(dst string) error) (int64, error) {
+	tmp, err := ioutil.TempDir(c.dir, cacheTempPrefix)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create artifact cache entry: %v", err)
+	}
+	defer os.RemoveAll(tmp)
+
+	dst := filepath.Join(tmp, filepath.Base(e.path))
+	if err := fetch(dst); err != nil {
+		return 0, err
+	}
+	info, err := os.Stat(dst)
+	if err != nil {
+		return 0, err
+	}
+
+	entryDir := filepath.Dir(e.path)
+	if err := os.RemoveAll(entryDir); err != nil {
+		return 0, err
+	}
+	if err := os.Rename(tmp, entryDir); err != nil {
+		return 0, fmt.Errorf("failed to add artifact to cache: %v", err)
+	}
+	return info.Size(), nil
+}
+
+// release marks the entry as no longer used by a download.
+func (c *artifactCache) release(e *cacheEntry) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	e.refs--
+	c.evict()
+}
+
+// size returns the total size of the cached artifacts. The lock must be
+// held.
+func (c *artifactCache) size() int64 {
+	var total int64
+	for _, e := range c.entries {
+		total += e.size
+	}
+	return total
+}
+
+// evict removes the least recently used entries that are not in use until the
+// cache fits its maximum size. The lock must be held.
+func (c *artifactCache) evict() {
+	total := c.size()
+	if total <= c.maxSize {
+		return
+	}
+
+	var unused []*cacheEntry
+	for _, e := range c.entries {
+		if e.refs == 0 {
+			unused = append(unused, e)
+		}
+	}
+	sort.Sort(cacheEntriesByLastUsed(unused))
+
+	for _, e := range unused {
+		if total <= c.maxSize {
+			return
+		}
+		delete(c.entries, e.key)
+		os.RemoveAll(filepath.Dir(e.path))
+		total -= e.size
+	}
+}
+
+// cacheEntriesByLastUsed sorts cache entries from the least recently used.
+type cacheEntriesByLastUsed []*cacheEntry
+
+func (c cacheEntriesByLastUsed) Len() int {
+	return len(c)
+}
+
+func (c cacheEntriesByLastUsed) Less(i, j int) bool {
+	return c[i].lastUsed.Before(c[j].lastUsed)
+}
+
+func (c cacheEntriesByLastUsed) Swap(i, j int) {
+	c[i], c[j] = c[j], c[i]
+}
@@ -0,0 +1,138 @@
+package getter
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestArtifactCache_Acquire(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nomad-test")
+	if err != nil {
+		t.Fatalf("failed to make temp directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	c, err := newArtifactCache(dir, 1024)
+	if err != nil {
+		t.Fatalf("bad: %v", err)
+	}
+
+	// Concurrent acquires share a single download
+	var lock sync.Mutex
+	fetches := 0
+	fetch := func(dst string) error {
+		lock.Lock()
+		fetches++
+		lock.Unlock()
+		return ioutil.WriteFile(dst, []byte("foo"), 0644)
+	}
+
+	var wg sync.WaitGroup
+	entries := make([]*cacheEntry, 5)
+	for i := range entries {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			e, err := c.acquire("a", "file", fetch)
+			if err != nil {
+				t.Errorf("acquire failed: %v", err)
+			}
+			entries[i] = e
+		}(i)
+	}
+	wg.Wait()
+
+	if fetches != 1 {
+		t.Fatalf("fetched %d times; want 1", fetches)
+	}
+	for _, e := range entries {
+		if e != entries[0] {
+			t.Fatalf("acquires returned different entries")
+		}
+		c.release(e)
+	}
+	if e := entries[0]; e.refs != 0 || e.size != 3 || e.path != filepath.Join(dir, "a", "file") {
+		t.Fatalf("bad entry: %#v", e)
+	}
+
+	// Failed downloads are not cached
+	if _, err := c.acquire("b", "file", func(string) error { return fmt.Errorf("failed") }); err == nil {
+		t.Fatalf("acquire should have failed")
+	}
+	if _, ok := c.entries["b"]; ok {
+		t.Fatalf("failed download should not be cached")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "b")); !os.IsNotExist(err) {
+		t.Fatalf("failed download should be removed: %v", err)
+	}
+}
+
+func TestArtifactCache_Evict(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nomad-test")
+	if err != nil {
+		t.Fatalf("failed to make temp directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	c, err := newArtifactCache(dir, 10)
+	if err != nil {
+		t.Fatalf("bad: %v", err)
+	}
+	fetch := func(dst string) error {
+		return ioutil.WriteFile(dst, []byte("12345"), 0644)
+	}
+
+	// Fill the cache, using a before b
+	a, err := c.acquire("a", "file", fetch)
+	if err != nil {
+		t.Fatalf("bad: %v", err)
+	}
+	c.release(a)
+	b, err := c.acquire("b", "file", fetch)
+	if err != nil {
+		t.Fatalf("bad: %v", err)
+	}
+	c.release(b)
+	if a, err = c.acquire("a", "file", fetch); err != nil {
+		t.Fatalf("bad: %v", err)
+	}
+
+	// Adding c evicts the least recently used entry that isn't in use
+	cEntry, err := c.acquire("c", "file", fetch)
+	if err != nil {
+		t.Fatalf("bad: %v", err)
+	}
+	if _, ok := c.entries["b"]; ok {
+		t.Fatalf("b should have been evicted")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "b")); !os.IsNotExist(err) {
+		t.Fatalf("b should be removed from disk: %v", err)
+	}
+	c.release(a)
+	c.release(cEntry)
+
+	// Entries are loaded back from disk
+	c2, err := newArtifactCache(dir, 10)
+	if err != nil {
+		t.Fatalf("bad: %v", err)
+	}
+	if len(c2.entries) != 2 || c2.entries["a"] == nil || c2.entries["c"] == nil {
+		t.Fatalf("bad entries: %#v", c2.entries)
+	}
+	if c2.size() != 10 {
+		t.Fatalf("bad size: %d", c2.size())
+	}
+
+	// Shrinking the cache evicts on load
+	c3, err := newArtifactCache(dir, 5)
+	if err != nil {
+		t.Fatalf("bad: %v", err)
+	}
+	if len(c3.entries) != 1 {
+		t.Fatalf("bad entries: %#v", c3.entries)
+	}
+}
@@ -1,8 +1,12 @@
 package getter
 
 import (
+	"crypto/sha256"
 	"fmt"
+	"io/ioutil"
 	"net/url"
+	"os"
+	"os/exec"
 	"path/filepath"
 	"strings"
 	"sync"
@@ -10,6 +14,7 @@ import (
 	gg "github.com/hashicorp/go-getter"
 	"github.com/hashicorp/nomad/client/driver/env"
 	"github.com/hashicorp/nomad/nomad/structs"
+	"github.com/hashicorp/nomad/nomad/structs/config"
 )
 
 var (
@@ -20,6 +25,10 @@ var (
 
 	// supported is the set of download schemes supported by Nomad
 	supported = []string{"http", "https", "s3", "hg", "git"}
+
+	// localGetters is used to copy verified or cached artifacts into the
+	// task directory
+	localGetters = map[string]gg.Getter{"file": &gg.FileGetter{Copy: true}}
 )
 
 const (
@@ -84,19 +93,213 @@ func getGetterUrl(taskEnv *env.TaskEnvironment, artifact *structs.TaskArtifact)
 	return url, nil
 }
 
-// GetArtifact downloads an artifact into the specified task directory.
-func GetArtifact(taskEnv *env.TaskEnvironment, artifact *structs.TaskArtifact, taskDir string) error {
+// GetArtifact downloads an artifact into the specified task directory. The
+// artifact config controls checksum enforcement, caching and signature
+// verification and may be nil.
+func GetArtifact(taskEnv *env.TaskEnvironment, artifact *structs.TaskArtifact, taskDir string, conf *config.ArtifactConfig) error {
 	url, err := getGetterUrl(taskEnv, artifact)
 	if err != nil {
 		return newGetError(artifact.GetterSource, err, false)
 	}
 
-	// Download the artifact
+	// Repositories are checked out at a revision rather than verified by a
+	// checksum so only files are required to have one.
+	checksum := taskEnv.ReplaceEnv(artifact.GetterOptions["checksum"])
+	if checksum == "" && conf.ChecksumRequired() && !isRepository(url) {
+		return newGetError(url, fmt.Errorf("artifact must specify a checksum"), false)
+	}
+
+	// Download the artifact directly unless it is cached or verified
 	dest := filepath.Join(taskDir, artifact.RelativeDest)
-	if err := getClient(url, dest).Get(); err != nil {
-		return newGetError(url, err, true)
+	cached := checksum != "" && conf.CacheEnabled()
+	if !cached && artifact.GetterSignature == "" {
+		if err := getClient(url, dest).Get(); err != nil {
+			return newGetError(url, err, true)
+		}
+		return nil
+	}
+
+	// Download the file without unarchiving it so it can be verified and
+	// cached as is
+	raw := artifact.Copy()
+	if raw.GetterOptions == nil {
+		raw.GetterOptions = make(map[string]string, 1)
+	}
+	archive := raw.GetterOptions["archive"]
+	raw.GetterOptions["archive"] = "false"
+	rawURL, err := getGetterUrl(taskEnv, raw)
+	if err != nil {
+		return newGetError(artifact.GetterSource, err, false)
+	}
+	name, err := fileName(rawURL)
+	if err != nil {
+		return newGetError(url, err, false)
+	}
+	fetch := func(dst string) error {
+		client := getClient(rawURL, dst)
+		client.Mode = gg.ClientModeFile
+		return client.Get()
+	}
+
+	var path string
+	if cached {
+		cache, err := getCache(conf.CacheDir, conf.CacheMaxSizeMB)
+		if err != nil {
+			return newGetError(url, err, true)
+		}
+		key, err := cacheKey(taskEnv, artifact, checksum)
+		if err != nil {
+			return newGetError(artifact.GetterSource, err, false)
+		}
+		entry, err := cache.acquire(key, name, fetch)
+		if err != nil {
+			return newGetError(url, err, true)
+		}
+		defer cache.release(entry)
+		path = entry.path
+	} else {
+		staging, err := ioutil.TempDir("", "nomad-artifact")
+		if err != nil {
+			return newGetError(url, err, true)
+		}
+		defer os.RemoveAll(staging)
+
+		path = filepath.Join(staging, name)
+		if err := fetch(path); err != nil {
+			return newGetError(url, err, true)
+		}
+	}
+
+	if artifact.GetterSignature != "" {
+		if err := verifyArtifact(taskEnv, artifact, path, conf); err != nil {
+			return err
+		}
+	}
+
+	// Copy the file into the task directory, unarchiving it if needed
+	src := path
+	if archive != "" {
+		src = fmt.Sprintf("%s?archive=%s", path, taskEnv.ReplaceEnv(archive))
+	}
+	client := &gg.Client{
+		Src:     src,
+		Dst:     dest,
+		Mode:    gg.ClientModeAny,
+		Getters: localGetters,
+	}
+	if err := client.Get(); err != nil {
+		return newGetError(url, err, false)
+	}
+
+	return nil
+}
+
+// GetPayload downloads the payload of a dispatched job from the given URL
+// into the file at dest. The payload is never unarchived.
+func GetPayload(src, dest string) error {
+	u, err := url.Parse(src)
+	if err != nil {
+		return newGetError(src, fmt.Errorf("failed to parse payload URL %q: %v", src, err), false)
+	}
+	q := u.Query()
+	q.Set("archive", "false")
+	u.RawQuery = q.Encode()
+
+	client := getClient(u.String(), dest)
+	client.Mode = gg.ClientModeFile
+	if err := client.Get(); err != nil {
+		return newGetError(src, err, true)
+	}
+	return nil
+}
+
+// isRepository returns whether the go-getter URL is a git or mercurial
+// repository.
+func isRepository(src string) bool {
+	forced, _, err := detect(src)
+	if err != nil {
+		return false
+	}
+	return forced == "git" || forced == "hg"
+}
+
+// fileName returns the name of the file the go-getter URL downloads.
+func fileName(src string) (string, error) {
+	_, u, err := detect(src)
+	if err != nil {
+		return "", err
+	}
+	name := filepath.Base(u.Path)
+	if name == "." || name == "/" {
+		return "", fmt.Errorf("artifact source %q does not name a file", src)
+	}
+	return name, nil
+}
+
+// detect returns the getter and the URL go-getter uses to download the
+// source.
+func detect(src string) (string, *url.URL, error) {
+	detected, err := gg.Detect(src, "", gg.Detectors)
+	if err != nil {
+		return "", nil, err
+	}
+
+	forced := ""
+	if idx := strings.Index(detected, "::"); idx != -1 {
+		forced, detected = detected[:idx], detected[idx+2:]
+	}
+	u, err := url.Parse(detected)
+	if err != nil {
+		return "", nil, err
+	}
+	if forced == "" {
+		forced = u.Scheme
+	}
+	return forced, u, nil
+}
+
+// cacheKey returns the key of the artifact in the cache, derived from its
+// source and checksum.
+func cacheKey(taskEnv *env.TaskEnvironment, artifact *structs.TaskArtifact, checksum string) (string, error) {
+	source := artifact.Copy()
+	delete(source.GetterOptions, "archive")
+	delete(source.GetterOptions, "checksum")
+	url, err := getGetterUrl(taskEnv, source)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(url+"\x00"+checksum))), nil
+}
+
+// verifyArtifact downloads the detached signature of the artifact and
+// verifies the downloaded file against the configured GPG keyrings.
+func verifyArtifact(taskEnv *env.TaskEnvironment, artifact *structs.TaskArtifact, path string, conf *config.ArtifactConfig) error {
+	sigURL := taskEnv.ReplaceEnv(artifact.GetterSignature)
+	if conf == nil || len(conf.GPGKeyrings) == 0 {
+		return newGetError(sigURL, fmt.Errorf("no GPG keyrings configured to verify the artifact signature"), false)
+	}
+
+	dir, err := ioutil.TempDir("", "nomad-artifact-sig")
+	if err != nil {
+		return newGetError(sigURL, err, true)
 	}
+	defer os.RemoveAll(dir)
 
+	sig := filepath.Join(dir, "artifact.sig")
+	client := getClient(sigURL, sig)
+	client.Mode = gg.ClientModeFile
+	if err := client.Get(); err != nil {
+		return newGetError(sigURL, fmt.Errorf("failed to download signature: %v", err), true)
+	}
+
+	args := []string{"--homedir", dir}
+	for _, keyring := range conf.GPGKeyrings {
+		args = append(args, "--keyring", keyring)
+	}
+	args = append(args, sig, path)
+	if out, err := exec.Command("gpgv", args...).CombinedOutput(); err != nil {
+		return newGetError(sigURL, fmt.Errorf("signature verification failed: %v: %s", err, strings.TrimSpace(string(out))), false)
+	}
 	return nil
 }
 
@@ -6,14 +6,18 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"reflect"
 	"strings"
+	"sync"
 	"testing"
 
 	"github.com/hashicorp/nomad/client/driver/env"
+	"github.com/hashicorp/nomad/helper"
 	"github.com/hashicorp/nomad/nomad/mock"
 	"github.com/hashicorp/nomad/nomad/structs"
+	"github.com/hashicorp/nomad/nomad/structs/config"
 )
 
 func TestGetArtifact_FileAndChecksum(t *testing.T) {
@@ -39,7 +43,7 @@ func TestGetArtifact_FileAndChecksum(t *testing.T) {
 
 	// Download the artifact
 	taskEnv := env.NewTaskEnvironment(mock.Node())
-	if err := GetArtifact(taskEnv, artifact, taskDir); err != nil {
+	if err := GetArtifact(taskEnv, artifact, taskDir, nil); err != nil {
 		t.Fatalf("GetArtifact failed: %v", err)
 	}
 
@@ -74,7 +78,7 @@ func TestGetArtifact_File_RelativeDest(t *testing.T) {
 
 	// Download the artifact
 	taskEnv := env.NewTaskEnvironment(mock.Node())
-	if err := GetArtifact(taskEnv, artifact, taskDir); err != nil {
+	if err := GetArtifact(taskEnv, artifact, taskDir, nil); err != nil {
 		t.Fatalf("GetArtifact failed: %v", err)
 	}
 
@@ -125,7 +129,7 @@ func TestGetArtifact_InvalidChecksum(t *testing.T) {
 
 	// Download the artifact and expect an error
 	taskEnv := env.NewTaskEnvironment(mock.Node())
-	if err := GetArtifact(taskEnv, artifact, taskDir); err == nil {
+	if err := GetArtifact(taskEnv, artifact, taskDir, nil); err == nil {
 		t.Fatalf("GetArtifact should have failed")
 	}
 }
@@ -191,7 +195,7 @@ func TestGetArtifact_Archive(t *testing.T) {
 	}
 
 	taskEnv := env.NewTaskEnvironment(mock.Node())
-	if err := GetArtifact(taskEnv, artifact, taskDir); err != nil {
+	if err := GetArtifact(taskEnv, artifact, taskDir, nil); err != nil {
 		t.Fatalf("GetArtifact failed: %v", err)
 	}
 
@@ -205,6 +209,44 @@ func TestGetArtifact_Archive(t *testing.T) {
 	checkContents(taskDir, expected, t)
 }
 
+func TestGetPayload(t *testing.T) {
+	// Create the test server hosting the payload
+	ts := httptest.NewServer(http.FileServer(http.Dir(filepath.Dir("./test-fixtures/"))))
+	defer ts.Close()
+
+	dir, err := ioutil.TempDir("", "nomad-test")
+	if err != nil {
+		t.Fatalf("failed to make temp directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Archives are downloaded as is
+	dest := filepath.Join(dir, "nested", "payload")
+	if err := GetPayload(ts.URL+"/archive.tar.gz", dest); err != nil {
+		t.Fatalf("GetPayload failed: %v", err)
+	}
+	actual, err := ioutil.ReadFile(dest)
+	if err != nil {
+		t.Fatalf("failed to read payload: %v", err)
+	}
+	expected, err := ioutil.ReadFile("./test-fixtures/archive.tar.gz")
+	if err != nil {
+		t.Fatalf("failed to read fixture: %v", err)
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("payload doesn't match the archive")
+	}
+
+	// Missing payloads are recoverable errors
+	err = GetPayload(ts.URL+"/missing", filepath.Join(dir, "missing"))
+	if err == nil {
+		t.Fatalf("expected error")
+	}
+	if gerr, ok := err.(*GetError); !ok || !gerr.IsRecoverable() {
+		t.Fatalf("bad error: %#v", err)
+	}
+}
+
 func TestGetGetterUrl_Queries(t *testing.T) {
 	taskEnv := env.NewTaskEnvironment(mock.Node())
 	cases := []struct {
@@ -303,3 +345,158 @@ func TestGetGetterUrl_Queries(t *testing.T) {
 		})
 	}
 }
+
+func TestGetArtifact_RequireChecksum(t *testing.T) {
+	taskDir, err := ioutil.TempDir("", "nomad-test")
+	if err != nil {
+		t.Fatalf("failed to make temp directory: %v", err)
+	}
+	defer os.RemoveAll(taskDir)
+
+	conf := &config.ArtifactConfig{RequireChecksum: helper.BoolToPtr(true)}
+	artifact := &structs.TaskArtifact{
+		GetterSource: "http://127.0.0.1:0/test.sh",
+	}
+
+	// Files without a checksum are rejected before being downloaded
+	taskEnv := env.NewTaskEnvironment(mock.Node())
+	err = GetArtifact(taskEnv, artifact, taskDir, conf)
+	if err == nil || !strings.Contains(err.Error(), "checksum") {
+		t.Fatalf("expected checksum error; got %v", err)
+	}
+	if err.(*GetError).IsRecoverable() {
+		t.Fatalf("missing checksum should not be recoverable")
+	}
+
+	// Repositories don't require a checksum
+	for _, src := range []string{"github.com/hashicorp/nomad", "git::https://example.com/repo.git", "hg::http://example.com/repo"} {
+		if !isRepository(src) {
+			t.Fatalf("expected %q to be a repository", src)
+		}
+	}
+	if isRepository("http://example.com/repo.tar.gz") {
+		t.Fatalf("file should not be a repository")
+	}
+}
+
+func TestGetArtifact_Cache(t *testing.T) {
+	// Create the test server counting the downloads
+	var lock sync.Mutex
+	requests := 0
+	fs := http.FileServer(http.Dir(filepath.Dir("./test-fixtures/")))
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		requests++
+		lock.Unlock()
+		fs.ServeHTTP(w, r)
+	}))
+	defer ts.Close()
+
+	cacheDir, err := ioutil.TempDir("", "nomad-test")
+	if err != nil {
+		t.Fatalf("failed to make temp directory: %v", err)
+	}
+	defer os.RemoveAll(cacheDir)
+	conf := &config.ArtifactConfig{
+		CacheDir:       cacheDir,
+		CacheMaxSizeMB: 1,
+	}
+
+	artifact := &structs.TaskArtifact{
+		GetterSource: fmt.Sprintf("%s/%s", ts.URL, "archive.tar.gz"),
+		GetterOptions: map[string]string{
+			"checksum": "sha1:20bab73c72c56490856f913cf594bad9a4d730f6",
+		},
+	}
+
+	// Download the artifact into several task directories
+	taskEnv := env.NewTaskEnvironment(mock.Node())
+	expected := map[string]string{
+		"exist/my.config": "hello world\n",
+		"new/my.config":   "hello world\n",
+		"test.sh":         "sleep 1\n",
+	}
+	for i := 0; i < 3; i++ {
+		taskDir, err := ioutil.TempDir("", "nomad-test")
+		if err != nil {
+			t.Fatalf("failed to make temp directory: %v", err)
+		}
+		defer os.RemoveAll(taskDir)
+
+		if err := GetArtifact(taskEnv, artifact, taskDir, conf); err != nil {
+			t.Fatalf("GetArtifact failed: %v", err)
+		}
+		checkContents(taskDir, expected, t)
+	}
+
+	if requests != 1 {
+		t.Fatalf("artifact downloaded %d times; want 1", requests)
+	}
+
+	// Changing the checksum is a different artifact
+	artifact.GetterOptions["checksum"] = "md5:aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"
+	taskDir, err := ioutil.TempDir("", "nomad-test")
+	if err != nil {
+		t.Fatalf("failed to make temp directory: %v", err)
+	}
+	defer os.RemoveAll(taskDir)
+	if err := GetArtifact(taskEnv, artifact, taskDir, conf); err == nil {
+		t.Fatalf("GetArtifact should have failed")
+	}
+	if requests != 2 {
+		t.Fatalf("artifact downloaded %d times; want 2", requests)
+	}
+}
+
+func TestGetArtifact_Signature(t *testing.T) {
+	if _, err := exec.LookPath("gpgv"); err != nil {
+		t.Skip("gpgv not found")
+	}
+
+	// Create the test server hosting the file to download
+	ts := httptest.NewServer(http.FileServer(http.Dir(filepath.Dir("./test-fixtures/"))))
+	defer ts.Close()
+
+	taskDir, err := ioutil.TempDir("", "nomad-test")
+	if err != nil {
+		t.Fatalf("failed to make temp directory: %v", err)
+	}
+	defer os.RemoveAll(taskDir)
+
+	keyring, err := filepath.Abs("./test-fixtures/trusted.gpg")
+	if err != nil {
+		t.Fatalf("bad: %v", err)
+	}
+	conf := &config.ArtifactConfig{GPGKeyrings: []string{keyring}}
+
+	file := "test.sh"
+	artifact := &structs.TaskArtifact{
+		GetterSource:    fmt.Sprintf("%s/%s", ts.URL, file),
+		GetterSignature: fmt.Sprintf("%s/%s.sig", ts.URL, file),
+	}
+
+	// Download the signed artifact
+	taskEnv := env.NewTaskEnvironment(mock.Node())
+	if err := GetArtifact(taskEnv, artifact, taskDir, conf); err != nil {
+		t.Fatalf("GetArtifact failed: %v", err)
+	}
+	checkContents(taskDir, map[string]string{file: "sleep 1\n"}, t)
+
+	// Verifying without keyrings fails
+	if err := GetArtifact(taskEnv, artifact, taskDir, nil); err == nil {
+		t.Fatalf("GetArtifact should have failed without keyrings")
+	}
+
+	// Verifying another file against the signature fails
+	artifact.GetterSource = fmt.Sprintf("%s/%s", ts.URL, "archive.tar.gz")
+	err = GetArtifact(taskEnv, artifact, taskDir, conf)
+	if err == nil || !strings.Contains(err.Error(), "signature verification failed") {
+		t.Fatalf("expected signature error; got %v", err)
+	}
+	if err.(*GetError).IsRecoverable() {
+		t.Fatalf("bad signature should not be recoverable")
+	}
+	if _, err := os.Stat(filepath.Join(taskDir, "archive.tar.gz")); !os.IsNotExist(err) {
+		t.Fatalf("unverified artifact should not be in the task directory: %v", err)
+	}
+}
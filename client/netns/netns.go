@@ -0,0 +1,25 @@
+// Package netns manages the network namespaces created for allocations that
+// use bridge networking.
+package netns
+
+import (
+	"errors"
+	"path/filepath"
+)
+
+const (
+	// DefaultNamespaceDir is the directory in which named network namespaces
+	// are bind mounted. It matches the location used by iproute2 so the
+	// namespaces can be inspected with `ip netns`.
+	DefaultNamespaceDir = "/var/run/netns"
+)
+
+var (
+	// ErrNotSupported is returned on platforms without network namespaces.
+	ErrNotSupported = errors.New("network namespaces are not supported on this platform")
+)
+
+// Path returns the path of the named network namespace for the allocation.
+func Path(allocID string) string {
+	return filepath.Join(DefaultNamespaceDir, allocID)
+}
@@ -0,0 +1,18 @@
+// +build !linux
+
+package netns
+
+// Create is not supported on this platform.
+func Create(allocID string) (string, error) {
+	return "", ErrNotSupported
+}
+
+// Destroy is not supported on this platform.
+func Destroy(path string) error {
+	return ErrNotSupported
+}
+
+// Do is not supported on this platform.
+func Do(path string, fn func() error) error {
+	return ErrNotSupported
+}
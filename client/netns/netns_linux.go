@@ -0,0 +1,119 @@
+package netns
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+
+	"golang.org/x/sys/unix"
+)
+
+// Create creates a new network namespace for the allocation and bind mounts
+// it so that it outlives the thread that created it. The path of the
+// namespace is returned.
+func Create(allocID string) (string, error) {
+	if err := os.MkdirAll(DefaultNamespaceDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create namespace dir: %v", err)
+	}
+
+	path := Path(allocID)
+	f, err := os.OpenFile(path, os.O_RDONLY|os.O_CREATE|os.O_EXCL, 0444)
+	if err != nil {
+		return "", fmt.Errorf("failed to create namespace file: %v", err)
+	}
+	f.Close()
+
+	// The namespace is created by unsharing a locked OS thread, which is then
+	// switched back to the original namespace.
+	errCh := make(chan error, 1)
+	go func() {
+		runtime.LockOSThread()
+
+		orig, err := os.Open(threadNamespacePath())
+		if err != nil {
+			runtime.UnlockOSThread()
+			errCh <- fmt.Errorf("failed to open current namespace: %v", err)
+			return
+		}
+		defer orig.Close()
+
+		if err := unix.Unshare(unix.CLONE_NEWNET); err != nil {
+			runtime.UnlockOSThread()
+			errCh <- fmt.Errorf("failed to unshare network namespace: %v", err)
+			return
+		}
+
+		err = unix.Mount(threadNamespacePath(), path, "none", unix.MS_BIND, "")
+
+		// If the thread can't be restored it is left locked so that the Go
+		// runtime terminates it instead of reusing it.
+		if rerr := unix.Setns(int(orig.Fd()), unix.CLONE_NEWNET); rerr != nil {
+			errCh <- fmt.Errorf("failed to restore network namespace: %v", rerr)
+			return
+		}
+		runtime.UnlockOSThread()
+
+		if err != nil {
+			err = fmt.Errorf("failed to bind mount network namespace: %v", err)
+		}
+		errCh <- err
+	}()
+
+	if err := <-errCh; err != nil {
+		os.Remove(path)
+		return "", err
+	}
+	return path, nil
+}
+
+// Destroy unmounts and removes a network namespace created by Create.
+func Destroy(path string) error {
+	if err := unix.Unmount(path, unix.MNT_DETACH); err != nil && err != unix.EINVAL && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to unmount network namespace: %v", err)
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove network namespace: %v", err)
+	}
+	return nil
+}
+
+// Do runs the function while the calling goroutine is switched into the
+// network namespace at the given path. Processes started by the function
+// inherit the namespace.
+func Do(path string, fn func() error) error {
+	ns, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open network namespace %q: %v", path, err)
+	}
+	defer ns.Close()
+
+	runtime.LockOSThread()
+
+	orig, err := os.Open(threadNamespacePath())
+	if err != nil {
+		runtime.UnlockOSThread()
+		return fmt.Errorf("failed to open current namespace: %v", err)
+	}
+	defer orig.Close()
+
+	if err := unix.Setns(int(ns.Fd()), unix.CLONE_NEWNET); err != nil {
+		runtime.UnlockOSThread()
+		return fmt.Errorf("failed to enter network namespace %q: %v", path, err)
+	}
+
+	fnErr := fn()
+
+	// If the thread can't be restored it is left locked so that the Go
+	// runtime terminates it instead of reusing it.
+	if err := unix.Setns(int(orig.Fd()), unix.CLONE_NEWNET); err != nil {
+		return fmt.Errorf("failed to restore network namespace: %v", err)
+	}
+	runtime.UnlockOSThread()
+	return fnErr
+}
+
+// threadNamespacePath returns the path of the network namespace of the
+// current thread.
+func threadNamespacePath() string {
+	return fmt.Sprintf("/proc/%d/task/%d/ns/net", os.Getpid(), unix.Gettid())
+}
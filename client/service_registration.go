@@ -0,0 +1,253 @@
+package client
+
+import (
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	consulapi "github.com/hashicorp/consul/api"
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+const (
+	// defaultServiceSyncInterval is the interval at which services of the
+	// task without checks are re-registered with the servers
+	defaultServiceSyncInterval = 30 * time.Second
+)
+
+// nomadService is a service of the task registered with the Nomad servers
+// along with the checks the client runs to determine its health.
+type nomadService struct {
+	reg    *structs.ServiceRegistration
+	checks []*structs.ServiceCheck
+
+	// registered is whether the current registration has been accepted by
+	// the servers
+	registered bool
+}
+
+// nomadServices returns the interpolated services of the task registered
+// with the Nomad servers and the interval their checks should be run at.
+func (r *TaskRunner) nomadServices() ([]*nomadService, time.Duration) {
+	env := r.getTaskEnv()
+	replace := func(s string) string {
+		if env == nil {
+			return s
+		}
+		return env.ReplaceEnv(s)
+	}
+
+	var services []*nomadService
+	interval := defaultServiceSyncInterval
+	for _, service := range r.task.Services {
+		if service.Provider != structs.ServiceProviderNomad {
+			continue
+		}
+
+		s := service.Copy()
+		s.Name = replace(s.Name)
+		s.PortLabel = replace(s.PortLabel)
+		for i, tag := range s.Tags {
+			s.Tags[i] = replace(tag)
+		}
+		host, port := r.task.FindHostAndPortFor(s.PortLabel)
+
+		for _, check := range s.Checks {
+			check.Name = replace(check.Name)
+			check.Path = replace(check.Path)
+			check.Protocol = replace(check.Protocol)
+			check.PortLabel = replace(check.PortLabel)
+			check.Method = replace(check.Method)
+			check.Body = replace(check.Body)
+			for k, v := range check.Header {
+				for i, value := range v {
+					v[i] = replace(value)
+				}
+				check.Header[k] = v
+			}
+			if check.Interval < interval {
+				interval = check.Interval
+			}
+		}
+
+		services = append(services, &nomadService{
+			reg: &structs.ServiceRegistration{
+				ID:          structs.ServiceRegistrationID(r.alloc.ID, r.task.Name, s),
+				ServiceName: s.Name,
+				NodeID:      r.config.Node.ID,
+				JobID:       r.alloc.JobID,
+				AllocID:     r.alloc.ID,
+				TaskName:    r.task.Name,
+				Datacenter:  r.config.Node.Datacenter,
+				Tags:        s.Tags,
+				Address:     host,
+				Port:        port,
+				Status:      consulapi.HealthPassing,
+			},
+			checks: s.Checks,
+		})
+	}
+	return services, interval
+}
+
+// checkStatus runs the checks of the service and returns its health. A
+// service is critical if any of its checks fails.
+func (r *TaskRunner) checkStatus(s *nomadService) string {
+	status := consulapi.HealthPassing
+	for _, check := range s.checks {
+		host, port := s.reg.Address, s.reg.Port
+		if check.PortLabel != "" {
+			host, port = r.task.FindHostAndPortFor(check.PortLabel)
+		}
+
+		var err error
+		switch check.Type {
+		case structs.ServiceCheckHTTP:
+			status, err = runHTTPCheck(check, host, port)
+		case structs.ServiceCheckTCP:
+			status, err = runTCPCheck(check, host, port)
+		default:
+			err = fmt.Errorf("unsupported check type %q", check.Type)
+			status = consulapi.HealthCritical
+		}
+		if err != nil {
+			r.logger.Printf("[DEBUG] client: check %q of service %q of task %q for alloc %q failed: %v",
+				check.Name, s.reg.ServiceName, r.task.Name, r.alloc.ID, err)
+		}
+		if status != consulapi.HealthPassing {
+			return status
+		}
+	}
+	return status
+}
+
+// runHTTPCheck runs an http check against the given address. Like Consul, a
+// 2xx response is passing, a 429 is a warning and anything else is critical.
+func runHTTPCheck(check *structs.ServiceCheck, host string, port int) (string, error) {
+	protocol := check.Protocol
+	if protocol == "" {
+		protocol = "http"
+	}
+	u := url.URL{
+		Scheme: protocol,
+		Host:   net.JoinHostPort(host, strconv.Itoa(port)),
+		Path:   check.Path,
+	}
+	method := check.Method
+	if method == "" {
+		method = "GET"
+	}
+
+	req, err := http.NewRequest(method, u.String(), bytes.NewBufferString(check.Body))
+	if err != nil {
+		return consulapi.HealthCritical, err
+	}
+	for k, v := range check.Header {
+		req.Header[k] = v
+	}
+
+	client := &http.Client{
+		Timeout: check.Timeout,
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: check.TLSSkipVerify},
+		},
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return consulapi.HealthCritical, err
+	}
+	resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return consulapi.HealthPassing, nil
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return consulapi.HealthWarning, nil
+	default:
+		return consulapi.HealthCritical, fmt.Errorf("unexpected response code %d", resp.StatusCode)
+	}
+}
+
+// runTCPCheck runs a tcp check against the given address. The check passes
+// if a connection can be established.
+func runTCPCheck(check *structs.ServiceCheck, host string, port int) (string, error) {
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort(host, strconv.Itoa(port)), check.Timeout)
+	if err != nil {
+		return consulapi.HealthCritical, err
+	}
+	conn.Close()
+	return consulapi.HealthPassing, nil
+}
+
+// registerServices registers the task's services with the Nomad servers and
+// keeps their health up to date by running their checks. Registrations are
+// only sent when the health of a service changes or a previous registration
+// failed. The services are deregistered once stopCh is closed.
+func (r *TaskRunner) registerServices(stopCh chan struct{}) {
+	services, interval := r.nomadServices()
+	if len(services) == 0 || r.rpc == nil {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		var changed []*structs.ServiceRegistration
+		for _, s := range services {
+			status := r.checkStatus(s)
+			if status != s.reg.Status || !s.registered {
+				s.reg.Status = status
+				s.registered = false
+				changed = append(changed, s.reg)
+			}
+		}
+
+		if len(changed) != 0 {
+			req := structs.ServiceRegistrationUpsertRequest{
+				Services:     changed,
+				WriteRequest: structs.WriteRequest{Region: r.config.Region},
+			}
+			var resp structs.GenericResponse
+			if err := r.rpc.RPC("ServiceRegistration.Upsert", &req, &resp); err != nil {
+				r.logger.Printf("[ERR] client: failed to register services of task %q for alloc %q: %v",
+					r.task.Name, r.alloc.ID, err)
+			} else {
+				for _, s := range services {
+					s.registered = true
+				}
+			}
+		}
+
+		select {
+		case <-stopCh:
+			r.deregisterServices(services)
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// deregisterServices removes the registrations of the task's services from
+// the Nomad servers.
+func (r *TaskRunner) deregisterServices(services []*nomadService) {
+	ids := make([]string, 0, len(services))
+	for _, s := range services {
+		ids = append(ids, s.reg.ID)
+	}
+	req := structs.ServiceRegistrationDeleteRequest{
+		NodeID:       r.config.Node.ID,
+		IDs:          ids,
+		WriteRequest: structs.WriteRequest{Region: r.config.Region},
+	}
+	var resp structs.GenericResponse
+	if err := r.rpc.RPC("ServiceRegistration.Delete", &req, &resp); err != nil {
+		r.logger.Printf("[ERR] client: failed to deregister services of task %q for alloc %q: %v",
+			r.task.Name, r.alloc.ID, err)
+	}
+}
@@ -0,0 +1,192 @@
+package client
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	consulapi "github.com/hashicorp/consul/api"
+	"github.com/hashicorp/nomad/nomad/mock"
+	"github.com/hashicorp/nomad/nomad/structs"
+	"github.com/hashicorp/nomad/testutil"
+)
+
+// serviceRegistrationRPC records the service registrations of task runners
+type serviceRegistrationRPC struct {
+	upserts [][]*structs.ServiceRegistration
+	deletes [][]string
+	sync.Mutex
+}
+
+func (m *serviceRegistrationRPC) RPC(method string, args interface{}, reply interface{}) error {
+	m.Lock()
+	defer m.Unlock()
+	switch method {
+	case "ServiceRegistration.Upsert":
+		var services []*structs.ServiceRegistration
+		for _, s := range args.(*structs.ServiceRegistrationUpsertRequest).Services {
+			services = append(services, s.Copy())
+		}
+		m.upserts = append(m.upserts, services)
+	case "ServiceRegistration.Delete":
+		m.deletes = append(m.deletes, args.(*structs.ServiceRegistrationDeleteRequest).IDs)
+	default:
+		return fmt.Errorf("unexpected RPC %q", method)
+	}
+	return nil
+}
+
+// lastStatus returns the status of the last registration of the service
+func (m *serviceRegistrationRPC) lastStatus() string {
+	m.Lock()
+	defer m.Unlock()
+	if len(m.upserts) == 0 {
+		return ""
+	}
+	return m.upserts[len(m.upserts)-1][0].Status
+}
+
+func TestRunHTTPCheck(t *testing.T) {
+	code := http.StatusOK
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.URL.Path != "/health" || r.Header.Get("X-Foo") != "bar" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(code)
+	}))
+	defer ts.Close()
+
+	host, portStr, _ := net.SplitHostPort(ts.Listener.Addr().String())
+	port, _ := strconv.Atoi(portStr)
+	check := &structs.ServiceCheck{
+		Type:    structs.ServiceCheckHTTP,
+		Path:    "/health",
+		Method:  "POST",
+		Header:  map[string][]string{"X-Foo": {"bar"}},
+		Timeout: time.Second,
+	}
+
+	cases := map[int]string{
+		http.StatusOK:                  consulapi.HealthPassing,
+		http.StatusTooManyRequests:     consulapi.HealthWarning,
+		http.StatusInternalServerError: consulapi.HealthCritical,
+	}
+	for c, exp := range cases {
+		code = c
+		if status, _ := runHTTPCheck(check, host, port); status != exp {
+			t.Fatalf("code %d: got status %q; want %q", c, status, exp)
+		}
+	}
+
+	if status, _ := runTCPCheck(check, host, port); status != consulapi.HealthPassing {
+		t.Fatalf("bad tcp check status: %q", status)
+	}
+	ts.Close()
+	if status, _ := runTCPCheck(check, host, port); status != consulapi.HealthCritical {
+		t.Fatalf("bad tcp check status: %q", status)
+	}
+}
+
+func TestTaskRunner_RegisterServices(t *testing.T) {
+	var lock sync.Mutex
+	code := http.StatusOK
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+		w.WriteHeader(code)
+	}))
+	defer ts.Close()
+	_, portStr, _ := net.SplitHostPort(ts.Listener.Addr().String())
+	port, _ := strconv.Atoi(portStr)
+
+	alloc := mock.Alloc()
+	task := alloc.Job.TaskGroups[0].Tasks[0]
+	task.Driver = "mock_driver"
+	task.Config = map[string]interface{}{
+		"exit_code": "0",
+		"run_for":   "100s",
+	}
+	task.Services = []*structs.Service{
+		{
+			Name:      "${NOMAD_TASK_NAME}-api",
+			PortLabel: "http",
+			Provider:  structs.ServiceProviderNomad,
+			Tags:      []string{"v1"},
+			Checks: []*structs.ServiceCheck{
+				{
+					Name:     "alive",
+					Type:     structs.ServiceCheckHTTP,
+					Path:     "/health",
+					Interval: 50 * time.Millisecond,
+					Timeout:  time.Second,
+				},
+			},
+		},
+	}
+	network := alloc.TaskResources[task.Name].Networks[0]
+	network.IP = "127.0.0.1"
+	network.DynamicPorts[0].Value = port
+
+	ctx := testTaskRunnerFromAlloc(t, false, alloc)
+	ctx.tr.config.Node = mock.Node()
+	rpc := &serviceRegistrationRPC{}
+	ctx.tr.rpc = rpc
+	ctx.tr.MarkReceived()
+	go ctx.tr.Run()
+	defer ctx.Cleanup()
+
+	testWaitForTaskToStart(t, ctx)
+	testutil.WaitForResult(func() (bool, error) {
+		if s := rpc.lastStatus(); s != consulapi.HealthPassing {
+			return false, fmt.Errorf("service not registered as passing: %q", s)
+		}
+		return true, nil
+	}, func(err error) {
+		t.Fatalf("err: %v", err)
+	})
+
+	rpc.Lock()
+	reg := rpc.upserts[0][0]
+	rpc.Unlock()
+	if reg.ServiceName != "web-api" || reg.Address != "127.0.0.1" || reg.Port != port ||
+		reg.AllocID != alloc.ID || reg.NodeID != ctx.tr.config.Node.ID || reg.TaskName != task.Name {
+		t.Fatalf("bad registration: %#v", reg)
+	}
+
+	// A failing check updates the registration
+	lock.Lock()
+	code = http.StatusInternalServerError
+	lock.Unlock()
+	testutil.WaitForResult(func() (bool, error) {
+		if s := rpc.lastStatus(); s != consulapi.HealthCritical {
+			return false, fmt.Errorf("service not registered as critical: %q", s)
+		}
+		return true, nil
+	}, func(err error) {
+		t.Fatalf("err: %v", err)
+	})
+
+	// Stopping the task deregisters the service
+	ctx.tr.Kill("test", "done", false)
+	select {
+	case <-ctx.tr.WaitCh():
+	case <-time.After(time.Duration(testutil.TestMultiplier()*15) * time.Second):
+		t.Fatalf("timeout")
+	}
+	testutil.WaitForResult(func() (bool, error) {
+		rpc.Lock()
+		defer rpc.Unlock()
+		if len(rpc.deletes) != 1 || len(rpc.deletes[0]) != 1 || rpc.deletes[0][0] != reg.ID {
+			return false, fmt.Errorf("service not deregistered: %#v", rpc.deletes)
+		}
+		return true, nil
+	}, func(err error) {
+		t.Fatalf("err: %v", err)
+	})
+}
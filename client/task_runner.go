@@ -52,6 +52,10 @@ const (
 	// vaultTokenFile is the name of the file holding the Vault token inside the
 	// task's secret directory
 	vaultTokenFile = "vault_token"
+
+	// identityTokenFile is the name of the file holding the workload identity
+	// token inside the task's secret directory
+	identityTokenFile = "nomad_token"
 )
 
 // TaskRunner is used to wrap a task within an allocation and provide the execution context.
@@ -72,10 +76,23 @@ type TaskRunner struct {
 	task    *structs.Task
 	taskDir *allocdir.TaskDir
 
+	// networkIsolation is the network namespace shared by the tasks of the
+	// allocation. It is nil if the task uses the host's network.
+	networkIsolation *dstructs.NetworkIsolationSpec
+
+	// consulChecks is used to watch the health of the task's service checks
+	// with check_restart behavior. It is created from the client's Consul
+	// configuration when first needed.
+	consulChecks consulChecksGetter
+
 	// taskEnv is the environment variables of the task
 	taskEnv     *env.TaskEnvironment
 	taskEnvLock sync.Mutex
 
+	// templateEnv is the environment variables rendered by the task's
+	// templates with env enabled
+	templateEnv map[string]string
+
 	// updateCh is used to receive updated versions of the allocation
 	updateCh chan *structs.Allocation
 
@@ -111,6 +128,13 @@ type TaskRunner struct {
 	// vaultClient is used to retrieve and renew any needed Vault token
 	vaultClient vaultclient.VaultClient
 
+	// identityToken is the signed workload identity of the task. Must
+	// acquire taskEnvLock when accessing.
+	identityToken string
+
+	// rpc is used to query the servers for the task
+	rpc config.RPCHandler
+
 	// templateManager is used to manage any consul-templates this task may have
 	templateManager *TaskTemplateManager
 
@@ -173,10 +197,10 @@ type SignalEvent struct {
 func NewTaskRunner(logger *log.Logger, config *config.Config,
 	updater TaskStateUpdater, taskDir *allocdir.TaskDir,
 	alloc *structs.Allocation, task *structs.Task,
-	vaultClient vaultclient.VaultClient) *TaskRunner {
+	vaultClient vaultclient.VaultClient, rpc config.RPCHandler) *TaskRunner {
 
 	// Merge in the task resources
-	task.Resources = alloc.TaskResources[task.Name]
+	task.Resources = taskResources(alloc, task.Name)
 
 	// Build the restart tracker.
 	tg := alloc.Job.LookupTaskGroup(alloc.TaskGroup)
@@ -196,6 +220,7 @@ func NewTaskRunner(logger *log.Logger, config *config.Config,
 		taskDir:          taskDir,
 		createdResources: driver.NewCreatedResources(),
 		vaultClient:      vaultClient,
+		rpc:              rpc,
 		vaultFuture:      NewTokenFuture().Set(""),
 		updateCh:         make(chan *structs.Allocation, 64),
 		destroyCh:        make(chan struct{}),
@@ -280,7 +305,7 @@ func (r *TaskRunner) RestoreState() error {
 			return err
 		}
 
-		ctx := driver.NewExecContext(r.taskDir)
+		ctx := r.newExecContext()
 		handle, err := d.Open(ctx, snap.HandleID)
 
 		// In the case it fails, we relaunch the task in the Run() method.
@@ -352,7 +377,9 @@ func (r *TaskRunner) setTaskEnv() error {
 	if err != nil {
 		return err
 	}
-	r.taskEnv = taskEnv
+	injectIdentity := r.task.Identity != nil && r.task.Identity.Env
+	r.taskEnv = taskEnv.SetWorkloadToken(r.identityToken, injectIdentity).
+		SetTemplateEnv(r.templateEnv).Build()
 	return nil
 }
 
@@ -363,6 +390,64 @@ func (r *TaskRunner) getTaskEnv() *env.TaskEnvironment {
 	return r.taskEnv
 }
 
+// SetTemplateEnv sets the environment variables rendered by the task's
+// templates. They are kept across rebuilds of the task environment.
+func (r *TaskRunner) SetTemplateEnv(m map[string]string) {
+	r.taskEnvLock.Lock()
+	r.templateEnv = m
+	r.taskEnvLock.Unlock()
+
+	if err := r.setTaskEnv(); err != nil {
+		r.logger.Printf("[ERR] client: alloc %q, task %q failed to set template environment: %v",
+			r.alloc.ID, r.task.Name, err)
+	}
+}
+
+// nomadTemplateFuncs returns the template functions that query the servers
+// about the task's job.
+func (r *TaskRunner) nomadTemplateFuncs() *nomadTemplateFuncs {
+	return newNomadTemplateFuncs(r.rpc, r.config.Region, r.alloc.JobID)
+}
+
+// SetNetworkIsolation sets the network namespace shared by the tasks of the
+// allocation. It must be called before the task is started.
+func (r *TaskRunner) SetNetworkIsolation(spec *dstructs.NetworkIsolationSpec) {
+	r.networkIsolation = spec
+}
+
+// newExecContext returns the execution context passed to the driver.
+func (r *TaskRunner) newExecContext() *driver.ExecContext {
+	ctx := driver.NewExecContext(r.taskDir)
+	ctx.NetworkIsolation = r.networkIsolation
+	return ctx
+}
+
+// taskResources returns the resources of the task in the allocation. The
+// network of the task group is appended so that its ports can be used by the
+// task's services and environment.
+func taskResources(alloc *structs.Allocation, taskName string) *structs.Resources {
+	resources := alloc.TaskResources[taskName]
+	if alloc.SharedResources == nil || len(alloc.SharedResources.Networks) == 0 {
+		return resources
+	}
+
+	merged := resources.Copy()
+	if merged == nil {
+		merged = &structs.Resources{}
+	}
+	for _, network := range alloc.SharedResources.Networks {
+		network = network.Copy()
+
+		// Tasks on a CNI network are addressed by the IP the network's
+		// plugins assigned to the allocation
+		if network.CNIName() != "" && alloc.NetworkStatus != nil && alloc.NetworkStatus.Address != "" {
+			network.IP = alloc.NetworkStatus.Address
+		}
+		merged.Networks = append(merged.Networks, network)
+	}
+	return merged
+}
+
 // createDriver makes a driver for the task
 func (r *TaskRunner) createDriver() (driver.Driver, error) {
 	env := r.getTaskEnv()
@@ -707,6 +792,114 @@ func (r *TaskRunner) writeToken(token string) error {
 	return nil
 }
 
+// setIdentityToken retrieves the signed workload identity of the task, either
+// from the secret directory or from the servers, and writes it to disk if the
+// task requests it. It returns whether the task should exit.
+func (r *TaskRunner) setIdentityToken() (exit bool, err error) {
+	r.taskEnvLock.Lock()
+	token := r.identityToken
+	r.taskEnvLock.Unlock()
+	if token != "" {
+		return false, nil
+	}
+
+	// Reuse the token of a previous run of the task
+	tokenPath := filepath.Join(r.taskDir.SecretsDir, identityTokenFile)
+	data, err := ioutil.ReadFile(tokenPath)
+	if err != nil && !os.IsNotExist(err) {
+		return false, fmt.Errorf("failed to read identity token for task %q in alloc %q: %v", r.task.Name, r.alloc.ID, err)
+	}
+	token = string(data)
+
+	if token == "" {
+		token, exit, err = r.deriveIdentityToken()
+		if exit || err != nil {
+			return exit, err
+		}
+	}
+
+	if r.task.Identity.File {
+		if err := ioutil.WriteFile(tokenPath, []byte(token), 0666); err != nil {
+			return false, fmt.Errorf("failed to save identity token to secret dir for task %q in alloc %q: %v", r.task.Name, r.alloc.ID, err)
+		}
+	}
+
+	r.taskEnvLock.Lock()
+	r.identityToken = token
+	r.taskEnvLock.Unlock()
+	return false, nil
+}
+
+// deriveIdentityToken requests the signed workload identity of the task from
+// the servers using exponential backoffs. It returns the token and whether the
+// task should exit.
+func (r *TaskRunner) deriveIdentityToken() (token string, exit bool, err error) {
+	attempts := 0
+	for {
+		req := structs.DeriveIdentityTokenRequest{
+			NodeID:   r.config.Node.ID,
+			SecretID: r.config.Node.SecretID,
+			AllocID:  r.alloc.ID,
+			Tasks:    []string{r.task.Name},
+			QueryOptions: structs.QueryOptions{
+				Region:     r.config.Region,
+				AllowStale: true,
+			},
+		}
+		var resp structs.DeriveIdentityTokenResponse
+		err := r.rpc.RPC("Node.DeriveIdentityToken", &req, &resp)
+		if err != nil {
+			// Failing to reach the servers is recoverable
+			err = structs.NewRecoverableError(fmt.Errorf("DeriveIdentityToken RPC failed: %v", err), true)
+		} else if resp.Error != nil {
+			err = resp.Error
+		} else if resp.Tasks[r.task.Name] == "" {
+			err = fmt.Errorf("failed to derive identity token: invalid response")
+		} else {
+			return resp.Tasks[r.task.Name], false, nil
+		}
+
+		// Check if we can't recover from the error
+		if !structs.IsRecoverable(err) {
+			return "", false, err
+		}
+
+		// Handle the retry case
+		backoff := (1 << (2 * uint64(attempts))) * vaultBackoffBaseline
+		if backoff > vaultBackoffLimit {
+			backoff = vaultBackoffLimit
+		}
+		r.logger.Printf("[ERR] client: failed to derive identity token for task %v on alloc %q: %v; retrying in %v",
+			r.task.Name, r.alloc.ID, err, backoff)
+
+		attempts++
+
+		// Wait till retrying
+		select {
+		case <-r.waitCh:
+			return "", true, nil
+		case <-time.After(backoff):
+		}
+	}
+}
+
+// writeEnvoyBootstrap writes the Envoy bootstrap configuration of the Connect
+// sidecar proxy for the given service to disk
+func (r *TaskRunner) writeEnvoyBootstrap(service string) error {
+	consulConf := r.config.ConsulConfig
+	bootstrap, err := buildEnvoyBootstrap(r.alloc, service, consulConf.GRPCAddr, consulConf.Token)
+	if err != nil {
+		return fmt.Errorf("failed to build Envoy bootstrap for task %q in alloc %q: %v", r.task.Name, r.alloc.ID, err)
+	}
+
+	bootstrapPath := filepath.Join(r.taskDir.SecretsDir, envoyBootstrapFile)
+	if err := ioutil.WriteFile(bootstrapPath, bootstrap, 0644); err != nil {
+		return fmt.Errorf("failed to save Envoy bootstrap to secret dir for task %q in alloc %q: %v", r.task.Name, r.alloc.ID, err)
+	}
+
+	return nil
+}
+
 // updatedTokenHandler is called when a new Vault token is retrieved. Things
 // that rely on the token should be updated here.
 func (r *TaskRunner) updatedTokenHandler() {
@@ -725,7 +918,7 @@ func (r *TaskRunner) updatedTokenHandler() {
 		// Create a new templateManager
 		var err error
 		r.templateManager, err = NewTaskTemplateManager(r, r.task.Templates,
-			r.config, r.vaultFuture.Get(), r.taskDir.Dir, r.getTaskEnv())
+			r.config, r.vaultFuture.Get(), r.taskDir.Dir, r.getTaskEnv(), r.nomadTemplateFuncs())
 		if err != nil {
 			err := fmt.Errorf("failed to build task's template manager: %v", err)
 			r.setState(structs.TaskStateDead, structs.NewTaskEvent(structs.TaskSetupFailure).SetSetupError(err).SetFailsTask())
@@ -751,6 +944,21 @@ func (r *TaskRunner) prestart(resultCh chan bool) {
 		r.logger.Printf("[DEBUG] client: retrieved Vault token for task %v in alloc %q", r.task.Name, r.alloc.ID)
 	}
 
+	if r.task.Identity != nil {
+		exit, err := r.setIdentityToken()
+		if exit {
+			resultCh <- false
+			return
+		}
+		if err != nil {
+			r.setState(
+				structs.TaskStateDead,
+				structs.NewTaskEvent(structs.TaskSetupFailure).SetSetupError(err).SetFailsTask())
+			resultCh <- false
+			return
+		}
+	}
+
 	if err := r.setTaskEnv(); err != nil {
 		r.setState(
 			structs.TaskStateDead,
@@ -759,8 +967,19 @@ func (r *TaskRunner) prestart(resultCh chan bool) {
 		return
 	}
 
+	// If the task is a Connect sidecar proxy write its Envoy bootstrap
+	if service, ok := r.task.ConnectProxyService(); ok {
+		if err := r.writeEnvoyBootstrap(service); err != nil {
+			r.setState(
+				structs.TaskStateDead,
+				structs.NewTaskEvent(structs.TaskSetupFailure).SetSetupError(err).SetFailsTask())
+			resultCh <- false
+			return
+		}
+	}
+
 	// If the job is a dispatch job and there is a payload write it to disk
-	requirePayload := len(r.alloc.Job.Payload) != 0 &&
+	requirePayload := len(r.alloc.Job.Payload) != 0 && r.alloc.Job.PayloadURL == "" &&
 		(r.task.DispatchPayload != nil && r.task.DispatchPayload.File != "")
 	if !r.payloadRendered && requirePayload {
 		renderTo := filepath.Join(r.taskDir.LocalDir, r.task.DispatchPayload.File)
@@ -801,7 +1020,7 @@ func (r *TaskRunner) prestart(resultCh chan bool) {
 		if !downloaded && len(r.task.Artifacts) > 0 {
 			r.setState(structs.TaskStatePending, structs.NewTaskEvent(structs.TaskDownloadingArtifacts))
 			for _, artifact := range r.task.Artifacts {
-				if err := getter.GetArtifact(r.getTaskEnv(), artifact, r.taskDir.Dir); err != nil {
+				if err := getter.GetArtifact(r.getTaskEnv(), artifact, r.taskDir.Dir, r.config.Artifact); err != nil {
 					wrapped := fmt.Errorf("failed to download artifact %q: %v", artifact.GetterSource, err)
 					r.logger.Printf("[DEBUG] client: %v", wrapped)
 					r.setState(structs.TaskStatePending,
@@ -816,6 +1035,22 @@ func (r *TaskRunner) prestart(resultCh chan bool) {
 			r.persistLock.Unlock()
 		}
 
+		// Download the payload of the dispatch job if it is referenced by URL
+		if !r.payloadRendered && r.alloc.Job.PayloadURL != "" &&
+			r.task.DispatchPayload != nil && r.task.DispatchPayload.File != "" {
+			renderTo := filepath.Join(r.taskDir.LocalDir, r.task.DispatchPayload.File)
+			if err := getter.GetPayload(r.alloc.Job.PayloadURL, renderTo); err != nil {
+				wrapped := fmt.Errorf("failed to download payload %q: %v", r.alloc.Job.PayloadURL, err)
+				r.logger.Printf("[DEBUG] client: %v", wrapped)
+				r.setState(structs.TaskStatePending,
+					structs.NewTaskEvent(structs.TaskArtifactDownloadFailed).SetDownloadError(wrapped))
+				r.restartTracker.SetStartError(structs.WrapRecoverable(wrapped.Error(), err))
+				goto RESTART
+			}
+
+			r.payloadRendered = true
+		}
+
 		// We don't have to wait for any template
 		if len(r.task.Templates) == 0 {
 			// Send the start signal
@@ -832,7 +1067,7 @@ func (r *TaskRunner) prestart(resultCh chan bool) {
 		if r.templateManager == nil {
 			var err error
 			r.templateManager, err = NewTaskTemplateManager(r, r.task.Templates,
-				r.config, r.vaultFuture.Get(), r.taskDir.Dir, r.getTaskEnv())
+				r.config, r.vaultFuture.Get(), r.taskDir.Dir, r.getTaskEnv(), r.nomadTemplateFuncs())
 			if err != nil {
 				err := fmt.Errorf("failed to build task's template manager: %v", err)
 				r.setState(structs.TaskStateDead, structs.NewTaskEvent(structs.TaskSetupFailure).SetSetupError(err).SetFailsTask())
@@ -894,6 +1129,8 @@ func (r *TaskRunner) run() {
 	if !handleEmpty {
 		stopCollection = make(chan struct{})
 		go r.collectResourceUsageStats(stopCollection)
+		go r.watchChecks(stopCollection)
+		go r.registerServices(stopCollection)
 		handleWaitCh = r.handle.WaitCh()
 	}
 
@@ -935,6 +1172,8 @@ func (r *TaskRunner) run() {
 					if stopCollection == nil {
 						stopCollection = make(chan struct{})
 						go r.collectResourceUsageStats(stopCollection)
+						go r.watchChecks(stopCollection)
+						go r.registerServices(stopCollection)
 					}
 
 					handleWaitCh = r.handle.WaitCh()
@@ -1071,7 +1310,7 @@ func (r *TaskRunner) cleanup() {
 
 	res := r.getCreatedResources()
 
-	ctx := driver.NewExecContext(r.taskDir)
+	ctx := r.newExecContext()
 	attempts := 1
 	var cleanupErr error
 	for retry := true; retry; attempts++ {
@@ -1192,7 +1431,7 @@ func (r *TaskRunner) startTask() error {
 	}
 
 	// Run prestart
-	ctx := driver.NewExecContext(r.taskDir)
+	ctx := r.newExecContext()
 	res, err := drv.Prestart(ctx, r.task)
 
 	// Merge newly created resources into previously created resources
@@ -1333,7 +1572,7 @@ func (r *TaskRunner) handleUpdate(update *structs.Allocation) error {
 	}
 
 	// Merge in the task resources
-	updatedTask.Resources = update.TaskResources[updatedTask.Name]
+	updatedTask.Resources = taskResources(update, updatedTask.Name)
 
 	// Update will update resources and store the new kill timeout.
 	var mErr multierror.Error
@@ -104,7 +104,7 @@ func testTaskRunnerFromAlloc(t *testing.T, restarts bool, alloc *structs.Allocat
 	}
 
 	vclient := vaultclient.NewMockVaultClient()
-	tr := NewTaskRunner(logger, conf, upd.Update, taskDir, alloc, task, vclient)
+	tr := NewTaskRunner(logger, conf, upd.Update, taskDir, alloc, task, vclient, nil)
 	if !restarts {
 		tr.restartTracker = noRestartsTracker()
 	}
@@ -366,7 +366,7 @@ func TestTaskRunner_SaveRestoreState(t *testing.T) {
 	// Create a new task runner
 	task2 := &structs.Task{Name: ctx.tr.task.Name, Driver: ctx.tr.task.Driver}
 	tr2 := NewTaskRunner(ctx.tr.logger, ctx.tr.config, ctx.upd.Update,
-		ctx.tr.taskDir, ctx.tr.alloc, task2, ctx.tr.vaultClient)
+		ctx.tr.taskDir, ctx.tr.alloc, task2, ctx.tr.vaultClient, nil)
 	tr2.restartTracker = noRestartsTracker()
 	if err := tr2.RestoreState(); err != nil {
 		t.Fatalf("err: %v", err)
@@ -1472,3 +1472,96 @@ func TestTaskRunner_CleanupFail(t *testing.T) {
 		t.Fatalf("expected %#v but found: %#v", expected, ctx.tr.createdResources.Resources)
 	}
 }
+
+// identityRPC answers the identity token requests of task runners
+type identityRPC struct {
+	tokens map[string]string
+	err    *structs.RecoverableError
+	calls  int
+}
+
+func (m *identityRPC) RPC(method string, args interface{}, reply interface{}) error {
+	if method != "Node.DeriveIdentityToken" {
+		return fmt.Errorf("unexpected RPC %q", method)
+	}
+	m.calls++
+	resp := reply.(*structs.DeriveIdentityTokenResponse)
+	resp.Tasks = m.tokens
+	resp.Error = m.err
+	return nil
+}
+
+func TestTaskRunner_IdentityToken(t *testing.T) {
+	alloc := mock.Alloc()
+	task := alloc.Job.TaskGroups[0].Tasks[0]
+	task.Identity = &structs.WorkloadIdentity{Env: true, File: true}
+
+	ctx := testTaskRunnerFromAlloc(t, false, alloc)
+	defer ctx.Cleanup()
+
+	ctx.tr.config.Node = mock.Node()
+	rpc := &identityRPC{tokens: map[string]string{task.Name: "a.b.c"}}
+	ctx.tr.rpc = rpc
+	if exit, err := ctx.tr.setIdentityToken(); exit || err != nil {
+		t.Fatalf("bad: %v %v", exit, err)
+	}
+	if err := ctx.tr.setTaskEnv(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// The token is exposed in the environment and the secret directory
+	if v := ctx.tr.getTaskEnv().EnvMap()["NOMAD_TOKEN"]; v != "a.b.c" {
+		t.Fatalf("bad NOMAD_TOKEN: %q", v)
+	}
+	data, err := ioutil.ReadFile(filepath.Join(ctx.tr.taskDir.SecretsDir, identityTokenFile))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if string(data) != "a.b.c" {
+		t.Fatalf("bad token file: %q", data)
+	}
+
+	// A restarted task runner reuses the token on disk
+	ctx.tr.identityToken = ""
+	rpc.tokens = map[string]string{task.Name: "d.e.f"}
+	if exit, err := ctx.tr.setIdentityToken(); exit || err != nil {
+		t.Fatalf("bad: %v %v", exit, err)
+	}
+	if ctx.tr.identityToken != "a.b.c" || rpc.calls != 1 {
+		t.Fatalf("bad: %q %d", ctx.tr.identityToken, rpc.calls)
+	}
+}
+
+func TestTaskRunner_IdentityToken_Error(t *testing.T) {
+	alloc := mock.Alloc()
+	task := alloc.Job.TaskGroups[0].Tasks[0]
+	task.Identity = &structs.WorkloadIdentity{}
+
+	ctx := testTaskRunnerFromAlloc(t, false, alloc)
+	defer ctx.Cleanup()
+
+	rpc := &identityRPC{
+		err: structs.NewRecoverableError(fmt.Errorf("SecretID mismatch"), false).(*structs.RecoverableError),
+	}
+	ctx.tr.config.Node = mock.Node()
+	ctx.tr.rpc = rpc
+	if _, err := ctx.tr.setIdentityToken(); err == nil {
+		t.Fatalf("expected error")
+	}
+
+	// The token is neither written to disk nor injected unless requested
+	rpc.err = nil
+	rpc.tokens = map[string]string{task.Name: "a.b.c"}
+	if exit, err := ctx.tr.setIdentityToken(); exit || err != nil {
+		t.Fatalf("bad: %v %v", exit, err)
+	}
+	if err := ctx.tr.setTaskEnv(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if _, ok := ctx.tr.getTaskEnv().EnvMap()["NOMAD_TOKEN"]; ok {
+		t.Fatalf("NOMAD_TOKEN should not be set")
+	}
+	if _, err := os.Stat(filepath.Join(ctx.tr.taskDir.SecretsDir, identityTokenFile)); !os.IsNotExist(err) {
+		t.Fatalf("token file should not exist: %v", err)
+	}
+}
@@ -0,0 +1,453 @@
+package client
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	dep "github.com/hashicorp/consul-template/dependency"
+	ctmpl "github.com/hashicorp/consul-template/template"
+	"github.com/hashicorp/nomad/client/config"
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+const (
+	// nomadQueryWaitTime is the maximum time a Nomad template query blocks
+	// waiting for a change on the servers.
+	nomadQueryWaitTime = 5 * time.Minute
+)
+
+// NomadAlloc is a running allocation as returned by the nomadAllocs template
+// function.
+type NomadAlloc struct {
+	ID        string
+	Name      string
+	Index     int
+	NodeID    string
+	JobID     string
+	TaskGroup string
+
+	// Address is the IP of the allocation's network and Ports maps the
+	// labels of the allocation's ports to their values.
+	Address string
+	Ports   map[string]int
+}
+
+// NomadService is an instance of a service as returned by the nomadService
+// template function.
+type NomadService struct {
+	Name      string
+	AllocID   string
+	NodeID    string
+	JobID     string
+	TaskGroup string
+	Task      string
+	Address   string
+	Port      int
+	Tags      []string
+}
+
+// nomadTemplateFuncs builds the template functions that query the Nomad
+// servers for data about jobs and their allocations.
+type nomadTemplateFuncs struct {
+	// rpc is used to query the servers
+	rpc config.RPCHandler
+
+	// region is the region of the client
+	region string
+
+	// jobID is the job of the task, used when a query doesn't name a job
+	jobID string
+}
+
+// newNomadTemplateFuncs returns the Nomad template functions of a task of the
+// given job. If rpc is nil, the functions return an error.
+func newNomadTemplateFuncs(rpc config.RPCHandler, region, jobID string) *nomadTemplateFuncs {
+	return &nomadTemplateFuncs{
+		rpc:    rpc,
+		region: region,
+		jobID:  jobID,
+	}
+}
+
+// FuncMap returns the Nomad template functions backed by the given brain.
+func (n *nomadTemplateFuncs) FuncMap(b *ctmpl.Brain, used, missing *dep.Set) template.FuncMap {
+	return template.FuncMap{
+		"nomadAllocs":  n.allocsFunc(b, used, missing),
+		"nomadService": n.serviceFunc(b, used, missing),
+		"nomadVar":     n.varFunc(b, used, missing),
+	}
+}
+
+// allocsFunc returns the running allocations of a task group, given as
+// "job/group".
+func (n *nomadTemplateFuncs) allocsFunc(b *ctmpl.Brain, used, missing *dep.Set) func(string) ([]*NomadAlloc, error) {
+	return func(s string) ([]*NomadAlloc, error) {
+		idx := strings.LastIndex(s, "/")
+		if idx <= 0 || idx == len(s)-1 {
+			return nil, fmt.Errorf("nomadAllocs: invalid task group %q; expected \"job/group\"", s)
+		}
+		d, err := n.query(nomadAllocsQuery, s[:idx], s[idx+1:])
+		if err != nil {
+			return nil, err
+		}
+
+		used.Add(d)
+		if value, ok := b.Recall(d); ok {
+			return value.([]*NomadAlloc), nil
+		}
+		missing.Add(d)
+		return nil, nil
+	}
+}
+
+// serviceFunc returns the instances of a service of running allocations,
+// given as "name" for the services of the task's job or "name@job".
+func (n *nomadTemplateFuncs) serviceFunc(b *ctmpl.Brain, used, missing *dep.Set) func(string) ([]*NomadService, error) {
+	return func(s string) ([]*NomadService, error) {
+		name, jobID := n.splitJob(s)
+		if name == "" {
+			return nil, fmt.Errorf("nomadService: invalid service %q; expected \"name\" or \"name@job\"", s)
+		}
+		d, err := n.query(nomadServiceQuery, jobID, name)
+		if err != nil {
+			return nil, err
+		}
+
+		used.Add(d)
+		if value, ok := b.Recall(d); ok {
+			return value.([]*NomadService), nil
+		}
+		missing.Add(d)
+		return nil, nil
+	}
+}
+
+// varFunc returns the value of a meta key of a job, given as "key" for the
+// task's job or "key@job". Missing jobs and keys return an empty string.
+func (n *nomadTemplateFuncs) varFunc(b *ctmpl.Brain, used, missing *dep.Set) func(string) (string, error) {
+	return func(s string) (string, error) {
+		key, jobID := n.splitJob(s)
+		if key == "" {
+			return "", fmt.Errorf("nomadVar: invalid key %q; expected \"key\" or \"key@job\"", s)
+		}
+		d, err := n.query(nomadVarQuery, jobID, key)
+		if err != nil {
+			return "", err
+		}
+
+		used.Add(d)
+		if value, ok := b.Recall(d); ok {
+			return value.(string), nil
+		}
+		missing.Add(d)
+		return "", nil
+	}
+}
+
+// splitJob splits a "name@job" argument, defaulting to the task's job.
+func (n *nomadTemplateFuncs) splitJob(s string) (string, string) {
+	if idx := strings.LastIndex(s, "@"); idx != -1 {
+		return s[:idx], s[idx+1:]
+	}
+	return s, n.jobID
+}
+
+// query returns a dependency on the given query of a job.
+func (n *nomadTemplateFuncs) query(kind nomadQueryKind, jobID, arg string) (*nomadQuery, error) {
+	if n.rpc == nil {
+		return nil, fmt.Errorf("Nomad template functions are not available")
+	}
+	if jobID == "" {
+		return nil, fmt.Errorf("%s: missing job", kind)
+	}
+	return &nomadQuery{
+		kind:   kind,
+		jobID:  jobID,
+		arg:    arg,
+		rpc:    n.rpc,
+		region: n.region,
+		stopCh: make(chan struct{}),
+	}, nil
+}
+
+// nomadQueryKind is the kind of data a Nomad template query returns.
+type nomadQueryKind string
+
+const (
+	nomadAllocsQuery  nomadQueryKind = "nomad.allocs"
+	nomadServiceQuery nomadQueryKind = "nomad.service"
+	nomadVarQuery     nomadQueryKind = "nomad.var"
+)
+
+// nomadQuery is a consul-template dependency on data of a Nomad job. It uses
+// blocking queries so templates are re-rendered when the data changes.
+type nomadQuery struct {
+	kind  nomadQueryKind
+	jobID string
+
+	// arg is the task group, service name or meta key of the query
+	arg string
+
+	rpc    config.RPCHandler
+	region string
+
+	stopCh   chan struct{}
+	stopLock sync.Mutex
+	stopped  bool
+}
+
+// nomadQueryResult is the result of a Nomad template query.
+type nomadQueryResult struct {
+	data interface{}
+	rm   *dep.ResponseMetadata
+	err  error
+}
+
+// Fetch queries the servers, blocking until the data changes past the wait
+// index or the dependency is stopped.
+func (d *nomadQuery) Fetch(clients *dep.ClientSet, opts *dep.QueryOptions) (interface{}, *dep.ResponseMetadata, error) {
+	select {
+	case <-d.stopCh:
+		return nil, nil, dep.ErrStopped
+	default:
+	}
+
+	q := structs.QueryOptions{
+		Region:        d.region,
+		AllowStale:    true,
+		MinQueryIndex: opts.WaitIndex,
+		MaxQueryTime:  nomadQueryWaitTime,
+	}
+
+	resultCh := make(chan *nomadQueryResult, 1)
+	go func() {
+		var r nomadQueryResult
+		switch d.kind {
+		case nomadVarQuery:
+			r.data, r.rm, r.err = d.fetchVar(q)
+		default:
+			r.data, r.rm, r.err = d.fetchAllocs(q)
+		}
+		resultCh <- &r
+	}()
+
+	select {
+	case <-d.stopCh:
+		return nil, nil, dep.ErrStopped
+	case r := <-resultCh:
+		if r.err != nil {
+			return nil, nil, fmt.Errorf("%s: %v", d, r.err)
+		}
+		return r.data, r.rm, nil
+	}
+}
+
+// fetchVar returns the value of a meta key of the job.
+func (d *nomadQuery) fetchVar(q structs.QueryOptions) (interface{}, *dep.ResponseMetadata, error) {
+	args := structs.JobSpecificRequest{JobID: d.jobID, QueryOptions: q}
+	var resp structs.SingleJobResponse
+	if err := d.rpc.RPC("Job.GetJob", &args, &resp); err != nil {
+		return nil, nil, err
+	}
+
+	value := ""
+	if resp.Job != nil {
+		value = resp.Job.Meta[d.arg]
+	}
+	return value, responseMetadata(&resp.QueryMeta), nil
+}
+
+// fetchAllocs returns the allocations or service instances of the running
+// allocations of the job.
+func (d *nomadQuery) fetchAllocs(q structs.QueryOptions) (interface{}, *dep.ResponseMetadata, error) {
+	args := structs.JobSpecificRequest{JobID: d.jobID, QueryOptions: q}
+	var resp structs.JobAllocationsResponse
+	if err := d.rpc.RPC("Job.Allocations", &args, &resp); err != nil {
+		return nil, nil, err
+	}
+
+	var ids []string
+	for _, stub := range resp.Allocations {
+		if stub.DesiredStatus != structs.AllocDesiredStatusRun ||
+			stub.ClientStatus != structs.AllocClientStatusRunning {
+			continue
+		}
+		if d.kind == nomadAllocsQuery && stub.TaskGroup != d.arg {
+			continue
+		}
+		ids = append(ids, stub.ID)
+	}
+
+	var allocs []*structs.Allocation
+	if len(ids) != 0 {
+		req := structs.AllocsGetRequest{
+			AllocIDs: ids,
+			QueryOptions: structs.QueryOptions{
+				Region:     d.region,
+				AllowStale: true,
+			},
+		}
+		var allocsResp structs.AllocsGetResponse
+		if err := d.rpc.RPC("Alloc.GetAllocs", &req, &allocsResp); err != nil {
+			return nil, nil, err
+		}
+		for _, alloc := range allocsResp.Allocs {
+			// Allocations garbage collected since listing them are skipped
+			if alloc != nil {
+				allocs = append(allocs, alloc)
+			}
+		}
+	}
+
+	rm := responseMetadata(&resp.QueryMeta)
+	if d.kind == nomadServiceQuery {
+		return nomadServices(allocs, d.arg), rm, nil
+	}
+	return nomadAllocs(allocs), rm, nil
+}
+
+// responseMetadata converts the metadata of a query response.
+func responseMetadata(meta *structs.QueryMeta) *dep.ResponseMetadata {
+	return &dep.ResponseMetadata{
+		LastIndex:   meta.Index,
+		LastContact: meta.LastContact,
+	}
+}
+
+func (d *nomadQuery) CanShare() bool {
+	return false
+}
+
+func (d *nomadQuery) String() string {
+	return fmt.Sprintf("%s(%s@%s)", d.kind, d.arg, d.jobID)
+}
+
+func (d *nomadQuery) Stop() {
+	d.stopLock.Lock()
+	defer d.stopLock.Unlock()
+	if !d.stopped {
+		close(d.stopCh)
+		d.stopped = true
+	}
+}
+
+func (d *nomadQuery) Type() dep.Type {
+	return dep.TypeNomad
+}
+
+// nomadAllocs converts allocations, sorted by their index and ID.
+func nomadAllocs(allocs []*structs.Allocation) []*NomadAlloc {
+	out := make([]*NomadAlloc, 0, len(allocs))
+	for _, alloc := range allocs {
+		a := &NomadAlloc{
+			ID:        alloc.ID,
+			Name:      alloc.Name,
+			Index:     alloc.Index(),
+			NodeID:    alloc.NodeID,
+			JobID:     alloc.JobID,
+			TaskGroup: alloc.TaskGroup,
+			Ports:     make(map[string]int),
+		}
+
+		// Visit the tasks in a stable order so the address doesn't change
+		// between queries
+		tasks := make([]string, 0, len(alloc.TaskResources))
+		for task := range alloc.TaskResources {
+			tasks = append(tasks, task)
+		}
+		sort.Strings(tasks)
+		for _, task := range tasks {
+			for _, network := range alloc.TaskResources[task].Networks {
+				if a.Address == "" {
+					a.Address = network.IP
+				}
+				for _, port := range network.ReservedPorts {
+					a.Ports[port.Label] = port.Value
+				}
+				for _, port := range network.DynamicPorts {
+					a.Ports[port.Label] = port.Value
+				}
+			}
+		}
+		out = append(out, a)
+	}
+	sort.Sort(nomadAllocsByIndex(out))
+	return out
+}
+
+type nomadAllocsByIndex []*NomadAlloc
+
+func (a nomadAllocsByIndex) Len() int      { return len(a) }
+func (a nomadAllocsByIndex) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
+func (a nomadAllocsByIndex) Less(i, j int) bool {
+	if a[i].Index != a[j].Index {
+		return a[i].Index < a[j].Index
+	}
+	return a[i].ID < a[j].ID
+}
+
+// nomadServices returns the instances of the named service declared by the
+// tasks of the allocations, sorted by their address and port.
+func nomadServices(allocs []*structs.Allocation, name string) []*NomadService {
+	out := make([]*NomadService, 0)
+	for _, alloc := range allocs {
+		tg := alloc.Job.LookupTaskGroup(alloc.TaskGroup)
+		if tg == nil {
+			continue
+		}
+		for _, task := range tg.Tasks {
+			for _, service := range task.Services {
+				if service.Name != name {
+					continue
+				}
+				s := &NomadService{
+					Name:      service.Name,
+					AllocID:   alloc.ID,
+					NodeID:    alloc.NodeID,
+					JobID:     alloc.JobID,
+					TaskGroup: alloc.TaskGroup,
+					Task:      task.Name,
+					Tags:      service.Tags,
+				}
+				if resources, ok := alloc.TaskResources[task.Name]; ok {
+					s.Address, s.Port = networkPort(resources.Networks, service.PortLabel)
+				}
+				out = append(out, s)
+			}
+		}
+	}
+	sort.Sort(nomadServicesByAddress(out))
+	return out
+}
+
+// networkPort returns the IP and host port of the port with the given label.
+func networkPort(networks []*structs.NetworkResource, label string) (string, int) {
+	for _, network := range networks {
+		for _, ports := range [][]structs.Port{network.ReservedPorts, network.DynamicPorts} {
+			for _, port := range ports {
+				if port.Label == label {
+					return network.IP, port.Value
+				}
+			}
+		}
+	}
+	return "", 0
+}
+
+type nomadServicesByAddress []*NomadService
+
+func (s nomadServicesByAddress) Len() int      { return len(s) }
+func (s nomadServicesByAddress) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s nomadServicesByAddress) Less(i, j int) bool {
+	if s[i].Address != s[j].Address {
+		return s[i].Address < s[j].Address
+	}
+	if s[i].Port != s[j].Port {
+		return s[i].Port < s[j].Port
+	}
+	return s[i].AllocID < s[j].AllocID
+}
@@ -0,0 +1,234 @@
+package client
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/nomad/nomad/mock"
+	"github.com/hashicorp/nomad/nomad/structs"
+	"github.com/hashicorp/nomad/testutil"
+)
+
+// mockNomadRPC serves the queries of the Nomad template functions from a job
+// and its allocations, blocking queries until they are updated.
+type mockNomadRPC struct {
+	job    *structs.Job
+	allocs []*structs.Allocation
+	index  uint64
+
+	// updateCh is closed and replaced when the job or allocations change
+	updateCh chan struct{}
+	lock     sync.Mutex
+}
+
+func newMockNomadRPC(job *structs.Job, allocs ...*structs.Allocation) *mockNomadRPC {
+	return &mockNomadRPC{
+		job:      job,
+		allocs:   allocs,
+		index:    1,
+		updateCh: make(chan struct{}),
+	}
+}
+
+// update modifies the job or allocations and unblocks the pending queries
+func (m *mockNomadRPC) update(f func()) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	f()
+	m.index++
+	close(m.updateCh)
+	m.updateCh = make(chan struct{})
+}
+
+// wait blocks while the index hasn't passed the min query index
+func (m *mockNomadRPC) wait(q *structs.QueryOptions) {
+	m.lock.Lock()
+	index, updateCh := m.index, m.updateCh
+	m.lock.Unlock()
+	if q.MinQueryIndex < index {
+		return
+	}
+	select {
+	case <-updateCh:
+	case <-time.After(q.MaxQueryTime):
+	}
+}
+
+func (m *mockNomadRPC) RPC(method string, args interface{}, reply interface{}) error {
+	switch method {
+	case "Job.GetJob":
+		req := args.(*structs.JobSpecificRequest)
+		m.wait(&req.QueryOptions)
+		m.lock.Lock()
+		defer m.lock.Unlock()
+		resp := reply.(*structs.SingleJobResponse)
+		if m.job.ID == req.JobID {
+			resp.Job = m.job.Copy()
+		}
+		resp.Index = m.index
+	case "Job.Allocations":
+		req := args.(*structs.JobSpecificRequest)
+		m.wait(&req.QueryOptions)
+		m.lock.Lock()
+		defer m.lock.Unlock()
+		resp := reply.(*structs.JobAllocationsResponse)
+		for _, alloc := range m.allocs {
+			if alloc.JobID == req.JobID {
+				resp.Allocations = append(resp.Allocations, alloc.Stub())
+			}
+		}
+		resp.Index = m.index
+	case "Alloc.GetAllocs":
+		req := args.(*structs.AllocsGetRequest)
+		m.lock.Lock()
+		defer m.lock.Unlock()
+		resp := reply.(*structs.AllocsGetResponse)
+		for _, id := range req.AllocIDs {
+			for _, alloc := range m.allocs {
+				if alloc.ID == id {
+					resp.Allocs = append(resp.Allocs, alloc.Copy())
+				}
+			}
+		}
+		resp.Index = m.index
+	default:
+		return fmt.Errorf("unexpected method %q", method)
+	}
+	return nil
+}
+
+// runningAlloc returns a running allocation of the job with the given index
+// and http port.
+func runningAlloc(job *structs.Job, index, port int) *structs.Allocation {
+	alloc := mock.Alloc()
+	alloc.Job = job
+	alloc.JobID = job.ID
+	alloc.Name = fmt.Sprintf("%s.web[%d]", job.Name, index)
+	alloc.ClientStatus = structs.AllocClientStatusRunning
+	alloc.TaskResources["web"].Networks[0].DynamicPorts[0].Value = port
+	return alloc
+}
+
+func TestTaskTemplateManager_Nomad_Allocs(t *testing.T) {
+	job := mock.Job()
+	alloc1 := runningAlloc(job, 0, 20000)
+	alloc2 := runningAlloc(job, 1, 20001)
+	stopped := runningAlloc(job, 2, 20002)
+	stopped.DesiredStatus = structs.AllocDesiredStatusStop
+	rpc := newMockNomadRPC(job, alloc1, alloc2, stopped)
+
+	// Make a template listing the peers that restarts the task when they move
+	file := "peers.txt"
+	template := &structs.Template{
+		EmbeddedTmpl: fmt.Sprintf(`{{range nomadAllocs "%s/web"}}{{.Index}}={{.Address}}:{{index .Ports "http"}}
+{{end}}`, job.ID),
+		DestPath:   file,
+		ChangeMode: structs.TemplateChangeModeRestart,
+	}
+
+	harness := newTestHarness(t, []*structs.Template{template}, false, false)
+	harness.nomadFuncs = newNomadTemplateFuncs(rpc, "global", job.ID)
+	harness.start(t)
+	defer harness.stop()
+
+	// Wait for the unblock
+	select {
+	case <-harness.mockHooks.UnblockCh:
+	case <-time.After(time.Duration(5*testutil.TestMultiplier()) * time.Second):
+		t.Fatalf("Task unblock should have been called")
+	}
+
+	path := filepath.Join(harness.taskDir, file)
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read rendered template from %q: %v", path, err)
+	}
+	expected := "0=192.168.0.100:20000\n1=192.168.0.100:20001\n"
+	if s := string(raw); s != expected {
+		t.Fatalf("Unexpected template data; got %q, want %q", s, expected)
+	}
+
+	// Move the second allocation
+	rpc.update(func() {
+		alloc2.ClientStatus = structs.AllocClientStatusComplete
+		alloc3 := runningAlloc(job, 1, 20003)
+		alloc3.TaskResources["web"].Networks[0].IP = "192.168.0.101"
+		rpc.allocs = append(rpc.allocs, alloc3)
+	})
+
+	// Wait for restart
+	select {
+	case <-harness.mockHooks.RestartCh:
+	case <-time.After(time.Duration(5*testutil.TestMultiplier()) * time.Second):
+		t.Fatalf("Should have received a restart: %+v", harness.mockHooks)
+	}
+
+	raw, err = ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read rendered template from %q: %v", path, err)
+	}
+	expected = "0=192.168.0.100:20000\n1=192.168.0.101:20003\n"
+	if s := string(raw); s != expected {
+		t.Fatalf("Unexpected template data; got %q, want %q", s, expected)
+	}
+}
+
+func TestTaskTemplateManager_Nomad_ServiceAndVar(t *testing.T) {
+	job := mock.Job()
+	job.Meta = map[string]string{"version": "1.2.3"}
+	job.TaskGroups[0].Tasks[0].Services[0].Name = "frontend"
+	rpc := newMockNomadRPC(job, runningAlloc(job, 0, 20000))
+
+	file := "config.txt"
+	template := &structs.Template{
+		EmbeddedTmpl: fmt.Sprintf(`{{range nomadService "frontend"}}{{.Task}} {{.Address}}:{{.Port}} {{end}}`+
+			`{{nomadVar "version"}} {{nomadVar "version@%s"}} [{{nomadVar "missing"}}]`, job.ID),
+		DestPath:   file,
+		ChangeMode: structs.TemplateChangeModeNoop,
+	}
+
+	harness := newTestHarness(t, []*structs.Template{template}, false, false)
+	harness.nomadFuncs = newNomadTemplateFuncs(rpc, "global", job.ID)
+	harness.start(t)
+	defer harness.stop()
+
+	select {
+	case <-harness.mockHooks.UnblockCh:
+	case <-time.After(time.Duration(5*testutil.TestMultiplier()) * time.Second):
+		t.Fatalf("Task unblock should have been called")
+	}
+
+	path := filepath.Join(harness.taskDir, file)
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read rendered template from %q: %v", path, err)
+	}
+	expected := "web 192.168.0.100:20000 1.2.3 1.2.3 []"
+	if s := string(raw); s != expected {
+		t.Fatalf("Unexpected template data; got %q, want %q", s, expected)
+	}
+}
+
+func TestNomadTemplateFuncs_Invalid(t *testing.T) {
+	n := newNomadTemplateFuncs(nil, "global", "example")
+	if _, err := n.query(nomadVarQuery, "example", "key"); err == nil {
+		t.Fatalf("expected error without rpc")
+	}
+
+	n = newNomadTemplateFuncs(newMockNomadRPC(mock.Job()), "global", "example")
+	funcs := n.FuncMap(nil, nil, nil)
+	allocs := funcs["nomadAllocs"].(func(string) ([]*NomadAlloc, error))
+	for _, s := range []string{"example", "/web", "example/"} {
+		if _, err := allocs(s); err == nil {
+			t.Fatalf("expected error for %q", s)
+		}
+	}
+	vars := funcs["nomadVar"].(func(string) (string, error))
+	if _, err := vars("@example"); err == nil {
+		t.Fatalf("expected error for missing key")
+	}
+}
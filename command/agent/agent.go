@@ -19,6 +19,7 @@ import (
 	"github.com/hashicorp/nomad/command/agent/consul"
 	"github.com/hashicorp/nomad/nomad"
 	"github.com/hashicorp/nomad/nomad/structs"
+	sconfig "github.com/hashicorp/nomad/nomad/structs/config"
 )
 
 const (
@@ -188,6 +189,32 @@ func convertServerConfig(agentConfig *Config, logOutput io.Writer) (*nomad.Confi
 		conf.HeartbeatGrace = dur
 	}
 
+	if rotation := agentConfig.Server.IdentityKeyRotation; rotation != "" {
+		dur, err := time.ParseDuration(rotation)
+		if err != nil {
+			return nil, err
+		}
+		conf.IdentityKeyRotation = dur
+	}
+
+	conf.AutoscalerEnabled = agentConfig.Server.AutoscalerEnabled
+	if interval := agentConfig.Server.AutoscalerInterval; interval != "" {
+		dur, err := time.ParseDuration(interval)
+		if err != nil {
+			return nil, err
+		} else if dur <= 0 {
+			return nil, fmt.Errorf("autoscaler_interval must be positive: %v", dur)
+		}
+		conf.AutoscalerInterval = dur
+	}
+	if cooldown := agentConfig.Server.AutoscalerCooldown; cooldown != "" {
+		dur, err := time.ParseDuration(cooldown)
+		if err != nil {
+			return nil, err
+		}
+		conf.AutoscalerCooldown = dur
+	}
+
 	if *agentConfig.Consul.AutoAdvertise && agentConfig.Consul.ServerServiceName == "" {
 		return nil, fmt.Errorf("server_service_name must be set when auto_advertise is enabled")
 	}
@@ -262,6 +289,18 @@ func (a *Agent) clientConfig() (*clientconfig.Config, error) {
 	if a.config.Client.CpuCompute != 0 {
 		conf.CpuCompute = a.config.Client.CpuCompute
 	}
+	if a.config.Client.CNIPath != "" {
+		conf.CNIPath = a.config.Client.CNIPath
+	}
+	if a.config.Client.CNIConfigDir != "" {
+		conf.CNIConfigDir = a.config.Client.CNIConfigDir
+	}
+	if a.config.Client.BridgeNetworkName != "" {
+		conf.BridgeNetworkName = a.config.Client.BridgeNetworkName
+	}
+	if a.config.Client.BridgeNetworkSubnet != "" {
+		conf.BridgeNetworkAllocSubnet = a.config.Client.BridgeNetworkSubnet
+	}
 	if a.config.Client.MaxKillTimeout != "" {
 		dur, err := time.ParseDuration(a.config.Client.MaxKillTimeout)
 		if err != nil {
@@ -318,6 +357,15 @@ func (a *Agent) clientConfig() (*clientconfig.Config, error) {
 	conf.GCInodeUsageThreshold = a.config.Client.GCInodeUsageThreshold
 	conf.NoHostUUID = a.config.Client.NoHostUUID
 
+	// Set the artifact configs, caching inside the state directory by default
+	conf.Artifact = a.config.Client.Artifact.Copy()
+	if conf.Artifact == nil {
+		conf.Artifact = sconfig.DefaultArtifactConfig()
+	}
+	if conf.Artifact.CacheDir == "" && conf.StateDir != "" {
+		conf.Artifact.CacheDir = filepath.Join(conf.StateDir, "artifacts")
+	}
+
 	return conf, nil
 }
 
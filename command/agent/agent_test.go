@@ -248,6 +248,15 @@ func TestAgent_ServerConfig(t *testing.T) {
 		t.Fatalf("expect 37s, got: %s", threshold)
 	}
 
+	conf.Server.IdentityKeyRotation = "72h"
+	if err := conf.normalizeAddrs(); err != nil {
+		t.Fatalf("error normalizing config: %v", err)
+	}
+	out, err = a.serverConfig()
+	if rotation := out.IdentityKeyRotation; rotation != time.Hour*72 {
+		t.Fatalf("expect 72h, got: %s", rotation)
+	}
+
 	// Defaults to the global bind addr
 	conf.Addresses.RPC = ""
 	conf.Addresses.Serf = ""
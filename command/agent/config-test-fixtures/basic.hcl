@@ -40,6 +40,10 @@ client {
 	network_interface = "eth0"
 	network_speed = 100
 	cpu_total_compute = 4444
+	cni_path = "/opt/cni/bin"
+	cni_config_dir = "/opt/cni/config"
+	bridge_network_name = "nomad"
+	bridge_network_subnet = "172.26.64.0/20"
 	reserved {
 		cpu = 10
 		memory = 10
@@ -59,6 +63,12 @@ client {
     gc_disk_usage_threshold = 82
     gc_inode_usage_threshold = 91
     no_host_uuid = true
+    artifact {
+        cache_dir = "/tmp/artifacts"
+        cache_max_size_mb = 2048
+        require_checksum = true
+        gpg_keyrings = ["/etc/nomad/trusted.gpg"]
+    }
 }
 server {
 	enabled = true
@@ -71,6 +81,10 @@ server {
 	job_gc_threshold = "12h"
 	eval_gc_threshold = "12h"
 	heartbeat_grace   = "30s"
+	identity_key_rotation = "48h"
+	autoscaler_enabled = true
+	autoscaler_interval = "1m"
+	autoscaler_cooldown = "10m"
 	retry_join = [ "1.1.1.1", "2.2.2.2" ]
 	start_join = [ "1.1.1.1", "2.2.2.2" ]
 	retry_max = 3
@@ -130,6 +144,7 @@ vault {
     tls_server_name = "foobar"
     tls_skip_verify = true
     create_from_role = "test_role"
+    allowed_roles = ["batch_role", "web_role"]
 }
 tls {
     http = true
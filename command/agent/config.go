@@ -185,6 +185,22 @@ type ClientConfig struct {
 	// CpuCompute is used to override any detected or default total CPU compute.
 	CpuCompute int `mapstructure:"cpu_total_compute"`
 
+	// CNIPath is the path to search for CNI plugins used by allocations with
+	// bridge or CNI networking.
+	CNIPath string `mapstructure:"cni_path"`
+
+	// CNIConfigDir is the directory containing the CNI network configurations
+	// that jobs can select using the "cni/<name>" network mode.
+	CNIConfigDir string `mapstructure:"cni_config_dir"`
+
+	// BridgeNetworkName is the name of the bridge created for allocations
+	// with bridge networking.
+	BridgeNetworkName string `mapstructure:"bridge_network_name"`
+
+	// BridgeNetworkSubnet is the subnet allocations with bridge networking
+	// are assigned addresses from.
+	BridgeNetworkSubnet string `mapstructure:"bridge_network_subnet"`
+
 	// MaxKillTimeout allows capping the user-specifiable KillTimeout.
 	MaxKillTimeout string `mapstructure:"max_kill_timeout"`
 
@@ -220,6 +236,9 @@ type ClientConfig struct {
 	// NoHostUUID disables using the host's UUID and will force generation of a
 	// random UUID.
 	NoHostUUID bool `mapstructure:"no_host_uuid"`
+
+	// Artifact configures the caching and verification of task artifacts.
+	Artifact *config.ArtifactConfig `mapstructure:"artifact"`
 }
 
 // ServerConfig is configuration specific to the server mode
@@ -267,6 +286,23 @@ type ServerConfig struct {
 	// processing delays and clock skew before marking a node as "down".
 	HeartbeatGrace string `mapstructure:"heartbeat_grace"`
 
+	// IdentityKeyRotation is how often the leader rotates the key signing the
+	// workload identities of tasks.
+	IdentityKeyRotation string `mapstructure:"identity_key_rotation"`
+
+	// AutoscalerEnabled enables the built-in autoscaler run by the leader,
+	// which changes the count of task groups according to their scaling
+	// policies.
+	AutoscalerEnabled bool `mapstructure:"autoscaler_enabled"`
+
+	// AutoscalerInterval is the interval at which the autoscaler evaluates
+	// the scaling policies.
+	AutoscalerInterval string `mapstructure:"autoscaler_interval"`
+
+	// AutoscalerCooldown is the default minimum duration between two changes
+	// of the count of a task group by the autoscaler.
+	AutoscalerCooldown string `mapstructure:"autoscaler_cooldown"`
+
 	// StartJoin is a list of addresses to attempt to join when the
 	// agent starts. If Serf is unable to communicate with any of these
 	// addresses, then the agent will error and exit.
@@ -534,6 +570,7 @@ func DefaultConfig() *Config {
 			GCParallelDestroys:    2,
 			GCInodeUsageThreshold: 70,
 			GCDiskUsageThreshold:  80,
+			Artifact:              config.DefaultArtifactConfig(),
 		},
 		Server: &ServerConfig{
 			Enabled:          false,
@@ -867,6 +904,18 @@ func (a *ServerConfig) Merge(b *ServerConfig) *ServerConfig {
 	if b.HeartbeatGrace != "" {
 		result.HeartbeatGrace = b.HeartbeatGrace
 	}
+	if b.IdentityKeyRotation != "" {
+		result.IdentityKeyRotation = b.IdentityKeyRotation
+	}
+	if b.AutoscalerEnabled {
+		result.AutoscalerEnabled = true
+	}
+	if b.AutoscalerInterval != "" {
+		result.AutoscalerInterval = b.AutoscalerInterval
+	}
+	if b.AutoscalerCooldown != "" {
+		result.AutoscalerCooldown = b.AutoscalerCooldown
+	}
 	if b.RetryMaxAttempts != 0 {
 		result.RetryMaxAttempts = b.RetryMaxAttempts
 	}
@@ -922,6 +971,18 @@ func (a *ClientConfig) Merge(b *ClientConfig) *ClientConfig {
 	if b.CpuCompute != 0 {
 		result.CpuCompute = b.CpuCompute
 	}
+	if b.CNIPath != "" {
+		result.CNIPath = b.CNIPath
+	}
+	if b.CNIConfigDir != "" {
+		result.CNIConfigDir = b.CNIConfigDir
+	}
+	if b.BridgeNetworkName != "" {
+		result.BridgeNetworkName = b.BridgeNetworkName
+	}
+	if b.BridgeNetworkSubnet != "" {
+		result.BridgeNetworkSubnet = b.BridgeNetworkSubnet
+	}
 	if b.MaxKillTimeout != "" {
 		result.MaxKillTimeout = b.MaxKillTimeout
 	}
@@ -952,6 +1013,11 @@ func (a *ClientConfig) Merge(b *ClientConfig) *ClientConfig {
 	if b.NoHostUUID {
 		result.NoHostUUID = b.NoHostUUID
 	}
+	if result.Artifact == nil && b.Artifact != nil {
+		result.Artifact = b.Artifact.Copy()
+	} else if b.Artifact != nil {
+		result.Artifact = result.Artifact.Merge(b.Artifact)
+	}
 
 	// Add the servers
 	result.Servers = append(result.Servers, b.Servers...)
@@ -337,6 +337,10 @@ func parseClient(result **ClientConfig, list *ast.ObjectList) error {
 		"network_interface",
 		"network_speed",
 		"cpu_total_compute",
+		"cni_path",
+		"cni_config_dir",
+		"bridge_network_name",
+		"bridge_network_subnet",
 		"max_kill_timeout",
 		"client_max_port",
 		"client_min_port",
@@ -347,6 +351,7 @@ func parseClient(result **ClientConfig, list *ast.ObjectList) error {
 		"gc_inode_usage_threshold",
 		"gc_parallel_destroys",
 		"no_host_uuid",
+		"artifact",
 	}
 	if err := checkHCLKeys(listVal, valid); err != nil {
 		return err
@@ -362,6 +367,7 @@ func parseClient(result **ClientConfig, list *ast.ObjectList) error {
 	delete(m, "chroot_env")
 	delete(m, "reserved")
 	delete(m, "stats")
+	delete(m, "artifact")
 
 	var config ClientConfig
 	dec, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
@@ -425,10 +431,59 @@ func parseClient(result **ClientConfig, list *ast.ObjectList) error {
 		}
 	}
 
+	// Parse artifact config
+	if o := listVal.Filter("artifact"); len(o.Items) > 0 {
+		if err := parseArtifact(&config.Artifact, o); err != nil {
+			return multierror.Prefix(err, "artifact ->")
+		}
+	}
+
 	*result = &config
 	return nil
 }
 
+func parseArtifact(result **config.ArtifactConfig, list *ast.ObjectList) error {
+	list = list.Elem()
+	if len(list.Items) > 1 {
+		return fmt.Errorf("only one 'artifact' block allowed")
+	}
+
+	// Get our artifact object
+	obj := list.Items[0]
+
+	// Value should be an object
+	var listVal *ast.ObjectList
+	if ot, ok := obj.Val.(*ast.ObjectType); ok {
+		listVal = ot.List
+	} else {
+		return fmt.Errorf("artifact value: should be an object")
+	}
+
+	// Check for invalid keys
+	valid := []string{
+		"cache_dir",
+		"cache_max_size_mb",
+		"require_checksum",
+		"gpg_keyrings",
+	}
+	if err := checkHCLKeys(listVal, valid); err != nil {
+		return err
+	}
+
+	var m map[string]interface{}
+	if err := hcl.DecodeObject(&m, listVal); err != nil {
+		return err
+	}
+
+	var artifact config.ArtifactConfig
+	if err := mapstructure.WeakDecode(m, &artifact); err != nil {
+		return err
+	}
+
+	*result = &artifact
+	return nil
+}
+
 func parseReserved(result **Resources, list *ast.ObjectList) error {
 	list = list.Elem()
 	if len(list.Items) > 1 {
@@ -504,6 +559,10 @@ func parseServer(result **ServerConfig, list *ast.ObjectList) error {
 		"eval_gc_threshold",
 		"job_gc_threshold",
 		"heartbeat_grace",
+		"identity_key_rotation",
+		"autoscaler_enabled",
+		"autoscaler_interval",
+		"autoscaler_cooldown",
 		"start_join",
 		"retry_join",
 		"retry_max",
@@ -638,6 +697,7 @@ func parseConsulConfig(result **config.ConsulConfig, list *ast.ObjectList) error
 		"checks_use_advertise",
 		"client_auto_join",
 		"client_service_name",
+		"grpc_address",
 		"key_file",
 		"server_auto_join",
 		"server_service_name",
@@ -721,6 +781,7 @@ func parseVaultConfig(result **config.VaultConfig, list *ast.ObjectList) error {
 	valid := []string{
 		"address",
 		"allow_unauthenticated",
+		"allowed_roles",
 		"enabled",
 		"task_token_ttl",
 		"ca_file",
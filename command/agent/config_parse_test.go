@@ -57,12 +57,16 @@ func TestConfig_Parse(t *testing.T) {
 						"/opt/myapp/etc": "/etc",
 						"/opt/myapp/bin": "/bin",
 					},
-					NetworkInterface: "eth0",
-					NetworkSpeed:     100,
-					CpuCompute:       4444,
-					MaxKillTimeout:   "10s",
-					ClientMinPort:    1000,
-					ClientMaxPort:    2000,
+					NetworkInterface:    "eth0",
+					NetworkSpeed:        100,
+					CpuCompute:          4444,
+					CNIPath:             "/opt/cni/bin",
+					CNIConfigDir:        "/opt/cni/config",
+					BridgeNetworkName:   "nomad",
+					BridgeNetworkSubnet: "172.26.64.0/20",
+					MaxKillTimeout:      "10s",
+					ClientMinPort:       1000,
+					ClientMaxPort:       2000,
 					Reserved: &Resources{
 						CPU:                 10,
 						MemoryMB:            10,
@@ -76,24 +80,34 @@ func TestConfig_Parse(t *testing.T) {
 					GCDiskUsageThreshold:  82,
 					GCInodeUsageThreshold: 91,
 					NoHostUUID:            true,
+					Artifact: &config.ArtifactConfig{
+						CacheDir:        "/tmp/artifacts",
+						CacheMaxSizeMB:  2048,
+						RequireChecksum: &trueValue,
+						GPGKeyrings:     []string{"/etc/nomad/trusted.gpg"},
+					},
 				},
 				Server: &ServerConfig{
-					Enabled:           true,
-					BootstrapExpect:   5,
-					DataDir:           "/tmp/data",
-					ProtocolVersion:   3,
-					NumSchedulers:     2,
-					EnabledSchedulers: []string{"test"},
-					NodeGCThreshold:   "12h",
-					EvalGCThreshold:   "12h",
-					JobGCThreshold:    "12h",
-					HeartbeatGrace:    "30s",
-					RetryJoin:         []string{"1.1.1.1", "2.2.2.2"},
-					StartJoin:         []string{"1.1.1.1", "2.2.2.2"},
-					RetryInterval:     "15s",
-					RejoinAfterLeave:  true,
-					RetryMaxAttempts:  3,
-					EncryptKey:        "abc",
+					Enabled:             true,
+					BootstrapExpect:     5,
+					DataDir:             "/tmp/data",
+					ProtocolVersion:     3,
+					NumSchedulers:       2,
+					EnabledSchedulers:   []string{"test"},
+					NodeGCThreshold:     "12h",
+					EvalGCThreshold:     "12h",
+					JobGCThreshold:      "12h",
+					HeartbeatGrace:      "30s",
+					IdentityKeyRotation: "48h",
+					AutoscalerEnabled:   true,
+					AutoscalerInterval:  "1m",
+					AutoscalerCooldown:  "10m",
+					RetryJoin:           []string{"1.1.1.1", "2.2.2.2"},
+					StartJoin:           []string{"1.1.1.1", "2.2.2.2"},
+					RetryInterval:       "15s",
+					RejoinAfterLeave:    true,
+					RetryMaxAttempts:    3,
+					EncryptKey:          "abc",
 				},
 				Telemetry: &Telemetry{
 					StatsiteAddr:             "127.0.0.1:1234",
@@ -138,6 +152,7 @@ func TestConfig_Parse(t *testing.T) {
 					AllowUnauthenticated: &trueValue,
 					Enabled:              &falseValue,
 					Role:                 "test_role",
+					AllowedRoles:         []string{"batch_role", "web_role"},
 					TLSCaFile:            "/path/to/ca/file",
 					TLSCaPath:            "/path/to/ca",
 					TLSCertFile:          "/path/to/cert/file",
@@ -217,21 +217,29 @@ func TestConfig_Merge(t *testing.T) {
 			GCParallelDestroys:    6,
 			GCDiskUsageThreshold:  71,
 			GCInodeUsageThreshold: 86,
+			Artifact: &config.ArtifactConfig{
+				CacheDir:       "/tmp/artifacts",
+				CacheMaxSizeMB: 1024,
+			},
 		},
 		Server: &ServerConfig{
-			Enabled:           true,
-			BootstrapExpect:   2,
-			DataDir:           "/tmp/data2",
-			ProtocolVersion:   2,
-			NumSchedulers:     2,
-			EnabledSchedulers: []string{structs.JobTypeBatch},
-			NodeGCThreshold:   "12h",
-			HeartbeatGrace:    "2m",
-			RejoinAfterLeave:  true,
-			StartJoin:         []string{"1.1.1.1"},
-			RetryJoin:         []string{"1.1.1.1"},
-			RetryInterval:     "10s",
-			retryInterval:     time.Second * 10,
+			Enabled:             true,
+			BootstrapExpect:     2,
+			DataDir:             "/tmp/data2",
+			ProtocolVersion:     2,
+			NumSchedulers:       2,
+			EnabledSchedulers:   []string{structs.JobTypeBatch},
+			NodeGCThreshold:     "12h",
+			HeartbeatGrace:      "2m",
+			IdentityKeyRotation: "24h",
+			AutoscalerEnabled:   true,
+			AutoscalerInterval:  "1m",
+			AutoscalerCooldown:  "10m",
+			RejoinAfterLeave:    true,
+			StartJoin:           []string{"1.1.1.1"},
+			RetryJoin:           []string{"1.1.1.1"},
+			RetryInterval:       "10s",
+			retryInterval:       time.Second * 10,
 		},
 		Ports: &Ports{
 			HTTP: 20000,
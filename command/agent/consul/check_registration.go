@@ -0,0 +1,71 @@
+package consul
+
+import (
+	"net"
+	"strconv"
+
+	consul "github.com/hashicorp/consul/api"
+
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+// checkRegistration is the registration of a check that uses fields the
+// vendored Consul API predates, such as gRPC checks or the method, headers and
+// body of HTTP checks. The registration is written to the agent endpoint
+// directly.
+type checkRegistration struct {
+	*consul.AgentCheckRegistration
+
+	GRPC       string              `json:",omitempty"`
+	GRPCUseTLS bool                `json:",omitempty"`
+	Method     string              `json:",omitempty"`
+	Header     map[string][]string `json:",omitempty"`
+	Body       string              `json:",omitempty"`
+}
+
+// newCheckRegistration returns the extended registration of the check or nil
+// if the check can be registered using the vendored Consul API. The host and
+// port are the address the check is run against.
+func newCheckRegistration(check *structs.ServiceCheck, chkReg *consul.AgentCheckRegistration,
+	host string, port int) *checkRegistration {
+
+	switch check.Type {
+	case structs.ServiceCheckHTTP:
+		if check.Method == "" && len(check.Header) == 0 && check.Body == "" {
+			return nil
+		}
+		return &checkRegistration{
+			AgentCheckRegistration: chkReg,
+			Method:                 check.Method,
+			Header:                 check.Header,
+			Body:                   check.Body,
+		}
+	case structs.ServiceCheckGRPC:
+		// Consul expects the address optionally followed by the service
+		// to check.
+		addr := net.JoinHostPort(host, strconv.Itoa(port))
+		if check.GRPCService != "" {
+			addr += "/" + check.GRPCService
+		}
+		return &checkRegistration{
+			AgentCheckRegistration: chkReg,
+			GRPC:                   addr,
+			GRPCUseTLS:             check.GRPCUseTLS,
+		}
+	default:
+		return nil
+	}
+}
+
+// lookupExtendedCheck returns the extended registration of the check with the
+// given ID or nil if the check uses the vendored Consul API.
+func (c *Syncer) lookupExtendedCheck(checkID string) *checkRegistration {
+	c.groupsLock.RLock()
+	defer c.groupsLock.RUnlock()
+	for _, checks := range c.extendedCheckGroups {
+		if check, ok := checks[checkID]; ok {
+			return check
+		}
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package consul
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/nomad/nomad/structs"
+	"github.com/hashicorp/nomad/nomad/structs/config"
+)
+
+func TestSyncer_ExtendedChecks(t *testing.T) {
+	agent, srv := newFakeConsulAgent()
+	defer srv.Close()
+
+	conf := config.DefaultConsulConfig()
+	conf.Addr = strings.TrimPrefix(srv.URL, "http://")
+	cs, err := NewSyncer(conf, make(chan struct{}), logger)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	cs.SetAddrFinder(func(label string) (string, int) {
+		return "10.0.0.1", 8080
+	})
+
+	httpCheck := &structs.ServiceCheck{
+		Name:          "http",
+		Type:          structs.ServiceCheckHTTP,
+		Path:          "/health",
+		Protocol:      "https",
+		Method:        "POST",
+		Header:        map[string][]string{"X-Foo": {"bar", "baz"}},
+		Body:          "{}",
+		TLSSkipVerify: true,
+		Interval:      10 * time.Second,
+		Timeout:       2 * time.Second,
+	}
+	grpcCheck := &structs.ServiceCheck{
+		Name:        "grpc",
+		Type:        structs.ServiceCheckGRPC,
+		GRPCService: "foo.Bar",
+		GRPCUseTLS:  true,
+		Interval:    10 * time.Second,
+		Timeout:     2 * time.Second,
+	}
+	tcpCheck := &structs.ServiceCheck{
+		Name:     "tcp",
+		Type:     structs.ServiceCheckTCP,
+		Interval: 10 * time.Second,
+		Timeout:  2 * time.Second,
+	}
+	service := &structs.Service{
+		Name:      "api",
+		PortLabel: "http",
+		Checks:    []*structs.ServiceCheck{httpCheck, grpcCheck, tcpCheck},
+	}
+	domain := NewExecutorDomain(allocID, "api")
+	services := map[ServiceKey]*structs.Service{
+		GenerateServiceKey(service): service,
+	}
+	if err := cs.SetServices(domain, services); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := cs.SyncServices(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	agent.Lock()
+	defer agent.Unlock()
+	if n := len(agent.checks); n != 3 {
+		t.Fatalf("expected 3 checks to be registered; got %d", n)
+	}
+
+	byName := make(map[string]map[string]interface{})
+	for _, reg := range agent.checks {
+		byName[reg["Name"].(string)] = reg
+	}
+
+	h := byName["http"]
+	if h["HTTP"] != "https://10.0.0.1:8080/health" || h["Method"] != "POST" || h["Body"] != "{}" || h["TLSSkipVerify"] != true {
+		t.Fatalf("bad http check registration: %#v", h)
+	}
+	expHeader := map[string]interface{}{"X-Foo": []interface{}{"bar", "baz"}}
+	if !reflect.DeepEqual(h["Header"], expHeader) {
+		t.Fatalf("bad http check header: got %#v; want %#v", h["Header"], expHeader)
+	}
+
+	g := byName["grpc"]
+	if g["GRPC"] != "10.0.0.1:8080/foo.Bar" || g["GRPCUseTLS"] != true {
+		t.Fatalf("bad grpc check registration: %#v", g)
+	}
+
+	tcp := byName["tcp"]
+	if tcp["TCP"] != "10.0.0.1:8080" {
+		t.Fatalf("bad tcp check registration: %#v", tcp)
+	}
+	for _, k := range []string{"GRPC", "Method", "Header", "Body"} {
+		if _, ok := tcp[k]; ok {
+			t.Fatalf("unexpected %q in tcp check registration: %#v", k, tcp)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package consul
+
+import (
+	"fmt"
+
+	consul "github.com/hashicorp/consul/api"
+
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+const (
+	// connectProxyKind is the kind of the service registered with Consul for
+	// the sidecar proxy of a Connect enabled service.
+	connectProxyKind = "connect-proxy"
+
+	// sidecarProxySuffix is appended to the service key of a Connect enabled
+	// service to build the key of its sidecar proxy service.
+	sidecarProxySuffix = "sidecar-proxy"
+)
+
+// connectProxyRegistration is the registration of a Connect sidecar proxy
+// service. The vendored Consul API predates Connect so the registration is
+// written to the agent endpoint directly.
+type connectProxyRegistration struct {
+	Kind    string
+	ID      string
+	Name    string
+	Tags    []string `json:",omitempty"`
+	Port    int      `json:",omitempty"`
+	Address string   `json:",omitempty"`
+	Proxy   *connectProxyConfig
+}
+
+// connectProxyConfig is the proxy configuration of a Connect sidecar proxy
+// service.
+type connectProxyConfig struct {
+	DestinationServiceName string
+	DestinationServiceID   string
+	LocalServiceAddress    string             `json:",omitempty"`
+	LocalServicePort       int                `json:",omitempty"`
+	Upstreams              []*connectUpstream `json:",omitempty"`
+}
+
+// connectUpstream is an upstream of a Connect sidecar proxy.
+type connectUpstream struct {
+	DestinationName string
+	LocalBindPort   int
+}
+
+// sidecarProxyKey returns the service key of the sidecar proxy of the service
+// with the given key.
+func sidecarProxyKey(key ServiceKey) ServiceKey {
+	return ServiceKey(fmt.Sprintf("%s-%s", key, sidecarProxySuffix))
+}
+
+// ConnectProxyServiceID returns the Consul service ID of the sidecar proxy
+// registered for the Connect enabled service in the given domain.
+func ConnectProxyServiceID(domain ServiceDomain, service *structs.Service) string {
+	return string(generateConsulServiceID(domain, sidecarProxyKey(GenerateServiceKey(service))))
+}
+
+// createConnectProxy creates the Consul registration of the sidecar proxy of a
+// Connect enabled service. The returned AgentServiceRegistration is used to
+// diff the proxy against the Consul agent while the connectProxyRegistration
+// is what gets registered.
+func (c *Syncer) createConnectProxy(service *structs.Service, serviceReg *consul.AgentServiceRegistration,
+	domain ServiceDomain, key ServiceKey) (*consul.AgentServiceRegistration, *connectProxyRegistration, error) {
+
+	c.registryLock.RLock()
+	defer c.registryLock.RUnlock()
+
+	sidecar := service.Connect.SidecarService
+	host, port := c.addrFinder(sidecar.Port)
+	if port == 0 {
+		return nil, nil, fmt.Errorf("sidecar proxy of service %q has no port %q", service.Name, sidecar.Port)
+	}
+
+	srv := &consul.AgentServiceRegistration{
+		ID:      string(generateConsulServiceID(domain, sidecarProxyKey(key))),
+		Name:    fmt.Sprintf("%s-%s", service.Name, sidecarProxySuffix),
+		Tags:    service.Tags,
+		Port:    port,
+		Address: host,
+	}
+
+	proxy := &connectProxyConfig{
+		DestinationServiceName: serviceReg.Name,
+		DestinationServiceID:   serviceReg.ID,
+		LocalServiceAddress:    "127.0.0.1",
+		LocalServicePort:       serviceReg.Port,
+	}
+	if sidecar.Proxy != nil {
+		for _, u := range sidecar.Proxy.Upstreams {
+			proxy.Upstreams = append(proxy.Upstreams, &connectUpstream{
+				DestinationName: u.DestinationName,
+				LocalBindPort:   u.LocalBindPort,
+			})
+		}
+	}
+
+	reg := &connectProxyRegistration{
+		Kind:    connectProxyKind,
+		ID:      srv.ID,
+		Name:    srv.Name,
+		Tags:    srv.Tags,
+		Port:    srv.Port,
+		Address: srv.Address,
+		Proxy:   proxy,
+	}
+	return srv, reg, nil
+}
+
+// registerService registers the service with the Consul agent, including the
+// proxy configuration if the service is a Connect sidecar proxy.
+func (c *Syncer) registerService(service *consul.AgentServiceRegistration) error {
+	if proxy := c.lookupConnectProxy(service.ID); proxy != nil {
+		_, err := c.client.Raw().Write("/v1/agent/service/register", proxy, nil, nil)
+		return err
+	}
+	return c.client.Agent().ServiceRegister(service)
+}
+
+// lookupConnectProxy returns the sidecar proxy registration with the given
+// service ID or nil if the service is not a sidecar proxy.
+func (c *Syncer) lookupConnectProxy(serviceID string) *connectProxyRegistration {
+	c.groupsLock.RLock()
+	defer c.groupsLock.RUnlock()
+	for _, proxies := range c.proxyGroups {
+		for _, proxy := range proxies {
+			if proxy.ID == serviceID {
+				return proxy
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,168 @@
+package consul
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/hashicorp/nomad/nomad/structs"
+	"github.com/hashicorp/nomad/nomad/structs/config"
+)
+
+// fakeConsulAgent is a stand-in for the Consul agent HTTP API that records
+// the raw service and check registrations it receives.
+type fakeConsulAgent struct {
+	sync.Mutex
+	services map[string]map[string]interface{}
+	checks   map[string]map[string]interface{}
+}
+
+func newFakeConsulAgent() (*fakeConsulAgent, *httptest.Server) {
+	agent := &fakeConsulAgent{
+		services: make(map[string]map[string]interface{}),
+		checks:   make(map[string]map[string]interface{}),
+	}
+	return agent, httptest.NewServer(agent)
+}
+
+func (f *fakeConsulAgent) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.Lock()
+	defer f.Unlock()
+
+	switch {
+	case r.URL.Path == "/v1/agent/services":
+		out := make(map[string]*api.AgentService, len(f.services))
+		for id, reg := range f.services {
+			port, _ := reg["Port"].(float64)
+			address, _ := reg["Address"].(string)
+			out[id] = &api.AgentService{
+				ID:      id,
+				Service: reg["Name"].(string),
+				Port:    int(port),
+				Address: address,
+			}
+		}
+		json.NewEncoder(w).Encode(out)
+	case r.URL.Path == "/v1/agent/checks":
+		out := make(map[string]*api.AgentCheck, len(f.checks))
+		for id, reg := range f.checks {
+			out[id] = &api.AgentCheck{
+				CheckID:   id,
+				Name:      reg["Name"].(string),
+				ServiceID: reg["ServiceID"].(string),
+			}
+		}
+		json.NewEncoder(w).Encode(out)
+	case r.URL.Path == "/v1/agent/check/register":
+		var reg map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&reg); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		f.checks[reg["ID"].(string)] = reg
+	case strings.HasPrefix(r.URL.Path, "/v1/agent/check/deregister/"):
+		delete(f.checks, strings.TrimPrefix(r.URL.Path, "/v1/agent/check/deregister/"))
+	case r.URL.Path == "/v1/agent/service/register":
+		var reg map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&reg); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		f.services[reg["ID"].(string)] = reg
+	case strings.HasPrefix(r.URL.Path, "/v1/agent/service/deregister/"):
+		delete(f.services, strings.TrimPrefix(r.URL.Path, "/v1/agent/service/deregister/"))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestSyncer_ConnectSidecar(t *testing.T) {
+	agent, srv := newFakeConsulAgent()
+	defer srv.Close()
+
+	conf := config.DefaultConsulConfig()
+	conf.Addr = strings.TrimPrefix(srv.URL, "http://")
+	cs, err := NewSyncer(conf, make(chan struct{}), logger)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	cs.SetAddrFinder(func(label string) (string, int) {
+		switch label {
+		case "http":
+			return "10.0.0.1", 8080
+		case "connect-proxy-api":
+			return "10.0.0.1", 21000
+		}
+		return "", 0
+	})
+
+	service := &structs.Service{
+		Name:      "api",
+		PortLabel: "http",
+		Connect: &structs.ConsulConnect{
+			SidecarService: &structs.ConsulSidecarService{
+				Port: "connect-proxy-api",
+				Proxy: &structs.ConsulProxy{
+					Upstreams: []*structs.ConsulUpstream{
+						{DestinationName: "db", LocalBindPort: 9191},
+					},
+				},
+			},
+		},
+	}
+	domain := NewExecutorDomain(allocID, "api")
+	services := map[ServiceKey]*structs.Service{
+		GenerateServiceKey(service): service,
+	}
+	if err := cs.SetServices(domain, services); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := cs.SyncServices(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	agent.Lock()
+	if n := len(agent.services); n != 2 {
+		agent.Unlock()
+		t.Fatalf("expected service and sidecar to be registered; got %d", n)
+	}
+	proxyID := ConnectProxyServiceID(domain, service)
+	proxy, ok := agent.services[proxyID]
+	agent.Unlock()
+	if !ok {
+		t.Fatalf("sidecar %q not registered", proxyID)
+	}
+
+	if proxy["Kind"] != "connect-proxy" || proxy["Name"] != "api-sidecar-proxy" || proxy["Port"] != float64(21000) {
+		t.Fatalf("bad sidecar registration: %#v", proxy)
+	}
+	proxyConf := proxy["Proxy"].(map[string]interface{})
+	if proxyConf["DestinationServiceName"] != "api" || proxyConf["LocalServicePort"] != float64(8080) {
+		t.Fatalf("bad proxy config: %#v", proxyConf)
+	}
+	upstreams := proxyConf["Upstreams"].([]interface{})
+	if len(upstreams) != 1 {
+		t.Fatalf("bad upstreams: %#v", upstreams)
+	}
+	upstream := upstreams[0].(map[string]interface{})
+	if upstream["DestinationName"] != "db" || upstream["LocalBindPort"] != float64(9191) {
+		t.Fatalf("bad upstream: %#v", upstream)
+	}
+
+	// Removing the service deregisters the sidecar as well
+	if err := cs.SetServices(domain, nil); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := cs.SyncServices(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	agent.Lock()
+	defer agent.Unlock()
+	if n := len(agent.services); n != 0 {
+		t.Fatalf("expected services to be deregistered; got %#v", agent.services)
+	}
+}
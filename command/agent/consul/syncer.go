@@ -100,6 +100,12 @@ func NewExecutorDomain(allocID, task string) ServiceDomain {
 	return ServiceDomain(fmt.Sprintf("executor-%s-%s", allocID, task))
 }
 
+// ServiceIDPrefix returns the prefix of the Consul service IDs of the services
+// registered in the given domain.
+func ServiceIDPrefix(domain ServiceDomain) string {
+	return fmt.Sprintf("%s-%s-", nomadServicePrefix, domain)
+}
+
 // Syncer allows syncing of services and checks with Consul
 type Syncer struct {
 	client          *consul.Client
@@ -112,7 +118,16 @@ type Syncer struct {
 	// independently of the Client or Server's services.
 	servicesGroups map[ServiceDomain]map[ServiceKey]*consul.AgentServiceRegistration
 	checkGroups    map[ServiceDomain]map[ServiceKey][]*consul.AgentCheckRegistration
-	groupsLock     sync.RWMutex
+
+	// proxyGroups holds the registrations of the Connect sidecar proxies
+	// found in servicesGroups keyed the same way.
+	proxyGroups map[ServiceDomain]map[ServiceKey]*connectProxyRegistration
+
+	// extendedCheckGroups holds the registrations of the checks found in
+	// checkGroups that use fields unknown to the vendored Consul API, keyed
+	// by check ID.
+	extendedCheckGroups map[ServiceDomain]map[string]*checkRegistration
+	groupsLock          sync.RWMutex
 
 	// The "Consul Registry" is a collection of Consul Services and
 	// Checks all guarded by the registryLock.
@@ -166,15 +181,17 @@ func NewSyncer(consulConfig *config.ConsulConfig, shutdownCh chan struct{}, logg
 		return nil, err
 	}
 	consulSyncer := Syncer{
-		client:            consulClient,
-		logger:            logger,
-		consulAvailable:   true,
-		shutdownCh:        shutdownCh,
-		servicesGroups:    make(map[ServiceDomain]map[ServiceKey]*consul.AgentServiceRegistration),
-		checkGroups:       make(map[ServiceDomain]map[ServiceKey][]*consul.AgentCheckRegistration),
-		checkRunners:      make(map[consulCheckID]*CheckRunner),
-		periodicCallbacks: make(map[string]types.PeriodicCallback),
-		notifySyncCh:      make(chan struct{}, 1),
+		client:              consulClient,
+		logger:              logger,
+		consulAvailable:     true,
+		shutdownCh:          shutdownCh,
+		servicesGroups:      make(map[ServiceDomain]map[ServiceKey]*consul.AgentServiceRegistration),
+		checkGroups:         make(map[ServiceDomain]map[ServiceKey][]*consul.AgentCheckRegistration),
+		proxyGroups:         make(map[ServiceDomain]map[ServiceKey]*connectProxyRegistration),
+		extendedCheckGroups: make(map[ServiceDomain]map[string]*checkRegistration),
+		checkRunners:        make(map[consulCheckID]*CheckRunner),
+		periodicCallbacks:   make(map[string]types.PeriodicCallback),
+		notifySyncCh:        make(chan struct{}, 1),
 		// default noop implementation of addrFinder
 		addrFinder:   func(string) (string, int) { return "", 0 },
 		syncInterval: defaultSyncInterval,
@@ -220,6 +237,8 @@ func (c *Syncer) SetServices(domain ServiceDomain, services map[ServiceKey]*stru
 	numServ := len(services)
 	registeredServices := make(map[ServiceKey]*consul.AgentServiceRegistration, numServ)
 	registeredChecks := make(map[ServiceKey][]*consul.AgentCheckRegistration, numServ)
+	registeredProxies := make(map[ServiceKey]*connectProxyRegistration)
+	extendedChecks := make(map[string]*checkRegistration)
 	for serviceKey, service := range services {
 		serviceReg, err := c.createService(service, domain, serviceKey)
 		if err != nil {
@@ -228,14 +247,29 @@ func (c *Syncer) SetServices(domain ServiceDomain, services map[ServiceKey]*stru
 		}
 		registeredServices[serviceKey] = serviceReg
 
+		// Register the sidecar proxy of Connect enabled services
+		if service.Connect.HasSidecar() {
+			proxySrv, proxyReg, err := c.createConnectProxy(service, serviceReg, domain, serviceKey)
+			if err != nil {
+				mErr.Errors = append(mErr.Errors, err)
+				continue
+			}
+			proxyKey := sidecarProxyKey(serviceKey)
+			registeredServices[proxyKey] = proxySrv
+			registeredProxies[proxyKey] = proxyReg
+		}
+
 		// Register the check(s) for this service
 		for _, chk := range service.Checks {
 			// Create a Consul check registration
-			chkReg, err := c.createCheckReg(chk, serviceReg)
+			chkReg, extReg, err := c.createCheckReg(chk, serviceReg)
 			if err != nil {
 				mErr.Errors = append(mErr.Errors, err)
 				continue
 			}
+			if extReg != nil {
+				extendedChecks[chkReg.ID] = extReg
+			}
 
 			// creating a nomad check if we have to handle this particular check type
 			c.registryLock.RLock()
@@ -311,6 +345,20 @@ func (c *Syncer) SetServices(domain ServiceDomain, services map[ServiceKey]*stru
 	for checkKey, checks := range registeredChecks {
 		checkKeys[checkKey] = checks
 	}
+
+	// Replace the sidecar proxies of the domain
+	if len(registeredProxies) > 0 {
+		c.proxyGroups[domain] = registeredProxies
+	} else {
+		delete(c.proxyGroups, domain)
+	}
+
+	// Replace the extended checks of the domain
+	if len(extendedChecks) > 0 {
+		c.extendedCheckGroups[domain] = extendedChecks
+	} else {
+		delete(c.extendedCheckGroups, domain)
+	}
 	c.groupsLock.Unlock()
 
 	// Sync immediately
@@ -679,13 +727,13 @@ func (c *Syncer) syncServices() error {
 	var mErr multierror.Error
 	missingServices, _, changedServices, removedServices := c.calcServicesDiff(consulServices)
 	for _, service := range missingServices {
-		if err := c.client.Agent().ServiceRegister(service); err != nil {
+		if err := c.registerService(service); err != nil {
 			mErr.Errors = append(mErr.Errors, err)
 		}
 	}
 	for _, service := range changedServices {
 		// Re-register the local service
-		if err := c.client.Agent().ServiceRegister(service); err != nil {
+		if err := c.registerService(service); err != nil {
 			mErr.Errors = append(mErr.Errors, err)
 		}
 	}
@@ -704,6 +752,10 @@ func (c *Syncer) registerCheck(chkReg *consul.AgentCheckRegistration) error {
 		cr.Start()
 	}
 	c.registryLock.RUnlock()
+	if ext := c.lookupExtendedCheck(chkReg.ID); ext != nil {
+		_, err := c.client.Raw().Write("/v1/agent/check/register", ext, nil, nil)
+		return err
+	}
 	return c.client.Agent().CheckRegister(chkReg)
 }
 
@@ -718,8 +770,10 @@ func (c *Syncer) ensureCheckRunning(chk *consul.AgentCheckRegistration) {
 }
 
 // createCheckReg creates a Check that can be registered with Nomad. It also
-// creates a Nomad check for the check types that it can handle.
-func (c *Syncer) createCheckReg(check *structs.ServiceCheck, serviceReg *consul.AgentServiceRegistration) (*consul.AgentCheckRegistration, error) {
+// creates a Nomad check for the check types that it can handle. If the check
+// uses fields the vendored Consul API does not know about, the returned
+// checkRegistration is what gets registered with Consul.
+func (c *Syncer) createCheckReg(check *structs.ServiceCheck, serviceReg *consul.AgentServiceRegistration) (*consul.AgentCheckRegistration, *checkRegistration, error) {
 	chkReg := consul.AgentCheckRegistration{
 		ID:        check.Hash(serviceReg.ID),
 		Name:      check.Name,
@@ -742,19 +796,22 @@ func (c *Syncer) createCheckReg(check *structs.ServiceCheck, serviceReg *consul.
 		}
 		relative, err := url.Parse(check.Path)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		url := base.ResolveReference(relative)
 		chkReg.HTTP = url.String()
+		chkReg.TLSSkipVerify = check.TLSSkipVerify
 	case structs.ServiceCheckTCP:
 		chkReg.TCP = net.JoinHostPort(host, strconv.Itoa(port))
+	case structs.ServiceCheckGRPC:
+		chkReg.TLSSkipVerify = check.TLSSkipVerify
 	case structs.ServiceCheckScript:
 		chkReg.TTL = (check.Interval + ttlCheckBuffer).String()
 	default:
-		return nil, fmt.Errorf("check type %+q not valid", check.Type)
+		return nil, nil, fmt.Errorf("check type %+q not valid", check.Type)
 	}
 	chkReg.Status = check.InitialStatus
-	return &chkReg, nil
+	return &chkReg, newCheckRegistration(check, &chkReg, host, port), nil
 }
 
 // generateConsulServiceID takes the domain and service key and returns a Consul
@@ -149,9 +149,9 @@ func TestCheckRegistration(t *testing.T) {
 	}
 	cs.SetAddrFinder(task.FindHostAndPortFor)
 	srvReg, _ := cs.createService(&service1, "domain", "key")
-	check1Reg, _ := cs.createCheckReg(&check1, srvReg)
-	check2Reg, _ := cs.createCheckReg(&check2, srvReg)
-	check3Reg, _ := cs.createCheckReg(&check3, srvReg)
+	check1Reg, _, _ := cs.createCheckReg(&check1, srvReg)
+	check2Reg, _, _ := cs.createCheckReg(&check2, srvReg)
+	check3Reg, _, _ := cs.createCheckReg(&check3, srvReg)
 
 	expected := "10.10.11.5:20002"
 	if check1Reg.TCP != expected {
@@ -35,6 +35,9 @@ func (s *HTTPServer) EvalSpecificRequest(resp http.ResponseWriter, req *http.Req
 	case strings.HasSuffix(path, "/allocations"):
 		evalID := strings.TrimSuffix(path, "/allocations")
 		return s.evalAllocations(resp, req, evalID)
+	case strings.HasSuffix(path, "/plan"):
+		evalID := strings.TrimSuffix(path, "/plan")
+		return s.evalPlans(resp, req, evalID)
 	default:
 		return s.evalQuery(resp, req, path)
 	}
@@ -64,6 +67,30 @@ func (s *HTTPServer) evalAllocations(resp http.ResponseWriter, req *http.Request
 	return out.Allocations, nil
 }
 
+func (s *HTTPServer) evalPlans(resp http.ResponseWriter, req *http.Request, evalID string) (interface{}, error) {
+	if req.Method != "GET" {
+		return nil, CodedError(405, ErrInvalidMethod)
+	}
+
+	args := structs.EvalSpecificRequest{
+		EvalID: evalID,
+	}
+	if s.parse(resp, req, &args.Region, &args.QueryOptions) {
+		return nil, nil
+	}
+
+	var out structs.EvalPlansResponse
+	if err := s.agent.RPC("Eval.Plans", &args, &out); err != nil {
+		return nil, err
+	}
+
+	setMeta(resp, &out.QueryMeta)
+	if out.Plans == nil {
+		return nil, CodedError(404, "eval plans not found")
+	}
+	return out.Plans, nil
+}
+
 func (s *HTTPServer) evalQuery(resp http.ResponseWriter, req *http.Request, evalID string) (interface{}, error) {
 	if req.Method != "GET" {
 		return nil, CodedError(405, ErrInvalidMethod)
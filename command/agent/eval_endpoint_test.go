@@ -3,6 +3,7 @@ package agent
 import (
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/hashicorp/nomad/nomad/mock"
@@ -193,3 +194,51 @@ func TestHTTP_EvalQuery(t *testing.T) {
 		}
 	})
 }
+
+func TestHTTP_EvalPlans(t *testing.T) {
+	httpTest(t, nil, func(s *TestServer) {
+		// Directly manipulate the state
+		state := s.Agent.server.State()
+		eval := mock.Eval()
+		plan := &structs.AppliedPlan{EvalID: eval.ID, JobID: eval.JobID}
+		plan.AddAllocs([]*structs.Allocation{mock.Alloc()})
+		if err := state.UpsertEvalPlan(1000, plan); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		// Make the HTTP request
+		req, err := http.NewRequest("GET", "/v1/evaluation/"+eval.ID+"/plan", nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW := httptest.NewRecorder()
+
+		// Make the request
+		obj, err := s.Server.EvalSpecificRequest(respW, req)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		// Check for the index
+		if respW.HeaderMap.Get("X-Nomad-Index") != "1000" {
+			t.Fatalf("bad index: %q", respW.HeaderMap.Get("X-Nomad-Index"))
+		}
+
+		// Check the ouptput
+		plans := obj.(*structs.EvalPlans)
+		if plans.EvalID != eval.ID || len(plans.Plans) != 1 {
+			t.Fatalf("bad: %#v", plans)
+		}
+
+		// Unknown evals return a 404
+		req, err = http.NewRequest("GET", "/v1/evaluation/"+structs.GenerateUUID()+"/plan", nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW = httptest.NewRecorder()
+		_, err = s.Server.EvalSpecificRequest(respW, req)
+		if err == nil || !strings.Contains(err.Error(), "eval plans not found") {
+			t.Fatalf("expected not found error, got: %v", err)
+		}
+	})
+}
@@ -4,12 +4,16 @@ package agent
 
 import (
 	"bytes"
+	"compress/gzip"
+	"encoding/binary"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"math"
 	"net/http"
 	"os"
 	"path/filepath"
+	"regexp"
 	"sort"
 	"strconv"
 	"strings"
@@ -21,6 +25,7 @@ import (
 
 	"github.com/docker/docker/pkg/ioutils"
 	"github.com/hashicorp/nomad/client/allocdir"
+	"github.com/hashicorp/nomad/client/driver/logging"
 	"github.com/hpcloud/tail/watch"
 	"github.com/ugorji/go/codec"
 )
@@ -32,6 +37,8 @@ var (
 	logTypeNotPresentErr  = fmt.Errorf("must provide log type (stdout/stderr)")
 	clientNotRunning      = fmt.Errorf("node is not running a Nomad Client")
 	invalidOrigin         = fmt.Errorf("origin must be start or end")
+	sinceWithOffsetErr    = fmt.Errorf("since can't be used with an offset")
+	untilWithFollowErr    = fmt.Errorf("until can't be used when following logs")
 )
 
 const (
@@ -552,7 +559,13 @@ func (s *HTTPServer) Stream(resp http.ResponseWriter, req *http.Request) (interf
 
 	// If offsetting from the end subtract from the size
 	if origin == "end" {
-		offset = fileInfo.Size - offset
+		size := fileInfo.Size
+		if isCompressed(path) {
+			if size, err = uncompressedSize(fs, path, size); err != nil {
+				return nil, err
+			}
+		}
+		offset = size - offset
 
 	}
 
@@ -564,7 +577,7 @@ func (s *HTTPServer) Stream(resp http.ResponseWriter, req *http.Request) (interf
 	framer.Run()
 	defer framer.Destroy()
 
-	err = s.stream(offset, path, fs, framer, nil)
+	err = s.stream(offset, path, fs, framer, nil, nil)
 	if err != nil && err != syscall.EPIPE {
 		return nil, err
 	}
@@ -573,14 +586,23 @@ func (s *HTTPServer) Stream(resp http.ResponseWriter, req *http.Request) (interf
 }
 
 // stream is the internal method to stream the content of a file. eofCancelCh is
-// used to cancel the stream if triggered while at EOF. If the connection is
-// broken an EPIPE error is returned
+// used to cancel the stream if triggered while at EOF. If a filter is given
+// only the data it selects is sent. Compressed rotated log files are streamed
+// decompressed and end at EOF as they are never written to again. If the
+// connection is broken an EPIPE error is returned
 func (s *HTTPServer) stream(offset int64, path string,
 	fs allocdir.AllocDirFS, framer *StreamFramer,
-	eofCancelCh chan error) error {
+	eofCancelCh chan error, filter *logFilter) error {
 
 	// Get the reader
-	f, err := fs.ReadAt(path, offset)
+	compressed := isCompressed(path)
+	var f io.ReadCloser
+	var err error
+	if compressed {
+		f, err = readCompressedAt(fs, path, offset)
+	} else {
+		f, err = fs.ReadAt(path, offset)
+	}
 	if err != nil {
 		return err
 	}
@@ -627,6 +649,16 @@ OUTER:
 		// Read up to the max frame size
 		n, readErr := f.Read(data)
 
+		// Stop at the limit of the filter
+		limitReached := false
+		if filter != nil && filter.limit >= 0 && offset+int64(n) >= filter.limit {
+			n = int(filter.limit - offset)
+			if n < 0 {
+				n = 0
+			}
+			limitReached = true
+		}
+
 		// Update the offset
 		offset += int64(n)
 
@@ -637,9 +669,19 @@ OUTER:
 
 		// Send the frame
 		if n != 0 {
-			if err := framer.Send(path, lastEvent, data[:n], offset); err != nil {
-				return parseFramerErr(err)
+			out := data[:n]
+			if filter != nil {
+				out = filter.lines(path, out, offset)
 			}
+			if len(out) != 0 {
+				if err := framer.Send(path, lastEvent, out, offset); err != nil {
+					return parseFramerErr(err)
+				}
+			}
+		}
+
+		if limitReached {
+			return nil
 		}
 
 		// Clear the last event
@@ -652,6 +694,11 @@ OUTER:
 			continue
 		}
 
+		// Compressed files are complete
+		if compressed {
+			return nil
+		}
+
 		// If EOF is hit, wait for a change to the file
 		if changes == nil {
 			changes, err = fs.ChangeEvents(path, offset, &t)
@@ -704,6 +751,12 @@ OUTER:
 // * offset: The offset to start streaming data at, defaults to zero.
 // * origin: Either "start" or "end" and defines from where the offset is
 //           applied. Defaults to "start".
+// * since: Only stream the output written since the given time, either as an
+//          RFC3339 timestamp or a duration ago such as "10m". Can't be used
+//          with an offset.
+// * until: Only stream the output written until the given time. Can't be used
+//          when following the logs.
+// * grep: Only stream the lines matching the regular expression.
 func (s *HTTPServer) Logs(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
 	var allocID, task, logType string
 	var plain, follow bool
@@ -756,6 +809,33 @@ func (s *HTTPServer) Logs(resp http.ResponseWriter, req *http.Request) (interfac
 		return nil, invalidOrigin
 	}
 
+	var filter *logFilter
+	since, until, grep := q.Get("since"), q.Get("until"), q.Get("grep")
+	if since != "" || until != "" || grep != "" {
+		filter = &logFilter{limit: -1}
+		if since != "" {
+			if offset != 0 {
+				return nil, sinceWithOffsetErr
+			}
+			if filter.since, err = parseLogTime(since); err != nil {
+				return nil, fmt.Errorf("error parsing since: %v", err)
+			}
+		}
+		if until != "" {
+			if follow {
+				return nil, untilWithFollowErr
+			}
+			if filter.until, err = parseLogTime(until); err != nil {
+				return nil, fmt.Errorf("error parsing until: %v", err)
+			}
+		}
+		if grep != "" {
+			if filter.grep, err = regexp.Compile(grep); err != nil {
+				return nil, fmt.Errorf("error parsing grep: %v", err)
+			}
+		}
+	}
+
 	fs, err := s.agent.client.GetAllocFS(allocID)
 	if err != nil {
 		return nil, err
@@ -782,18 +862,27 @@ func (s *HTTPServer) Logs(resp http.ResponseWriter, req *http.Request) (interfac
 	// Create an output that gets flushed on every write
 	output := ioutils.NewWriteFlusher(resp)
 
-	return nil, s.logs(follow, plain, offset, origin, task, logType, fs, output)
+	return nil, s.logs(follow, plain, offset, origin, task, logType, fs, output, filter)
 }
 
 func (s *HTTPServer) logs(follow, plain bool, offset int64,
 	origin, task, logType string,
-	fs allocdir.AllocDirFS, output io.WriteCloser) error {
+	fs allocdir.AllocDirFS, output io.WriteCloser, filter *logFilter) error {
 
 	// Create the framer
 	framer := NewStreamFramer(output, plain, streamHeartbeatRate, streamBatchWindow, streamFrameSize)
 	framer.Run()
 	defer framer.Destroy()
 
+	// Send the last line if it wasn't terminated by a newline
+	if filter != nil {
+		defer func() {
+			if data := filter.flush(); len(data) != 0 {
+				framer.Send(filter.file, "", data, filter.offset)
+			}
+		}()
+	}
+
 	// Path to the logs
 	logPath := filepath.Join(allocdir.SharedAllocName, allocdir.LogDirName)
 
@@ -809,6 +898,40 @@ func (s *HTTPServer) logs(follow, plain bool, offset int64,
 		return invalidOrigin
 	}
 
+	// Find the output written in the time range of the filter
+	untilIdx, untilOffset := int64(-1), int64(0)
+	if filter != nil && (!filter.since.IsZero() || !filter.until.IsZero()) {
+		entries, err := listLogs(fs, logPath)
+		if err != nil {
+			return fmt.Errorf("failed to list entries: %v", err)
+		}
+		indexes, err := logIndexes(entries, task, logType)
+		if err != nil {
+			return err
+		}
+		if len(indexes) == 0 {
+			return fmt.Errorf("log entry for task %q and log type %q not found", task, logType)
+		}
+		sort.Sort(indexes)
+
+		if !filter.since.IsZero() {
+			var ok bool
+			nextIdx, offset, ok = logTimeOffset(fs, logPath, indexes, filter.since)
+			if !ok {
+				// Nothing was written since, so start at the end
+				last := indexes[len(indexes)-1]
+				nextIdx, offset = last.idx, last.entry.Size
+			}
+		}
+
+		if !filter.until.IsZero() {
+			// The output written during the second of until is included
+			if idx, offset, ok := logTimeOffset(fs, logPath, indexes, filter.until.Add(time.Second)); ok {
+				untilIdx, untilOffset = idx, offset
+			}
+		}
+	}
+
 	// Create a tomb to cancel watch events
 	t := tomb.Tomb{}
 	defer func() {
@@ -823,7 +946,7 @@ func (s *HTTPServer) logs(follow, plain bool, offset int64,
 		// 3) Open log file at correct offset
 		// 3a) No error, read contents
 		// 3b) If file doesn't exist, goto 1 as it may have been rotated out
-		entries, err := fs.List(logPath)
+		entries, err := listLogs(fs, logPath)
 		if err != nil {
 			return fmt.Errorf("failed to list entries: %v", err)
 		}
@@ -846,7 +969,19 @@ func (s *HTTPServer) logs(follow, plain bool, offset int64,
 
 		var eofCancelCh chan error
 		exitAfter := false
-		if !follow && idx > maxIndex {
+		if filter != nil {
+			filter.limit = -1
+		}
+		if untilIdx >= 0 && idx > untilIdx {
+			// Exceeded the time range of the filter
+			return nil
+		} else if untilIdx >= 0 && idx == untilIdx {
+			// Stop at the end of the time range
+			filter.limit = untilOffset
+			eofCancelCh = make(chan error)
+			close(eofCancelCh)
+			exitAfter = true
+		} else if !follow && idx > maxIndex {
 			// Exceeded what was there initially so return
 			return nil
 		} else if !follow && idx == maxIndex {
@@ -859,7 +994,7 @@ func (s *HTTPServer) logs(follow, plain bool, offset int64,
 		}
 
 		p := filepath.Join(logPath, logEntry.Name)
-		err = s.stream(openOffset, p, fs, framer, eofCancelCh)
+		err = s.stream(openOffset, p, fs, framer, eofCancelCh, filter)
 
 		if err != nil {
 			// Check if there was an error where the file does not exist. That means
@@ -958,10 +1093,12 @@ func (a indexTupleArray) Less(i, j int) bool { return a[i].idx < a[j].idx }
 func (a indexTupleArray) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
 
 // logIndexes takes a set of entries and returns a indexTupleArray of
-// the desired log file entries. If the indexes could not be determined, an
-// error is returned.
+// the desired log file entries. If a log file exists both compressed and
+// uncompressed, the uncompressed entry is used as its compression didn't
+// complete. If the indexes could not be determined, an error is returned.
 func logIndexes(entries []*allocdir.AllocFileInfo, task, logType string) (indexTupleArray, error) {
 	var indexes []indexTuple
+	seen := make(map[int64]int)
 	prefix := fmt.Sprintf("%s.%s.", task, logType)
 	for _, entry := range entries {
 		if entry.IsDir {
@@ -975,17 +1112,108 @@ func logIndexes(entries []*allocdir.AllocFileInfo, task, logType string) (indexT
 		}
 
 		// Convert to an int
+		compressed := strings.HasSuffix(idxStr, logging.CompressedLogSuffix)
+		idxStr = strings.TrimSuffix(idxStr, logging.CompressedLogSuffix)
 		idx, err := strconv.Atoi(idxStr)
 		if err != nil {
 			return nil, fmt.Errorf("failed to convert %q to a log index: %v", idxStr, err)
 		}
 
-		indexes = append(indexes, indexTuple{idx: int64(idx), entry: entry})
+		tuple := indexTuple{idx: int64(idx), entry: entry}
+		if i, ok := seen[tuple.idx]; ok {
+			if !compressed {
+				indexes[i] = tuple
+			}
+			continue
+		}
+		seen[tuple.idx] = len(indexes)
+		indexes = append(indexes, tuple)
 	}
 
 	return indexTupleArray(indexes), nil
 }
 
+// listLogs lists the log files in the log directory. The size of compressed
+// log files is their uncompressed size, so offsets can be computed across
+// compressed and uncompressed files alike.
+func listLogs(fs allocdir.AllocDirFS, logPath string) ([]*allocdir.AllocFileInfo, error) {
+	entries, err := fs.List(logPath)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if entry.IsDir || !isCompressed(entry.Name) {
+			continue
+		}
+		size, err := uncompressedSize(fs, filepath.Join(logPath, entry.Name), entry.Size)
+		if err != nil {
+			// The file may have been purged while listing
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		entry.Size = size
+	}
+	return entries, nil
+}
+
+// isCompressed returns whether the file at the path is a compressed rotated
+// log file.
+func isCompressed(path string) bool {
+	return strings.HasSuffix(path, logging.CompressedLogSuffix)
+}
+
+// uncompressedSize returns the uncompressed size of a gzip compressed file,
+// which is stored in the last four bytes of the file.
+func uncompressedSize(fs allocdir.AllocDirFS, path string, size int64) (int64, error) {
+	if size < 4 {
+		return 0, fmt.Errorf("invalid compressed file %q", path)
+	}
+	r, err := fs.ReadAt(path, size-4)
+	if err != nil {
+		return 0, err
+	}
+	defer r.Close()
+
+	var isize uint32
+	if err := binary.Read(r, binary.LittleEndian, &isize); err != nil {
+		return 0, fmt.Errorf("failed to read size of compressed file %q: %v", path, err)
+	}
+	return int64(isize), nil
+}
+
+// gzipReadCloser closes both the gzip reader and the underlying file
+type gzipReadCloser struct {
+	*gzip.Reader
+	file io.Closer
+}
+
+func (g *gzipReadCloser) Close() error {
+	g.Reader.Close()
+	return g.file.Close()
+}
+
+// readCompressedAt returns a reader of the decompressed content of a gzip
+// compressed file starting at the given offset of the decompressed content.
+func readCompressedAt(fs allocdir.AllocDirFS, path string, offset int64) (io.ReadCloser, error) {
+	f, err := fs.ReadAt(path, 0)
+	if err != nil {
+		return nil, err
+	}
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to decompress %q: %v", path, err)
+	}
+	r := &gzipReadCloser{Reader: gz, file: f}
+	if _, err := io.CopyN(ioutil.Discard, r, offset); err != nil && err != io.EOF {
+		r.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
 // findClosest takes a list of entries, the desired log index and desired log
 // offset (which can be negative, treated as offset from end), task name and log
 // type and returns the log entry, the log index, the offset to read from and a
@@ -1057,3 +1285,108 @@ func findClosest(entries []*allocdir.AllocFileInfo, desiredIdx, desiredOffset in
 
 	return indexes[idx].entry, indexes[idx].idx, offset, nil
 }
+
+// logTimeOffset returns the index of the log file and the offset in it of the
+// first output written during or after the second of the given time. The
+// indexes must be sorted. Log files without an index are matched by their
+// modification time. It returns false if all output was written before.
+func logTimeOffset(fs allocdir.AllocDirFS, logPath string, indexes indexTupleArray, t time.Time) (int64, int64, bool) {
+	for _, index := range indexes {
+		logFile := strings.TrimSuffix(index.entry.Name, logging.CompressedLogSuffix)
+		entries, err := readLogIndex(fs, filepath.Join(logPath, logging.LogIndexFileName(logFile)))
+		if err != nil {
+			if index.entry.ModTime.Before(t.Truncate(time.Second)) {
+				continue
+			}
+			return index.idx, 0, true
+		}
+
+		if offset, ok := logging.LogIndexOffset(entries, t); ok {
+			return index.idx, offset, true
+		}
+	}
+	return 0, 0, false
+}
+
+// readLogIndex reads the index of a log file
+func readLogIndex(fs allocdir.AllocDirFS, path string) ([]*logging.LogIndexEntry, error) {
+	r, err := fs.ReadAt(path, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return logging.ParseLogIndex(r)
+}
+
+// parseLogTime parses a time given either as an RFC3339 timestamp or as a
+// duration before the current time, such as "10m".
+func parseLogTime(value string) (time.Time, error) {
+	if d, err := time.ParseDuration(value); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	return time.Parse(time.RFC3339Nano, value)
+}
+
+// logFilter selects the log lines streamed by the logs endpoint
+type logFilter struct {
+	// since and until select the output written in a time range. They are
+	// ignored if zero.
+	since time.Time
+	until time.Time
+
+	// grep selects the lines matching it if set
+	grep *regexp.Regexp
+
+	// limit is the offset at which streaming the current file stops. It is
+	// negative if the whole file is streamed.
+	limit int64
+
+	// partial is the last line of the streamed data if it wasn't terminated
+	// by a newline yet. file and offset are where it was read.
+	partial []byte
+	file    string
+	offset  int64
+}
+
+// lines returns the complete lines of the data matching the filter. The data
+// was read from the given file, ending at offset.
+func (f *logFilter) lines(file string, data []byte, offset int64) []byte {
+	if f.grep == nil {
+		return data
+	}
+	f.file, f.offset = file, offset
+
+	var out []byte
+	buf := append(f.partial, data...)
+	for {
+		i := bytes.IndexByte(buf, '\n')
+		if i < 0 {
+			break
+		}
+		if line := buf[:i+1]; f.grep.Match(line[:i]) {
+			out = append(out, line...)
+		}
+		buf = buf[i+1:]
+	}
+
+	// Avoid buffering overly long lines
+	if len(buf) >= streamFrameSize {
+		if f.grep.Match(buf) {
+			out = append(out, buf...)
+		}
+		buf = nil
+	}
+	f.partial = append([]byte(nil), buf...)
+	return out
+}
+
+// flush returns the last line if it wasn't terminated by a newline and
+// matches the filter.
+func (f *logFilter) flush() []byte {
+	partial := f.partial
+	f.partial = nil
+	if len(partial) == 0 || f.grep == nil || !f.grep.Match(partial) {
+		return nil
+	}
+	return partial
+}
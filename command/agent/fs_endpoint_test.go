@@ -2,6 +2,7 @@ package agent
 
 import (
 	"bytes"
+	"compress/gzip"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -12,6 +13,7 @@ import (
 	"os"
 	"path/filepath"
 	"reflect"
+	"regexp"
 	"runtime"
 	"strconv"
 	"strings"
@@ -19,6 +21,7 @@ import (
 	"time"
 
 	"github.com/hashicorp/nomad/client/allocdir"
+	"github.com/hashicorp/nomad/client/driver/logging"
 	"github.com/hashicorp/nomad/testutil"
 	"github.com/ugorji/go/codec"
 )
@@ -568,7 +571,7 @@ func TestHTTP_Stream_NoFile(t *testing.T) {
 		framer.Run()
 		defer framer.Destroy()
 
-		if err := s.Server.stream(0, "foo", ad, framer, nil); err == nil {
+		if err := s.Server.stream(0, "foo", ad, framer, nil, nil); err == nil {
 			t.Fatalf("expected an error when streaming unknown file")
 		}
 	})
@@ -629,7 +632,7 @@ func TestHTTP_Stream_Modify(t *testing.T) {
 
 		// Start streaming
 		go func() {
-			if err := s.Server.stream(0, streamFile, ad, framer, nil); err != nil {
+			if err := s.Server.stream(0, streamFile, ad, framer, nil, nil); err != nil {
 				t.Fatalf("stream() failed: %v", err)
 			}
 		}()
@@ -710,7 +713,7 @@ func TestHTTP_Stream_Truncate(t *testing.T) {
 
 		// Start streaming
 		go func() {
-			if err := s.Server.stream(0, streamFile, ad, framer, nil); err != nil {
+			if err := s.Server.stream(0, streamFile, ad, framer, nil, nil); err != nil {
 				t.Fatalf("stream() failed: %v", err)
 			}
 		}()
@@ -812,7 +815,7 @@ func TestHTTP_Stream_Delete(t *testing.T) {
 
 		// Start streaming
 		go func() {
-			if err := s.Server.stream(0, streamFile, ad, framer, nil); err != nil {
+			if err := s.Server.stream(0, streamFile, ad, framer, nil, nil); err != nil {
 				t.Fatalf("stream() failed: %v", err)
 			}
 		}()
@@ -901,7 +904,7 @@ func TestHTTP_Logs_NoFollow(t *testing.T) {
 
 		// Start streaming logs
 		go func() {
-			if err := s.Server.logs(false, false, 0, OriginStart, task, logType, ad, wrappedW); err != nil {
+			if err := s.Server.logs(false, false, 0, OriginStart, task, logType, ad, wrappedW, nil); err != nil {
 				t.Fatalf("logs() failed: %v", err)
 			}
 		}()
@@ -990,7 +993,7 @@ func TestHTTP_Logs_Follow(t *testing.T) {
 
 		// Start streaming logs
 		go func() {
-			if err := s.Server.logs(true, false, 0, OriginStart, task, logType, ad, wrappedW); err != nil {
+			if err := s.Server.logs(true, false, 0, OriginStart, task, logType, ad, wrappedW, nil); err != nil {
 				t.Fatalf("logs() failed: %v", err)
 			}
 		}()
@@ -1103,7 +1106,7 @@ func BenchmarkHTTP_Logs_Follow(t *testing.B) {
 
 		// Start streaming logs
 		go func() {
-			if err := s.Server.logs(true, false, 0, OriginStart, task, logType, ad, wrappedW); err != nil {
+			if err := s.Server.logs(true, false, 0, OriginStart, task, logType, ad, wrappedW, nil); err != nil {
 				t.Fatalf("logs() failed: %v", err)
 			}
 		}()
@@ -1334,3 +1337,195 @@ func TestLogs_findClosest(t *testing.T) {
 		}
 	}
 }
+
+func TestHTTP_Logs_Filter(t *testing.T) {
+	httpTest(t, nil, func(s *TestServer) {
+		// Get a temp alloc dir and create the log dir
+		ad := tempAllocDir(t)
+		defer os.RemoveAll(ad.AllocDir)
+
+		logDir := filepath.Join(ad.SharedDir, allocdir.LogDirName)
+		if err := os.MkdirAll(logDir, 0777); err != nil {
+			t.Fatalf("Failed to make log dir: %v", err)
+		}
+
+		// Create two log files with an index entry for every line
+		task := "foo"
+		logType := "stdout"
+		start := time.Date(2017, 5, 1, 10, 0, 0, 0, time.UTC)
+		files := []string{"a1 ERROR x\na2 ok\n", "b1 ERROR y\nb2 ERROR z"}
+		for i, data := range files {
+			logFile := fmt.Sprintf("%s.%s.%d", task, logType, i)
+			if err := ioutil.WriteFile(filepath.Join(logDir, logFile), []byte(data), 0777); err != nil {
+				t.Fatalf("Failed to create file: %v", err)
+			}
+
+			index := fmt.Sprintf("%d 0\n%d 11\n",
+				start.Add(time.Duration(2*i)*time.Second).UnixNano(),
+				start.Add(time.Duration(2*i+1)*time.Second).UnixNano())
+			if err := ioutil.WriteFile(filepath.Join(logDir, logging.LogIndexFileName(logFile)), []byte(index), 0777); err != nil {
+				t.Fatalf("Failed to create index: %v", err)
+			}
+		}
+
+		cases := []struct {
+			filter   *logFilter
+			expected string
+		}{
+			{
+				filter:   &logFilter{since: start.Add(1 * time.Second)},
+				expected: "a2 ok\nb1 ERROR y\nb2 ERROR z",
+			},
+			{
+				filter:   &logFilter{until: start.Add(2500 * time.Millisecond)},
+				expected: "a1 ERROR x\na2 ok\nb1 ERROR y\n",
+			},
+			{
+				filter:   &logFilter{grep: regexp.MustCompile("ERROR")},
+				expected: "a1 ERROR x\nb1 ERROR y\nb2 ERROR z",
+			},
+			{
+				filter: &logFilter{
+					since: start.Add(1 * time.Second),
+					until: start.Add(2 * time.Second),
+					grep:  regexp.MustCompile("ERROR"),
+				},
+				expected: "b1 ERROR y\n",
+			},
+			{
+				filter:   &logFilter{since: start.Add(time.Hour)},
+				expected: "",
+			},
+		}
+
+		for i, c := range cases {
+			r, w := io.Pipe()
+			outCh := make(chan []byte, 1)
+			go func() {
+				out, _ := ioutil.ReadAll(r)
+				outCh <- out
+			}()
+
+			c.filter.limit = -1
+			if err := s.Server.logs(false, true, 0, OriginStart, task, logType, ad, w, c.filter); err != nil {
+				t.Fatalf("case %d: logs() failed: %v", i, err)
+			}
+
+			select {
+			case out := <-outCh:
+				if string(out) != c.expected {
+					t.Fatalf("case %d: got %q; want %q", i, out, c.expected)
+				}
+			case <-time.After(10 * time.Duration(testutil.TestMultiplier()) * streamBatchWindow):
+				t.Fatalf("case %d: timeout", i)
+			}
+		}
+	})
+}
+
+func TestParseLogTime(t *testing.T) {
+	ts, err := parseLogTime("2017-05-01T10:00:00Z")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !ts.Equal(time.Date(2017, 5, 1, 10, 0, 0, 0, time.UTC)) {
+		t.Fatalf("bad time: %v", ts)
+	}
+
+	ts, err = parseLogTime("10m")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if d := time.Since(ts); d < 10*time.Minute || d > 11*time.Minute {
+		t.Fatalf("bad time: %v", ts)
+	}
+
+	if _, err := parseLogTime("yesterday"); err == nil {
+		t.Fatalf("expected error")
+	}
+}
+
+func TestHTTP_Logs_Compressed(t *testing.T) {
+	httpTest(t, nil, func(s *TestServer) {
+		// Get a temp alloc dir and create the log dir
+		ad := tempAllocDir(t)
+		defer os.RemoveAll(ad.AllocDir)
+
+		logDir := filepath.Join(ad.SharedDir, allocdir.LogDirName)
+		if err := os.MkdirAll(logDir, 0777); err != nil {
+			t.Fatalf("Failed to make log dir: %v", err)
+		}
+
+		// Create two compressed rotated files and the current file
+		task := "foo"
+		logType := "stdout"
+		for i, data := range []string{"abc\n", "def\n"} {
+			var buf bytes.Buffer
+			gz := gzip.NewWriter(&buf)
+			gz.Write([]byte(data))
+			gz.Close()
+			logFile := fmt.Sprintf("%s.%s.%d%s", task, logType, i, logging.CompressedLogSuffix)
+			if err := ioutil.WriteFile(filepath.Join(logDir, logFile), buf.Bytes(), 0777); err != nil {
+				t.Fatalf("Failed to create file: %v", err)
+			}
+		}
+		logFile := fmt.Sprintf("%s.%s.%d", task, logType, 2)
+		if err := ioutil.WriteFile(filepath.Join(logDir, logFile), []byte("ghi\n"), 0777); err != nil {
+			t.Fatalf("Failed to create file: %v", err)
+		}
+
+		cases := []struct {
+			origin   string
+			offset   int64
+			expected string
+		}{
+			{OriginStart, 0, "abc\ndef\nghi\n"},
+			{OriginStart, 6, "f\nghi\n"},
+			{OriginEnd, 6, "f\nghi\n"},
+		}
+		for i, c := range cases {
+			r, w := io.Pipe()
+			outCh := make(chan []byte, 1)
+			go func() {
+				out, _ := ioutil.ReadAll(r)
+				outCh <- out
+			}()
+
+			if err := s.Server.logs(false, true, c.offset, c.origin, task, logType, ad, w, nil); err != nil {
+				t.Fatalf("case %d: logs() failed: %v", i, err)
+			}
+
+			select {
+			case out := <-outCh:
+				if string(out) != c.expected {
+					t.Fatalf("case %d: got %q; want %q", i, out, c.expected)
+				}
+			case <-time.After(10 * time.Duration(testutil.TestMultiplier()) * streamBatchWindow):
+				t.Fatalf("case %d: timeout", i)
+			}
+		}
+	})
+}
+
+func TestLogIndexes_Compressed(t *testing.T) {
+	entries := []*allocdir.AllocFileInfo{
+		{Name: "foo.stdout.0.gz"},
+		{Name: "foo.stdout.1"},
+		{Name: "foo.stdout.1.gz"},
+		{Name: "foo.stdout.2"},
+		{Name: ".foo.stdout.2.idx"},
+	}
+	indexes, err := logIndexes(entries, "foo", "stdout")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	expected := []string{"foo.stdout.0.gz", "foo.stdout.1", "foo.stdout.2"}
+	if len(indexes) != len(expected) {
+		t.Fatalf("bad indexes: %v", indexes)
+	}
+	for i, index := range indexes {
+		if index.idx != int64(i) || index.entry.Name != expected[i] {
+			t.Fatalf("bad index %d: %d %s", i, index.idx, index.entry.Name)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package agent
+
+import (
+	"net/http"
+
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+// JWKSRequest returns the JSON Web Key Set that third parties use to verify
+// the workload identities of tasks.
+func (s *HTTPServer) JWKSRequest(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	if req.Method != "GET" {
+		return nil, CodedError(405, ErrInvalidMethod)
+	}
+
+	args := structs.GenericRequest{}
+	if s.parse(resp, req, &args.Region, &args.QueryOptions) {
+		return nil, nil
+	}
+
+	var out structs.IdentityPublicKeysResponse
+	if err := s.agent.RPC("Identity.PublicKeys", &args, &out); err != nil {
+		return nil, err
+	}
+
+	setMeta(resp, &out.QueryMeta)
+	if out.Keys == nil {
+		out.Keys = make([]*structs.JSONWebKey, 0)
+	}
+	return &structs.JSONWebKeySet{Keys: out.Keys}, nil
+}
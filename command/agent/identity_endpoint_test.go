@@ -0,0 +1,54 @@
+package agent
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hashicorp/nomad/nomad/structs"
+	"github.com/hashicorp/nomad/testutil"
+)
+
+func TestHTTP_JWKS(t *testing.T) {
+	httpTest(t, nil, func(s *TestServer) {
+		// The leader creates the identity key once elected
+		var keys *structs.JSONWebKeySet
+		testutil.WaitForResult(func() (bool, error) {
+			req, err := http.NewRequest("GET", "/.well-known/jwks.json", nil)
+			if err != nil {
+				return false, err
+			}
+			respW := httptest.NewRecorder()
+
+			obj, err := s.Server.JWKSRequest(respW, req)
+			if err != nil {
+				return false, err
+			}
+			keys = obj.(*structs.JSONWebKeySet)
+			if len(keys.Keys) != 1 {
+				return false, fmt.Errorf("expected one key: %#v", keys)
+			}
+			return true, nil
+		}, func(err error) {
+			t.Fatalf("err: %v", err)
+		})
+
+		key := keys.Keys[0]
+		if key.KeyType != "RSA" || key.Algorithm != structs.IdentityKeyAlgorithmRS256 || key.Use != "sig" {
+			t.Fatalf("bad key: %#v", key)
+		}
+		if key.KeyID == "" || key.N == "" || key.E != "AQAB" {
+			t.Fatalf("bad key: %#v", key)
+		}
+
+		// Only GET is allowed
+		req, err := http.NewRequest("PUT", "/.well-known/jwks.json", nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if _, err := s.Server.JWKSRequest(httptest.NewRecorder(), req); err == nil {
+			t.Fatalf("expected error")
+		}
+	})
+}
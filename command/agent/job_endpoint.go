@@ -63,6 +63,9 @@ func (s *HTTPServer) JobSpecificRequest(resp http.ResponseWriter, req *http.Requ
 	case strings.HasSuffix(path, "/dispatch"):
 		jobName := strings.TrimSuffix(path, "/dispatch")
 		return s.jobDispatchRequest(resp, req, jobName)
+	case strings.HasSuffix(path, "/scale"):
+		jobName := strings.TrimSuffix(path, "/scale")
+		return s.jobScale(resp, req, jobName)
 	default:
 		return s.jobCRUD(resp, req, path)
 	}
@@ -368,6 +371,59 @@ func (s *HTTPServer) jobDispatchRequest(resp http.ResponseWriter, req *http.Requ
 	return out, nil
 }
 
+func (s *HTTPServer) jobScale(resp http.ResponseWriter, req *http.Request, name string) (interface{}, error) {
+	switch req.Method {
+	case "GET":
+		return s.jobScaleStatus(resp, req, name)
+	case "PUT", "POST":
+		return s.jobScaleAction(resp, req, name)
+	default:
+		return nil, CodedError(405, ErrInvalidMethod)
+	}
+}
+
+func (s *HTTPServer) jobScaleStatus(resp http.ResponseWriter, req *http.Request, name string) (interface{}, error) {
+	args := structs.JobSpecificRequest{
+		JobID: name,
+	}
+	if s.parse(resp, req, &args.Region, &args.QueryOptions) {
+		return nil, nil
+	}
+
+	var out structs.JobScaleStatusResponse
+	if err := s.agent.RPC("Job.ScaleStatus", &args, &out); err != nil {
+		return nil, err
+	}
+
+	setMeta(resp, &out.QueryMeta)
+	if out.JobScaleStatus == nil {
+		return nil, CodedError(404, "job not found")
+	}
+	return out.JobScaleStatus, nil
+}
+
+func (s *HTTPServer) jobScaleAction(resp http.ResponseWriter, req *http.Request, name string) (interface{}, error) {
+	args := structs.JobScaleRequest{}
+	if err := decodeBody(req, &args); err != nil {
+		return nil, CodedError(400, err.Error())
+	}
+	if args.JobID != "" && args.JobID != name {
+		return nil, CodedError(400, "Job ID does not match")
+	}
+	if args.JobID == "" {
+		args.JobID = name
+	}
+
+	s.parseRegion(req, &args.Region)
+
+	var out structs.JobRegisterResponse
+	if err := s.agent.RPC("Job.Scale", &args, &out); err != nil {
+		return nil, err
+	}
+	setIndex(resp, out.Index)
+	return out, nil
+}
+
 func ApiJobToStructJob(job *api.Job) *structs.Job {
 	job.Canonicalize()
 
@@ -447,11 +503,19 @@ func ApiTgToStructsTG(taskGroup *api.TaskGroup, tg *structs.TaskGroup) {
 		Delay:    *taskGroup.RestartPolicy.Delay,
 		Mode:     *taskGroup.RestartPolicy.Mode,
 	}
+	if len(taskGroup.Networks) > 0 {
+		tg.Networks = ApiNetworkResourceToStructs(taskGroup.Networks)
+	}
+
 	tg.EphemeralDisk = &structs.EphemeralDisk{
 		Sticky:  *taskGroup.EphemeralDisk.Sticky,
 		SizeMB:  *taskGroup.EphemeralDisk.SizeMB,
 		Migrate: *taskGroup.EphemeralDisk.Migrate,
 	}
+
+	if taskGroup.Scaling != nil {
+		tg.Scaling = ApiScalingPolicyToStructs(taskGroup.Scaling)
+	}
 	tg.Meta = taskGroup.Meta
 	tg.Tasks = make([]*structs.Task, len(taskGroup.Tasks))
 	for l, task := range taskGroup.Tasks {
@@ -461,11 +525,61 @@ func ApiTgToStructsTG(taskGroup *api.TaskGroup, tg *structs.TaskGroup) {
 	}
 }
 
+// ApiScalingPolicyToStructs converts the scaling policy of a task group from
+// the API representation to the internal one. A missing maximum is converted
+// to -1 so that validation reports it.
+func ApiScalingPolicyToStructs(in *api.ScalingPolicy) *structs.ScalingPolicy {
+	out := &structs.ScalingPolicy{
+		ID:      in.ID,
+		Max:     -1,
+		Policy:  in.Policy,
+		Enabled: true,
+	}
+	if in.Min != nil {
+		out.Min = *in.Min
+	}
+	if in.Max != nil {
+		out.Max = *in.Max
+	}
+	if in.Enabled != nil {
+		out.Enabled = *in.Enabled
+	}
+	return out
+}
+
+// ApiConsulConnectToStructs converts a Consul Connect configuration from the
+// API representation to the internal one.
+func ApiConsulConnectToStructs(in *api.ConsulConnect) *structs.ConsulConnect {
+	if in == nil {
+		return nil
+	}
+
+	out := &structs.ConsulConnect{}
+	if in.SidecarService == nil {
+		return out
+	}
+
+	out.SidecarService = &structs.ConsulSidecarService{
+		Port: in.SidecarService.Port,
+	}
+	if in.SidecarService.Proxy != nil {
+		out.SidecarService.Proxy = &structs.ConsulProxy{}
+		for _, u := range in.SidecarService.Proxy.Upstreams {
+			out.SidecarService.Proxy.Upstreams = append(out.SidecarService.Proxy.Upstreams, &structs.ConsulUpstream{
+				DestinationName: u.DestinationName,
+				LocalBindPort:   u.LocalBindPort,
+			})
+		}
+	}
+	return out
+}
+
 func ApiTaskToStructsTask(apiTask *api.Task, structsTask *structs.Task) {
 	structsTask.Name = apiTask.Name
 	structsTask.Driver = apiTask.Driver
 	structsTask.User = apiTask.User
 	structsTask.Leader = apiTask.Leader
+	structsTask.Kind = apiTask.Kind
 	structsTask.Config = apiTask.Config
 	structsTask.Constraints = make([]*structs.Constraint, len(apiTask.Constraints))
 	for i, constraint := range apiTask.Constraints {
@@ -480,6 +594,7 @@ func ApiTaskToStructsTask(apiTask *api.Task, structsTask *structs.Task) {
 			Name:      service.Name,
 			PortLabel: service.PortLabel,
 			Tags:      service.Tags,
+			Provider:  service.Provider,
 		}
 		structsTask.Services[i].Checks = make([]*structs.ServiceCheck, len(service.Checks))
 		for j, check := range service.Checks {
@@ -494,48 +609,57 @@ func ApiTaskToStructsTask(apiTask *api.Task, structsTask *structs.Task) {
 				Interval:      check.Interval,
 				Timeout:       check.Timeout,
 				InitialStatus: check.InitialStatus,
+				Method:        check.Method,
+				Header:        check.Header,
+				Body:          check.Body,
+				TLSSkipVerify: check.TLSSkipVerify,
+				GRPCService:   check.GRPCService,
+				GRPCUseTLS:    check.GRPCUseTLS,
+			}
+			if check.CheckRestart != nil {
+				structsTask.Services[i].Checks[j].CheckRestart = &structs.CheckRestart{
+					Limit:          check.CheckRestart.Limit,
+					Grace:          check.CheckRestart.Grace,
+					IgnoreWarnings: check.CheckRestart.IgnoreWarnings,
+				}
 			}
 		}
+		structsTask.Services[i].Connect = ApiConsulConnectToStructs(service.Connect)
 	}
 	structsTask.Resources = &structs.Resources{
 		CPU:      *apiTask.Resources.CPU,
 		MemoryMB: *apiTask.Resources.MemoryMB,
 		IOPS:     *apiTask.Resources.IOPS,
 	}
-	structsTask.Resources.Networks = make([]*structs.NetworkResource, len(apiTask.Resources.Networks))
-	for i, nw := range apiTask.Resources.Networks {
-		structsTask.Resources.Networks[i] = &structs.NetworkResource{
-			CIDR:  nw.CIDR,
-			IP:    nw.IP,
-			MBits: *nw.MBits,
-		}
-		structsTask.Resources.Networks[i].DynamicPorts = make([]structs.Port, len(nw.DynamicPorts))
-		structsTask.Resources.Networks[i].ReservedPorts = make([]structs.Port, len(nw.ReservedPorts))
-		for j, dp := range nw.DynamicPorts {
-			structsTask.Resources.Networks[i].DynamicPorts[j] = structs.Port{
-				Label: dp.Label,
-				Value: dp.Value,
-			}
-		}
-		for j, rp := range nw.ReservedPorts {
-			structsTask.Resources.Networks[i].ReservedPorts[j] = structs.Port{
-				Label: rp.Label,
-				Value: rp.Value,
-			}
-		}
-	}
+	structsTask.Resources.Networks = ApiNetworkResourceToStructs(apiTask.Resources.Networks)
 	structsTask.Meta = apiTask.Meta
 	structsTask.KillTimeout = *apiTask.KillTimeout
 	structsTask.LogConfig = &structs.LogConfig{
 		MaxFiles:      *apiTask.LogConfig.MaxFiles,
 		MaxFileSizeMB: *apiTask.LogConfig.MaxFileSizeMB,
+		Compress:      *apiTask.LogConfig.Compress,
+		MaxAge:        *apiTask.LogConfig.MaxAge,
+	}
+	if l := len(apiTask.LogConfig.Sinks); l != 0 {
+		structsTask.LogConfig.Sinks = make([]*structs.LogSink, l)
+		for i, sink := range apiTask.LogConfig.Sinks {
+			structsTask.LogConfig.Sinks[i] = &structs.LogSink{
+				Type:       *sink.Type,
+				Address:    *sink.Address,
+				BatchSize:  *sink.BatchSize,
+				BatchWait:  *sink.BatchWait,
+				BufferSize: *sink.BufferSize,
+				Headers:    sink.Headers,
+			}
+		}
 	}
 	structsTask.Artifacts = make([]*structs.TaskArtifact, len(apiTask.Artifacts))
 	for k, ta := range apiTask.Artifacts {
 		structsTask.Artifacts[k] = &structs.TaskArtifact{
-			GetterSource:  *ta.GetterSource,
-			GetterOptions: ta.GetterOptions,
-			RelativeDest:  *ta.RelativeDest,
+			GetterSource:    *ta.GetterSource,
+			GetterOptions:   ta.GetterOptions,
+			RelativeDest:    *ta.RelativeDest,
+			GetterSignature: *ta.GetterSignature,
 		}
 	}
 	if apiTask.Vault != nil {
@@ -544,8 +668,17 @@ func ApiTaskToStructsTask(apiTask *api.Task, structsTask *structs.Task) {
 			Env:          *apiTask.Vault.Env,
 			ChangeMode:   *apiTask.Vault.ChangeMode,
 			ChangeSignal: *apiTask.Vault.ChangeSignal,
+			Role:         *apiTask.Vault.Role,
+			EntityAlias:  *apiTask.Vault.EntityAlias,
+		}
+	}
+	if apiTask.Identity != nil {
+		structsTask.Identity = &structs.WorkloadIdentity{
+			Env:  *apiTask.Identity.Env,
+			File: *apiTask.Identity.File,
 		}
 	}
+
 	structsTask.Templates = make([]*structs.Template, len(apiTask.Templates))
 	for i, template := range apiTask.Templates {
 		structsTask.Templates[i] = &structs.Template{
@@ -558,6 +691,7 @@ func ApiTaskToStructsTask(apiTask *api.Task, structsTask *structs.Task) {
 			Perms:        *template.Perms,
 			LeftDelim:    *template.LeftDelim,
 			RightDelim:   *template.RightDelim,
+			Envvars:      *template.Envvars,
 		}
 	}
 	if apiTask.DispatchPayload != nil {
@@ -572,3 +706,34 @@ func ApiConstraintToStructs(c1 *api.Constraint, c2 *structs.Constraint) {
 	c2.RTarget = c1.RTarget
 	c2.Operand = c1.Operand
 }
+
+// ApiNetworkResourceToStructs converts the API network resources to their
+// structs representation.
+func ApiNetworkResourceToStructs(in []*api.NetworkResource) []*structs.NetworkResource {
+	out := make([]*structs.NetworkResource, len(in))
+	for i, nw := range in {
+		out[i] = &structs.NetworkResource{
+			CIDR:  nw.CIDR,
+			IP:    nw.IP,
+			MBits: *nw.MBits,
+			Mode:  nw.Mode,
+		}
+		out[i].DynamicPorts = make([]structs.Port, len(nw.DynamicPorts))
+		out[i].ReservedPorts = make([]structs.Port, len(nw.ReservedPorts))
+		for j, dp := range nw.DynamicPorts {
+			out[i].DynamicPorts[j] = structs.Port{
+				Label: dp.Label,
+				Value: dp.Value,
+				To:    dp.To,
+			}
+		}
+		for j, rp := range nw.ReservedPorts {
+			out[i].ReservedPorts[j] = structs.Port{
+				Label: rp.Label,
+				Value: rp.Value,
+				To:    rp.To,
+			}
+		}
+	}
+	return out
+}
@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -683,6 +684,76 @@ func TestHTTP_JobDispatch(t *testing.T) {
 	})
 }
 
+func TestHTTP_JobScale(t *testing.T) {
+	httpTest(t, nil, func(s *TestServer) {
+		// Create the job
+		job := mock.Job()
+		args := structs.JobRegisterRequest{
+			Job:          job,
+			WriteRequest: structs.WriteRequest{Region: "global"},
+		}
+		var resp structs.JobRegisterResponse
+		if err := s.Agent.RPC("Job.Register", &args, &resp); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		// Make the request
+		args2 := structs.JobScaleRequest{
+			TaskGroup: "web",
+			Count:     helper.Int64ToPtr(5),
+			Message:   "scaling down",
+		}
+		buf := encodeReq(args2)
+		req, err := http.NewRequest("PUT", "/v1/job/"+job.ID+"/scale", buf)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW := httptest.NewRecorder()
+
+		obj, err := s.Server.JobSpecificRequest(respW, req)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		// Check the response
+		scale := obj.(structs.JobRegisterResponse)
+		if scale.EvalID == "" || scale.JobModifyIndex == 0 {
+			t.Fatalf("bad: %v", scale)
+		}
+		if respW.HeaderMap.Get("X-Nomad-Index") == "" {
+			t.Fatalf("missing index")
+		}
+
+		// Query the scaling status
+		req, err = http.NewRequest("GET", "/v1/job/"+job.ID+"/scale", nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW = httptest.NewRecorder()
+
+		obj, err = s.Server.JobSpecificRequest(respW, req)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		status := obj.(*structs.JobScaleStatus)
+		tg := status.TaskGroups["web"]
+		if tg.Desired != 5 || len(tg.Events) != 1 || tg.Events[0].Message != "scaling down" {
+			t.Fatalf("bad: %#v", tg)
+		}
+
+		// Query a missing job
+		req, err = http.NewRequest("GET", "/v1/job/missing/scale", nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW = httptest.NewRecorder()
+		if _, err := s.Server.JobSpecificRequest(respW, req); err == nil || !strings.Contains(err.Error(), "job not found") {
+			t.Fatalf("expected not found error, got: %v", err)
+		}
+	})
+}
+
 func TestJobs_ApiJobToStructsJob(t *testing.T) {
 	apiJob := &api.Job{
 		Region:      helper.StringToPtr("global"),
@@ -742,6 +813,22 @@ func TestJobs_ApiJobToStructsJob(t *testing.T) {
 					Sticky:  helper.BoolToPtr(true),
 					Migrate: helper.BoolToPtr(true),
 				},
+				Scaling: &api.ScalingPolicy{
+					Min:     helper.Int64ToPtr(1),
+					Max:     helper.Int64ToPtr(20),
+					Enabled: helper.BoolToPtr(false),
+					Policy: map[string]interface{}{
+						"metric": "cpu",
+					},
+				},
+				Networks: []*api.NetworkResource{
+					{
+						Mode:          "bridge",
+						MBits:         helper.IntToPtr(10),
+						ReservedPorts: []api.Port{{Label: "http", Value: 80, To: 8080}},
+						DynamicPorts:  []api.Port{{Label: "admin", To: 9000}},
+					},
+				},
 				Meta: map[string]string{
 					"key": "value",
 				},
@@ -771,6 +858,7 @@ func TestJobs_ApiJobToStructsJob(t *testing.T) {
 								Name:      "serviceA",
 								Tags:      []string{"1", "2"},
 								PortLabel: "foo",
+								Provider:  "nomad",
 								Checks: []api.ServiceCheck{
 									{
 										Id:            "hello",
@@ -784,6 +872,15 @@ func TestJobs_ApiJobToStructsJob(t *testing.T) {
 										Interval:      4 * time.Second,
 										Timeout:       2 * time.Second,
 										InitialStatus: "ok",
+										Method:        "POST",
+										Header:        map[string][]string{"X-Foo": {"bar"}},
+										Body:          "{}",
+										TLSSkipVerify: true,
+										CheckRestart: &api.CheckRestart{
+											Limit:          3,
+											Grace:          10 * time.Second,
+											IgnoreWarnings: true,
+										},
 									},
 								},
 							},
@@ -817,6 +914,20 @@ func TestJobs_ApiJobToStructsJob(t *testing.T) {
 						LogConfig: &api.LogConfig{
 							MaxFiles:      helper.IntToPtr(10),
 							MaxFileSizeMB: helper.IntToPtr(100),
+							Compress:      helper.BoolToPtr(true),
+							MaxAge:        helper.TimeToPtr(24 * time.Hour),
+							Sinks: []*api.LogSink{
+								{
+									Type:       helper.StringToPtr("http"),
+									Address:    helper.StringToPtr("https://logs.example.com"),
+									BatchSize:  helper.IntToPtr(50),
+									BatchWait:  helper.TimeToPtr(5 * time.Second),
+									BufferSize: helper.IntToPtr(1000),
+									Headers: map[string]string{
+										"Authorization": "Bearer foo",
+									},
+								},
+							},
 						},
 						Artifacts: []*api.TaskArtifact{
 							{
@@ -824,7 +935,8 @@ func TestJobs_ApiJobToStructsJob(t *testing.T) {
 								GetterOptions: map[string]string{
 									"a": "b",
 								},
-								RelativeDest: helper.StringToPtr("dest"),
+								RelativeDest:    helper.StringToPtr("dest"),
+								GetterSignature: helper.StringToPtr("source.sig"),
 							},
 						},
 						Vault: &api.Vault{
@@ -832,6 +944,12 @@ func TestJobs_ApiJobToStructsJob(t *testing.T) {
 							Env:          helper.BoolToPtr(true),
 							ChangeMode:   helper.StringToPtr("c"),
 							ChangeSignal: helper.StringToPtr("sighup"),
+							Role:         helper.StringToPtr("web"),
+							EntityAlias:  helper.StringToPtr("web-alias"),
+						},
+						Identity: &api.WorkloadIdentity{
+							Env:  helper.BoolToPtr(true),
+							File: helper.BoolToPtr(false),
 						},
 						Templates: []*api.Template{
 							{
@@ -844,6 +962,7 @@ func TestJobs_ApiJobToStructsJob(t *testing.T) {
 								Perms:        helper.StringToPtr("666"),
 								LeftDelim:    helper.StringToPtr("abc"),
 								RightDelim:   helper.StringToPtr("def"),
+								Envvars:      helper.BoolToPtr(true),
 							},
 						},
 						DispatchPayload: &api.DispatchPayloadConfig{
@@ -919,6 +1038,22 @@ func TestJobs_ApiJobToStructsJob(t *testing.T) {
 					Sticky:  true,
 					Migrate: true,
 				},
+				Scaling: &structs.ScalingPolicy{
+					Min:     1,
+					Max:     20,
+					Enabled: false,
+					Policy: map[string]interface{}{
+						"metric": "cpu",
+					},
+				},
+				Networks: []*structs.NetworkResource{
+					{
+						Mode:          "bridge",
+						MBits:         10,
+						ReservedPorts: []structs.Port{{Label: "http", Value: 80, To: 8080}},
+						DynamicPorts:  []structs.Port{{Label: "admin", To: 9000}},
+					},
+				},
 				Meta: map[string]string{
 					"key": "value",
 				},
@@ -946,6 +1081,7 @@ func TestJobs_ApiJobToStructsJob(t *testing.T) {
 								Name:      "serviceA",
 								Tags:      []string{"1", "2"},
 								PortLabel: "foo",
+								Provider:  "nomad",
 								Checks: []*structs.ServiceCheck{
 									&structs.ServiceCheck{
 										Name:          "bar",
@@ -958,6 +1094,15 @@ func TestJobs_ApiJobToStructsJob(t *testing.T) {
 										Interval:      4 * time.Second,
 										Timeout:       2 * time.Second,
 										InitialStatus: "ok",
+										Method:        "POST",
+										Header:        map[string][]string{"X-Foo": {"bar"}},
+										Body:          "{}",
+										TLSSkipVerify: true,
+										CheckRestart: &structs.CheckRestart{
+											Limit:          3,
+											Grace:          10 * time.Second,
+											IgnoreWarnings: true,
+										},
 									},
 								},
 							},
@@ -991,6 +1136,20 @@ func TestJobs_ApiJobToStructsJob(t *testing.T) {
 						LogConfig: &structs.LogConfig{
 							MaxFiles:      10,
 							MaxFileSizeMB: 100,
+							Compress:      true,
+							MaxAge:        24 * time.Hour,
+							Sinks: []*structs.LogSink{
+								{
+									Type:       "http",
+									Address:    "https://logs.example.com",
+									BatchSize:  50,
+									BatchWait:  5 * time.Second,
+									BufferSize: 1000,
+									Headers: map[string]string{
+										"Authorization": "Bearer foo",
+									},
+								},
+							},
 						},
 						Artifacts: []*structs.TaskArtifact{
 							{
@@ -998,7 +1157,8 @@ func TestJobs_ApiJobToStructsJob(t *testing.T) {
 								GetterOptions: map[string]string{
 									"a": "b",
 								},
-								RelativeDest: "dest",
+								RelativeDest:    "dest",
+								GetterSignature: "source.sig",
 							},
 						},
 						Vault: &structs.Vault{
@@ -1006,6 +1166,12 @@ func TestJobs_ApiJobToStructsJob(t *testing.T) {
 							Env:          true,
 							ChangeMode:   "c",
 							ChangeSignal: "sighup",
+							Role:         "web",
+							EntityAlias:  "web-alias",
+						},
+						Identity: &structs.WorkloadIdentity{
+							Env:  true,
+							File: false,
 						},
 						Templates: []*structs.Template{
 							{
@@ -1018,6 +1184,7 @@ func TestJobs_ApiJobToStructsJob(t *testing.T) {
 								Perms:        "666",
 								LeftDelim:    "abc",
 								RightDelim:   "def",
+								Envvars:      true,
 							},
 						},
 						DispatchPayload: &structs.DispatchPayloadConfig{
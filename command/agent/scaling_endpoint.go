@@ -0,0 +1,61 @@
+package agent
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+func (s *HTTPServer) ScalingPoliciesRequest(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	if req.Method != "GET" {
+		return nil, CodedError(405, ErrInvalidMethod)
+	}
+
+	args := structs.ScalingPolicyListRequest{
+		JobID: req.URL.Query().Get("job"),
+	}
+	if s.parse(resp, req, &args.Region, &args.QueryOptions) {
+		return nil, nil
+	}
+
+	var out structs.ScalingPolicyListResponse
+	if err := s.agent.RPC("Scaling.ListPolicies", &args, &out); err != nil {
+		return nil, err
+	}
+
+	setMeta(resp, &out.QueryMeta)
+	if out.Policies == nil {
+		out.Policies = make([]*structs.ScalingPolicyListStub, 0)
+	}
+	return out.Policies, nil
+}
+
+func (s *HTTPServer) ScalingPolicySpecificRequest(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	if req.Method != "GET" {
+		return nil, CodedError(405, ErrInvalidMethod)
+	}
+
+	id := strings.TrimPrefix(req.URL.Path, "/v1/scaling/policy/")
+	if id == "" {
+		return nil, CodedError(400, "Missing scaling policy ID")
+	}
+
+	args := structs.ScalingPolicySpecificRequest{
+		ID: id,
+	}
+	if s.parse(resp, req, &args.Region, &args.QueryOptions) {
+		return nil, nil
+	}
+
+	var out structs.SingleScalingPolicyResponse
+	if err := s.agent.RPC("Scaling.GetPolicy", &args, &out); err != nil {
+		return nil, err
+	}
+
+	setMeta(resp, &out.QueryMeta)
+	if out.Policy == nil {
+		return nil, CodedError(404, "scaling policy not found")
+	}
+	return out.Policy, nil
+}
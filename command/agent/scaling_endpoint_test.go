@@ -0,0 +1,116 @@
+package agent
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hashicorp/nomad/nomad/mock"
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+func TestHTTP_ScalingPolicies(t *testing.T) {
+	httpTest(t, nil, func(s *TestServer) {
+		// Directly manipulate the state
+		state := s.Agent.server.State()
+		job1 := mock.Job()
+		job1.TaskGroups[0].Scaling = &structs.ScalingPolicy{ID: structs.GenerateUUID(), Max: 20}
+		job2 := mock.Job()
+		job2.TaskGroups[0].Scaling = &structs.ScalingPolicy{ID: structs.GenerateUUID(), Max: 20}
+		if err := state.UpsertJob(1000, job1); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if err := state.UpsertJob(1001, job2); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		// Make the HTTP request
+		req, err := http.NewRequest("GET", "/v1/scaling/policies", nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW := httptest.NewRecorder()
+
+		// Make the request
+		obj, err := s.Server.ScalingPoliciesRequest(respW, req)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		// Check for the index
+		if respW.HeaderMap.Get("X-Nomad-Index") == "" {
+			t.Fatalf("missing index")
+		}
+		if respW.HeaderMap.Get("X-Nomad-KnownLeader") != "true" {
+			t.Fatalf("missing known leader")
+		}
+		if respW.HeaderMap.Get("X-Nomad-LastContact") == "" {
+			t.Fatalf("missing last contact")
+		}
+
+		// Check the policies
+		if n := len(obj.([]*structs.ScalingPolicyListStub)); n != 2 {
+			t.Fatalf("bad: %d", n)
+		}
+
+		// Filter the policies by job
+		req, err = http.NewRequest("GET", "/v1/scaling/policies?job="+job1.ID, nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		obj, err = s.Server.ScalingPoliciesRequest(httptest.NewRecorder(), req)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		policies := obj.([]*structs.ScalingPolicyListStub)
+		if len(policies) != 1 || policies[0].JobID != job1.ID {
+			t.Fatalf("bad: %#v", policies)
+		}
+	})
+}
+
+func TestHTTP_ScalingPolicyQuery(t *testing.T) {
+	httpTest(t, nil, func(s *TestServer) {
+		// Directly manipulate the state
+		state := s.Agent.server.State()
+		job := mock.Job()
+		job.TaskGroups[0].Scaling = &structs.ScalingPolicy{ID: structs.GenerateUUID(), Max: 20}
+		if err := state.UpsertJob(1000, job); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		// Make the HTTP request
+		id := job.TaskGroups[0].Scaling.ID
+		req, err := http.NewRequest("GET", "/v1/scaling/policy/"+id, nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW := httptest.NewRecorder()
+
+		// Make the request
+		obj, err := s.Server.ScalingPolicySpecificRequest(respW, req)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		// Check for the index
+		if respW.HeaderMap.Get("X-Nomad-Index") != "1000" {
+			t.Fatalf("bad index: %q", respW.HeaderMap.Get("X-Nomad-Index"))
+		}
+
+		// Check the policy
+		policy := obj.(*structs.ScalingPolicy)
+		if policy.ID != id || policy.JobID != job.ID || policy.TaskGroup != "web" {
+			t.Fatalf("bad: %#v", policy)
+		}
+
+		// Missing policies are not found
+		req, err = http.NewRequest("GET", "/v1/scaling/policy/"+structs.GenerateUUID(), nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if _, err := s.Server.ScalingPolicySpecificRequest(httptest.NewRecorder(), req); err == nil {
+			t.Fatalf("expected error")
+		}
+	})
+}
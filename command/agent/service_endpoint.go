@@ -0,0 +1,59 @@
+package agent
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+func (s *HTTPServer) ServicesRequest(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	if req.Method != "GET" {
+		return nil, CodedError(405, ErrInvalidMethod)
+	}
+
+	args := structs.GenericRequest{}
+	if s.parse(resp, req, &args.Region, &args.QueryOptions) {
+		return nil, nil
+	}
+
+	var out structs.ServiceRegistrationListResponse
+	if err := s.agent.RPC("ServiceRegistration.List", &args, &out); err != nil {
+		return nil, err
+	}
+
+	setMeta(resp, &out.QueryMeta)
+	if out.Services == nil {
+		out.Services = make([]*structs.ServiceRegistrationListStub, 0)
+	}
+	return out.Services, nil
+}
+
+func (s *HTTPServer) ServiceSpecificRequest(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	if req.Method != "GET" {
+		return nil, CodedError(405, ErrInvalidMethod)
+	}
+
+	name := strings.TrimPrefix(req.URL.Path, "/v1/service/")
+	if name == "" {
+		return nil, CodedError(400, "Missing service name")
+	}
+
+	args := structs.ServiceRegistrationByNameRequest{
+		ServiceName: name,
+	}
+	if s.parse(resp, req, &args.Region, &args.QueryOptions) {
+		return nil, nil
+	}
+
+	var out structs.ServiceRegistrationByNameResponse
+	if err := s.agent.RPC("ServiceRegistration.GetService", &args, &out); err != nil {
+		return nil, err
+	}
+
+	setMeta(resp, &out.QueryMeta)
+	if out.Services == nil {
+		out.Services = make([]*structs.ServiceRegistration, 0)
+	}
+	return out.Services, nil
+}
@@ -0,0 +1,94 @@
+package agent
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hashicorp/nomad/nomad/mock"
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+func TestHTTP_ServiceList(t *testing.T) {
+	httpTest(t, nil, func(s *TestServer) {
+		// Directly manipulate the state
+		state := s.Agent.server.State()
+		service1 := mock.ServiceRegistration()
+		service2 := mock.ServiceRegistration()
+		service2.ServiceName = "api"
+		err := state.UpsertServiceRegistrations(1000,
+			[]*structs.ServiceRegistration{service1, service2})
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		// Make the HTTP request
+		req, err := http.NewRequest("GET", "/v1/services", nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW := httptest.NewRecorder()
+
+		// Make the request
+		obj, err := s.Server.ServicesRequest(respW, req)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		// Check for the index
+		if respW.HeaderMap.Get("X-Nomad-Index") == "" {
+			t.Fatalf("missing index")
+		}
+		if respW.HeaderMap.Get("X-Nomad-KnownLeader") != "true" {
+			t.Fatalf("missing known leader")
+		}
+		if respW.HeaderMap.Get("X-Nomad-LastContact") == "" {
+			t.Fatalf("missing last contact")
+		}
+
+		// Check the services
+		services := obj.([]*structs.ServiceRegistrationListStub)
+		if len(services) != 2 || services[0].ServiceName != "api" || services[1].ServiceName != "web" {
+			t.Fatalf("bad: %#v", services)
+		}
+	})
+}
+
+func TestHTTP_ServiceQuery(t *testing.T) {
+	httpTest(t, nil, func(s *TestServer) {
+		// Directly manipulate the state
+		state := s.Agent.server.State()
+		service1 := mock.ServiceRegistration()
+		service2 := mock.ServiceRegistration()
+		service2.ServiceName = "api"
+		err := state.UpsertServiceRegistrations(1000,
+			[]*structs.ServiceRegistration{service1, service2})
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		// Make the HTTP request
+		req, err := http.NewRequest("GET", "/v1/service/web", nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW := httptest.NewRecorder()
+
+		// Make the request
+		obj, err := s.Server.ServiceSpecificRequest(respW, req)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		// Check for the index
+		if respW.HeaderMap.Get("X-Nomad-Index") == "" {
+			t.Fatalf("missing index")
+		}
+
+		// Check the service instances
+		services := obj.([]*structs.ServiceRegistration)
+		if len(services) != 1 || services[0].ID != service1.ID {
+			t.Fatalf("bad: %#v", services)
+		}
+	})
+}
@@ -16,9 +16,23 @@ Usage: nomad agent-info [options]
 
   Display status information about the local agent.
 
+  Agent-info will return one of the following exit codes:
+    * 0: Agent information displayed.
+    * 1: Error querying or formatting the agent information.
+
 General Options:
 
-  ` + generalOptionsUsage()
+  ` + generalOptionsUsage() + `
+
+Agent Info Options:
+
+  -json
+    Output the agent information in its JSON format. The configuration,
+    members and statistics of the agent are included.
+
+  -t
+    Format and display the agent information using a Go template.
+`
 	return strings.TrimSpace(helpText)
 }
 
@@ -27,12 +41,23 @@ func (c *AgentInfoCommand) Synopsis() string {
 }
 
 func (c *AgentInfoCommand) Run(args []string) int {
+	var json bool
+	var tmpl string
+
 	flags := c.Meta.FlagSet("agent-info", FlagSetClient)
 	flags.Usage = func() { c.Ui.Output(c.Help()) }
+	flags.BoolVar(&json, "json", false, "")
+	flags.StringVar(&tmpl, "t", "", "")
 	if err := flags.Parse(args); err != nil {
 		return 1
 	}
 
+	format, err := outputFormat(json, tmpl)
+	if err != nil {
+		c.Ui.Error(err.Error())
+		return 1
+	}
+
 	// Check that we either got no jobs or exactly one.
 	args = flags.Args()
 	if len(args) > 0 {
@@ -54,6 +79,16 @@ func (c *AgentInfoCommand) Run(args []string) int {
 		return 1
 	}
 
+	if len(format) > 0 {
+		out, err := formatData(format, tmpl, info)
+		if err != nil {
+			c.Ui.Error(err.Error())
+			return 1
+		}
+		c.Ui.Output(out)
+		return 0
+	}
+
 	// Sort and output agent info
 	statsKeys := make([]string, 0, len(info.Stats))
 	for key := range info.Stats {
@@ -22,6 +22,14 @@ func TestAgentInfoCommand_Run(t *testing.T) {
 	if code != 0 {
 		t.Fatalf("expected exit 0, got: %d", code)
 	}
+
+	// Output the agent information in its JSON format
+	if code := cmd.Run([]string{"-address=" + url, "-json"}); code != 0 {
+		t.Fatalf("expected exit 0, got: %d", code)
+	}
+	if out := ui.OutputWriter.String(); !strings.Contains(out, `"stats"`) {
+		t.Fatalf("expected stats in output, got: %s", out)
+	}
 }
 
 func TestAgentInfoCommand_Fails(t *testing.T) {
@@ -44,4 +52,13 @@ func TestAgentInfoCommand_Fails(t *testing.T) {
 	if out := ui.ErrorWriter.String(); !strings.Contains(out, "Error querying agent info") {
 		t.Fatalf("expected failed query error, got: %s", out)
 	}
+	ui.ErrorWriter.Reset()
+
+	// Fails on both -json and -t options being specified
+	if code := cmd.Run([]string{"-json", "-t", "{{.ID}}"}); code != 1 {
+		t.Fatalf("expected exit code 1, got: %d", code)
+	}
+	if out := ui.ErrorWriter.String(); !strings.Contains(out, "Both -json and -t are not allowed") {
+		t.Fatalf("expected format flags error, got: %s", out)
+	}
 }
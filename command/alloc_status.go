@@ -218,6 +218,10 @@ func (c *AllocStatusCommand) Run(args []string) int {
 		fmt.Sprintf("Created At|%s", formatUnixNanoTime(alloc.CreateTime)),
 	}
 
+	if alloc.NetworkStatus != nil && alloc.NetworkStatus.Address != "" {
+		basic = append(basic, fmt.Sprintf("Network Address|%s", alloc.NetworkStatus.Address))
+	}
+
 	if verbose {
 		basic = append(basic,
 			fmt.Sprintf("Evaluated Nodes|%d", alloc.Metrics.NodesEvaluated),
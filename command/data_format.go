@@ -73,3 +73,31 @@ func (p *TemplateFormat) TransformData(data interface{}) (string, error) {
 	}
 	return fmt.Sprint(out), nil
 }
+
+// outputFormat returns the format selected by the -json and -t flags of a
+// command, or an empty string if the output should not be formatted.
+func outputFormat(json bool, tmpl string) (string, error) {
+	switch {
+	case json && len(tmpl) > 0:
+		return "", fmt.Errorf("Both -json and -t are not allowed")
+	case json:
+		return "json", nil
+	case len(tmpl) > 0:
+		return "template", nil
+	}
+	return "", nil
+}
+
+// formatData transforms the data using the given format and template.
+func formatData(format, tmpl string, data interface{}) (string, error) {
+	f, err := DataFormat(format, tmpl)
+	if err != nil {
+		return "", fmt.Errorf("Error getting formatter: %s", err)
+	}
+
+	out, err := f.TransformData(data)
+	if err != nil {
+		return "", fmt.Errorf("Error formatting the data: %s", err)
+	}
+	return out, nil
+}
@@ -62,3 +62,31 @@ func TestInvalidJSONTemplate(t *testing.T) {
 		t.Fatalf("expected not specified template error, got: %s", err.Error())
 	}
 }
+
+func TestOutputFormat(t *testing.T) {
+	cases := []struct {
+		JSON     bool
+		Tmpl     string
+		Expected string
+		Err      bool
+	}{
+		{false, "", "", false},
+		{true, "", "json", false},
+		{false, "{{.ID}}", "template", false},
+		{true, "{{.ID}}", "", true},
+	}
+	for _, c := range cases {
+		format, err := outputFormat(c.JSON, c.Tmpl)
+		if (err != nil) != c.Err {
+			t.Fatalf("outputFormat(%v, %q) err: %v", c.JSON, c.Tmpl, err)
+		}
+		if format != c.Expected {
+			t.Fatalf("outputFormat(%v, %q) = %q; want %q", c.JSON, c.Tmpl, format, c.Expected)
+		}
+	}
+
+	out, err := formatData("template", "{{.Name}}", tData)
+	if err != nil || out != "example" {
+		t.Fatalf("bad: %q %v", out, err)
+	}
+}
@@ -30,7 +30,7 @@ Eval Status Options:
     Monitor an outstanding evaluation
 
   -verbose
-    Show full information.
+    Show full information, including the plans applied for the evaluation.
 
   -json
     Output the evaluation in its JSON format.
@@ -246,9 +246,96 @@ func (c *EvalStatusCommand) Run(args []string) int {
 		}
 	}
 
+	if verbose {
+		plans, _, err := client.Evaluations().Plans(eval.ID, nil)
+		if err != nil && !strings.Contains(err.Error(), "not found") {
+			c.Ui.Error(fmt.Sprintf("Error querying evaluation plans: %s", err))
+			return 1
+		}
+		if plans != nil && len(plans.Plans) > 0 {
+			c.Ui.Output(c.Colorize().Color(formatAppliedPlans(plans.Plans, length)))
+		}
+	}
+
 	return 0
 }
 
+// formatAppliedPlans returns the plans applied for an evaluation, most recent
+// first, with their annotations and the allocations they placed, updated or
+// stopped.
+func formatAppliedPlans(plans []*api.AppliedPlan, length int) string {
+	var out []string
+	for _, plan := range plans {
+		out = append(out, fmt.Sprintf("\n[bold]Plan Applied at Index %d[reset]", plan.AllocIndex))
+
+		rejected := make([]string, len(plan.RejectedNodes))
+		for i, node := range plan.RejectedNodes {
+			rejected[i] = limit(node, length)
+		}
+		basic := []string{
+			fmt.Sprintf("Applied At|%s", formatUnixNanoTime(plan.ApplyTime)),
+			fmt.Sprintf("Refresh Index|%d", plan.RefreshIndex),
+			fmt.Sprintf("Rejected Nodes|%s", strings.Join(rejected, ",")),
+		}
+		out = append(out, formatKV(basic))
+
+		if plan.Annotations != nil && len(plan.Annotations.DesiredTGUpdates) > 0 {
+			groups := make([]string, 0, len(plan.Annotations.DesiredTGUpdates))
+			for tg := range plan.Annotations.DesiredTGUpdates {
+				groups = append(groups, tg)
+			}
+			sort.Strings(groups)
+
+			updates := []string{"Task Group|Ignore|Place|Migrate|Stop|In-Place Update|Destructive Update"}
+			for _, tg := range groups {
+				u := plan.Annotations.DesiredTGUpdates[tg]
+				updates = append(updates, fmt.Sprintf("%s|%d|%d|%d|%d|%d|%d",
+					tg, u.Ignore, u.Place, u.Migrate, u.Stop, u.InPlaceUpdate, u.DestructiveUpdate))
+			}
+			out = append(out, "", formatList(updates))
+		}
+
+		var stubs []*api.AllocationListStub
+		actions := make(map[string]string)
+		for _, allocs := range plan.NodeAllocation {
+			for _, alloc := range allocs {
+				stubs = append(stubs, alloc)
+				actions[alloc.ID] = "update"
+				if alloc.CreateIndex == plan.AllocIndex {
+					actions[alloc.ID] = "place"
+				}
+			}
+		}
+		for _, allocs := range plan.NodeUpdate {
+			for _, alloc := range allocs {
+				stubs = append(stubs, alloc)
+				actions[alloc.ID] = alloc.DesiredStatus
+			}
+		}
+		if len(stubs) == 0 {
+			continue
+		}
+		sort.Slice(stubs, func(i, j int) bool {
+			if stubs[i].NodeID != stubs[j].NodeID {
+				return stubs[i].NodeID < stubs[j].NodeID
+			}
+			return stubs[i].ID < stubs[j].ID
+		})
+
+		allocs := []string{"ID|Node ID|Task Group|Action|Description"}
+		for _, alloc := range stubs {
+			allocs = append(allocs, fmt.Sprintf("%s|%s|%s|%s|%s",
+				limit(alloc.ID, length),
+				limit(alloc.NodeID, length),
+				alloc.TaskGroup,
+				actions[alloc.ID],
+				alloc.DesiredDescription))
+		}
+		out = append(out, "", formatList(allocs))
+	}
+	return strings.Join(out, "\n")
+}
+
 func sortedTaskGroupFromMetrics(groups map[string]*api.AllocationMetric) []string {
 	tgs := make([]string, 0, len(groups))
 	for tg, _ := range groups {
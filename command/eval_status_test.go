@@ -4,6 +4,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/hashicorp/nomad/api"
 	"github.com/mitchellh/cli"
 )
 
@@ -54,3 +55,44 @@ func TestEvalStatusCommand_Fails(t *testing.T) {
 	}
 
 }
+
+func TestEvalStatusCommand_FormatAppliedPlans(t *testing.T) {
+	plans := []*api.AppliedPlan{
+		{
+			NodeAllocation: map[string][]*api.AllocationListStub{
+				"node1": {
+					{ID: "placed", NodeID: "node1", TaskGroup: "web", CreateIndex: 10},
+					{ID: "updated", NodeID: "node1", TaskGroup: "web", CreateIndex: 5},
+				},
+			},
+			NodeUpdate: map[string][]*api.AllocationListStub{
+				"node2": {
+					{ID: "stopped", NodeID: "node2", TaskGroup: "web", DesiredStatus: "stop",
+						DesiredDescription: "alloc not needed due to job update"},
+				},
+			},
+			RejectedNodes: []string{"node3"},
+			Annotations: &api.PlanAnnotations{
+				DesiredTGUpdates: map[string]*api.DesiredUpdates{
+					"web": {Place: 1, Stop: 1, InPlaceUpdate: 1},
+				},
+			},
+			AllocIndex: 10,
+		},
+	}
+
+	out := formatAppliedPlans(plans, fullId)
+	for _, expected := range []string{
+		"Plan Applied at Index 10",
+		"node3",
+		"Destructive Update",
+		"placed   node1    web         place",
+		"updated  node1    web         update",
+		"stopped  node2    web         stop",
+		"alloc not needed due to job update",
+	} {
+		if !strings.Contains(out, expected) {
+			t.Fatalf("expected %q in output, got:\n%s", expected, out)
+		}
+	}
+}
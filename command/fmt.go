@@ -0,0 +1,310 @@
+package command
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/nomad/jobspec"
+)
+
+const (
+	// fmtDiffContext is the number of unchanged lines shown around each
+	// change of a diff
+	fmtDiffContext = 3
+)
+
+type FmtCommand struct {
+	Meta
+
+	// The fields below can be overwritten for tests
+	testStdin  io.Reader
+	testStdout io.Writer
+}
+
+func (c *FmtCommand) Help() string {
+	helpText := `
+Usage: nomad fmt [options] [<path>...]
+
+  Rewrites job files in their canonical format. The keys of each stanza are
+  ordered, indentation and the alignment of values are normalized, and
+  comments are preserved.
+
+  Each path may be a job file or a directory. Directories are searched
+  recursively for files ending in ".nomad" or ".nomad.hcl". If no path is
+  given, the current directory is used. If the path is "-", the job file is
+  read from stdin and the formatted job is written to stdout.
+
+  The names of the files that were rewritten are printed. The exit code is 1
+  if a file could not be formatted, or if -check is set and a file is not in
+  its canonical format.
+
+Fmt Options:
+
+  -check
+    Check whether the files are in their canonical format without rewriting
+    them. The names of the files that are not are printed.
+
+  -diff
+    Display the differences between the files and their canonical format.
+`
+	return strings.TrimSpace(helpText)
+}
+
+func (c *FmtCommand) Synopsis() string {
+	return "Rewrite job files in their canonical format"
+}
+
+func (c *FmtCommand) Run(args []string) int {
+	var check, diff bool
+
+	flags := c.Meta.FlagSet("fmt", FlagSetNone)
+	flags.Usage = func() { c.Ui.Output(c.Help()) }
+	flags.BoolVar(&check, "check", false, "")
+	flags.BoolVar(&diff, "diff", false, "")
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	paths := flags.Args()
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+
+	// Format stdin to stdout
+	if len(paths) == 1 && paths[0] == "-" {
+		return c.formatStdin(check, diff)
+	}
+
+	// Find the files to format
+	var files []string
+	for _, path := range paths {
+		found, err := fmtFiles(path)
+		if err != nil {
+			c.Ui.Error(fmt.Sprintf("Error finding job files: %s", err))
+			return 1
+		}
+		files = append(files, found...)
+	}
+
+	code := 0
+	for _, file := range files {
+		src, err := ioutil.ReadFile(file)
+		if err != nil {
+			c.Ui.Error(fmt.Sprintf("Error reading %q: %s", file, err))
+			code = 1
+			continue
+		}
+		out, err := jobspec.Format(src)
+		if err != nil {
+			c.Ui.Error(fmt.Sprintf("Error formatting %q: %s", file, err))
+			code = 1
+			continue
+		}
+		if bytes.Equal(src, out) {
+			continue
+		}
+
+		c.Ui.Output(file)
+		if diff {
+			c.Ui.Output(fmtDiff(file, src, out))
+		}
+		if check {
+			code = 1
+			continue
+		}
+
+		info, err := os.Stat(file)
+		if err != nil {
+			c.Ui.Error(fmt.Sprintf("Error reading %q: %s", file, err))
+			code = 1
+			continue
+		}
+		if err := ioutil.WriteFile(file, out, info.Mode()); err != nil {
+			c.Ui.Error(fmt.Sprintf("Error writing %q: %s", file, err))
+			code = 1
+		}
+	}
+	return code
+}
+
+// formatStdin formats the job file read from stdin. The formatted job is
+// written to stdout unless only checking or displaying the differences.
+func (c *FmtCommand) formatStdin(check, diff bool) int {
+	var stdin io.Reader = os.Stdin
+	if c.testStdin != nil {
+		stdin = c.testStdin
+	}
+	var stdout io.Writer = os.Stdout
+	if c.testStdout != nil {
+		stdout = c.testStdout
+	}
+
+	src, err := ioutil.ReadAll(stdin)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error reading stdin: %s", err))
+		return 1
+	}
+	out, err := jobspec.Format(src)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error formatting stdin: %s", err))
+		return 1
+	}
+
+	changed := !bytes.Equal(src, out)
+	if diff && changed {
+		c.Ui.Output(fmtDiff("<stdin>", src, out))
+	}
+	if check || diff {
+		if check && changed {
+			return 1
+		}
+		return 0
+	}
+	stdout.Write(out)
+	return 0
+}
+
+// fmtFiles returns the job files to format at the given path. Directories are
+// searched recursively for job files, skipping hidden directories.
+func fmtFiles(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+
+	var files []string
+	err = filepath.Walk(path, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		name := info.Name()
+		if info.IsDir() {
+			if p != path && strings.HasPrefix(name, ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if strings.HasSuffix(name, ".nomad") || strings.HasSuffix(name, ".nomad.hcl") {
+			files = append(files, p)
+		}
+		return nil
+	})
+	return files, err
+}
+
+// fmtDiff returns a unified diff of the lines of a and b.
+func fmtDiff(name string, a, b []byte) string {
+	aLines := strings.Split(strings.TrimSuffix(string(a), "\n"), "\n")
+	bLines := strings.Split(strings.TrimSuffix(string(b), "\n"), "\n")
+
+	// Find the longest common subsequence of the lines
+	lcs := make([][]int, len(aLines)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(bLines)+1)
+	}
+	for i := len(aLines) - 1; i >= 0; i-- {
+		for j := len(bLines) - 1; j >= 0; j-- {
+			if aLines[i] == bLines[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	// Build the edits turning a into b
+	type edit struct {
+		op   byte
+		line string
+	}
+	var edits []edit
+	i, j := 0, 0
+	for i < len(aLines) || j < len(bLines) {
+		switch {
+		case i < len(aLines) && j < len(bLines) && aLines[i] == bLines[j]:
+			edits = append(edits, edit{' ', aLines[i]})
+			i++
+			j++
+		case i < len(aLines) && (j == len(bLines) || lcs[i+1][j] >= lcs[i][j+1]):
+			edits = append(edits, edit{'-', aLines[i]})
+			i++
+		default:
+			edits = append(edits, edit{'+', bLines[j]})
+			j++
+		}
+	}
+
+	// Group the edits into hunks surrounded by unchanged lines
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "--- %s\n+++ %s (formatted)\n", name, name)
+	aLine, bLine := 1, 1
+	for start := 0; start < len(edits); {
+		if edits[start].op == ' ' {
+			start++
+			aLine++
+			bLine++
+			continue
+		}
+
+		// Extend the hunk until the changes are further apart than twice
+		// the context
+		end := start
+		for k := start; k < len(edits) && k <= end+2*fmtDiffContext; k++ {
+			if edits[k].op != ' ' {
+				end = k
+			}
+		}
+
+		from := start - fmtDiffContext
+		if from < 0 {
+			from = 0
+		}
+		to := end + fmtDiffContext + 1
+		if to > len(edits) {
+			to = len(edits)
+		}
+
+		aStart, bStart := aLine-(start-from), bLine-(start-from)
+		aLen, bLen := 0, 0
+		var hunk bytes.Buffer
+		for _, e := range edits[from:to] {
+			if e.op != '+' {
+				aLen++
+			}
+			if e.op != '-' {
+				bLen++
+			}
+			fmt.Fprintf(&hunk, "%c%s\n", e.op, e.line)
+		}
+		if aLen == 0 {
+			aStart--
+		}
+		if bLen == 0 {
+			bStart--
+		}
+		fmt.Fprintf(&buf, "@@ -%d,%d +%d,%d @@\n", aStart, aLen, bStart, bLen)
+		buf.Write(hunk.Bytes())
+
+		// Advance past the hunk
+		for _, e := range edits[start:to] {
+			if e.op != '+' {
+				aLine++
+			}
+			if e.op != '-' {
+				bLine++
+			}
+		}
+		start = to
+	}
+	return strings.TrimSuffix(buf.String(), "\n")
+}
@@ -0,0 +1,192 @@
+package command
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mitchellh/cli"
+)
+
+const (
+	fmtUnformatted = `job "example" {
+    type = "service"
+    datacenters = ["dc1"]
+}
+`
+	fmtFormatted = `job "example" {
+  datacenters = ["dc1"]
+  type        = "service"
+}
+`
+)
+
+func TestFmtCommand_Implements(t *testing.T) {
+	var _ cli.Command = &FmtCommand{}
+}
+
+func TestFmtCommand_Dir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nomad")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"example.nomad":           fmtUnformatted,
+		"formatted.nomad":         fmtFormatted,
+		"nested/example.nomad":    fmtUnformatted,
+		"nested/ignored.hcl":      fmtUnformatted,
+		".hidden/example.nomad":   fmtUnformatted,
+		"nested/example.txt":      "not a job",
+		"nested/bad.nomad.txt":    "job {",
+		"other/example.nomad.hcl": fmtUnformatted,
+	}
+	for name, contents := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		if err := ioutil.WriteFile(path, []byte(contents), 0644); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}
+
+	// Checking reports the unformatted files without rewriting them
+	ui := new(cli.MockUi)
+	cmd := &FmtCommand{Meta: Meta{Ui: ui}}
+	if code := cmd.Run([]string{"-check", dir}); code != 1 {
+		t.Fatalf("expected exit code 1, got: %d", code)
+	}
+	out := ui.OutputWriter.String()
+	for _, name := range []string{"example.nomad", "nested/example.nomad", "other/example.nomad.hcl"} {
+		if !strings.Contains(out, filepath.Join(dir, name)+"\n") {
+			t.Fatalf("expected %q to be listed: %s", name, out)
+		}
+	}
+	for _, name := range []string{"formatted.nomad", "ignored.hcl", ".hidden"} {
+		if strings.Contains(out, name) {
+			t.Fatalf("expected %q to not be listed: %s", name, out)
+		}
+	}
+	if src, _ := ioutil.ReadFile(filepath.Join(dir, "example.nomad")); string(src) != fmtUnformatted {
+		t.Fatalf("file was rewritten: %s", src)
+	}
+
+	// Formatting rewrites the files
+	ui = new(cli.MockUi)
+	cmd = &FmtCommand{Meta: Meta{Ui: ui}}
+	if code := cmd.Run([]string{dir}); code != 0 {
+		t.Fatalf("expected exit code 0, got: %d: %s", code, ui.ErrorWriter.String())
+	}
+	for _, name := range []string{"example.nomad", "nested/example.nomad", "other/example.nomad.hcl"} {
+		if src, _ := ioutil.ReadFile(filepath.Join(dir, name)); string(src) != fmtFormatted {
+			t.Fatalf("%s was not formatted: %s", name, src)
+		}
+	}
+	for _, name := range []string{"nested/ignored.hcl", ".hidden/example.nomad"} {
+		if src, _ := ioutil.ReadFile(filepath.Join(dir, name)); string(src) != fmtUnformatted {
+			t.Fatalf("%s was rewritten: %s", name, src)
+		}
+	}
+
+	// The formatted files pass the check
+	ui = new(cli.MockUi)
+	cmd = &FmtCommand{Meta: Meta{Ui: ui}}
+	if code := cmd.Run([]string{"-check", dir}); code != 0 {
+		t.Fatalf("expected exit code 0, got: %d: %s", code, ui.OutputWriter.String())
+	}
+}
+
+func TestFmtCommand_Fails(t *testing.T) {
+	ui := new(cli.MockUi)
+	cmd := &FmtCommand{Meta: Meta{Ui: ui}}
+
+	// Fails on a missing path
+	if code := cmd.Run([]string{"/unicorns/leprechauns"}); code != 1 {
+		t.Fatalf("expected exit code 1, got: %d", code)
+	}
+	if out := ui.ErrorWriter.String(); !strings.Contains(out, "Error finding job files") {
+		t.Fatalf("expected finding error, got: %s", out)
+	}
+	ui.ErrorWriter.Reset()
+
+	// Fails on invalid HCL
+	fh, err := ioutil.TempFile("", "nomad")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.Remove(fh.Name())
+	if _, err := fh.WriteString("job {"); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if code := cmd.Run([]string{fh.Name()}); code != 1 {
+		t.Fatalf("expected exit code 1, got: %d", code)
+	}
+	if out := ui.ErrorWriter.String(); !strings.Contains(out, "Error formatting") {
+		t.Fatalf("expected formatting error, got: %s", out)
+	}
+}
+
+func TestFmtCommand_Stdin(t *testing.T) {
+	ui := new(cli.MockUi)
+	var stdout bytes.Buffer
+	cmd := &FmtCommand{
+		Meta:       Meta{Ui: ui},
+		testStdin:  strings.NewReader(fmtUnformatted),
+		testStdout: &stdout,
+	}
+	if code := cmd.Run([]string{"-"}); code != 0 {
+		t.Fatalf("expected exit code 0, got: %d: %s", code, ui.ErrorWriter.String())
+	}
+	if stdout.String() != fmtFormatted {
+		t.Fatalf("bad output: %s", stdout.String())
+	}
+
+	// Checking with a diff
+	cmd = &FmtCommand{
+		Meta:      Meta{Ui: ui},
+		testStdin: strings.NewReader(fmtUnformatted),
+	}
+	if code := cmd.Run([]string{"-check", "-diff", "-"}); code != 1 {
+		t.Fatalf("expected exit code 1, got: %d", code)
+	}
+	expected := `--- <stdin>
++++ <stdin> (formatted)
+@@ -1,4 +1,4 @@
+ job "example" {
+-    type = "service"
+-    datacenters = ["dc1"]
++  datacenters = ["dc1"]
++  type        = "service"
+ }
+`
+	if out := ui.OutputWriter.String(); out != expected {
+		t.Fatalf("got:\n%s\nwant:\n%s", out, expected)
+	}
+}
+
+func TestFmtCommand_Diff(t *testing.T) {
+	a := "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\nk\nl\nm\n"
+	b := "a\nB\nc\nd\ne\nf\ng\nh\ni\nj\nk\nl\nm\nn\n"
+	expected := `--- file
++++ file (formatted)
+@@ -1,5 +1,5 @@
+ a
+-b
++B
+ c
+ d
+ e
+@@ -11,3 +11,4 @@
+ k
+ l
+ m
++n`
+	if out := fmtDiff("file", []byte(a), []byte(b)); out != expected {
+		t.Fatalf("got:\n%s\nwant:\n%s", out, expected)
+	}
+}
@@ -2,6 +2,7 @@ package command
 
 import (
 	"bytes"
+	"flag"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -11,6 +12,7 @@ import (
 
 	gg "github.com/hashicorp/go-getter"
 	"github.com/hashicorp/nomad/api"
+	flaghelper "github.com/hashicorp/nomad/helper/flag-helpers"
 	"github.com/hashicorp/nomad/jobspec"
 
 	"github.com/ryanuber/columnize"
@@ -227,10 +229,21 @@ READ:
 }
 
 type JobGetter struct {
+	// vars and varFiles are the values of the job's input variables
+	vars     []string
+	varFiles []string
+
 	// The fields below can be overwritten for tests
 	testStdin io.Reader
 }
 
+// addVarFlags adds the flags setting the values of the job's input variables
+// to the flag set.
+func (j *JobGetter) addVarFlags(flags *flag.FlagSet) {
+	flags.Var((*flaghelper.StringFlag)(&j.vars), "var", "")
+	flags.Var((*flaghelper.StringFlag)(&j.varFiles), "var-file", "")
+}
+
 // StructJob returns the Job struct from jobfile.
 func (j *JobGetter) ApiJob(jpath string) (*api.Job, error) {
 	var jobfile io.Reader
@@ -280,8 +293,18 @@ func (j *JobGetter) ApiJob(jpath string) (*api.Job, error) {
 		}
 	}
 
-	// Parse the JobFile
-	jobStruct, err := jobspec.Parse(jobfile)
+	// Parse the JobFile. Relative paths of file functions are resolved
+	// against the directory of local job files.
+	path := ""
+	if _, err := os.Stat(jpath); err == nil {
+		path = jpath
+	}
+	jobStruct, err := jobspec.ParseWithConfig(&jobspec.ParseConfig{
+		Path:     path,
+		Body:     jobfile,
+		ArgVars:  j.vars,
+		VarFiles: j.varFiles,
+	})
 	if err != nil {
 		return nil, fmt.Errorf("Error parsing job file from %s: %v", jpath, err)
 	}
@@ -6,6 +6,7 @@ import (
 	"io/ioutil"
 	"net/http"
 	"os"
+	"path/filepath"
 	"reflect"
 	"strings"
 	"testing"
@@ -287,3 +288,65 @@ func TestJobGetter_HTTPServer(t *testing.T) {
 		t.Fatalf("Unexpected file")
 	}
 }
+
+// Test StructJob with input variables set by flags
+func TestJobGetter_Variables(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nomad")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	jobfile := `
+variable "name" {
+  type = "string"
+}
+
+variable "datacenters" {
+  type    = "list"
+  default = ["dc1"]
+}
+
+job "${var.name}" {
+  datacenters = "${var.datacenters}"
+  meta {
+    motd = "${trimspace(file("motd.txt"))}"
+  }
+}
+`
+	jpath := filepath.Join(dir, "example.nomad")
+	files := map[string]string{
+		"example.nomad": jobfile,
+		"motd.txt":      "hello\n",
+		"vars.hcl":      `name = "example"`,
+	}
+	for name, contents := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(contents), 0644); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}
+
+	j := &JobGetter{
+		vars:     []string{`datacenters=["dc2", "dc3"]`},
+		varFiles: []string{filepath.Join(dir, "vars.hcl")},
+	}
+	aj, err := j.ApiJob(jpath)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if *aj.ID != "example" {
+		t.Fatalf("bad job ID: %q", *aj.ID)
+	}
+	if !reflect.DeepEqual(aj.Datacenters, []string{"dc2", "dc3"}) {
+		t.Fatalf("bad datacenters: %v", aj.Datacenters)
+	}
+	if aj.Meta["motd"] != "hello" {
+		t.Fatalf("bad meta: %v", aj.Meta)
+	}
+
+	// A missing variable is an error
+	j = &JobGetter{}
+	if _, err := j.ApiJob(jpath); err == nil || !strings.Contains(err.Error(), `variable "name" is required`) {
+		t.Fatalf("expected required variable error; got %v", err)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"os"
 	"strings"
 
+	"github.com/hashicorp/nomad/api"
 	flaghelper "github.com/hashicorp/nomad/helper/flag-helpers"
 )
 
@@ -19,8 +20,10 @@ Usage: nomad job dispatch [options] <parameterized job> [input source]
 
 Dispatch creates an instance of a parameterized job. A data payload to the
 dispatched instance can be provided via stdin by using "-" or by specifiying a
-path to a file. Metadata can be supplied by using the meta flag one or more
-times. 
+path to a file. Payloads exceeding the size limit of dispatch requests can
+instead be referenced by URL using the payload-url flag, in which case they are
+downloaded by the clients running the job. Metadata can be supplied by using
+the meta flag one or more times.
 
 Upon successful creation, the dispatched job ID will be printed and the
 triggered evaluation will be monitored. This can be disabled by supplying the
@@ -39,6 +42,18 @@ Dispatch Options:
     once to inject multiple metadata key/value pairs. Arbitrary keys are not
     allowed. The parameterized job must allow the key to be merged.
     
+  -payload-url <url>
+    The URL of the payload of the dispatched job. The payload is downloaded
+    by the clients running the job into the file configured by the
+    dispatch_payload stanza of its tasks. It can't be used with an input
+    source. The supported URLs are the same as for artifacts.
+
+  -idempotency-token <token>
+    If set, dispatching the job again with the same token within 24 hours
+    doesn't create a new instance of the job, and the ID of the instance
+    dispatched the first time is printed instead. This allows retrying a
+    dispatch safely.
+
   -detach
     Return immediately instead of entering monitor mode. After job dispatch,
     the evaluation ID will be printed to the screen, which can be used to
@@ -56,12 +71,15 @@ func (c *JobDispatchCommand) Synopsis() string {
 
 func (c *JobDispatchCommand) Run(args []string) int {
 	var detach, verbose bool
+	var payloadURL, idempotencyToken string
 	var meta []string
 
 	flags := c.Meta.FlagSet("job dispatch", FlagSetClient)
 	flags.Usage = func() { c.Ui.Output(c.Help()) }
 	flags.BoolVar(&detach, "detach", false, "")
 	flags.BoolVar(&verbose, "verbose", false, "")
+	flags.StringVar(&payloadURL, "payload-url", "", "")
+	flags.StringVar(&idempotencyToken, "idempotency-token", "", "")
 	flags.Var((*flaghelper.StringFlag)(&meta), "meta", "")
 
 	if err := flags.Parse(args); err != nil {
@@ -85,6 +103,11 @@ func (c *JobDispatchCommand) Run(args []string) int {
 	var payload []byte
 	var readErr error
 
+	if len(args) == 2 && payloadURL != "" {
+		c.Ui.Error("The payload URL can't be used with an input source")
+		return 1
+	}
+
 	// Read the input
 	if len(args) == 2 {
 		switch args[1] {
@@ -119,7 +142,14 @@ func (c *JobDispatchCommand) Run(args []string) int {
 	}
 
 	// Dispatch the job
-	resp, _, err := client.Jobs().Dispatch(job, metaMap, payload, nil)
+	req := &api.JobDispatchRequest{
+		JobID:            job,
+		Meta:             metaMap,
+		Payload:          payload,
+		PayloadURL:       payloadURL,
+		IdempotencyToken: idempotencyToken,
+	}
+	resp, _, err := client.Jobs().DispatchOpts(req, nil)
 	if err != nil {
 		c.Ui.Error(fmt.Sprintf("Failed to dispatch job: %s", err))
 		return 1
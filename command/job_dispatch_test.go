@@ -33,6 +33,15 @@ func TestJobDispatchCommand_Fails(t *testing.T) {
 	}
 	ui.ErrorWriter.Reset()
 
+	// Fails when both a payload URL and an input source are given
+	if code := cmd.Run([]string{"-payload-url=https://example.com/input", "foo", "-"}); code != 1 {
+		t.Fatalf("expect exit 1, got: %d", code)
+	}
+	if out := ui.ErrorWriter.String(); !strings.Contains(out, "can't be used with an input source") {
+		t.Fatalf("expect payload URL error: %v", out)
+	}
+	ui.ErrorWriter.Reset()
+
 	if code := cmd.Run([]string{"-address=nope", "foo"}); code != 1 {
 		t.Fatalf("expected exit code 1, got: %d", code)
 	}
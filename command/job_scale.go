@@ -0,0 +1,137 @@
+package command
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	flaghelper "github.com/hashicorp/nomad/helper/flag-helpers"
+)
+
+type JobScaleCommand struct {
+	Meta
+}
+
+func (c *JobScaleCommand) Help() string {
+	helpText := `
+Usage: nomad job scale [options] <job> <group> <count>
+
+Scale changes the count of a task group of a running job. The change is
+recorded in the scaling history of the job together with an optional message
+and metadata.
+
+Upon successful scaling, the triggered evaluation will be monitored. This can
+be disabled by supplying the detach flag.
+
+General Options:
+
+  ` + generalOptionsUsage() + `
+
+Scale Options:
+
+  -message <message>
+    A message describing the reason for the scaling, recorded in the scaling
+    history of the job.
+
+  -meta <key>=<value>
+    Meta takes a key/value pair seperated by "=". The metadata is recorded in
+    the scaling history of the job. The flag can be provided more than once to
+    record multiple metadata key/value pairs.
+
+  -detach
+    Return immediately instead of entering monitor mode. After scaling, the
+    evaluation ID will be printed to the screen, which can be used to examine
+    the evaluation using the eval-status command.
+
+  -verbose
+    Display full information.
+`
+	return strings.TrimSpace(helpText)
+}
+
+func (c *JobScaleCommand) Synopsis() string {
+	return "Change the count of a task group of a job"
+}
+
+func (c *JobScaleCommand) Run(args []string) int {
+	var detach, verbose bool
+	var message string
+	var meta []string
+
+	flags := c.Meta.FlagSet("job scale", FlagSetClient)
+	flags.Usage = func() { c.Ui.Output(c.Help()) }
+	flags.BoolVar(&detach, "detach", false, "")
+	flags.BoolVar(&verbose, "verbose", false, "")
+	flags.StringVar(&message, "message", "", "")
+	flags.Var((*flaghelper.StringFlag)(&meta), "meta", "")
+
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	// Truncate the id unless full length is requested
+	length := shortId
+	if verbose {
+		length = fullId
+	}
+
+	// Check that we got the job, group and count
+	args = flags.Args()
+	if len(args) != 3 {
+		c.Ui.Error(c.Help())
+		return 1
+	}
+
+	job, group := args[0], args[1]
+	count, err := strconv.Atoi(args[2])
+	if err != nil || count < 0 {
+		c.Ui.Error(fmt.Sprintf("Invalid count %q: must be a non-negative integer", args[2]))
+		return 1
+	}
+
+	// Build the meta
+	var metaMap map[string]interface{}
+	if len(meta) != 0 {
+		metaMap = make(map[string]interface{}, len(meta))
+	}
+	for _, m := range meta {
+		split := strings.SplitN(m, "=", 2)
+		if len(split) != 2 {
+			c.Ui.Error(fmt.Sprintf("Error parsing meta value: %v", m))
+			return 1
+		}
+
+		metaMap[split[0]] = split[1]
+	}
+
+	// Get the HTTP client
+	client, err := c.Meta.Client()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error initializing client: %s", err))
+		return 1
+	}
+
+	// Scale the job
+	resp, _, err := client.Jobs().Scale(job, group, &count, message, false, metaMap, nil)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Failed to scale job: %s", err))
+		return 1
+	}
+
+	// See if an evaluation was created. If the job is periodic or
+	// parameterized there will be no eval.
+	evalCreated := resp.EvalID != ""
+	if !evalCreated {
+		c.Ui.Output(fmt.Sprintf("Job %q scaled, no evaluation was created", job))
+		return 0
+	}
+
+	if detach {
+		c.Ui.Output("Job scaling successful")
+		c.Ui.Output("Evaluation ID: " + resp.EvalID)
+		return 0
+	}
+
+	mon := newMonitor(c.Ui, client, length)
+	return mon.monitor(resp.EvalID, false)
+}
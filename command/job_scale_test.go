@@ -0,0 +1,52 @@
+package command
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mitchellh/cli"
+)
+
+func TestJobScaleCommand_Implements(t *testing.T) {
+	var _ cli.Command = &JobScaleCommand{}
+}
+
+func TestJobScaleCommand_Fails(t *testing.T) {
+	ui := new(cli.MockUi)
+	cmd := &JobScaleCommand{Meta: Meta{Ui: ui}}
+
+	// Fails on misuse
+	if code := cmd.Run([]string{"some", "bad"}); code != 1 {
+		t.Fatalf("expected exit code 1, got: %d", code)
+	}
+	if out := ui.ErrorWriter.String(); !strings.Contains(out, cmd.Help()) {
+		t.Fatalf("expected help output, got: %s", out)
+	}
+	ui.ErrorWriter.Reset()
+
+	// Fails on an invalid count
+	if code := cmd.Run([]string{"foo", "bar", "-1"}); code != 1 {
+		t.Fatalf("expected exit code 1, got: %d", code)
+	}
+	if out := ui.ErrorWriter.String(); !strings.Contains(out, "Invalid count") {
+		t.Fatalf("expected invalid count error, got: %s", out)
+	}
+	ui.ErrorWriter.Reset()
+
+	// Fails on an invalid meta value
+	if code := cmd.Run([]string{"-meta=nope", "foo", "bar", "2"}); code != 1 {
+		t.Fatalf("expected exit code 1, got: %d", code)
+	}
+	if out := ui.ErrorWriter.String(); !strings.Contains(out, "Error parsing meta") {
+		t.Fatalf("expected meta parsing error, got: %s", out)
+	}
+	ui.ErrorWriter.Reset()
+
+	if code := cmd.Run([]string{"-address=nope", "foo", "bar", "2"}); code != 1 {
+		t.Fatalf("expected exit code 1, got: %d", code)
+	}
+	if out := ui.ErrorWriter.String(); !strings.Contains(out, "Failed to scale job") {
+		t.Fatalf("expected failed query error, got: %s", out)
+	}
+	ui.ErrorWriter.Reset()
+}
@@ -5,6 +5,7 @@ import (
 	"io"
 	"os"
 	"os/signal"
+	"regexp"
 	"strings"
 	"syscall"
 	"time"
@@ -22,6 +23,10 @@ Usage: nomad logs [options] <allocation> <task>
 
   Streams the stdout/stderr of the given allocation and task.
 
+  Logs will return one of the following exit codes:
+    * 0: Logs streamed, or the allocations matching a prefix displayed.
+    * 1: Error querying the allocation or reading the logs.
+
 General Options:
 
   ` + generalOptionsUsage() + `
@@ -51,6 +56,26 @@ Logs Specific Options:
 
   -c
     Sets the tail location in number of bytes relative to the end of the logs.
+
+  -since
+    Only show the logs written since the given time, either as an RFC3339
+    timestamp or a duration before now such as "10m". Times have a resolution
+    of a second. Can't be used with -tail.
+
+  -until
+    Only show the logs written until the given time, either as an RFC3339
+    timestamp or a duration before now. Can't be used with -tail or -f.
+
+  -grep
+    Only show the lines matching the given regular expression.
+
+  -json
+    Output the allocations in their JSON format if the allocation ID prefix
+    matches multiple allocations. The logs are always output as is.
+
+  -t
+    Format and display the allocations matching the allocation ID prefix
+    using a Go template.
   `
 	return strings.TrimSpace(helpText)
 }
@@ -60,8 +85,9 @@ func (l *LogsCommand) Synopsis() string {
 }
 
 func (l *LogsCommand) Run(args []string) int {
-	var verbose, job, tail, stderr, follow bool
+	var verbose, job, tail, stderr, follow, json bool
 	var numLines, numBytes int64
+	var since, until, grep, tmpl string
 
 	flags := l.Meta.FlagSet("logs", FlagSetClient)
 	flags.Usage = func() { l.Ui.Output(l.Help()) }
@@ -72,12 +98,23 @@ func (l *LogsCommand) Run(args []string) int {
 	flags.BoolVar(&stderr, "stderr", false, "")
 	flags.Int64Var(&numLines, "n", -1, "")
 	flags.Int64Var(&numBytes, "c", -1, "")
+	flags.StringVar(&since, "since", "", "")
+	flags.StringVar(&until, "until", "", "")
+	flags.StringVar(&grep, "grep", "", "")
+	flags.BoolVar(&json, "json", false, "")
+	flags.StringVar(&tmpl, "t", "", "")
 
 	if err := flags.Parse(args); err != nil {
 		return 1
 	}
 	args = flags.Args()
 
+	format, err := outputFormat(json, tmpl)
+	if err != nil {
+		l.Ui.Error(err.Error())
+		return 1
+	}
+
 	if numArgs := len(args); numArgs < 1 {
 		if job {
 			l.Ui.Error("Job ID required. See help:\n")
@@ -92,6 +129,40 @@ func (l *LogsCommand) Run(args []string) int {
 		return 1
 	}
 
+	// Parse the filter
+	var filter *api.LogFilter
+	if since != "" || until != "" || grep != "" {
+		if tail && (since != "" || until != "") {
+			l.Ui.Error("-since and -until can't be used with -tail")
+			return 1
+		}
+		if follow && until != "" {
+			l.Ui.Error("-until can't be used with -f")
+			return 1
+		}
+		if grep != "" {
+			if _, err := regexp.Compile(grep); err != nil {
+				l.Ui.Error(fmt.Sprintf("Error parsing -grep: %v", err))
+				return 1
+			}
+		}
+
+		filter = &api.LogFilter{Grep: grep}
+		var err error
+		if since != "" {
+			if filter.Since, err = parseLogTime(since); err != nil {
+				l.Ui.Error(fmt.Sprintf("Error parsing -since: %v", err))
+				return 1
+			}
+		}
+		if until != "" {
+			if filter.Until, err = parseLogTime(until); err != nil {
+				l.Ui.Error(fmt.Sprintf("Error parsing -until: %v", err))
+				return 1
+			}
+		}
+	}
+
 	client, err := l.Meta.Client()
 	if err != nil {
 		l.Ui.Error(fmt.Sprintf("Error initializing client: %v", err))
@@ -134,6 +205,16 @@ func (l *LogsCommand) Run(args []string) int {
 		return 1
 	}
 	if len(allocs) > 1 {
+		if len(format) > 0 {
+			out, err := formatData(format, tmpl, allocs)
+			if err != nil {
+				l.Ui.Error(err.Error())
+				return 1
+			}
+			l.Ui.Output(out)
+			return 0
+		}
+
 		// Format the allocs
 		out := make([]string, len(allocs)+1)
 		out[0] = "ID|Eval ID|Job ID|Task Group|Desired Status|Client Status"
@@ -199,7 +280,7 @@ func (l *LogsCommand) Run(args []string) int {
 	var r io.ReadCloser
 	var readErr error
 	if !tail {
-		r, readErr = l.followFile(client, alloc, follow, task, logType, api.OriginStart, 0)
+		r, readErr = l.followFile(client, alloc, follow, task, logType, api.OriginStart, 0, filter)
 		if readErr != nil {
 			readErr = fmt.Errorf("Error reading file: %v", readErr)
 		}
@@ -218,7 +299,7 @@ func (l *LogsCommand) Run(args []string) int {
 			numLines = defaultTailLines
 		}
 
-		r, readErr = l.followFile(client, alloc, follow, task, logType, api.OriginEnd, offset)
+		r, readErr = l.followFile(client, alloc, follow, task, logType, api.OriginEnd, offset, filter)
 
 		// If numLines is set, wrap the reader
 		if numLines != -1 {
@@ -243,10 +324,10 @@ func (l *LogsCommand) Run(args []string) int {
 // followFile outputs the contents of the file to stdout relative to the end of
 // the file.
 func (l *LogsCommand) followFile(client *api.Client, alloc *api.Allocation,
-	follow bool, task, logType, origin string, offset int64) (io.ReadCloser, error) {
+	follow bool, task, logType, origin string, offset int64, filter *api.LogFilter) (io.ReadCloser, error) {
 
 	cancel := make(chan struct{})
-	frames, err := client.AllocFS().Logs(alloc, follow, task, logType, origin, offset, cancel, nil)
+	frames, err := client.AllocFS().FilteredLogs(alloc, follow, task, logType, origin, offset, filter, cancel, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -268,3 +349,16 @@ func (l *LogsCommand) followFile(client *api.Client, alloc *api.Allocation,
 
 	return r, nil
 }
+
+// parseLogTime parses a time given either as an RFC3339 timestamp or as a
+// duration before now, such as "10m".
+func parseLogTime(value string) (time.Time, error) {
+	if d, err := time.ParseDuration(value); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q is neither a duration nor an RFC3339 timestamp", value)
+	}
+	return t, nil
+}
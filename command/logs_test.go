@@ -61,5 +61,31 @@ func TestLogsCommand_Fails(t *testing.T) {
 	if out := ui.ErrorWriter.String(); !strings.Contains(out, "No allocation(s) with prefix or id") {
 		t.Fatalf("expected not found error, got: %s", out)
 	}
+	ui.ErrorWriter.Reset()
+
+	// Fails on invalid filters
+	cases := map[string][]string{
+		"can't be used with -tail": {"-tail", "-since=10m"},
+		"can't be used with -f":    {"-f", "-until=10m"},
+		"Error parsing -since":     {"-since=yesterday"},
+		"Error parsing -grep":      {"-grep=("},
+	}
+	for expected, args := range cases {
+		args = append(args, "-address="+url, "26470238-5CF2-438F-8772-DC67CFB0705C")
+		if code := cmd.Run(args); code != 1 {
+			t.Fatalf("expected exit 1, got: %d", code)
+		}
+		if out := ui.ErrorWriter.String(); !strings.Contains(out, expected) {
+			t.Fatalf("expected %q error, got: %s", expected, out)
+		}
+		ui.ErrorWriter.Reset()
+	}
 
+	// Fails on both -json and -t options being specified
+	if code := cmd.Run([]string{"-json", "-t", "{{.ID}}", "26470238-5CF2-438F-8772-DC67CFB0705C"}); code != 1 {
+		t.Fatalf("expected exit code 1, got: %d", code)
+	}
+	if out := ui.ErrorWriter.String(); !strings.Contains(out, "Both -json and -t are not allowed") {
+		t.Fatalf("expected format flags error, got: %s", out)
+	}
 }
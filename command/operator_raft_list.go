@@ -18,6 +18,10 @@ Usage: nomad operator raft list-peers [options]
 
 Displays the current Raft peer configuration.
 
+List-peers will return one of the following exit codes:
+  * 0: Peers displayed.
+  * 1: Error querying or formatting the configuration.
+
 General Options:
 
   ` + generalOptionsUsage() + `
@@ -28,6 +32,12 @@ List Peers Options:
     The -stale argument defaults to "false" which means the leader provides the
     result. If the cluster is in an outage state without a leader, you may need
     to set -stale to "true" to get the configuration from a non-leader server.
+
+  -json
+    Output the Raft configuration in its JSON format.
+
+  -t
+    Format and display the Raft configuration using a Go template.
 `
 	return strings.TrimSpace(helpText)
 }
@@ -37,17 +47,26 @@ func (c *OperatorRaftListCommand) Synopsis() string {
 }
 
 func (c *OperatorRaftListCommand) Run(args []string) int {
-	var stale bool
+	var stale, json bool
+	var tmpl string
 
 	flags := c.Meta.FlagSet("raft", FlagSetClient)
 	flags.Usage = func() { c.Ui.Output(c.Help()) }
 
 	flags.BoolVar(&stale, "stale", false, "")
+	flags.BoolVar(&json, "json", false, "")
+	flags.StringVar(&tmpl, "t", "", "")
 	if err := flags.Parse(args); err != nil {
 		c.Ui.Error(fmt.Sprintf("Failed to parse args: %v", err))
 		return 1
 	}
 
+	format, err := outputFormat(json, tmpl)
+	if err != nil {
+		c.Ui.Error(err.Error())
+		return 1
+	}
+
 	// Set up a client.
 	client, err := c.Meta.Client()
 	if err != nil {
@@ -66,6 +85,17 @@ func (c *OperatorRaftListCommand) Run(args []string) int {
 		return 1
 	}
 
+	// Format it as requested.
+	if len(format) > 0 {
+		out, err := formatData(format, tmpl, reply)
+		if err != nil {
+			c.Ui.Error(err.Error())
+			return 1
+		}
+		c.Ui.Output(out)
+		return 0
+	}
+
 	// Format it as a nice table.
 	result := []string{"Node|ID|Address|State|Voter"}
 	for _, s := range reply.Servers {
@@ -27,4 +27,22 @@ func TestOperator_Raft_ListPeers(t *testing.T) {
 	if !strings.Contains(output, "leader") {
 		t.Fatalf("bad: %s", output)
 	}
+
+	// Output the configuration in its JSON format
+	ui.OutputWriter.Reset()
+	if code := c.Run([]string{"-address=" + addr, "-json"}); code != 0 {
+		t.Fatalf("bad: %d. %#v", code, ui.ErrorWriter.String())
+	}
+	if out := ui.OutputWriter.String(); !strings.Contains(out, `"Servers"`) {
+		t.Fatalf("bad: %s", out)
+	}
+
+	// Format the configuration using a template
+	ui.OutputWriter.Reset()
+	if code := c.Run([]string{"-address=" + addr, "-t", "{{range .Servers}}{{.Leader}}{{end}}"}); code != 0 {
+		t.Fatalf("bad: %d. %#v", code, ui.ErrorWriter.String())
+	}
+	if out := strings.TrimSpace(ui.OutputWriter.String()); out != "true" {
+		t.Fatalf("bad: %s", out)
+	}
 }
@@ -28,6 +28,17 @@ type PlanCommand struct {
 	color *colorstring.Colorize
 }
 
+// PlanResult is the result of a plan output by the plan command when the
+// -json or -t flag is set. The fields of the diff are sorted by name so the
+// output is stable for a given job.
+type PlanResult struct {
+	*api.JobPlanResponse
+
+	// Changes is set if allocations would be created or destroyed, in which
+	// case the exit code is 1.
+	Changes bool
+}
+
 func (c *PlanCommand) Help() string {
 	helpText := `
 Usage: nomad plan [options] <path>
@@ -69,6 +80,23 @@ Plan Options:
 
   -verbose
     Increase diff verbosity.
+
+  -json
+    Output the plan in its JSON format. The diff, the annotations of the
+    scheduler dry-run, the placement failures and the job modify index are
+    included. The exit codes are unchanged.
+
+  -t
+    Format and display the plan using a Go template.
+
+  -var 'key=value'
+    Set the value of an input variable declared in the job file. List and map
+    values are written in HCL syntax. This flag can be repeated and overrides
+    values set in variable files.
+
+  -var-file=path
+    Load the values of input variables from an HCL file of "key = value"
+    assignments. This flag can be repeated; later files override earlier ones.
 `
 	return strings.TrimSpace(helpText)
 }
@@ -78,17 +106,27 @@ func (c *PlanCommand) Synopsis() string {
 }
 
 func (c *PlanCommand) Run(args []string) int {
-	var diff, verbose bool
+	var diff, verbose, json bool
+	var tmpl string
 
 	flags := c.Meta.FlagSet("plan", FlagSetClient)
 	flags.Usage = func() { c.Ui.Output(c.Help()) }
 	flags.BoolVar(&diff, "diff", true, "")
 	flags.BoolVar(&verbose, "verbose", false, "")
+	flags.BoolVar(&json, "json", false, "")
+	flags.StringVar(&tmpl, "t", "", "")
+	c.JobGetter.addVarFlags(flags)
 
 	if err := flags.Parse(args); err != nil {
 		return 255
 	}
 
+	format, err := outputFormat(json, tmpl)
+	if err != nil {
+		c.Ui.Error(err.Error())
+		return 255
+	}
+
 	// Check that we got exactly one job
 	args = flags.Args()
 	if len(args) != 1 {
@@ -123,6 +161,21 @@ func (c *PlanCommand) Run(args []string) int {
 		return 255
 	}
 
+	// Output the formatted plan if requested
+	code := getExitCode(resp)
+	if len(format) > 0 {
+		out, err := formatData(format, tmpl, &PlanResult{
+			JobPlanResponse: resp,
+			Changes:         code == 1,
+		})
+		if err != nil {
+			c.Ui.Error(err.Error())
+			return 255
+		}
+		c.Ui.Output(out)
+		return code
+	}
+
 	// Print the diff if not disabled
 	if diff {
 		c.Ui.Output(fmt.Sprintf("%s\n",
@@ -136,7 +189,7 @@ func (c *PlanCommand) Run(args []string) int {
 
 	// Print the job index info
 	c.Ui.Output(c.Colorize().Color(formatJobModifyIndex(resp.JobModifyIndex, path)))
-	return getExitCode(resp)
+	return code
 }
 
 // getExitCode returns 0:
@@ -108,6 +108,19 @@ job "job1" {
 	}
 }
 
+func TestPlanCommand_FormatFlags(t *testing.T) {
+	ui := new(cli.MockUi)
+	cmd := &PlanCommand{Meta: Meta{Ui: ui}}
+
+	// Fails on both -json and -t options being specified
+	if code := cmd.Run([]string{"-json", "-t", "{{.Diff}}", "example.nomad"}); code != 255 {
+		t.Fatalf("expected exit code 255, got: %d", code)
+	}
+	if out := ui.ErrorWriter.String(); !strings.Contains(out, "Both -json and -t are not allowed") {
+		t.Fatalf("expected format flags error, got: %s", out)
+	}
+}
+
 func TestPlanCommand_From_STDIN(t *testing.T) {
 	stdinR, stdinW, err := os.Pipe()
 	if err != nil {
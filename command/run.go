@@ -88,6 +88,15 @@ Run Options:
   -output
     Output the JSON that would be submitted to the HTTP API without submitting
     the job.
+
+  -var 'key=value'
+    Set the value of an input variable declared in the job file. List and map
+    values are written in HCL syntax. This flag can be repeated and overrides
+    values set in variable files.
+
+  -var-file=path
+    Load the values of input variables from an HCL file of "key = value"
+    assignments. This flag can be repeated; later files override earlier ones.
 `
 	return strings.TrimSpace(helpText)
 }
@@ -105,6 +114,7 @@ func (c *RunCommand) Run(args []string) int {
 	flags.BoolVar(&detach, "detach", false, "")
 	flags.BoolVar(&verbose, "verbose", false, "")
 	flags.BoolVar(&output, "output", false, "")
+	c.JobGetter.addVarFlags(flags)
 	flags.StringVar(&checkIndexStr, "check-index", "", "")
 	flags.StringVar(&vaultToken, "vault-token", "", "")
 
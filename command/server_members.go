@@ -14,6 +14,13 @@ type ServerMembersCommand struct {
 	Meta
 }
 
+// ServerMember is a server output by the server-members command when the
+// -json or -t flag is set.
+type ServerMember struct {
+	*api.AgentMember
+	Leader bool
+}
+
 func (c *ServerMembersCommand) Help() string {
 	helpText := `
 Usage: nomad server-members [options]
@@ -21,6 +28,10 @@ Usage: nomad server-members [options]
   Display a list of the known servers and their status. Only Nomad servers are
   able to service this command.
 
+  Server-members will return one of the following exit codes:
+    * 0: Members displayed.
+    * 1: Error querying or formatting the members.
+
 General Options:
 
   ` + generalOptionsUsage() + `
@@ -31,6 +42,13 @@ Server Members Options:
     Show detailed information about each member. This dumps
     a raw set of tags which shows more information than the
     default output format.
+
+  -json
+    Output the members in their JSON format. Each member is annotated with
+    whether it is the leader of its region.
+
+  -t
+    Format and display the members using a Go template.
 `
 	return strings.TrimSpace(helpText)
 }
@@ -40,16 +58,25 @@ func (c *ServerMembersCommand) Synopsis() string {
 }
 
 func (c *ServerMembersCommand) Run(args []string) int {
-	var detailed bool
+	var detailed, json bool
+	var tmpl string
 
 	flags := c.Meta.FlagSet("server-members", FlagSetClient)
 	flags.Usage = func() { c.Ui.Output(c.Help()) }
 	flags.BoolVar(&detailed, "detailed", false, "Show detailed output")
+	flags.BoolVar(&json, "json", false, "")
+	flags.StringVar(&tmpl, "t", "", "")
 
 	if err := flags.Parse(args); err != nil {
 		return 1
 	}
 
+	format, err := outputFormat(json, tmpl)
+	if err != nil {
+		c.Ui.Error(err.Error())
+		return 1
+	}
+
 	// Check for extra arguments
 	args = flags.Args()
 	if len(args) != 0 {
@@ -86,6 +113,25 @@ func (c *ServerMembersCommand) Run(args []string) int {
 		return 1
 	}
 
+	// Format and output the members if requested
+	if len(format) > 0 {
+		members := make([]*ServerMember, len(srvMembers.Members))
+		for i, member := range srvMembers.Members {
+			members[i] = &ServerMember{
+				AgentMember: member,
+				Leader:      isRegionLeader(member, leaders),
+			}
+		}
+
+		out, err := formatData(format, tmpl, members)
+		if err != nil {
+			c.Ui.Error(err.Error())
+			return 1
+		}
+		c.Ui.Output(out)
+		return 0
+	}
+
 	// Format the list
 	var out []string
 	if detailed {
@@ -104,16 +150,7 @@ func standardOutput(mem []*api.AgentMember, leaders map[string]string) []string
 	members := make([]string, len(mem)+1)
 	members[0] = "Name|Address|Port|Status|Leader|Protocol|Build|Datacenter|Region"
 	for i, member := range mem {
-		reg := member.Tags["region"]
-		regLeader, ok := leaders[reg]
-		isLeader := false
-		if ok {
-			if regLeader == net.JoinHostPort(member.Addr, member.Tags["port"]) {
-
-				isLeader = true
-			}
-		}
-
+		isLeader := isRegionLeader(member, leaders)
 		members[i+1] = fmt.Sprintf("%s|%s|%d|%s|%t|%d|%s|%s|%s",
 			member.Name,
 			member.Addr,
@@ -149,6 +186,12 @@ func detailedOutput(mem []*api.AgentMember) []string {
 	return members
 }
 
+// isRegionLeader returns whether the member is the leader of its region.
+func isRegionLeader(member *api.AgentMember, leaders map[string]string) bool {
+	regLeader, ok := leaders[member.Tags["region"]]
+	return ok && regLeader == net.JoinHostPort(member.Addr, member.Tags["port"])
+}
+
 // regionLeaders returns a map of regions to the IP of the member that is the
 // leader.
 func regionLeaders(client *api.Client, mem []*api.AgentMember) (map[string]string, error) {
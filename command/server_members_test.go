@@ -40,6 +40,15 @@ func TestServerMembersCommand_Run(t *testing.T) {
 	if out := ui.OutputWriter.String(); !strings.Contains(out, "Tags") {
 		t.Fatalf("expected tags in output, got: %s", out)
 	}
+	ui.OutputWriter.Reset()
+
+	// Format the members using a template
+	if code := cmd.Run([]string{"-address=" + url, "-t", "{{range .}}{{.Name}} {{.Leader}}{{end}}"}); code != 0 {
+		t.Fatalf("expected exit 0, got: %d", code)
+	}
+	if out := strings.TrimSpace(ui.OutputWriter.String()); out != name+" true" {
+		t.Fatalf("expected %q output, got: %s", name+" true", out)
+	}
 }
 
 func TestMembersCommand_Fails(t *testing.T) {
@@ -62,4 +71,13 @@ func TestMembersCommand_Fails(t *testing.T) {
 	if out := ui.ErrorWriter.String(); !strings.Contains(out, "Error querying servers") {
 		t.Fatalf("expected failed query error, got: %s", out)
 	}
+	ui.ErrorWriter.Reset()
+
+	// Fails on both -json and -t options being specified
+	if code := cmd.Run([]string{"-json", "-t", "{{.ID}}"}); code != 1 {
+		t.Fatalf("expected exit code 1, got: %d", code)
+	}
+	if out := ui.ErrorWriter.String(); !strings.Contains(out, "Both -json and -t are not allowed") {
+		t.Fatalf("expected format flags error, got: %s", out)
+	}
 }
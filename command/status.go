@@ -24,6 +24,16 @@ type StatusCommand struct {
 	verbose   bool
 }
 
+// JobStatus is the status of a job output by the status command when the
+// -json or -t flag is set.
+type JobStatus struct {
+	Job         *api.Job
+	Summary     *api.JobSummary
+	Children    []*api.JobListStub
+	Evaluations []*api.Evaluation
+	Allocations []*api.AllocationListStub
+}
+
 func (c *StatusCommand) Help() string {
 	helpText := `
 Usage: nomad status [options] <job>
@@ -31,6 +41,10 @@ Usage: nomad status [options] <job>
   Display status information about jobs. If no job ID is given,
   a list of all known jobs will be dumped.
 
+  Status will return one of the following exit codes:
+    * 0: Status displayed, even if a prefix matched multiple jobs.
+    * 1: Error querying or formatting the status.
+
 General Options:
 
   ` + generalOptionsUsage() + `
@@ -50,6 +64,15 @@ Status Options:
 
   -verbose
     Display full information.
+
+  -json
+    Output the job status in its JSON format. The job, its summary, its
+    evaluations and allocations, and the jobs it launched are included.
+
+  -t
+    Format and display the job status using a Go template.
+
+  The -short, -evals and -verbose flags only affect the default output.
 `
 	return strings.TrimSpace(helpText)
 }
@@ -59,7 +82,8 @@ func (c *StatusCommand) Synopsis() string {
 }
 
 func (c *StatusCommand) Run(args []string) int {
-	var short bool
+	var short, json bool
+	var tmpl string
 
 	flags := c.Meta.FlagSet("status", FlagSetClient)
 	flags.Usage = func() { c.Ui.Output(c.Help()) }
@@ -67,11 +91,19 @@ func (c *StatusCommand) Run(args []string) int {
 	flags.BoolVar(&c.evals, "evals", false, "")
 	flags.BoolVar(&c.allAllocs, "all-allocs", false, "")
 	flags.BoolVar(&c.verbose, "verbose", false, "")
+	flags.BoolVar(&json, "json", false, "")
+	flags.StringVar(&tmpl, "t", "", "")
 
 	if err := flags.Parse(args); err != nil {
 		return 1
 	}
 
+	format, err := outputFormat(json, tmpl)
+	if err != nil {
+		c.Ui.Error(err.Error())
+		return 1
+	}
+
 	// Check that we either got no jobs or exactly one.
 	args = flags.Args()
 	if len(args) > 1 {
@@ -100,6 +132,10 @@ func (c *StatusCommand) Run(args []string) int {
 			return 1
 		}
 
+		if len(format) > 0 {
+			return c.outputFormatted(format, tmpl, jobs)
+		}
+
 		if len(jobs) == 0 {
 			// No output if we have no jobs
 			c.Ui.Output("No running jobs")
@@ -121,6 +157,9 @@ func (c *StatusCommand) Run(args []string) int {
 		return 1
 	}
 	if len(jobs) > 1 && strings.TrimSpace(jobID) != jobs[0].ID {
+		if len(format) > 0 {
+			return c.outputFormatted(format, tmpl, jobs)
+		}
 		c.Ui.Output(fmt.Sprintf("Prefix matched multiple jobs\n\n%s", createStatusListOutput(jobs)))
 		return 0
 	}
@@ -131,6 +170,15 @@ func (c *StatusCommand) Run(args []string) int {
 		return 1
 	}
 
+	if len(format) > 0 {
+		status, err := c.jobStatus(client, job)
+		if err != nil {
+			c.Ui.Error(err.Error())
+			return 1
+		}
+		return c.outputFormatted(format, tmpl, status)
+	}
+
 	periodic := job.IsPeriodic()
 	parameterized := job.IsParameterized()
 
@@ -185,6 +233,63 @@ func (c *StatusCommand) Run(args []string) int {
 	return 0
 }
 
+// outputFormatted outputs the data using the given format and template.
+func (c *StatusCommand) outputFormatted(format, tmpl string, data interface{}) int {
+	out, err := formatData(format, tmpl, data)
+	if err != nil {
+		c.Ui.Error(err.Error())
+		return 1
+	}
+	c.Ui.Output(out)
+	return 0
+}
+
+// jobStatus queries the status of the passed job. If a request fails, an
+// error is returned.
+func (c *StatusCommand) jobStatus(client *api.Client, job *api.Job) (*JobStatus, error) {
+	status := &JobStatus{Job: job}
+
+	summary, _, err := client.Jobs().Summary(*job.ID, nil)
+	if err != nil {
+		return nil, fmt.Errorf("Error querying job summary: %s", err)
+	}
+	status.Summary = summary
+
+	// Periodic and parameterized jobs launch children instead of allocations
+	var prefix string
+	if job.IsParameterized() {
+		prefix = *job.ID + structs.DispatchLaunchSuffix
+	} else if job.IsPeriodic() {
+		prefix = *job.ID + structs.PeriodicLaunchSuffix
+	}
+	if prefix != "" {
+		children, _, err := client.Jobs().PrefixList(prefix)
+		if err != nil {
+			return nil, fmt.Errorf("Error querying job: %s", err)
+		}
+		status.Children = make([]*api.JobListStub, 0, len(children))
+		for _, child := range children {
+			if child.ParentID == *job.ID {
+				status.Children = append(status.Children, child)
+			}
+		}
+		return status, nil
+	}
+
+	evals, _, err := client.Jobs().Evaluations(*job.ID, nil)
+	if err != nil {
+		return nil, fmt.Errorf("Error querying job evaluations: %s", err)
+	}
+	status.Evaluations = evals
+
+	allocs, _, err := client.Jobs().Allocations(*job.ID, c.allAllocs, nil)
+	if err != nil {
+		return nil, fmt.Errorf("Error querying job allocations: %s", err)
+	}
+	status.Allocations = allocs
+	return status, nil
+}
+
 // outputPeriodicInfo prints information about the passed periodic job. If a
 // request fails, an error is returned.
 func (c *StatusCommand) outputPeriodicInfo(client *api.Client, job *api.Job) error {
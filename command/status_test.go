@@ -80,6 +80,25 @@ func TestStatusCommand_Run(t *testing.T) {
 	}
 	ui.OutputWriter.Reset()
 
+	// Output a single job in its JSON format
+	if code := cmd.Run([]string{"-address=" + url, "-json", "job2_sfx"}); code != 0 {
+		t.Fatalf("expected exit 0, got: %d", code)
+	}
+	out = ui.OutputWriter.String()
+	if !strings.Contains(out, `"Summary"`) || !strings.Contains(out, `"Allocations"`) {
+		t.Fatalf("expected job status in JSON format, got: %s", out)
+	}
+	ui.OutputWriter.Reset()
+
+	// Format the job list using a template
+	if code := cmd.Run([]string{"-address=" + url, "-t", "{{range .}}{{.ID}} {{end}}"}); code != 0 {
+		t.Fatalf("expected exit 0, got: %d", code)
+	}
+	if out = strings.TrimSpace(ui.OutputWriter.String()); out != "job1_sfx job2_sfx" {
+		t.Fatalf("expected both jobs, got: %s", out)
+	}
+	ui.OutputWriter.Reset()
+
 	// Query a single job showing evals
 	if code := cmd.Run([]string{"-address=" + url, "-evals", "job2_sfx"}); code != 0 {
 		t.Fatalf("expected exit 0, got: %d", code)
@@ -184,6 +203,15 @@ func TestStatusCommand_Fails(t *testing.T) {
 	if out := ui.ErrorWriter.String(); !strings.Contains(out, "Error querying jobs") {
 		t.Fatalf("expected failed query error, got: %s", out)
 	}
+	ui.ErrorWriter.Reset()
+
+	// Fails on both -json and -t options being specified
+	if code := cmd.Run([]string{"-json", "-t", "{{.ID}}"}); code != 1 {
+		t.Fatalf("expected exit code 1, got: %d", code)
+	}
+	if out := ui.ErrorWriter.String(); !strings.Contains(out, "Both -json and -t are not allowed") {
+		t.Fatalf("expected format flags error, got: %s", out)
+	}
 }
 
 func waitForSuccess(ui cli.Ui, client *api.Client, length int, t *testing.T, evalId string) int {
@@ -24,6 +24,17 @@ Usage: nomad validate [options] <path>
   If the supplied path is "-", the jobfile is read from stdin. Otherwise
   it is read from the file at the supplied path or downloaded and
   read from URL specified.
+
+Validate Options:
+
+  -var 'key=value'
+    Set the value of an input variable declared in the job file. List and map
+    values are written in HCL syntax. This flag can be repeated and overrides
+    values set in variable files.
+
+  -var-file=path
+    Load the values of input variables from an HCL file of "key = value"
+    assignments. This flag can be repeated; later files override earlier ones.
 `
 	return strings.TrimSpace(helpText)
 }
@@ -35,6 +46,7 @@ func (c *ValidateCommand) Synopsis() string {
 func (c *ValidateCommand) Run(args []string) int {
 	flags := c.Meta.FlagSet("validate", FlagSetNone)
 	flags.Usage = func() { c.Ui.Output(c.Help()) }
+	c.JobGetter.addVarFlags(flags)
 	if err := flags.Parse(args); err != nil {
 		return 1
 	}
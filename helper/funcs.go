@@ -49,6 +49,11 @@ func IntToPtr(i int) *int {
 	return &i
 }
 
+// Int64ToPtr returns the pointer to an int64
+func Int64ToPtr(i int64) *int64 {
+	return &i
+}
+
 // UintToPtr returns the pointer to an uint
 func Uint64ToPtr(u uint64) *uint64 {
 	return &u
@@ -173,6 +178,19 @@ func CopyMapStringFloat64(m map[string]float64) map[string]float64 {
 	return c
 }
 
+func CopyMapStringInterface(m map[string]interface{}) map[string]interface{} {
+	l := len(m)
+	if l == 0 {
+		return nil
+	}
+
+	c := make(map[string]interface{}, l)
+	for k, v := range m {
+		c[k] = v
+	}
+	return c
+}
+
 func CopySliceString(s []string) []string {
 	l := len(s)
 	if l == 0 {
@@ -0,0 +1,548 @@
+package jobspec
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// exprTokenType is the type of a token of an interpolated expression
+type exprTokenType int
+
+const (
+	exprEOF exprTokenType = iota
+	exprIdent
+	exprNumber
+	exprString
+	exprPunct
+)
+
+// exprToken is a token of an interpolated expression
+type exprToken struct {
+	typ  exprTokenType
+	text string
+}
+
+// exprTwoCharPuncts are the operators made of two characters
+var exprTwoCharPuncts = []string{"==", "!=", "<=", ">=", "&&", "||"}
+
+// lexExpr splits an interpolated expression into tokens.
+func lexExpr(s string) ([]exprToken, error) {
+	var tokens []exprToken
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '_' || unicode.IsLetter(rune(c)):
+			j := i + 1
+			for j < len(s) && (s[j] == '_' || unicode.IsLetter(rune(s[j])) || unicode.IsDigit(rune(s[j]))) {
+				j++
+			}
+			tokens = append(tokens, exprToken{exprIdent, s[i:j]})
+			i = j
+		case unicode.IsDigit(rune(c)):
+			j := i + 1
+			for j < len(s) && (unicode.IsDigit(rune(s[j])) || s[j] == '.') {
+				j++
+			}
+			tokens = append(tokens, exprToken{exprNumber, s[i:j]})
+			i = j
+		case c == '"':
+			j := i + 1
+			for ; j < len(s) && s[j] != '"'; j++ {
+				if s[j] == '\\' {
+					j++
+				}
+			}
+			if j >= len(s) {
+				return nil, fmt.Errorf("unterminated string in %q", s)
+			}
+			str, err := strconv.Unquote(s[i : j+1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid string %s: %v", s[i:j+1], err)
+			}
+			tokens = append(tokens, exprToken{exprString, str})
+			i = j + 1
+		default:
+			punct := string(c)
+			for _, p := range exprTwoCharPuncts {
+				if strings.HasPrefix(s[i:], p) {
+					punct = p
+					break
+				}
+			}
+			if !strings.Contains("+-*/%<>!()[]{},.?:=", string(c)) && len(punct) == 1 {
+				return nil, fmt.Errorf("unexpected character %q", c)
+			}
+			tokens = append(tokens, exprToken{exprPunct, punct})
+			i += len(punct)
+		}
+	}
+	return append(tokens, exprToken{typ: exprEOF}), nil
+}
+
+// evalContext is the scope interpolated expressions are evaluated in
+type evalContext struct {
+	// vars are the values of the input variables
+	vars map[string]interface{}
+
+	// baseDir is the directory relative paths of file functions are
+	// resolved against
+	baseDir string
+}
+
+// exprParser evaluates an interpolated expression while parsing it
+type exprParser struct {
+	ctx    *evalContext
+	tokens []exprToken
+	pos    int
+
+	// skip is greater than zero while parsing operands that are not
+	// evaluated, such as the branch of a conditional that is not taken
+	skip int
+}
+
+// evalExpr evaluates the interpolated expression in the context.
+func (ctx *evalContext) evalExpr(s string) (interface{}, error) {
+	tokens, err := lexExpr(s)
+	if err != nil {
+		return nil, err
+	}
+	p := &exprParser{ctx: ctx, tokens: tokens}
+	v, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.typ != exprEOF {
+		return nil, fmt.Errorf("unexpected %q", t.text)
+	}
+	return v, nil
+}
+
+func (p *exprParser) peek() exprToken {
+	return p.tokens[p.pos]
+}
+
+func (p *exprParser) next() exprToken {
+	t := p.tokens[p.pos]
+	if t.typ != exprEOF {
+		p.pos++
+	}
+	return t
+}
+
+// accept consumes the next token if it is the given punctuation
+func (p *exprParser) accept(punct string) bool {
+	if t := p.peek(); t.typ == exprPunct && t.text == punct {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *exprParser) expect(punct string) error {
+	if !p.accept(punct) {
+		t := p.peek()
+		if t.typ == exprEOF {
+			return fmt.Errorf("expected %q but reached the end of the expression", punct)
+		}
+		return fmt.Errorf("expected %q but found %q", punct, t.text)
+	}
+	return nil
+}
+
+func (p *exprParser) parseExpr() (interface{}, error) {
+	cond, err := p.parseBinary(0)
+	if err != nil {
+		return nil, err
+	}
+	if !p.accept("?") {
+		return cond, nil
+	}
+
+	b, ok := cond.(bool)
+	if !ok && p.skip == 0 {
+		return nil, fmt.Errorf("condition must be a bool, got %s", typeName(cond))
+	}
+
+	// Only the branch that is taken is evaluated
+	a, err := p.parseSkipped(!b)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(":"); err != nil {
+		return nil, err
+	}
+	c, err := p.parseSkipped(b)
+	if err != nil {
+		return nil, err
+	}
+	if b {
+		return a, nil
+	}
+	return c, nil
+}
+
+// parseSkipped parses an expression without evaluating it if skip is set
+func (p *exprParser) parseSkipped(skip bool) (interface{}, error) {
+	if skip {
+		p.skip++
+		defer func() { p.skip-- }()
+	}
+	return p.parseExpr()
+}
+
+// exprPrecedence is the list of binary operators from the lowest to the
+// highest precedence
+var exprPrecedence = [][]string{
+	{"||"},
+	{"&&"},
+	{"==", "!="},
+	{"<", "<=", ">", ">="},
+	{"+", "-"},
+	{"*", "/", "%"},
+}
+
+func (p *exprParser) parseBinary(level int) (interface{}, error) {
+	if level == len(exprPrecedence) {
+		return p.parseUnary()
+	}
+
+	left, err := p.parseBinary(level + 1)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t := p.peek()
+		if t.typ != exprPunct || !containsString(exprPrecedence[level], t.text) {
+			return left, nil
+		}
+		p.next()
+
+		// Logical operators short-circuit
+		short := (t.text == "&&" && left == false) || (t.text == "||" && left == true)
+		if short {
+			p.skip++
+		}
+		right, err := p.parseBinary(level + 1)
+		if short {
+			p.skip--
+		}
+		if err != nil {
+			return nil, err
+		}
+		if short || p.skip > 0 {
+			continue
+		}
+		if left, err = binaryOp(t.text, left, right); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (p *exprParser) parseUnary() (interface{}, error) {
+	switch {
+	case p.accept("!"):
+		v, err := p.parseUnary()
+		if err != nil || p.skip > 0 {
+			return nil, err
+		}
+		b, ok := v.(bool)
+		if !ok {
+			return nil, fmt.Errorf("operator ! requires a bool, got %s", typeName(v))
+		}
+		return !b, nil
+	case p.accept("-"):
+		v, err := p.parseUnary()
+		if err != nil || p.skip > 0 {
+			return nil, err
+		}
+		n, ok := v.(float64)
+		if !ok {
+			return nil, fmt.Errorf("operator - requires a number, got %s", typeName(v))
+		}
+		return -n, nil
+	}
+	return p.parsePostfix()
+}
+
+func (p *exprParser) parsePostfix() (interface{}, error) {
+	v, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		switch {
+		case p.accept("["):
+			key, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect("]"); err != nil {
+				return nil, err
+			}
+			if p.skip > 0 {
+				continue
+			}
+			if v, err = index(v, key); err != nil {
+				return nil, err
+			}
+		case p.accept("."):
+			t := p.next()
+			if t.typ != exprIdent && t.typ != exprNumber {
+				return nil, fmt.Errorf("expected attribute name after \".\"")
+			}
+			if p.skip > 0 {
+				continue
+			}
+			key := interface{}(t.text)
+			if t.typ == exprNumber {
+				n, _ := strconv.ParseFloat(t.text, 64)
+				key = n
+			}
+			if v, err = index(v, key); err != nil {
+				return nil, err
+			}
+		default:
+			return v, nil
+		}
+	}
+}
+
+func (p *exprParser) parsePrimary() (interface{}, error) {
+	t := p.next()
+	switch t.typ {
+	case exprNumber:
+		n, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q", t.text)
+		}
+		return n, nil
+	case exprString:
+		return t.text, nil
+	case exprIdent:
+		switch {
+		case t.text == "true":
+			return true, nil
+		case t.text == "false":
+			return false, nil
+		case p.accept("("):
+			args, err := p.parseList(")")
+			if err != nil || p.skip > 0 {
+				return nil, err
+			}
+			return p.ctx.call(t.text, args)
+		case t.text == "var":
+			if err := p.expect("."); err != nil {
+				return nil, err
+			}
+			name := p.next()
+			if name.typ != exprIdent {
+				return nil, fmt.Errorf("expected variable name after \"var.\"")
+			}
+			v, ok := p.ctx.vars[name.text]
+			if !ok && p.skip == 0 {
+				return nil, fmt.Errorf("undeclared variable %q", name.text)
+			}
+			return v, nil
+		default:
+			return nil, fmt.Errorf("unknown reference %q", t.text)
+		}
+	case exprPunct:
+		switch t.text {
+		case "(":
+			v, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			return v, p.expect(")")
+		case "[":
+			list, err := p.parseList("]")
+			if err != nil {
+				return nil, err
+			}
+			return list, nil
+		case "{":
+			return p.parseMap()
+		}
+		return nil, fmt.Errorf("unexpected %q", t.text)
+	}
+	return nil, fmt.Errorf("unexpected end of expression")
+}
+
+// parseList parses the comma separated expressions up to the closing
+// punctuation
+func (p *exprParser) parseList(end string) ([]interface{}, error) {
+	list := make([]interface{}, 0)
+	for !p.accept(end) {
+		if len(list) != 0 {
+			if err := p.expect(","); err != nil {
+				return nil, err
+			}
+			// Allow a trailing comma
+			if p.accept(end) {
+				break
+			}
+		}
+		v, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, v)
+	}
+	return list, nil
+}
+
+// parseMap parses a map of the form { key = value, ... }
+func (p *exprParser) parseMap() (map[string]interface{}, error) {
+	m := make(map[string]interface{})
+	for !p.accept("}") {
+		if len(m) != 0 {
+			if err := p.expect(","); err != nil {
+				return nil, err
+			}
+			if p.accept("}") {
+				break
+			}
+		}
+		key := p.next()
+		if key.typ != exprIdent && key.typ != exprString {
+			return nil, fmt.Errorf("invalid map key %q", key.text)
+		}
+		if !p.accept("=") {
+			if err := p.expect(":"); err != nil {
+				return nil, err
+			}
+		}
+		v, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		m[key.text] = v
+	}
+	return m, nil
+}
+
+// index returns the element of the list or map with the given key
+func index(v, key interface{}) (interface{}, error) {
+	switch c := v.(type) {
+	case []interface{}:
+		n, ok := key.(float64)
+		if !ok || n != math.Trunc(n) {
+			return nil, fmt.Errorf("list index must be a whole number, got %s", typeName(key))
+		}
+		if n < 0 || int(n) >= len(c) {
+			return nil, fmt.Errorf("list index %d out of range for list of length %d", int(n), len(c))
+		}
+		return c[int(n)], nil
+	case map[string]interface{}:
+		k, err := toString(key)
+		if err != nil {
+			return nil, fmt.Errorf("map key must be a string: %v", err)
+		}
+		e, ok := c[k]
+		if !ok {
+			return nil, fmt.Errorf("map has no key %q", k)
+		}
+		return e, nil
+	}
+	return nil, fmt.Errorf("can not index a %s", typeName(v))
+}
+
+// binaryOp applies the binary operator to the operands
+func binaryOp(op string, a, b interface{}) (interface{}, error) {
+	switch op {
+	case "==":
+		return reflect.DeepEqual(a, b), nil
+	case "!=":
+		return !reflect.DeepEqual(a, b), nil
+	case "&&", "||":
+		x, ok1 := a.(bool)
+		y, ok2 := b.(bool)
+		if !ok1 || !ok2 {
+			return nil, fmt.Errorf("operator %s requires bools, got %s and %s", op, typeName(a), typeName(b))
+		}
+		if op == "&&" {
+			return x && y, nil
+		}
+		return x || y, nil
+	}
+
+	x, ok1 := a.(float64)
+	y, ok2 := b.(float64)
+	if !ok1 || !ok2 {
+		return nil, fmt.Errorf("operator %s requires numbers, got %s and %s", op, typeName(a), typeName(b))
+	}
+	switch op {
+	case "<":
+		return x < y, nil
+	case "<=":
+		return x <= y, nil
+	case ">":
+		return x > y, nil
+	case ">=":
+		return x >= y, nil
+	case "+":
+		return x + y, nil
+	case "-":
+		return x - y, nil
+	case "*":
+		return x * y, nil
+	case "/":
+		if y == 0 {
+			return nil, fmt.Errorf("division by zero")
+		}
+		return x / y, nil
+	case "%":
+		if y == 0 {
+			return nil, fmt.Errorf("division by zero")
+		}
+		return math.Mod(x, y), nil
+	}
+	return nil, fmt.Errorf("unknown operator %q", op)
+}
+
+// typeName returns the name of the type of an evaluated value
+func typeName(v interface{}) string {
+	switch v.(type) {
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "bool"
+	case []interface{}:
+		return "list"
+	case map[string]interface{}:
+		return "map"
+	case nil:
+		return "null"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+// toString converts a primitive value to its string representation
+func toString(v interface{}) (string, error) {
+	switch s := v.(type) {
+	case string:
+		return s, nil
+	case float64:
+		return strconv.FormatFloat(s, 'f', -1, 64), nil
+	case bool:
+		return strconv.FormatBool(s), nil
+	}
+	return "", fmt.Errorf("can not convert a %s to a string", typeName(v))
+}
+
+func containsString(list []string, s string) bool {
+	for _, e := range list {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
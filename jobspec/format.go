@@ -0,0 +1,462 @@
+package jobspec
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/hcl/ast"
+	"github.com/hashicorp/hcl/hcl/parser"
+	"github.com/hashicorp/hcl/hcl/token"
+)
+
+const (
+	// formatIndent is the indentation of each level of a formatted job file
+	formatIndent = "  "
+)
+
+// stanzaKeyOrder is the canonical ordering of the keys of each stanza known
+// by the parser. Attributes are emitted before blocks, each in the order
+// listed here. Keys that are not listed keep their order after the listed
+// keys.
+var stanzaKeyOrder = map[string][]string{
+	"": {"variable", "job"},
+	"variable": {
+		"type", "description", "default", "validation",
+	},
+	"validation": {"condition", "error_message"},
+	"job": {
+		"id", "name", "region", "datacenters", "type", "priority",
+		"all_at_once", "vault_token", "constraint", "update", "periodic",
+		"parameterized", "meta", "vault", "group", "task",
+	},
+	"group": {
+		"count", "constraint", "restart", "ephemeral_disk", "network",
+		"scaling", "meta", "vault", "task",
+	},
+	"task": {
+		"driver", "user", "leader", "kill_timeout", "config", "constraint",
+		"env", "meta", "artifact", "template", "dispatch_payload", "service",
+		"resources", "logs", "vault", "identity",
+	},
+	"constraint": {
+		"attribute", "operator", "value", "distinct_hosts",
+		"distinct_property", "regexp", "set_contains", "version",
+	},
+	"restart":          {"attempts", "interval", "delay", "mode"},
+	"ephemeral_disk":   {"sticky", "migrate", "size"},
+	"logs":             {"max_files", "max_file_size", "compress", "max_age", "sink"},
+	"sink":             {"type", "address", "batch_size", "batch_wait", "buffer_size", "headers"},
+	"dispatch_payload": {"file"},
+	"artifact":         {"source", "destination", "options", "signature"},
+	"template": {
+		"source", "destination", "data", "change_mode", "change_signal",
+		"splay", "perms", "left_delimiter", "right_delimiter", "env",
+	},
+	"service":         {"name", "provider", "port", "tags", "check", "connect"},
+	"connect":         {"sidecar_service"},
+	"sidecar_service": {"port", "proxy"},
+	"check": {
+		"name", "type", "command", "args", "path", "protocol", "method",
+		"port", "interval", "timeout", "initial_status", "body",
+		"tls_skip_verify", "grpc_service", "grpc_use_tls", "header",
+		"check_restart",
+	},
+	"check_restart": {"limit", "grace", "ignore_warnings"},
+	"resources":     {"cpu", "memory", "disk", "iops", "network"},
+	"network":       {"mode", "mbits", "port"},
+	"update":        {"stagger", "max_parallel"},
+	"periodic":      {"cron", "prohibit_overlap", "time_zone", "enabled"},
+	"vault":         {"policies", "role", "entity_alias", "env", "change_mode", "change_signal"},
+	"identity":      {"env", "file"},
+	"parameterized": {"payload", "meta_required", "meta_optional"},
+	"scaling":       {"enabled", "min", "max", "policy"},
+}
+
+// freeformStanzas are the stanzas whose keys are user defined. Their keys,
+// and those of any stanza nested in them, keep their order.
+var freeformStanzas = map[string]struct{}{
+	"config":  {},
+	"env":     {},
+	"header":  {},
+	"headers": {},
+	"meta":    {},
+	"options": {},
+	"policy":  {},
+	"proxy":   {},
+}
+
+// Format returns the job file src in canonical form. The keys of the known
+// stanzas are reordered, indentation and alignment are normalized, and
+// comments are preserved.
+func Format(src []byte) ([]byte, error) {
+	file, err := parser.Parse(src)
+	if err != nil {
+		return nil, err
+	}
+	root, ok := file.Node.(*ast.ObjectList)
+	if !ok {
+		return nil, fmt.Errorf("error parsing: file doesn't contain a root object")
+	}
+
+	p := &formatter{
+		before:   make(map[ast.Node][]*ast.CommentGroup),
+		trailing: make(map[ast.Node][]*ast.CommentGroup),
+	}
+	p.assignComments(root, file.Comments)
+	p.objectList(root, "", false, 0)
+
+	out := bytes.TrimSpace(p.buf.Bytes())
+	return append(out, '\n'), nil
+}
+
+// formatter emits the canonical form of a parsed job file.
+type formatter struct {
+	buf bytes.Buffer
+
+	// before are the comments that are not attached to a node by the parser
+	// and are emitted before the given item or list element. trailing are
+	// those following the last item of an object or list.
+	before   map[ast.Node][]*ast.CommentGroup
+	trailing map[ast.Node][]*ast.CommentGroup
+}
+
+// assignComments finds the comments that the parser did not attach as lead or
+// line comments and assigns them to the item or list element following them
+// in their innermost enclosing object or list.
+func (p *formatter) assignComments(root *ast.ObjectList, comments []*ast.CommentGroup) {
+	attached := make(map[*ast.CommentGroup]struct{})
+	ast.Walk(root, func(n ast.Node) (ast.Node, bool) {
+		switch t := n.(type) {
+		case *ast.ObjectItem:
+			attached[t.LeadComment] = struct{}{}
+			attached[t.LineComment] = struct{}{}
+		case *ast.LiteralType:
+			attached[t.LeadComment] = struct{}{}
+			attached[t.LineComment] = struct{}{}
+		}
+		return n, true
+	})
+
+	for _, c := range comments {
+		if _, ok := attached[c]; ok {
+			continue
+		}
+
+		// Descend into the innermost object or list containing the comment
+		var container ast.Node = root
+		nodes := objectNodes(root)
+		offset := c.Pos().Offset
+	DESCEND:
+		for {
+			for _, item := range nodes {
+				var n ast.Node
+				if i, ok := item.(*ast.ObjectItem); ok {
+					n = i.Val
+				} else {
+					n = item
+				}
+				switch t := n.(type) {
+				case *ast.ObjectType:
+					if t.Lbrace.Offset < offset && offset < t.Rbrace.Offset {
+						container, nodes = t, objectNodes(t.List)
+						continue DESCEND
+					}
+				case *ast.ListType:
+					if t.Lbrack.Offset < offset && offset < t.Rbrack.Offset {
+						container, nodes = t, t.List
+						continue DESCEND
+					}
+				}
+			}
+			break
+		}
+
+		next := ast.Node(nil)
+		for _, n := range nodes {
+			if n.Pos().Offset > offset {
+				next = n
+				break
+			}
+		}
+		if next != nil {
+			p.before[next] = append(p.before[next], c)
+		} else {
+			p.trailing[container] = append(p.trailing[container], c)
+		}
+	}
+}
+
+// objectNodes returns the items of the object list as nodes.
+func objectNodes(list *ast.ObjectList) []ast.Node {
+	nodes := make([]ast.Node, len(list.Items))
+	for i, item := range list.Items {
+		nodes[i] = item
+	}
+	return nodes
+}
+
+// objectList emits the items of the stanza at the given indentation level.
+func (p *formatter) objectList(list *ast.ObjectList, stanza string, freeform bool, level int) {
+	items := make([]*ast.ObjectItem, len(list.Items))
+	copy(items, list.Items)
+	if !freeform {
+		sortItems(items, stanzaKeyOrder[stanza])
+	}
+
+	// Align the values of each run of single line attributes not separated
+	// by comments
+	widths := make([]int, len(items))
+	for i := 0; i < len(items); {
+		j, width := i, 0
+		for ; j < len(items) && !isBlock(items[j]); j++ {
+			if j != i && (p.hasLeadComments(items[j]) || p.multiline(items[j-1].Val)) {
+				break
+			}
+			if w := len(itemKey(items[j])); w > width {
+				width = w
+			}
+		}
+		for k := i; k < j; k++ {
+			widths[k] = width
+		}
+		if j == i {
+			j++
+		}
+		i = j
+	}
+
+	for i, item := range items {
+		block := isBlock(item)
+		if i != 0 && (block || isBlock(items[i-1]) || len(p.before[item]) != 0) {
+			p.buf.WriteString("\n")
+		}
+		p.leadComments(item, level)
+
+		p.indent(level)
+		key := itemKey(item)
+		p.buf.WriteString(key)
+		name := strings.Trim(item.Keys[0].Token.Text, `"`)
+		if block {
+			_, nested := freeformStanzas[name]
+			p.buf.WriteString(" ")
+			p.objectType(item.Val.(*ast.ObjectType), name, freeform || nested, level)
+		} else {
+			p.buf.WriteString(strings.Repeat(" ", widths[i]-len(key)))
+			p.buf.WriteString(" = ")
+			p.value(item.Val, name, freeform, level)
+		}
+		if c := item.LineComment; c != nil && !isLeadComment(item) {
+			p.buf.WriteString(" ")
+			p.buf.WriteString(commentText(c))
+		}
+		p.buf.WriteString("\n")
+	}
+
+	if trailing := p.trailing[list]; len(trailing) != 0 {
+		if len(items) != 0 {
+			p.buf.WriteString("\n")
+		}
+		p.comments(trailing, level)
+	}
+}
+
+// sortItems stably sorts the items by the position of their key in order,
+// placing attributes before blocks.
+func sortItems(items []*ast.ObjectItem, order []string) {
+	rank := func(item *ast.ObjectItem) int {
+		r := len(order)
+		name := strings.Trim(item.Keys[0].Token.Text, `"`)
+		for i, key := range order {
+			if key == name {
+				r = i
+				break
+			}
+		}
+		if isBlock(item) {
+			r += len(order) + 1
+		}
+		return r
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		return rank(items[i]) < rank(items[j])
+	})
+}
+
+// isBlock returns whether the item is a stanza rather than an attribute.
+func isBlock(item *ast.ObjectItem) bool {
+	_, ok := item.Val.(*ast.ObjectType)
+	return ok
+}
+
+// itemKey returns the keys of the item as they are written.
+func itemKey(item *ast.ObjectItem) string {
+	keys := make([]string, len(item.Keys))
+	for i, k := range item.Keys {
+		keys[i] = k.Token.Text
+	}
+	return strings.Join(keys, " ")
+}
+
+// isLeadComment returns whether the line comment the parser attached to the
+// item is on a line before it, in which case it is emitted as a lead comment.
+func isLeadComment(item *ast.ObjectItem) bool {
+	c := item.LineComment
+	if c == nil {
+		return false
+	}
+	if c.Pos().Line < item.Keys[0].Pos().Line {
+		return true
+	}
+	lit, ok := item.Val.(*ast.LiteralType)
+	return ok && lit.Token.Type == token.HEREDOC
+}
+
+// hasLeadComments returns whether any comment is emitted before the item.
+func (p *formatter) hasLeadComments(item *ast.ObjectItem) bool {
+	return len(p.before[item]) != 0 || item.LeadComment != nil || isLeadComment(item)
+}
+
+// leadComments emits the comments preceding the item.
+func (p *formatter) leadComments(item *ast.ObjectItem, level int) {
+	if before := p.before[item]; len(before) != 0 {
+		p.comments(before, level)
+		if item.LeadComment != nil {
+			p.buf.WriteString("\n")
+		}
+	}
+	if isLeadComment(item) {
+		p.comments([]*ast.CommentGroup{item.LineComment}, level)
+	}
+	if item.LeadComment != nil {
+		p.comments([]*ast.CommentGroup{item.LeadComment}, level)
+	}
+}
+
+// comments emits each comment on its own line.
+func (p *formatter) comments(groups []*ast.CommentGroup, level int) {
+	for _, g := range groups {
+		for _, c := range g.List {
+			p.indent(level)
+			p.buf.WriteString(strings.TrimSpace(c.Text))
+			p.buf.WriteString("\n")
+		}
+	}
+}
+
+// commentText returns the comments of the group joined on a single line.
+func commentText(g *ast.CommentGroup) string {
+	texts := make([]string, len(g.List))
+	for i, c := range g.List {
+		texts[i] = strings.TrimSpace(c.Text)
+	}
+	return strings.Join(texts, " ")
+}
+
+// objectType emits the body of an object enclosed in braces.
+func (p *formatter) objectType(o *ast.ObjectType, stanza string, freeform bool, level int) {
+	if len(o.List.Items) == 0 && len(p.trailing[o]) == 0 {
+		p.buf.WriteString("{}")
+		return
+	}
+
+	// Comments trailing the object are stored against the object rather
+	// than its list
+	if trailing := p.trailing[o]; len(trailing) != 0 {
+		p.trailing[o.List] = trailing
+	}
+
+	p.buf.WriteString("{\n")
+	p.objectList(o.List, stanza, freeform, level+1)
+	p.indent(level)
+	p.buf.WriteString("}")
+}
+
+// value emits the value of an attribute.
+func (p *formatter) value(n ast.Node, stanza string, freeform bool, level int) {
+	switch t := n.(type) {
+	case *ast.LiteralType:
+		p.buf.WriteString(strings.TrimRight(t.Token.Text, "\n"))
+	case *ast.ObjectType:
+		_, nested := freeformStanzas[stanza]
+		p.objectType(t, stanza, freeform || nested, level)
+	case *ast.ListType:
+		p.listType(t, stanza, freeform, level)
+	}
+}
+
+// listType emits a list. Lists written on a single line without comments are
+// kept on a single line, others place each element on its own line.
+func (p *formatter) listType(l *ast.ListType, stanza string, freeform bool, level int) {
+	if len(l.List) == 0 && len(p.trailing[l]) == 0 {
+		p.buf.WriteString("[]")
+		return
+	}
+
+	if !p.multiline(l) {
+		p.buf.WriteString("[")
+		for i, elem := range l.List {
+			if i != 0 {
+				p.buf.WriteString(", ")
+			}
+			p.value(elem, stanza, freeform, level)
+		}
+		p.buf.WriteString("]")
+		return
+	}
+
+	p.buf.WriteString("[\n")
+	for _, elem := range l.List {
+		p.comments(p.before[elem], level+1)
+		lit, isLit := elem.(*ast.LiteralType)
+		if isLit && lit.LeadComment != nil {
+			p.comments([]*ast.CommentGroup{lit.LeadComment}, level+1)
+		}
+		p.indent(level + 1)
+		p.value(elem, stanza, freeform, level+1)
+		if isLit && lit.Token.Type == token.HEREDOC {
+			// The heredoc marker must end its line
+			p.buf.WriteString("\n")
+			p.indent(level + 1)
+		}
+		p.buf.WriteString(",")
+		if isLit && lit.LineComment != nil {
+			p.buf.WriteString(" ")
+			p.buf.WriteString(commentText(lit.LineComment))
+		}
+		p.buf.WriteString("\n")
+	}
+	p.comments(p.trailing[l], level+1)
+	p.indent(level)
+	p.buf.WriteString("]")
+}
+
+// multiline returns whether the value is emitted on multiple lines.
+func (p *formatter) multiline(n ast.Node) bool {
+	switch t := n.(type) {
+	case *ast.LiteralType:
+		return t.Token.Type == token.HEREDOC
+	case *ast.ObjectType:
+		return len(t.List.Items) != 0 || len(p.trailing[t]) != 0
+	case *ast.ListType:
+		if t.Lbrack.Line != t.Rbrack.Line || len(p.trailing[t]) != 0 {
+			return true
+		}
+		for _, elem := range t.List {
+			if len(p.before[elem]) != 0 {
+				return true
+			}
+			lit, ok := elem.(*ast.LiteralType)
+			if !ok || lit.LeadComment != nil || lit.LineComment != nil || p.multiline(lit) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// indent emits the indentation of the given level.
+func (p *formatter) indent(level int) {
+	p.buf.WriteString(strings.Repeat(formatIndent, level))
+}
@@ -0,0 +1,73 @@
+package jobspec
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFormat(t *testing.T) {
+	src, err := ioutil.ReadFile(filepath.Join("./test-fixtures", "format.hcl"))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	expected, err := ioutil.ReadFile(filepath.Join("./test-fixtures", "format.golden.hcl"))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	out, err := Format(src)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !bytes.Equal(out, expected) {
+		t.Fatalf("got:\n%s\nwant:\n%s", out, expected)
+	}
+}
+
+func TestFormat_Fixtures(t *testing.T) {
+	paths, err := filepath.Glob(filepath.Join("./test-fixtures", "*.hcl"))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	for _, path := range paths {
+		src, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		out, err := Format(src)
+		if err != nil {
+			// Only files that fail to parse can't be formatted
+			if _, perr := Parse(bytes.NewReader(src)); perr == nil {
+				t.Fatalf("%s: %v", path, err)
+			}
+			continue
+		}
+
+		// Formatting is idempotent
+		again, err := Format(out)
+		if err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+		if !bytes.Equal(out, again) {
+			t.Fatalf("%s: formatting is not idempotent:\n%s\n\n%s", path, out, again)
+		}
+
+		// The formatted job is unchanged
+		if strings.HasPrefix(filepath.Base(path), "variables") {
+			continue
+		}
+		expected, expectedErr := Parse(bytes.NewReader(src))
+		job, err := Parse(bytes.NewReader(out))
+		if (expectedErr == nil) != (err == nil) {
+			t.Fatalf("%s: got error %v; want %v", path, err, expectedErr)
+		}
+		if !reflect.DeepEqual(job, expected) {
+			t.Fatalf("%s: formatted job differs:\n%s", path, out)
+		}
+	}
+}
@@ -0,0 +1,733 @@
+package jobspec
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// exprFunc is a function that can be called in interpolated expressions
+type exprFunc func(ctx *evalContext, args []interface{}) (interface{}, error)
+
+// exprFuncs is the standard library of functions available in job
+// specifications
+var exprFuncs = map[string]exprFunc{
+	// String functions
+	"format":        funcFormat,
+	"join":          funcJoin,
+	"lower":         stringFunc(strings.ToLower),
+	"regex_replace": funcRegexReplace,
+	"replace":       funcReplace,
+	"split":         funcSplit,
+	"substr":        funcSubstr,
+	"title":         stringFunc(strings.Title),
+	"trim":          stringsFunc(strings.Trim),
+	"trimprefix":    stringsFunc(strings.TrimPrefix),
+	"trimspace":     stringFunc(strings.TrimSpace),
+	"trimsuffix":    stringsFunc(strings.TrimSuffix),
+	"upper":         stringFunc(strings.ToUpper),
+
+	// Collection functions
+	"coalesce": funcCoalesce,
+	"compact":  funcCompact,
+	"concat":   funcConcat,
+	"contains": funcContains,
+	"distinct": funcDistinct,
+	"element":  funcElement,
+	"flatten":  funcFlatten,
+	"keys":     funcKeys,
+	"length":   funcLength,
+	"lookup":   funcLookup,
+	"merge":    funcMerge,
+	"reverse":  funcReverse,
+	"slice":    funcSlice,
+	"sort":     funcSort,
+	"values":   funcValues,
+
+	// Numeric functions
+	"abs":      numberFunc(math.Abs),
+	"ceil":     numberFunc(math.Ceil),
+	"floor":    numberFunc(math.Floor),
+	"max":      funcMax,
+	"min":      funcMin,
+	"parseint": funcParseInt,
+
+	// Encoding functions
+	"base64decode": funcBase64Decode,
+	"base64encode": stringFunc(func(s string) string { return base64.StdEncoding.EncodeToString([]byte(s)) }),
+	"jsondecode":   funcJSONDecode,
+	"jsonencode":   funcJSONEncode,
+	"urlencode":    stringFunc(url.QueryEscape),
+
+	// File functions
+	"abspath":    funcAbsPath,
+	"basename":   stringFunc(filepath.Base),
+	"dirname":    stringFunc(filepath.Dir),
+	"file":       funcFile,
+	"fileexists": funcFileExists,
+
+	// Type conversion functions
+	"tobool":   funcToBool,
+	"tonumber": funcToNumber,
+	"tostring": funcToString,
+}
+
+// call calls the function with the given arguments
+func (ctx *evalContext) call(name string, args []interface{}) (interface{}, error) {
+	f, ok := exprFuncs[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown function %q", name)
+	}
+	v, err := f(ctx, args)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+	return v, nil
+}
+
+// checkArgs verifies the number of arguments passed to a function
+func checkArgs(args []interface{}, n int) error {
+	if len(args) != n {
+		return fmt.Errorf("expected %d arguments, got %d", n, len(args))
+	}
+	return nil
+}
+
+func stringArg(args []interface{}, i int) (string, error) {
+	s, err := toString(args[i])
+	if err != nil {
+		return "", fmt.Errorf("argument %d: %v", i+1, err)
+	}
+	return s, nil
+}
+
+func numberArg(args []interface{}, i int) (float64, error) {
+	n, ok := args[i].(float64)
+	if !ok {
+		return 0, fmt.Errorf("argument %d must be a number, got %s", i+1, typeName(args[i]))
+	}
+	return n, nil
+}
+
+func listArg(args []interface{}, i int) ([]interface{}, error) {
+	l, ok := args[i].([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("argument %d must be a list, got %s", i+1, typeName(args[i]))
+	}
+	return l, nil
+}
+
+func mapArg(args []interface{}, i int) (map[string]interface{}, error) {
+	m, ok := args[i].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("argument %d must be a map, got %s", i+1, typeName(args[i]))
+	}
+	return m, nil
+}
+
+// stringFunc wraps a function of one string
+func stringFunc(f func(string) string) exprFunc {
+	return func(ctx *evalContext, args []interface{}) (interface{}, error) {
+		if err := checkArgs(args, 1); err != nil {
+			return nil, err
+		}
+		s, err := stringArg(args, 0)
+		if err != nil {
+			return nil, err
+		}
+		return f(s), nil
+	}
+}
+
+// stringsFunc wraps a function of two strings
+func stringsFunc(f func(string, string) string) exprFunc {
+	return func(ctx *evalContext, args []interface{}) (interface{}, error) {
+		if err := checkArgs(args, 2); err != nil {
+			return nil, err
+		}
+		a, err := stringArg(args, 0)
+		if err != nil {
+			return nil, err
+		}
+		b, err := stringArg(args, 1)
+		if err != nil {
+			return nil, err
+		}
+		return f(a, b), nil
+	}
+}
+
+// numberFunc wraps a function of one number
+func numberFunc(f func(float64) float64) exprFunc {
+	return func(ctx *evalContext, args []interface{}) (interface{}, error) {
+		if err := checkArgs(args, 1); err != nil {
+			return nil, err
+		}
+		n, err := numberArg(args, 0)
+		if err != nil {
+			return nil, err
+		}
+		return f(n), nil
+	}
+}
+
+func funcFormat(ctx *evalContext, args []interface{}) (interface{}, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("expected at least 1 argument")
+	}
+	format, err := stringArg(args, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	// Whole numbers are formatted as integers so that verbs such as %d work
+	fargs := make([]interface{}, len(args)-1)
+	for i, a := range args[1:] {
+		if n, ok := a.(float64); ok && n == math.Trunc(n) {
+			a = int64(n)
+		}
+		fargs[i] = a
+	}
+	return fmt.Sprintf(format, fargs...), nil
+}
+
+func funcJoin(ctx *evalContext, args []interface{}) (interface{}, error) {
+	if err := checkArgs(args, 2); err != nil {
+		return nil, err
+	}
+	sep, err := stringArg(args, 0)
+	if err != nil {
+		return nil, err
+	}
+	list, err := listArg(args, 1)
+	if err != nil {
+		return nil, err
+	}
+	parts := make([]string, len(list))
+	for i, e := range list {
+		if parts[i], err = toString(e); err != nil {
+			return nil, fmt.Errorf("element %d: %v", i, err)
+		}
+	}
+	return strings.Join(parts, sep), nil
+}
+
+func funcRegexReplace(ctx *evalContext, args []interface{}) (interface{}, error) {
+	if err := checkArgs(args, 3); err != nil {
+		return nil, err
+	}
+	var strs [3]string
+	for i := range strs {
+		s, err := stringArg(args, i)
+		if err != nil {
+			return nil, err
+		}
+		strs[i] = s
+	}
+	re, err := regexp.Compile(strs[1])
+	if err != nil {
+		return nil, err
+	}
+	return re.ReplaceAllString(strs[0], strs[2]), nil
+}
+
+func funcReplace(ctx *evalContext, args []interface{}) (interface{}, error) {
+	if err := checkArgs(args, 3); err != nil {
+		return nil, err
+	}
+	var strs [3]string
+	for i := range strs {
+		s, err := stringArg(args, i)
+		if err != nil {
+			return nil, err
+		}
+		strs[i] = s
+	}
+	return strings.Replace(strs[0], strs[1], strs[2], -1), nil
+}
+
+func funcSplit(ctx *evalContext, args []interface{}) (interface{}, error) {
+	if err := checkArgs(args, 2); err != nil {
+		return nil, err
+	}
+	sep, err := stringArg(args, 0)
+	if err != nil {
+		return nil, err
+	}
+	s, err := stringArg(args, 1)
+	if err != nil {
+		return nil, err
+	}
+	parts := strings.Split(s, sep)
+	out := make([]interface{}, len(parts))
+	for i, p := range parts {
+		out[i] = p
+	}
+	return out, nil
+}
+
+func funcSubstr(ctx *evalContext, args []interface{}) (interface{}, error) {
+	if err := checkArgs(args, 3); err != nil {
+		return nil, err
+	}
+	s, err := stringArg(args, 0)
+	if err != nil {
+		return nil, err
+	}
+	offset, err := numberArg(args, 1)
+	if err != nil {
+		return nil, err
+	}
+	length, err := numberArg(args, 2)
+	if err != nil {
+		return nil, err
+	}
+
+	runes := []rune(s)
+	start := int(offset)
+	if start < 0 {
+		start += len(runes)
+	}
+	if start < 0 || start > len(runes) {
+		return nil, fmt.Errorf("offset %d out of range for string of length %d", int(offset), len(runes))
+	}
+	end := len(runes)
+	if length >= 0 && start+int(length) < end {
+		end = start + int(length)
+	}
+	return string(runes[start:end]), nil
+}
+
+func funcCoalesce(ctx *evalContext, args []interface{}) (interface{}, error) {
+	for _, a := range args {
+		if s, ok := a.(string); ok && s == "" {
+			continue
+		}
+		if a != nil {
+			return a, nil
+		}
+	}
+	return nil, fmt.Errorf("no non-empty argument")
+}
+
+func funcCompact(ctx *evalContext, args []interface{}) (interface{}, error) {
+	if err := checkArgs(args, 1); err != nil {
+		return nil, err
+	}
+	list, err := listArg(args, 0)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]interface{}, 0, len(list))
+	for _, e := range list {
+		if s, ok := e.(string); ok && s == "" {
+			continue
+		}
+		out = append(out, e)
+	}
+	return out, nil
+}
+
+func funcConcat(ctx *evalContext, args []interface{}) (interface{}, error) {
+	out := make([]interface{}, 0)
+	for i := range args {
+		list, err := listArg(args, i)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, list...)
+	}
+	return out, nil
+}
+
+func funcContains(ctx *evalContext, args []interface{}) (interface{}, error) {
+	if err := checkArgs(args, 2); err != nil {
+		return nil, err
+	}
+	list, err := listArg(args, 0)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range list {
+		if eq, _ := binaryOp("==", e, args[1]); eq == true {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func funcDistinct(ctx *evalContext, args []interface{}) (interface{}, error) {
+	if err := checkArgs(args, 1); err != nil {
+		return nil, err
+	}
+	list, err := listArg(args, 0)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]interface{}, 0, len(list))
+	for _, e := range list {
+		if found, _ := funcContains(ctx, []interface{}{out, e}); found == false {
+			out = append(out, e)
+		}
+	}
+	return out, nil
+}
+
+func funcElement(ctx *evalContext, args []interface{}) (interface{}, error) {
+	if err := checkArgs(args, 2); err != nil {
+		return nil, err
+	}
+	list, err := listArg(args, 0)
+	if err != nil {
+		return nil, err
+	}
+	n, err := numberArg(args, 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return nil, fmt.Errorf("can not use element on an empty list")
+	}
+
+	// The index wraps around the list
+	return list[int(n)%len(list)], nil
+}
+
+func funcFlatten(ctx *evalContext, args []interface{}) (interface{}, error) {
+	if err := checkArgs(args, 1); err != nil {
+		return nil, err
+	}
+	list, err := listArg(args, 0)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]interface{}, 0, len(list))
+	for _, e := range list {
+		if nested, ok := e.([]interface{}); ok {
+			flat, err := funcFlatten(ctx, []interface{}{nested})
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, flat.([]interface{})...)
+		} else {
+			out = append(out, e)
+		}
+	}
+	return out, nil
+}
+
+// sortedKeys returns the keys of the map in lexical order
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func funcKeys(ctx *evalContext, args []interface{}) (interface{}, error) {
+	if err := checkArgs(args, 1); err != nil {
+		return nil, err
+	}
+	m, err := mapArg(args, 0)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]interface{}, 0, len(m))
+	for _, k := range sortedKeys(m) {
+		out = append(out, k)
+	}
+	return out, nil
+}
+
+func funcValues(ctx *evalContext, args []interface{}) (interface{}, error) {
+	if err := checkArgs(args, 1); err != nil {
+		return nil, err
+	}
+	m, err := mapArg(args, 0)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]interface{}, 0, len(m))
+	for _, k := range sortedKeys(m) {
+		out = append(out, m[k])
+	}
+	return out, nil
+}
+
+func funcLength(ctx *evalContext, args []interface{}) (interface{}, error) {
+	if err := checkArgs(args, 1); err != nil {
+		return nil, err
+	}
+	switch v := args[0].(type) {
+	case string:
+		return float64(len([]rune(v))), nil
+	case []interface{}:
+		return float64(len(v)), nil
+	case map[string]interface{}:
+		return float64(len(v)), nil
+	}
+	return nil, fmt.Errorf("can not take the length of a %s", typeName(args[0]))
+}
+
+func funcLookup(ctx *evalContext, args []interface{}) (interface{}, error) {
+	if len(args) != 2 && len(args) != 3 {
+		return nil, fmt.Errorf("expected 2 or 3 arguments, got %d", len(args))
+	}
+	m, err := mapArg(args, 0)
+	if err != nil {
+		return nil, err
+	}
+	key, err := stringArg(args, 1)
+	if err != nil {
+		return nil, err
+	}
+	if v, ok := m[key]; ok {
+		return v, nil
+	}
+	if len(args) == 3 {
+		return args[2], nil
+	}
+	return nil, fmt.Errorf("map has no key %q", key)
+}
+
+func funcMerge(ctx *evalContext, args []interface{}) (interface{}, error) {
+	out := make(map[string]interface{})
+	for i := range args {
+		m, err := mapArg(args, i)
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range m {
+			out[k] = v
+		}
+	}
+	return out, nil
+}
+
+func funcReverse(ctx *evalContext, args []interface{}) (interface{}, error) {
+	if err := checkArgs(args, 1); err != nil {
+		return nil, err
+	}
+	list, err := listArg(args, 0)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]interface{}, len(list))
+	for i, e := range list {
+		out[len(list)-1-i] = e
+	}
+	return out, nil
+}
+
+func funcSlice(ctx *evalContext, args []interface{}) (interface{}, error) {
+	if err := checkArgs(args, 3); err != nil {
+		return nil, err
+	}
+	list, err := listArg(args, 0)
+	if err != nil {
+		return nil, err
+	}
+	start, err := numberArg(args, 1)
+	if err != nil {
+		return nil, err
+	}
+	end, err := numberArg(args, 2)
+	if err != nil {
+		return nil, err
+	}
+	if start < 0 || end > float64(len(list)) || start > end {
+		return nil, fmt.Errorf("invalid range [%d, %d) for list of length %d", int(start), int(end), len(list))
+	}
+	return append([]interface{}{}, list[int(start):int(end)]...), nil
+}
+
+func funcSort(ctx *evalContext, args []interface{}) (interface{}, error) {
+	if err := checkArgs(args, 1); err != nil {
+		return nil, err
+	}
+	list, err := listArg(args, 0)
+	if err != nil {
+		return nil, err
+	}
+	strs := make([]string, len(list))
+	for i, e := range list {
+		if strs[i], err = toString(e); err != nil {
+			return nil, fmt.Errorf("element %d: %v", i, err)
+		}
+	}
+	sort.Strings(strs)
+	out := make([]interface{}, len(strs))
+	for i, s := range strs {
+		out[i] = s
+	}
+	return out, nil
+}
+
+func funcMax(ctx *evalContext, args []interface{}) (interface{}, error) {
+	return minMax(args, math.Max)
+}
+
+func funcMin(ctx *evalContext, args []interface{}) (interface{}, error) {
+	return minMax(args, math.Min)
+}
+
+func minMax(args []interface{}, f func(float64, float64) float64) (interface{}, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("expected at least 1 argument")
+	}
+	out, err := numberArg(args, 0)
+	if err != nil {
+		return nil, err
+	}
+	for i := 1; i < len(args); i++ {
+		n, err := numberArg(args, i)
+		if err != nil {
+			return nil, err
+		}
+		out = f(out, n)
+	}
+	return out, nil
+}
+
+func funcParseInt(ctx *evalContext, args []interface{}) (interface{}, error) {
+	if err := checkArgs(args, 2); err != nil {
+		return nil, err
+	}
+	s, err := stringArg(args, 0)
+	if err != nil {
+		return nil, err
+	}
+	base, err := numberArg(args, 1)
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.ParseInt(s, int(base), 64)
+	if err != nil {
+		return nil, err
+	}
+	return float64(n), nil
+}
+
+func funcBase64Decode(ctx *evalContext, args []interface{}) (interface{}, error) {
+	if err := checkArgs(args, 1); err != nil {
+		return nil, err
+	}
+	s, err := stringArg(args, 0)
+	if err != nil {
+		return nil, err
+	}
+	b, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+func funcJSONDecode(ctx *evalContext, args []interface{}) (interface{}, error) {
+	if err := checkArgs(args, 1); err != nil {
+		return nil, err
+	}
+	s, err := stringArg(args, 0)
+	if err != nil {
+		return nil, err
+	}
+	var v interface{}
+	if err := json.Unmarshal([]byte(s), &v); err != nil {
+		return nil, err
+	}
+	return normalizeValue(v)
+}
+
+func funcJSONEncode(ctx *evalContext, args []interface{}) (interface{}, error) {
+	if err := checkArgs(args, 1); err != nil {
+		return nil, err
+	}
+	b, err := json.Marshal(args[0])
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// path resolves a path relative to the directory of the job file
+func (ctx *evalContext) path(p string) string {
+	if filepath.IsAbs(p) {
+		return p
+	}
+	return filepath.Join(ctx.baseDir, p)
+}
+
+func funcAbsPath(ctx *evalContext, args []interface{}) (interface{}, error) {
+	if err := checkArgs(args, 1); err != nil {
+		return nil, err
+	}
+	p, err := stringArg(args, 0)
+	if err != nil {
+		return nil, err
+	}
+	return filepath.Abs(ctx.path(p))
+}
+
+func funcFile(ctx *evalContext, args []interface{}) (interface{}, error) {
+	if err := checkArgs(args, 1); err != nil {
+		return nil, err
+	}
+	p, err := stringArg(args, 0)
+	if err != nil {
+		return nil, err
+	}
+	b, err := ioutil.ReadFile(ctx.path(p))
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+func funcFileExists(ctx *evalContext, args []interface{}) (interface{}, error) {
+	if err := checkArgs(args, 1); err != nil {
+		return nil, err
+	}
+	p, err := stringArg(args, 0)
+	if err != nil {
+		return nil, err
+	}
+	fi, err := os.Stat(ctx.path(p))
+	if os.IsNotExist(err) {
+		return false, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return !fi.IsDir(), nil
+}
+
+func funcToBool(ctx *evalContext, args []interface{}) (interface{}, error) {
+	if err := checkArgs(args, 1); err != nil {
+		return nil, err
+	}
+	return convertValue(args[0], varTypeBool)
+}
+
+func funcToNumber(ctx *evalContext, args []interface{}) (interface{}, error) {
+	if err := checkArgs(args, 1); err != nil {
+		return nil, err
+	}
+	return convertValue(args[0], varTypeNumber)
+}
+
+func funcToString(ctx *evalContext, args []interface{}) (interface{}, error) {
+	if err := checkArgs(args, 1); err != nil {
+		return nil, err
+	}
+	return convertValue(args[0], varTypeString)
+}
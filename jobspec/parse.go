@@ -23,14 +23,37 @@ import (
 var reDynamicPorts = regexp.MustCompile("^[a-zA-Z0-9_]+$")
 var errPortLabel = fmt.Errorf("Port label does not conform to naming requirements %s", reDynamicPorts.String())
 
+// ParseConfig configures the parsing of a job spec with input variables.
+type ParseConfig struct {
+	// Path is the path of the job file. It is used in error messages and
+	// file functions resolve relative paths against its directory. If
+	// empty, they are resolved against the working directory.
+	Path string
+
+	// Body is the job spec
+	Body io.Reader
+
+	// ArgVars are the values of variables given as "name=value"
+	ArgVars []string
+
+	// VarFiles are the paths of files setting the values of variables
+	VarFiles []string
+}
+
 // Parse parses the job spec from the given io.Reader.
 //
 // Due to current internal limitations, the entire contents of the
 // io.Reader will be copied into memory first before parsing.
 func Parse(r io.Reader) (*api.Job, error) {
+	return ParseWithConfig(&ParseConfig{Body: r})
+}
+
+// ParseWithConfig parses the job spec, evaluating the expressions that
+// reference its variables or call functions.
+func ParseWithConfig(c *ParseConfig) (*api.Job, error) {
 	// Copy the reader into an in-memory buffer first since HCL requires it.
 	var buf bytes.Buffer
-	if _, err := io.Copy(&buf, r); err != nil {
+	if _, err := io.Copy(&buf, c.Body); err != nil {
 		return nil, err
 	}
 
@@ -50,15 +73,33 @@ func Parse(r io.Reader) (*api.Job, error) {
 	// Check for invalid keys
 	valid := []string{
 		"job",
+		"variable",
 	}
 	if err := checkHCLKeys(list, valid); err != nil {
 		return nil, err
 	}
 
+	// Evaluate the variables and interpolate them into the job
+	vars, err := parseVariables(c.Path, list.Filter("variable"))
+	if err != nil {
+		return nil, fmt.Errorf("error parsing 'variable': %s", err)
+	}
+	baseDir := "."
+	if c.Path != "" && c.Path != "-" {
+		baseDir = filepath.Dir(c.Path)
+	}
+	ctx := &evalContext{baseDir: baseDir}
+	if ctx.vars, err = variableValues(c, vars, ctx); err != nil {
+		return nil, fmt.Errorf("error evaluating variables: %s", err)
+	}
+	matches := list.Filter("job")
+	if err := interpolate(c.Path, matches, ctx); err != nil {
+		return nil, fmt.Errorf("error interpolating job: %s", err)
+	}
+
 	var job api.Job
 
 	// Parse the job out
-	matches := list.Filter("job")
 	if len(matches.Items) == 0 {
 		return nil, fmt.Errorf("'job' stanza not found")
 	}
@@ -82,7 +123,7 @@ func ParseFile(path string) (*api.Job, error) {
 	}
 	defer f.Close()
 
-	return Parse(f)
+	return ParseWithConfig(&ParseConfig{Path: path, Body: f})
 }
 
 func parseJob(result *api.Job, list *ast.ObjectList) error {
@@ -273,6 +314,8 @@ func parseGroups(result *api.Job, list *ast.ObjectList) error {
 			"task",
 			"ephemeral_disk",
 			"vault",
+			"network",
+			"scaling",
 		}
 		if err := checkHCLKeys(listVal, valid); err != nil {
 			return multierror.Prefix(err, fmt.Sprintf("'%s' ->", n))
@@ -288,6 +331,8 @@ func parseGroups(result *api.Job, list *ast.ObjectList) error {
 		delete(m, "restart")
 		delete(m, "ephemeral_disk")
 		delete(m, "vault")
+		delete(m, "network")
+		delete(m, "scaling")
 
 		// Build the group with the basic decode
 		var g api.TaskGroup
@@ -318,6 +363,20 @@ func parseGroups(result *api.Job, list *ast.ObjectList) error {
 			}
 		}
 
+		// Parse the shared network
+		if o := listVal.Filter("network"); len(o.Items) > 0 {
+			if err := parseGroupNetwork(&g.Networks, o); err != nil {
+				return multierror.Prefix(err, fmt.Sprintf("'%s', network ->", n))
+			}
+		}
+
+		// Parse the scaling policy
+		if o := listVal.Filter("scaling"); len(o.Items) > 0 {
+			if err := parseScalingPolicy(&g.Scaling, o); err != nil {
+				return multierror.Prefix(err, fmt.Sprintf("'%s', scaling ->", n))
+			}
+		}
+
 		// Parse out meta fields. These are in HCL as a list so we need
 		// to iterate over them and merge them.
 		if metaO := listVal.Filter("meta"); len(metaO.Items) > 0 {
@@ -521,6 +580,57 @@ func parseEphemeralDisk(result **api.EphemeralDisk, list *ast.ObjectList) error
 	return nil
 }
 
+func parseScalingPolicy(result **api.ScalingPolicy, list *ast.ObjectList) error {
+	list = list.Elem()
+	if len(list.Items) > 1 {
+		return fmt.Errorf("only one 'scaling' block allowed")
+	}
+
+	// Get our scaling object
+	obj := list.Items[0]
+
+	// Check for invalid keys
+	valid := []string{
+		"min",
+		"max",
+		"enabled",
+		"policy",
+	}
+	if err := checkHCLKeys(obj.Val, valid); err != nil {
+		return err
+	}
+
+	var m map[string]interface{}
+	if err := hcl.DecodeObject(&m, obj.Val); err != nil {
+		return err
+	}
+	delete(m, "policy")
+
+	var policy api.ScalingPolicy
+	if err := mapstructure.WeakDecode(m, &policy); err != nil {
+		return err
+	}
+
+	// The policy is opaque to Nomad, so it is decoded as is
+	var listVal *ast.ObjectList
+	if ot, ok := obj.Val.(*ast.ObjectType); ok {
+		listVal = ot.List
+	} else {
+		return fmt.Errorf("scaling: should be an object")
+	}
+	if o := listVal.Filter("policy"); len(o.Items) > 0 {
+		if len(o.Items) > 1 {
+			return fmt.Errorf("only one 'policy' block allowed")
+		}
+		if err := hcl.DecodeObject(&policy.Policy, o.Items[0].Val); err != nil {
+			return err
+		}
+	}
+
+	*result = &policy
+	return nil
+}
+
 // parseBool takes an interface value and tries to convert it to a boolean and
 // returns an error if the type can't be converted.
 func parseBool(value interface{}) (bool, error) {
@@ -571,6 +681,7 @@ func parseTasks(jobName string, taskGroupName string, result *[]*api.Task, list
 			"dispatch_payload",
 			"driver",
 			"env",
+			"identity",
 			"kill_timeout",
 			"leader",
 			"logs",
@@ -594,6 +705,7 @@ func parseTasks(jobName string, taskGroupName string, result *[]*api.Task, list
 		delete(m, "constraint")
 		delete(m, "dispatch_payload")
 		delete(m, "env")
+		delete(m, "identity")
 		delete(m, "logs")
 		delete(m, "meta")
 		delete(m, "resources")
@@ -696,6 +808,9 @@ func parseTasks(jobName string, taskGroupName string, result *[]*api.Task, list
 			valid := []string{
 				"max_files",
 				"max_file_size",
+				"compress",
+				"max_age",
+				"sink",
 			}
 			if err := checkHCLKeys(logsBlock.Val, valid); err != nil {
 				return multierror.Prefix(err, fmt.Sprintf("'%s', logs ->", n))
@@ -704,12 +819,30 @@ func parseTasks(jobName string, taskGroupName string, result *[]*api.Task, list
 			if err := hcl.DecodeObject(&m, logsBlock.Val); err != nil {
 				return err
 			}
+			delete(m, "sink")
 
 			var log api.LogConfig
-			if err := mapstructure.WeakDecode(m, &log); err != nil {
+			dec, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+				DecodeHook:       mapstructure.StringToTimeDurationHookFunc(),
+				WeaklyTypedInput: true,
+				Result:           &log,
+			})
+			if err != nil {
+				return err
+			}
+			if err := dec.Decode(m); err != nil {
 				return err
 			}
 
+			// Parse the sinks
+			if ot, ok := logsBlock.Val.(*ast.ObjectType); ok {
+				if so := ot.List.Filter("sink"); len(so.Items) > 0 {
+					if err := parseLogSinks(&log.Sinks, so); err != nil {
+						return multierror.Prefix(err, fmt.Sprintf("'%s', logs ->", n))
+					}
+				}
+			}
+
 			t.LogConfig = &log
 		}
 
@@ -741,6 +874,13 @@ func parseTasks(jobName string, taskGroupName string, result *[]*api.Task, list
 			t.Vault = v
 		}
 
+		// If we have an identity block, then parse that
+		if o := listVal.Filter("identity"); len(o.Items) > 0 {
+			if err := parseIdentity(&t.Identity, o); err != nil {
+				return multierror.Prefix(err, fmt.Sprintf("'%s', identity ->", n))
+			}
+		}
+
 		// If we have a dispatch_payload block parse that
 		if o := listVal.Filter("dispatch_payload"); len(o.Items) > 0 {
 			if len(o.Items) > 1 {
@@ -780,6 +920,7 @@ func parseArtifacts(result *[]*api.TaskArtifact, list *ast.ObjectList) error {
 			"source",
 			"options",
 			"destination",
+			"signature",
 		}
 		if err := checkHCLKeys(o.Val, valid); err != nil {
 			return err
@@ -839,6 +980,62 @@ func parseArtifactOption(result map[string]string, list *ast.ObjectList) error {
 	return nil
 }
 
+func parseLogSinks(result *[]*api.LogSink, list *ast.ObjectList) error {
+	for idx, o := range list.Elem().Items {
+		// Check for invalid keys
+		valid := []string{
+			"type",
+			"address",
+			"batch_size",
+			"batch_wait",
+			"buffer_size",
+			"headers",
+		}
+		if err := checkHCLKeys(o.Val, valid); err != nil {
+			return multierror.Prefix(err, fmt.Sprintf("sink (%d) ->", idx))
+		}
+
+		var m map[string]interface{}
+		if err := hcl.DecodeObject(&m, o.Val); err != nil {
+			return err
+		}
+		delete(m, "headers")
+
+		var sink api.LogSink
+		dec, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+			DecodeHook:       mapstructure.StringToTimeDurationHookFunc(),
+			WeaklyTypedInput: true,
+			Result:           &sink,
+		})
+		if err != nil {
+			return err
+		}
+		if err := dec.Decode(m); err != nil {
+			return err
+		}
+
+		// Parse the headers
+		if ot, ok := o.Val.(*ast.ObjectType); ok {
+			if ho := ot.List.Filter("headers"); len(ho.Items) > 0 {
+				if len(ho.Items) > 1 {
+					return fmt.Errorf("sink (%d) -> only one 'headers' block allowed", idx)
+				}
+				var hm map[string]interface{}
+				if err := hcl.DecodeObject(&hm, ho.Items[0].Val); err != nil {
+					return err
+				}
+				if err := mapstructure.WeakDecode(hm, &sink.Headers); err != nil {
+					return err
+				}
+			}
+		}
+
+		*result = append(*result, &sink)
+	}
+
+	return nil
+}
+
 func parseTemplates(result *[]*api.Template, list *ast.ObjectList) error {
 	for _, o := range list.Elem().Items {
 		// Check for invalid keys
@@ -847,6 +1044,7 @@ func parseTemplates(result *[]*api.Template, list *ast.ObjectList) error {
 			"change_signal",
 			"data",
 			"destination",
+			"env",
 			"left_delimiter",
 			"perms",
 			"right_delimiter",
@@ -895,6 +1093,8 @@ func parseServices(jobName string, taskGroupName string, task *api.Task, service
 			"tags",
 			"port",
 			"check",
+			"connect",
+			"provider",
 		}
 		if err := checkHCLKeys(o.Val, valid); err != nil {
 			return multierror.Prefix(err, fmt.Sprintf("service (%d) ->", idx))
@@ -907,6 +1107,7 @@ func parseServices(jobName string, taskGroupName string, task *api.Task, service
 		}
 
 		delete(m, "check")
+		delete(m, "connect")
 
 		if err := mapstructure.WeakDecode(m, &service); err != nil {
 			return err
@@ -926,12 +1127,129 @@ func parseServices(jobName string, taskGroupName string, task *api.Task, service
 			}
 		}
 
+		// Parse the Connect configuration
+		if co := checkList.Filter("connect"); len(co.Items) > 0 {
+			if err := parseConnect(&service.Connect, co); err != nil {
+				return multierror.Prefix(err, fmt.Sprintf("service: '%s', connect ->", service.Name))
+			}
+		}
+
 		task.Services[idx] = &service
 	}
 
 	return nil
 }
 
+func parseConnect(result **api.ConsulConnect, list *ast.ObjectList) error {
+	list = list.Elem()
+	if len(list.Items) > 1 {
+		return fmt.Errorf("only one 'connect' block allowed per service")
+	}
+
+	// Get our connect object
+	obj := list.Items[0]
+
+	// Check for invalid keys
+	valid := []string{
+		"sidecar_service",
+	}
+	if err := checkHCLKeys(obj.Val, valid); err != nil {
+		return err
+	}
+
+	var connect api.ConsulConnect
+	var listVal *ast.ObjectList
+	if ot, ok := obj.Val.(*ast.ObjectType); ok {
+		listVal = ot.List
+	} else {
+		return fmt.Errorf("connect: should be an object")
+	}
+
+	if so := listVal.Filter("sidecar_service"); len(so.Items) > 0 {
+		if err := parseSidecarService(&connect.SidecarService, so); err != nil {
+			return multierror.Prefix(err, "sidecar_service ->")
+		}
+	}
+
+	*result = &connect
+	return nil
+}
+
+func parseSidecarService(result **api.ConsulSidecarService, list *ast.ObjectList) error {
+	list = list.Elem()
+	if len(list.Items) > 1 {
+		return fmt.Errorf("only one 'sidecar_service' block allowed")
+	}
+
+	// Get our sidecar service object
+	obj := list.Items[0]
+
+	// Check for invalid keys
+	valid := []string{
+		"port",
+		"proxy",
+	}
+	if err := checkHCLKeys(obj.Val, valid); err != nil {
+		return err
+	}
+
+	var m map[string]interface{}
+	if err := hcl.DecodeObject(&m, obj.Val); err != nil {
+		return err
+	}
+	delete(m, "proxy")
+
+	var sidecar api.ConsulSidecarService
+	if err := mapstructure.WeakDecode(m, &sidecar); err != nil {
+		return err
+	}
+
+	var listVal *ast.ObjectList
+	if ot, ok := obj.Val.(*ast.ObjectType); ok {
+		listVal = ot.List
+	} else {
+		return fmt.Errorf("sidecar_service: should be an object")
+	}
+
+	if po := listVal.Filter("proxy"); len(po.Items) > 0 {
+		if len(po.Items) > 1 {
+			return fmt.Errorf("only one 'proxy' block allowed")
+		}
+
+		var proxyList *ast.ObjectList
+		if ot, ok := po.Items[0].Val.(*ast.ObjectType); ok {
+			proxyList = ot.List
+		} else {
+			return fmt.Errorf("proxy: should be an object")
+		}
+		if err := checkHCLKeys(po.Items[0].Val, []string{"upstreams"}); err != nil {
+			return multierror.Prefix(err, "proxy ->")
+		}
+
+		proxy := &api.ConsulProxy{}
+		for _, uo := range proxyList.Filter("upstreams").Items {
+			if err := checkHCLKeys(uo.Val, []string{"destination_name", "local_bind_port"}); err != nil {
+				return multierror.Prefix(err, "proxy -> upstreams ->")
+			}
+
+			var um map[string]interface{}
+			if err := hcl.DecodeObject(&um, uo.Val); err != nil {
+				return err
+			}
+
+			var upstream api.ConsulUpstream
+			if err := mapstructure.WeakDecode(um, &upstream); err != nil {
+				return err
+			}
+			proxy.Upstreams = append(proxy.Upstreams, &upstream)
+		}
+		sidecar.Proxy = proxy
+	}
+
+	*result = &sidecar
+	return nil
+}
+
 func parseChecks(service *api.Service, checkObjs *ast.ObjectList) error {
 	service.Checks = make([]api.ServiceCheck, len(checkObjs.Items))
 	for idx, co := range checkObjs.Items {
@@ -947,6 +1265,13 @@ func parseChecks(service *api.Service, checkObjs *ast.ObjectList) error {
 			"command",
 			"args",
 			"initial_status",
+			"method",
+			"header",
+			"body",
+			"tls_skip_verify",
+			"grpc_service",
+			"grpc_use_tls",
+			"check_restart",
 		}
 		if err := checkHCLKeys(co.Val, valid); err != nil {
 			return multierror.Prefix(err, "check ->")
@@ -957,6 +1282,9 @@ func parseChecks(service *api.Service, checkObjs *ast.ObjectList) error {
 		if err := hcl.DecodeObject(&cm, co.Val); err != nil {
 			return err
 		}
+		delete(cm, "header")
+		delete(cm, "check_restart")
+
 		dec, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
 			DecodeHook:       mapstructure.StringToTimeDurationHookFunc(),
 			WeaklyTypedInput: true,
@@ -969,12 +1297,75 @@ func parseChecks(service *api.Service, checkObjs *ast.ObjectList) error {
 			return err
 		}
 
+		var checkList *ast.ObjectList
+		if ot, ok := co.Val.(*ast.ObjectType); ok {
+			checkList = ot.List
+		} else {
+			return fmt.Errorf("check: should be an object")
+		}
+
+		// Parse the headers
+		if o := checkList.Filter("header"); len(o.Items) > 0 {
+			if len(o.Items) > 1 {
+				return fmt.Errorf("only one 'header' block allowed per check")
+			}
+			var hm map[string]interface{}
+			if err := hcl.DecodeObject(&hm, o.Items[0].Val); err != nil {
+				return multierror.Prefix(err, "check -> header ->")
+			}
+			if err := mapstructure.WeakDecode(hm, &check.Header); err != nil {
+				return multierror.Prefix(err, "check -> header ->")
+			}
+		}
+
+		// Parse the restart behavior
+		if o := checkList.Filter("check_restart"); len(o.Items) > 0 {
+			if len(o.Items) > 1 {
+				return fmt.Errorf("only one 'check_restart' block allowed per check")
+			}
+			cr, err := parseCheckRestart(o.Items[0])
+			if err != nil {
+				return multierror.Prefix(err, "check -> check_restart ->")
+			}
+			check.CheckRestart = cr
+		}
+
 		service.Checks[idx] = check
 	}
 
 	return nil
 }
 
+func parseCheckRestart(o *ast.ObjectItem) (*api.CheckRestart, error) {
+	valid := []string{
+		"limit",
+		"grace",
+		"ignore_warnings",
+	}
+	if err := checkHCLKeys(o.Val, valid); err != nil {
+		return nil, err
+	}
+
+	var m map[string]interface{}
+	if err := hcl.DecodeObject(&m, o.Val); err != nil {
+		return nil, err
+	}
+
+	var restart api.CheckRestart
+	dec, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		DecodeHook:       mapstructure.StringToTimeDurationHookFunc(),
+		WeaklyTypedInput: true,
+		Result:           &restart,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := dec.Decode(m); err != nil {
+		return nil, err
+	}
+	return &restart, nil
+}
+
 func parseResources(result *api.Resources, list *ast.ObjectList) error {
 	list = list.Elem()
 	if len(list.Items) == 0 {
@@ -1057,9 +1448,50 @@ func parseResources(result *api.Resources, list *ast.ObjectList) error {
 	return nil
 }
 
+func parseGroupNetwork(result *[]*api.NetworkResource, list *ast.ObjectList) error {
+	list = list.Elem()
+	if len(list.Items) > 1 {
+		return fmt.Errorf("only one 'network' block allowed per group")
+	}
+
+	// Check for invalid keys
+	valid := []string{
+		"mode",
+		"mbits",
+		"port",
+	}
+	if err := checkHCLKeys(list.Items[0].Val, valid); err != nil {
+		return err
+	}
+
+	var r api.NetworkResource
+	var m map[string]interface{}
+	if err := hcl.DecodeObject(&m, list.Items[0].Val); err != nil {
+		return err
+	}
+	delete(m, "port")
+	if err := mapstructure.WeakDecode(m, &r); err != nil {
+		return err
+	}
+
+	var networkObj *ast.ObjectList
+	if ot, ok := list.Items[0].Val.(*ast.ObjectType); ok {
+		networkObj = ot.List
+	} else {
+		return fmt.Errorf("network: should be an object")
+	}
+	if err := parsePorts(networkObj, &r); err != nil {
+		return multierror.Prefix(err, "ports ->")
+	}
+
+	*result = []*api.NetworkResource{&r}
+	return nil
+}
+
 func parsePorts(networkObj *ast.ObjectList, nw *api.NetworkResource) error {
 	// Check for invalid keys
 	valid := []string{
+		"mode",
 		"mbits",
 		"port",
 	}
@@ -1208,6 +1640,8 @@ func parseVault(result *api.Vault, list *ast.ObjectList) error {
 		"env",
 		"change_mode",
 		"change_signal",
+		"role",
+		"entity_alias",
 	}
 	if err := checkHCLKeys(listVal, valid); err != nil {
 		return multierror.Prefix(err, "vault ->")
@@ -1225,6 +1659,37 @@ func parseVault(result *api.Vault, list *ast.ObjectList) error {
 	return nil
 }
 
+func parseIdentity(result **api.WorkloadIdentity, list *ast.ObjectList) error {
+	list = list.Elem()
+	if len(list.Items) > 1 {
+		return fmt.Errorf("only one 'identity' block allowed per task")
+	}
+
+	// Get our resource object
+	o := list.Items[0]
+
+	// Check for invalid keys
+	valid := []string{
+		"env",
+		"file",
+	}
+	if err := checkHCLKeys(o.Val, valid); err != nil {
+		return err
+	}
+
+	var m map[string]interface{}
+	if err := hcl.DecodeObject(&m, o.Val); err != nil {
+		return err
+	}
+
+	var w api.WorkloadIdentity
+	if err := mapstructure.WeakDecode(m, &w); err != nil {
+		return err
+	}
+	*result = &w
+	return nil
+}
+
 func parseParameterizedJob(result **api.ParameterizedJobConfig, list *ast.ObjectList) error {
 	list = list.Elem()
 	if len(list.Items) > 1 {
@@ -63,6 +63,24 @@ job "binstore-storagelocker" {
       logs {
         max_files     = 14
         max_file_size = 101
+        compress      = true
+        max_age       = "72h"
+
+        sink {
+          type    = "syslog"
+          address = "udp://127.0.0.1:514"
+        }
+
+        sink {
+          type       = "http"
+          address    = "https://logs.example.com/ingest"
+          batch_size = 50
+          batch_wait = "5s"
+
+          headers {
+            Authorization = "Bearer foo"
+          }
+        }
       }
 
       env {
@@ -126,6 +144,7 @@ job "binstore-storagelocker" {
       artifact {
         source = "http://bar.com/artifact"
         destination = "test/foo/"
+        signature = "http://bar.com/artifact.sig"
 
         options {
           checksum = "md5:ff1cc0d3432dad54d607c1505fb7245c"
@@ -150,6 +169,7 @@ job "binstore-storagelocker" {
         perms = "777"
         left_delimiter = "--"
         right_delimiter = "__"
+        env = true
       }
     }
 
@@ -176,6 +196,12 @@ job "binstore-storagelocker" {
         env = false
         change_mode = "signal"
         change_signal = "SIGUSR1"
+        role = "web"
+        entity_alias = "web-alias"
+      }
+
+      identity {
+        env = true
       }
     }
 
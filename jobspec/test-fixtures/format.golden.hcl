@@ -0,0 +1,48 @@
+# The example job
+job "example" {
+  datacenters = [
+    "dc1",
+    "dc2", # secondary
+  ]
+  type = "service"
+
+  meta {
+    zeta  = "1"
+    alpha = "2"
+  }
+
+  group "cache" {
+    count = 1
+
+    # Restart failed tasks quickly
+    restart {
+      attempts = 10
+      mode     = "delay"
+    }
+
+    task "redis" {
+      driver = "docker"
+
+      config {
+        port_map {
+          db = 6379
+        }
+
+        image = "redis:3.2"
+      }
+
+      resources {
+        cpu    = 500
+        memory = 256 # MB
+
+        network {
+          mbits = 10
+
+          port "db" {}
+        }
+      }
+    }
+  }
+
+  # TODO: add an update stanza
+}
@@ -0,0 +1,42 @@
+# The example job
+job "example" {
+    group "cache" {
+        task "redis" {
+            resources {
+                network {
+                    port "db" {}
+                    mbits = 10
+                }
+                memory = 256 # MB
+                cpu = 500
+            }
+
+            config {
+                port_map {
+                    db = 6379
+                }
+                image = "redis:3.2"
+            }
+            driver = "docker"
+        }
+        count = 1
+
+        # Restart failed tasks quickly
+        restart {
+            mode = "delay"
+            attempts = 10
+        }
+    }
+
+    datacenters = ["dc1",
+        "dc2", # secondary
+    ]
+    type = "service"
+
+    meta {
+        zeta = "1"
+        alpha = "2"
+    }
+
+    # TODO: add an update stanza
+}
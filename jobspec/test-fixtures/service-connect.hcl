@@ -0,0 +1,26 @@
+job "connect" {
+  group "group" {
+    task "api" {
+      service {
+        name = "api"
+        port = "http"
+
+        connect {
+          sidecar_service {
+            proxy {
+              upstreams {
+                destination_name = "db"
+                local_bind_port  = 9191
+              }
+
+              upstreams {
+                destination_name = "cache"
+                local_bind_port  = 9192
+              }
+            }
+          }
+        }
+      }
+    }
+  }
+}
@@ -0,0 +1,18 @@
+job "service_provider" {
+    group "group" {
+        task "task" {
+          service {
+            name     = "web"
+            port     = "http"
+            provider = "nomad"
+
+            check {
+              name     = "alive"
+              type     = "tcp"
+              interval = "10s"
+              timeout  = "2s"
+            }
+          }
+        }
+    }
+}
@@ -0,0 +1,26 @@
+job "foo" {
+  group "bar" {
+    network {
+      mode  = "bridge"
+      mbits = 20
+
+      port "http" {
+        static = 80
+        to     = 8080
+      }
+
+      port "admin" {
+        to = 9000
+      }
+    }
+
+    task "web" {
+      driver = "exec"
+
+      service {
+        name = "web"
+        port = "http"
+      }
+    }
+  }
+}
@@ -0,0 +1,21 @@
+job "foo" {
+  group "bar" {
+    count = 3
+
+    scaling {
+      min     = 1
+      max     = 10
+      enabled = false
+
+      policy {
+        metric   = "cpu"
+        target   = 70
+        cooldown = "2m"
+      }
+    }
+
+    task "web" {
+      driver = "exec"
+    }
+  }
+}
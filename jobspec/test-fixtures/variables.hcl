@@ -0,0 +1,56 @@
+variable "datacenters" {
+  type        = "list"
+  default     = ["dc1"]
+  description = "The datacenters to run the job in"
+}
+
+variable "env" {
+  type = "string"
+
+  validation {
+    condition     = "contains([\"dev\", \"prod\"], var.env)"
+    error_message = "The env must be dev or prod."
+  }
+}
+
+variable "count" {
+  type    = "number"
+  default = 1
+}
+
+variable "image_tags" {
+  type = "map"
+
+  default {
+    dev  = "latest"
+    prod = "1.2.3"
+  }
+}
+
+job "${format("web-%s", var.env)}" {
+  datacenters = "${var.datacenters}"
+
+  meta {
+    env         = "${upper(var.env)}"
+    description = "web ${var.env} in ${join(",", var.datacenters)}"
+    motd        = "${trimspace(file("variables-motd.txt"))}"
+    node        = "${node.unique.name}"
+    escaped     = "$${var.env}"
+  }
+
+  group "web" {
+    count = "${var.count}"
+
+    task "web" {
+      driver = "docker"
+
+      config {
+        image = "redis:${lookup(var.image_tags, var.env, "latest")}"
+      }
+
+      env {
+        DEBUG = "${var.env == "dev" ? "1" : "0"}"
+      }
+    }
+  }
+}
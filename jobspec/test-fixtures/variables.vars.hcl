@@ -0,0 +1,2 @@
+env   = "prod"
+count = 3
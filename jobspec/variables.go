@@ -0,0 +1,553 @@
+package jobspec
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/hcl"
+	"github.com/hashicorp/hcl/hcl/ast"
+	"github.com/hashicorp/hcl/hcl/token"
+	"github.com/mitchellh/mapstructure"
+)
+
+const (
+	varTypeAny    = "any"
+	varTypeString = "string"
+	varTypeNumber = "number"
+	varTypeBool   = "bool"
+	varTypeList   = "list"
+	varTypeMap    = "map"
+)
+
+var reVariableName = regexp.MustCompile("^[a-zA-Z_][a-zA-Z0-9_]*$")
+
+// variable is an input variable declared by a variable block
+type variable struct {
+	Name        string
+	Type        string
+	Default     interface{}
+	Description string
+	Validations []*variableValidation
+
+	// pos is the position of the declaration in the job file
+	pos token.Pos
+}
+
+// variableValidation is a rule the value of a variable must satisfy
+type variableValidation struct {
+	Condition    string
+	ErrorMessage string `mapstructure:"error_message"`
+
+	pos token.Pos
+}
+
+// posError prefixes the error with the position it occurred at in the file.
+func posError(path string, pos token.Pos, err error) error {
+	if path == "" {
+		return fmt.Errorf("%d:%d: %v", pos.Line, pos.Column, err)
+	}
+	return fmt.Errorf("%s:%d:%d: %v", path, pos.Line, pos.Column, err)
+}
+
+// parseVariables parses the variable blocks of the job file
+func parseVariables(path string, list *ast.ObjectList) (map[string]*variable, error) {
+	vars := make(map[string]*variable)
+	for _, o := range list.Items {
+		if len(o.Keys) != 1 {
+			return nil, posError(path, o.Pos(), fmt.Errorf("variable block must have exactly one label"))
+		}
+		name := o.Keys[0].Token.Value().(string)
+		if !reVariableName.MatchString(name) {
+			return nil, posError(path, o.Pos(), fmt.Errorf("invalid variable name %q", name))
+		}
+		if _, ok := vars[name]; ok {
+			return nil, posError(path, o.Pos(), fmt.Errorf("duplicate variable %q", name))
+		}
+
+		// Check for invalid keys
+		valid := []string{
+			"type",
+			"default",
+			"description",
+			"validation",
+		}
+		if err := checkHCLKeys(o.Val, valid); err != nil {
+			return nil, posError(path, o.Pos(), multierror.Prefix(err, fmt.Sprintf("variable %q ->", name)))
+		}
+
+		var m map[string]interface{}
+		if err := hcl.DecodeObject(&m, o.Val); err != nil {
+			return nil, posError(path, o.Pos(), err)
+		}
+		delete(m, "validation")
+		def, hasDefault := m["default"]
+		delete(m, "default")
+
+		v := &variable{Name: name, Type: varTypeAny, pos: o.Pos()}
+		if err := mapstructure.WeakDecode(m, v); err != nil {
+			return nil, posError(path, o.Pos(), err)
+		}
+		switch v.Type {
+		case varTypeAny, varTypeString, varTypeNumber, varTypeBool, varTypeList, varTypeMap:
+		default:
+			return nil, posError(path, o.Pos(), fmt.Errorf("variable %q has invalid type %q", name, v.Type))
+		}
+
+		if hasDefault {
+			val, err := normalizeValue(def)
+			if err == nil {
+				val, err = convertValue(val, v.Type)
+			}
+			if err != nil {
+				return nil, posError(path, o.Pos(), fmt.Errorf("invalid default of variable %q: %v", name, err))
+			}
+			v.Default = val
+		}
+
+		if ot, ok := o.Val.(*ast.ObjectType); ok {
+			for _, vo := range ot.List.Filter("validation").Items {
+				if err := checkHCLKeys(vo.Val, []string{"condition", "error_message"}); err != nil {
+					return nil, posError(path, vo.Pos(), multierror.Prefix(err, fmt.Sprintf("variable %q validation ->", name)))
+				}
+				var vm map[string]interface{}
+				if err := hcl.DecodeObject(&vm, vo.Val); err != nil {
+					return nil, posError(path, vo.Pos(), err)
+				}
+				validation := &variableValidation{pos: vo.Pos()}
+				if err := mapstructure.WeakDecode(vm, validation); err != nil {
+					return nil, posError(path, vo.Pos(), err)
+				}
+				if validation.Condition == "" {
+					return nil, posError(path, vo.Pos(), fmt.Errorf("validation of variable %q is missing a condition", name))
+				}
+				v.Validations = append(v.Validations, validation)
+			}
+		}
+
+		vars[name] = v
+	}
+	return vars, nil
+}
+
+// parseVarFile returns the variable values set by a variables file
+func parseVarFile(path string) (map[string]interface{}, error) {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading variables file: %v", err)
+	}
+	root, err := hcl.Parse(string(contents))
+	if err != nil {
+		return nil, fmt.Errorf("error parsing variables file %s: %v", path, err)
+	}
+	list, ok := root.Node.(*ast.ObjectList)
+	if !ok {
+		return nil, fmt.Errorf("error parsing variables file %s: root should be an object", path)
+	}
+
+	values := make(map[string]interface{})
+	for _, o := range list.Items {
+		name := o.Keys[0].Token.Value().(string)
+		var raw interface{}
+		if err := hcl.DecodeObject(&raw, o.Val); err != nil {
+			return nil, posError(path, o.Pos(), err)
+		}
+		v, err := normalizeValue(raw)
+		if err != nil {
+			return nil, posError(path, o.Pos(), fmt.Errorf("invalid value of variable %q: %v", name, err))
+		}
+		values[name] = v
+	}
+	return values, nil
+}
+
+// parseArgVar parses a variable value given as "name=value". Values of list
+// and map variables are parsed as HCL.
+func parseArgVar(arg string, vars map[string]*variable) (string, interface{}, error) {
+	idx := strings.Index(arg, "=")
+	if idx < 1 {
+		return "", nil, fmt.Errorf("invalid variable %q, must be of the form name=value", arg)
+	}
+	name, value := arg[:idx], arg[idx+1:]
+
+	v, ok := vars[name]
+	if !ok || (v.Type != varTypeList && v.Type != varTypeMap) {
+		return name, value, nil
+	}
+
+	root, err := hcl.Parse("value = " + value)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid value of variable %q: %v", name, err)
+	}
+	var m map[string]interface{}
+	if err := hcl.DecodeObject(&m, root); err != nil {
+		return "", nil, fmt.Errorf("invalid value of variable %q: %v", name, err)
+	}
+	val, err := normalizeValue(m["value"])
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid value of variable %q: %v", name, err)
+	}
+	return name, val, nil
+}
+
+// variableValues returns the value of every declared variable from the
+// variables files, overridden by the variables given as arguments, or the
+// default of the variable.
+func variableValues(c *ParseConfig, vars map[string]*variable, ctx *evalContext) (map[string]interface{}, error) {
+	given := make(map[string]interface{})
+	for _, f := range c.VarFiles {
+		values, err := parseVarFile(f)
+		if err != nil {
+			return nil, err
+		}
+		for name, v := range values {
+			if _, ok := vars[name]; !ok {
+				return nil, fmt.Errorf("%s: undeclared variable %q", f, name)
+			}
+			given[name] = v
+		}
+	}
+	for _, arg := range c.ArgVars {
+		name, v, err := parseArgVar(arg, vars)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := vars[name]; !ok {
+			return nil, fmt.Errorf("undeclared variable %q", name)
+		}
+		given[name] = v
+	}
+
+	var mErr multierror.Error
+	values := make(map[string]interface{}, len(vars))
+	names := make([]string, 0, len(vars))
+	for name := range vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		v := vars[name]
+		val, ok := given[name]
+		if !ok {
+			if v.Default == nil {
+				mErr.Errors = append(mErr.Errors, posError(c.Path, v.pos, fmt.Errorf("variable %q is required but has no value", name)))
+				continue
+			}
+			val = v.Default
+		}
+
+		val, err := convertValue(val, v.Type)
+		if err != nil {
+			mErr.Errors = append(mErr.Errors, posError(c.Path, v.pos, fmt.Errorf("invalid value of variable %q: %v", name, err)))
+			continue
+		}
+		values[name] = val
+	}
+	if err := mErr.ErrorOrNil(); err != nil {
+		return nil, err
+	}
+
+	// Validations may reference any variable
+	ctx.vars = values
+	for _, name := range names {
+		v := vars[name]
+		for _, validation := range v.Validations {
+			cond := strings.TrimSpace(validation.Condition)
+			if strings.HasPrefix(cond, "${") && strings.HasSuffix(cond, "}") {
+				cond = cond[2 : len(cond)-1]
+			}
+			ok, err := ctx.evalExpr(cond)
+			if err != nil {
+				mErr.Errors = append(mErr.Errors, posError(c.Path, validation.pos, fmt.Errorf("invalid validation condition of variable %q: %v", name, err)))
+				continue
+			}
+			if b, isBool := ok.(bool); !isBool {
+				mErr.Errors = append(mErr.Errors, posError(c.Path, validation.pos, fmt.Errorf("validation condition of variable %q must be a bool, got %s", name, typeName(ok))))
+			} else if !b {
+				msg := validation.ErrorMessage
+				if msg == "" {
+					msg = fmt.Sprintf("condition %q is false", validation.Condition)
+				}
+				mErr.Errors = append(mErr.Errors, posError(c.Path, v.pos, fmt.Errorf("invalid value of variable %q: %s", name, msg)))
+			}
+		}
+	}
+	return values, mErr.ErrorOrNil()
+}
+
+// normalizeValue converts a decoded HCL or JSON value to the types used when
+// evaluating expressions: strings, float64 numbers, bools, lists and maps.
+func normalizeValue(v interface{}) (interface{}, error) {
+	switch t := v.(type) {
+	case string, bool:
+		return t, nil
+	case int:
+		return float64(t), nil
+	case int64:
+		return float64(t), nil
+	case float64:
+		return t, nil
+	case []interface{}:
+		out := make([]interface{}, len(t))
+		for i, e := range t {
+			n, err := normalizeValue(e)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = n
+		}
+		return out, nil
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(t))
+		for k, e := range t {
+			n, err := normalizeValue(e)
+			if err != nil {
+				return nil, err
+			}
+			out[k] = n
+		}
+		return out, nil
+	case []map[string]interface{}:
+		// HCL decodes objects as lists of maps
+		out := make(map[string]interface{})
+		for _, m := range t {
+			n, err := normalizeValue(m)
+			if err != nil {
+				return nil, err
+			}
+			for k, e := range n.(map[string]interface{}) {
+				out[k] = e
+			}
+		}
+		return out, nil
+	}
+	return nil, fmt.Errorf("unsupported value of type %T", v)
+}
+
+// convertValue converts the value to the type of a variable
+func convertValue(v interface{}, typ string) (interface{}, error) {
+	switch typ {
+	case varTypeAny:
+		return v, nil
+	case varTypeString:
+		return toString(v)
+	case varTypeNumber:
+		switch t := v.(type) {
+		case float64:
+			return t, nil
+		case string:
+			n, err := strconv.ParseFloat(strings.TrimSpace(t), 64)
+			if err != nil {
+				return nil, fmt.Errorf("can not convert %q to a number", t)
+			}
+			return n, nil
+		}
+	case varTypeBool:
+		switch t := v.(type) {
+		case bool:
+			return t, nil
+		case string:
+			b, err := strconv.ParseBool(t)
+			if err != nil {
+				return nil, fmt.Errorf("can not convert %q to a bool", t)
+			}
+			return b, nil
+		}
+	case varTypeList:
+		if _, ok := v.([]interface{}); ok {
+			return v, nil
+		}
+	case varTypeMap:
+		if _, ok := v.(map[string]interface{}); ok {
+			return v, nil
+		}
+	}
+	return nil, fmt.Errorf("can not convert a %s to a %s", typeName(v), typ)
+}
+
+// interpolate evaluates the expressions in the strings of the job file that
+// reference variables or call functions. Other interpolations, such as
+// ${node.datacenter} or ${NOMAD_TASK_NAME}, are left for runtime. A string
+// that consists of a single expression is replaced by the value of the
+// expression so that numbers, bools, lists and maps can be interpolated.
+func interpolate(path string, list *ast.ObjectList, ctx *evalContext) error {
+	var mErr multierror.Error
+	ast.Walk(list, func(n ast.Node) (ast.Node, bool) {
+		// Labels of blocks, such as the name of the job, must remain
+		// strings
+		if key, ok := n.(*ast.ObjectKey); ok && key.Token.Type == token.STRING {
+			s := key.Token.Value().(string)
+			v, err := ctx.interpolateString(s)
+			if err == nil {
+				s, err = toString(v)
+			}
+			if err != nil {
+				mErr.Errors = append(mErr.Errors, posError(path, key.Pos(), err))
+			} else {
+				key.Token = token.Token{Type: token.STRING, Pos: key.Pos(), Text: strconv.Quote(s), JSON: true}
+			}
+			return n, false
+		}
+
+		lit, ok := n.(*ast.LiteralType)
+		if !ok || (lit.Token.Type != token.STRING && lit.Token.Type != token.HEREDOC) {
+			return n, true
+		}
+
+		s, ok := lit.Token.Value().(string)
+		if !ok {
+			return n, true
+		}
+		v, err := ctx.interpolateString(s)
+		if err != nil {
+			mErr.Errors = append(mErr.Errors, posError(path, lit.Pos(), err))
+			return n, false
+		}
+		if str, ok := v.(string); ok && str == s {
+			return n, false
+		}
+		node, err := valueNode(v, lit.Pos())
+		if err != nil {
+			mErr.Errors = append(mErr.Errors, posError(path, lit.Pos(), err))
+			return n, false
+		}
+		return node, false
+	})
+	return mErr.ErrorOrNil()
+}
+
+// interpolateString evaluates the parse time expressions in the string. If
+// the string is a single expression its value is returned as is, otherwise
+// the values are interpolated into the string. "$${" escapes an
+// interpolation.
+func (ctx *evalContext) interpolateString(s string) (interface{}, error) {
+	var buf bytes.Buffer
+	for i := 0; i < len(s); {
+		escaped := strings.HasPrefix(s[i:], "$${")
+		if escaped {
+			i++
+		}
+		if !strings.HasPrefix(s[i:], "${") {
+			buf.WriteByte(s[i])
+			i++
+			continue
+		}
+
+		// Unterminated interpolations are left as is
+		end := interpolationEnd(s, i)
+		if end < 0 {
+			buf.WriteString(s[i:])
+			break
+		}
+		expr := s[i+2 : end]
+		if escaped {
+			buf.WriteString(s[i : end+1])
+			i = end + 1
+			continue
+		}
+		if !isParseTimeExpr(expr) {
+			buf.WriteString(s[i : end+1])
+			i = end + 1
+			continue
+		}
+
+		v, err := ctx.evalExpr(expr)
+		if err != nil {
+			return nil, fmt.Errorf("error evaluating %q: %v", s[i:end+1], err)
+		}
+		if i == 0 && end == len(s)-1 {
+			return v, nil
+		}
+		str, err := toString(v)
+		if err != nil {
+			return nil, fmt.Errorf("error interpolating %q: %v", s[i:end+1], err)
+		}
+		buf.WriteString(str)
+		i = end + 1
+	}
+	return buf.String(), nil
+}
+
+// interpolationEnd returns the index of the brace closing the interpolation
+// starting at the given index, skipping braces in nested strings, or -1 if
+// the interpolation is not terminated.
+func interpolationEnd(s string, start int) int {
+	depth := 0
+	inString := false
+	for i := start + 1; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case inString && c == '\\':
+			i++
+		case c == '"':
+			inString = !inString
+		case inString:
+		case c == '{':
+			depth++
+		case c == '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// reParseTimeExpr matches expressions that reference variables or call
+// functions
+var reParseTimeExpr = regexp.MustCompile(`^\s*!?\s*(var\s*\.|[a-z_][a-z0-9_]*\s*\()`)
+
+// isParseTimeExpr returns whether the expression is evaluated when parsing
+// the job rather than by the client at runtime.
+func isParseTimeExpr(expr string) bool {
+	return reParseTimeExpr.MatchString(expr)
+}
+
+// valueNode returns the HCL node of a value
+func valueNode(v interface{}, pos token.Pos) (ast.Node, error) {
+	switch t := v.(type) {
+	case string:
+		return &ast.LiteralType{Token: token.Token{Type: token.STRING, Pos: pos, Text: strconv.Quote(t), JSON: true}}, nil
+	case bool:
+		return &ast.LiteralType{Token: token.Token{Type: token.BOOL, Pos: pos, Text: strconv.FormatBool(t)}}, nil
+	case float64:
+		if t == math.Trunc(t) && math.Abs(t) < 1<<53 {
+			return &ast.LiteralType{Token: token.Token{Type: token.NUMBER, Pos: pos, Text: strconv.FormatInt(int64(t), 10)}}, nil
+		}
+		return &ast.LiteralType{Token: token.Token{Type: token.FLOAT, Pos: pos, Text: strconv.FormatFloat(t, 'f', -1, 64)}}, nil
+	case []interface{}:
+		list := &ast.ListType{Lbrack: pos}
+		for _, e := range t {
+			n, err := valueNode(e, pos)
+			if err != nil {
+				return nil, err
+			}
+			list.List = append(list.List, n)
+		}
+		return list, nil
+	case map[string]interface{}:
+		obj := &ast.ObjectType{Lbrace: pos, List: &ast.ObjectList{}}
+		for _, k := range sortedKeys(t) {
+			n, err := valueNode(t[k], pos)
+			if err != nil {
+				return nil, err
+			}
+			obj.List.Add(&ast.ObjectItem{
+				Keys: []*ast.ObjectKey{
+					{Token: token.Token{Type: token.STRING, Pos: pos, Text: strconv.Quote(k), JSON: true}},
+				},
+				Assign: pos,
+				Val:    n,
+			})
+		}
+		return obj, nil
+	}
+	return nil, fmt.Errorf("can not use a %s value in the job", typeName(v))
+}
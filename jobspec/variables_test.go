@@ -0,0 +1,232 @@
+package jobspec
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/nomad/api"
+	"github.com/hashicorp/nomad/helper"
+)
+
+func TestEvalExpr(t *testing.T) {
+	ctx := &evalContext{
+		vars: map[string]interface{}{
+			"name":  "web",
+			"count": float64(3),
+			"dcs":   []interface{}{"dc1", "dc2"},
+			"tags":  map[string]interface{}{"env": "prod"},
+			"debug": false,
+		},
+		baseDir: "test-fixtures",
+	}
+
+	cases := []struct {
+		Expr     string
+		Expected interface{}
+	}{
+		{`var.name`, "web"},
+		{`var.count * 2 + 1`, float64(7)},
+		{`var.dcs[1]`, "dc2"},
+		{`var.tags.env`, "prod"},
+		{`var.tags["env"]`, "prod"},
+		{`!var.debug && var.count >= 3`, true},
+		{`var.debug || var.count != 3`, false},
+		{`var.count > 2 ? "many" : "few"`, "many"},
+		{`length(var.dcs) == 0 ? "none" : var.dcs[0]`, "dc1"},
+		{`length(var.dcs) > 5 && var.dcs[5] == "dc6"`, false},
+		{`upper(var.name)`, "WEB"},
+		{`format("%s-%d", var.name, var.count)`, "web-3"},
+		{`join(",", concat(var.dcs, ["dc3"]))`, "dc1,dc2,dc3"},
+		{`split(",", "a,b")`, []interface{}{"a", "b"}},
+		{`replace("a-b-c", "-", "_")`, "a_b_c"},
+		{`regex_replace("web-01", "[0-9]+", "x")`, "web-x"},
+		{`substr("hello", 1, 3)`, "ell"},
+		{`trimprefix("v1.2", "v")`, "1.2"},
+		{`contains(var.dcs, "dc2")`, true},
+		{`distinct(["a", "b", "a"])`, []interface{}{"a", "b"}},
+		{`element(var.dcs, 3)`, "dc2"},
+		{`flatten([["a"], ["b", ["c"]]])`, []interface{}{"a", "b", "c"}},
+		{`keys(merge(var.tags, {team = "ops"}))`, []interface{}{"env", "team"}},
+		{`lookup(var.tags, "missing", "default")`, "default"},
+		{`reverse(slice(var.dcs, 0, 2))`, []interface{}{"dc2", "dc1"}},
+		{`max(1, var.count, 2) + min(4, 5)`, float64(7)},
+		{`floor(2.5) + ceil(0.5) + abs(-1)`, float64(4)},
+		{`parseint("ff", 16)`, float64(255)},
+		{`base64decode(base64encode("nomad"))`, "nomad"},
+		{`jsonencode({a = [1, true]})`, `{"a":[1,true]}`},
+		{`jsondecode("{\"a\": [1]}").a[0]`, float64(1)},
+		{`urlencode("a b")`, "a+b"},
+		{`trimspace(file("variables-motd.txt"))`, "Hello from Nomad"},
+		{`fileexists("missing.txt")`, false},
+		{`basename("a/b/c.txt")`, "c.txt"},
+		{`tonumber("42") + 1`, float64(43)},
+		{`tostring(var.count)`, "3"},
+		{`tobool("true")`, true},
+		{`coalesce("", var.name)`, "web"},
+		{`compact(["a", "", "b"])`, []interface{}{"a", "b"}},
+		{`sort(["b", "a"])`, []interface{}{"a", "b"}},
+		{`values(var.tags)`, []interface{}{"prod"}},
+	}
+
+	for _, c := range cases {
+		v, err := ctx.evalExpr(c.Expr)
+		if err != nil {
+			t.Fatalf("%s: %v", c.Expr, err)
+		}
+		if !reflect.DeepEqual(v, c.Expected) {
+			t.Fatalf("%s: got %#v; want %#v", c.Expr, v, c.Expected)
+		}
+	}
+
+	errCases := map[string]string{
+		`var.missing`:         "undeclared variable",
+		`nope(1)`:             "unknown function",
+		`var.dcs[2]`:          "out of range",
+		`var.name + 1`:        "requires numbers",
+		`upper(var.name`:      "expected",
+		`var.count ? 1 : 2`:   "must be a bool",
+		`lookup(var.tags, 1)`: "no key",
+	}
+	for expr, exp := range errCases {
+		_, err := ctx.evalExpr(expr)
+		if err == nil || !strings.Contains(err.Error(), exp) {
+			t.Fatalf("%s: expected error containing %q; got %v", expr, exp, err)
+		}
+	}
+}
+
+func TestInterpolateString(t *testing.T) {
+	ctx := &evalContext{
+		vars: map[string]interface{}{
+			"env":   "prod",
+			"count": float64(2),
+			"dcs":   []interface{}{"dc1"},
+		},
+	}
+
+	cases := []struct {
+		In       string
+		Expected interface{}
+	}{
+		{"plain", "plain"},
+		{"${var.count}", float64(2)},
+		{"${var.dcs}", []interface{}{"dc1"}},
+		{"web-${var.env}-${var.count}", "web-prod-2"},
+		{"${NOMAD_TASK_NAME}-${var.env}", "${NOMAD_TASK_NAME}-prod"},
+		{"${attr.kernel.name}", "${attr.kernel.name}"},
+		{`${meta["rack"]}`, `${meta["rack"]}`},
+		{"$${var.env}", "${var.env}"},
+		{`${lookup({a = "}"}, "a")}`, "}"},
+		{"unterminated ${var.env", "unterminated ${var.env"},
+	}
+	for _, c := range cases {
+		v, err := ctx.interpolateString(c.In)
+		if err != nil {
+			t.Fatalf("%s: %v", c.In, err)
+		}
+		if !reflect.DeepEqual(v, c.Expected) {
+			t.Fatalf("%s: got %#v; want %#v", c.In, v, c.Expected)
+		}
+	}
+
+	if _, err := ctx.interpolateString("dcs: ${var.dcs}"); err == nil {
+		t.Fatalf("expected error interpolating a list into a string")
+	}
+}
+
+func TestParseWithConfig_Variables(t *testing.T) {
+	path, err := filepath.Abs(filepath.Join("./test-fixtures", "variables.hcl"))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer f.Close()
+
+	job, err := ParseWithConfig(&ParseConfig{
+		Path:     path,
+		Body:     f,
+		ArgVars:  []string{"datacenters=[\"us-east-1\", \"us-west-1\"]"},
+		VarFiles: []string{filepath.Join("./test-fixtures", "variables.vars.hcl")},
+	})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	expected := &api.Job{
+		ID:          helper.StringToPtr("web-prod"),
+		Name:        helper.StringToPtr("web-prod"),
+		Datacenters: []string{"us-east-1", "us-west-1"},
+		Meta: map[string]string{
+			"env":         "PROD",
+			"description": "web prod in us-east-1,us-west-1",
+			"motd":        "Hello from Nomad",
+			"node":        "${node.unique.name}",
+			"escaped":     "${var.env}",
+		},
+		TaskGroups: []*api.TaskGroup{
+			{
+				Name:  helper.StringToPtr("web"),
+				Count: helper.IntToPtr(3),
+				Tasks: []*api.Task{
+					{
+						Name:   "web",
+						Driver: "docker",
+						Config: map[string]interface{}{
+							"image": "redis:1.2.3",
+						},
+						Env: map[string]string{
+							"DEBUG": "0",
+						},
+					},
+				},
+			},
+		},
+	}
+	if !reflect.DeepEqual(job, expected) {
+		t.Fatalf("got:\n%#v\nwant:\n%#v", job, expected)
+	}
+}
+
+func TestParseWithConfig_VariableErrors(t *testing.T) {
+	path := filepath.Join("./test-fixtures", "variables.hcl")
+
+	cases := []struct {
+		Name     string
+		ArgVars  []string
+		Expected string
+	}{
+		{"required", nil, `variables.hcl:7:10: variable "env" is required but has no value`},
+		{"validation", []string{"env=test"}, `variables.hcl:7:10: invalid value of variable "env": The env must be dev or prod.`},
+		{"type", []string{"env=dev", "count=many"}, `variables.hcl:16:10: invalid value of variable "count"`},
+		{"undeclared", []string{"env=dev", "region=global"}, `undeclared variable "region"`},
+		{"malformed", []string{"env"}, "must be of the form name=value"},
+	}
+	for _, c := range cases {
+		f, err := os.Open(path)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		_, err = ParseWithConfig(&ParseConfig{Path: path, Body: f, ArgVars: c.ArgVars})
+		f.Close()
+		if err == nil || !strings.Contains(err.Error(), c.Expected) {
+			t.Fatalf("%s: expected error containing %q; got %v", c.Name, c.Expected, err)
+		}
+	}
+
+	// Expression errors point at the string they occur in
+	src := `
+job "example" {
+  datacenters = ["${upper(var.missing)}"]
+}
+`
+	_, err := ParseWithConfig(&ParseConfig{Path: "example.nomad", Body: strings.NewReader(src)})
+	if err == nil || !strings.Contains(err.Error(), `example.nomad:3:18: error evaluating "${upper(var.missing)}": undeclared variable "missing"`) {
+		t.Fatalf("bad error: %v", err)
+	}
+}
@@ -0,0 +1,294 @@
+package nomad
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"time"
+
+	multierror "github.com/hashicorp/go-multierror"
+	cstructs "github.com/hashicorp/nomad/client/structs"
+	"github.com/hashicorp/nomad/helper"
+	"github.com/hashicorp/nomad/nomad/state"
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+const (
+	// autoscalerStatsTimeout bounds the time spent fetching the resource
+	// usage of an allocation from the client running it
+	autoscalerStatsTimeout = 10 * time.Second
+
+	// autoscalerMessage is the message of the scaling events recorded by
+	// the built-in autoscaler
+	autoscalerMessage = "scaled by the built-in autoscaler"
+)
+
+// allocStatsFn returns the resource usage of an allocation running on a node
+type allocStatsFn func(node *structs.Node, allocID string) (*cstructs.AllocResourceUsage, error)
+
+// runAutoscaler is a long lived function run by the leader that periodically
+// evaluates the scaling policies of task groups and changes their count to
+// keep the utilization of their allocations close to the target of the
+// policy.
+func (s *Server) runAutoscaler(stopCh chan struct{}) {
+	ticker := time.NewTicker(s.config.AutoscalerInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := s.autoscale(); err != nil {
+				s.logger.Printf("[ERR] nomad.autoscaler: %v", err)
+			}
+		case <-stopCh:
+			return
+		}
+	}
+}
+
+// autoscale evaluates all the enabled scaling policies that set a target
+// for the built-in autoscaler.
+func (s *Server) autoscale() error {
+	snap, err := s.fsm.State().Snapshot()
+	if err != nil {
+		return err
+	}
+
+	iter, err := snap.ScalingPolicies(nil)
+	if err != nil {
+		return err
+	}
+
+	var mErr multierror.Error
+	for {
+		raw := iter.Next()
+		if raw == nil {
+			break
+		}
+
+		policy := raw.(*structs.ScalingPolicy)
+		if !policy.Enabled {
+			continue
+		}
+		target, err := policy.ScalingTarget()
+		if err != nil || target == nil {
+			continue
+		}
+
+		if err := s.autoscalePolicy(snap, policy, target); err != nil {
+			mErr.Errors = append(mErr.Errors,
+				fmt.Errorf("failed to scale group %q of job %q: %v", policy.TaskGroup, policy.JobID, err))
+		}
+	}
+	return mErr.ErrorOrNil()
+}
+
+// autoscalePolicy changes the count of the task group targeted by the policy
+// if the utilization of its allocations is off target and the cooldown since
+// the last change of its count has elapsed.
+func (s *Server) autoscalePolicy(snap *state.StateSnapshot, policy *structs.ScalingPolicy,
+	target *structs.ScalingTarget) error {
+	job, err := snap.JobByID(nil, policy.JobID)
+	if err != nil {
+		return err
+	}
+	if job == nil || job.IsPeriodic() || job.IsParameterized() {
+		return nil
+	}
+	tg := job.LookupTaskGroup(policy.TaskGroup)
+	if tg == nil {
+		return nil
+	}
+
+	// Respect the cooldown since the last change of the count
+	cooldown := target.Cooldown
+	if cooldown == 0 {
+		cooldown = s.config.AutoscalerCooldown
+	}
+	events, err := snap.ScalingEventsByJob(nil, job.ID)
+	if err != nil {
+		return err
+	}
+	if events != nil {
+		for _, event := range events.ScalingEvents[tg.Name] {
+			if event.Count == nil {
+				continue
+			}
+			if time.Since(time.Unix(0, event.Time)) < cooldown {
+				return nil
+			}
+			break
+		}
+	}
+
+	utilization, ok, err := s.groupUtilization(snap, job.ID, tg.Name, target.Metric)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return nil
+	}
+
+	count := autoscaledCount(tg.Count, utilization, target.Target, policy.Min, policy.Max)
+	if count == tg.Count {
+		return nil
+	}
+
+	req := &structs.JobScaleRequest{
+		JobID:     job.ID,
+		TaskGroup: tg.Name,
+		Count:     helper.Int64ToPtr(int64(count)),
+		Message:   autoscalerMessage,
+		Meta: map[string]interface{}{
+			"metric":      target.Metric,
+			"utilization": utilization,
+			"target":      target.Target,
+		},
+		WriteRequest: structs.WriteRequest{Region: s.config.Region},
+	}
+	var resp structs.JobRegisterResponse
+	if err := s.RPC("Job.Scale", req, &resp); err != nil {
+		return err
+	}
+
+	s.logger.Printf("[INFO] nomad.autoscaler: scaled group %q of job %q from %d to %d (%s utilization %.1f%%, target %.1f%%)",
+		tg.Name, job.ID, tg.Count, count, target.Metric, utilization, target.Target)
+	return nil
+}
+
+// groupUtilization returns the average utilization of the given metric over
+// the running allocations of the task group, in percent of the resources
+// they reserve. False is returned if the resource usage of no allocation is
+// known.
+func (s *Server) groupUtilization(snap *state.StateSnapshot, jobID, group, metric string) (float64, bool, error) {
+	allocs, err := snap.AllocsByJob(nil, jobID, false)
+	if err != nil {
+		return 0, false, err
+	}
+
+	statsFn := s.autoscalerStats
+	if statsFn == nil {
+		statsFn = s.fetchAllocStats
+	}
+
+	var total float64
+	var n int
+	var lastErr error
+	for _, alloc := range allocs {
+		if alloc.TaskGroup != group || alloc.TerminalStatus() ||
+			alloc.ClientStatus != structs.AllocClientStatusRunning {
+			continue
+		}
+
+		node, err := snap.NodeByID(nil, alloc.NodeID)
+		if err != nil {
+			return 0, false, err
+		}
+		if node == nil {
+			continue
+		}
+
+		usage, err := statsFn(node, alloc.ID)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if u, ok := allocUtilization(alloc, usage, metric); ok {
+			total += u
+			n++
+		}
+	}
+
+	// Only fail if the usage of no allocation could be fetched
+	if n == 0 {
+		return 0, false, lastErr
+	}
+	return total / float64(n), true, nil
+}
+
+// allocUtilization returns the utilization of the given metric by the
+// allocation, in percent of the resources it reserves.
+func allocUtilization(alloc *structs.Allocation, usage *cstructs.AllocResourceUsage, metric string) (float64, bool) {
+	if usage == nil || usage.ResourceUsage == nil {
+		return 0, false
+	}
+
+	var reserved, used float64
+	for _, r := range alloc.TaskResources {
+		switch metric {
+		case structs.ScalingMetricCPU:
+			reserved += float64(r.CPU)
+		case structs.ScalingMetricMemory:
+			reserved += float64(r.MemoryMB) * 1024 * 1024
+		}
+	}
+	switch metric {
+	case structs.ScalingMetricCPU:
+		if usage.ResourceUsage.CpuStats == nil {
+			return 0, false
+		}
+		used = usage.ResourceUsage.CpuStats.TotalTicks
+	case structs.ScalingMetricMemory:
+		if usage.ResourceUsage.MemoryStats == nil {
+			return 0, false
+		}
+		used = float64(usage.ResourceUsage.MemoryStats.RSS)
+	}
+
+	if reserved <= 0 {
+		return 0, false
+	}
+	return used / reserved * 100, true
+}
+
+// autoscaledCount returns the count that brings the utilization of a task
+// group back to the target, bounded by the policy.
+func autoscaledCount(count int, utilization, target float64, min, max int64) int {
+	desired := int64(count)
+	if count > 0 {
+		desired = int64(math.Ceil(float64(count) * utilization / target))
+	}
+	if desired < min {
+		desired = min
+	}
+	if desired > max {
+		desired = max
+	}
+	return int(desired)
+}
+
+// fetchAllocStats queries the resource usage of an allocation from the HTTP
+// API of the client running it.
+func (s *Server) fetchAllocStats(node *structs.Node, allocID string) (*cstructs.AllocResourceUsage, error) {
+	if node.HTTPAddr == "" {
+		return nil, fmt.Errorf("node %q has no HTTP address", node.ID)
+	}
+
+	client := &http.Client{Timeout: autoscalerStatsTimeout}
+	scheme := "http"
+	if node.TLSEnabled {
+		tlsConf, err := s.config.tlsConfig().OutgoingTLSConfig()
+		if err != nil {
+			return nil, err
+		}
+		client.Transport = &http.Transport{TLSClientConfig: tlsConf}
+		scheme = "https"
+	}
+
+	url := fmt.Sprintf("%s://%s/v1/client/allocation/%s/stats", scheme, node.HTTPAddr, allocID)
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected response code querying allocation %q stats: %d", allocID, resp.StatusCode)
+	}
+
+	var usage cstructs.AllocResourceUsage
+	if err := json.NewDecoder(resp.Body).Decode(&usage); err != nil {
+		return nil, err
+	}
+	return &usage, nil
+}
@@ -0,0 +1,175 @@
+package nomad
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/net-rpc-msgpackrpc"
+	cstructs "github.com/hashicorp/nomad/client/structs"
+	"github.com/hashicorp/nomad/nomad/mock"
+	"github.com/hashicorp/nomad/nomad/structs"
+	"github.com/hashicorp/nomad/testutil"
+)
+
+func TestAutoscaledCount(t *testing.T) {
+	cases := []struct {
+		Count       int
+		Utilization float64
+		Target      float64
+		Min, Max    int64
+		Expected    int
+	}{
+		{4, 50, 50, 1, 10, 4},
+		{4, 100, 50, 1, 10, 8},
+		{4, 10, 50, 1, 10, 1},
+		{4, 30, 50, 2, 10, 3},
+		{4, 200, 50, 1, 10, 10},
+		{4, 0, 50, 2, 10, 2},
+		{0, 0, 50, 1, 10, 1},
+	}
+	for _, c := range cases {
+		if out := autoscaledCount(c.Count, c.Utilization, c.Target, c.Min, c.Max); out != c.Expected {
+			t.Fatalf("autoscaledCount(%d, %v, %v, %d, %d) = %d; want %d",
+				c.Count, c.Utilization, c.Target, c.Min, c.Max, out, c.Expected)
+		}
+	}
+}
+
+func TestAutoscaler_Autoscale(t *testing.T) {
+	s1 := testServer(t, func(c *Config) {
+		c.NumSchedulers = 0 // Prevent automatic dequeue
+	})
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	// Register a job with a scaling policy targeting 50% of CPU
+	job := mock.Job()
+	job.TaskGroups[0].Count = 4
+	job.TaskGroups[0].Scaling = &structs.ScalingPolicy{
+		Min:     1,
+		Max:     6,
+		Enabled: true,
+		Policy: map[string]interface{}{
+			"metric":   "cpu",
+			"target":   50,
+			"cooldown": "1h",
+		},
+	}
+	req := &structs.JobRegisterRequest{
+		Job:          job,
+		WriteRequest: structs.WriteRequest{Region: "global"},
+	}
+	var resp structs.JobRegisterResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Job.Register", req, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Create running allocations of the group on a node
+	state := s1.fsm.State()
+	stored, err := state.JobByID(nil, job.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	node := mock.Node()
+	if err := state.UpsertNode(1000, node); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	var allocs []*structs.Allocation
+	for i := 0; i < 2; i++ {
+		alloc := mock.Alloc()
+		alloc.JobID = job.ID
+		alloc.Job = stored
+		alloc.NodeID = node.ID
+		alloc.ClientStatus = structs.AllocClientStatusRunning
+		allocs = append(allocs, alloc)
+	}
+	if err := state.UpsertJobSummary(1001, mock.JobSummary(job.ID)); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := state.UpsertAllocs(1002, allocs); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// The allocations use all of their CPU
+	ticks := 500.0
+	s1.autoscalerStats = func(n *structs.Node, allocID string) (*cstructs.AllocResourceUsage, error) {
+		return &cstructs.AllocResourceUsage{
+			ResourceUsage: &cstructs.ResourceUsage{
+				CpuStats: &cstructs.CpuStats{TotalTicks: ticks},
+			},
+		}, nil
+	}
+	if err := s1.autoscale(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// The group is scaled up to the maximum of the policy
+	out, err := state.JobByID(nil, job.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out.TaskGroups[0].Count != 6 {
+		t.Fatalf("bad count: %d", out.TaskGroups[0].Count)
+	}
+	events, err := state.ScalingEventsByJob(nil, job.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	web := events.ScalingEvents["web"]
+	if len(web) != 1 || web[0].Message != autoscalerMessage || web[0].EvalID == "" ||
+		web[0].Meta["metric"] != "cpu" || web[0].PreviousCount != 4 {
+		t.Fatalf("bad events: %#v", web)
+	}
+
+	// The cooldown prevents scaling down right away
+	ticks = 50
+	if err := s1.autoscale(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	out, err = state.JobByID(nil, job.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out.TaskGroups[0].Count != 6 {
+		t.Fatalf("bad count: %d", out.TaskGroups[0].Count)
+	}
+}
+
+func TestAutoscaler_FetchAllocStats(t *testing.T) {
+	s1 := testServer(t, nil)
+	defer s1.Shutdown()
+
+	alloc := mock.Alloc()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/client/allocation/"+alloc.ID+"/stats" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(&cstructs.AllocResourceUsage{
+			ResourceUsage: &cstructs.ResourceUsage{
+				MemoryStats: &cstructs.MemoryStats{RSS: 128 * 1024 * 1024},
+			},
+		})
+	}))
+	defer srv.Close()
+
+	node := mock.Node()
+	node.HTTPAddr = strings.TrimPrefix(srv.URL, "http://")
+	usage, err := s1.fetchAllocStats(node, alloc.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	u, ok := allocUtilization(alloc, usage, structs.ScalingMetricMemory)
+	if !ok || u != 50 {
+		t.Fatalf("bad utilization: %v %v", u, ok)
+	}
+
+	// Unknown allocations return an error
+	if _, err := s1.fetchAllocStats(node, "missing"); err == nil {
+		t.Fatalf("expected error")
+	}
+}
@@ -177,6 +177,23 @@ type Config struct {
 	// of all the heartbeats.
 	FailoverHeartbeatTTL time.Duration
 
+	// IdentityKeyRotation is how often the leader rotates the key signing
+	// the workload identities of tasks.
+	IdentityKeyRotation time.Duration
+
+	// AutoscalerEnabled enables the built-in autoscaler run by the leader,
+	// which changes the count of task groups according to the resource
+	// usage of their allocations and their scaling policies.
+	AutoscalerEnabled bool
+
+	// AutoscalerInterval is the interval at which the autoscaler evaluates
+	// the scaling policies.
+	AutoscalerInterval time.Duration
+
+	// AutoscalerCooldown is the minimum duration between two changes of the
+	// count of a task group by the autoscaler, unless set by its policy.
+	AutoscalerCooldown time.Duration
+
 	// ConsulConfig is this Agent's Consul configuration
 	ConsulConfig *config.ConsulConfig
 
@@ -237,6 +254,9 @@ func DefaultConfig() *Config {
 		MaxHeartbeatsPerSecond: 50.0,
 		HeartbeatGrace:         10 * time.Second,
 		FailoverHeartbeatTTL:   300 * time.Second,
+		IdentityKeyRotation:    30 * 24 * time.Hour,
+		AutoscalerInterval:     30 * time.Second,
+		AutoscalerCooldown:     5 * time.Minute,
 		ConsulConfig:           config.DefaultConsulConfig(),
 		VaultConfig:            config.DefaultVaultConfig(),
 		RPCHoldTimeout:         5 * time.Second,
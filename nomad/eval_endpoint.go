@@ -357,3 +357,42 @@ func (e *Eval) Allocations(args *structs.EvalSpecificRequest,
 		}}
 	return e.srv.blockingRPC(&opts)
 }
+
+// Plans is used to list the plans applied for an evaluation
+func (e *Eval) Plans(args *structs.EvalSpecificRequest,
+	reply *structs.EvalPlansResponse) error {
+	if done, err := e.srv.forward("Eval.Plans", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "eval", "plans"}, time.Now())
+
+	// Setup the blocking query
+	opts := blockingOptions{
+		queryOpts: &args.QueryOptions,
+		queryMeta: &reply.QueryMeta,
+		run: func(ws memdb.WatchSet, state *state.StateStore) error {
+			// Look for the plans
+			out, err := state.EvalPlansByEval(ws, args.EvalID)
+			if err != nil {
+				return err
+			}
+
+			// Setup the output
+			reply.Plans = out
+			if out != nil {
+				reply.Index = out.ModifyIndex
+			} else {
+				// Use the last index that affected the eval plan table
+				index, err := state.Index("eval_plan")
+				if err != nil {
+					return err
+				}
+				reply.Index = index
+			}
+
+			// Set the query response
+			e.srv.setQueryMeta(&reply.QueryMeta)
+			return nil
+		}}
+	return e.srv.blockingRPC(&opts)
+}
@@ -735,3 +735,53 @@ func TestEvalEndpoint_Reblock(t *testing.T) {
 		t.Fatalf("ReblockEval didn't insert eval into the blocked eval tracker")
 	}
 }
+
+func TestEvalEndpoint_Plans(t *testing.T) {
+	s1 := testServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	// Record a plan applied for an eval
+	eval := mock.Eval()
+	alloc := mock.Alloc()
+	alloc.EvalID = eval.ID
+	plan := &structs.AppliedPlan{EvalID: eval.ID, JobID: eval.JobID}
+	plan.AddAllocs([]*structs.Allocation{alloc})
+	state := s1.fsm.State()
+	if err := state.UpsertEvalPlan(1000, plan); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Lookup the plans
+	get := &structs.EvalSpecificRequest{
+		EvalID:       eval.ID,
+		QueryOptions: structs.QueryOptions{Region: "global"},
+	}
+	var resp structs.EvalPlansResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Eval.Plans", get, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.Index != 1000 {
+		t.Fatalf("Bad index: %d %d", resp.Index, 1000)
+	}
+	if resp.Plans == nil || len(resp.Plans.Plans) != 1 {
+		t.Fatalf("bad: %#v", resp.Plans)
+	}
+	if stubs := resp.Plans.Plans[0].NodeAllocation[alloc.NodeID]; len(stubs) != 1 || stubs[0].ID != alloc.ID {
+		t.Fatalf("bad: %#v", resp.Plans.Plans[0])
+	}
+
+	// Lookup the plans of an unknown eval
+	get.EvalID = structs.GenerateUUID()
+	var resp2 structs.EvalPlansResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Eval.Plans", get, &resp2); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp2.Index != 1000 {
+		t.Fatalf("Bad index: %d %d", resp2.Index, 1000)
+	}
+	if resp2.Plans != nil {
+		t.Fatalf("unexpected plans: %#v", resp2.Plans)
+	}
+}
@@ -39,6 +39,11 @@ const (
 	PeriodicLaunchSnapshot
 	JobSummarySnapshot
 	VaultAccessorSnapshot
+	IdentityKeySnapshot
+	ServiceRegistrationSnapshot
+	ScalingEventSnapshot
+	ScalingPolicySnapshot
+	EvalPlansSnapshot
 )
 
 // nomadFSM implements a finite state machine that is used
@@ -153,6 +158,16 @@ func (n *nomadFSM) Apply(log *raft.Log) interface{} {
 		return n.applyUpsertVaultAccessor(buf[1:], log.Index)
 	case structs.VaultAccessorDegisterRequestType:
 		return n.applyDeregisterVaultAccessor(buf[1:], log.Index)
+	case structs.IdentityKeyUpsertRequestType:
+		return n.applyUpsertIdentityKey(buf[1:], log.Index)
+	case structs.IdentityKeyDeleteRequestType:
+		return n.applyDeleteIdentityKeys(buf[1:], log.Index)
+	case structs.ServiceRegistrationUpsertRequestType:
+		return n.applyUpsertServiceRegistrations(buf[1:], log.Index)
+	case structs.ServiceRegistrationDeleteRequestType:
+		return n.applyDeleteServiceRegistrations(buf[1:], log.Index)
+	case structs.JobScaleRequestType:
+		return n.applyJobScale(buf[1:], log.Index)
 	default:
 		if ignoreUnknown {
 			n.logger.Printf("[WARN] nomad.fsm: ignoring unknown message type (%d), upgrade to newer version", msgType)
@@ -436,6 +451,16 @@ func (n *nomadFSM) applyAllocUpdate(buf []byte, index uint64) interface{} {
 		n.logger.Printf("[ERR] nomad.fsm: UpsertAllocs failed: %v", err)
 		return err
 	}
+
+	// Record the plan the allocations were applied for
+	if plan := req.Plan; plan != nil {
+		plan.AddAllocs(req.Alloc)
+		plan.AllocIndex = index
+		if err := n.state.UpsertEvalPlan(index, plan); err != nil {
+			n.logger.Printf("[ERR] nomad.fsm: UpsertEvalPlan failed: %v", err)
+			return err
+		}
+	}
 	return nil
 }
 
@@ -526,6 +551,100 @@ func (n *nomadFSM) applyDeregisterVaultAccessor(buf []byte, index uint64) interf
 	return nil
 }
 
+// applyUpsertIdentityKey stores a key signing workload identities
+func (n *nomadFSM) applyUpsertIdentityKey(buf []byte, index uint64) interface{} {
+	defer metrics.MeasureSince([]string{"nomad", "fsm", "upsert_identity_key"}, time.Now())
+	var req structs.IdentityKeyUpsertRequest
+	if err := structs.Decode(buf, &req); err != nil {
+		panic(fmt.Errorf("failed to decode request: %v", err))
+	}
+
+	if err := n.state.UpsertIdentityKey(index, req.Key); err != nil {
+		n.logger.Printf("[ERR] nomad.fsm: UpsertIdentityKey failed: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+// applyDeleteIdentityKeys deletes a set of retired identity keys
+func (n *nomadFSM) applyDeleteIdentityKeys(buf []byte, index uint64) interface{} {
+	defer metrics.MeasureSince([]string{"nomad", "fsm", "delete_identity_keys"}, time.Now())
+	var req structs.IdentityKeyDeleteRequest
+	if err := structs.Decode(buf, &req); err != nil {
+		panic(fmt.Errorf("failed to decode request: %v", err))
+	}
+
+	if err := n.state.DeleteIdentityKeys(index, req.KeyIDs); err != nil {
+		n.logger.Printf("[ERR] nomad.fsm: DeleteIdentityKeys failed: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+// applyJobScale records a scaling event of a task group and updates its count
+func (n *nomadFSM) applyJobScale(buf []byte, index uint64) interface{} {
+	defer metrics.MeasureSince([]string{"nomad", "fsm", "scale_job"}, time.Now())
+	var req structs.ScalingEventRequest
+	if err := structs.Decode(buf, &req); err != nil {
+		panic(fmt.Errorf("failed to decode request: %v", err))
+	}
+
+	if err := n.state.ScaleJob(index, req.JobID, req.TaskGroup, req.Event); err != nil {
+		n.logger.Printf("[ERR] nomad.fsm: ScaleJob failed: %v", err)
+		return err
+	}
+
+	// Update the job tracked by the periodic dispatcher so that launched
+	// children use the new count
+	if req.Event.Count != nil {
+		job, err := n.state.JobByID(memdb.NewWatchSet(), req.JobID)
+		if err != nil {
+			n.logger.Printf("[ERR] nomad.fsm: JobByID failed: %v", err)
+			return err
+		}
+		if err := n.periodicDispatcher.Add(job); err != nil {
+			n.logger.Printf("[ERR] nomad.fsm: periodicDispatcher.Add failed: %v", err)
+			return err
+		}
+	}
+
+	return nil
+}
+
+// applyUpsertServiceRegistrations registers instances of services
+func (n *nomadFSM) applyUpsertServiceRegistrations(buf []byte, index uint64) interface{} {
+	defer metrics.MeasureSince([]string{"nomad", "fsm", "upsert_service_registrations"}, time.Now())
+	var req structs.ServiceRegistrationUpsertRequest
+	if err := structs.Decode(buf, &req); err != nil {
+		panic(fmt.Errorf("failed to decode request: %v", err))
+	}
+
+	if err := n.state.UpsertServiceRegistrations(index, req.Services); err != nil {
+		n.logger.Printf("[ERR] nomad.fsm: UpsertServiceRegistrations failed: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+// applyDeleteServiceRegistrations deregisters instances of services
+func (n *nomadFSM) applyDeleteServiceRegistrations(buf []byte, index uint64) interface{} {
+	defer metrics.MeasureSince([]string{"nomad", "fsm", "delete_service_registrations"}, time.Now())
+	var req structs.ServiceRegistrationDeleteRequest
+	if err := structs.Decode(buf, &req); err != nil {
+		panic(fmt.Errorf("failed to decode request: %v", err))
+	}
+
+	if err := n.state.DeleteServiceRegistrations(index, req.IDs); err != nil {
+		n.logger.Printf("[ERR] nomad.fsm: DeleteServiceRegistrations failed: %v", err)
+		return err
+	}
+
+	return nil
+}
+
 func (n *nomadFSM) Snapshot() (raft.FSMSnapshot, error) {
 	// Create a new snapshot
 	snap, err := n.state.Snapshot()
@@ -663,6 +782,51 @@ func (n *nomadFSM) Restore(old io.ReadCloser) error {
 				return err
 			}
 
+		case IdentityKeySnapshot:
+			key := new(structs.IdentityKey)
+			if err := dec.Decode(key); err != nil {
+				return err
+			}
+			if err := restore.IdentityKeyRestore(key); err != nil {
+				return err
+			}
+
+		case ServiceRegistrationSnapshot:
+			service := new(structs.ServiceRegistration)
+			if err := dec.Decode(service); err != nil {
+				return err
+			}
+			if err := restore.ServiceRegistrationRestore(service); err != nil {
+				return err
+			}
+
+		case ScalingEventSnapshot:
+			events := new(structs.JobScalingEvents)
+			if err := dec.Decode(events); err != nil {
+				return err
+			}
+			if err := restore.ScalingEventsRestore(events); err != nil {
+				return err
+			}
+
+		case ScalingPolicySnapshot:
+			policy := new(structs.ScalingPolicy)
+			if err := dec.Decode(policy); err != nil {
+				return err
+			}
+			if err := restore.ScalingPolicyRestore(policy); err != nil {
+				return err
+			}
+
+		case EvalPlansSnapshot:
+			plans := new(structs.EvalPlans)
+			if err := dec.Decode(plans); err != nil {
+				return err
+			}
+			if err := restore.EvalPlansRestore(plans); err != nil {
+				return err
+			}
+
 		default:
 			return fmt.Errorf("Unrecognized snapshot type: %v", msgType)
 		}
@@ -861,6 +1025,26 @@ func (s *nomadSnapshot) Persist(sink raft.SnapshotSink) error {
 		sink.Cancel()
 		return err
 	}
+	if err := s.persistIdentityKeys(sink, encoder); err != nil {
+		sink.Cancel()
+		return err
+	}
+	if err := s.persistServiceRegistrations(sink, encoder); err != nil {
+		sink.Cancel()
+		return err
+	}
+	if err := s.persistScalingEvents(sink, encoder); err != nil {
+		sink.Cancel()
+		return err
+	}
+	if err := s.persistScalingPolicies(sink, encoder); err != nil {
+		sink.Cancel()
+		return err
+	}
+	if err := s.persistEvalPlans(sink, encoder); err != nil {
+		sink.Cancel()
+		return err
+	}
 	return nil
 }
 
@@ -1081,6 +1265,131 @@ func (s *nomadSnapshot) persistVaultAccessors(sink raft.SnapshotSink,
 	return nil
 }
 
+func (s *nomadSnapshot) persistIdentityKeys(sink raft.SnapshotSink,
+	encoder *codec.Encoder) error {
+
+	ws := memdb.NewWatchSet()
+	keys, err := s.snap.IdentityKeys(ws)
+	if err != nil {
+		return err
+	}
+
+	for {
+		raw := keys.Next()
+		if raw == nil {
+			break
+		}
+
+		key := raw.(*structs.IdentityKey)
+
+		sink.Write([]byte{byte(IdentityKeySnapshot)})
+		if err := encoder.Encode(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *nomadSnapshot) persistServiceRegistrations(sink raft.SnapshotSink,
+	encoder *codec.Encoder) error {
+
+	ws := memdb.NewWatchSet()
+	services, err := s.snap.ServiceRegistrations(ws)
+	if err != nil {
+		return err
+	}
+
+	for {
+		raw := services.Next()
+		if raw == nil {
+			break
+		}
+
+		service := raw.(*structs.ServiceRegistration)
+
+		sink.Write([]byte{byte(ServiceRegistrationSnapshot)})
+		if err := encoder.Encode(service); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *nomadSnapshot) persistScalingEvents(sink raft.SnapshotSink,
+	encoder *codec.Encoder) error {
+
+	ws := memdb.NewWatchSet()
+	iter, err := s.snap.ScalingEvents(ws)
+	if err != nil {
+		return err
+	}
+
+	for {
+		raw := iter.Next()
+		if raw == nil {
+			break
+		}
+
+		events := raw.(*structs.JobScalingEvents)
+
+		sink.Write([]byte{byte(ScalingEventSnapshot)})
+		if err := encoder.Encode(events); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *nomadSnapshot) persistScalingPolicies(sink raft.SnapshotSink,
+	encoder *codec.Encoder) error {
+
+	ws := memdb.NewWatchSet()
+	iter, err := s.snap.ScalingPolicies(ws)
+	if err != nil {
+		return err
+	}
+
+	for {
+		raw := iter.Next()
+		if raw == nil {
+			break
+		}
+
+		policy := raw.(*structs.ScalingPolicy)
+
+		sink.Write([]byte{byte(ScalingPolicySnapshot)})
+		if err := encoder.Encode(policy); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *nomadSnapshot) persistEvalPlans(sink raft.SnapshotSink,
+	encoder *codec.Encoder) error {
+
+	ws := memdb.NewWatchSet()
+	iter, err := s.snap.EvalPlans(ws)
+	if err != nil {
+		return err
+	}
+
+	for {
+		raw := iter.Next()
+		if raw == nil {
+			break
+		}
+
+		plans := raw.(*structs.EvalPlans)
+
+		sink.Write([]byte{byte(EvalPlansSnapshot)})
+		if err := encoder.Encode(plans); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // Release is a no-op, as we just need to GC the pointer
 // to the state store snapshot. There is nothing to explicitly
 // cleanup.
@@ -9,6 +9,7 @@ import (
 	"time"
 
 	memdb "github.com/hashicorp/go-memdb"
+	"github.com/hashicorp/nomad/helper"
 	"github.com/hashicorp/nomad/nomad/mock"
 	"github.com/hashicorp/nomad/nomad/state"
 	"github.com/hashicorp/nomad/nomad/structs"
@@ -662,6 +663,55 @@ func TestFSM_UpsertAllocs(t *testing.T) {
 	}
 }
 
+func TestFSM_UpsertAllocs_Plan(t *testing.T) {
+	fsm := testFSM(t)
+
+	alloc := mock.Alloc()
+	stop := mock.Alloc()
+	stop.DesiredStatus = structs.AllocDesiredStatusStop
+	fsm.State().UpsertJobSummary(1, mock.JobSummary(alloc.JobID))
+	fsm.State().UpsertJobSummary(2, mock.JobSummary(stop.JobID))
+	req := structs.AllocUpdateRequest{
+		Alloc: []*structs.Allocation{alloc, stop},
+		Plan: &structs.AppliedPlan{
+			EvalID:       alloc.EvalID,
+			JobID:        alloc.JobID,
+			RefreshIndex: 1,
+		},
+	}
+	buf, err := structs.Encode(structs.AllocUpdateRequestType, req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	resp := fsm.Apply(makeLog(buf))
+	if resp != nil {
+		t.Fatalf("resp: %v", resp)
+	}
+
+	// Verify the plan is recorded with the allocations
+	ws := memdb.NewWatchSet()
+	out, err := fsm.State().EvalPlansByEval(ws, alloc.EvalID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out == nil || len(out.Plans) != 1 {
+		t.Fatalf("bad: %#v", out)
+	}
+	plan := out.Plans[0]
+	if plan.AllocIndex != 1 || plan.RefreshIndex != 1 {
+		t.Fatalf("bad: %#v", plan)
+	}
+	placed := plan.NodeAllocation[alloc.NodeID]
+	if len(placed) != 1 || placed[0].ID != alloc.ID || placed[0].CreateIndex != 1 {
+		t.Fatalf("bad: %#v", plan.NodeAllocation)
+	}
+	updated := plan.NodeUpdate[stop.NodeID]
+	if len(updated) != 1 || updated[0].ID != stop.ID {
+		t.Fatalf("bad: %#v", plan.NodeUpdate)
+	}
+}
+
 func TestFSM_UpsertAllocs_SharedJob(t *testing.T) {
 	fsm := testFSM(t)
 
@@ -987,6 +1037,188 @@ func TestFSM_DeregisterVaultAccessor(t *testing.T) {
 	}
 }
 
+func TestFSM_UpsertIdentityKey(t *testing.T) {
+	fsm := testFSM(t)
+
+	key := mock.IdentityKey()
+	req := structs.IdentityKeyUpsertRequest{
+		Key: key,
+	}
+	buf, err := structs.Encode(structs.IdentityKeyUpsertRequestType, req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	resp := fsm.Apply(makeLog(buf))
+	if resp != nil {
+		t.Fatalf("resp: %v", resp)
+	}
+
+	// Verify we are registered
+	ws := memdb.NewWatchSet()
+	out, err := fsm.State().IdentityKeyByID(ws, key.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out == nil {
+		t.Fatalf("not found!")
+	}
+	if out.CreateIndex != 1 || !out.Active {
+		t.Fatalf("bad: %#v", out)
+	}
+}
+
+func TestFSM_DeleteIdentityKeys(t *testing.T) {
+	fsm := testFSM(t)
+
+	key := mock.IdentityKey()
+	if err := fsm.State().UpsertIdentityKey(1000, key); err != nil {
+		t.Fatalf("bad: %v", err)
+	}
+
+	req := structs.IdentityKeyDeleteRequest{
+		KeyIDs: []string{key.ID},
+	}
+	buf, err := structs.Encode(structs.IdentityKeyDeleteRequestType, req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	resp := fsm.Apply(makeLog(buf))
+	if resp != nil {
+		t.Fatalf("resp: %v", resp)
+	}
+
+	ws := memdb.NewWatchSet()
+	out, err := fsm.State().IdentityKeyByID(ws, key.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out != nil {
+		t.Fatalf("not deleted!")
+	}
+}
+
+func TestFSM_UpsertServiceRegistrations(t *testing.T) {
+	fsm := testFSM(t)
+
+	service := mock.ServiceRegistration()
+	req := structs.ServiceRegistrationUpsertRequest{
+		Services: []*structs.ServiceRegistration{service},
+	}
+	buf, err := structs.Encode(structs.ServiceRegistrationUpsertRequestType, req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	resp := fsm.Apply(makeLog(buf))
+	if resp != nil {
+		t.Fatalf("resp: %v", resp)
+	}
+
+	// Verify we are registered
+	ws := memdb.NewWatchSet()
+	out, err := fsm.State().ServiceRegistrationByID(ws, service.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out == nil {
+		t.Fatalf("not found!")
+	}
+	if out.CreateIndex != 1 {
+		t.Fatalf("bad: %#v", out)
+	}
+}
+
+func TestFSM_JobScale(t *testing.T) {
+	fsm := testFSM(t)
+
+	job := mock.Job()
+	if err := fsm.State().UpsertJob(1000, job); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	req := structs.ScalingEventRequest{
+		JobID:     job.ID,
+		TaskGroup: "web",
+		Event: &structs.ScalingEvent{
+			Time:    time.Now().UnixNano(),
+			Count:   helper.Int64ToPtr(3),
+			Message: "scaling down",
+		},
+	}
+	buf, err := structs.Encode(structs.JobScaleRequestType, req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	resp := fsm.Apply(makeLog(buf))
+	if resp != nil {
+		t.Fatalf("resp: %v", resp)
+	}
+
+	// Verify the count was changed and the event recorded
+	ws := memdb.NewWatchSet()
+	out, err := fsm.State().JobByID(ws, job.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out.TaskGroups[0].Count != 3 || out.JobModifyIndex != 1 {
+		t.Fatalf("bad: %#v", out)
+	}
+	events, err := fsm.State().ScalingEventsByJob(ws, job.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if events == nil || len(events.ScalingEvents["web"]) != 1 {
+		t.Fatalf("bad: %#v", events)
+	}
+	if e := events.ScalingEvents["web"][0]; e.PreviousCount != 10 || e.CreateIndex != 1 {
+		t.Fatalf("bad: %#v", e)
+	}
+
+	// Scaling a missing task group fails
+	req.TaskGroup = "missing"
+	buf, err = structs.Encode(structs.JobScaleRequestType, req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp := fsm.Apply(makeLog(buf)); resp == nil {
+		t.Fatalf("expected error")
+	}
+}
+
+func TestFSM_DeleteServiceRegistrations(t *testing.T) {
+	fsm := testFSM(t)
+
+	service := mock.ServiceRegistration()
+	if err := fsm.State().UpsertServiceRegistrations(1000, []*structs.ServiceRegistration{service}); err != nil {
+		t.Fatalf("bad: %v", err)
+	}
+
+	req := structs.ServiceRegistrationDeleteRequest{
+		IDs: []string{service.ID},
+	}
+	buf, err := structs.Encode(structs.ServiceRegistrationDeleteRequestType, req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	resp := fsm.Apply(makeLog(buf))
+	if resp != nil {
+		t.Fatalf("resp: %v", resp)
+	}
+
+	ws := memdb.NewWatchSet()
+	out, err := fsm.State().ServiceRegistrationByID(ws, service.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out != nil {
+		t.Fatalf("not deleted!")
+	}
+}
+
 func testSnapshotRestore(t *testing.T, fsm *nomadFSM) *nomadFSM {
 	// Snapshot
 	snap, err := fsm.Snapshot()
@@ -1265,6 +1497,118 @@ func TestFSM_SnapshotRestore_VaultAccessors(t *testing.T) {
 	}
 }
 
+func TestFSM_SnapshotRestore_IdentityKeys(t *testing.T) {
+	// Add some state
+	fsm := testFSM(t)
+	state := fsm.State()
+	k1 := mock.IdentityKey()
+	k2 := mock.IdentityKey()
+	state.UpsertIdentityKey(1000, k1)
+	state.UpsertIdentityKey(1001, k2)
+
+	// Verify the contents
+	fsm2 := testSnapshotRestore(t, fsm)
+	state2 := fsm2.State()
+	ws := memdb.NewWatchSet()
+	out1, _ := state2.IdentityKeyByID(ws, k1.ID)
+	out2, _ := state2.IdentityKeyByID(ws, k2.ID)
+	if out1 == nil || out1.Active || out1.RetireIndex != 1001 {
+		t.Fatalf("bad: %#v", out1)
+	}
+	if !reflect.DeepEqual(k2, out2) {
+		t.Fatalf("bad: \n%#v\n%#v", out2, k2)
+	}
+}
+
+func TestFSM_SnapshotRestore_ServiceRegistrations(t *testing.T) {
+	// Add some state
+	fsm := testFSM(t)
+	state := fsm.State()
+	s1 := mock.ServiceRegistration()
+	s2 := mock.ServiceRegistration()
+	state.UpsertServiceRegistrations(1000, []*structs.ServiceRegistration{s1, s2})
+
+	// Verify the contents
+	fsm2 := testSnapshotRestore(t, fsm)
+	state2 := fsm2.State()
+	ws := memdb.NewWatchSet()
+	out1, _ := state2.ServiceRegistrationByID(ws, s1.ID)
+	out2, _ := state2.ServiceRegistrationByID(ws, s2.ID)
+	if !reflect.DeepEqual(s1, out1) {
+		t.Fatalf("bad: \n%#v\n%#v", out1, s1)
+	}
+	if !reflect.DeepEqual(s2, out2) {
+		t.Fatalf("bad: \n%#v\n%#v", out2, s2)
+	}
+}
+
+func TestFSM_SnapshotRestore_ScalingEvents(t *testing.T) {
+	// Add some state
+	fsm := testFSM(t)
+	state := fsm.State()
+	job := mock.Job()
+	state.UpsertJob(1000, job)
+	event := &structs.ScalingEvent{Count: helper.Int64ToPtr(2), Message: "test"}
+	state.ScaleJob(1001, job.ID, "web", event)
+
+	// Verify the contents
+	fsm2 := testSnapshotRestore(t, fsm)
+	state2 := fsm2.State()
+	ws := memdb.NewWatchSet()
+	expected, _ := state.ScalingEventsByJob(ws, job.ID)
+	out, _ := state2.ScalingEventsByJob(ws, job.ID)
+	if !reflect.DeepEqual(expected, out) {
+		t.Fatalf("bad: \n%#v\n%#v", out, expected)
+	}
+}
+
+func TestFSM_SnapshotRestore_ScalingPolicies(t *testing.T) {
+	// Add some state
+	fsm := testFSM(t)
+	state := fsm.State()
+	job := mock.Job()
+	job.TaskGroups[0].Scaling = &structs.ScalingPolicy{
+		ID:      structs.GenerateUUID(),
+		Min:     1,
+		Max:     20,
+		Enabled: true,
+		Policy: map[string]interface{}{
+			"metric":   "cpu",
+			"cooldown": "1m",
+		},
+	}
+	state.UpsertJob(1000, job)
+
+	// Verify the contents
+	fsm2 := testSnapshotRestore(t, fsm)
+	state2 := fsm2.State()
+	ws := memdb.NewWatchSet()
+	expected, _ := state.ScalingPolicyByID(ws, job.TaskGroups[0].Scaling.ID)
+	out, _ := state2.ScalingPolicyByID(ws, job.TaskGroups[0].Scaling.ID)
+	if expected == nil || !reflect.DeepEqual(expected, out) {
+		t.Fatalf("bad: \n%#v\n%#v", out, expected)
+	}
+}
+
+func TestFSM_SnapshotRestore_EvalPlans(t *testing.T) {
+	// Add some state
+	fsm := testFSM(t)
+	state := fsm.State()
+	plan := &structs.AppliedPlan{EvalID: structs.GenerateUUID()}
+	plan.AddAllocs([]*structs.Allocation{mock.Alloc()})
+	state.UpsertEvalPlan(1000, plan)
+
+	// Verify the contents
+	fsm2 := testSnapshotRestore(t, fsm)
+	state2 := fsm2.State()
+	ws := memdb.NewWatchSet()
+	expected, _ := state.EvalPlansByEval(ws, plan.EvalID)
+	out, _ := state2.EvalPlansByEval(ws, plan.EvalID)
+	if expected == nil || !reflect.DeepEqual(expected, out) {
+		t.Fatalf("bad: \n%#v\n%#v", out, expected)
+	}
+}
+
 func TestFSM_SnapshotRestore_AddMissingSummary(t *testing.T) {
 	// Add some state
 	fsm := testFSM(t)
@@ -0,0 +1,216 @@
+package nomad
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"time"
+
+	memdb "github.com/hashicorp/go-memdb"
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+const (
+	// identityKeyBits is the size of the RSA keys signing workload identities
+	identityKeyBits = 2048
+
+	// identityKeyCheckInterval is the maximum interval at which the leader
+	// checks whether the identity key should be rotated and retired keys
+	// deleted
+	identityKeyCheckInterval = 1 * time.Hour
+)
+
+// generateIdentityKey creates a new active key to sign workload identities.
+func generateIdentityKey() (*structs.IdentityKey, error) {
+	priv, err := rsa.GenerateKey(rand.Reader, identityKeyBits)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate identity key: %v", err)
+	}
+
+	return &structs.IdentityKey{
+		ID:         structs.GenerateUUID(),
+		Algorithm:  structs.IdentityKeyAlgorithmRS256,
+		PrivateKey: x509.MarshalPKCS1PrivateKey(priv),
+		Active:     true,
+		CreateTime: time.Now().UnixNano(),
+	}, nil
+}
+
+// signIdentity returns the claims encoded as a JSON Web Token signed by the
+// key.
+func signIdentity(key *structs.IdentityKey, claims *structs.IdentityClaims) (string, error) {
+	if key.Algorithm != structs.IdentityKeyAlgorithmRS256 {
+		return "", fmt.Errorf("unsupported identity key algorithm %q", key.Algorithm)
+	}
+	priv, err := x509.ParsePKCS1PrivateKey(key.PrivateKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse identity key: %v", err)
+	}
+
+	header, err := json.Marshal(map[string]string{
+		"alg": key.Algorithm,
+		"kid": key.ID,
+		"typ": "JWT",
+	})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	signed := encodeSegment(header) + "." + encodeSegment(payload)
+	digest := sha256.Sum256([]byte(signed))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, priv, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("failed to sign identity: %v", err)
+	}
+	return signed + "." + encodeSegment(sig), nil
+}
+
+// identityPublicKey returns the JSON Web Key verifying the tokens signed by
+// the key.
+func identityPublicKey(key *structs.IdentityKey) (*structs.JSONWebKey, error) {
+	priv, err := x509.ParsePKCS1PrivateKey(key.PrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse identity key %q: %v", key.ID, err)
+	}
+
+	return &structs.JSONWebKey{
+		KeyID:     key.ID,
+		KeyType:   "RSA",
+		Algorithm: key.Algorithm,
+		Use:       "sig",
+		N:         encodeSegment(priv.PublicKey.N.Bytes()),
+		E:         encodeSegment(big.NewInt(int64(priv.PublicKey.E)).Bytes()),
+	}, nil
+}
+
+// encodeSegment encodes a segment of a JSON Web Token.
+func encodeSegment(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// manageIdentityKeys is a long lived function run by the leader that creates
+// the key signing workload identities, rotates it and deletes the retired
+// keys that no longer sign the identity of a running allocation.
+func (s *Server) manageIdentityKeys(stopCh chan struct{}) {
+	interval := identityKeyCheckInterval
+	if half := s.config.IdentityKeyRotation / 2; half > 0 && half < interval {
+		interval = half
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.rotateIdentityKey(); err != nil {
+			s.logger.Printf("[ERR] nomad: failed to rotate identity key: %v", err)
+		}
+		if err := s.reapIdentityKeys(); err != nil {
+			s.logger.Printf("[ERR] nomad: failed to delete retired identity keys: %v", err)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-stopCh:
+			return
+		}
+	}
+}
+
+// rotateIdentityKey creates a new active identity key if there is none or if
+// the active key is older than the rotation period.
+func (s *Server) rotateIdentityKey() error {
+	active, err := s.fsm.State().ActiveIdentityKey(nil)
+	if err != nil {
+		return err
+	}
+	if active != nil && time.Since(time.Unix(0, active.CreateTime)) < s.config.IdentityKeyRotation {
+		return nil
+	}
+
+	key, err := generateIdentityKey()
+	if err != nil {
+		return err
+	}
+	req := structs.IdentityKeyUpsertRequest{
+		Key: key,
+		WriteRequest: structs.WriteRequest{
+			Region: s.config.Region,
+		},
+	}
+	if _, _, err := s.raftApply(structs.IdentityKeyUpsertRequestType, &req); err != nil {
+		return err
+	}
+
+	s.logger.Printf("[INFO] nomad: rotated identity key to %q", key.ID)
+	return nil
+}
+
+// reapIdentityKeys deletes the retired identity keys that may only have signed
+// the identities of allocations that have since terminated. A key only signs
+// identities for allocations created before it is retired.
+func (s *Server) reapIdentityKeys() error {
+	snap, err := s.fsm.State().Snapshot()
+	if err != nil {
+		return err
+	}
+	ws := memdb.NewWatchSet()
+
+	// Find the oldest allocation that may still use its identity
+	oldest := uint64(math.MaxUint64)
+	allocs, err := snap.Allocs(ws)
+	if err != nil {
+		return err
+	}
+	for {
+		raw := allocs.Next()
+		if raw == nil {
+			break
+		}
+		alloc := raw.(*structs.Allocation)
+		if !alloc.Terminated() && alloc.CreateIndex < oldest {
+			oldest = alloc.CreateIndex
+		}
+	}
+
+	keys, err := snap.IdentityKeys(ws)
+	if err != nil {
+		return err
+	}
+	var unused []string
+	for {
+		raw := keys.Next()
+		if raw == nil {
+			break
+		}
+		key := raw.(*structs.IdentityKey)
+		if !key.Active && key.RetireIndex <= oldest {
+			unused = append(unused, key.ID)
+		}
+	}
+	if len(unused) == 0 {
+		return nil
+	}
+
+	req := structs.IdentityKeyDeleteRequest{
+		KeyIDs: unused,
+		WriteRequest: structs.WriteRequest{
+			Region: s.config.Region,
+		},
+	}
+	if _, _, err := s.raftApply(structs.IdentityKeyDeleteRequestType, &req); err != nil {
+		return err
+	}
+
+	s.logger.Printf("[DEBUG] nomad: deleted %d retired identity keys", len(unused))
+	return nil
+}
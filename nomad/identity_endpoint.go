@@ -0,0 +1,63 @@
+package nomad
+
+import (
+	"time"
+
+	"github.com/armon/go-metrics"
+	"github.com/hashicorp/go-memdb"
+	"github.com/hashicorp/nomad/nomad/state"
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+// Identity endpoint is used to publish the keys that verify workload
+// identities
+type Identity struct {
+	srv *Server
+}
+
+// PublicKeys is used to list the public keys of the active and retired
+// identity keys
+func (i *Identity) PublicKeys(args *structs.GenericRequest,
+	reply *structs.IdentityPublicKeysResponse) error {
+	if done, err := i.srv.forward("Identity.PublicKeys", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "identity", "public_keys"}, time.Now())
+
+	// Setup the blocking query
+	opts := blockingOptions{
+		queryOpts: &args.QueryOptions,
+		queryMeta: &reply.QueryMeta,
+		run: func(ws memdb.WatchSet, state *state.StateStore) error {
+			iter, err := state.IdentityKeys(ws)
+			if err != nil {
+				return err
+			}
+
+			keys := make([]*structs.JSONWebKey, 0)
+			for {
+				raw := iter.Next()
+				if raw == nil {
+					break
+				}
+				key, err := identityPublicKey(raw.(*structs.IdentityKey))
+				if err != nil {
+					return err
+				}
+				keys = append(keys, key)
+			}
+			reply.Keys = keys
+
+			// Use the last index that affected the identity keys table
+			index, err := state.Index("identity_keys")
+			if err != nil {
+				return err
+			}
+			reply.Index = index
+
+			// Set the query response
+			i.srv.setQueryMeta(&reply.QueryMeta)
+			return nil
+		}}
+	return i.srv.blockingRPC(&opts)
+}
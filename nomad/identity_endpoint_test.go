@@ -0,0 +1,93 @@
+package nomad
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hashicorp/net-rpc-msgpackrpc"
+	"github.com/hashicorp/nomad/nomad/structs"
+	"github.com/hashicorp/nomad/testutil"
+)
+
+func TestIdentityEndpoint_PublicKeys(t *testing.T) {
+	s1 := testServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	// Wait for the leader to create the first key
+	var key *structs.IdentityKey
+	testutil.WaitForResult(func() (bool, error) {
+		var err error
+		key, err = s1.fsm.State().ActiveIdentityKey(nil)
+		return key != nil, err
+	}, func(err error) {
+		t.Fatalf("should have an identity key: %v", err)
+	})
+
+	get := &structs.GenericRequest{
+		QueryOptions: structs.QueryOptions{Region: "global"},
+	}
+	var resp structs.IdentityPublicKeysResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Identity.PublicKeys", get, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.Index != key.ModifyIndex {
+		t.Fatalf("Bad index: %d %d", resp.Index, key.ModifyIndex)
+	}
+	if len(resp.Keys) != 1 {
+		t.Fatalf("bad: %#v", resp.Keys)
+	}
+	jwk := resp.Keys[0]
+	if jwk.KeyID != key.ID || jwk.KeyType != "RSA" || jwk.Algorithm != "RS256" || jwk.Use != "sig" || jwk.N == "" || jwk.E == "" {
+		t.Fatalf("bad: %#v", jwk)
+	}
+}
+
+func TestIdentityEndpoint_PublicKeys_Blocking(t *testing.T) {
+	s1 := testServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	var key *structs.IdentityKey
+	testutil.WaitForResult(func() (bool, error) {
+		var err error
+		key, err = s1.fsm.State().ActiveIdentityKey(nil)
+		return key != nil, err
+	}, func(err error) {
+		t.Fatalf("should have an identity key: %v", err)
+	})
+
+	// Rotate the key in a delay
+	s1.config.IdentityKeyRotation = time.Nanosecond
+	time.AfterFunc(100*time.Millisecond, func() {
+		if err := s1.rotateIdentityKey(); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	})
+
+	req := &structs.GenericRequest{
+		QueryOptions: structs.QueryOptions{
+			Region:        "global",
+			MinQueryIndex: key.ModifyIndex,
+		},
+	}
+	start := time.Now()
+	var resp structs.IdentityPublicKeysResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Identity.PublicKeys", req, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Fatalf("should block (returned in %s) %#v", elapsed, resp)
+	}
+	if resp.Index <= key.ModifyIndex {
+		t.Fatalf("Bad index: %d %d", resp.Index, key.ModifyIndex)
+	}
+
+	// Both the retired and the active key are published
+	if len(resp.Keys) != 2 {
+		t.Fatalf("bad: %#v", resp.Keys)
+	}
+}
@@ -0,0 +1,199 @@
+package nomad
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	memdb "github.com/hashicorp/go-memdb"
+	"github.com/hashicorp/nomad/nomad/mock"
+	"github.com/hashicorp/nomad/nomad/structs"
+	"github.com/hashicorp/nomad/testutil"
+)
+
+// verifyIdentity verifies the token against the public key and returns its
+// claims.
+func verifyIdentity(t *testing.T, token string, jwk *structs.JSONWebKey) *structs.IdentityClaims {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		t.Fatalf("bad token: %q", token)
+	}
+
+	decode := func(s string) []byte {
+		b, err := base64.RawURLEncoding.DecodeString(s)
+		if err != nil {
+			t.Fatalf("failed to decode %q: %v", s, err)
+		}
+		return b
+	}
+
+	var header map[string]string
+	if err := json.Unmarshal(decode(parts[0]), &header); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if header["alg"] != "RS256" || header["kid"] != jwk.KeyID || header["typ"] != "JWT" {
+		t.Fatalf("bad header: %#v", header)
+	}
+
+	pub := &rsa.PublicKey{
+		N: new(big.Int).SetBytes(decode(jwk.N)),
+		E: int(new(big.Int).SetBytes(decode(jwk.E)).Int64()),
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], decode(parts[2])); err != nil {
+		t.Fatalf("failed to verify token: %v", err)
+	}
+
+	var claims structs.IdentityClaims
+	if err := json.Unmarshal(decode(parts[1]), &claims); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	return &claims
+}
+
+func TestIdentity_SignVerify(t *testing.T) {
+	key, err := generateIdentityKey()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	jwk, err := identityPublicKey(key)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	alloc := mock.Alloc()
+	claims := structs.NewIdentityClaims("global", alloc, "web", time.Now())
+	token, err := signIdentity(key, claims)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	out := verifyIdentity(t, token, jwk)
+	if !reflect.DeepEqual(out, claims) {
+		t.Fatalf("bad:\n%#v\n%#v", out, claims)
+	}
+
+	// Tampered tokens must not verify
+	other, err := generateIdentityKey()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	otherJWK, err := identityPublicKey(other)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	parts := strings.Split(token, ".")
+	pub := &rsa.PublicKey{
+		E: 65537,
+	}
+	n, _ := base64.RawURLEncoding.DecodeString(otherJWK.N)
+	pub.N = new(big.Int).SetBytes(n)
+	sig, _ := base64.RawURLEncoding.DecodeString(parts[2])
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], sig); err == nil {
+		t.Fatalf("token verified with the wrong key")
+	}
+
+	// Unknown algorithms are rejected
+	key.Algorithm = "HS256"
+	if _, err := signIdentity(key, claims); err == nil {
+		t.Fatalf("expected error")
+	}
+}
+
+func TestLeader_IdentityKey_Create(t *testing.T) {
+	s1 := testServer(t, nil)
+	defer s1.Shutdown()
+	testutil.WaitForLeader(t, s1.RPC)
+
+	// The leader creates a key on election
+	var key *structs.IdentityKey
+	testutil.WaitForResult(func() (bool, error) {
+		var err error
+		key, err = s1.fsm.State().ActiveIdentityKey(nil)
+		return key != nil, err
+	}, func(err error) {
+		t.Fatalf("should have an identity key: %v", err)
+	})
+
+	// The key is not rotated before the rotation period
+	if err := s1.rotateIdentityKey(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	active, err := s1.fsm.State().ActiveIdentityKey(nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if active.ID != key.ID {
+		t.Fatalf("key should not have been rotated")
+	}
+}
+
+func TestLeader_IdentityKey_RotateReap(t *testing.T) {
+	s1 := testServer(t, func(c *Config) {
+		c.IdentityKeyRotation = time.Hour
+	})
+	defer s1.Shutdown()
+	testutil.WaitForLeader(t, s1.RPC)
+	state := s1.fsm.State()
+
+	var key *structs.IdentityKey
+	testutil.WaitForResult(func() (bool, error) {
+		var err error
+		key, err = state.ActiveIdentityKey(nil)
+		return key != nil, err
+	}, func(err error) {
+		t.Fatalf("should have an identity key: %v", err)
+	})
+
+	// Create a running allocation signed by the first key
+	alloc := mock.Alloc()
+	if err := state.UpsertAllocs(key.ModifyIndex, []*structs.Allocation{alloc}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Age the key past the rotation period
+	s1.config.IdentityKeyRotation = time.Nanosecond
+	if err := s1.rotateIdentityKey(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	active, err := state.ActiveIdentityKey(nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if active == nil || active.ID == key.ID {
+		t.Fatalf("key should have been rotated: %#v", active)
+	}
+
+	// The retired key is kept while the allocation is running
+	if err := s1.reapIdentityKeys(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	ws := memdb.NewWatchSet()
+	if out, _ := state.IdentityKeyByID(ws, key.ID); out == nil {
+		t.Fatalf("retired key should not have been deleted")
+	}
+
+	// Once the allocation is terminal, the retired key is deleted
+	alloc = alloc.Copy()
+	alloc.ClientStatus = structs.AllocClientStatusComplete
+	if err := state.UpdateAllocsFromClient(active.ModifyIndex+1, []*structs.Allocation{alloc}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := s1.reapIdentityKeys(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out, _ := state.IdentityKeyByID(ws, key.ID); out != nil {
+		t.Fatalf("retired key should have been deleted")
+	}
+	if out, _ := state.IdentityKeyByID(ws, active.ID); out == nil {
+		t.Fatalf("active key should not have been deleted")
+	}
+}
@@ -3,6 +3,7 @@ package nomad
 import (
 	"context"
 	"fmt"
+	"net/url"
 	"strings"
 	"time"
 
@@ -26,6 +27,19 @@ const (
 	// DispatchPayloadSizeLimit is the maximum size of the uncompressed input
 	// data payload.
 	DispatchPayloadSizeLimit = 16 * 1024
+
+	// DispatchIdempotencyWindow is the period during which a dispatch with
+	// an idempotency token returns the job dispatched by a previous request
+	// with the same token.
+	DispatchIdempotencyWindow = 24 * time.Hour
+
+	// connectSidecarImage is the Docker image used to run the Envoy sidecar
+	// proxy of Connect enabled services.
+	connectSidecarImage = "envoyproxy/envoy:v1.11.2"
+
+	// envoyBootstrapPath is the path of the Envoy bootstrap configuration
+	// the client renders into the sidecar task's secrets directory.
+	envoyBootstrapPath = "${NOMAD_SECRETS_DIR}/envoy_bootstrap.json"
 )
 
 var (
@@ -61,11 +75,19 @@ func (j *Job) Register(args *structs.JobRegisterRequest, reply *structs.JobRegis
 	// Add implicit constraints
 	setImplicitConstraints(args.Job)
 
+	// Add the sidecar proxies of Connect enabled services
+	injectConnectSidecars(args.Job)
+
 	// Validate the job.
 	if err := validateJob(args.Job); err != nil {
 		return err
 	}
 
+	// Keep the IDs of the existing scaling policies
+	if err := setScalingPolicyIDs(j.srv.fsm.State(), args.Job); err != nil {
+		return err
+	}
+
 	if args.EnforceIndex {
 		// Lookup the job
 		snap, err := j.srv.fsm.State().Snapshot()
@@ -98,6 +120,18 @@ func (j *Job) Register(args *structs.JobRegisterRequest, reply *structs.JobRegis
 			return fmt.Errorf("Vault not enabled and Vault policies requested")
 		}
 
+		// Check the tasks only create tokens from roles the servers allow
+		var disallowed []string
+		for _, role := range structs.VaultRolesSet(policies) {
+			if !vconf.AllowsRole(role) {
+				disallowed = append(disallowed, role)
+			}
+		}
+		if len(disallowed) != 0 {
+			return fmt.Errorf("Vault roles not allowed by the servers requested: %s",
+				strings.Join(disallowed, ", "))
+		}
+
 		// Have to check if the user has permissions
 		if !vconf.AllowsUnauthenticated() {
 			if args.Job.VaultToken == "" {
@@ -176,6 +210,28 @@ func (j *Job) Register(args *structs.JobRegisterRequest, reply *structs.JobRegis
 	return nil
 }
 
+// setScalingPolicyIDs sets the IDs of the scaling policies of the task groups
+// of the job. The ID of the existing policy of a task group is kept, and new
+// policies are given a new ID.
+func setScalingPolicyIDs(state *state.StateStore, job *structs.Job) error {
+	for _, tg := range job.TaskGroups {
+		if tg.Scaling == nil {
+			continue
+		}
+
+		existing, err := state.ScalingPolicyByTarget(nil, job.ID, tg.Name)
+		if err != nil {
+			return err
+		}
+		if existing != nil {
+			tg.Scaling.ID = existing.ID
+		} else {
+			tg.Scaling.ID = structs.GenerateUUID()
+		}
+	}
+	return nil
+}
+
 // setImplicitConstraints adds implicit constraints to the job based on the
 // features it is requesting.
 func setImplicitConstraints(j *structs.Job) {
@@ -237,6 +293,78 @@ func setImplicitConstraints(j *structs.Job) {
 	}
 }
 
+// injectConnectSidecars adds a sidecar proxy task to every task group with a
+// Connect enabled service and allocates the port the proxy listens on for
+// inbound Connect traffic on the task registering the service. It is safe to
+// call on jobs that already contain the sidecar tasks.
+func injectConnectSidecars(j *structs.Job) {
+	for _, tg := range j.TaskGroups {
+		for _, task := range tg.Tasks {
+			for _, service := range task.Services {
+				if !service.Connect.HasSidecar() {
+					continue
+				}
+
+				// Allocate the inbound port on the task registering the service
+				// unless the user referenced an existing port.
+				if service.Connect.SidecarService.Port == "" {
+					service.Connect.SidecarService.Port = structs.ConnectProxyPortLabel(service.Name)
+				}
+				addConnectProxyPort(task, service.Connect.SidecarService.Port)
+
+				name := structs.ConnectProxyTaskName(service.Name)
+				if tg.LookupTask(name) != nil {
+					continue
+				}
+				tg.Tasks = append(tg.Tasks, newConnectSidecarTask(service.Name))
+			}
+		}
+	}
+}
+
+// addConnectProxyPort adds a dynamic port with the given label to the task if
+// it does not already have a port with that label.
+func addConnectProxyPort(task *structs.Task, label string) {
+	if task.Resources == nil {
+		task.Resources = structs.DefaultResources()
+	}
+	for _, network := range task.Resources.Networks {
+		if _, ok := network.MapLabelToValues(nil)[label]; ok {
+			return
+		}
+	}
+
+	if len(task.Resources.Networks) == 0 {
+		task.Resources.Networks = []*structs.NetworkResource{{MBits: 1}}
+	}
+	network := task.Resources.Networks[0]
+	network.DynamicPorts = append(network.DynamicPorts, structs.Port{Label: label})
+}
+
+// newConnectSidecarTask returns the Envoy sidecar proxy task for the given
+// Connect enabled service.
+func newConnectSidecarTask(service string) *structs.Task {
+	return &structs.Task{
+		Name:   structs.ConnectProxyTaskName(service),
+		Kind:   fmt.Sprintf("%s:%s", structs.ConnectProxyPrefix, service),
+		Driver: "docker",
+		Config: map[string]interface{}{
+			"image":        connectSidecarImage,
+			"network_mode": "host",
+			"args":         []interface{}{"-c", envoyBootstrapPath},
+		},
+		Resources: &structs.Resources{
+			CPU:      250,
+			MemoryMB: 128,
+		},
+		KillTimeout: structs.DefaultKillTimeout,
+		LogConfig: &structs.LogConfig{
+			MaxFiles:      2,
+			MaxFileSizeMB: 2,
+		},
+	}
+}
+
 // getSignalConstraint builds a suitable constraint based on the required
 // signals
 func getSignalConstraint(signals []string) *structs.Constraint {
@@ -625,11 +753,19 @@ func (j *Job) Plan(args *structs.JobPlanRequest, reply *structs.JobPlanResponse)
 	// Add implicit constraints
 	setImplicitConstraints(args.Job)
 
+	// Add the sidecar proxies of Connect enabled services
+	injectConnectSidecars(args.Job)
+
 	// Validate the job.
 	if err := validateJob(args.Job); err != nil {
 		return err
 	}
 
+	// Keep the IDs of the existing scaling policies
+	if err := setScalingPolicyIDs(j.srv.fsm.State(), args.Job); err != nil {
+		return err
+	}
+
 	// Acquire a snapshot of the state
 	snap, err := j.srv.fsm.State().Snapshot()
 	if err != nil {
@@ -771,7 +907,7 @@ func validateJob(job *structs.Job) error {
 		multierror.Append(validationErrors, fmt.Errorf("job type cannot be core"))
 	}
 
-	if len(job.Payload) != 0 {
+	if len(job.Payload) != 0 || job.PayloadURL != "" {
 		multierror.Append(validationErrors, fmt.Errorf("job can't be submitted with a payload, only dispatched"))
 	}
 
@@ -790,6 +926,13 @@ func (j *Job) Dispatch(args *structs.JobDispatchRequest, reply *structs.JobDispa
 		return fmt.Errorf("missing parameterized job ID")
 	}
 
+	// Serialize the dispatches with an idempotency token so that the
+	// existing children are up to date when looking for the token
+	if args.IdempotencyToken != "" {
+		j.srv.dispatchLock.Lock()
+		defer j.srv.dispatchLock.Unlock()
+	}
+
 	snap, err := j.srv.fsm.State().Snapshot()
 	if err != nil {
 		return err
@@ -812,6 +955,17 @@ func (j *Job) Dispatch(args *structs.JobDispatchRequest, reply *structs.JobDispa
 		return err
 	}
 
+	// Return the job dispatched by a previous request with the same token
+	if args.IdempotencyToken != "" {
+		existing, err := dispatchedByToken(snap, parameterizedJob.ID, args.IdempotencyToken, time.Now())
+		if err != nil {
+			return err
+		}
+		if existing != nil {
+			return dispatchReply(snap, existing, reply)
+		}
+	}
+
 	// Derive the child job and commit it via Raft
 	dispatchJob := parameterizedJob.Copy()
 	dispatchJob.ParameterizedJob = nil
@@ -829,6 +983,8 @@ func (j *Job) Dispatch(args *structs.JobDispatchRequest, reply *structs.JobDispa
 
 	// Compress the payload
 	dispatchJob.Payload = snappy.Encode(nil, args.Payload)
+	dispatchJob.PayloadURL = args.PayloadURL
+	dispatchJob.DispatchIdempotencyToken = args.IdempotencyToken
 
 	regReq := &structs.JobRegisterRequest{
 		Job:          dispatchJob,
@@ -879,11 +1035,75 @@ func (j *Job) Dispatch(args *structs.JobDispatchRequest, reply *structs.JobDispa
 	return nil
 }
 
+// dispatchedByToken returns the job dispatched from the parameterized job
+// with the given idempotency token within the idempotency window, or nil if
+// there is none.
+func dispatchedByToken(snap *state.StateSnapshot, parentID, token string, now time.Time) (*structs.Job, error) {
+	iter, err := snap.JobsByIDPrefix(nil, parentID+structs.DispatchLaunchSuffix)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		raw := iter.Next()
+		if raw == nil {
+			return nil, nil
+		}
+
+		job := raw.(*structs.Job)
+		if job.ParentID != parentID || job.DispatchIdempotencyToken != token {
+			continue
+		}
+		dispatched, err := structs.DispatchedTime(job.ID)
+		if err != nil || now.Sub(dispatched) > DispatchIdempotencyWindow {
+			continue
+		}
+		return job, nil
+	}
+}
+
+// dispatchReply sets up the reply of a dispatch returning an existing
+// dispatched job and the evaluation created when it was dispatched.
+func dispatchReply(snap *state.StateSnapshot, job *structs.Job, reply *structs.JobDispatchResponse) error {
+	reply.DispatchedJobID = job.ID
+	reply.JobCreateIndex = job.CreateIndex
+	reply.Index = job.CreateIndex
+
+	evals, err := snap.EvalsByJob(nil, job.ID)
+	if err != nil {
+		return err
+	}
+	for _, eval := range evals {
+		if eval.TriggeredBy != structs.EvalTriggerJobRegister || eval.JobModifyIndex != job.CreateIndex {
+			continue
+		}
+		reply.EvalID = eval.ID
+		reply.EvalCreateIndex = eval.CreateIndex
+		reply.Index = eval.CreateIndex
+		break
+	}
+	return nil
+}
+
 // validateDispatchRequest returns whether the request is valid given the
 // parameterized job.
 func validateDispatchRequest(req *structs.JobDispatchRequest, job *structs.Job) error {
+	// Check the payload is either inline or referenced by URL
+	if len(req.Payload) != 0 && req.PayloadURL != "" {
+		return fmt.Errorf("Payload and payload URL can't both be provided")
+	}
+	if req.PayloadURL != "" {
+		u, err := url.Parse(req.PayloadURL)
+		if err != nil {
+			return fmt.Errorf("Invalid payload URL %q: %v", req.PayloadURL, err)
+		}
+		if u.Scheme == "" {
+			return fmt.Errorf("Payload URL %q must be absolute", req.PayloadURL)
+		}
+	}
+
 	// Check the payload constraint is met
-	hasInputData := len(req.Payload) != 0
+	hasInputData := len(req.Payload) != 0 || req.PayloadURL != ""
 	if job.ParameterizedJob.Payload == structs.DispatchPayloadRequired && !hasInputData {
 		return fmt.Errorf("Payload is not provided but required by parameterized job")
 	} else if job.ParameterizedJob.Payload == structs.DispatchPayloadForbidden && hasInputData {
@@ -944,3 +1164,195 @@ func validateDispatchRequest(req *structs.JobDispatchRequest, job *structs.Job)
 
 	return nil
 }
+
+// Scale is used to change the count of a task group of a job. The count is
+// changed on the current version of the job and a scaling event is recorded
+// in its scaling history.
+func (j *Job) Scale(args *structs.JobScaleRequest, reply *structs.JobRegisterResponse) error {
+	if done, err := j.srv.forward("Job.Scale", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "job", "scale"}, time.Now())
+
+	// Validate the arguments
+	if args.JobID == "" {
+		return fmt.Errorf("missing job ID for scaling")
+	}
+	if args.TaskGroup == "" {
+		return fmt.Errorf("missing task group for scaling")
+	}
+	if args.Count == nil && args.Message == "" {
+		return fmt.Errorf("scaling request must set a count or a message")
+	}
+	if args.Count != nil && *args.Count < 0 {
+		return fmt.Errorf("scaling count can not be negative: %d", *args.Count)
+	}
+
+	// Lookup the job
+	snap, err := j.srv.fsm.State().Snapshot()
+	if err != nil {
+		return err
+	}
+	ws := memdb.NewWatchSet()
+	job, err := snap.JobByID(ws, args.JobID)
+	if err != nil {
+		return err
+	}
+	if job == nil {
+		return fmt.Errorf("job %q not found", args.JobID)
+	}
+	tg := job.LookupTaskGroup(args.TaskGroup)
+	if tg == nil {
+		return fmt.Errorf("task group %q not found in job %q", args.TaskGroup, args.JobID)
+	}
+	if args.Count != nil && job.Type == structs.JobTypeSystem {
+		return fmt.Errorf("can not scale system job %q", args.JobID)
+	}
+	if p := tg.Scaling; args.Count != nil && p != nil && (*args.Count < p.Min || *args.Count > p.Max) {
+		return fmt.Errorf("scaling count %d is outside of the bounds [%d, %d] of the scaling policy",
+			*args.Count, p.Min, p.Max)
+	}
+
+	// Only changes of the count of jobs that are scheduled need an evaluation
+	var eval *structs.Evaluation
+	if args.Count != nil && !job.IsPeriodic() && !job.IsParameterized() {
+		eval = &structs.Evaluation{
+			ID:          structs.GenerateUUID(),
+			Priority:    job.Priority,
+			Type:        job.Type,
+			TriggeredBy: structs.EvalTriggerScaling,
+			JobID:       job.ID,
+			Status:      structs.EvalStatusPending,
+		}
+	}
+
+	event := &structs.ScalingEvent{
+		Time:    time.Now().UnixNano(),
+		Count:   args.Count,
+		Message: args.Message,
+		Error:   args.Error,
+		Meta:    args.Meta,
+	}
+	if eval != nil {
+		event.EvalID = eval.ID
+	}
+	req := &structs.ScalingEventRequest{
+		JobID:        args.JobID,
+		TaskGroup:    args.TaskGroup,
+		Event:        event,
+		WriteRequest: args.WriteRequest,
+	}
+
+	// Commit the scaling via Raft
+	resp, index, err := j.srv.raftApply(structs.JobScaleRequestType, req)
+	if err != nil {
+		j.srv.logger.Printf("[ERR] nomad.job: Scale failed: %v", err)
+		return err
+	}
+	if err, ok := resp.(error); ok && err != nil {
+		return err
+	}
+
+	// Populate the reply with job information
+	reply.Index = index
+	if args.Count != nil {
+		reply.JobModifyIndex = index
+	}
+	if eval == nil {
+		return nil
+	}
+
+	// Commit the evaluation via Raft
+	eval.JobModifyIndex = index
+	update := &structs.EvalUpdateRequest{
+		Evals:        []*structs.Evaluation{eval},
+		WriteRequest: structs.WriteRequest{Region: args.Region},
+	}
+	_, evalIndex, err := j.srv.raftApply(structs.EvalUpdateRequestType, update)
+	if err != nil {
+		j.srv.logger.Printf("[ERR] nomad.job: Eval create failed: %v", err)
+		return err
+	}
+
+	// Populate the reply with eval information
+	reply.EvalID = eval.ID
+	reply.EvalCreateIndex = evalIndex
+	reply.Index = evalIndex
+	return nil
+}
+
+// ScaleStatus is used to retrieve the count, allocations and scaling history
+// of the task groups of a job
+func (j *Job) ScaleStatus(args *structs.JobSpecificRequest,
+	reply *structs.JobScaleStatusResponse) error {
+	if done, err := j.srv.forward("Job.ScaleStatus", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "job", "scale_status"}, time.Now())
+
+	// Setup the blocking query
+	opts := blockingOptions{
+		queryOpts: &args.QueryOptions,
+		queryMeta: &reply.QueryMeta,
+		run: func(ws memdb.WatchSet, state *state.StateStore) error {
+			job, err := state.JobByID(ws, args.JobID)
+			if err != nil {
+				return err
+			}
+			if job == nil {
+				reply.JobScaleStatus = nil
+
+				// Use the last index that affected the jobs table
+				index, err := state.Index("jobs")
+				if err != nil {
+					return err
+				}
+				reply.Index = index
+				j.srv.setQueryMeta(&reply.QueryMeta)
+				return nil
+			}
+
+			summary, err := state.JobSummaryByID(ws, args.JobID)
+			if err != nil {
+				return err
+			}
+			events, err := state.ScalingEventsByJob(ws, args.JobID)
+			if err != nil {
+				return err
+			}
+
+			// Setup the output
+			status := &structs.JobScaleStatus{
+				JobID:          job.ID,
+				JobCreateIndex: job.CreateIndex,
+				JobModifyIndex: job.JobModifyIndex,
+				TaskGroups:     make(map[string]*structs.TaskGroupScaleStatus, len(job.TaskGroups)),
+			}
+			reply.Index = job.ModifyIndex
+			for _, tg := range job.TaskGroups {
+				tgStatus := &structs.TaskGroupScaleStatus{Desired: tg.Count}
+				if summary != nil {
+					tgSummary := summary.Summary[tg.Name]
+					tgStatus.Placed = tgSummary.Starting + tgSummary.Running
+					tgStatus.Running = tgSummary.Running
+					tgStatus.Queued = tgSummary.Queued
+				}
+				if events != nil {
+					tgStatus.Events = events.ScalingEvents[tg.Name]
+				}
+				status.TaskGroups[tg.Name] = tgStatus
+			}
+			if summary != nil && summary.ModifyIndex > reply.Index {
+				reply.Index = summary.ModifyIndex
+			}
+			if events != nil && events.ModifyIndex > reply.Index {
+				reply.Index = events.ModifyIndex
+			}
+			reply.JobScaleStatus = status
+
+			// Set the query response
+			j.srv.setQueryMeta(&reply.QueryMeta)
+			return nil
+		}}
+	return j.srv.blockingRPC(&opts)
+}
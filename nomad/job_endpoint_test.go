@@ -9,6 +9,7 @@ import (
 
 	memdb "github.com/hashicorp/go-memdb"
 	"github.com/hashicorp/net-rpc-msgpackrpc"
+	"github.com/hashicorp/nomad/helper"
 	"github.com/hashicorp/nomad/nomad/mock"
 	"github.com/hashicorp/nomad/nomad/structs"
 	"github.com/hashicorp/nomad/testutil"
@@ -89,6 +90,71 @@ func TestJobEndpoint_Register(t *testing.T) {
 	}
 }
 
+func TestJobEndpoint_Register_ScalingPolicy(t *testing.T) {
+	s1 := testServer(t, func(c *Config) {
+		c.NumSchedulers = 0 // Prevent automatic dequeue
+	})
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	// Create the register request
+	job := mock.Job()
+	job.TaskGroups[0].Scaling = &structs.ScalingPolicy{Min: 1, Max: 20, Enabled: true}
+	req := &structs.JobRegisterRequest{
+		Job:          job,
+		WriteRequest: structs.WriteRequest{Region: "global"},
+	}
+
+	// Fetch the response
+	var resp structs.JobRegisterResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Job.Register", req, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Check the policy is created and targets the task group
+	state := s1.fsm.State()
+	policy, err := state.ScalingPolicyByTarget(nil, job.ID, "web")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if policy == nil || policy.ID == "" || policy.Max != 20 {
+		t.Fatalf("bad: %#v", policy)
+	}
+
+	// Updating the job keeps the ID of the policy
+	job2 := mock.Job()
+	job2.ID = job.ID
+	job2.TaskGroups[0].Scaling = &structs.ScalingPolicy{Min: 1, Max: 30, Enabled: true}
+	req.Job = job2
+	if err := msgpackrpc.CallWithCodec(codec, "Job.Register", req, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	out, err := state.ScalingPolicyByTarget(nil, job.ID, "web")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out == nil || out.ID != policy.ID || out.Max != 30 {
+		t.Fatalf("bad: %#v", out)
+	}
+	stored, err := state.JobByID(nil, job.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if stored.TaskGroups[0].Scaling.ID != policy.ID {
+		t.Fatalf("bad: %#v", stored.TaskGroups[0].Scaling)
+	}
+
+	// A count outside of the bounds of the policy is invalid
+	job3 := mock.Job()
+	job3.TaskGroups[0].Scaling = &structs.ScalingPolicy{Min: 1, Max: 5, Enabled: true}
+	req.Job = job3
+	err = msgpackrpc.CallWithCodec(codec, "Job.Register", req, &resp)
+	if err == nil || !strings.Contains(err.Error(), "outside of the bounds") {
+		t.Fatalf("expected bounds error, got: %v", err)
+	}
+}
+
 func TestJobEndpoint_Register_InvalidDriverConfig(t *testing.T) {
 	s1 := testServer(t, func(c *Config) {
 		c.NumSchedulers = 0 // Prevent automatic dequeue
@@ -147,6 +213,76 @@ func TestJobEndpoint_Register_Payload(t *testing.T) {
 	}
 }
 
+func TestJobEndpoint_Register_Connect(t *testing.T) {
+	s1 := testServer(t, func(c *Config) {
+		c.NumSchedulers = 0 // Prevent automatic dequeue
+	})
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	// Create the register request with a Connect enabled service
+	job := mock.Job()
+	job.TaskGroups[0].Tasks[0].Services[0].Connect = &structs.ConsulConnect{
+		SidecarService: &structs.ConsulSidecarService{
+			Proxy: &structs.ConsulProxy{
+				Upstreams: []*structs.ConsulUpstream{
+					{DestinationName: "db", LocalBindPort: 9191},
+				},
+			},
+		},
+	}
+	req := &structs.JobRegisterRequest{
+		Job:          job,
+		WriteRequest: structs.WriteRequest{Region: "global"},
+	}
+
+	// Register the job twice to ensure the sidecar is only injected once
+	var resp structs.JobRegisterResponse
+	for i := 0; i < 2; i++ {
+		if err := msgpackrpc.CallWithCodec(codec, "Job.Register", req, &resp); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+
+	state := s1.fsm.State()
+	ws := memdb.NewWatchSet()
+	out, err := state.JobByID(ws, job.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out == nil {
+		t.Fatalf("expected job")
+	}
+
+	tg := out.TaskGroups[0]
+	if len(tg.Tasks) != 2 {
+		t.Fatalf("expected sidecar task to be injected once; got %d tasks", len(tg.Tasks))
+	}
+	sidecar := tg.LookupTask("connect-proxy-web-frontend")
+	if sidecar == nil {
+		t.Fatalf("expected sidecar task; got %#v", tg.Tasks)
+	}
+	if service, ok := sidecar.ConnectProxyService(); !ok || service != "web-frontend" {
+		t.Fatalf("bad sidecar kind: %q", sidecar.Kind)
+	}
+	if sidecar.Driver != "docker" {
+		t.Fatalf("bad sidecar driver: %q", sidecar.Driver)
+	}
+
+	// The inbound port of the proxy is allocated on the registering task
+	task := tg.Tasks[0]
+	if port := task.Services[0].Connect.SidecarService.Port; port != "connect-proxy-web-frontend" {
+		t.Fatalf("bad sidecar port: %q", port)
+	}
+	if _, ok := task.Resources.Networks[0].MapLabelToValues(nil)["connect-proxy-web-frontend"]; !ok {
+		t.Fatalf("expected sidecar port on task; got %#v", task.Resources.Networks[0])
+	}
+	if n := len(task.Resources.Networks[0].DynamicPorts); n != 3 {
+		t.Fatalf("expected sidecar port to be added once; got %d dynamic ports", n)
+	}
+}
+
 func TestJobEndpoint_Register_Existing(t *testing.T) {
 	s1 := testServer(t, func(c *Config) {
 		c.NumSchedulers = 0 // Prevent automatic dequeue
@@ -517,6 +653,63 @@ func TestJobEndpoint_Register_Vault_AllowUnauthenticated(t *testing.T) {
 	}
 }
 
+func TestJobEndpoint_Register_Vault_Role(t *testing.T) {
+	s1 := testServer(t, func(c *Config) {
+		c.NumSchedulers = 0 // Prevent automatic dequeue
+	})
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	// Enable vault, allow authenticated and allow a role
+	tr := true
+	s1.config.VaultConfig.Enabled = &tr
+	s1.config.VaultConfig.AllowUnauthenticated = &tr
+	s1.config.VaultConfig.AllowedRoles = []string{"web"}
+
+	// Replace the Vault Client on the server
+	s1.vault = &TestVaultClient{}
+
+	// Create the register request with a job asking for a role that isn't
+	// allowed
+	job := mock.Job()
+	job.TaskGroups[0].Tasks[0].Vault = &structs.Vault{
+		Policies:   []string{"foo"},
+		ChangeMode: structs.VaultChangeModeRestart,
+		Role:       "batch",
+	}
+	req := &structs.JobRegisterRequest{
+		Job:          job,
+		WriteRequest: structs.WriteRequest{Region: "global"},
+	}
+
+	var resp structs.JobRegisterResponse
+	err := msgpackrpc.CallWithCodec(codec, "Job.Register", req, &resp)
+	if err == nil || !strings.Contains(err.Error(), "roles not allowed") || !strings.Contains(err.Error(), "batch") {
+		t.Fatalf("expected role not allowed error: %v", err)
+	}
+
+	// Ask for the allowed role
+	job.TaskGroups[0].Tasks[0].Vault.Role = "web"
+	if err := msgpackrpc.CallWithCodec(codec, "Job.Register", req, &resp); err != nil {
+		t.Fatalf("bad: %v", err)
+	}
+
+	// Check for the job in the FSM
+	state := s1.fsm.State()
+	ws := memdb.NewWatchSet()
+	out, err := state.JobByID(ws, job.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out == nil {
+		t.Fatalf("expected job")
+	}
+	if role := out.TaskGroups[0].Tasks[0].Vault.Role; role != "web" {
+		t.Fatalf("bad role: %q", role)
+	}
+}
+
 func TestJobEndpoint_Register_Vault_NoToken(t *testing.T) {
 	s1 := testServer(t, func(c *Config) {
 		c.NumSchedulers = 0 // Prevent automatic dequeue
@@ -1970,6 +2163,16 @@ func TestJobEndpoint_Dispatch(t *testing.T) {
 	reqInputDataTooLarge := &structs.JobDispatchRequest{
 		Payload: make([]byte, DispatchPayloadSizeLimit+100),
 	}
+	reqInputURL := &structs.JobDispatchRequest{
+		PayloadURL: "https://example.com/payload.json",
+	}
+	reqInputDataAndURL := &structs.JobDispatchRequest{
+		Payload:    []byte("hello world"),
+		PayloadURL: "https://example.com/payload.json",
+	}
+	reqRelativeURL := &structs.JobDispatchRequest{
+		PayloadURL: "payload.json",
+	}
 
 	type testCase struct {
 		name             string
@@ -2057,6 +2260,33 @@ func TestJobEndpoint_Dispatch(t *testing.T) {
 			err:              true,
 			errStr:           "Payload exceeds maximum size",
 		},
+		{
+			name:             "require input data w/ URL",
+			parameterizedJob: d2,
+			dispatchReq:      reqInputURL,
+			err:              false,
+		},
+		{
+			name:             "disallow input data w/ URL",
+			parameterizedJob: d3,
+			dispatchReq:      reqInputURL,
+			err:              true,
+			errStr:           "provided but forbidden",
+		},
+		{
+			name:             "optional input w/ data and URL",
+			parameterizedJob: d1,
+			dispatchReq:      reqInputDataAndURL,
+			err:              true,
+			errStr:           "can't both be provided",
+		},
+		{
+			name:             "optional input w/ relative URL",
+			parameterizedJob: d1,
+			dispatchReq:      reqRelativeURL,
+			err:              true,
+			errStr:           "must be absolute",
+		},
 		{
 			name:             "periodic job dispatched, ensure no eval",
 			parameterizedJob: d6,
@@ -2129,6 +2359,9 @@ func TestJobEndpoint_Dispatch(t *testing.T) {
 				if out.ParentID != tc.parameterizedJob.ID {
 					t.Fatalf("bad parent ID")
 				}
+				if out.PayloadURL != tc.dispatchReq.PayloadURL {
+					t.Fatalf("bad payload URL: %q", out.PayloadURL)
+				}
 
 				if tc.noEval {
 					return
@@ -2156,3 +2389,234 @@ func TestJobEndpoint_Dispatch(t *testing.T) {
 		})
 	}
 }
+
+func TestJobEndpoint_Dispatch_IdempotencyToken(t *testing.T) {
+	s1 := testServer(t, func(c *Config) {
+		c.NumSchedulers = 0 // Prevent automatic dequeue
+	})
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	// Register a parameterized job
+	job := mock.Job()
+	job.Type = structs.JobTypeBatch
+	job.ParameterizedJob = &structs.ParameterizedJobConfig{}
+	regReq := &structs.JobRegisterRequest{
+		Job:          job,
+		WriteRequest: structs.WriteRequest{Region: "global"},
+	}
+	var regResp structs.JobRegisterResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Job.Register", regReq, &regResp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Dispatch it with a token
+	req := &structs.JobDispatchRequest{
+		JobID:            job.ID,
+		IdempotencyToken: "foo",
+		WriteRequest:     structs.WriteRequest{Region: "global"},
+	}
+	var resp structs.JobDispatchResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Job.Dispatch", req, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.DispatchedJobID == "" || resp.EvalID == "" {
+		t.Fatalf("bad response: %#v", resp)
+	}
+
+	// Dispatching with the same token returns the same job and eval
+	var resp2 structs.JobDispatchResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Job.Dispatch", req, &resp2); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp2.DispatchedJobID != resp.DispatchedJobID || resp2.EvalID != resp.EvalID ||
+		resp2.JobCreateIndex != resp.JobCreateIndex || resp2.EvalCreateIndex != resp.EvalCreateIndex {
+		t.Fatalf("bad response: %#v; want %#v", resp2, resp)
+	}
+
+	// Dispatching with another token creates a new job
+	req.IdempotencyToken = "bar"
+	var resp3 structs.JobDispatchResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Job.Dispatch", req, &resp3); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp3.DispatchedJobID == resp.DispatchedJobID {
+		t.Fatalf("expected a new job")
+	}
+
+	state := s1.fsm.State()
+	out, err := state.JobByID(nil, resp3.DispatchedJobID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out == nil || out.DispatchIdempotencyToken != "bar" {
+		t.Fatalf("bad job: %#v", out)
+	}
+
+	// The token is ignored outside of the idempotency window
+	snap, err := state.Snapshot()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	existing, err := dispatchedByToken(snap, job.ID, "foo", time.Now().Add(DispatchIdempotencyWindow+time.Minute))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if existing != nil {
+		t.Fatalf("unexpected job: %#v", existing)
+	}
+}
+
+func TestJobEndpoint_Scale(t *testing.T) {
+	s1 := testServer(t, func(c *Config) {
+		c.NumSchedulers = 0 // Prevent automatic dequeue
+	})
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	// Register the job
+	job := mock.Job()
+	reg := &structs.JobRegisterRequest{
+		Job:          job,
+		WriteRequest: structs.WriteRequest{Region: "global"},
+	}
+	var regResp structs.JobRegisterResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Job.Register", reg, &regResp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Scale the task group
+	req := &structs.JobScaleRequest{
+		JobID:        job.ID,
+		TaskGroup:    "web",
+		Count:        helper.Int64ToPtr(13),
+		Message:      "scaling up",
+		Meta:         map[string]interface{}{"source": "test"},
+		WriteRequest: structs.WriteRequest{Region: "global"},
+	}
+	var resp structs.JobRegisterResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Job.Scale", req, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.JobModifyIndex <= regResp.JobModifyIndex {
+		t.Fatalf("bad job modify index: %d", resp.JobModifyIndex)
+	}
+
+	// Check the count was changed
+	state := s1.fsm.State()
+	ws := memdb.NewWatchSet()
+	out, err := state.JobByID(ws, job.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out.TaskGroups[0].Count != 13 {
+		t.Fatalf("bad count: %d", out.TaskGroups[0].Count)
+	}
+	if out.JobModifyIndex != resp.JobModifyIndex {
+		t.Fatalf("bad job modify index: %d", out.JobModifyIndex)
+	}
+
+	// Lookup the evaluation
+	eval, err := state.EvalByID(ws, resp.EvalID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if eval == nil {
+		t.Fatalf("expected eval")
+	}
+	if eval.TriggeredBy != structs.EvalTriggerScaling || eval.JobModifyIndex != resp.JobModifyIndex {
+		t.Fatalf("bad: %#v", eval)
+	}
+
+	// Record an error without changing the count
+	req.Count = nil
+	req.Message = "metrics unavailable"
+	req.Error = true
+	req.Meta = nil
+	var resp2 structs.JobRegisterResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Job.Scale", req, &resp2); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp2.EvalID != "" {
+		t.Fatalf("unexpected eval: %s", resp2.EvalID)
+	}
+
+	// Check the scaling status
+	get := &structs.JobSpecificRequest{
+		JobID:        job.ID,
+		QueryOptions: structs.QueryOptions{Region: "global"},
+	}
+	var status structs.JobScaleStatusResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Job.ScaleStatus", get, &status); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if status.Index != resp2.Index {
+		t.Fatalf("bad index: %d %d", status.Index, resp2.Index)
+	}
+	tg := status.JobScaleStatus.TaskGroups["web"]
+	if tg == nil || tg.Desired != 13 || len(tg.Events) != 2 {
+		t.Fatalf("bad status: %#v", tg)
+	}
+	first, second := tg.Events[1], tg.Events[0]
+	if *first.Count != 13 || first.PreviousCount != 10 || first.Message != "scaling up" ||
+		first.EvalID != resp.EvalID || first.Meta["source"] != "test" || first.Error {
+		t.Fatalf("bad event: %#v", first)
+	}
+	if second.Count != nil || second.PreviousCount != 13 || !second.Error || second.Message != "metrics unavailable" {
+		t.Fatalf("bad event: %#v", second)
+	}
+}
+
+func TestJobEndpoint_Scale_Invalid(t *testing.T) {
+	s1 := testServer(t, func(c *Config) {
+		c.NumSchedulers = 0 // Prevent automatic dequeue
+	})
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	job := mock.Job()
+	job.TaskGroups[0].Scaling = &structs.ScalingPolicy{Min: 1, Max: 20, Enabled: true}
+	system := mock.SystemJob()
+	for _, j := range []*structs.Job{job, system} {
+		reg := &structs.JobRegisterRequest{
+			Job:          j,
+			WriteRequest: structs.WriteRequest{Region: "global"},
+		}
+		var resp structs.JobRegisterResponse
+		if err := msgpackrpc.CallWithCodec(codec, "Job.Register", reg, &resp); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+
+	cases := []struct {
+		JobID     string
+		TaskGroup string
+		Count     *int64
+		Expected  string
+	}{
+		{"", "web", helper.Int64ToPtr(1), "missing job ID"},
+		{job.ID, "", helper.Int64ToPtr(1), "missing task group"},
+		{job.ID, "web", nil, "must set a count or a message"},
+		{job.ID, "web", helper.Int64ToPtr(-1), "can not be negative"},
+		{"missing", "web", helper.Int64ToPtr(1), "not found"},
+		{job.ID, "missing", helper.Int64ToPtr(1), `task group "missing" not found`},
+		{system.ID, "web", helper.Int64ToPtr(2), "can not scale system job"},
+		{job.ID, "web", helper.Int64ToPtr(25), "outside of the bounds"},
+	}
+	for _, c := range cases {
+		req := &structs.JobScaleRequest{
+			JobID:        c.JobID,
+			TaskGroup:    c.TaskGroup,
+			Count:        c.Count,
+			WriteRequest: structs.WriteRequest{Region: "global"},
+		}
+		var resp structs.JobRegisterResponse
+		err := msgpackrpc.CallWithCodec(codec, "Job.Scale", req, &resp)
+		if err == nil || !strings.Contains(err.Error(), c.Expected) {
+			t.Fatalf("expected error containing %q; got %v", c.Expected, err)
+		}
+	}
+}
@@ -162,6 +162,14 @@ func (s *Server) establishLeadership(stopCh chan struct{}) error {
 	// Periodically unblock failed allocations
 	go s.periodicUnblockFailedEvals(stopCh)
 
+	// Create and rotate the key signing workload identities
+	go s.manageIdentityKeys(stopCh)
+
+	// Scale task groups according to their scaling policies
+	if s.config.AutoscalerEnabled {
+		go s.runAutoscaler(stopCh)
+	}
+
 	// Setup the heartbeat timers. This is done both when starting up or when
 	// a leader fail over happens. Since the timers are maintained by the leader
 	// node, effectively this means all the timers are renewed at the time of failover.
@@ -307,6 +307,32 @@ func VaultAccessor() *structs.VaultAccessor {
 	}
 }
 
+func IdentityKey() *structs.IdentityKey {
+	return &structs.IdentityKey{
+		ID:         structs.GenerateUUID(),
+		Algorithm:  structs.IdentityKeyAlgorithmRS256,
+		PrivateKey: []byte("private"),
+		Active:     true,
+		CreateTime: time.Now().UnixNano(),
+	}
+}
+
+func ServiceRegistration() *structs.ServiceRegistration {
+	return &structs.ServiceRegistration{
+		ID:          structs.GenerateUUID(),
+		ServiceName: "web",
+		NodeID:      structs.GenerateUUID(),
+		JobID:       "example",
+		AllocID:     structs.GenerateUUID(),
+		TaskName:    "web",
+		Datacenter:  "dc1",
+		Tags:        []string{"http"},
+		Address:     "10.0.0.1",
+		Port:        8080,
+		Status:      "passing",
+	}
+}
+
 func Plan() *structs.Plan {
 	return &structs.Plan{
 		Priority: 50,
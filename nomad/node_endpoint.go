@@ -1036,6 +1036,20 @@ func (n *Node) DeriveVaultToken(args *structs.DeriveVaultTokenRequest,
 		return nil
 	}
 
+	// The allowed roles may have changed since the job was registered
+	var disallowed []string
+	for _, task := range args.Tasks {
+		if role := tg[task].Role; !n.srv.config.VaultConfig.AllowsRole(role) {
+			disallowed = append(disallowed, role)
+		}
+	}
+	if len(disallowed) != 0 {
+		e := fmt.Errorf("Requested Vault tokens from roles not allowed by the servers: %s",
+			strings.Join(disallowed, ", "))
+		setErr(e, false)
+		return nil
+	}
+
 	// At this point the request is valid and we should contact Vault for
 	// tokens.
 
@@ -1108,6 +1122,7 @@ func (n *Node) DeriveVaultToken(args *structs.DeriveVaultTokenRequest,
 			NodeID:      alloc.NodeID,
 			AllocID:     alloc.ID,
 			CreationTTL: w.TTL,
+			Role:        tg[task].Role,
 		}
 
 		accessors = append(accessors, accessor)
@@ -1152,3 +1167,132 @@ func (n *Node) DeriveVaultToken(args *structs.DeriveVaultTokenRequest,
 	n.srv.setQueryMeta(&reply.QueryMeta)
 	return nil
 }
+
+// DeriveIdentityToken is used by the clients to request the signed workload
+// identity tokens of tasks
+func (n *Node) DeriveIdentityToken(args *structs.DeriveIdentityTokenRequest,
+	reply *structs.DeriveIdentityTokenResponse) error {
+
+	// setErr is a helper for setting the recoverable error on the reply and
+	// logging it
+	setErr := func(e error, recoverable bool) {
+		if e == nil {
+			return
+		}
+		reply.Error = structs.NewRecoverableError(e, recoverable).(*structs.RecoverableError)
+		n.srv.logger.Printf("[ERR] nomad.client: DeriveIdentityToken failed (recoverable %v): %v", recoverable, e)
+	}
+
+	if done, err := n.srv.forward("Node.DeriveIdentityToken", args, args, reply); done {
+		setErr(err, structs.IsRecoverable(err) || err == structs.ErrNoLeader)
+		return nil
+	}
+	defer metrics.MeasureSince([]string{"nomad", "client", "derive_identity_token"}, time.Now())
+
+	// Verify the arguments
+	if args.NodeID == "" {
+		setErr(fmt.Errorf("missing node ID"), false)
+		return nil
+	}
+	if args.SecretID == "" {
+		setErr(fmt.Errorf("missing node SecretID"), false)
+		return nil
+	}
+	if args.AllocID == "" {
+		setErr(fmt.Errorf("missing allocation ID"), false)
+		return nil
+	}
+	if len(args.Tasks) == 0 {
+		setErr(fmt.Errorf("no tasks specified"), false)
+		return nil
+	}
+
+	// Verify the following:
+	// * The Node exists and has the correct SecretID
+	// * The Allocation exists on the specified node
+	// * The allocation contains the given tasks and they each have an
+	//   identity
+	snap, err := n.srv.fsm.State().Snapshot()
+	if err != nil {
+		setErr(err, false)
+		return nil
+	}
+	ws := memdb.NewWatchSet()
+	node, err := snap.NodeByID(ws, args.NodeID)
+	if err != nil {
+		setErr(err, false)
+		return nil
+	}
+	if node == nil {
+		setErr(fmt.Errorf("Node %q does not exist", args.NodeID), false)
+		return nil
+	}
+	if node.SecretID != args.SecretID {
+		setErr(fmt.Errorf("SecretID mismatch"), false)
+		return nil
+	}
+
+	alloc, err := snap.AllocByID(ws, args.AllocID)
+	if err != nil {
+		setErr(err, false)
+		return nil
+	}
+	if alloc == nil {
+		setErr(fmt.Errorf("Allocation %q does not exist", args.AllocID), false)
+		return nil
+	}
+	if alloc.NodeID != args.NodeID {
+		setErr(fmt.Errorf("Allocation %q not running on Node %q", args.AllocID, args.NodeID), false)
+		return nil
+	}
+	if alloc.TerminalStatus() {
+		setErr(fmt.Errorf("Can't request identity token for terminal allocation"), false)
+		return nil
+	}
+
+	tg := alloc.Job.LookupTaskGroup(alloc.TaskGroup)
+	if tg == nil {
+		setErr(fmt.Errorf("Allocation %q has no task group %q", alloc.ID, alloc.TaskGroup), false)
+		return nil
+	}
+	var unneeded []string
+	for _, task := range args.Tasks {
+		if t := tg.LookupTask(task); t == nil || t.Identity == nil {
+			unneeded = append(unneeded, task)
+		}
+	}
+	if len(unneeded) != 0 {
+		e := fmt.Errorf("Requested identity tokens for tasks without an identity: %s",
+			strings.Join(unneeded, ", "))
+		setErr(e, false)
+		return nil
+	}
+
+	// The leader creates the key when it is elected so it may not exist yet
+	key, err := snap.ActiveIdentityKey(ws)
+	if err != nil {
+		setErr(err, false)
+		return nil
+	}
+	if key == nil {
+		setErr(fmt.Errorf("no identity key to sign with"), true)
+		return nil
+	}
+
+	now := time.Now()
+	tokens := make(map[string]string, len(args.Tasks))
+	for _, task := range args.Tasks {
+		claims := structs.NewIdentityClaims(n.srv.config.Region, alloc, task, now)
+		token, err := signIdentity(key, claims)
+		if err != nil {
+			setErr(err, false)
+			return nil
+		}
+		tokens[task] = token
+	}
+
+	reply.Index = key.ModifyIndex
+	reply.Tasks = tokens
+	n.srv.setQueryMeta(&reply.QueryMeta)
+	return nil
+}
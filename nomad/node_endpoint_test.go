@@ -1979,6 +1979,89 @@ func TestClientEndpoint_DeriveVaultToken(t *testing.T) {
 	}
 }
 
+func TestClientEndpoint_DeriveVaultToken_Role(t *testing.T) {
+	s1 := testServer(t, nil)
+	defer s1.Shutdown()
+	state := s1.fsm.State()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	// Enable vault, allow authenticated and allow a role
+	tr := true
+	s1.config.VaultConfig.Enabled = &tr
+	s1.config.VaultConfig.AllowUnauthenticated = &tr
+	s1.config.VaultConfig.AllowedRoles = []string{"web"}
+
+	// Replace the Vault Client on the server
+	tvc := &TestVaultClient{}
+	s1.vault = tvc
+
+	// Create the node
+	node := mock.Node()
+	if err := state.UpsertNode(2, node); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Create an allocation whose task creates its token from a role
+	alloc := mock.Alloc()
+	alloc.NodeID = node.ID
+	task := alloc.Job.TaskGroups[0].Tasks[0]
+	tasks := []string{task.Name}
+	task.Vault = &structs.Vault{Policies: []string{"a", "b"}, Role: "web"}
+	if err := state.UpsertAllocs(3, []*structs.Allocation{alloc}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Return a secret for the task
+	accessor := structs.GenerateUUID()
+	secret := &vapi.Secret{
+		WrapInfo: &vapi.SecretWrapInfo{
+			Token:           structs.GenerateUUID(),
+			WrappedAccessor: accessor,
+			TTL:             10,
+		},
+	}
+	tvc.SetCreateTokenSecret(alloc.ID, task.Name, secret)
+
+	req := &structs.DeriveVaultTokenRequest{
+		NodeID:   node.ID,
+		SecretID: node.SecretID,
+		AllocID:  alloc.ID,
+		Tasks:    tasks,
+		QueryOptions: structs.QueryOptions{
+			Region: "global",
+		},
+	}
+
+	var resp structs.DeriveVaultTokenResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Node.DeriveVaultToken", req, &resp); err != nil {
+		t.Fatalf("bad: %v", err)
+	}
+	if resp.Error != nil {
+		t.Fatalf("bad: %v", resp.Error)
+	}
+
+	// The accessor tracks the role of the token
+	ws := memdb.NewWatchSet()
+	va, err := state.VaultAccessor(ws, accessor)
+	if err != nil {
+		t.Fatalf("bad: %v", err)
+	}
+	if va == nil || va.Role != "web" {
+		t.Fatalf("bad: %#v", va)
+	}
+
+	// Disallow the role on the servers
+	s1.config.VaultConfig.AllowedRoles = nil
+	resp = structs.DeriveVaultTokenResponse{}
+	if err := msgpackrpc.CallWithCodec(codec, "Node.DeriveVaultToken", req, &resp); err != nil {
+		t.Fatalf("bad: %v", err)
+	}
+	if resp.Error == nil || resp.Error.IsRecoverable() || !strings.Contains(resp.Error.Error(), "not allowed") {
+		t.Fatalf("expected role not allowed error: %v", resp.Error)
+	}
+}
+
 func TestClientEndpoint_DeriveVaultToken_VaultError(t *testing.T) {
 	s1 := testServer(t, nil)
 	defer s1.Shutdown()
@@ -2034,3 +2117,149 @@ func TestClientEndpoint_DeriveVaultToken_VaultError(t *testing.T) {
 		t.Fatalf("bad: %+v", resp.Error)
 	}
 }
+
+func TestClientEndpoint_DeriveIdentityToken_Bad(t *testing.T) {
+	s1 := testServer(t, nil)
+	defer s1.Shutdown()
+	state := s1.fsm.State()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	// Create the node
+	node := mock.Node()
+	if err := state.UpsertNode(2, node); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Create an alloc
+	alloc := mock.Alloc()
+	task := alloc.Job.TaskGroups[0].Tasks[0]
+	tasks := []string{task.Name}
+	if err := state.UpsertAllocs(3, []*structs.Allocation{alloc}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	req := &structs.DeriveIdentityTokenRequest{
+		NodeID:   node.ID,
+		SecretID: structs.GenerateUUID(),
+		AllocID:  alloc.ID,
+		Tasks:    tasks,
+		QueryOptions: structs.QueryOptions{
+			Region: "global",
+		},
+	}
+
+	var resp structs.DeriveIdentityTokenResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Node.DeriveIdentityToken", req, &resp); err != nil {
+		t.Fatalf("bad: %v", err)
+	}
+	if resp.Error == nil || !strings.Contains(resp.Error.Error(), "SecretID mismatch") {
+		t.Fatalf("Expected SecretID mismatch: %v", resp.Error)
+	}
+
+	// Put the correct SecretID
+	req.SecretID = node.SecretID
+
+	// Now we should get an error about the allocation not running on the node
+	resp = structs.DeriveIdentityTokenResponse{}
+	if err := msgpackrpc.CallWithCodec(codec, "Node.DeriveIdentityToken", req, &resp); err != nil {
+		t.Fatalf("bad: %v", err)
+	}
+	if resp.Error == nil || !strings.Contains(resp.Error.Error(), "not running on Node") {
+		t.Fatalf("Expected not running on node error: %v", resp.Error)
+	}
+
+	// Update to be running on the node
+	alloc.NodeID = node.ID
+	if err := state.UpsertAllocs(4, []*structs.Allocation{alloc}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Now we should get an error about the task not having an identity
+	resp = structs.DeriveIdentityTokenResponse{}
+	if err := msgpackrpc.CallWithCodec(codec, "Node.DeriveIdentityToken", req, &resp); err != nil {
+		t.Fatalf("bad: %v", err)
+	}
+	if resp.Error == nil || !strings.Contains(resp.Error.Error(), "without an identity") {
+		t.Fatalf("Expected no identity error: %v", resp.Error)
+	}
+
+	// Update to be terminal
+	alloc.DesiredStatus = structs.AllocDesiredStatusStop
+	if err := state.UpsertAllocs(5, []*structs.Allocation{alloc}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	resp = structs.DeriveIdentityTokenResponse{}
+	if err := msgpackrpc.CallWithCodec(codec, "Node.DeriveIdentityToken", req, &resp); err != nil {
+		t.Fatalf("bad: %v", err)
+	}
+	if resp.Error == nil || !strings.Contains(resp.Error.Error(), "terminal") {
+		t.Fatalf("Expected terminal allocation error: %v", resp.Error)
+	}
+}
+
+func TestClientEndpoint_DeriveIdentityToken(t *testing.T) {
+	s1 := testServer(t, nil)
+	defer s1.Shutdown()
+	state := s1.fsm.State()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	// Wait for the leader to create the identity key
+	var key *structs.IdentityKey
+	testutil.WaitForResult(func() (bool, error) {
+		var err error
+		key, err = state.ActiveIdentityKey(nil)
+		return key != nil, err
+	}, func(err error) {
+		t.Fatalf("should have an identity key: %v", err)
+	})
+	jwk, err := identityPublicKey(key)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Create the node
+	node := mock.Node()
+	if err := state.UpsertNode(key.ModifyIndex+1, node); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Create an allocation with a task that has an identity
+	alloc := mock.Alloc()
+	alloc.NodeID = node.ID
+	task := alloc.Job.TaskGroups[0].Tasks[0]
+	task.Identity = &structs.WorkloadIdentity{Env: true}
+	if err := state.UpsertAllocs(key.ModifyIndex+2, []*structs.Allocation{alloc}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	req := &structs.DeriveIdentityTokenRequest{
+		NodeID:   node.ID,
+		SecretID: node.SecretID,
+		AllocID:  alloc.ID,
+		Tasks:    []string{task.Name},
+		QueryOptions: structs.QueryOptions{
+			Region: "global",
+		},
+	}
+
+	var resp structs.DeriveIdentityTokenResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Node.DeriveIdentityToken", req, &resp); err != nil {
+		t.Fatalf("bad: %v", err)
+	}
+	if resp.Error != nil {
+		t.Fatalf("bad: %v", resp.Error)
+	}
+
+	token, ok := resp.Tasks[task.Name]
+	if !ok {
+		t.Fatalf("no token for task: %#v", resp.Tasks)
+	}
+	claims := verifyIdentity(t, token, jwk)
+	if claims.Region != "global" || claims.JobID != alloc.JobID || claims.TaskGroup != alloc.TaskGroup ||
+		claims.Task != task.Name || claims.AllocID != alloc.ID || claims.NodeID != node.ID {
+		t.Fatalf("bad claims: %#v", claims)
+	}
+}
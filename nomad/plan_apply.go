@@ -106,7 +106,7 @@ func (s *Server) planApply() {
 		}
 
 		// Dispatch the Raft transaction for the plan
-		future, err := s.applyPlan(pending.plan.Job, result, snap)
+		future, err := s.applyPlan(pending.plan, result, snap)
 		if err != nil {
 			s.logger.Printf("[ERR] nomad: failed to submit plan: %v", err)
 			pending.respond(nil, err)
@@ -120,7 +120,7 @@ func (s *Server) planApply() {
 }
 
 // applyPlan is used to apply the plan result and to return the alloc index
-func (s *Server) applyPlan(job *structs.Job, result *structs.PlanResult, snap *state.StateSnapshot) (raft.ApplyFuture, error) {
+func (s *Server) applyPlan(plan *structs.Plan, result *structs.PlanResult, snap *state.StateSnapshot) (raft.ApplyFuture, error) {
 	// Determine the miniumum number of updates, could be more if there
 	// are multiple updates per node
 	minUpdates := len(result.NodeUpdate)
@@ -128,9 +128,15 @@ func (s *Server) applyPlan(job *structs.Job, result *structs.PlanResult, snap *s
 
 	// Setup the update request
 	req := structs.AllocUpdateRequest{
-		Job:   job,
+		Job:   plan.Job,
 		Alloc: make([]*structs.Allocation, 0, minUpdates),
 	}
+
+	// Record the plan with the evaluation it was submitted for
+	if plan.EvalID != "" {
+		req.Plan = structs.AppliedPlanFromResult(plan, result)
+		req.Plan.ApplyTime = time.Now().UTC().UnixNano()
+	}
 	for _, updateList := range result.NodeUpdate {
 		req.Alloc = append(req.Alloc, updateList...)
 	}
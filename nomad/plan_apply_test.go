@@ -83,7 +83,7 @@ func TestPlanApply_applyPlan(t *testing.T) {
 	}
 
 	// Apply the plan
-	future, err := s1.applyPlan(alloc.Job, plan, snap)
+	future, err := s1.applyPlan(&structs.Plan{EvalID: alloc.EvalID, Job: alloc.Job}, plan, snap)
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
@@ -112,6 +112,18 @@ func TestPlanApply_applyPlan(t *testing.T) {
 		t.Fatalf("missing alloc")
 	}
 
+	// Check the plan was recorded with the evaluation
+	plans, err := s1.fsm.State().EvalPlansByEval(ws, alloc.EvalID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if plans == nil || len(plans.Plans) != 1 || plans.Plans[0].AllocIndex != index {
+		t.Fatalf("bad: %#v", plans)
+	}
+	if stubs := plans.Plans[0].NodeAllocation[alloc.NodeID]; len(stubs) != 1 || stubs[0].ID != alloc.ID {
+		t.Fatalf("bad: %#v", plans.Plans[0])
+	}
+
 	// Evict alloc, Register alloc2
 	allocEvict := new(structs.Allocation)
 	*allocEvict = *alloc
@@ -137,7 +149,7 @@ func TestPlanApply_applyPlan(t *testing.T) {
 	}
 
 	// Apply the plan
-	future, err = s1.applyPlan(job, plan, snap)
+	future, err = s1.applyPlan(&structs.Plan{Job: job}, plan, snap)
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
@@ -0,0 +1,112 @@
+package nomad
+
+import (
+	"time"
+
+	"github.com/armon/go-metrics"
+	"github.com/hashicorp/go-memdb"
+	"github.com/hashicorp/nomad/nomad/state"
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+// Scaling endpoint is used to query the scaling policies of task groups
+type Scaling struct {
+	srv *Server
+}
+
+// ListPolicies is used to list the scaling policies, optionally only those of
+// a job
+func (s *Scaling) ListPolicies(args *structs.ScalingPolicyListRequest,
+	reply *structs.ScalingPolicyListResponse) error {
+	if done, err := s.srv.forward("Scaling.ListPolicies", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "scaling", "list_policies"}, time.Now())
+
+	// Setup the blocking query
+	opts := blockingOptions{
+		queryOpts: &args.QueryOptions,
+		queryMeta: &reply.QueryMeta,
+		run: func(ws memdb.WatchSet, state *state.StateStore) error {
+			policies := make([]*structs.ScalingPolicyListStub, 0)
+			if args.JobID != "" {
+				out, err := state.ScalingPoliciesByJob(ws, args.JobID)
+				if err != nil {
+					return err
+				}
+				for _, policy := range out {
+					policies = append(policies, policy.Stub())
+				}
+			} else {
+				iter, err := state.ScalingPolicies(ws)
+				if err != nil {
+					return err
+				}
+				for {
+					raw := iter.Next()
+					if raw == nil {
+						break
+					}
+					policies = append(policies, raw.(*structs.ScalingPolicy).Stub())
+				}
+			}
+			reply.Policies = policies
+
+			// Use the last index that affected the scaling policy table
+			index, err := state.Index("scaling_policy")
+			if err != nil {
+				return err
+			}
+
+			// Must provide non-zero index to prevent blocking
+			// Index 1 is impossible anyways (due to Raft internals)
+			if index == 0 {
+				reply.Index = 1
+			} else {
+				reply.Index = index
+			}
+
+			// Set the query response
+			s.srv.setQueryMeta(&reply.QueryMeta)
+			return nil
+		}}
+	return s.srv.blockingRPC(&opts)
+}
+
+// GetPolicy is used to return a specific scaling policy
+func (s *Scaling) GetPolicy(args *structs.ScalingPolicySpecificRequest,
+	reply *structs.SingleScalingPolicyResponse) error {
+	if done, err := s.srv.forward("Scaling.GetPolicy", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "scaling", "get_policy"}, time.Now())
+
+	// Setup the blocking query
+	opts := blockingOptions{
+		queryOpts: &args.QueryOptions,
+		queryMeta: &reply.QueryMeta,
+		run: func(ws memdb.WatchSet, state *state.StateStore) error {
+			out, err := state.ScalingPolicyByID(ws, args.ID)
+			if err != nil {
+				return err
+			}
+
+			// Setup the output
+			reply.Policy = out
+			if out != nil {
+				reply.Index = out.ModifyIndex
+			} else {
+				// Use the last index that affected the scaling policy table
+				index, err := state.Index("scaling_policy")
+				if err != nil {
+					return err
+				}
+				reply.Index = index
+			}
+
+			// Set the query response
+			s.srv.setQueryMeta(&reply.QueryMeta)
+			return nil
+		}}
+	return s.srv.blockingRPC(&opts)
+}
@@ -0,0 +1,105 @@
+package nomad
+
+import (
+	"testing"
+
+	"github.com/hashicorp/net-rpc-msgpackrpc"
+	"github.com/hashicorp/nomad/nomad/mock"
+	"github.com/hashicorp/nomad/nomad/structs"
+	"github.com/hashicorp/nomad/testutil"
+)
+
+func TestScalingEndpoint_ListPolicies(t *testing.T) {
+	s1 := testServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	// Create two jobs with scaling policies
+	state := s1.fsm.State()
+	job1 := mock.Job()
+	job1.TaskGroups[0].Scaling = &structs.ScalingPolicy{ID: structs.GenerateUUID(), Max: 20}
+	job2 := mock.Job()
+	job2.TaskGroups[0].Scaling = &structs.ScalingPolicy{ID: structs.GenerateUUID(), Max: 20}
+	if err := state.UpsertJob(1000, job1); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := state.UpsertJob(1001, job2); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// List all the policies
+	req := &structs.ScalingPolicyListRequest{
+		QueryOptions: structs.QueryOptions{Region: "global"},
+	}
+	var resp structs.ScalingPolicyListResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Scaling.ListPolicies", req, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.Index != 1001 {
+		t.Fatalf("bad index: %d", resp.Index)
+	}
+	if len(resp.Policies) != 2 {
+		t.Fatalf("bad: %#v", resp.Policies)
+	}
+
+	// List the policies of a job
+	req.JobID = job1.ID
+	var resp2 structs.ScalingPolicyListResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Scaling.ListPolicies", req, &resp2); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(resp2.Policies) != 1 {
+		t.Fatalf("bad: %#v", resp2.Policies)
+	}
+	if p := resp2.Policies[0]; p.ID != job1.TaskGroups[0].Scaling.ID || p.JobID != job1.ID || p.TaskGroup != "web" {
+		t.Fatalf("bad: %#v", p)
+	}
+}
+
+func TestScalingEndpoint_GetPolicy(t *testing.T) {
+	s1 := testServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	state := s1.fsm.State()
+	job := mock.Job()
+	job.TaskGroups[0].Scaling = &structs.ScalingPolicy{
+		ID:      structs.GenerateUUID(),
+		Min:     1,
+		Max:     20,
+		Enabled: true,
+		Policy: map[string]interface{}{
+			"metric": "cpu",
+		},
+	}
+	if err := state.UpsertJob(1000, job); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	req := &structs.ScalingPolicySpecificRequest{
+		ID:           job.TaskGroups[0].Scaling.ID,
+		QueryOptions: structs.QueryOptions{Region: "global"},
+	}
+	var resp structs.SingleScalingPolicyResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Scaling.GetPolicy", req, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.Index != 1000 {
+		t.Fatalf("bad index: %d", resp.Index)
+	}
+	if p := resp.Policy; p == nil || p.Max != 20 || p.Policy["metric"] != "cpu" {
+		t.Fatalf("bad: %#v", p)
+	}
+
+	// Lookup a missing policy
+	req.ID = structs.GenerateUUID()
+	var resp2 structs.SingleScalingPolicyResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Scaling.GetPolicy", req, &resp2); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp2.Policy != nil {
+		t.Fatalf("bad: %#v", resp2.Policy)
+	}
+}
@@ -142,6 +142,15 @@ type Server struct {
 	// vault is the client for communicating with Vault.
 	vault VaultClient
 
+	// autoscalerStats fetches the resource usage of allocations for the
+	// built-in autoscaler. It defaults to querying the clients and can be
+	// overridden for testing.
+	autoscalerStats allocStatsFn
+
+	// dispatchLock serializes the dispatches that carry an idempotency token
+	// so that concurrent retries can't both create a child job.
+	dispatchLock sync.Mutex
+
 	// Worker used for processing
 	workers []*Worker
 
@@ -153,16 +162,19 @@ type Server struct {
 
 // Holds the RPC endpoints
 type endpoints struct {
-	Status   *Status
-	Node     *Node
-	Job      *Job
-	Eval     *Eval
-	Plan     *Plan
-	Alloc    *Alloc
-	Region   *Region
-	Periodic *Periodic
-	System   *System
-	Operator *Operator
+	Status              *Status
+	Node                *Node
+	Job                 *Job
+	Eval                *Eval
+	Plan                *Plan
+	Alloc               *Alloc
+	Region              *Region
+	Periodic            *Periodic
+	System              *System
+	Operator            *Operator
+	Identity            *Identity
+	ServiceRegistration *ServiceRegistration
+	Scaling             *Scaling
 }
 
 // NewServer is used to construct a new Nomad server from the
@@ -653,6 +665,9 @@ func (s *Server) setupRPC(tlsWrap tlsutil.RegionWrapper) error {
 	s.endpoints.Region = &Region{s}
 	s.endpoints.Status = &Status{s}
 	s.endpoints.System = &System{s}
+	s.endpoints.Identity = &Identity{s}
+	s.endpoints.ServiceRegistration = &ServiceRegistration{s}
+	s.endpoints.Scaling = &Scaling{s}
 
 	// Register the handlers
 	s.rpcServer.Register(s.endpoints.Alloc)
@@ -665,6 +680,9 @@ func (s *Server) setupRPC(tlsWrap tlsutil.RegionWrapper) error {
 	s.rpcServer.Register(s.endpoints.Region)
 	s.rpcServer.Register(s.endpoints.Status)
 	s.rpcServer.Register(s.endpoints.System)
+	s.rpcServer.Register(s.endpoints.Identity)
+	s.rpcServer.Register(s.endpoints.ServiceRegistration)
+	s.rpcServer.Register(s.endpoints.Scaling)
 
 	list, err := net.ListenTCP("tcp", s.config.RPCAddr)
 	if err != nil {
@@ -0,0 +1,227 @@
+package nomad
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/armon/go-metrics"
+	"github.com/hashicorp/go-memdb"
+	multierror "github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/nomad/nomad/state"
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+// ServiceRegistration endpoint is used by clients to register the services of
+// their tasks with the servers and to query the registered services
+type ServiceRegistration struct {
+	srv *Server
+}
+
+// Upsert is used by clients to register or update instances of services
+func (s *ServiceRegistration) Upsert(args *structs.ServiceRegistrationUpsertRequest,
+	reply *structs.GenericResponse) error {
+	if done, err := s.srv.forward("ServiceRegistration.Upsert", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "service_registration", "upsert"}, time.Now())
+
+	if len(args.Services) == 0 {
+		return fmt.Errorf("must register at least one service")
+	}
+
+	// Only allow registering the services of running allocations placed on
+	// the registering node
+	snap, err := s.srv.fsm.State().Snapshot()
+	if err != nil {
+		return err
+	}
+	var mErr multierror.Error
+	for _, service := range args.Services {
+		if err := service.Validate(); err != nil {
+			mErr.Errors = append(mErr.Errors, fmt.Errorf("service %q invalid: %v", service.ID, err))
+			continue
+		}
+
+		alloc, err := snap.AllocByID(nil, service.AllocID)
+		if err != nil {
+			return err
+		}
+		if alloc == nil {
+			mErr.Errors = append(mErr.Errors, fmt.Errorf("service %q invalid: allocation %q not found", service.ID, service.AllocID))
+		} else if alloc.NodeID != service.NodeID {
+			mErr.Errors = append(mErr.Errors, fmt.Errorf("service %q invalid: allocation %q not placed on node %q", service.ID, service.AllocID, service.NodeID))
+		} else if alloc.Terminated() {
+			mErr.Errors = append(mErr.Errors, fmt.Errorf("service %q invalid: allocation %q is terminal", service.ID, service.AllocID))
+		}
+	}
+	if err := mErr.ErrorOrNil(); err != nil {
+		return err
+	}
+
+	_, index, err := s.srv.raftApply(structs.ServiceRegistrationUpsertRequestType, args)
+	if err != nil {
+		s.srv.logger.Printf("[ERR] nomad.service_registration: Upsert failed: %v", err)
+		return err
+	}
+
+	reply.Index = index
+	return nil
+}
+
+// Delete is used by clients to deregister instances of services of tasks that
+// have stopped
+func (s *ServiceRegistration) Delete(args *structs.ServiceRegistrationDeleteRequest,
+	reply *structs.GenericResponse) error {
+	if done, err := s.srv.forward("ServiceRegistration.Delete", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "service_registration", "delete"}, time.Now())
+
+	if args.NodeID == "" {
+		return fmt.Errorf("missing node ID")
+	}
+	if len(args.IDs) == 0 {
+		return fmt.Errorf("must deregister at least one service")
+	}
+
+	// Clients may only deregister their own services
+	snap, err := s.srv.fsm.State().Snapshot()
+	if err != nil {
+		return err
+	}
+	for _, id := range args.IDs {
+		service, err := snap.ServiceRegistrationByID(nil, id)
+		if err != nil {
+			return err
+		}
+		if service != nil && service.NodeID != args.NodeID {
+			return fmt.Errorf("service %q not registered by node %q", id, args.NodeID)
+		}
+	}
+
+	_, index, err := s.srv.raftApply(structs.ServiceRegistrationDeleteRequestType, args)
+	if err != nil {
+		s.srv.logger.Printf("[ERR] nomad.service_registration: Delete failed: %v", err)
+		return err
+	}
+
+	reply.Index = index
+	return nil
+}
+
+// List is used to list the registered services
+func (s *ServiceRegistration) List(args *structs.GenericRequest,
+	reply *structs.ServiceRegistrationListResponse) error {
+	if done, err := s.srv.forward("ServiceRegistration.List", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "service_registration", "list"}, time.Now())
+
+	// Setup the blocking query
+	opts := blockingOptions{
+		queryOpts: &args.QueryOptions,
+		queryMeta: &reply.QueryMeta,
+		run: func(ws memdb.WatchSet, state *state.StateStore) error {
+			iter, err := state.ServiceRegistrations(ws)
+			if err != nil {
+				return err
+			}
+
+			// Collect the union of the tags of the instances of each
+			// service
+			tags := make(map[string]map[string]struct{})
+			for {
+				raw := iter.Next()
+				if raw == nil {
+					break
+				}
+				service := raw.(*structs.ServiceRegistration)
+				if _, ok := tags[service.ServiceName]; !ok {
+					tags[service.ServiceName] = make(map[string]struct{})
+				}
+				for _, tag := range service.Tags {
+					tags[service.ServiceName][tag] = struct{}{}
+				}
+			}
+
+			services := make([]*structs.ServiceRegistrationListStub, 0, len(tags))
+			for name, set := range tags {
+				stub := &structs.ServiceRegistrationListStub{
+					ServiceName: name,
+					Tags:        make([]string, 0, len(set)),
+				}
+				for tag := range set {
+					stub.Tags = append(stub.Tags, tag)
+				}
+				sort.Strings(stub.Tags)
+				services = append(services, stub)
+			}
+			sort.Slice(services, func(i, j int) bool {
+				return services[i].ServiceName < services[j].ServiceName
+			})
+			reply.Services = services
+
+			// Use the last index that affected the services table
+			index, err := state.Index("services")
+			if err != nil {
+				return err
+			}
+
+			// Must provide non-zero index to prevent blocking
+			// Index 1 is impossible anyways (due to Raft internals)
+			if index == 0 {
+				reply.Index = 1
+			} else {
+				reply.Index = index
+			}
+
+			// Set the query response
+			s.srv.setQueryMeta(&reply.QueryMeta)
+			return nil
+		}}
+	return s.srv.blockingRPC(&opts)
+}
+
+// GetService is used to return the instances of a service
+func (s *ServiceRegistration) GetService(args *structs.ServiceRegistrationByNameRequest,
+	reply *structs.ServiceRegistrationByNameResponse) error {
+	if done, err := s.srv.forward("ServiceRegistration.GetService", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "service_registration", "get_service"}, time.Now())
+
+	// Setup the blocking query
+	opts := blockingOptions{
+		queryOpts: &args.QueryOptions,
+		queryMeta: &reply.QueryMeta,
+		run: func(ws memdb.WatchSet, state *state.StateStore) error {
+			services, err := state.ServiceRegistrationsByName(ws, args.ServiceName)
+			if err != nil {
+				return err
+			}
+			if services == nil {
+				services = make([]*structs.ServiceRegistration, 0)
+			}
+			reply.Services = services
+
+			// Use the last index that affected the services table
+			index, err := state.Index("services")
+			if err != nil {
+				return err
+			}
+
+			// Must provide non-zero index to prevent blocking
+			// Index 1 is impossible anyways (due to Raft internals)
+			if index == 0 {
+				reply.Index = 1
+			} else {
+				reply.Index = index
+			}
+
+			// Set the query response
+			s.srv.setQueryMeta(&reply.QueryMeta)
+			return nil
+		}}
+	return s.srv.blockingRPC(&opts)
+}
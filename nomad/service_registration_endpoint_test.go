@@ -0,0 +1,193 @@
+package nomad
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/net-rpc-msgpackrpc"
+	"github.com/hashicorp/nomad/nomad/mock"
+	"github.com/hashicorp/nomad/nomad/structs"
+	"github.com/hashicorp/nomad/testutil"
+)
+
+func TestServiceRegistrationEndpoint_Upsert(t *testing.T) {
+	s1 := testServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	// Create a running allocation
+	state := s1.fsm.State()
+	alloc := mock.Alloc()
+	if err := state.UpsertAllocs(1000, []*structs.Allocation{alloc}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	service := mock.ServiceRegistration()
+	service.NodeID = alloc.NodeID
+	service.AllocID = alloc.ID
+	req := &structs.ServiceRegistrationUpsertRequest{
+		Services:     []*structs.ServiceRegistration{service},
+		WriteRequest: structs.WriteRequest{Region: "global"},
+	}
+	var resp structs.GenericResponse
+	if err := msgpackrpc.CallWithCodec(codec, "ServiceRegistration.Upsert", req, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.Index == 0 {
+		t.Fatalf("bad index: %d", resp.Index)
+	}
+
+	out, err := state.ServiceRegistrationByID(nil, service.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out == nil || out.CreateIndex != resp.Index {
+		t.Fatalf("bad: %#v", out)
+	}
+
+	// Registering a service of an allocation on another node fails
+	other := mock.ServiceRegistration()
+	other.AllocID = alloc.ID
+	req.Services = []*structs.ServiceRegistration{other}
+	err = msgpackrpc.CallWithCodec(codec, "ServiceRegistration.Upsert", req, &resp)
+	if err == nil || !strings.Contains(err.Error(), "not placed on node") {
+		t.Fatalf("expected node mismatch error: %v", err)
+	}
+
+	// Registering a service of an unknown allocation fails
+	req.Services = []*structs.ServiceRegistration{mock.ServiceRegistration()}
+	err = msgpackrpc.CallWithCodec(codec, "ServiceRegistration.Upsert", req, &resp)
+	if err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Fatalf("expected missing allocation error: %v", err)
+	}
+}
+
+func TestServiceRegistrationEndpoint_Delete(t *testing.T) {
+	s1 := testServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	state := s1.fsm.State()
+	service := mock.ServiceRegistration()
+	if err := state.UpsertServiceRegistrations(1000, []*structs.ServiceRegistration{service}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Other nodes can not deregister the service
+	req := &structs.ServiceRegistrationDeleteRequest{
+		NodeID:       structs.GenerateUUID(),
+		IDs:          []string{service.ID},
+		WriteRequest: structs.WriteRequest{Region: "global"},
+	}
+	var resp structs.GenericResponse
+	if err := msgpackrpc.CallWithCodec(codec, "ServiceRegistration.Delete", req, &resp); err == nil {
+		t.Fatalf("expected error")
+	}
+
+	req.NodeID = service.NodeID
+	if err := msgpackrpc.CallWithCodec(codec, "ServiceRegistration.Delete", req, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.Index == 0 {
+		t.Fatalf("bad index: %d", resp.Index)
+	}
+
+	out, err := state.ServiceRegistrationByID(nil, service.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out != nil {
+		t.Fatalf("service not deleted: %#v", out)
+	}
+}
+
+func TestServiceRegistrationEndpoint_List(t *testing.T) {
+	s1 := testServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	s1a := mock.ServiceRegistration()
+	s1a.Tags = []string{"b", "a"}
+	s1b := mock.ServiceRegistration()
+	s1b.Tags = []string{"c", "a"}
+	s2 := mock.ServiceRegistration()
+	s2.ServiceName = "api"
+	s2.Tags = nil
+	state := s1.fsm.State()
+	if err := state.UpsertServiceRegistrations(1000, []*structs.ServiceRegistration{s1a, s1b, s2}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	get := &structs.GenericRequest{
+		QueryOptions: structs.QueryOptions{Region: "global"},
+	}
+	var resp structs.ServiceRegistrationListResponse
+	if err := msgpackrpc.CallWithCodec(codec, "ServiceRegistration.List", get, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.Index != 1000 {
+		t.Fatalf("Bad index: %d %d", resp.Index, 1000)
+	}
+
+	expected := []*structs.ServiceRegistrationListStub{
+		{ServiceName: "api", Tags: []string{}},
+		{ServiceName: "web", Tags: []string{"a", "b", "c"}},
+	}
+	if !reflect.DeepEqual(resp.Services, expected) {
+		t.Fatalf("bad: %#v", resp.Services)
+	}
+}
+
+func TestServiceRegistrationEndpoint_GetService_Blocking(t *testing.T) {
+	s1 := testServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	state := s1.fsm.State()
+	other := mock.ServiceRegistration()
+	other.ServiceName = "api"
+	service := mock.ServiceRegistration()
+
+	// Upsert an unrelated service first
+	time.AfterFunc(100*time.Millisecond, func() {
+		if err := state.UpsertServiceRegistrations(100, []*structs.ServiceRegistration{other}); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	})
+
+	// Upsert the service we are watching later
+	time.AfterFunc(200*time.Millisecond, func() {
+		if err := state.UpsertServiceRegistrations(200, []*structs.ServiceRegistration{service}); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	})
+
+	req := &structs.ServiceRegistrationByNameRequest{
+		ServiceName: service.ServiceName,
+		QueryOptions: structs.QueryOptions{
+			Region:        "global",
+			MinQueryIndex: 150,
+		},
+	}
+	start := time.Now()
+	var resp structs.ServiceRegistrationByNameResponse
+	if err := msgpackrpc.CallWithCodec(codec, "ServiceRegistration.GetService", req, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	if elapsed := time.Since(start); elapsed < 200*time.Millisecond {
+		t.Fatalf("should block (returned in %s) %#v", elapsed, resp)
+	}
+	if resp.Index != 200 {
+		t.Fatalf("Bad index: %d %d", resp.Index, 200)
+	}
+	if len(resp.Services) != 1 || resp.Services[0].ID != service.ID {
+		t.Fatalf("bad: %#v", resp.Services)
+	}
+}
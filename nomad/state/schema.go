@@ -24,6 +24,11 @@ func stateStoreSchema() *memdb.DBSchema {
 		evalTableSchema,
 		allocTableSchema,
 		vaultAccessorTableSchema,
+		identityKeyTableSchema,
+		serviceRegistrationTableSchema,
+		scalingEventTableSchema,
+		scalingPolicyTableSchema,
+		evalPlanTableSchema,
 	}
 
 	// Add each of the tables
@@ -339,3 +344,129 @@ func vaultAccessorTableSchema() *memdb.TableSchema {
 		},
 	}
 }
+
+// identityKeyTableSchema returns the MemDB schema for the identity key table.
+// This table tracks the keys used to sign the workload identities of tasks.
+func identityKeyTableSchema() *memdb.TableSchema {
+	return &memdb.TableSchema{
+		Name: "identity_keys",
+		Indexes: map[string]*memdb.IndexSchema{
+			// The primary index is the key id
+			"id": &memdb.IndexSchema{
+				Name:         "id",
+				AllowMissing: false,
+				Unique:       true,
+				Indexer: &memdb.StringFieldIndex{
+					Field: "ID",
+				},
+			},
+		},
+	}
+}
+
+// serviceRegistrationTableSchema returns the MemDB schema for the service
+// registration table. This table tracks the instances of the services
+// registered with the Nomad servers by clients.
+func serviceRegistrationTableSchema() *memdb.TableSchema {
+	return &memdb.TableSchema{
+		Name: "services",
+		Indexes: map[string]*memdb.IndexSchema{
+			// The primary index is the registration id
+			"id": &memdb.IndexSchema{
+				Name:         "id",
+				AllowMissing: false,
+				Unique:       true,
+				Indexer: &memdb.StringFieldIndex{
+					Field: "ID",
+				},
+			},
+
+			"service_name": &memdb.IndexSchema{
+				Name:         "service_name",
+				AllowMissing: false,
+				Unique:       false,
+				Indexer: &memdb.StringFieldIndex{
+					Field: "ServiceName",
+				},
+			},
+
+			"alloc_id": &memdb.IndexSchema{
+				Name:         "alloc_id",
+				AllowMissing: false,
+				Unique:       false,
+				Indexer: &memdb.StringFieldIndex{
+					Field: "AllocID",
+				},
+			},
+		},
+	}
+}
+
+// scalingEventTableSchema returns the MemDB schema for the scaling event
+// table. This table tracks the scaling history of the task groups of jobs.
+func scalingEventTableSchema() *memdb.TableSchema {
+	return &memdb.TableSchema{
+		Name: "scaling_event",
+		Indexes: map[string]*memdb.IndexSchema{
+			// The primary index is the job id
+			"id": &memdb.IndexSchema{
+				Name:         "id",
+				AllowMissing: false,
+				Unique:       true,
+				Indexer: &memdb.StringFieldIndex{
+					Field: "JobID",
+				},
+			},
+		},
+	}
+}
+
+// scalingPolicyTableSchema returns the MemDB schema for the scaling policy
+// table. This table tracks the scaling policies of the task groups of jobs.
+func scalingPolicyTableSchema() *memdb.TableSchema {
+	return &memdb.TableSchema{
+		Name: "scaling_policy",
+		Indexes: map[string]*memdb.IndexSchema{
+			// Primary index is used for policy management
+			// and simple direct lookup. ID is required to be
+			// unique.
+			"id": &memdb.IndexSchema{
+				Name:         "id",
+				AllowMissing: false,
+				Unique:       true,
+				Indexer: &memdb.UUIDFieldIndex{
+					Field: "ID",
+				},
+			},
+
+			// Job index is used to lookup the policies of a job
+			"job": &memdb.IndexSchema{
+				Name:         "job",
+				AllowMissing: false,
+				Unique:       false,
+				Indexer: &memdb.StringFieldIndex{
+					Field: "JobID",
+				},
+			},
+		},
+	}
+}
+
+// evalPlanTableSchema returns the MemDB schema for the eval plan table. This
+// table tracks the plans applied for evaluations.
+func evalPlanTableSchema() *memdb.TableSchema {
+	return &memdb.TableSchema{
+		Name: "eval_plan",
+		Indexes: map[string]*memdb.IndexSchema{
+			// The primary index is the eval id
+			"id": &memdb.IndexSchema{
+				Name:         "id",
+				AllowMissing: false,
+				Unique:       true,
+				Indexer: &memdb.UUIDFieldIndex{
+					Field: "EvalID",
+				},
+			},
+		},
+	}
+}
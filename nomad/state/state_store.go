@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"reflect"
 
 	"github.com/hashicorp/go-memdb"
 	"github.com/hashicorp/nomad/nomad/structs"
@@ -300,6 +301,17 @@ func (s *StateStore) UpsertJob(index uint64, job *structs.Job) error {
 	txn := s.db.Txn(true)
 	defer txn.Abort()
 
+	if err := s.upsertJobImpl(index, job, txn); err != nil {
+		return err
+	}
+
+	txn.Commit()
+	return nil
+}
+
+// upsertJobImpl is the implementation of registering or updating a job
+// within a transaction
+func (s *StateStore) upsertJobImpl(index uint64, job *structs.Job, txn *memdb.Txn) error {
 	// Check if the job already exists
 	existing, err := txn.First("jobs", "id", job.ID)
 	if err != nil {
@@ -345,6 +357,11 @@ func (s *StateStore) UpsertJob(index uint64, job *structs.Job) error {
 	// COMPAT 0.4.1 -> 0.5
 	s.addEphemeralDiskToTaskGroups(job)
 
+	// Update the scaling policies of the task groups
+	if err := s.updateJobScalingPolicies(index, job, txn); err != nil {
+		return fmt.Errorf("unable to update scaling policies: %v", err)
+	}
+
 	// Insert the job
 	if err := txn.Insert("jobs", job); err != nil {
 		return fmt.Errorf("job insert failed: %v", err)
@@ -352,11 +369,237 @@ func (s *StateStore) UpsertJob(index uint64, job *structs.Job) error {
 	if err := txn.Insert("index", &IndexEntry{"jobs", index}); err != nil {
 		return fmt.Errorf("index update failed: %v", err)
 	}
+	return nil
+}
+
+// updateJobScalingPolicies inserts, updates and deletes the scaling policies
+// of the job so that they match the scaling policies of its task groups.
+func (s *StateStore) updateJobScalingPolicies(index uint64, job *structs.Job, txn *memdb.Txn) error {
+	iter, err := txn.Get("scaling_policy", "job", job.ID)
+	if err != nil {
+		return fmt.Errorf("scaling policy lookup failed: %v", err)
+	}
+	existing := make(map[string]*structs.ScalingPolicy)
+	for {
+		raw := iter.Next()
+		if raw == nil {
+			break
+		}
+		policy := raw.(*structs.ScalingPolicy)
+		existing[policy.TaskGroup] = policy
+	}
+
+	modified := false
+	for _, tg := range job.TaskGroups {
+		if tg.Scaling == nil {
+			continue
+		}
+
+		policy := tg.Scaling.Copy()
+		policy.JobID = job.ID
+		policy.TaskGroup = tg.Name
+		prev := existing[tg.Name]
+		delete(existing, tg.Name)
+		if policy.ID == "" {
+			if prev == nil {
+				return fmt.Errorf("scaling policy of task group %q has no ID", tg.Name)
+			}
+			policy.ID = prev.ID
+			tg.Scaling.ID = prev.ID
+		}
+
+		if prev != nil && prev.ID == policy.ID {
+			policy.CreateIndex = prev.CreateIndex
+			policy.ModifyIndex = prev.ModifyIndex
+			if reflect.DeepEqual(prev, policy) {
+				continue
+			}
+		} else {
+			if prev != nil {
+				if err := txn.Delete("scaling_policy", prev); err != nil {
+					return fmt.Errorf("scaling policy delete failed: %v", err)
+				}
+			}
+			policy.CreateIndex = index
+		}
+		policy.ModifyIndex = index
+
+		if err := txn.Insert("scaling_policy", policy); err != nil {
+			return fmt.Errorf("scaling policy insert failed: %v", err)
+		}
+		modified = true
+	}
+
+	// Delete the policies of task groups that no longer have one
+	for _, policy := range existing {
+		if err := txn.Delete("scaling_policy", policy); err != nil {
+			return fmt.Errorf("scaling policy delete failed: %v", err)
+		}
+		modified = true
+	}
+
+	if modified {
+		if err := txn.Insert("index", &IndexEntry{"scaling_policy", index}); err != nil {
+			return fmt.Errorf("index update failed: %v", err)
+		}
+	}
+	return nil
+}
+
+// ScaleJob records a scaling event of a task group of a job and, if the event
+// changes its count, updates the count of the task group. Both happen within
+// a single transaction so the count is changed on the current version of the
+// job.
+func (s *StateStore) ScaleJob(index uint64, jobID, group string, event *structs.ScalingEvent) error {
+	txn := s.db.Txn(true)
+	defer txn.Abort()
+
+	existing, err := txn.First("jobs", "id", jobID)
+	if err != nil {
+		return fmt.Errorf("job lookup failed: %v", err)
+	}
+	if existing == nil {
+		return fmt.Errorf("job %q not found", jobID)
+	}
+	job := existing.(*structs.Job)
+	tg := job.LookupTaskGroup(group)
+	if tg == nil {
+		return fmt.Errorf("task group %q not found in job %q", group, jobID)
+	}
+
+	// Update the count of the task group
+	event.PreviousCount = int64(tg.Count)
+	event.CreateIndex = index
+	if event.Count != nil {
+		job = job.Copy()
+		job.LookupTaskGroup(group).Count = int(*event.Count)
+		if err := s.upsertJobImpl(index, job, txn); err != nil {
+			return err
+		}
+	}
+
+	// Record the event in the scaling history of the job
+	var events *structs.JobScalingEvents
+	raw, err := txn.First("scaling_event", "id", jobID)
+	if err != nil {
+		return fmt.Errorf("scaling event lookup failed: %v", err)
+	}
+	if raw != nil {
+		events = raw.(*structs.JobScalingEvents).Copy()
+	} else {
+		events = &structs.JobScalingEvents{JobID: jobID}
+	}
+	events.AddEvent(group, event)
+	events.ModifyIndex = index
+
+	if err := txn.Insert("scaling_event", events); err != nil {
+		return fmt.Errorf("scaling event insert failed: %v", err)
+	}
+	if err := txn.Insert("index", &IndexEntry{"scaling_event", index}); err != nil {
+		return fmt.Errorf("index update failed: %v", err)
+	}
 
 	txn.Commit()
 	return nil
 }
 
+// ScalingEventsByJob returns the scaling history of the job
+func (s *StateStore) ScalingEventsByJob(ws memdb.WatchSet, jobID string) (*structs.JobScalingEvents, error) {
+	txn := s.db.Txn(false)
+
+	watchCh, existing, err := txn.FirstWatch("scaling_event", "id", jobID)
+	if err != nil {
+		return nil, fmt.Errorf("scaling event lookup failed: %v", err)
+	}
+	ws.Add(watchCh)
+
+	if existing != nil {
+		return existing.(*structs.JobScalingEvents), nil
+	}
+	return nil, nil
+}
+
+// ScalingPolicyByID returns the scaling policy with the given ID
+func (s *StateStore) ScalingPolicyByID(ws memdb.WatchSet, id string) (*structs.ScalingPolicy, error) {
+	txn := s.db.Txn(false)
+
+	watchCh, existing, err := txn.FirstWatch("scaling_policy", "id", id)
+	if err != nil {
+		return nil, fmt.Errorf("scaling policy lookup failed: %v", err)
+	}
+	ws.Add(watchCh)
+
+	if existing != nil {
+		return existing.(*structs.ScalingPolicy), nil
+	}
+	return nil, nil
+}
+
+// ScalingPolicyByTarget returns the scaling policy of the task group of a job
+func (s *StateStore) ScalingPolicyByTarget(ws memdb.WatchSet, jobID, group string) (*structs.ScalingPolicy, error) {
+	policies, err := s.ScalingPoliciesByJob(ws, jobID)
+	if err != nil {
+		return nil, err
+	}
+	for _, policy := range policies {
+		if policy.TaskGroup == group {
+			return policy, nil
+		}
+	}
+	return nil, nil
+}
+
+// ScalingPoliciesByJob returns the scaling policies of the task groups of a
+// job
+func (s *StateStore) ScalingPoliciesByJob(ws memdb.WatchSet, jobID string) ([]*structs.ScalingPolicy, error) {
+	txn := s.db.Txn(false)
+
+	iter, err := txn.Get("scaling_policy", "job", jobID)
+	if err != nil {
+		return nil, err
+	}
+
+	ws.Add(iter.WatchCh())
+
+	var out []*structs.ScalingPolicy
+	for {
+		raw := iter.Next()
+		if raw == nil {
+			break
+		}
+		out = append(out, raw.(*structs.ScalingPolicy))
+	}
+	return out, nil
+}
+
+// ScalingPolicies returns an iterator over all the scaling policies
+func (s *StateStore) ScalingPolicies(ws memdb.WatchSet) (memdb.ResultIterator, error) {
+	txn := s.db.Txn(false)
+
+	iter, err := txn.Get("scaling_policy", "id")
+	if err != nil {
+		return nil, err
+	}
+
+	ws.Add(iter.WatchCh())
+
+	return iter, nil
+}
+
+// ScalingEvents returns an iterator over the scaling history of all jobs
+func (s *StateStore) ScalingEvents(ws memdb.WatchSet) (memdb.ResultIterator, error) {
+	txn := s.db.Txn(false)
+
+	iter, err := txn.Get("scaling_event", "id")
+	if err != nil {
+		return nil, err
+	}
+
+	ws.Add(iter.WatchCh())
+
+	return iter, nil
+}
+
 // DeleteJob is used to deregister a job
 func (s *StateStore) DeleteJob(index uint64, jobID string) error {
 	txn := s.db.Txn(true)
@@ -433,6 +676,24 @@ func (s *StateStore) DeleteJob(index uint64, jobID string) error {
 		return fmt.Errorf("index update failed: %v", err)
 	}
 
+	// Delete the scaling history
+	if n, err := txn.DeleteAll("scaling_event", "id", jobID); err != nil {
+		return fmt.Errorf("deleting scaling events failed: %v", err)
+	} else if n != 0 {
+		if err := txn.Insert("index", &IndexEntry{"scaling_event", index}); err != nil {
+			return fmt.Errorf("index update failed: %v", err)
+		}
+	}
+
+	// Delete the scaling policies
+	if n, err := txn.DeleteAll("scaling_policy", "job", jobID); err != nil {
+		return fmt.Errorf("deleting scaling policies failed: %v", err)
+	} else if n != 0 {
+		if err := txn.Insert("index", &IndexEntry{"scaling_policy", index}); err != nil {
+			return fmt.Errorf("index update failed: %v", err)
+		}
+	}
+
 	txn.Commit()
 	return nil
 }
@@ -784,6 +1045,7 @@ func (s *StateStore) DeleteEval(index uint64, evals []string, allocs []string) e
 	defer txn.Abort()
 
 	jobs := make(map[string]string, len(evals))
+	deletedPlans := false
 	for _, eval := range evals {
 		existing, err := txn.First("evals", "id", eval)
 		if err != nil {
@@ -797,6 +1059,13 @@ func (s *StateStore) DeleteEval(index uint64, evals []string, allocs []string) e
 		}
 		jobID := existing.(*structs.Evaluation).JobID
 		jobs[jobID] = ""
+
+		// Delete the plans applied for the evaluation
+		if n, err := txn.DeleteAll("eval_plan", "id", eval); err != nil {
+			return fmt.Errorf("eval plan delete failed: %v", err)
+		} else if n > 0 {
+			deletedPlans = true
+		}
 	}
 
 	for _, alloc := range allocs {
@@ -810,6 +1079,9 @@ func (s *StateStore) DeleteEval(index uint64, evals []string, allocs []string) e
 		if err := txn.Delete("allocs", existing); err != nil {
 			return fmt.Errorf("alloc delete failed: %v", err)
 		}
+		if err := s.deleteServiceRegistrationsByAlloc(txn, index, alloc); err != nil {
+			return err
+		}
 	}
 
 	// Update the indexes
@@ -819,6 +1091,11 @@ func (s *StateStore) DeleteEval(index uint64, evals []string, allocs []string) e
 	if err := txn.Insert("index", &IndexEntry{"allocs", index}); err != nil {
 		return fmt.Errorf("index update failed: %v", err)
 	}
+	if deletedPlans {
+		if err := txn.Insert("index", &IndexEntry{"eval_plan", index}); err != nil {
+			return fmt.Errorf("index update failed: %v", err)
+		}
+	}
 
 	// Set the job's status
 	if err := s.setJobStatuses(index, txn, jobs, true); err != nil {
@@ -829,6 +1106,70 @@ func (s *StateStore) DeleteEval(index uint64, evals []string, allocs []string) e
 	return nil
 }
 
+// UpsertEvalPlan is used to record a plan applied for an evaluation
+func (s *StateStore) UpsertEvalPlan(index uint64, plan *structs.AppliedPlan) error {
+	txn := s.db.Txn(true)
+	defer txn.Abort()
+
+	raw, err := txn.First("eval_plan", "id", plan.EvalID)
+	if err != nil {
+		return fmt.Errorf("eval plan lookup failed: %v", err)
+	}
+
+	var plans *structs.EvalPlans
+	if raw != nil {
+		plans = raw.(*structs.EvalPlans).Copy()
+	} else {
+		plans = &structs.EvalPlans{
+			EvalID:      plan.EvalID,
+			JobID:       plan.JobID,
+			CreateIndex: index,
+		}
+	}
+	plans.AddPlan(plan)
+	plans.ModifyIndex = index
+
+	if err := txn.Insert("eval_plan", plans); err != nil {
+		return fmt.Errorf("eval plan insert failed: %v", err)
+	}
+	if err := txn.Insert("index", &IndexEntry{"eval_plan", index}); err != nil {
+		return fmt.Errorf("index update failed: %v", err)
+	}
+
+	txn.Commit()
+	return nil
+}
+
+// EvalPlansByEval returns the plans applied for the evaluation
+func (s *StateStore) EvalPlansByEval(ws memdb.WatchSet, evalID string) (*structs.EvalPlans, error) {
+	txn := s.db.Txn(false)
+
+	watchCh, existing, err := txn.FirstWatch("eval_plan", "id", evalID)
+	if err != nil {
+		return nil, fmt.Errorf("eval plan lookup failed: %v", err)
+	}
+	ws.Add(watchCh)
+
+	if existing != nil {
+		return existing.(*structs.EvalPlans), nil
+	}
+	return nil, nil
+}
+
+// EvalPlans returns an iterator over the plans applied for all evaluations
+func (s *StateStore) EvalPlans(ws memdb.WatchSet) (memdb.ResultIterator, error) {
+	txn := s.db.Txn(false)
+
+	iter, err := txn.Get("eval_plan", "id")
+	if err != nil {
+		return nil, err
+	}
+
+	ws.Add(iter.WatchCh())
+
+	return iter, nil
+}
+
 // EvalByID is used to lookup an eval by its ID
 func (s *StateStore) EvalByID(ws memdb.WatchSet, id string) (*structs.Evaluation, error) {
 	txn := s.db.Txn(false)
@@ -952,6 +1293,7 @@ func (s *StateStore) nestedUpdateAllocFromClient(txn *memdb.Txn, index uint64, a
 	copyAlloc.ClientStatus = alloc.ClientStatus
 	copyAlloc.ClientDescription = alloc.ClientDescription
 	copyAlloc.TaskStates = alloc.TaskStates
+	copyAlloc.NetworkStatus = alloc.NetworkStatus
 
 	// Update the modify index
 	copyAlloc.ModifyIndex = index
@@ -965,6 +1307,13 @@ func (s *StateStore) nestedUpdateAllocFromClient(txn *memdb.Txn, index uint64, a
 		return fmt.Errorf("alloc insert failed: %v", err)
 	}
 
+	// The services of stopped allocations are no longer reachable
+	if copyAlloc.Terminated() {
+		if err := s.deleteServiceRegistrationsByAlloc(txn, index, copyAlloc.ID); err != nil {
+			return err
+		}
+	}
+
 	// Set the job's status
 	forceStatus := ""
 	if !copyAlloc.TerminalStatus() {
@@ -1028,6 +1377,13 @@ func (s *StateStore) UpsertAllocs(index uint64, allocs []*structs.Allocation) er
 			return fmt.Errorf("alloc insert failed: %v", err)
 		}
 
+		// The services of lost allocations are no longer reachable
+		if alloc.ClientStatus == structs.AllocClientStatusLost {
+			if err := s.deleteServiceRegistrationsByAlloc(txn, index, alloc.ID); err != nil {
+				return err
+			}
+		}
+
 		// If the allocation is running, force the job to running status.
 		forceStatus := ""
 		if !alloc.TerminalStatus() {
@@ -1329,6 +1685,277 @@ func (s *StateStore) VaultAccessorsByNode(ws memdb.WatchSet, nodeID string) ([]*
 	return out, nil
 }
 
+// UpsertIdentityKey is used to register an identity key. If the key is
+// active, the previously active key is retired.
+func (s *StateStore) UpsertIdentityKey(index uint64, key *structs.IdentityKey) error {
+	txn := s.db.Txn(true)
+	defer txn.Abort()
+
+	existing, err := txn.First("identity_keys", "id", key.ID)
+	if err != nil {
+		return fmt.Errorf("identity key lookup failed: %v", err)
+	}
+	if existing != nil {
+		key.CreateIndex = existing.(*structs.IdentityKey).CreateIndex
+	} else {
+		key.CreateIndex = index
+	}
+	key.ModifyIndex = index
+
+	// Retire the other active keys
+	if key.Active {
+		iter, err := txn.Get("identity_keys", "id")
+		if err != nil {
+			return fmt.Errorf("identity key lookup failed: %v", err)
+		}
+		var retired []*structs.IdentityKey
+		for {
+			raw := iter.Next()
+			if raw == nil {
+				break
+			}
+			other := raw.(*structs.IdentityKey)
+			if other.Active && other.ID != key.ID {
+				retired = append(retired, other)
+			}
+		}
+		for _, other := range retired {
+			other = other.Copy()
+			other.Active = false
+			other.RetireIndex = index
+			other.ModifyIndex = index
+			if err := txn.Insert("identity_keys", other); err != nil {
+				return fmt.Errorf("identity key insert failed: %v", err)
+			}
+		}
+	}
+
+	if err := txn.Insert("identity_keys", key); err != nil {
+		return fmt.Errorf("identity key insert failed: %v", err)
+	}
+	if err := txn.Insert("index", &IndexEntry{"identity_keys", index}); err != nil {
+		return fmt.Errorf("index update failed: %v", err)
+	}
+
+	txn.Commit()
+	return nil
+}
+
+// DeleteIdentityKeys is used to delete a set of identity keys
+func (s *StateStore) DeleteIdentityKeys(index uint64, ids []string) error {
+	txn := s.db.Txn(true)
+	defer txn.Abort()
+
+	for _, id := range ids {
+		if _, err := txn.DeleteAll("identity_keys", "id", id); err != nil {
+			return fmt.Errorf("identity key delete failed: %v", err)
+		}
+	}
+
+	if err := txn.Insert("index", &IndexEntry{"identity_keys", index}); err != nil {
+		return fmt.Errorf("index update failed: %v", err)
+	}
+
+	txn.Commit()
+	return nil
+}
+
+// IdentityKeyByID returns the identity key with the given ID
+func (s *StateStore) IdentityKeyByID(ws memdb.WatchSet, id string) (*structs.IdentityKey, error) {
+	txn := s.db.Txn(false)
+
+	watchCh, existing, err := txn.FirstWatch("identity_keys", "id", id)
+	if err != nil {
+		return nil, fmt.Errorf("identity key lookup failed: %v", err)
+	}
+
+	ws.Add(watchCh)
+
+	if existing != nil {
+		return existing.(*structs.IdentityKey), nil
+	}
+
+	return nil, nil
+}
+
+// IdentityKeys returns an iterator over all the identity keys
+func (s *StateStore) IdentityKeys(ws memdb.WatchSet) (memdb.ResultIterator, error) {
+	txn := s.db.Txn(false)
+
+	iter, err := txn.Get("identity_keys", "id")
+	if err != nil {
+		return nil, err
+	}
+
+	ws.Add(iter.WatchCh())
+
+	return iter, nil
+}
+
+// ActiveIdentityKey returns the identity key used to sign new workload
+// identities or nil if there is none
+func (s *StateStore) ActiveIdentityKey(ws memdb.WatchSet) (*structs.IdentityKey, error) {
+	iter, err := s.IdentityKeys(ws)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		raw := iter.Next()
+		if raw == nil {
+			return nil, nil
+		}
+		if key := raw.(*structs.IdentityKey); key.Active {
+			return key, nil
+		}
+	}
+}
+
+// UpsertServiceRegistrations is used to register or update instances of
+// services
+func (s *StateStore) UpsertServiceRegistrations(index uint64, services []*structs.ServiceRegistration) error {
+	txn := s.db.Txn(true)
+	defer txn.Abort()
+
+	for _, service := range services {
+		existing, err := txn.First("services", "id", service.ID)
+		if err != nil {
+			return fmt.Errorf("service registration lookup failed: %v", err)
+		}
+
+		if existing != nil {
+			service.CreateIndex = existing.(*structs.ServiceRegistration).CreateIndex
+		} else {
+			service.CreateIndex = index
+		}
+		service.ModifyIndex = index
+
+		if err := txn.Insert("services", service); err != nil {
+			return fmt.Errorf("service registration insert failed: %v", err)
+		}
+	}
+
+	if err := txn.Insert("index", &IndexEntry{"services", index}); err != nil {
+		return fmt.Errorf("index update failed: %v", err)
+	}
+
+	txn.Commit()
+	return nil
+}
+
+// DeleteServiceRegistrations is used to deregister a set of service instances
+func (s *StateStore) DeleteServiceRegistrations(index uint64, ids []string) error {
+	txn := s.db.Txn(true)
+	defer txn.Abort()
+
+	for _, id := range ids {
+		if _, err := txn.DeleteAll("services", "id", id); err != nil {
+			return fmt.Errorf("service registration delete failed: %v", err)
+		}
+	}
+
+	if err := txn.Insert("index", &IndexEntry{"services", index}); err != nil {
+		return fmt.Errorf("index update failed: %v", err)
+	}
+
+	txn.Commit()
+	return nil
+}
+
+// deleteServiceRegistrationsByAlloc deletes the service instances of an
+// allocation within the given transaction
+func (s *StateStore) deleteServiceRegistrationsByAlloc(txn *memdb.Txn, index uint64, allocID string) error {
+	num, err := txn.DeleteAll("services", "alloc_id", allocID)
+	if err != nil {
+		return fmt.Errorf("service registration delete failed: %v", err)
+	}
+	if num == 0 {
+		return nil
+	}
+
+	if err := txn.Insert("index", &IndexEntry{"services", index}); err != nil {
+		return fmt.Errorf("index update failed: %v", err)
+	}
+	return nil
+}
+
+// ServiceRegistrationByID returns the service instance with the given ID
+func (s *StateStore) ServiceRegistrationByID(ws memdb.WatchSet, id string) (*structs.ServiceRegistration, error) {
+	txn := s.db.Txn(false)
+
+	watchCh, existing, err := txn.FirstWatch("services", "id", id)
+	if err != nil {
+		return nil, fmt.Errorf("service registration lookup failed: %v", err)
+	}
+
+	ws.Add(watchCh)
+
+	if existing != nil {
+		return existing.(*structs.ServiceRegistration), nil
+	}
+
+	return nil, nil
+}
+
+// ServiceRegistrations returns an iterator over all the service instances
+func (s *StateStore) ServiceRegistrations(ws memdb.WatchSet) (memdb.ResultIterator, error) {
+	txn := s.db.Txn(false)
+
+	iter, err := txn.Get("services", "id")
+	if err != nil {
+		return nil, err
+	}
+
+	ws.Add(iter.WatchCh())
+
+	return iter, nil
+}
+
+// ServiceRegistrationsByName returns the instances of the service with the
+// given name
+func (s *StateStore) ServiceRegistrationsByName(ws memdb.WatchSet, name string) ([]*structs.ServiceRegistration, error) {
+	txn := s.db.Txn(false)
+
+	iter, err := txn.Get("services", "service_name", name)
+	if err != nil {
+		return nil, err
+	}
+
+	ws.Add(iter.WatchCh())
+
+	var out []*structs.ServiceRegistration
+	for {
+		raw := iter.Next()
+		if raw == nil {
+			break
+		}
+		out = append(out, raw.(*structs.ServiceRegistration))
+	}
+	return out, nil
+}
+
+// ServiceRegistrationsByAlloc returns the service instances of the allocation
+func (s *StateStore) ServiceRegistrationsByAlloc(ws memdb.WatchSet, allocID string) ([]*structs.ServiceRegistration, error) {
+	txn := s.db.Txn(false)
+
+	iter, err := txn.Get("services", "alloc_id", allocID)
+	if err != nil {
+		return nil, err
+	}
+
+	ws.Add(iter.WatchCh())
+
+	var out []*structs.ServiceRegistration
+	for {
+		raw := iter.Next()
+		if raw == nil {
+			break
+		}
+		out = append(out, raw.(*structs.ServiceRegistration))
+	}
+	return out, nil
+}
+
 // LastIndex returns the greatest index value for all indexes
 func (s *StateStore) LatestIndex() (uint64, error) {
 	indexes, err := s.Indexes()
@@ -1822,6 +2449,46 @@ func (s *StateStore) updateSummaryWithAlloc(index uint64, alloc *structs.Allocat
 	return nil
 }
 
+// EvalPlansRestore is used to restore the plans applied for an evaluation
+func (r *StateRestore) EvalPlansRestore(plans *structs.EvalPlans) error {
+	if err := r.txn.Insert("eval_plan", plans); err != nil {
+		return fmt.Errorf("eval plan insert failed: %v", err)
+	}
+	return nil
+}
+
+// ScalingEventsRestore is used to restore the scaling history of a job
+func (r *StateRestore) ScalingEventsRestore(events *structs.JobScalingEvents) error {
+	if err := r.txn.Insert("scaling_event", events); err != nil {
+		return fmt.Errorf("scaling event insert failed: %v", err)
+	}
+	return nil
+}
+
+// ScalingPolicyRestore is used to restore a scaling policy
+func (r *StateRestore) ScalingPolicyRestore(policy *structs.ScalingPolicy) error {
+	if err := r.txn.Insert("scaling_policy", policy); err != nil {
+		return fmt.Errorf("scaling policy insert failed: %v", err)
+	}
+	return nil
+}
+
+// ServiceRegistrationRestore is used to restore a service instance
+func (r *StateRestore) ServiceRegistrationRestore(service *structs.ServiceRegistration) error {
+	if err := r.txn.Insert("services", service); err != nil {
+		return fmt.Errorf("service registration insert failed: %v", err)
+	}
+	return nil
+}
+
+// IdentityKeyRestore is used to restore an identity key
+func (r *StateRestore) IdentityKeyRestore(key *structs.IdentityKey) error {
+	if err := r.txn.Insert("identity_keys", key); err != nil {
+		return fmt.Errorf("identity key insert failed: %v", err)
+	}
+	return nil
+}
+
 // addEphemeralDiskToTaskGroups adds missing EphemeralDisk objects to TaskGroups
 func (s *StateStore) addEphemeralDiskToTaskGroups(job *structs.Job) {
 	for _, tg := range job.TaskGroups {
@@ -9,6 +9,7 @@ import (
 	"time"
 
 	memdb "github.com/hashicorp/go-memdb"
+	"github.com/hashicorp/nomad/helper"
 	"github.com/hashicorp/nomad/nomad/mock"
 	"github.com/hashicorp/nomad/nomad/structs"
 )
@@ -628,6 +629,189 @@ func TestStateStore_UpsertJob_ChildJob(t *testing.T) {
 	}
 }
 
+func TestStateStore_ScaleJob(t *testing.T) {
+	state := testStateStore(t)
+	job := mock.Job()
+	if err := state.UpsertJob(1000, job); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Create watchsets so we can test that scaling fires the watch
+	ws := memdb.NewWatchSet()
+	if _, err := state.ScalingEventsByJob(ws, job.ID); err != nil {
+		t.Fatalf("bad: %v", err)
+	}
+
+	// Record more events than are tracked
+	for i := 0; i < structs.JobTrackedScalingEvents+5; i++ {
+		event := &structs.ScalingEvent{Count: helper.Int64ToPtr(int64(i))}
+		if err := state.ScaleJob(uint64(1001+i), job.ID, "web", event); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+	if !watchFired(ws) {
+		t.Fatalf("bad")
+	}
+
+	ws = memdb.NewWatchSet()
+	out, err := state.JobByID(ws, job.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	last := structs.JobTrackedScalingEvents + 4
+	if out.TaskGroups[0].Count != last || out.JobModifyIndex != uint64(1001+last) {
+		t.Fatalf("bad: %#v", out)
+	}
+
+	events, err := state.ScalingEventsByJob(ws, job.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	web := events.ScalingEvents["web"]
+	if len(web) != structs.JobTrackedScalingEvents {
+		t.Fatalf("bad: %d", len(web))
+	}
+	if *web[0].Count != int64(last) || web[0].PreviousCount != int64(last-1) {
+		t.Fatalf("bad: %#v", web[0])
+	}
+
+	// The original job is not modified
+	if job.TaskGroups[0].Count != 10 {
+		t.Fatalf("bad: %d", job.TaskGroups[0].Count)
+	}
+
+	// Scaling a missing task group fails
+	if err := state.ScaleJob(2000, job.ID, "missing", &structs.ScalingEvent{}); err == nil {
+		t.Fatalf("expected error")
+	}
+
+	// Deleting the job deletes its scaling history
+	if err := state.DeleteJob(2001, job.ID); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !watchFired(ws) {
+		t.Fatalf("bad")
+	}
+	events, err = state.ScalingEventsByJob(ws, job.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if events != nil {
+		t.Fatalf("bad: %#v", events)
+	}
+	index, err := state.Index("scaling_event")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if index != 2001 {
+		t.Fatalf("bad: %d", index)
+	}
+}
+
+func TestStateStore_UpsertJob_ScalingPolicies(t *testing.T) {
+	state := testStateStore(t)
+	job := mock.Job()
+	job.TaskGroups[0].Scaling = &structs.ScalingPolicy{
+		ID:      structs.GenerateUUID(),
+		Min:     1,
+		Max:     20,
+		Enabled: true,
+	}
+	if err := state.UpsertJob(1000, job); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	ws := memdb.NewWatchSet()
+	policy, err := state.ScalingPolicyByTarget(ws, job.ID, "web")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if policy == nil || policy.ID != job.TaskGroups[0].Scaling.ID || policy.JobID != job.ID ||
+		policy.CreateIndex != 1000 || policy.ModifyIndex != 1000 {
+		t.Fatalf("bad: %#v", policy)
+	}
+
+	// Updating the job without changing the policy keeps it untouched
+	job2 := job.Copy()
+	job2.Meta["foo"] = "baz"
+	if err := state.UpsertJob(1001, job2); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if watchFired(ws) {
+		t.Fatalf("bad")
+	}
+
+	// Scaling the job keeps the policy
+	event := &structs.ScalingEvent{Count: helper.Int64ToPtr(5)}
+	if err := state.ScaleJob(1002, job.ID, "web", event); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if watchFired(ws) {
+		t.Fatalf("bad")
+	}
+
+	// A policy without an ID keeps the ID of the existing policy
+	job3 := job.Copy()
+	job3.TaskGroups[0].Scaling.ID = ""
+	job3.TaskGroups[0].Scaling.Max = 30
+	if err := state.UpsertJob(1003, job3); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !watchFired(ws) {
+		t.Fatalf("bad")
+	}
+	ws = memdb.NewWatchSet()
+	out, err := state.ScalingPolicyByID(ws, policy.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out == nil || out.Max != 30 || out.CreateIndex != 1000 || out.ModifyIndex != 1003 {
+		t.Fatalf("bad: %#v", out)
+	}
+	if job3.TaskGroups[0].Scaling.ID != policy.ID {
+		t.Fatalf("bad: %#v", job3.TaskGroups[0].Scaling)
+	}
+
+	// Removing the policy from the task group deletes it
+	job4 := job.Copy()
+	job4.TaskGroups[0].Scaling = nil
+	if err := state.UpsertJob(1004, job4); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !watchFired(ws) {
+		t.Fatalf("bad")
+	}
+	policies, err := state.ScalingPoliciesByJob(nil, job.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(policies) != 0 {
+		t.Fatalf("bad: %#v", policies)
+	}
+
+	// Deleting the job deletes its policies
+	if err := state.UpsertJob(1005, job); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := state.DeleteJob(1006, job.ID); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	iter, err := state.ScalingPolicies(nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if raw := iter.Next(); raw != nil {
+		t.Fatalf("bad: %#v", raw)
+	}
+	index, err := state.Index("scaling_policy")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if index != 1006 {
+		t.Fatalf("bad: %d", index)
+	}
+}
+
 func TestStateStore_DeleteJob_Job(t *testing.T) {
 	state := testStateStore(t)
 	job := mock.Job()
@@ -1888,6 +2072,72 @@ func TestStateStore_DeleteEval_Eval(t *testing.T) {
 	}
 }
 
+func TestStateStore_UpsertEvalPlan(t *testing.T) {
+	state := testStateStore(t)
+	eval := mock.Eval()
+	if err := state.UpsertEvals(1000, []*structs.Evaluation{eval}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Create a watchset so we can test that upsert fires the watch
+	ws := memdb.NewWatchSet()
+	if _, err := state.EvalPlansByEval(ws, eval.ID); err != nil {
+		t.Fatalf("bad: %v", err)
+	}
+
+	// Record more plans than are tracked
+	for i := 0; i < structs.EvalTrackedPlans+2; i++ {
+		plan := &structs.AppliedPlan{EvalID: eval.ID, JobID: eval.JobID}
+		plan.AddAllocs([]*structs.Allocation{mock.Alloc()})
+		plan.AllocIndex = uint64(1001 + i)
+		if err := state.UpsertEvalPlan(uint64(1001+i), plan); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+	if !watchFired(ws) {
+		t.Fatalf("bad")
+	}
+
+	ws = memdb.NewWatchSet()
+	out, err := state.EvalPlansByEval(ws, eval.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	last := uint64(1001 + structs.EvalTrackedPlans + 1)
+	if out.CreateIndex != 1001 || out.ModifyIndex != last {
+		t.Fatalf("bad: %#v", out)
+	}
+	if len(out.Plans) != structs.EvalTrackedPlans || out.Plans[0].AllocIndex != last {
+		t.Fatalf("bad: %#v", out.Plans)
+	}
+
+	index, err := state.Index("eval_plan")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if index != last {
+		t.Fatalf("bad: %d", index)
+	}
+
+	// Deleting the eval deletes its plans
+	if err := state.DeleteEval(2000, []string{eval.ID}, nil); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !watchFired(ws) {
+		t.Fatalf("bad")
+	}
+	out, err = state.EvalPlansByEval(nil, eval.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out != nil {
+		t.Fatalf("bad: %#v", out)
+	}
+	if index, _ := state.Index("eval_plan"); index != 2000 {
+		t.Fatalf("bad: %d", index)
+	}
+}
+
 func TestStateStore_DeleteEval_ChildJob(t *testing.T) {
 	state := testStateStore(t)
 
@@ -2180,11 +2430,12 @@ func TestStateStore_UpdateAllocsFromClient(t *testing.T) {
 	// Create the delta updates
 	ts := map[string]*structs.TaskState{"web": &structs.TaskState{State: structs.TaskStateRunning}}
 	update := &structs.Allocation{
-		ID:           alloc.ID,
-		ClientStatus: structs.AllocClientStatusComplete,
-		TaskStates:   ts,
-		JobID:        alloc.JobID,
-		TaskGroup:    alloc.TaskGroup,
+		ID:            alloc.ID,
+		ClientStatus:  structs.AllocClientStatusComplete,
+		TaskStates:    ts,
+		NetworkStatus: &structs.AllocNetworkStatus{InterfaceName: "eth0", Address: "10.0.0.5"},
+		JobID:         alloc.JobID,
+		TaskGroup:     alloc.TaskGroup,
 	}
 	err = state.UpdateAllocsFromClient(1001, []*structs.Allocation{update})
 	if err != nil {
@@ -2195,6 +2446,14 @@ func TestStateStore_UpdateAllocsFromClient(t *testing.T) {
 		t.Fatalf("bad")
 	}
 
+	out, err := state.AllocByID(nil, alloc.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !reflect.DeepEqual(out.NetworkStatus, update.NetworkStatus) {
+		t.Fatalf("bad network status: %#v", out.NetworkStatus)
+	}
+
 	ws = memdb.NewWatchSet()
 	summary, err = state.JobSummaryByID(ws, parent.ID)
 	if err != nil {
@@ -4094,6 +4353,265 @@ func TestStateStore_RestoreVaultAccessor(t *testing.T) {
 	}
 }
 
+func TestStateStore_UpsertIdentityKey(t *testing.T) {
+	state := testStateStore(t)
+	k1 := mock.IdentityKey()
+
+	ws := memdb.NewWatchSet()
+	if _, err := state.IdentityKeyByID(ws, k1.ID); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := state.UpsertIdentityKey(1000, k1); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !watchFired(ws) {
+		t.Fatalf("bad")
+	}
+
+	// Upserting a new active key retires the previous one
+	k2 := mock.IdentityKey()
+	if err := state.UpsertIdentityKey(1001, k2); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	ws = memdb.NewWatchSet()
+	out, err := state.IdentityKeyByID(ws, k1.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out.Active || out.RetireIndex != 1001 || out.CreateIndex != 1000 || out.ModifyIndex != 1001 {
+		t.Fatalf("bad: %#v", out)
+	}
+	if k1.Active == false {
+		t.Fatalf("upserted key should not be modified")
+	}
+
+	active, err := state.ActiveIdentityKey(ws)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if active == nil || active.ID != k2.ID || active.CreateIndex != 1001 {
+		t.Fatalf("bad: %#v", active)
+	}
+
+	index, err := state.Index("identity_keys")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if index != 1001 {
+		t.Fatalf("bad: %d", index)
+	}
+}
+
+func TestStateStore_DeleteIdentityKeys(t *testing.T) {
+	state := testStateStore(t)
+	k1 := mock.IdentityKey()
+	k2 := mock.IdentityKey()
+	if err := state.UpsertIdentityKey(1000, k1); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := state.UpsertIdentityKey(1001, k2); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	ws := memdb.NewWatchSet()
+	if _, err := state.IdentityKeys(ws); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := state.DeleteIdentityKeys(1002, []string{k1.ID}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !watchFired(ws) {
+		t.Fatalf("bad")
+	}
+
+	ws = memdb.NewWatchSet()
+	out, err := state.IdentityKeyByID(ws, k1.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out != nil {
+		t.Fatalf("key should be deleted: %#v", out)
+	}
+	if out, _ := state.IdentityKeyByID(ws, k2.ID); out == nil {
+		t.Fatalf("key should exist")
+	}
+
+	index, err := state.Index("identity_keys")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if index != 1002 {
+		t.Fatalf("bad: %d", index)
+	}
+}
+
+func TestStateStore_RestoreIdentityKey(t *testing.T) {
+	state := testStateStore(t)
+	k := mock.IdentityKey()
+
+	restore, err := state.Restore()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	err = restore.IdentityKeyRestore(k)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	restore.Commit()
+
+	ws := memdb.NewWatchSet()
+	out, err := state.IdentityKeyByID(ws, k.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	if !reflect.DeepEqual(out, k) {
+		t.Fatalf("Bad: %#v %#v", out, k)
+	}
+
+	if watchFired(ws) {
+		t.Fatalf("bad")
+	}
+}
+
+func TestStateStore_UpsertServiceRegistrations(t *testing.T) {
+	state := testStateStore(t)
+	s1 := mock.ServiceRegistration()
+	s2 := mock.ServiceRegistration()
+	s2.ServiceName = "db"
+
+	ws := memdb.NewWatchSet()
+	if _, err := state.ServiceRegistrationsByName(ws, s1.ServiceName); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := state.UpsertServiceRegistrations(1000, []*structs.ServiceRegistration{s1, s2}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !watchFired(ws) {
+		t.Fatalf("bad")
+	}
+
+	// Updating the registration keeps its create index
+	update := s1.Copy()
+	update.Status = "critical"
+	if err := state.UpsertServiceRegistrations(1001, []*structs.ServiceRegistration{update}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	ws = memdb.NewWatchSet()
+	out, err := state.ServiceRegistrationsByName(ws, s1.ServiceName)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(out) != 1 || out[0].Status != "critical" || out[0].CreateIndex != 1000 || out[0].ModifyIndex != 1001 {
+		t.Fatalf("bad: %#v", out)
+	}
+
+	byAlloc, err := state.ServiceRegistrationsByAlloc(ws, s2.AllocID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(byAlloc) != 1 || byAlloc[0].ID != s2.ID {
+		t.Fatalf("bad: %#v", byAlloc)
+	}
+
+	index, err := state.Index("services")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if index != 1001 {
+		t.Fatalf("bad: %d", index)
+	}
+}
+
+func TestStateStore_DeleteServiceRegistrations(t *testing.T) {
+	state := testStateStore(t)
+	s1 := mock.ServiceRegistration()
+	s2 := mock.ServiceRegistration()
+	if err := state.UpsertServiceRegistrations(1000, []*structs.ServiceRegistration{s1, s2}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	ws := memdb.NewWatchSet()
+	if _, err := state.ServiceRegistrations(ws); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := state.DeleteServiceRegistrations(1001, []string{s1.ID}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !watchFired(ws) {
+		t.Fatalf("bad")
+	}
+
+	ws = memdb.NewWatchSet()
+	if out, _ := state.ServiceRegistrationByID(ws, s1.ID); out != nil {
+		t.Fatalf("registration should be deleted: %#v", out)
+	}
+	if out, _ := state.ServiceRegistrationByID(ws, s2.ID); out == nil {
+		t.Fatalf("registration should exist")
+	}
+
+	index, err := state.Index("services")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if index != 1001 {
+		t.Fatalf("bad: %d", index)
+	}
+}
+
+func TestStateStore_ServiceRegistrations_TerminalAlloc(t *testing.T) {
+	state := testStateStore(t)
+	alloc := mock.Alloc()
+	if err := state.UpsertJobSummary(999, mock.JobSummary(alloc.JobID)); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := state.UpsertAllocs(1000, []*structs.Allocation{alloc}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	service := mock.ServiceRegistration()
+	service.AllocID = alloc.ID
+	service.NodeID = alloc.NodeID
+	if err := state.UpsertServiceRegistrations(1001, []*structs.ServiceRegistration{service}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// A running allocation keeps its services
+	update := alloc.Copy()
+	update.ClientStatus = structs.AllocClientStatusRunning
+	if err := state.UpdateAllocsFromClient(1002, []*structs.Allocation{update}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	ws := memdb.NewWatchSet()
+	if out, _ := state.ServiceRegistrationByID(ws, service.ID); out == nil {
+		t.Fatalf("registration should exist")
+	}
+
+	// A stopped allocation has its services removed
+	update = alloc.Copy()
+	update.ClientStatus = structs.AllocClientStatusComplete
+	if err := state.UpdateAllocsFromClient(1003, []*structs.Allocation{update}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !watchFired(ws) {
+		t.Fatalf("bad")
+	}
+	if out, _ := state.ServiceRegistrationByID(nil, service.ID); out != nil {
+		t.Fatalf("registration should be deleted: %#v", out)
+	}
+
+	index, err := state.Index("services")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if index != 1003 {
+		t.Fatalf("bad: %d", index)
+	}
+}
+
 func TestStateStore_Abandon(t *testing.T) {
 	s := testStateStore(t)
 	abandonCh := s.AbandonCh()
@@ -0,0 +1,77 @@
+package config
+
+import "github.com/hashicorp/nomad/helper"
+
+// ArtifactConfig configures how a Nomad client downloads task artifacts.
+type ArtifactConfig struct {
+	// CacheDir is the directory in which downloaded artifacts are cached. It
+	// defaults to a directory inside the client's state directory.
+	CacheDir string `mapstructure:"cache_dir"`
+
+	// CacheMaxSizeMB is the maximum size of the artifact cache in megabytes.
+	// Once exceeded, the least recently used artifacts are evicted. Setting
+	// it to zero disables caching.
+	CacheMaxSizeMB int `mapstructure:"cache_max_size_mb"`
+
+	// RequireChecksum rejects file artifacts that do not specify a checksum
+	// option.
+	RequireChecksum *bool `mapstructure:"require_checksum"`
+
+	// GPGKeyrings is the set of keyrings holding the public keys that
+	// artifact signatures are verified against.
+	GPGKeyrings []string `mapstructure:"gpg_keyrings"`
+}
+
+// DefaultArtifactConfig returns the canonical defaults for the artifact
+// downloads of a Nomad client.
+func DefaultArtifactConfig() *ArtifactConfig {
+	return &ArtifactConfig{
+		RequireChecksum: helper.BoolToPtr(false),
+	}
+}
+
+// CacheEnabled returns whether downloaded artifacts should be cached.
+func (a *ArtifactConfig) CacheEnabled() bool {
+	return a != nil && a.CacheDir != "" && a.CacheMaxSizeMB > 0
+}
+
+// ChecksumRequired returns whether file artifacts must specify a checksum.
+func (a *ArtifactConfig) ChecksumRequired() bool {
+	return a != nil && a.RequireChecksum != nil && *a.RequireChecksum
+}
+
+// Merge merges two ArtifactConfigs together, with values in b taking
+// precedence.
+func (a *ArtifactConfig) Merge(b *ArtifactConfig) *ArtifactConfig {
+	result := a.Copy()
+
+	if b.CacheDir != "" {
+		result.CacheDir = b.CacheDir
+	}
+	if b.CacheMaxSizeMB != 0 {
+		result.CacheMaxSizeMB = b.CacheMaxSizeMB
+	}
+	if b.RequireChecksum != nil {
+		result.RequireChecksum = helper.BoolToPtr(*b.RequireChecksum)
+	}
+	if len(b.GPGKeyrings) != 0 {
+		result.GPGKeyrings = helper.CopySliceString(b.GPGKeyrings)
+	}
+
+	return result
+}
+
+// Copy returns a copy of this ArtifactConfig.
+func (a *ArtifactConfig) Copy() *ArtifactConfig {
+	if a == nil {
+		return nil
+	}
+
+	nc := new(ArtifactConfig)
+	*nc = *a
+	if a.RequireChecksum != nil {
+		nc.RequireChecksum = helper.BoolToPtr(*a.RequireChecksum)
+	}
+	nc.GPGKeyrings = helper.CopySliceString(a.GPGKeyrings)
+	return nc
+}
@@ -0,0 +1,35 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestArtifactConfig_Merge(t *testing.T) {
+	trueValue, falseValue := true, false
+	c1 := &ArtifactConfig{
+		CacheDir:        "1",
+		CacheMaxSizeMB:  1,
+		RequireChecksum: &falseValue,
+		GPGKeyrings:     []string{"1"},
+	}
+
+	c2 := &ArtifactConfig{
+		CacheDir:        "2",
+		CacheMaxSizeMB:  0,
+		RequireChecksum: &trueValue,
+		GPGKeyrings:     []string{"2", "3"},
+	}
+
+	e := &ArtifactConfig{
+		CacheDir:        "2",
+		CacheMaxSizeMB:  1,
+		RequireChecksum: &trueValue,
+		GPGKeyrings:     []string{"2", "3"},
+	}
+
+	result := c1.Merge(c2)
+	if !reflect.DeepEqual(result, e) {
+		t.Fatalf("bad:\n%#v\n%#v", result, e)
+	}
+}
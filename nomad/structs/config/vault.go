@@ -3,6 +3,7 @@ package config
 import (
 	"time"
 
+	"github.com/hashicorp/nomad/helper"
 	vault "github.com/hashicorp/vault/api"
 )
 
@@ -37,6 +38,11 @@ type VaultConfig struct {
 	// role the token is from.
 	Role string `mapstructure:"create_from_role"`
 
+	// AllowedRoles is the set of roles, other than the create_from_role, that
+	// jobs may create their tasks' tokens from. Nomad's token must have the
+	// same capabilities on these roles as on the create_from_role.
+	AllowedRoles []string `mapstructure:"allowed_roles"`
+
 	// AllowUnauthenticated allows users to submit jobs requiring Vault tokens
 	// without providing a Vault token proving they have access to these
 	// policies.
@@ -99,6 +105,20 @@ func (a *VaultConfig) AllowsUnauthenticated() bool {
 	return a.AllowUnauthenticated != nil && *a.AllowUnauthenticated
 }
 
+// AllowsRole returns whether task tokens may be created from the given role.
+// The empty role is the create_from_role.
+func (a *VaultConfig) AllowsRole(role string) bool {
+	if role == "" || role == a.Role {
+		return true
+	}
+	for _, r := range a.AllowedRoles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
 // Merge merges two Vault configurations together.
 func (a *VaultConfig) Merge(b *VaultConfig) *VaultConfig {
 	result := *a
@@ -109,6 +129,9 @@ func (a *VaultConfig) Merge(b *VaultConfig) *VaultConfig {
 	if b.Role != "" {
 		result.Role = b.Role
 	}
+	if len(b.AllowedRoles) != 0 {
+		result.AllowedRoles = helper.CopySliceString(b.AllowedRoles)
+	}
 	if b.TaskTokenTTL != "" {
 		result.TaskTokenTTL = b.TaskTokenTTL
 	}
@@ -179,5 +202,6 @@ func (c *VaultConfig) Copy() *VaultConfig {
 
 	nc := new(VaultConfig)
 	*nc = *c
+	nc.AllowedRoles = helper.CopySliceString(c.AllowedRoles)
 	return nc
 }
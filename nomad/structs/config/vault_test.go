@@ -11,6 +11,7 @@ func TestVaultConfig_Merge(t *testing.T) {
 		Enabled:              &falseValue,
 		Token:                "1",
 		Role:                 "1",
+		AllowedRoles:         []string{"1"},
 		AllowUnauthenticated: &trueValue,
 		TaskTokenTTL:         "1",
 		Addr:                 "1",
@@ -26,6 +27,7 @@ func TestVaultConfig_Merge(t *testing.T) {
 		Enabled:              &trueValue,
 		Token:                "2",
 		Role:                 "2",
+		AllowedRoles:         []string{"2", "3"},
 		AllowUnauthenticated: &falseValue,
 		TaskTokenTTL:         "2",
 		Addr:                 "2",
@@ -41,6 +43,7 @@ func TestVaultConfig_Merge(t *testing.T) {
 		Enabled:              &trueValue,
 		Token:                "2",
 		Role:                 "2",
+		AllowedRoles:         []string{"2", "3"},
 		AllowUnauthenticated: &falseValue,
 		TaskTokenTTL:         "2",
 		Addr:                 "2",
@@ -57,3 +60,19 @@ func TestVaultConfig_Merge(t *testing.T) {
 		t.Fatalf("bad:\n%#v\n%#v", result, e)
 	}
 }
+
+func TestVaultConfig_AllowsRole(t *testing.T) {
+	c := &VaultConfig{
+		Role:         "default",
+		AllowedRoles: []string{"a", "b"},
+	}
+
+	for _, role := range []string{"", "default", "a", "b"} {
+		if !c.AllowsRole(role) {
+			t.Fatalf("role %q should be allowed", role)
+		}
+	}
+	if c.AllowsRole("c") {
+		t.Fatalf("role c should not be allowed")
+	}
+}
@@ -224,6 +224,16 @@ func (tg *TaskGroup) Diff(other *TaskGroup, contextual bool) (*TaskGroupDiff, er
 		diff.Objects = append(diff.Objects, diskDiff)
 	}
 
+	// Network Resources diff
+	if nDiffs := networkResourceDiffs(tg.Networks, other.Networks, contextual); nDiffs != nil {
+		diff.Objects = append(diff.Objects, nDiffs...)
+	}
+
+	// Scaling policy diff
+	if sDiff := scalingPolicyDiff(tg.Scaling, other.Scaling, contextual); sDiff != nil {
+		diff.Objects = append(diff.Objects, sDiff)
+	}
+
 	// Tasks diff
 	tasks, err := taskDiffs(tg.Tasks, other.Tasks, contextual)
 	if err != nil {
@@ -370,8 +380,7 @@ func (t *Task) Diff(other *Task, contextual bool) (*TaskDiff, error) {
 	}
 
 	// LogConfig diff
-	lDiff := primitiveObjectDiff(t.LogConfig, other.LogConfig, nil, "LogConfig", contextual)
-	if lDiff != nil {
+	if lDiff := logConfigDiff(t.LogConfig, other.LogConfig, contextual); lDiff != nil {
 		diff.Objects = append(diff.Objects, lDiff)
 	}
 
@@ -403,6 +412,11 @@ func (t *Task) Diff(other *Task, contextual bool) (*TaskDiff, error) {
 		diff.Objects = append(diff.Objects, vDiff)
 	}
 
+	// Identity diff
+	if iDiff := primitiveObjectDiff(t.Identity, other.Identity, nil, "Identity", contextual); iDiff != nil {
+		diff.Objects = append(diff.Objects, iDiff)
+	}
+
 	// Template diff
 	tmplDiffs := primitiveObjectSetDiff(
 		interfaceSlice(t.Templates),
@@ -510,6 +524,115 @@ func serviceDiff(old, new *Service, contextual bool) *ObjectDiff {
 		diff.Objects = append(diff.Objects, cDiffs...)
 	}
 
+	// Connect diffs
+	if cDiff := connectDiff(old.Connect, new.Connect, contextual); cDiff != nil {
+		diff.Objects = append(diff.Objects, cDiff)
+	}
+
+	return diff
+}
+
+// logConfigDiff returns the diff of two log configs. If contextual diff is
+// enabled, all fields will be returned, even if no diff occurred.
+func logConfigDiff(old, new *LogConfig, contextual bool) *ObjectDiff {
+	diff := primitiveObjectDiff(old, new, nil, "LogConfig", contextual)
+
+	var oldSinks, newSinks []*LogSink
+	if old != nil {
+		oldSinks = old.Sinks
+	}
+	if new != nil {
+		newSinks = new.Sinks
+	}
+	sDiffs := primitiveObjectSetDiff(
+		interfaceSlice(oldSinks),
+		interfaceSlice(newSinks),
+		nil,
+		"Sink",
+		contextual)
+	if sDiffs == nil {
+		return diff
+	}
+
+	if diff == nil {
+		diff = &ObjectDiff{Type: DiffTypeEdited, Name: "LogConfig"}
+		if contextual {
+			oldPrimitiveFlat := flatmap.Flatten(old, nil, true)
+			newPrimitiveFlat := flatmap.Flatten(new, nil, true)
+			delete(oldPrimitiveFlat, "")
+			delete(newPrimitiveFlat, "")
+			diff.Fields = fieldDiffs(oldPrimitiveFlat, newPrimitiveFlat, contextual)
+		}
+	}
+	diff.Objects = append(diff.Objects, sDiffs...)
+	return diff
+}
+
+// connectDiff returns the diff of two Consul Connect objects. If contextual
+// diff is enabled, all fields will be returned, even if no diff occurred.
+func connectDiff(old, new *ConsulConnect, contextual bool) *ObjectDiff {
+	diff := &ObjectDiff{Type: DiffTypeNone, Name: "ConsulConnect"}
+
+	if reflect.DeepEqual(old, new) {
+		return nil
+	} else if old == nil {
+		old = &ConsulConnect{}
+		diff.Type = DiffTypeAdded
+	} else if new == nil {
+		new = &ConsulConnect{}
+		diff.Type = DiffTypeDeleted
+	} else {
+		diff.Type = DiffTypeEdited
+	}
+
+	if sDiff := sidecarServiceDiff(old.SidecarService, new.SidecarService, contextual); sDiff != nil {
+		diff.Objects = append(diff.Objects, sDiff)
+	}
+
+	return diff
+}
+
+// sidecarServiceDiff returns the diff of two Connect sidecar service objects.
+// If contextual diff is enabled, all fields will be returned, even if no diff
+// occurred.
+func sidecarServiceDiff(old, new *ConsulSidecarService, contextual bool) *ObjectDiff {
+	diff := &ObjectDiff{Type: DiffTypeNone, Name: "SidecarService"}
+	var oldPrimitiveFlat, newPrimitiveFlat map[string]string
+
+	if reflect.DeepEqual(old, new) {
+		return nil
+	} else if old == nil {
+		old = &ConsulSidecarService{}
+		diff.Type = DiffTypeAdded
+		newPrimitiveFlat = flatmap.Flatten(new, nil, true)
+	} else if new == nil {
+		new = &ConsulSidecarService{}
+		diff.Type = DiffTypeDeleted
+		oldPrimitiveFlat = flatmap.Flatten(old, nil, true)
+	} else {
+		diff.Type = DiffTypeEdited
+		oldPrimitiveFlat = flatmap.Flatten(old, nil, true)
+		newPrimitiveFlat = flatmap.Flatten(new, nil, true)
+	}
+
+	// Diff the primitive fields.
+	diff.Fields = fieldDiffs(oldPrimitiveFlat, newPrimitiveFlat, contextual)
+
+	// Upstream diffs
+	var oldUpstreams, newUpstreams []*ConsulUpstream
+	if old.Proxy != nil {
+		oldUpstreams = old.Proxy.Upstreams
+	}
+	if new.Proxy != nil {
+		newUpstreams = new.Proxy.Upstreams
+	}
+	if uDiffs := primitiveObjectSetDiff(
+		interfaceSlice(oldUpstreams),
+		interfaceSlice(newUpstreams),
+		nil, "Upstream", contextual); uDiffs != nil {
+		diff.Objects = append(diff.Objects, uDiffs...)
+	}
+
 	return diff
 }
 
@@ -570,9 +693,33 @@ func serviceCheckDiff(old, new *ServiceCheck, contextual bool) *ObjectDiff {
 
 	// Diff the primitive fields.
 	diff.Fields = fieldDiffs(oldPrimitiveFlat, newPrimitiveFlat, contextual)
+
+	// Header diffs
+	if headerDiff := checkHeaderDiff(old.Header, new.Header, contextual); headerDiff != nil {
+		diff.Objects = append(diff.Objects, headerDiff)
+	}
+
+	// CheckRestart diffs
+	if crDiff := primitiveObjectDiff(old.CheckRestart, new.CheckRestart, nil, "CheckRestart", contextual); crDiff != nil {
+		diff.Objects = append(diff.Objects, crDiff)
+	}
+
 	return diff
 }
 
+// checkHeaderDiff returns the diff of the HTTP headers of a service check.
+// Multiple values of a header are compared as a comma separated list.
+func checkHeaderDiff(old, new map[string][]string, contextual bool) *ObjectDiff {
+	flatten := func(header map[string][]string) map[string]string {
+		flat := make(map[string]string, len(header))
+		for k, v := range header {
+			flat[k] = strings.Join(v, ",")
+		}
+		return flat
+	}
+	return primitiveObjectDiff(flatten(old), flatten(new), nil, "Header", contextual)
+}
+
 // serviceCheckDiffs diffs a set of service checks. If contextual diff is
 // enabled, unchanged fields within objects nested in the tasks will be
 // returned.
@@ -640,6 +787,45 @@ func vaultDiff(old, new *Vault, contextual bool) *ObjectDiff {
 	return diff
 }
 
+// scalingPolicyDiff returns the diff of two scaling policy objects, ignoring
+// the identifying fields set by the servers. If contextual diff is enabled,
+// all fields will be returned, even if no diff occurred.
+func scalingPolicyDiff(old, new *ScalingPolicy, contextual bool) *ObjectDiff {
+	diff := &ObjectDiff{Type: DiffTypeNone, Name: "Scaling"}
+	var oldPrimitiveFlat, newPrimitiveFlat map[string]string
+	filter := []string{"ID", "JobID", "TaskGroup", "CreateIndex", "ModifyIndex"}
+
+	if old == nil && new == nil {
+		return nil
+	} else if old == nil {
+		old = &ScalingPolicy{}
+		diff.Type = DiffTypeAdded
+		newPrimitiveFlat = flatmap.Flatten(new, filter, true)
+	} else if new == nil {
+		new = &ScalingPolicy{}
+		diff.Type = DiffTypeDeleted
+		oldPrimitiveFlat = flatmap.Flatten(old, filter, true)
+	} else {
+		oldPrimitiveFlat = flatmap.Flatten(old, filter, true)
+		newPrimitiveFlat = flatmap.Flatten(new, filter, true)
+		if reflect.DeepEqual(oldPrimitiveFlat, newPrimitiveFlat) && reflect.DeepEqual(old.Policy, new.Policy) {
+			return nil
+		}
+		diff.Type = DiffTypeEdited
+	}
+
+	// Diff the primitive fields.
+	diff.Fields = fieldDiffs(oldPrimitiveFlat, newPrimitiveFlat, contextual)
+
+	// Policy diff
+	if pDiff := configDiff(old.Policy, new.Policy, contextual); pDiff != nil {
+		pDiff.Name = "Policy"
+		diff.Objects = append(diff.Objects, pDiff)
+	}
+
+	return diff
+}
+
 // parameterizedJobDiff returns the diff of two parameterized job objects. If
 // contextual diff is enabled, all fields will be returned, even if no diff
 // occurred.
@@ -1726,6 +1726,62 @@ func TestTaskGroupDiff(t *testing.T) {
 				},
 			},
 		},
+		{
+			// Scaling policy edited
+			Old: &TaskGroup{
+				Scaling: &ScalingPolicy{
+					ID:      "foo",
+					Min:     1,
+					Max:     5,
+					Enabled: true,
+					Policy: map[string]interface{}{
+						"target": 70,
+					},
+				},
+			},
+			New: &TaskGroup{
+				Scaling: &ScalingPolicy{
+					ID:      "bar",
+					Min:     1,
+					Max:     10,
+					Enabled: true,
+					Policy: map[string]interface{}{
+						"target": 80,
+					},
+				},
+			},
+			Expected: &TaskGroupDiff{
+				Type: DiffTypeEdited,
+				Objects: []*ObjectDiff{
+					{
+						Type: DiffTypeEdited,
+						Name: "Scaling",
+						Fields: []*FieldDiff{
+							{
+								Type: DiffTypeEdited,
+								Name: "Max",
+								Old:  "5",
+								New:  "10",
+							},
+						},
+						Objects: []*ObjectDiff{
+							{
+								Type: DiffTypeEdited,
+								Name: "Policy",
+								Fields: []*FieldDiff{
+									{
+										Type: DiffTypeEdited,
+										Name: "target",
+										Old:  "70",
+										New:  "80",
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
 		{
 			// EphemeralDisk edited with context
 			Contextual: true,
@@ -2188,6 +2244,18 @@ func TestTaskDiff(t *testing.T) {
 						Type: DiffTypeAdded,
 						Name: "LogConfig",
 						Fields: []*FieldDiff{
+							{
+								Type: DiffTypeAdded,
+								Name: "Compress",
+								Old:  "",
+								New:  "false",
+							},
+							{
+								Type: DiffTypeAdded,
+								Name: "MaxAge",
+								Old:  "",
+								New:  "0",
+							},
 							{
 								Type: DiffTypeAdded,
 								Name: "MaxFileSizeMB",
@@ -2221,6 +2289,18 @@ func TestTaskDiff(t *testing.T) {
 						Type: DiffTypeDeleted,
 						Name: "LogConfig",
 						Fields: []*FieldDiff{
+							{
+								Type: DiffTypeDeleted,
+								Name: "Compress",
+								Old:  "false",
+								New:  "",
+							},
+							{
+								Type: DiffTypeDeleted,
+								Name: "MaxAge",
+								Old:  "0",
+								New:  "",
+							},
 							{
 								Type: DiffTypeDeleted,
 								Name: "MaxFileSizeMB",
@@ -2298,6 +2378,18 @@ func TestTaskDiff(t *testing.T) {
 						Type: DiffTypeEdited,
 						Name: "LogConfig",
 						Fields: []*FieldDiff{
+							{
+								Type: DiffTypeNone,
+								Name: "Compress",
+								Old:  "false",
+								New:  "false",
+							},
+							{
+								Type: DiffTypeNone,
+								Name: "MaxAge",
+								Old:  "0",
+								New:  "0",
+							},
 							{
 								Type: DiffTypeEdited,
 								Name: "MaxFileSizeMB",
@@ -2315,6 +2407,74 @@ func TestTaskDiff(t *testing.T) {
 				},
 			},
 		},
+		{
+			// LogConfig sink added
+			Old: &Task{
+				LogConfig: &LogConfig{
+					MaxFiles:      1,
+					MaxFileSizeMB: 10,
+				},
+			},
+			New: &Task{
+				LogConfig: &LogConfig{
+					MaxFiles:      1,
+					MaxFileSizeMB: 10,
+					Sinks: []*LogSink{
+						{
+							Type:    LogSinkTypeSyslog,
+							Address: "udp://127.0.0.1:514",
+						},
+					},
+				},
+			},
+			Expected: &TaskDiff{
+				Type: DiffTypeEdited,
+				Objects: []*ObjectDiff{
+					{
+						Type: DiffTypeEdited,
+						Name: "LogConfig",
+						Objects: []*ObjectDiff{
+							{
+								Type: DiffTypeAdded,
+								Name: "Sink",
+								Fields: []*FieldDiff{
+									{
+										Type: DiffTypeAdded,
+										Name: "Address",
+										Old:  "",
+										New:  "udp://127.0.0.1:514",
+									},
+									{
+										Type: DiffTypeAdded,
+										Name: "BatchSize",
+										Old:  "",
+										New:  "0",
+									},
+									{
+										Type: DiffTypeAdded,
+										Name: "BatchWait",
+										Old:  "",
+										New:  "0",
+									},
+									{
+										Type: DiffTypeAdded,
+										Name: "BufferSize",
+										Old:  "",
+										New:  "0",
+									},
+									{
+										Type: DiffTypeAdded,
+										Name: "Type",
+										Old:  "",
+										New:  "syslog",
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
 		{
 			// Artifacts edited
 			Old: &Task{
@@ -2349,7 +2509,8 @@ func TestTaskDiff(t *testing.T) {
 						GetterOptions: map[string]string{
 							"bam": "baz",
 						},
-						RelativeDest: "bam",
+						RelativeDest:    "bam",
+						GetterSignature: "bam.sig",
 					},
 				},
 			},
@@ -2366,6 +2527,12 @@ func TestTaskDiff(t *testing.T) {
 								Old:  "",
 								New:  "baz",
 							},
+							{
+								Type: DiffTypeAdded,
+								Name: "GetterSignature",
+								Old:  "",
+								New:  "bam.sig",
+							},
 							{
 								Type: DiffTypeAdded,
 								Name: "GetterSource",
@@ -2593,6 +2760,12 @@ func TestTaskDiff(t *testing.T) {
 												Old:  "",
 												New:  "foo",
 											},
+											{
+												Type: DiffTypeAdded,
+												Name: "To",
+												Old:  "",
+												New:  "0",
+											},
 											{
 												Type: DiffTypeAdded,
 												Name: "Value",
@@ -2611,6 +2784,12 @@ func TestTaskDiff(t *testing.T) {
 												Old:  "",
 												New:  "baz",
 											},
+											{
+												Type: DiffTypeAdded,
+												Name: "To",
+												Old:  "",
+												New:  "0",
+											},
 										},
 									},
 								},
@@ -2637,6 +2816,12 @@ func TestTaskDiff(t *testing.T) {
 												Old:  "foo",
 												New:  "",
 											},
+											{
+												Type: DiffTypeDeleted,
+												Name: "To",
+												Old:  "0",
+												New:  "",
+											},
 											{
 												Type: DiffTypeDeleted,
 												Name: "Value",
@@ -2655,6 +2840,12 @@ func TestTaskDiff(t *testing.T) {
 												Old:  "bar",
 												New:  "",
 											},
+											{
+												Type: DiffTypeDeleted,
+												Name: "To",
+												Old:  "0",
+												New:  "",
+											},
 										},
 									},
 								},
@@ -2862,6 +3053,12 @@ func TestTaskDiff(t *testing.T) {
 								Old:  "boom_port",
 								New:  "boom_port",
 							},
+							{
+								Type: DiffTypeNone,
+								Name: "boom.To",
+								Old:  "0",
+								New:  "0",
+							},
 							{
 								Type: DiffTypeNone,
 								Name: "boom.Value",
@@ -3005,6 +3202,12 @@ func TestTaskDiff(t *testing.T) {
 								Old:  "foo",
 								New:  "bar",
 							},
+							{
+								Type: DiffTypeNone,
+								Name: "Provider",
+								Old:  "",
+								New:  "",
+							},
 						},
 					},
 				},
@@ -3119,6 +3322,12 @@ func TestTaskDiff(t *testing.T) {
 										Old:  "",
 										New:  "foo",
 									},
+									{
+										Type: DiffTypeAdded,
+										Name: "GRPCUseTLS",
+										Old:  "",
+										New:  "false",
+									},
 									{
 										Type: DiffTypeAdded,
 										Name: "Interval",
@@ -3143,6 +3352,12 @@ func TestTaskDiff(t *testing.T) {
 										Old:  "",
 										New:  "http",
 									},
+									{
+										Type: DiffTypeAdded,
+										Name: "TLSSkipVerify",
+										Old:  "",
+										New:  "false",
+									},
 									{
 										Type: DiffTypeAdded,
 										Name: "Timeout",
@@ -3167,6 +3382,12 @@ func TestTaskDiff(t *testing.T) {
 										Old:  "foo",
 										New:  "",
 									},
+									{
+										Type: DiffTypeDeleted,
+										Name: "GRPCUseTLS",
+										Old:  "false",
+										New:  "",
+									},
 									{
 										Type: DiffTypeDeleted,
 										Name: "Interval",
@@ -3191,6 +3412,12 @@ func TestTaskDiff(t *testing.T) {
 										Old:  "http",
 										New:  "",
 									},
+									{
+										Type: DiffTypeDeleted,
+										Name: "TLSSkipVerify",
+										Old:  "false",
+										New:  "",
+									},
 									{
 										Type: DiffTypeDeleted,
 										Name: "Timeout",
@@ -3272,18 +3499,42 @@ func TestTaskDiff(t *testing.T) {
 								Old:  "",
 								New:  "",
 							},
+							{
+								Type: DiffTypeNone,
+								Name: "Provider",
+								Old:  "",
+								New:  "",
+							},
 						},
 						Objects: []*ObjectDiff{
 							{
 								Type: DiffTypeEdited,
 								Name: "Check",
 								Fields: []*FieldDiff{
+									{
+										Type: DiffTypeNone,
+										Name: "Body",
+										Old:  "",
+										New:  "",
+									},
 									{
 										Type: DiffTypeNone,
 										Name: "Command",
 										Old:  "foo",
 										New:  "foo",
 									},
+									{
+										Type: DiffTypeNone,
+										Name: "GRPCService",
+										Old:  "",
+										New:  "",
+									},
+									{
+										Type: DiffTypeNone,
+										Name: "GRPCUseTLS",
+										Old:  "false",
+										New:  "false",
+									},
 									{
 										Type: DiffTypeEdited,
 										Name: "InitialStatus",
@@ -3296,6 +3547,12 @@ func TestTaskDiff(t *testing.T) {
 										Old:  "1000000000",
 										New:  "1000000000",
 									},
+									{
+										Type: DiffTypeNone,
+										Name: "Method",
+										Old:  "",
+										New:  "",
+									},
 									{
 										Type: DiffTypeNone,
 										Name: "Name",
@@ -3320,6 +3577,12 @@ func TestTaskDiff(t *testing.T) {
 										Old:  "http",
 										New:  "http",
 									},
+									{
+										Type: DiffTypeNone,
+										Name: "TLSSkipVerify",
+										Old:  "false",
+										New:  "false",
+									},
 									{
 										Type: DiffTypeNone,
 										Name: "Timeout",
@@ -3339,6 +3602,161 @@ func TestTaskDiff(t *testing.T) {
 				},
 			},
 		},
+		{
+			// Service Check header and check_restart edited
+			Old: &Task{
+				Services: []*Service{
+					{
+						Name: "foo",
+						Checks: []*ServiceCheck{
+							{
+								Name:     "foo",
+								Type:     "http",
+								Path:     "foo",
+								Interval: 1 * time.Second,
+								Timeout:  1 * time.Second,
+								Header: map[string][]string{
+									"X-Foo": {"bar"},
+								},
+								CheckRestart: &CheckRestart{
+									Limit: 2,
+									Grace: 1 * time.Second,
+								},
+							},
+						},
+					},
+				},
+			},
+			New: &Task{
+				Services: []*Service{
+					{
+						Name: "foo",
+						Checks: []*ServiceCheck{
+							{
+								Name:     "foo",
+								Type:     "http",
+								Path:     "foo",
+								Interval: 1 * time.Second,
+								Timeout:  1 * time.Second,
+								Header: map[string][]string{
+									"X-Foo": {"bar", "baz"},
+								},
+								CheckRestart: &CheckRestart{
+									Limit: 3,
+									Grace: 1 * time.Second,
+								},
+							},
+						},
+					},
+				},
+			},
+			Expected: &TaskDiff{
+				Type: DiffTypeEdited,
+				Objects: []*ObjectDiff{
+					{
+						Type: DiffTypeEdited,
+						Name: "Service",
+						Objects: []*ObjectDiff{
+							{
+								Type: DiffTypeEdited,
+								Name: "Check",
+								Objects: []*ObjectDiff{
+									{
+										Type: DiffTypeEdited,
+										Name: "Header",
+										Fields: []*FieldDiff{
+											{
+												Type: DiffTypeEdited,
+												Name: "X-Foo",
+												Old:  "bar",
+												New:  "bar,baz",
+											},
+										},
+									},
+									{
+										Type: DiffTypeEdited,
+										Name: "CheckRestart",
+										Fields: []*FieldDiff{
+											{
+												Type: DiffTypeEdited,
+												Name: "Limit",
+												Old:  "2",
+												New:  "3",
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			// Identity added
+			Old: &Task{},
+			New: &Task{
+				Identity: &WorkloadIdentity{
+					Env:  true,
+					File: false,
+				},
+			},
+			Expected: &TaskDiff{
+				Type: DiffTypeEdited,
+				Objects: []*ObjectDiff{
+					{
+						Type: DiffTypeAdded,
+						Name: "Identity",
+						Fields: []*FieldDiff{
+							{
+								Type: DiffTypeAdded,
+								Name: "Env",
+								Old:  "",
+								New:  "true",
+							},
+							{
+								Type: DiffTypeAdded,
+								Name: "File",
+								Old:  "",
+								New:  "false",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			// Identity edited
+			Old: &Task{
+				Identity: &WorkloadIdentity{
+					Env:  true,
+					File: false,
+				},
+			},
+			New: &Task{
+				Identity: &WorkloadIdentity{
+					Env:  true,
+					File: true,
+				},
+			},
+			Expected: &TaskDiff{
+				Type: DiffTypeEdited,
+				Objects: []*ObjectDiff{
+					{
+						Type: DiffTypeEdited,
+						Name: "Identity",
+						Fields: []*FieldDiff{
+							{
+								Type: DiffTypeEdited,
+								Name: "File",
+								Old:  "false",
+								New:  "true",
+							},
+						},
+					},
+				},
+			},
+		},
 		{
 			// Vault added
 			Old: &Task{},
@@ -3567,12 +3985,24 @@ func TestTaskDiff(t *testing.T) {
 								Old:  "SIGUSR1",
 								New:  "SIGUSR1",
 							},
+							{
+								Type: DiffTypeNone,
+								Name: "EntityAlias",
+								Old:  "",
+								New:  "",
+							},
 							{
 								Type: DiffTypeNone,
 								Name: "Env",
 								Old:  "true",
 								New:  "true",
 							},
+							{
+								Type: DiffTypeNone,
+								Name: "Role",
+								Old:  "",
+								New:  "",
+							},
 						},
 						Objects: []*ObjectDiff{
 							{
@@ -3647,6 +4077,7 @@ func TestTaskDiff(t *testing.T) {
 						ChangeSignal: "SIGHUP3",
 						Splay:        3,
 						Perms:        "0776",
+						Envvars:      true,
 					},
 				},
 			},
@@ -3681,6 +4112,12 @@ func TestTaskDiff(t *testing.T) {
 								Old:  "",
 								New:  "baz3",
 							},
+							{
+								Type: DiffTypeAdded,
+								Name: "Envvars",
+								Old:  "",
+								New:  "true",
+							},
 							{
 								Type: DiffTypeAdded,
 								Name: "Perms",
@@ -3729,6 +4166,12 @@ func TestTaskDiff(t *testing.T) {
 								Old:  "baz2",
 								New:  "",
 							},
+							{
+								Type: DiffTypeDeleted,
+								Name: "Envvars",
+								Old:  "false",
+								New:  "",
+							},
 							{
 								Type: DiffTypeDeleted,
 								Name: "Perms",
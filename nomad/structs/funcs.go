@@ -4,6 +4,7 @@ import (
 	crand "crypto/rand"
 	"fmt"
 	"math"
+	"sort"
 )
 
 // RemoveAllocs is used to remove any allocs with the given IDs
@@ -201,3 +202,24 @@ func VaultPoliciesSet(policies map[string]map[string]*Vault) []string {
 	}
 	return flattened
 }
+
+// VaultRolesSet takes the structure returned by VaultPolicies and returns the
+// set of roles the tasks' tokens are created from, excluding the default role
+func VaultRolesSet(policies map[string]map[string]*Vault) []string {
+	set := make(map[string]struct{})
+
+	for _, tgp := range policies {
+		for _, tp := range tgp {
+			if tp.Role != "" {
+				set[tp.Role] = struct{}{}
+			}
+		}
+	}
+
+	flattened := make([]string, 0, len(set))
+	for r := range set {
+		flattened = append(flattened, r)
+	}
+	sort.Strings(flattened)
+	return flattened
+}
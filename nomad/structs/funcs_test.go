@@ -2,6 +2,7 @@ package structs
 
 import (
 	"fmt"
+	"reflect"
 	"regexp"
 	"testing"
 )
@@ -102,7 +103,7 @@ func TestAllocsFit_PortsOvercommitted(t *testing.T) {
 						Device:        "eth0",
 						IP:            "10.0.0.1",
 						MBits:         50,
-						ReservedPorts: []Port{{"main", 8000}},
+						ReservedPorts: []Port{{"main", 8000, 0}},
 					},
 				},
 			},
@@ -153,7 +154,7 @@ func TestAllocsFit(t *testing.T) {
 					Device:        "eth0",
 					IP:            "10.0.0.1",
 					MBits:         50,
-					ReservedPorts: []Port{{"main", 80}},
+					ReservedPorts: []Port{{"main", 80, 0}},
 				},
 			},
 		},
@@ -170,7 +171,7 @@ func TestAllocsFit(t *testing.T) {
 					Device:        "eth0",
 					IP:            "10.0.0.1",
 					MBits:         50,
-					ReservedPorts: []Port{{"main", 8000}},
+					ReservedPorts: []Port{{"main", 8000, 0}},
 				},
 			},
 		},
@@ -254,6 +255,25 @@ func TestScoreFit(t *testing.T) {
 	}
 }
 
+func TestVaultRolesSet(t *testing.T) {
+	policies := map[string]map[string]*Vault{
+		"web": {
+			"server": {Policies: []string{"a"}, Role: "web"},
+			"proxy":  {Policies: []string{"a"}},
+		},
+		"batch": {
+			"worker": {Policies: []string{"b"}, Role: "batch"},
+			"sync":   {Policies: []string{"b"}, Role: "web"},
+		},
+	}
+
+	roles := VaultRolesSet(policies)
+	expected := []string{"batch", "web"}
+	if !reflect.DeepEqual(roles, expected) {
+		t.Fatalf("bad: %v", roles)
+	}
+}
+
 func TestGenerateUUID(t *testing.T) {
 	prev := GenerateUUID()
 	for i := 0; i < 100; i++ {
@@ -93,6 +93,14 @@ func (idx *NetworkIndex) SetNode(node *Node) (collide bool) {
 // true if there is a collision
 func (idx *NetworkIndex) AddAllocs(allocs []*Allocation) (collide bool) {
 	for _, alloc := range allocs {
+		if alloc.SharedResources != nil {
+			for _, n := range alloc.SharedResources.Networks {
+				if idx.AddReserved(n) {
+					collide = true
+				}
+			}
+		}
+
 		for _, task := range alloc.TaskResources {
 			if len(task.Networks) == 0 {
 				continue
@@ -207,6 +215,7 @@ func (idx *NetworkIndex) AssignNetwork(ask *NetworkResource) (out *NetworkResour
 			MBits:         ask.MBits,
 			ReservedPorts: ask.ReservedPorts,
 			DynamicPorts:  ask.DynamicPorts,
+			Mode:          ask.Mode,
 		}
 
 		// Try to stochastically pick the dynamic ports as it is faster and
@@ -14,7 +14,7 @@ func TestNetworkIndex_Overcommitted(t *testing.T) {
 		Device:        "eth0",
 		IP:            "192.168.0.100",
 		MBits:         505,
-		ReservedPorts: []Port{{"one", 8000}, {"two", 9000}},
+		ReservedPorts: []Port{{"one", 8000, 0}, {"two", 9000, 0}},
 	}
 	collide := idx.AddReserved(reserved)
 	if collide {
@@ -65,7 +65,7 @@ func TestNetworkIndex_SetNode(t *testing.T) {
 				&NetworkResource{
 					Device:        "eth0",
 					IP:            "192.168.0.100",
-					ReservedPorts: []Port{{"ssh", 22}},
+					ReservedPorts: []Port{{"ssh", 22, 0}},
 					MBits:         1,
 				},
 			},
@@ -101,7 +101,7 @@ func TestNetworkIndex_AddAllocs(t *testing.T) {
 							Device:        "eth0",
 							IP:            "192.168.0.100",
 							MBits:         20,
-							ReservedPorts: []Port{{"one", 8000}, {"two", 9000}},
+							ReservedPorts: []Port{{"one", 8000, 0}, {"two", 9000, 0}},
 						},
 					},
 				},
@@ -115,7 +115,7 @@ func TestNetworkIndex_AddAllocs(t *testing.T) {
 							Device:        "eth0",
 							IP:            "192.168.0.100",
 							MBits:         50,
-							ReservedPorts: []Port{{"one", 10000}},
+							ReservedPorts: []Port{{"one", 10000, 0}},
 						},
 					},
 				},
@@ -141,6 +141,68 @@ func TestNetworkIndex_AddAllocs(t *testing.T) {
 	}
 }
 
+func TestNetworkIndex_AddAllocs_SharedNetwork(t *testing.T) {
+	idx := NewNetworkIndex()
+	allocs := []*Allocation{
+		&Allocation{
+			SharedResources: &Resources{
+				Networks: []*NetworkResource{
+					&NetworkResource{
+						Device:        "eth0",
+						IP:            "192.168.0.100",
+						MBits:         20,
+						Mode:          NetworkModeBridge,
+						ReservedPorts: []Port{{Label: "one", Value: 8000, To: 80}},
+					},
+				},
+			},
+			TaskResources: map[string]*Resources{
+				"web": &Resources{
+					Networks: []*NetworkResource{
+						&NetworkResource{
+							Device:        "eth0",
+							IP:            "192.168.0.100",
+							MBits:         10,
+							ReservedPorts: []Port{{Label: "two", Value: 9000}},
+						},
+					},
+				},
+			},
+		},
+	}
+	if idx.AddAllocs(allocs) {
+		t.Fatalf("bad")
+	}
+
+	if idx.UsedBandwidth["eth0"] != 30 {
+		t.Fatalf("Bad: %d", idx.UsedBandwidth["eth0"])
+	}
+	if !idx.UsedPorts["192.168.0.100"].Check(8000) {
+		t.Fatalf("Bad")
+	}
+	if !idx.UsedPorts["192.168.0.100"].Check(9000) {
+		t.Fatalf("Bad")
+	}
+
+	// A task of another allocation using the same port collides
+	other := &Allocation{
+		TaskResources: map[string]*Resources{
+			"api": &Resources{
+				Networks: []*NetworkResource{
+					&NetworkResource{
+						Device:        "eth0",
+						IP:            "192.168.0.100",
+						ReservedPorts: []Port{{Label: "one", Value: 8000}},
+					},
+				},
+			},
+		},
+	}
+	if !idx.AddAllocs([]*Allocation{other}) {
+		t.Fatalf("expected collision")
+	}
+}
+
 func TestNetworkIndex_AddReserved(t *testing.T) {
 	idx := NewNetworkIndex()
 
@@ -148,7 +210,7 @@ func TestNetworkIndex_AddReserved(t *testing.T) {
 		Device:        "eth0",
 		IP:            "192.168.0.100",
 		MBits:         20,
-		ReservedPorts: []Port{{"one", 8000}, {"two", 9000}},
+		ReservedPorts: []Port{{"one", 8000, 0}, {"two", 9000, 0}},
 	}
 	collide := idx.AddReserved(reserved)
 	if collide {
@@ -189,7 +251,7 @@ func TestNetworkIndex_yieldIP(t *testing.T) {
 				&NetworkResource{
 					Device:        "eth0",
 					IP:            "192.168.0.100",
-					ReservedPorts: []Port{{"ssh", 22}},
+					ReservedPorts: []Port{{"ssh", 22, 0}},
 					MBits:         1,
 				},
 			},
@@ -227,7 +289,7 @@ func TestNetworkIndex_AssignNetwork(t *testing.T) {
 				&NetworkResource{
 					Device:        "eth0",
 					IP:            "192.168.0.100",
-					ReservedPorts: []Port{{"ssh", 22}},
+					ReservedPorts: []Port{{"ssh", 22, 0}},
 					MBits:         1,
 				},
 			},
@@ -244,7 +306,7 @@ func TestNetworkIndex_AssignNetwork(t *testing.T) {
 							Device:        "eth0",
 							IP:            "192.168.0.100",
 							MBits:         20,
-							ReservedPorts: []Port{{"one", 8000}, {"two", 9000}},
+							ReservedPorts: []Port{{"one", 8000, 0}, {"two", 9000, 0}},
 						},
 					},
 				},
@@ -258,7 +320,7 @@ func TestNetworkIndex_AssignNetwork(t *testing.T) {
 							Device:        "eth0",
 							IP:            "192.168.0.100",
 							MBits:         50,
-							ReservedPorts: []Port{{"main", 10000}},
+							ReservedPorts: []Port{{"main", 10000, 0}},
 						},
 					},
 				},
@@ -269,7 +331,7 @@ func TestNetworkIndex_AssignNetwork(t *testing.T) {
 
 	// Ask for a reserved port
 	ask := &NetworkResource{
-		ReservedPorts: []Port{{"main", 8000}},
+		ReservedPorts: []Port{{"main", 8000, 0}},
 	}
 	offer, err := idx.AssignNetwork(ask)
 	if err != nil {
@@ -281,14 +343,14 @@ func TestNetworkIndex_AssignNetwork(t *testing.T) {
 	if offer.IP != "192.168.0.101" {
 		t.Fatalf("bad: %#v", offer)
 	}
-	rp := Port{"main", 8000}
+	rp := Port{"main", 8000, 0}
 	if len(offer.ReservedPorts) != 1 || offer.ReservedPorts[0] != rp {
 		t.Fatalf("bad: %#v", offer)
 	}
 
 	// Ask for dynamic ports
 	ask = &NetworkResource{
-		DynamicPorts: []Port{{"http", 0}, {"https", 0}, {"admin", 0}},
+		DynamicPorts: []Port{{"http", 0, 0}, {"https", 0, 0}, {"admin", 0, 0}},
 	}
 	offer, err = idx.AssignNetwork(ask)
 	if err != nil {
@@ -311,8 +373,8 @@ func TestNetworkIndex_AssignNetwork(t *testing.T) {
 
 	// Ask for reserved + dynamic ports
 	ask = &NetworkResource{
-		ReservedPorts: []Port{{"main", 2345}},
-		DynamicPorts:  []Port{{"http", 0}, {"https", 0}, {"admin", 0}},
+		ReservedPorts: []Port{{"main", 2345, 0}},
+		DynamicPorts:  []Port{{"http", 0, 0}, {"https", 0, 0}, {"admin", 0, 0}},
 	}
 	offer, err = idx.AssignNetwork(ask)
 	if err != nil {
@@ -325,7 +387,7 @@ func TestNetworkIndex_AssignNetwork(t *testing.T) {
 		t.Fatalf("bad: %#v", offer)
 	}
 
-	rp = Port{"main", 2345}
+	rp = Port{"main", 2345, 0}
 	if len(offer.ReservedPorts) != 1 || offer.ReservedPorts[0] != rp {
 		t.Fatalf("bad: %#v", offer)
 	}
@@ -377,7 +439,7 @@ func TestNetworkIndex_AssignNetwork_Dynamic_Contention(t *testing.T) {
 
 	// Ask for dynamic ports
 	ask := &NetworkResource{
-		DynamicPorts: []Port{{"http", 0}},
+		DynamicPorts: []Port{{"http", 0, 0}},
 	}
 	offer, err := idx.AssignNetwork(ask)
 	if err != nil {
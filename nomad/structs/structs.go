@@ -11,6 +11,7 @@ import (
 	"fmt"
 	"io"
 	"net"
+	"net/url"
 	"os"
 	"path/filepath"
 	"reflect"
@@ -53,6 +54,11 @@ const (
 	ReconcileJobSummariesRequestType
 	VaultAccessorRegisterRequestType
 	VaultAccessorDegisterRequestType
+	IdentityKeyUpsertRequestType
+	IdentityKeyDeleteRequestType
+	ServiceRegistrationUpsertRequestType
+	ServiceRegistrationDeleteRequestType
+	JobScaleRequestType
 )
 
 const (
@@ -279,6 +285,43 @@ type JobDispatchRequest struct {
 	JobID   string
 	Payload []byte
 	Meta    map[string]string
+
+	// PayloadURL is the URL of the payload, downloaded by the clients
+	// running the dispatched job. It is used instead of Payload for payloads
+	// exceeding the size limit.
+	PayloadURL string
+
+	// IdempotencyToken, if set, makes repeated dispatches with the same
+	// token return the job dispatched by the first one.
+	IdempotencyToken string
+	WriteRequest
+}
+
+// JobScaleRequest is used for the Job.Scale endpoint to change the count of
+// a task group of a job
+type JobScaleRequest struct {
+	JobID     string
+	TaskGroup string
+
+	// Count is the new count of the task group. If it is nil, only the
+	// scaling event is recorded.
+	Count *int64
+
+	// Message, Error and Meta describe the scaling event recorded in the
+	// scaling history of the job
+	Message string
+	Error   bool
+	Meta    map[string]interface{}
+
+	WriteRequest
+}
+
+// ScalingEventRequest is used to apply a scaling event to a task group of a
+// job via Raft
+type ScalingEventRequest struct {
+	JobID     string
+	TaskGroup string
+	Event     *ScalingEvent
 	WriteRequest
 }
 
@@ -363,6 +406,10 @@ type AllocUpdateRequest struct {
 	// It is pulled out since it is common to reduce payload size.
 	Job *Job
 
+	// Plan is the record of the plan the allocations are applied for, if
+	// any. Its allocations are filled in from Alloc when applied.
+	Plan *AppliedPlan
+
 	WriteRequest
 }
 
@@ -436,6 +483,10 @@ type VaultAccessor struct {
 	Accessor    string
 	CreationTTL int
 
+	// Role is the Vault role the token was created from. It is empty if the
+	// token was created from the servers' create_from_role.
+	Role string
+
 	// Raft Indexes
 	CreateIndex uint64
 }
@@ -452,6 +503,95 @@ type DeriveVaultTokenResponse struct {
 	QueryMeta
 }
 
+// DeriveIdentityTokenRequest is used to request workload identity tokens for
+// the following tasks in the given allocation
+type DeriveIdentityTokenRequest struct {
+	NodeID   string
+	SecretID string
+	AllocID  string
+	Tasks    []string
+	QueryOptions
+}
+
+// DeriveIdentityTokenResponse returns the workload identity token for each
+// requested task
+type DeriveIdentityTokenResponse struct {
+	// Tasks is a mapping between the task name and its signed token
+	Tasks map[string]string
+
+	// Error stores any error that occured. Errors are stored here so we can
+	// communicate whether it is retriable
+	Error *RecoverableError
+
+	QueryMeta
+}
+
+// IdentityKeyUpsertRequest is used to store a key signing workload identities
+type IdentityKeyUpsertRequest struct {
+	Key *IdentityKey
+	WriteRequest
+}
+
+// IdentityKeyDeleteRequest is used to delete retired identity keys
+type IdentityKeyDeleteRequest struct {
+	KeyIDs []string
+	WriteRequest
+}
+
+// IdentityPublicKeysResponse is used to return the public keys that workload
+// identities can be verified with
+type IdentityPublicKeysResponse struct {
+	Keys []*JSONWebKey
+	QueryMeta
+}
+
+// ServiceRegistrationUpsertRequest is used by clients to register or update
+// the services of the tasks they run
+type ServiceRegistrationUpsertRequest struct {
+	Services []*ServiceRegistration
+	WriteRequest
+}
+
+// ServiceRegistrationDeleteRequest is used by clients to deregister services
+// of tasks that have stopped
+type ServiceRegistrationDeleteRequest struct {
+	NodeID string
+	IDs    []string
+	WriteRequest
+}
+
+// ServiceRegistrationByNameRequest is used to query the instances of a service
+type ServiceRegistrationByNameRequest struct {
+	ServiceName string
+	QueryOptions
+}
+
+// ScalingPolicyListRequest is used to list the scaling policies, optionally
+// only those of a job
+type ScalingPolicyListRequest struct {
+	JobID string
+	QueryOptions
+}
+
+// ScalingPolicySpecificRequest is used to query a specific scaling policy
+type ScalingPolicySpecificRequest struct {
+	ID string
+	QueryOptions
+}
+
+// ServiceRegistrationListResponse is used to list the registered services
+type ServiceRegistrationListResponse struct {
+	Services []*ServiceRegistrationListStub
+	QueryMeta
+}
+
+// ServiceRegistrationByNameResponse is used to return the instances of a
+// service
+type ServiceRegistrationByNameResponse struct {
+	Services []*ServiceRegistration
+	QueryMeta
+}
+
 // GenericRequest is used to request where no
 // specific information is needed.
 type GenericRequest struct {
@@ -554,6 +694,24 @@ type JobSummaryResponse struct {
 	QueryMeta
 }
 
+// JobScaleStatusResponse is used to return the scaling status of a job
+type JobScaleStatusResponse struct {
+	JobScaleStatus *JobScaleStatus
+	QueryMeta
+}
+
+// ScalingPolicyListResponse is used to list the scaling policies
+type ScalingPolicyListResponse struct {
+	Policies []*ScalingPolicyListStub
+	QueryMeta
+}
+
+// SingleScalingPolicyResponse is used to return a single scaling policy
+type SingleScalingPolicyResponse struct {
+	Policy *ScalingPolicy
+	QueryMeta
+}
+
 type JobDispatchResponse struct {
 	DispatchedJobID string
 	EvalID          string
@@ -626,6 +784,12 @@ type SingleEvalResponse struct {
 	QueryMeta
 }
 
+// EvalPlansResponse is used to return the plans applied for an evaluation
+type EvalPlansResponse struct {
+	Plans *EvalPlans
+	QueryMeta
+}
+
 // EvalDequeueResponse is used to return from a dequeue
 type EvalDequeueResponse struct {
 	Eval  *Evaluation
@@ -988,8 +1152,27 @@ func (r *Resources) GoString() string {
 type Port struct {
 	Label string
 	Value int
+
+	// To is the port inside the allocation's network namespace that the
+	// host port is mapped to. It is only used with bridge and CNI networking
+	// and defaults to the host port value when unset.
+	To int
 }
 
+const (
+	// NetworkModeHost shares the host's network namespace with the tasks.
+	NetworkModeHost = "host"
+
+	// NetworkModeBridge creates a network namespace per allocation that is
+	// attached to a bridge on the host and shared by all the tasks.
+	NetworkModeBridge = "bridge"
+
+	// NetworkModeCNIPrefix prefixes the name of a CNI network configuration
+	// loaded from the client's CNI config directory. The allocation gets its
+	// own network namespace which is set up by the configured plugins.
+	NetworkModeCNIPrefix = "cni/"
+)
+
 // NetworkResource is used to represent available network
 // resources
 type NetworkResource struct {
@@ -999,6 +1182,7 @@ type NetworkResource struct {
 	MBits         int    // Throughput
 	ReservedPorts []Port // Reserved ports
 	DynamicPorts  []Port // Dynamically assigned ports
+	Mode          string // Networking mode, only used for task group networks
 }
 
 func (n *NetworkResource) Canonicalize() {
@@ -1067,6 +1251,63 @@ func (n *NetworkResource) MapLabelToValues(port_map map[string]int) map[string]i
 	return labelValues
 }
 
+// PortMap returns a mapping of port labels to the port inside the network
+// namespace that the host port should be forwarded to.
+func (n *NetworkResource) PortMap() map[string]int {
+	m := make(map[string]int, len(n.ReservedPorts)+len(n.DynamicPorts))
+	for _, ports := range [][]Port{n.ReservedPorts, n.DynamicPorts} {
+		for _, port := range ports {
+			if port.To > 0 {
+				m[port.Label] = port.To
+			} else {
+				m[port.Label] = port.Value
+			}
+		}
+	}
+	return m
+}
+
+// CNIName returns the name of the CNI network configuration used by the
+// network or an empty string if the network doesn't use CNI.
+func (n *NetworkResource) CNIName() string {
+	if !strings.HasPrefix(n.Mode, NetworkModeCNIPrefix) {
+		return ""
+	}
+	return strings.TrimPrefix(n.Mode, NetworkModeCNIPrefix)
+}
+
+// Isolated returns whether the tasks using the network run in a network
+// namespace of the allocation rather than the host's.
+func (n *NetworkResource) Isolated() bool {
+	return n.Mode == NetworkModeBridge || n.CNIName() != ""
+}
+
+// ValidateGroupNetwork validates a network requested at the task group level.
+func (n *NetworkResource) ValidateGroupNetwork() error {
+	var mErr multierror.Error
+	switch {
+	case n.Mode == "", n.Mode == NetworkModeHost, n.Mode == NetworkModeBridge:
+	case n.CNIName() != "":
+	case n.Mode == NetworkModeCNIPrefix:
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("network mode %q is missing the CNI network name", n.Mode))
+	default:
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("invalid network mode %q", n.Mode))
+	}
+
+	if err := n.MeetsMinResources(); err != nil {
+		mErr.Errors = append(mErr.Errors, err)
+	}
+
+	for _, ports := range [][]Port{n.ReservedPorts, n.DynamicPorts} {
+		for _, port := range ports {
+			if port.To < 0 || port.To >= maxValidPort {
+				mErr.Errors = append(mErr.Errors, fmt.Errorf("port %q has invalid mapped port %d", port.Label, port.To))
+			}
+		}
+	}
+	return mErr.ErrorOrNil()
+}
+
 const (
 	// JobTypeNomad is reserved for internal system tasks and is
 	// always handled by the CoreScheduler.
@@ -1157,6 +1398,14 @@ type Job struct {
 	// Payload is the payload supplied when the job was dispatched.
 	Payload []byte
 
+	// PayloadURL is the URL of the payload supplied when the job was
+	// dispatched, downloaded by the client instead of an inline Payload.
+	PayloadURL string
+
+	// DispatchIdempotencyToken is the idempotency token of the dispatch
+	// request that created the job.
+	DispatchIdempotencyToken string
+
 	// Meta is used to associate arbitrary metadata with this
 	// job. This is opaque to Nomad.
 	Meta map[string]string
@@ -1275,6 +1524,10 @@ func (j *Job) Validate() error {
 				fmt.Errorf("Job task group %s has count %d. Count cannot exceed 1 with system scheduler",
 					tg.Name, tg.Count))
 		}
+		if j.Type == JobTypeSystem && tg.Scaling != nil {
+			mErr.Errors = append(mErr.Errors,
+				fmt.Errorf("Job task group %s has a scaling policy. System jobs can not be scaled", tg.Name))
+		}
 	}
 
 	// Validate the task group
@@ -1529,6 +1782,274 @@ type TaskGroupSummary struct {
 	Lost     int
 }
 
+const (
+	// JobTrackedScalingEvents is the number of scaling events kept in the
+	// scaling history of each task group
+	JobTrackedScalingEvents = 20
+)
+
+// ScalingEvent records a change of the count of a task group, or an attempt
+// to change it
+type ScalingEvent struct {
+	// Time is the time of the event in nanoseconds since the epoch
+	Time int64
+
+	// Count is the new count of the task group, nil if the count was not
+	// changed. PreviousCount is the count before the event.
+	Count         *int64
+	PreviousCount int64
+
+	// Message, Error and Meta describe the event
+	Message string
+	Error   bool
+	Meta    map[string]interface{}
+
+	// EvalID is the evaluation created for the event, if any
+	EvalID string
+
+	CreateIndex uint64
+}
+
+// Copy returns a copy of the scaling event
+func (e *ScalingEvent) Copy() *ScalingEvent {
+	if e == nil {
+		return nil
+	}
+	ne := new(ScalingEvent)
+	*ne = *e
+	if e.Count != nil {
+		ne.Count = helper.Int64ToPtr(*e.Count)
+	}
+	ne.Meta = helper.CopyMapStringInterface(e.Meta)
+	return ne
+}
+
+// JobScalingEvents is the scaling history of the task groups of a job
+type JobScalingEvents struct {
+	JobID string
+
+	// ScalingEvents are the events of each task group, most recent first
+	ScalingEvents map[string][]*ScalingEvent
+
+	ModifyIndex uint64
+}
+
+// Copy returns a copy of the scaling history
+func (j *JobScalingEvents) Copy() *JobScalingEvents {
+	if j == nil {
+		return nil
+	}
+	nj := new(JobScalingEvents)
+	*nj = *j
+	nj.ScalingEvents = make(map[string][]*ScalingEvent, len(j.ScalingEvents))
+	for group, events := range j.ScalingEvents {
+		ne := make([]*ScalingEvent, len(events))
+		for i, e := range events {
+			ne[i] = e.Copy()
+		}
+		nj.ScalingEvents[group] = ne
+	}
+	return nj
+}
+
+// AddEvent adds an event to the scaling history of the task group, dropping
+// the oldest events beyond JobTrackedScalingEvents.
+func (j *JobScalingEvents) AddEvent(group string, event *ScalingEvent) {
+	if j.ScalingEvents == nil {
+		j.ScalingEvents = make(map[string][]*ScalingEvent)
+	}
+	events := append([]*ScalingEvent{event}, j.ScalingEvents[group]...)
+	if len(events) > JobTrackedScalingEvents {
+		events = events[:JobTrackedScalingEvents]
+	}
+	j.ScalingEvents[group] = events
+}
+
+// JobScaleStatus is the scaling status of the task groups of a job
+type JobScaleStatus struct {
+	JobID          string
+	JobCreateIndex uint64
+	JobModifyIndex uint64
+	TaskGroups     map[string]*TaskGroupScaleStatus
+}
+
+// TaskGroupScaleStatus is the scaling status of a task group
+type TaskGroupScaleStatus struct {
+	// Desired is the count of the task group, and Placed, Running and Queued
+	// are the number of its allocations in each state
+	Desired int
+	Placed  int
+	Running int
+	Queued  int
+
+	// Events is the scaling history of the task group, most recent first
+	Events []*ScalingEvent
+}
+
+const (
+	// ScalingMetricCPU and ScalingMetricMemory are the metrics the built-in
+	// autoscaler can scale a task group on. The utilization is the resource
+	// usage of the allocations relative to the resources they reserve.
+	ScalingMetricCPU    = "cpu"
+	ScalingMetricMemory = "memory"
+)
+
+// ScalingPolicy bounds the count of a task group and holds the policy used by
+// autoscalers to change it
+type ScalingPolicy struct {
+	// ID is the UUID of the policy, kept across updates of the job
+	ID string
+
+	// JobID and TaskGroup are the task group the policy applies to
+	JobID     string
+	TaskGroup string
+
+	// Min and Max bound the count of the task group
+	Min int64
+	Max int64
+
+	// Policy is opaque to Nomad, except for the keys read by the built-in
+	// autoscaler. See ScalingTarget.
+	Policy map[string]interface{}
+
+	// Enabled is whether autoscalers should act on the policy
+	Enabled bool
+
+	CreateIndex uint64
+	ModifyIndex uint64
+}
+
+// Copy returns a copy of the scaling policy
+func (p *ScalingPolicy) Copy() *ScalingPolicy {
+	if p == nil {
+		return nil
+	}
+	np := new(ScalingPolicy)
+	*np = *p
+	np.Policy = helper.CopyMapStringInterface(p.Policy)
+	return np
+}
+
+// Validate checks the bounds of the policy against the count of the task
+// group and the keys of the policy read by the built-in autoscaler
+func (p *ScalingPolicy) Validate(count int) error {
+	var mErr multierror.Error
+	if p.Max < 0 {
+		mErr.Errors = append(mErr.Errors, errors.New("Maximum count must be specified and can't be negative"))
+	}
+	if p.Min < 0 {
+		mErr.Errors = append(mErr.Errors, errors.New("Minimum count can't be negative"))
+	}
+	if p.Min > p.Max && p.Max >= 0 {
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("Minimum count %d is greater than maximum count %d", p.Min, p.Max))
+	}
+	if c := int64(count); p.Max >= 0 && (c < p.Min || c > p.Max) {
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("Task group count %d is outside of the bounds [%d, %d]", count, p.Min, p.Max))
+	}
+	if _, err := p.ScalingTarget(); err != nil {
+		mErr.Errors = append(mErr.Errors, err)
+	}
+	return mErr.ErrorOrNil()
+}
+
+// Stub returns a summary of the scaling policy
+func (p *ScalingPolicy) Stub() *ScalingPolicyListStub {
+	return &ScalingPolicyListStub{
+		ID:          p.ID,
+		JobID:       p.JobID,
+		TaskGroup:   p.TaskGroup,
+		Min:         p.Min,
+		Max:         p.Max,
+		Enabled:     p.Enabled,
+		CreateIndex: p.CreateIndex,
+		ModifyIndex: p.ModifyIndex,
+	}
+}
+
+// ScalingTarget returns the target utilization set by the policy for the
+// built-in autoscaler, or nil if the policy doesn't set a metric. The policy
+// sets the metric to scale on ("cpu" or "memory"), the target average
+// utilization in percent and an optional cooldown between two changes of the
+// count.
+func (p *ScalingPolicy) ScalingTarget() (*ScalingTarget, error) {
+	raw, ok := p.Policy["metric"]
+	if !ok {
+		return nil, nil
+	}
+
+	metric, ok := raw.(string)
+	if !ok || (metric != ScalingMetricCPU && metric != ScalingMetricMemory) {
+		return nil, fmt.Errorf("Policy metric must be %q or %q", ScalingMetricCPU, ScalingMetricMemory)
+	}
+	target := &ScalingTarget{Metric: metric}
+
+	switch v := p.Policy["target"].(type) {
+	case int:
+		target.Target = float64(v)
+	case int64:
+		target.Target = float64(v)
+	case uint64:
+		target.Target = float64(v)
+	case float64:
+		target.Target = v
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return nil, fmt.Errorf("Policy target %q is not a number", v)
+		}
+		target.Target = f
+	case nil:
+		return nil, errors.New("Policy target must be set with the metric")
+	default:
+		return nil, fmt.Errorf("Policy target %v is not a number", v)
+	}
+	if target.Target <= 0 || target.Target > 100 {
+		return nil, fmt.Errorf("Policy target %v must be a percentage greater than 0", target.Target)
+	}
+
+	if raw, ok := p.Policy["cooldown"]; ok {
+		s, ok := raw.(string)
+		if !ok {
+			return nil, fmt.Errorf("Policy cooldown %v must be a duration", raw)
+		}
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return nil, fmt.Errorf("Policy cooldown %q invalid: %v", s, err)
+		}
+		if d < 0 {
+			return nil, fmt.Errorf("Policy cooldown %q can't be negative", s)
+		}
+		target.Cooldown = d
+	}
+	return target, nil
+}
+
+// ScalingTarget is the part of a scaling policy read by the built-in
+// autoscaler
+type ScalingTarget struct {
+	// Metric is the metric to scale on
+	Metric string
+
+	// Target is the target average utilization of the allocations
+	Target float64
+
+	// Cooldown is the minimum duration between two changes of the count. If
+	// zero, the default of the server is used.
+	Cooldown time.Duration
+}
+
+// ScalingPolicyListStub is a summary of a scaling policy
+type ScalingPolicyListStub struct {
+	ID          string
+	JobID       string
+	TaskGroup   string
+	Min         int64
+	Max         int64
+	Enabled     bool
+	CreateIndex uint64
+	ModifyIndex uint64
+}
+
 // UpdateStrategy is used to modify how updates are done
 type UpdateStrategy struct {
 	// Stagger is the amount of time between the updates
@@ -1757,6 +2278,26 @@ func DispatchedID(templateID string, t time.Time) string {
 	return fmt.Sprintf("%s%s%d-%s", templateID, DispatchLaunchSuffix, t.Unix(), u)
 }
 
+// DispatchedTime returns the time a job was dispatched at. This is only valid
+// for jobs created by a dispatch and will otherwise return an error.
+func DispatchedTime(jobID string) (time.Time, error) {
+	index := strings.LastIndex(jobID, DispatchLaunchSuffix)
+	if index == -1 {
+		return time.Time{}, fmt.Errorf("couldn't parse dispatch time from job: %v", jobID)
+	}
+
+	suffix := jobID[index+len(DispatchLaunchSuffix):]
+	if i := strings.Index(suffix, "-"); i != -1 {
+		suffix = suffix[:i]
+	}
+	dispatched, err := strconv.ParseInt(suffix, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("couldn't parse dispatch time from job: %v", jobID)
+	}
+
+	return time.Unix(dispatched, 0), nil
+}
+
 // DispatchPayloadConfig configures how a task gets its input from a job dispatch
 type DispatchPayloadConfig struct {
 	// File specifies a relative path to where the input data should be written
@@ -1898,9 +2439,17 @@ type TaskGroup struct {
 	// EphemeralDisk is the disk resources that the task group requests
 	EphemeralDisk *EphemeralDisk
 
+	// Networks are the network resources shared by all the tasks in the
+	// group. When the network mode is bridge, the tasks share a network
+	// namespace created for each allocation.
+	Networks []*NetworkResource
+
 	// Meta is used to associate arbitrary metadata with this
 	// task group. This is opaque to Nomad.
 	Meta map[string]string
+
+	// Scaling is the scaling policy of the task group, bounding its count
+	Scaling *ScalingPolicy
 }
 
 func (tg *TaskGroup) Copy() *TaskGroup {
@@ -1921,11 +2470,21 @@ func (tg *TaskGroup) Copy() *TaskGroup {
 		ntg.Tasks = tasks
 	}
 
+	if tg.Networks != nil {
+		networks := make([]*NetworkResource, len(tg.Networks))
+		for i, n := range tg.Networks {
+			networks[i] = n.Copy()
+		}
+		ntg.Networks = networks
+	}
+
 	ntg.Meta = helper.CopyMapStringString(ntg.Meta)
 
 	if tg.EphemeralDisk != nil {
 		ntg.EphemeralDisk = tg.EphemeralDisk.Copy()
 	}
+
+	ntg.Scaling = tg.Scaling.Copy()
 	return ntg
 }
 
@@ -1947,6 +2506,16 @@ func (tg *TaskGroup) Canonicalize(job *Job) {
 		tg.EphemeralDisk = DefaultEphemeralDisk()
 	}
 
+	for _, network := range tg.Networks {
+		network.Canonicalize()
+	}
+
+	// Target the scaling policy at the task group
+	if tg.Scaling != nil {
+		tg.Scaling.JobID = job.ID
+		tg.Scaling.TaskGroup = tg.Name
+	}
+
 	for _, task := range tg.Tasks {
 		task.Canonicalize(job, tg)
 	}
@@ -2000,6 +2569,43 @@ func (tg *TaskGroup) Validate() error {
 		mErr.Errors = append(mErr.Errors, fmt.Errorf("Task Group %v should have an ephemeral disk object", tg.Name))
 	}
 
+	if tg.Scaling != nil {
+		if err := tg.Scaling.Validate(tg.Count); err != nil {
+			outer := fmt.Errorf("Scaling policy validation failed: %v", err)
+			mErr.Errors = append(mErr.Errors, outer)
+		}
+	}
+
+	// Validate the group network and ensure its ports do not collide with
+	// those of the tasks
+	if len(tg.Networks) > 1 {
+		mErr.Errors = append(mErr.Errors, errors.New("Only one task group network is allowed"))
+	}
+	portLabels := make(map[string]struct{})
+	for _, network := range tg.Networks {
+		if err := network.ValidateGroupNetwork(); err != nil {
+			outer := fmt.Errorf("Network validation failed: %v", err)
+			mErr.Errors = append(mErr.Errors, outer)
+		}
+		for label := range network.MapLabelToValues(nil) {
+			portLabels[label] = struct{}{}
+		}
+	}
+	if len(tg.Networks) > 0 {
+		for _, task := range tg.Tasks {
+			if task.Resources == nil {
+				continue
+			}
+			for _, network := range task.Resources.Networks {
+				for label := range network.MapLabelToValues(nil) {
+					if _, ok := portLabels[label]; ok {
+						mErr.Errors = append(mErr.Errors, fmt.Errorf("Port label %q in task %q conflicts with the task group network", label, task.Name))
+					}
+				}
+			}
+		}
+	}
+
 	// Check for duplicate tasks and that there is only leader task if any
 	tasks := make(map[string]int)
 	leaderTasks := 0
@@ -2023,7 +2629,7 @@ func (tg *TaskGroup) Validate() error {
 
 	// Validate the tasks
 	for _, task := range tg.Tasks {
-		if err := task.Validate(tg.EphemeralDisk); err != nil {
+		if err := task.Validate(tg.EphemeralDisk, tg.Networks); err != nil {
 			outer := fmt.Errorf("Task %s validation failed: %v", task.Name, err)
 			mErr.Errors = append(mErr.Errors, outer)
 		}
@@ -2050,6 +2656,7 @@ const (
 	ServiceCheckHTTP   = "http"
 	ServiceCheckTCP    = "tcp"
 	ServiceCheckScript = "script"
+	ServiceCheckGRPC   = "grpc"
 
 	// minCheckInterval is the minimum check interval permitted.  Consul
 	// currently has its MinInterval set to 1s.  Mirror that here for
@@ -2064,16 +2671,23 @@ const (
 // The ServiceCheck data model represents the consul health check that
 // Nomad registers for a Task
 type ServiceCheck struct {
-	Name          string        // Name of the check, defaults to id
-	Type          string        // Type of the check - tcp, http, docker and script
-	Command       string        // Command is the command to run for script checks
-	Args          []string      // Args is a list of argumes for script checks
-	Path          string        // path of the health check url for http type check
-	Protocol      string        // Protocol to use if check is http, defaults to http
-	PortLabel     string        // The port to use for tcp/http checks
-	Interval      time.Duration // Interval of the check
-	Timeout       time.Duration // Timeout of the response from the check before consul fails the check
-	InitialStatus string        // Initial status of the check
+	Name          string              // Name of the check, defaults to id
+	Type          string              // Type of the check - tcp, http, docker and script
+	Command       string              // Command is the command to run for script checks
+	Args          []string            // Args is a list of argumes for script checks
+	Path          string              // path of the health check url for http type check
+	Protocol      string              // Protocol to use if check is http, defaults to http
+	PortLabel     string              // The port to use for tcp/http checks
+	Interval      time.Duration       // Interval of the check
+	Timeout       time.Duration       // Timeout of the response from the check before consul fails the check
+	InitialStatus string              // Initial status of the check
+	Method        string              // HTTP method to use for http checks, defaults to GET
+	Header        map[string][]string // HTTP headers to send with http checks
+	Body          string              // Body to send with http checks
+	TLSSkipVerify bool                // Skip TLS verification when Protocol is https
+	GRPCService   string              // Service to check for grpc checks
+	GRPCUseTLS    bool                // Whether to use TLS for grpc checks
+	CheckRestart  *CheckRestart       // Restart the task when the check is unhealthy
 }
 
 func (sc *ServiceCheck) Copy() *ServiceCheck {
@@ -2082,6 +2696,14 @@ func (sc *ServiceCheck) Copy() *ServiceCheck {
 	}
 	nsc := new(ServiceCheck)
 	*nsc = *sc
+	nsc.Args = helper.CopySliceString(sc.Args)
+	nsc.CheckRestart = sc.CheckRestart.Copy()
+	if sc.Header != nil {
+		nsc.Header = make(map[string][]string, len(sc.Header))
+		for k, v := range sc.Header {
+			nsc.Header[k] = helper.CopySliceString(v)
+		}
+	}
 	return nsc
 }
 
@@ -2092,6 +2714,10 @@ func (sc *ServiceCheck) Canonicalize(serviceName string) {
 		sc.Args = nil
 	}
 
+	if len(sc.Header) == 0 {
+		sc.Header = nil
+	}
+
 	if sc.Name == "" {
 		sc.Name = fmt.Sprintf("service: %q check", serviceName)
 	}
@@ -2111,6 +2737,12 @@ func (sc *ServiceCheck) validate() error {
 			return fmt.Errorf("http type must have a valid http path")
 		}
 
+		if sc.Timeout == 0 {
+			return fmt.Errorf("missing required value timeout. Timeout cannot be less than %v", minCheckInterval)
+		} else if sc.Timeout < minCheckTimeout {
+			return fmt.Errorf("timeout (%v) is lower than required minimum timeout %v", sc.Timeout, minCheckInterval)
+		}
+	case ServiceCheckGRPC:
 		if sc.Timeout == 0 {
 			return fmt.Errorf("missing required value timeout. Timeout cannot be less than %v", minCheckInterval)
 		} else if sc.Timeout < minCheckTimeout {
@@ -2124,7 +2756,23 @@ func (sc *ServiceCheck) validate() error {
 		// TODO: enforce timeout on the Client side and reenable
 		// validation.
 	default:
-		return fmt.Errorf(`invalid type (%+q), must be one of "http", "tcp", or "script" type`, sc.Type)
+		return fmt.Errorf(`invalid type (%+q), must be one of "http", "tcp", "grpc", or "script" type`, sc.Type)
+	}
+
+	if strings.ToLower(sc.Type) != ServiceCheckHTTP {
+		if sc.Method != "" || len(sc.Header) != 0 || sc.Body != "" {
+			return fmt.Errorf("method, header and body are only valid for http checks")
+		}
+	}
+
+	if strings.ToLower(sc.Type) != ServiceCheckGRPC {
+		if sc.GRPCService != "" || sc.GRPCUseTLS {
+			return fmt.Errorf("grpc_service and grpc_use_tls are only valid for grpc checks")
+		}
+	}
+
+	if err := sc.CheckRestart.Validate(); err != nil {
+		return err
 	}
 
 	if sc.Interval == 0 {
@@ -2150,7 +2798,7 @@ func (sc *ServiceCheck) validate() error {
 // RequiresPort returns whether the service check requires the task has a port.
 func (sc *ServiceCheck) RequiresPort() bool {
 	switch sc.Type {
-	case ServiceCheckHTTP, ServiceCheckTCP:
+	case ServiceCheckHTTP, ServiceCheckTCP, ServiceCheckGRPC:
 		return true
 	default:
 		return false
@@ -2169,9 +2817,80 @@ func (sc *ServiceCheck) Hash(serviceID string) string {
 	io.WriteString(h, sc.PortLabel)
 	io.WriteString(h, sc.Interval.String())
 	io.WriteString(h, sc.Timeout.String())
+
+	// Only include the fields added for http and grpc checks when they are
+	// set so the IDs of existing checks do not change.
+	if sc.Method != "" {
+		io.WriteString(h, sc.Method)
+	}
+	if len(sc.Header) != 0 {
+		keys := make([]string, 0, len(sc.Header))
+		for k := range sc.Header {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			io.WriteString(h, k)
+			io.WriteString(h, strings.Join(sc.Header[k], ""))
+		}
+	}
+	if sc.Body != "" {
+		io.WriteString(h, sc.Body)
+	}
+	if sc.TLSSkipVerify {
+		io.WriteString(h, "true")
+	}
+	if sc.GRPCService != "" {
+		io.WriteString(h, sc.GRPCService)
+	}
+	if sc.GRPCUseTLS {
+		io.WriteString(h, "true")
+	}
 	return fmt.Sprintf("%x", h.Sum(nil))
 }
 
+// CheckRestart describes if and when a task should be restarted based on the
+// health of one of its service checks.
+type CheckRestart struct {
+	// Limit is the number of consecutive unhealthy results of the check
+	// after which the task is restarted. Zero disables restarting.
+	Limit int
+
+	// Grace is the time to wait after the task has started before the
+	// health of the check is taken into account.
+	Grace time.Duration
+
+	// IgnoreWarnings treats checks in the warning state as healthy.
+	IgnoreWarnings bool
+}
+
+func (c *CheckRestart) Copy() *CheckRestart {
+	if c == nil {
+		return nil
+	}
+
+	nc := new(CheckRestart)
+	*nc = *c
+	return nc
+}
+
+func (c *CheckRestart) Validate() error {
+	if c == nil {
+		return nil
+	}
+
+	var mErr multierror.Error
+	if c.Limit < 0 {
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("limit must be greater than or equal to 0 but found %d", c.Limit))
+	}
+
+	if c.Grace < 0 {
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("grace period must be greater than or equal to 0 but found %d", c.Grace))
+	}
+
+	return mErr.ErrorOrNil()
+}
+
 // Service represents a Consul service definition in Nomad
 type Service struct {
 	// Name of the service registered with Consul. Consul defaults the
@@ -2179,107 +2898,372 @@ type Service struct {
 	// as one of the seed values when generating a Consul ServiceID.
 	Name string
 
-	// PortLabel is either the numeric port number or the `host:port`.
-	// To specify the port number using the host's Consul Advertise
-	// address, specify an empty host in the PortLabel (e.g. `:port`).
-	PortLabel string
-	Tags      []string        // List of tags for the service
-	Checks    []*ServiceCheck // List of checks associated with the service
+	// PortLabel is either the numeric port number or the `host:port`.
+	// To specify the port number using the host's Consul Advertise
+	// address, specify an empty host in the PortLabel (e.g. `:port`).
+	PortLabel string
+	Tags      []string        // List of tags for the service
+	Checks    []*ServiceCheck // List of checks associated with the service
+
+	// Provider is where the service is registered, either Consul or the
+	// Nomad servers.
+	Provider string
+
+	// Connect is the Consul Connect configuration of the service. If set, a
+	// sidecar proxy is registered for the service and injected into the
+	// task group.
+	Connect *ConsulConnect
+}
+
+func (s *Service) Copy() *Service {
+	if s == nil {
+		return nil
+	}
+	ns := new(Service)
+	*ns = *s
+	ns.Tags = helper.CopySliceString(ns.Tags)
+	ns.Connect = s.Connect.Copy()
+
+	if s.Checks != nil {
+		checks := make([]*ServiceCheck, len(ns.Checks))
+		for i, c := range ns.Checks {
+			checks[i] = c.Copy()
+		}
+		ns.Checks = checks
+	}
+
+	return ns
+}
+
+// Canonicalize interpolates values of Job, Task Group and Task in the Service
+// Name. This also generates check names, service id and check ids.
+func (s *Service) Canonicalize(job string, taskGroup string, task string) {
+	// Ensure empty lists are treated as null to avoid scheduler issues when
+	// using DeepEquals
+	if len(s.Tags) == 0 {
+		s.Tags = nil
+	}
+	if len(s.Checks) == 0 {
+		s.Checks = nil
+	}
+	if s.Provider == "" {
+		s.Provider = ServiceProviderConsul
+	}
+
+	s.Name = args.ReplaceEnv(s.Name, map[string]string{
+		"JOB":       job,
+		"TASKGROUP": taskGroup,
+		"TASK":      task,
+		"BASE":      fmt.Sprintf("%s-%s-%s", job, taskGroup, task),
+	},
+	)
+
+	for _, check := range s.Checks {
+		check.Canonicalize(s.Name)
+	}
+}
+
+// Validate checks if the Check definition is valid
+func (s *Service) Validate() error {
+	var mErr multierror.Error
+
+	// Ensure the service name is valid per the below RFCs but make an exception
+	// for our interpolation syntax
+	// RFC-952 §1 (https://tools.ietf.org/html/rfc952), RFC-1123 §2.1
+	// (https://tools.ietf.org/html/rfc1123), and RFC-2782
+	// (https://tools.ietf.org/html/rfc2782).
+	re := regexp.MustCompile(`^(?i:[a-z0-9]|[a-z0-9\$][a-zA-Z0-9\-\$\{\}\_\.]*[a-z0-9\}])$`)
+	if !re.MatchString(s.Name) {
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("service name must be valid per RFC 1123 and can contain only alphanumeric characters or dashes: %q", s.Name))
+	}
+
+	switch s.Provider {
+	case "", ServiceProviderConsul, ServiceProviderNomad:
+	default:
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("invalid provider %q, must be %q or %q", s.Provider, ServiceProviderConsul, ServiceProviderNomad))
+	}
+
+	for _, c := range s.Checks {
+		if s.PortLabel == "" && c.RequiresPort() {
+			mErr.Errors = append(mErr.Errors, fmt.Errorf("check %s invalid: check requires a port but the service %+q has no port", c.Name, s.Name))
+			continue
+		}
+
+		if err := c.validate(); err != nil {
+			mErr.Errors = append(mErr.Errors, fmt.Errorf("check %s invalid: %v", c.Name, err))
+		}
+
+		// Checks of services registered with Nomad are run by the client
+		if s.Provider == ServiceProviderNomad {
+			switch c.Type {
+			case ServiceCheckHTTP, ServiceCheckTCP:
+			default:
+				mErr.Errors = append(mErr.Errors, fmt.Errorf("check %s invalid: services with provider %q only support %q and %q checks", c.Name, ServiceProviderNomad, ServiceCheckHTTP, ServiceCheckTCP))
+			}
+			if c.CheckRestart != nil {
+				mErr.Errors = append(mErr.Errors, fmt.Errorf("check %s invalid: check_restart is only supported for services with provider %q", c.Name, ServiceProviderConsul))
+			}
+		}
+	}
+
+	if s.Connect != nil {
+		if s.Provider == ServiceProviderNomad {
+			mErr.Errors = append(mErr.Errors, fmt.Errorf("connect is only supported for services with provider %q", ServiceProviderConsul))
+		} else if err := s.Connect.Validate(); err != nil {
+			mErr.Errors = append(mErr.Errors, fmt.Errorf("connect invalid: %v", err))
+		}
+	}
+	return mErr.ErrorOrNil()
+}
+
+// ValidateName checks if the services Name is valid and should be called after
+// the name has been interpolated
+func (s *Service) ValidateName(name string) error {
+	// Ensure the service name is valid per RFC-952 §1
+	// (https://tools.ietf.org/html/rfc952), RFC-1123 §2.1
+	// (https://tools.ietf.org/html/rfc1123), and RFC-2782
+	// (https://tools.ietf.org/html/rfc2782).
+	re := regexp.MustCompile(`^(?i:[a-z0-9]|[a-z0-9][a-z0-9\-]{0,61}[a-z0-9])$`)
+	if !re.MatchString(name) {
+		return fmt.Errorf("service name must be valid per RFC 1123 and can contain only alphanumeric characters or dashes and must be no longer than 63 characters: %q", name)
+	}
+	return nil
+}
+
+// Hash calculates the hash of the check based on it's content and the service
+// which owns it
+func (s *Service) Hash() string {
+	h := sha1.New()
+	io.WriteString(h, s.Name)
+	io.WriteString(h, strings.Join(s.Tags, ""))
+	io.WriteString(h, s.PortLabel)
+	return fmt.Sprintf("%x", h.Sum(nil))
+}
+
+const (
+	// ServiceProviderConsul registers the service with Consul
+	ServiceProviderConsul = "consul"
+
+	// ServiceProviderNomad registers the service with the Nomad servers
+	ServiceProviderNomad = "nomad"
+)
+
+// ServiceRegistrationID returns the ID of the registration of a service of the
+// given task with the Nomad servers. The ID is stable so that re-registering
+// the service updates the existing registration.
+func ServiceRegistrationID(allocID, task string, service *Service) string {
+	return fmt.Sprintf("_nomad-task-%s-%s-%s-%s", allocID, task, service.Name, service.PortLabel)
+}
+
+// ServiceRegistration is an instance of a service registered with the Nomad
+// servers by the client running the task it belongs to.
+type ServiceRegistration struct {
+	// ID uniquely identifies the registration
+	ID string
+
+	// ServiceName is the interpolated name of the service
+	ServiceName string
+
+	// NodeID, JobID, AllocID and TaskName identify the task providing the
+	// service
+	NodeID   string
+	JobID    string
+	AllocID  string
+	TaskName string
+
+	// Datacenter is the datacenter of the node running the task
+	Datacenter string
+
+	// Tags are the interpolated tags of the service
+	Tags []string
+
+	// Address and Port are where the service can be reached
+	Address string
+	Port    int
+
+	// Status is the health of the service as computed from the results of
+	// its checks by the client. It is passing if the service has no checks.
+	Status string
+
+	// Raft Indexes
+	CreateIndex uint64
+	ModifyIndex uint64
+}
+
+func (s *ServiceRegistration) Copy() *ServiceRegistration {
+	if s == nil {
+		return nil
+	}
+	ns := new(ServiceRegistration)
+	*ns = *s
+	ns.Tags = helper.CopySliceString(s.Tags)
+	return ns
+}
+
+// Validate is used to validate a service registration sent by a client
+func (s *ServiceRegistration) Validate() error {
+	var mErr multierror.Error
+	if s.ID == "" {
+		mErr.Errors = append(mErr.Errors, errors.New("missing registration ID"))
+	}
+	if s.ServiceName == "" {
+		mErr.Errors = append(mErr.Errors, errors.New("missing service name"))
+	}
+	if s.NodeID == "" {
+		mErr.Errors = append(mErr.Errors, errors.New("missing node ID"))
+	}
+	if s.AllocID == "" {
+		mErr.Errors = append(mErr.Errors, errors.New("missing allocation ID"))
+	}
+
+	switch s.Status {
+	case api.HealthPassing, api.HealthWarning, api.HealthCritical:
+	default:
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("invalid status %q", s.Status))
+	}
+	return mErr.ErrorOrNil()
+}
+
+// ServiceRegistrationListStub is used to list the registered services along
+// with the union of the tags of their instances
+type ServiceRegistrationListStub struct {
+	ServiceName string
+	Tags        []string
+}
+
+const (
+	// ConnectProxyPrefix is the prefix of the kind of tasks that run a
+	// Consul Connect sidecar proxy for a service.
+	ConnectProxyPrefix = "connect-proxy"
+)
+
+// ConnectProxyTaskName returns the name of the sidecar task injected into a
+// task group for the given service.
+func ConnectProxyTaskName(service string) string {
+	return fmt.Sprintf("%s-%s", ConnectProxyPrefix, service)
+}
+
+// ConnectProxyPortLabel returns the label of the dynamic port the sidecar
+// proxy of the given service listens on for inbound Connect traffic.
+func ConnectProxyPortLabel(service string) string {
+	return fmt.Sprintf("%s-%s", ConnectProxyPrefix, service)
+}
+
+// ConsulConnect represents a Consul Connect configuration for a service.
+type ConsulConnect struct {
+	// SidecarService is the sidecar proxy service to register alongside the
+	// service.
+	SidecarService *ConsulSidecarService
+}
+
+func (c *ConsulConnect) Copy() *ConsulConnect {
+	if c == nil {
+		return nil
+	}
+	nc := new(ConsulConnect)
+	*nc = *c
+	nc.SidecarService = c.SidecarService.Copy()
+	return nc
+}
+
+// HasSidecar returns whether a sidecar proxy should be run for the service.
+func (c *ConsulConnect) HasSidecar() bool {
+	return c != nil && c.SidecarService != nil
+}
+
+// Validate returns an error if the Connect configuration is invalid.
+func (c *ConsulConnect) Validate() error {
+	if c.SidecarService == nil {
+		return errors.New("Connect must have a sidecar_service")
+	}
+	return c.SidecarService.Validate()
+}
+
+// ConsulSidecarService is the sidecar proxy service registered for a Connect
+// enabled service.
+type ConsulSidecarService struct {
+	// Port is the label of the port the proxy listens on for inbound Connect
+	// traffic. It defaults to a dynamic port allocated for the proxy.
+	Port string
+
+	// Proxy configures the upstreams of the proxy.
+	Proxy *ConsulProxy
 }
 
-func (s *Service) Copy() *Service {
+func (s *ConsulSidecarService) Copy() *ConsulSidecarService {
 	if s == nil {
 		return nil
 	}
-	ns := new(Service)
+	ns := new(ConsulSidecarService)
 	*ns = *s
-	ns.Tags = helper.CopySliceString(ns.Tags)
-
-	if s.Checks != nil {
-		checks := make([]*ServiceCheck, len(ns.Checks))
-		for i, c := range ns.Checks {
-			checks[i] = c.Copy()
-		}
-		ns.Checks = checks
-	}
-
+	ns.Proxy = s.Proxy.Copy()
 	return ns
 }
 
-// Canonicalize interpolates values of Job, Task Group and Task in the Service
-// Name. This also generates check names, service id and check ids.
-func (s *Service) Canonicalize(job string, taskGroup string, task string) {
-	// Ensure empty lists are treated as null to avoid scheduler issues when
-	// using DeepEquals
-	if len(s.Tags) == 0 {
-		s.Tags = nil
-	}
-	if len(s.Checks) == 0 {
-		s.Checks = nil
+// Validate returns an error if the sidecar service is invalid.
+func (s *ConsulSidecarService) Validate() error {
+	if s.Proxy == nil {
+		return nil
 	}
+	return s.Proxy.Validate()
+}
 
-	s.Name = args.ReplaceEnv(s.Name, map[string]string{
-		"JOB":       job,
-		"TASKGROUP": taskGroup,
-		"TASK":      task,
-		"BASE":      fmt.Sprintf("%s-%s-%s", job, taskGroup, task),
-	},
-	)
+// ConsulProxy is the configuration of a Connect sidecar proxy.
+type ConsulProxy struct {
+	// Upstreams are the services the proxy exposes on local ports.
+	Upstreams []*ConsulUpstream
+}
 
-	for _, check := range s.Checks {
-		check.Canonicalize(s.Name)
+func (p *ConsulProxy) Copy() *ConsulProxy {
+	if p == nil {
+		return nil
 	}
+	np := new(ConsulProxy)
+	*np = *p
+	if p.Upstreams != nil {
+		np.Upstreams = make([]*ConsulUpstream, len(p.Upstreams))
+		for i, u := range p.Upstreams {
+			np.Upstreams[i] = u.Copy()
+		}
+	}
+	return np
 }
 
-// Validate checks if the Check definition is valid
-func (s *Service) Validate() error {
+// Validate returns an error if the proxy configuration is invalid.
+func (p *ConsulProxy) Validate() error {
 	var mErr multierror.Error
-
-	// Ensure the service name is valid per the below RFCs but make an exception
-	// for our interpolation syntax
-	// RFC-952 §1 (https://tools.ietf.org/html/rfc952), RFC-1123 §2.1
-	// (https://tools.ietf.org/html/rfc1123), and RFC-2782
-	// (https://tools.ietf.org/html/rfc2782).
-	re := regexp.MustCompile(`^(?i:[a-z0-9]|[a-z0-9\$][a-zA-Z0-9\-\$\{\}\_\.]*[a-z0-9\}])$`)
-	if !re.MatchString(s.Name) {
-		mErr.Errors = append(mErr.Errors, fmt.Errorf("service name must be valid per RFC 1123 and can contain only alphanumeric characters or dashes: %q", s.Name))
-	}
-
-	for _, c := range s.Checks {
-		if s.PortLabel == "" && c.RequiresPort() {
-			mErr.Errors = append(mErr.Errors, fmt.Errorf("check %s invalid: check requires a port but the service %+q has no port", c.Name, s.Name))
-			continue
+	ports := make(map[int]string, len(p.Upstreams))
+	for i, u := range p.Upstreams {
+		if u.DestinationName == "" {
+			mErr.Errors = append(mErr.Errors, fmt.Errorf("upstream %d missing destination_name", i+1))
 		}
-
-		if err := c.validate(); err != nil {
-			mErr.Errors = append(mErr.Errors, fmt.Errorf("check %s invalid: %v", c.Name, err))
+		if u.LocalBindPort <= 0 || u.LocalBindPort > 65535 {
+			mErr.Errors = append(mErr.Errors, fmt.Errorf("upstream %d has invalid local_bind_port %d", i+1, u.LocalBindPort))
+		} else if other, ok := ports[u.LocalBindPort]; ok {
+			mErr.Errors = append(mErr.Errors, fmt.Errorf("upstream %q uses the same local_bind_port as %q", u.DestinationName, other))
+		} else {
+			ports[u.LocalBindPort] = u.DestinationName
 		}
 	}
 	return mErr.ErrorOrNil()
 }
 
-// ValidateName checks if the services Name is valid and should be called after
-// the name has been interpolated
-func (s *Service) ValidateName(name string) error {
-	// Ensure the service name is valid per RFC-952 §1
-	// (https://tools.ietf.org/html/rfc952), RFC-1123 §2.1
-	// (https://tools.ietf.org/html/rfc1123), and RFC-2782
-	// (https://tools.ietf.org/html/rfc2782).
-	re := regexp.MustCompile(`^(?i:[a-z0-9]|[a-z0-9][a-z0-9\-]{0,61}[a-z0-9])$`)
-	if !re.MatchString(name) {
-		return fmt.Errorf("service name must be valid per RFC 1123 and can contain only alphanumeric characters or dashes and must be no longer than 63 characters: %q", name)
-	}
-	return nil
+// ConsulUpstream is a service the Connect proxy exposes on a local port.
+type ConsulUpstream struct {
+	// DestinationName is the name of the upstream service.
+	DestinationName string
+
+	// LocalBindPort is the port the proxy listens on for the upstream.
+	LocalBindPort int
 }
 
-// Hash calculates the hash of the check based on it's content and the service
-// which owns it
-func (s *Service) Hash() string {
-	h := sha1.New()
-	io.WriteString(h, s.Name)
-	io.WriteString(h, strings.Join(s.Tags, ""))
-	io.WriteString(h, s.PortLabel)
-	return fmt.Sprintf("%x", h.Sum(nil))
+func (u *ConsulUpstream) Copy() *ConsulUpstream {
+	if u == nil {
+		return nil
+	}
+	nu := new(ConsulUpstream)
+	*nu = *u
+	return nu
 }
 
 const (
@@ -2292,6 +3276,35 @@ const (
 type LogConfig struct {
 	MaxFiles      int
 	MaxFileSizeMB int
+
+	// Compress enables gzip compression of rotated log files. The log
+	// storage of MaxFiles * MaxFileSizeMB is then accounted by the size of
+	// the compressed files, retaining more history in the same disk space.
+	Compress bool
+
+	// MaxAge is the age after which rotated log files are removed. Zero
+	// retains files regardless of their age.
+	MaxAge time.Duration
+
+	// Sinks are destinations the output of the task is forwarded to in
+	// addition to the log files.
+	Sinks []*LogSink
+}
+
+// Copy returns a copy of the log config.
+func (l *LogConfig) Copy() *LogConfig {
+	if l == nil {
+		return nil
+	}
+	nl := new(LogConfig)
+	*nl = *l
+	if l.Sinks != nil {
+		nl.Sinks = make([]*LogSink, len(l.Sinks))
+		for i, sink := range l.Sinks {
+			nl.Sinks[i] = sink.Copy()
+		}
+	}
+	return nl
 }
 
 // DefaultLogConfig returns the default LogConfig values.
@@ -2312,6 +3325,108 @@ func (l *LogConfig) Validate() error {
 	if l.MaxFileSizeMB < 1 {
 		mErr.Errors = append(mErr.Errors, fmt.Errorf("minimum file size is 1MB; got %d", l.MaxFileSizeMB))
 	}
+	if l.MaxAge < 0 {
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("max age must not be negative; got %v", l.MaxAge))
+	}
+	for i, sink := range l.Sinks {
+		if err := sink.Validate(); err != nil {
+			outer := fmt.Errorf("Sink %d validation failed: %v", i+1, err)
+			mErr.Errors = append(mErr.Errors, outer)
+		}
+	}
+	return mErr.ErrorOrNil()
+}
+
+const (
+	// LogSinkTypeSyslog forwards log lines to a syslog server using the
+	// RFC5424 format.
+	LogSinkTypeSyslog = "syslog"
+
+	// LogSinkTypeUnix forwards log lines as JSON lines to a unix socket.
+	LogSinkTypeUnix = "unix"
+
+	// LogSinkTypeHTTP forwards batches of log lines as JSON lines to an HTTP
+	// endpoint.
+	LogSinkTypeHTTP = "http"
+)
+
+// LogSink is a destination the output of a task is forwarded to.
+type LogSink struct {
+	// Type is the type of the sink.
+	Type string
+
+	// Address is where the sink forwards log lines to. Syslog sinks use
+	// tcp://host:port or udp://host:port, unix sinks the path of a socket and
+	// HTTP sinks an http or https URL.
+	Address string
+
+	// BatchSize is the maximum number of log lines sent to an HTTP sink in a
+	// single request.
+	BatchSize int
+
+	// BatchWait is the maximum time log lines are held back waiting for a
+	// batch to fill before they are sent to an HTTP sink.
+	BatchWait time.Duration
+
+	// BufferSize is the maximum number of log lines buffered while the sink
+	// can't keep up. The oldest lines are dropped once it is reached.
+	BufferSize int
+
+	// Headers are added to the requests sent to an HTTP sink.
+	Headers map[string]string
+}
+
+// Copy returns a copy of the log sink.
+func (s *LogSink) Copy() *LogSink {
+	if s == nil {
+		return nil
+	}
+	ns := new(LogSink)
+	*ns = *s
+	ns.Headers = helper.CopyMapStringString(s.Headers)
+	return ns
+}
+
+// Validate returns an error if the log sink is invalid.
+func (s *LogSink) Validate() error {
+	var mErr multierror.Error
+	switch s.Type {
+	case LogSinkTypeSyslog:
+		u, err := url.Parse(s.Address)
+		if err != nil || (u.Scheme != "tcp" && u.Scheme != "udp") || u.Host == "" {
+			mErr.Errors = append(mErr.Errors, fmt.Errorf("syslog address must be given as tcp://host:port or udp://host:port; got %q", s.Address))
+		}
+	case LogSinkTypeUnix:
+		if s.Address == "" {
+			mErr.Errors = append(mErr.Errors, fmt.Errorf("unix sink requires the path of a socket"))
+		}
+	case LogSinkTypeHTTP:
+		u, err := url.Parse(s.Address)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			mErr.Errors = append(mErr.Errors, fmt.Errorf("http address must be an http or https URL; got %q", s.Address))
+		}
+	default:
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("invalid sink type %q; must be one of %q, %q or %q",
+			s.Type, LogSinkTypeSyslog, LogSinkTypeUnix, LogSinkTypeHTTP))
+	}
+
+	if s.Type != LogSinkTypeHTTP {
+		if s.BatchSize != 0 || s.BatchWait != 0 {
+			mErr.Errors = append(mErr.Errors, fmt.Errorf("batching is only supported by http sinks"))
+		}
+		if len(s.Headers) != 0 {
+			mErr.Errors = append(mErr.Errors, fmt.Errorf("headers are only supported by http sinks"))
+		}
+	}
+	if s.BatchSize < 0 {
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("batch size must be positive; got %d", s.BatchSize))
+	}
+	if s.BatchWait < 0 {
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("batch wait must be positive; got %v", s.BatchWait))
+	}
+	if s.BufferSize < 0 {
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("buffer size must be positive; got %d", s.BufferSize))
+	}
 	return mErr.ErrorOrNil()
 }
 
@@ -2340,6 +3455,10 @@ type Task struct {
 	// have access to.
 	Vault *Vault
 
+	// Identity exposes a token signed by the servers that proves the identity
+	// of the task to other services.
+	Identity *WorkloadIdentity
+
 	// Templates are the set of templates to be rendered for the task.
 	Templates []*Template
 
@@ -2371,6 +3490,20 @@ type Task struct {
 	// Leader marks the task as the leader within the group. When the leader
 	// task exits, other tasks will be gracefully terminated.
 	Leader bool
+
+	// Kind marks tasks that Nomad injected into the task group for a special
+	// purpose. Connect sidecar proxies have the kind "connect-proxy:<service>".
+	Kind string
+}
+
+// ConnectProxyService returns the name of the service the task is a Connect
+// sidecar proxy for, and whether the task is a Connect sidecar proxy at all.
+func (t *Task) ConnectProxyService() (string, bool) {
+	parts := strings.SplitN(t.Kind, ":", 2)
+	if len(parts) != 2 || parts[0] != ConnectProxyPrefix || parts[1] == "" {
+		return "", false
+	}
+	return parts[1], true
 }
 
 func (t *Task) Copy() *Task {
@@ -2392,9 +3525,11 @@ func (t *Task) Copy() *Task {
 	nt.Constraints = CopySliceConstraints(nt.Constraints)
 
 	nt.Vault = nt.Vault.Copy()
+	nt.Identity = nt.Identity.Copy()
 	nt.Resources = nt.Resources.Copy()
 	nt.Meta = helper.CopyMapStringString(nt.Meta)
 	nt.DispatchPayload = nt.DispatchPayload.Copy()
+	nt.LogConfig = nt.LogConfig.Copy()
 
 	if t.Artifacts != nil {
 		artifacts := make([]*TaskArtifact, 0, len(t.Artifacts))
@@ -2464,15 +3599,22 @@ func (t *Task) GoString() string {
 
 func (t *Task) FindHostAndPortFor(portLabel string) (string, int) {
 	for _, network := range t.Resources.Networks {
-		if p, ok := network.MapLabelToValues(nil)[portLabel]; ok {
+		// Allocations on a CNI network are reachable on their own address so
+		// the port inside the network namespace is advertised.
+		portMap := map[string]int(nil)
+		if network.CNIName() != "" {
+			portMap = network.PortMap()
+		}
+		if p, ok := network.MapLabelToValues(portMap)[portLabel]; ok {
 			return network.IP, p
 		}
 	}
 	return "", 0
 }
 
-// Validate is used to sanity check a task
-func (t *Task) Validate(ephemeralDisk *EphemeralDisk) error {
+// Validate is used to sanity check a task. The task group's networks are
+// passed so that services may reference the ports they define.
+func (t *Task) Validate(ephemeralDisk *EphemeralDisk, tgNetworks []*NetworkResource) error {
 	var mErr multierror.Error
 	if t.Name == "" {
 		mErr.Errors = append(mErr.Errors, errors.New("Missing task name"))
@@ -2524,7 +3666,7 @@ func (t *Task) Validate(ephemeralDisk *EphemeralDisk) error {
 	}
 
 	// Validate Services
-	if err := validateServices(t); err != nil {
+	if err := validateServices(t, tgNetworks); err != nil {
 		mErr.Errors = append(mErr.Errors, err)
 	}
 
@@ -2577,7 +3719,7 @@ func (t *Task) Validate(ephemeralDisk *EphemeralDisk) error {
 
 // validateServices takes a task and validates the services within it are valid
 // and reference ports that exist.
-func validateServices(t *Task) error {
+func validateServices(t *Task, tgNetworks []*NetworkResource) error {
 	var mErr multierror.Error
 
 	// Ensure that services don't ask for non-existent ports and their names are
@@ -2621,6 +3763,11 @@ func validateServices(t *Task) error {
 			}
 		}
 	}
+	for _, network := range tgNetworks {
+		for portLabel := range network.MapLabelToValues(nil) {
+			portLabels[portLabel] = struct{}{}
+		}
+	}
 
 	// Ensure all ports referenced in services exist.
 	for servicePort, services := range servicePorts {
@@ -2685,6 +3832,18 @@ type Template struct {
 	// delimiter is utilized when parsing the template.
 	LeftDelim  string
 	RightDelim string
+
+	// Envvars enables exposing the template as environment variables
+	// instead of as a file. The template must be of the form:
+	//
+	//	VAR_NAME_1={{ key service/my-key }}
+	//	VAR_NAME_2=raw string and {{ env "attr.kernel.name" }}
+	//
+	// Lines will be split on the initial "=" with the first part being the
+	// key name and the second part the value.
+	// Empty lines and lines starting with # will be ignored, but to avoid
+	// escaping issues #s within lines will not be treated as comments.
+	Envvars bool
 }
 
 // DefaultTemplate returns a default template.
@@ -3106,6 +4265,11 @@ type TaskArtifact struct {
 	// RelativeDest is the download destination given relative to the task's
 	// directory.
 	RelativeDest string
+
+	// GetterSignature is the source of a detached GPG signature of the
+	// artifact. If set, the artifact is verified against the public keys
+	// configured on the client before it is unpacked.
+	GetterSignature string
 }
 
 func (ta *TaskArtifact) Copy() *TaskArtifact {
@@ -3326,6 +4490,14 @@ type Vault struct {
 	// ChangeSignal is the signal sent to the task when a new token is
 	// retrieved. This is only valid when using the signal change mode.
 	ChangeSignal string
+
+	// Role is the Vault role the task's token is created from. If empty, the
+	// servers' create_from_role is used.
+	Role string
+
+	// EntityAlias is the name of the entity alias the task's token is
+	// created for. It requires a role that allows the alias.
+	EntityAlias string
 }
 
 func DefaultVaultBlock() *Vault {
@@ -3369,6 +4541,10 @@ func (v *Vault) Validate() error {
 		}
 	}
 
+	if v.EntityAlias != "" && v.Role == "" {
+		multierror.Append(&mErr, fmt.Errorf("Role must be specified when using an entity alias"))
+	}
+
 	switch v.ChangeMode {
 	case VaultChangeModeSignal:
 		if v.ChangeSignal == "" {
@@ -3382,6 +4558,124 @@ func (v *Vault) Validate() error {
 	return mErr.ErrorOrNil()
 }
 
+const (
+	// IdentityKeyAlgorithmRS256 signs workload identities using RSA PKCS #1
+	// v1.5 signatures with SHA-256.
+	IdentityKeyAlgorithmRS256 = "RS256"
+)
+
+// WorkloadIdentity configures how the token proving the identity of a task is
+// exposed to it.
+type WorkloadIdentity struct {
+	// Env marks whether the token should be exposed as an environment
+	// variable
+	Env bool
+
+	// File marks whether the token should be written to the task's secrets
+	// directory
+	File bool
+}
+
+// Copy returns a copy of this WorkloadIdentity block.
+func (w *WorkloadIdentity) Copy() *WorkloadIdentity {
+	if w == nil {
+		return nil
+	}
+
+	nw := new(WorkloadIdentity)
+	*nw = *w
+	return nw
+}
+
+// IdentityKey is a key used by the servers to sign the workload identities of
+// tasks. The leader periodically rotates the key, retiring the previous one.
+// Retired keys are kept to verify the tokens they signed until no allocation
+// that may use them remains.
+type IdentityKey struct {
+	// ID is the key ID set in the header of the tokens the key signed
+	ID string
+
+	// Algorithm is the signing algorithm of the key
+	Algorithm string
+
+	// PrivateKey is the DER encoded PKCS #1 private key
+	PrivateKey []byte
+
+	// Active marks the key used to sign new tokens. Only one key is active
+	// at a time.
+	Active bool
+
+	// CreateTime is the time the key was created at in nanoseconds
+	CreateTime int64
+
+	// RetireIndex is the Raft index at which the key stopped being active
+	RetireIndex uint64
+
+	// Raft Indexes
+	CreateIndex uint64
+	ModifyIndex uint64
+}
+
+// Copy returns a copy of this IdentityKey.
+func (k *IdentityKey) Copy() *IdentityKey {
+	if k == nil {
+		return nil
+	}
+
+	nk := new(IdentityKey)
+	*nk = *k
+	nk.PrivateKey = make([]byte, len(k.PrivateKey))
+	copy(nk.PrivateKey, k.PrivateKey)
+	return nk
+}
+
+// IdentityClaims are the claims of the token proving the identity of a task.
+type IdentityClaims struct {
+	Subject   string `json:"sub"`
+	IssuedAt  int64  `json:"iat"`
+	NotBefore int64  `json:"nbf"`
+	Region    string `json:"nomad_region"`
+	JobID     string `json:"nomad_job_id"`
+	TaskGroup string `json:"nomad_task_group"`
+	Task      string `json:"nomad_task"`
+	AllocID   string `json:"nomad_allocation_id"`
+	NodeID    string `json:"nomad_node_id"`
+}
+
+// NewIdentityClaims returns the identity claims of the task of the allocation
+// in the given region.
+func NewIdentityClaims(region string, alloc *Allocation, task string, now time.Time) *IdentityClaims {
+	return &IdentityClaims{
+		Subject:   fmt.Sprintf("%s:%s:%s:%s", region, alloc.JobID, alloc.TaskGroup, task),
+		IssuedAt:  now.Unix(),
+		NotBefore: now.Unix(),
+		Region:    region,
+		JobID:     alloc.JobID,
+		TaskGroup: alloc.TaskGroup,
+		Task:      task,
+		AllocID:   alloc.ID,
+		NodeID:    alloc.NodeID,
+	}
+}
+
+// JSONWebKey is a public key that workload identities can be verified with,
+// in the JSON Web Key format of RFC 7517.
+type JSONWebKey struct {
+	KeyID     string `json:"kid"`
+	KeyType   string `json:"kty"`
+	Algorithm string `json:"alg"`
+	Use       string `json:"use"`
+
+	// N and E are the base64url encoded modulus and exponent of an RSA key
+	N string `json:"n"`
+	E string `json:"e"`
+}
+
+// JSONWebKeySet is a set of JSON Web Keys.
+type JSONWebKeySet struct {
+	Keys []*JSONWebKey `json:"keys"`
+}
+
 const (
 	AllocDesiredStatusRun   = "run"   // Allocation should run
 	AllocDesiredStatusStop  = "stop"  // Allocation should stop
@@ -3449,6 +4743,10 @@ type Allocation struct {
 	// TaskStates stores the state of each task,
 	TaskStates map[string]*TaskState
 
+	// NetworkStatus is the status of the allocation's network namespace as
+	// reported by the client.
+	NetworkStatus *AllocNetworkStatus
+
 	// PreviousAllocation is the allocation that this allocation is replacing
 	PreviousAllocation string
 
@@ -3485,6 +4783,7 @@ func (a *Allocation) Copy() *Allocation {
 	}
 
 	na.Metrics = na.Metrics.Copy()
+	na.NetworkStatus = na.NetworkStatus.Copy()
 
 	if a.TaskStates != nil {
 		ts := make(map[string]*TaskState, len(na.TaskStates))
@@ -3496,6 +4795,26 @@ func (a *Allocation) Copy() *Allocation {
 	return na
 }
 
+// AllocNetworkStatus is the status of the network namespace of an
+// allocation using bridge or CNI networking.
+type AllocNetworkStatus struct {
+	// InterfaceName is the name of the interface the address is assigned to
+	InterfaceName string
+
+	// Address is the IP address of the allocation inside its namespace
+	Address string
+}
+
+// Copy returns a copy of the network status
+func (a *AllocNetworkStatus) Copy() *AllocNetworkStatus {
+	if a == nil {
+		return nil
+	}
+	na := new(AllocNetworkStatus)
+	*na = *a
+	return na
+}
+
 // TerminalStatus returns if the desired or actual status is terminal and
 // will no longer transition.
 func (a *Allocation) TerminalStatus() bool {
@@ -3743,6 +5062,7 @@ const (
 	EvalTriggerScheduled     = "scheduled"
 	EvalTriggerRollingUpdate = "rolling-update"
 	EvalTriggerMaxPlans      = "max-plan-attempts"
+	EvalTriggerScaling       = "job-scaling"
 )
 
 const (
@@ -4118,6 +5438,131 @@ func (p *PlanResult) FullCommit(plan *Plan) (bool, int, int) {
 	return actual == expected, expected, actual
 }
 
+const (
+	// EvalTrackedPlans is the number of applied plans recorded for each
+	// evaluation
+	EvalTrackedPlans = 10
+)
+
+// AppliedPlan is the record of a plan applied for an evaluation. The
+// allocations are recorded as stubs to bound the size of the record.
+type AppliedPlan struct {
+	// EvalID and JobID are the evaluation the plan was submitted for and its
+	// job
+	EvalID string
+	JobID  string
+
+	// Priority and AllAtOnce are copied from the plan
+	Priority  int
+	AllAtOnce bool
+
+	// NodeUpdate and NodeAllocation are the committed updates and
+	// allocations of each node
+	NodeUpdate     map[string][]*AllocListStub
+	NodeAllocation map[string][]*AllocListStub
+
+	// RejectedNodes are the nodes whose updates or allocations were not
+	// committed because of stale data or over commitment
+	RejectedNodes []string
+
+	// Annotations are the annotations of the plan made by the scheduler
+	Annotations *PlanAnnotations
+
+	// RefreshIndex is the index the scheduler was asked to refresh its state
+	// up to, if the plan was partially committed
+	RefreshIndex uint64
+
+	// AllocIndex is the Raft index at which the plan was applied
+	AllocIndex uint64
+
+	// ApplyTime is the time at which the plan was applied
+	ApplyTime int64
+}
+
+// AppliedPlanFromResult returns the record of the plan applied with the given
+// result. The allocations of the result are recorded when the plan is
+// applied by the state store.
+func AppliedPlanFromResult(plan *Plan, result *PlanResult) *AppliedPlan {
+	applied := &AppliedPlan{
+		EvalID:       plan.EvalID,
+		Priority:     plan.Priority,
+		AllAtOnce:    plan.AllAtOnce,
+		Annotations:  plan.Annotations,
+		RefreshIndex: result.RefreshIndex,
+	}
+	if plan.Job != nil {
+		applied.JobID = plan.Job.ID
+	}
+
+	rejected := make(map[string]struct{})
+	for node := range plan.NodeUpdate {
+		if _, ok := result.NodeUpdate[node]; !ok {
+			rejected[node] = struct{}{}
+		}
+	}
+	for node := range plan.NodeAllocation {
+		if _, ok := result.NodeAllocation[node]; !ok {
+			rejected[node] = struct{}{}
+		}
+	}
+	for node := range rejected {
+		applied.RejectedNodes = append(applied.RejectedNodes, node)
+	}
+	sort.Strings(applied.RejectedNodes)
+	return applied
+}
+
+// AddAllocs records the allocations applied with the plan. Allocations that
+// are stopped or evicted are recorded as updates.
+func (p *AppliedPlan) AddAllocs(allocs []*Allocation) {
+	p.NodeUpdate = make(map[string][]*AllocListStub)
+	p.NodeAllocation = make(map[string][]*AllocListStub)
+	for _, alloc := range allocs {
+		stub := alloc.Stub()
+		stub.TaskStates = nil
+		if alloc.DesiredStatus == AllocDesiredStatusRun {
+			p.NodeAllocation[alloc.NodeID] = append(p.NodeAllocation[alloc.NodeID], stub)
+		} else {
+			p.NodeUpdate[alloc.NodeID] = append(p.NodeUpdate[alloc.NodeID], stub)
+		}
+	}
+}
+
+// EvalPlans is the record of the plans applied for an evaluation
+type EvalPlans struct {
+	EvalID string
+	JobID  string
+
+	// Plans are the applied plans, most recent first
+	Plans []*AppliedPlan
+
+	CreateIndex uint64
+	ModifyIndex uint64
+}
+
+// Copy returns a copy of the record. The plans are shared as they are not
+// modified once recorded.
+func (e *EvalPlans) Copy() *EvalPlans {
+	if e == nil {
+		return nil
+	}
+	ne := new(EvalPlans)
+	*ne = *e
+	ne.Plans = make([]*AppliedPlan, len(e.Plans))
+	copy(ne.Plans, e.Plans)
+	return ne
+}
+
+// AddPlan adds a plan to the record, dropping the oldest plans beyond
+// EvalTrackedPlans.
+func (e *EvalPlans) AddPlan(plan *AppliedPlan) {
+	plans := append([]*AppliedPlan{plan}, e.Plans...)
+	if len(plans) > EvalTrackedPlans {
+		plans = plans[:EvalTrackedPlans]
+	}
+	e.Plans = plans
+}
+
 // PlanAnnotations holds annotations made by the scheduler to give further debug
 // information to operators.
 type PlanAnnotations struct {
@@ -450,10 +450,200 @@ func TestTaskGroup_Validate(t *testing.T) {
 	}
 }
 
+func TestTaskGroup_Validate_Scaling(t *testing.T) {
+	j := testJob()
+	tg := j.TaskGroups[0]
+	tg.Count = 5
+	tg.Scaling = &ScalingPolicy{
+		Min:     1,
+		Max:     10,
+		Enabled: true,
+		Policy: map[string]interface{}{
+			"metric":   "cpu",
+			"target":   int64(70),
+			"cooldown": "2m",
+		},
+	}
+	if err := tg.Validate(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Count out of bounds and a minimum greater than the maximum
+	tg.Count = 20
+	tg.Scaling.Min = 12
+	err := tg.Validate()
+	if err == nil {
+		t.Fatalf("expected error")
+	}
+	if !strings.Contains(err.Error(), "outside of the bounds") {
+		t.Fatalf("err: %s", err)
+	}
+	if !strings.Contains(err.Error(), "greater than maximum count") {
+		t.Fatalf("err: %s", err)
+	}
+
+	// Missing maximum
+	tg.Count = 5
+	tg.Scaling.Min = 1
+	tg.Scaling.Max = -1
+	err = tg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "Maximum count must be specified") {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Invalid policy for the built-in autoscaler
+	tg.Scaling.Max = 10
+	tg.Scaling.Policy["metric"] = "disk"
+	err = tg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "Policy metric") {
+		t.Fatalf("err: %v", err)
+	}
+	tg.Scaling.Policy["metric"] = "memory"
+	tg.Scaling.Policy["cooldown"] = "soon"
+	err = tg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "Policy cooldown") {
+		t.Fatalf("err: %v", err)
+	}
+
+	// System jobs can not be scaled
+	j = testJob()
+	j.Type = JobTypeSystem
+	j.TaskGroups[0].Count = 1
+	j.TaskGroups[0].Scaling = &ScalingPolicy{Min: 1, Max: 1}
+	err = j.Validate()
+	if err == nil || !strings.Contains(err.Error(), "System jobs can not be scaled") {
+		t.Fatalf("err: %v", err)
+	}
+}
+
+func TestScalingPolicy_ScalingTarget(t *testing.T) {
+	p := &ScalingPolicy{}
+	if target, err := p.ScalingTarget(); err != nil || target != nil {
+		t.Fatalf("bad: %#v %v", target, err)
+	}
+
+	p.Policy = map[string]interface{}{
+		"metric":   "memory",
+		"target":   "80",
+		"cooldown": "30s",
+	}
+	target, err := p.ScalingTarget()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	expected := &ScalingTarget{
+		Metric:   ScalingMetricMemory,
+		Target:   80,
+		Cooldown: 30 * time.Second,
+	}
+	if !reflect.DeepEqual(target, expected) {
+		t.Fatalf("bad: %#v", target)
+	}
+
+	p.Policy["target"] = 150.0
+	if _, err := p.ScalingTarget(); err == nil {
+		t.Fatalf("expected error")
+	}
+	delete(p.Policy, "target")
+	if _, err := p.ScalingTarget(); err == nil {
+		t.Fatalf("expected error")
+	}
+}
+
+func TestTaskGroup_Validate_Network(t *testing.T) {
+	j := testJob()
+	tg := j.TaskGroups[0]
+	task := tg.Tasks[0]
+
+	// Move the task's port to the group so the service references it
+	task.Resources.Networks = nil
+	tg.Networks = []*NetworkResource{
+		{
+			Mode:          NetworkModeBridge,
+			MBits:         10,
+			DynamicPorts:  []Port{{Label: "http", To: 8080}},
+			ReservedPorts: []Port{{Label: "admin", Value: 9000}},
+		},
+	}
+	if err := tg.Validate(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Invalid mode and mapped port
+	tg.Networks[0].Mode = "overlay"
+	tg.Networks[0].DynamicPorts[0].To = 70000
+	err := tg.Validate()
+	if err == nil {
+		t.Fatalf("expected error")
+	}
+	if !strings.Contains(err.Error(), "invalid network mode") {
+		t.Fatalf("err: %s", err)
+	}
+	if !strings.Contains(err.Error(), "invalid mapped port") {
+		t.Fatalf("err: %s", err)
+	}
+
+	// Port label collision with the task
+	tg.Networks[0].Mode = NetworkModeBridge
+	tg.Networks[0].DynamicPorts[0].To = 8080
+	task.Resources.Networks = []*NetworkResource{
+		{
+			MBits:        10,
+			DynamicPorts: []Port{{Label: "admin"}},
+		},
+	}
+	err = tg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "conflicts with the task group network") {
+		t.Fatalf("err: %v", err)
+	}
+
+	// CNI networks must be named
+	task.Resources.Networks = nil
+	tg.Networks[0].Mode = "cni/macvlan"
+	if err := tg.Validate(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	tg.Networks[0].Mode = NetworkModeCNIPrefix
+	err = tg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "missing the CNI network name") {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Only one network
+	tg.Networks[0].Mode = NetworkModeBridge
+	tg.Networks = append(tg.Networks, &NetworkResource{MBits: 10})
+	err = tg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "Only one task group network") {
+		t.Fatalf("err: %v", err)
+	}
+}
+
+func TestTask_FindHostAndPortFor_CNI(t *testing.T) {
+	task := &Task{
+		Resources: &Resources{
+			Networks: []*NetworkResource{
+				{
+					IP:           "10.0.0.5",
+					DynamicPorts: []Port{{Label: "http", Value: 23456, To: 8080}},
+				},
+			},
+		},
+	}
+	if ip, port := task.FindHostAndPortFor("http"); ip != "10.0.0.5" || port != 23456 {
+		t.Fatalf("bad: %s:%d", ip, port)
+	}
+
+	// Allocations on a CNI network advertise the port inside the namespace
+	task.Resources.Networks[0].Mode = "cni/macvlan"
+	if ip, port := task.FindHostAndPortFor("http"); ip != "10.0.0.5" || port != 8080 {
+		t.Fatalf("bad: %s:%d", ip, port)
+	}
+}
+
 func TestTask_Validate(t *testing.T) {
 	task := &Task{}
 	ephemeralDisk := DefaultEphemeralDisk()
-	err := task.Validate(ephemeralDisk)
+	err := task.Validate(ephemeralDisk, nil)
 	mErr := err.(*multierror.Error)
 	if !strings.Contains(mErr.Errors[0].Error(), "task name") {
 		t.Fatalf("err: %s", err)
@@ -466,7 +656,7 @@ func TestTask_Validate(t *testing.T) {
 	}
 
 	task = &Task{Name: "web/foo"}
-	err = task.Validate(ephemeralDisk)
+	err = task.Validate(ephemeralDisk, nil)
 	mErr = err.(*multierror.Error)
 	if !strings.Contains(mErr.Errors[0].Error(), "slashes") {
 		t.Fatalf("err: %s", err)
@@ -483,7 +673,7 @@ func TestTask_Validate(t *testing.T) {
 		LogConfig: DefaultLogConfig(),
 	}
 	ephemeralDisk.SizeMB = 200
-	err = task.Validate(ephemeralDisk)
+	err = task.Validate(ephemeralDisk, nil)
 	if err != nil {
 		t.Fatalf("err: %s", err)
 	}
@@ -497,7 +687,7 @@ func TestTask_Validate(t *testing.T) {
 			LTarget: "${meta.rack}",
 		})
 
-	err = task.Validate(ephemeralDisk)
+	err = task.Validate(ephemeralDisk, nil)
 	mErr = err.(*multierror.Error)
 	if !strings.Contains(mErr.Errors[0].Error(), "task level: distinct_hosts") {
 		t.Fatalf("err: %s", err)
@@ -580,7 +770,7 @@ func TestTask_Validate_Services(t *testing.T) {
 		},
 	}
 
-	err := task.Validate(ephemeralDisk)
+	err := task.Validate(ephemeralDisk, nil)
 	if err == nil {
 		t.Fatal("expected an error")
 	}
@@ -601,7 +791,7 @@ func TestTask_Validate_Services(t *testing.T) {
 		t.Fatalf("err: %v", err)
 	}
 
-	if err = task1.Validate(ephemeralDisk); err != nil {
+	if err = task1.Validate(ephemeralDisk, nil); err != nil {
 		t.Fatalf("err : %v", err)
 	}
 }
@@ -649,6 +839,111 @@ func TestTask_Validate_Service_Check(t *testing.T) {
 	}
 }
 
+func TestTask_Validate_Service_Check_GRPC(t *testing.T) {
+	check := ServiceCheck{
+		Name:        "check-name",
+		Type:        ServiceCheckGRPC,
+		GRPCService: "foo.Bar",
+		Interval:    10 * time.Second,
+		Timeout:     2 * time.Second,
+	}
+	if err := check.validate(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !check.RequiresPort() {
+		t.Fatalf("grpc checks should require a port")
+	}
+
+	check.Timeout = 0
+	if err := check.validate(); err == nil || !strings.Contains(err.Error(), "timeout") {
+		t.Fatalf("expected timeout error: %v", err)
+	}
+
+	check.Timeout = 2 * time.Second
+	check.Method = "POST"
+	if err := check.validate(); err == nil || !strings.Contains(err.Error(), "only valid for http checks") {
+		t.Fatalf("expected http fields error: %v", err)
+	}
+}
+
+func TestTask_Validate_Service_Check_HTTP(t *testing.T) {
+	check := ServiceCheck{
+		Name:     "check-name",
+		Type:     ServiceCheckHTTP,
+		Path:     "/health",
+		Method:   "POST",
+		Header:   map[string][]string{"X-Foo": {"bar", "baz"}},
+		Body:     "{}",
+		Interval: 10 * time.Second,
+		Timeout:  2 * time.Second,
+	}
+	if err := check.validate(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	check.GRPCUseTLS = true
+	if err := check.validate(); err == nil || !strings.Contains(err.Error(), "only valid for grpc checks") {
+		t.Fatalf("expected grpc fields error: %v", err)
+	}
+
+	check.GRPCUseTLS = false
+	check.CheckRestart = &CheckRestart{Limit: -1, Grace: -1 * time.Second}
+	err := check.validate()
+	if err == nil {
+		t.Fatalf("expected check_restart error")
+	}
+	if !strings.Contains(err.Error(), "limit") || !strings.Contains(err.Error(), "grace") {
+		t.Fatalf("err: %v", err)
+	}
+}
+
+func TestServiceCheck_Hash(t *testing.T) {
+	check := &ServiceCheck{
+		Name:     "check-name",
+		Type:     ServiceCheckHTTP,
+		Path:     "/health",
+		Interval: 10 * time.Second,
+		Timeout:  2 * time.Second,
+	}
+	orig := check.Hash("service")
+
+	// Restart behavior is not part of the Consul registration
+	check.CheckRestart = &CheckRestart{Limit: 3}
+	if h := check.Hash("service"); h != orig {
+		t.Fatalf("check_restart changed the hash")
+	}
+
+	check.Header = map[string][]string{"X-Foo": {"bar"}}
+	withHeader := check.Hash("service")
+	if withHeader == orig {
+		t.Fatalf("header did not change the hash")
+	}
+
+	check.TLSSkipVerify = true
+	if h := check.Hash("service"); h == withHeader {
+		t.Fatalf("tls_skip_verify did not change the hash")
+	}
+}
+
+func TestServiceCheck_Copy(t *testing.T) {
+	check := &ServiceCheck{
+		Name:         "check-name",
+		Type:         ServiceCheckHTTP,
+		Header:       map[string][]string{"X-Foo": {"bar"}},
+		CheckRestart: &CheckRestart{Limit: 3, Grace: 10 * time.Second},
+	}
+	c := check.Copy()
+	if !reflect.DeepEqual(check, c) {
+		t.Fatalf("Copy() returned an unequal ServiceCheck; got %#v; want %#v", c, check)
+	}
+
+	c.Header["X-Foo"][0] = "baz"
+	c.CheckRestart.Limit = 5
+	if check.Header["X-Foo"][0] != "bar" || check.CheckRestart.Limit != 3 {
+		t.Fatalf("Copy() did not deep copy the check")
+	}
+}
+
 func TestTask_Validate_LogConfig(t *testing.T) {
 	task := &Task{
 		LogConfig: DefaultLogConfig(),
@@ -657,13 +952,60 @@ func TestTask_Validate_LogConfig(t *testing.T) {
 		SizeMB: 1,
 	}
 
-	err := task.Validate(ephemeralDisk)
+	err := task.Validate(ephemeralDisk, nil)
 	mErr := err.(*multierror.Error)
 	if !strings.Contains(mErr.Errors[3].Error(), "log storage") {
 		t.Fatalf("err: %s", err)
 	}
 }
 
+func TestLogConfig_Validate_MaxAge(t *testing.T) {
+	l := DefaultLogConfig()
+	l.Compress = true
+	l.MaxAge = 72 * time.Hour
+	if err := l.Validate(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	l.MaxAge = -time.Hour
+	if err := l.Validate(); err == nil || !strings.Contains(err.Error(), "max age") {
+		t.Fatalf("expected max age error: %v", err)
+	}
+}
+
+func TestLogSink_Validate(t *testing.T) {
+	cases := []struct {
+		Input *LogSink
+		Err   bool
+	}{
+		{&LogSink{Type: LogSinkTypeSyslog, Address: "udp://127.0.0.1:514"}, false},
+		{&LogSink{Type: LogSinkTypeSyslog, Address: "tcp://logs.example.com:601", BufferSize: 100}, false},
+		{&LogSink{Type: LogSinkTypeSyslog, Address: "127.0.0.1:514"}, true},
+		{&LogSink{Type: LogSinkTypeSyslog, Address: "udp://127.0.0.1:514", BatchSize: 10}, true},
+		{&LogSink{Type: LogSinkTypeUnix, Address: "/run/fluentd.sock"}, false},
+		{&LogSink{Type: LogSinkTypeUnix}, true},
+		{&LogSink{Type: LogSinkTypeUnix, Address: "/run/fluentd.sock", Headers: map[string]string{"foo": "bar"}}, true},
+		{&LogSink{Type: LogSinkTypeHTTP, Address: "https://logs.example.com/ingest", BatchSize: 100, BatchWait: time.Second}, false},
+		{&LogSink{Type: LogSinkTypeHTTP, Address: "ftp://logs.example.com"}, true},
+		{&LogSink{Type: LogSinkTypeHTTP, Address: "https://logs.example.com", BatchSize: -1}, true},
+		{&LogSink{Type: "kafka", Address: "localhost:9092"}, true},
+	}
+
+	for i, tc := range cases {
+		err := tc.Input.Validate()
+		if (err != nil) != tc.Err {
+			t.Fatalf("case %d: %v", i, err)
+		}
+	}
+
+	// Sinks are validated with the log config
+	config := DefaultLogConfig()
+	config.Sinks = []*LogSink{{Type: LogSinkTypeUnix}}
+	if err := config.Validate(); err == nil || !strings.Contains(err.Error(), "Sink 1 validation failed") {
+		t.Fatalf("err: %v", err)
+	}
+}
+
 func TestTask_Validate_Template(t *testing.T) {
 
 	bad := &Template{}
@@ -674,7 +1016,7 @@ func TestTask_Validate_Template(t *testing.T) {
 		SizeMB: 1,
 	}
 
-	err := task.Validate(ephemeralDisk)
+	err := task.Validate(ephemeralDisk, nil)
 	if !strings.Contains(err.Error(), "Template 1 validation failed") {
 		t.Fatalf("err: %s", err)
 	}
@@ -687,7 +1029,7 @@ func TestTask_Validate_Template(t *testing.T) {
 	}
 
 	task.Templates = []*Template{good, good}
-	err = task.Validate(ephemeralDisk)
+	err = task.Validate(ephemeralDisk, nil)
 	if !strings.Contains(err.Error(), "same destination as") {
 		t.Fatalf("err: %s", err)
 	}
@@ -890,7 +1232,7 @@ func TestResource_Add(t *testing.T) {
 			&NetworkResource{
 				CIDR:          "10.0.0.0/8",
 				MBits:         100,
-				ReservedPorts: []Port{{"ssh", 22}},
+				ReservedPorts: []Port{{"ssh", 22, 0}},
 			},
 		},
 	}
@@ -903,7 +1245,7 @@ func TestResource_Add(t *testing.T) {
 			&NetworkResource{
 				IP:            "10.0.0.1",
 				MBits:         50,
-				ReservedPorts: []Port{{"web", 80}},
+				ReservedPorts: []Port{{"web", 80, 0}},
 			},
 		},
 	}
@@ -922,7 +1264,7 @@ func TestResource_Add(t *testing.T) {
 			&NetworkResource{
 				CIDR:          "10.0.0.0/8",
 				MBits:         150,
-				ReservedPorts: []Port{{"ssh", 22}, {"web", 80}},
+				ReservedPorts: []Port{{"ssh", 22, 0}, {"web", 80, 0}},
 			},
 		},
 	}
@@ -938,7 +1280,7 @@ func TestResource_Add_Network(t *testing.T) {
 		Networks: []*NetworkResource{
 			&NetworkResource{
 				MBits:        50,
-				DynamicPorts: []Port{{"http", 0}, {"https", 0}},
+				DynamicPorts: []Port{{"http", 0, 0}, {"https", 0, 0}},
 			},
 		},
 	}
@@ -946,7 +1288,7 @@ func TestResource_Add_Network(t *testing.T) {
 		Networks: []*NetworkResource{
 			&NetworkResource{
 				MBits:        25,
-				DynamicPorts: []Port{{"admin", 0}},
+				DynamicPorts: []Port{{"admin", 0, 0}},
 			},
 		},
 	}
@@ -964,7 +1306,7 @@ func TestResource_Add_Network(t *testing.T) {
 		Networks: []*NetworkResource{
 			&NetworkResource{
 				MBits:        75,
-				DynamicPorts: []Port{{"http", 0}, {"https", 0}, {"admin", 0}},
+				DynamicPorts: []Port{{"http", 0, 0}, {"https", 0, 0}, {"admin", 0, 0}},
 			},
 		},
 	}
@@ -1172,6 +1514,107 @@ func TestService_Canonicalize(t *testing.T) {
 
 }
 
+func TestService_Connect_Validate(t *testing.T) {
+	s := &Service{
+		Name: "api",
+		Connect: &ConsulConnect{
+			SidecarService: &ConsulSidecarService{
+				Proxy: &ConsulProxy{
+					Upstreams: []*ConsulUpstream{
+						{DestinationName: "db", LocalBindPort: 9191},
+						{DestinationName: "cache", LocalBindPort: 9191},
+						{LocalBindPort: 0},
+					},
+				},
+			},
+		},
+	}
+
+	err := s.Validate()
+	if err == nil {
+		t.Fatalf("expected error")
+	}
+	for _, exp := range []string{"same local_bind_port", "missing destination_name", "invalid local_bind_port"} {
+		if !strings.Contains(err.Error(), exp) {
+			t.Fatalf("expected error containing %q; got %v", exp, err)
+		}
+	}
+
+	s.Connect.SidecarService.Proxy.Upstreams = s.Connect.SidecarService.Proxy.Upstreams[:1]
+	if err := s.Validate(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	s.Connect.SidecarService = nil
+	if err := s.Validate(); err == nil {
+		t.Fatalf("expected error for missing sidecar_service")
+	}
+}
+
+func TestService_Provider_Validate(t *testing.T) {
+	s := &Service{
+		Name:      "api",
+		PortLabel: "http",
+		Provider:  "etcd",
+	}
+	err := s.Validate()
+	if err == nil || !strings.Contains(err.Error(), "invalid provider") {
+		t.Fatalf("expected invalid provider error; got %v", err)
+	}
+
+	s.Provider = ServiceProviderNomad
+	s.Checks = []*ServiceCheck{
+		{
+			Name:     "alive",
+			Type:     ServiceCheckTCP,
+			Interval: 10 * time.Second,
+			Timeout:  2 * time.Second,
+		},
+	}
+	if err := s.Validate(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	s.Checks = append(s.Checks, &ServiceCheck{
+		Name:         "script",
+		Type:         ServiceCheckScript,
+		Command:      "/bin/true",
+		Interval:     10 * time.Second,
+		Timeout:      2 * time.Second,
+		CheckRestart: &CheckRestart{Limit: 1},
+	})
+	s.Connect = &ConsulConnect{SidecarService: &ConsulSidecarService{}}
+	err = s.Validate()
+	if err == nil {
+		t.Fatalf("expected error")
+	}
+	for _, exp := range []string{"only support", "check_restart is only supported", "connect is only supported"} {
+		if !strings.Contains(err.Error(), exp) {
+			t.Fatalf("expected error containing %q; got %v", exp, err)
+		}
+	}
+}
+
+func TestTask_ConnectProxyService(t *testing.T) {
+	cases := map[string]struct {
+		service string
+		ok      bool
+	}{
+		"":                  {"", false},
+		"connect-proxy":     {"", false},
+		"connect-proxy:":    {"", false},
+		"other:api":         {"", false},
+		"connect-proxy:api": {"api", true},
+	}
+	for kind, exp := range cases {
+		task := &Task{Kind: kind}
+		service, ok := task.ConnectProxyService()
+		if service != exp.service || ok != exp.ok {
+			t.Fatalf("kind %q: got (%q, %v); want (%q, %v)", kind, service, ok, exp.service, exp.ok)
+		}
+	}
+}
+
 func TestJob_ExpandServiceNames(t *testing.T) {
 	j := &Job{
 		Name: "my-job",
@@ -1590,6 +2033,20 @@ func TestVault_Validate(t *testing.T) {
 	if !strings.Contains(err.Error(), "root") {
 		t.Fatalf("Expected root error")
 	}
+
+	v = &Vault{
+		Policies:    []string{"foo"},
+		ChangeMode:  VaultChangeModeRestart,
+		EntityAlias: "web",
+	}
+	if err := v.Validate(); err == nil || !strings.Contains(err.Error(), "Role must") {
+		t.Fatalf("Expected role required error: %v", err)
+	}
+
+	v.Role = "web"
+	if err := v.Validate(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
 }
 
 func TestParameterizedJobConfig_Validate(t *testing.T) {
@@ -1670,3 +2127,55 @@ func TestIsRecoverable(t *testing.T) {
 		t.Errorf("Explicitly recoverable errors *should* be recoverable")
 	}
 }
+
+func TestAppliedPlanFromResult(t *testing.T) {
+	job := &Job{ID: "example"}
+	plan := &Plan{
+		EvalID:   "eval",
+		Priority: 50,
+		Job:      job,
+		NodeUpdate: map[string][]*Allocation{
+			"node1": []*Allocation{{ID: "stop"}},
+			"node3": []*Allocation{{ID: "rejected-stop"}},
+		},
+		NodeAllocation: map[string][]*Allocation{
+			"node1": []*Allocation{{ID: "place"}},
+			"node2": []*Allocation{{ID: "rejected-place"}},
+		},
+		Annotations: &PlanAnnotations{},
+	}
+	result := &PlanResult{
+		NodeUpdate:     map[string][]*Allocation{"node1": plan.NodeUpdate["node1"]},
+		NodeAllocation: map[string][]*Allocation{"node1": plan.NodeAllocation["node1"]},
+		RefreshIndex:   100,
+	}
+
+	applied := AppliedPlanFromResult(plan, result)
+	if applied.EvalID != "eval" || applied.JobID != "example" || applied.Priority != 50 ||
+		applied.RefreshIndex != 100 || applied.Annotations != plan.Annotations {
+		t.Fatalf("bad: %#v", applied)
+	}
+	if !reflect.DeepEqual(applied.RejectedNodes, []string{"node2", "node3"}) {
+		t.Fatalf("bad rejected nodes: %v", applied.RejectedNodes)
+	}
+}
+
+func TestEvalPlans_AddPlan(t *testing.T) {
+	plans := &EvalPlans{EvalID: "eval"}
+	for i := 0; i < EvalTrackedPlans+1; i++ {
+		plans.AddPlan(&AppliedPlan{AllocIndex: uint64(i)})
+	}
+	if len(plans.Plans) != EvalTrackedPlans {
+		t.Fatalf("bad: %d", len(plans.Plans))
+	}
+	if plans.Plans[0].AllocIndex != EvalTrackedPlans || plans.Plans[EvalTrackedPlans-1].AllocIndex != 1 {
+		t.Fatalf("bad order: %d %d", plans.Plans[0].AllocIndex, plans.Plans[EvalTrackedPlans-1].AllocIndex)
+	}
+
+	// Copies don't share the list of plans
+	c := plans.Copy()
+	c.AddPlan(&AppliedPlan{})
+	if plans.Plans[0].AllocIndex != EvalTrackedPlans {
+		t.Fatalf("copy modified the original")
+	}
+}
@@ -14,6 +14,7 @@ import (
 	"gopkg.in/tomb.v2"
 
 	metrics "github.com/armon/go-metrics"
+	"github.com/hashicorp/consul/lib"
 	multierror "github.com/hashicorp/go-multierror"
 	"github.com/hashicorp/nomad/nomad/structs"
 	"github.com/hashicorp/nomad/nomad/structs/config"
@@ -568,15 +569,23 @@ func (v *vaultClient) renew() error {
 
 // getWrappingFn returns an appropriate wrapping function for Nomad Servers
 func (v *vaultClient) getWrappingFn() func(operation, path string) string {
-	createPath := "auth/token/create"
+	createPaths := make(map[string]struct{})
 	role := v.getRole()
 	if role != "" {
-		createPath = fmt.Sprintf("auth/token/create/%s", role)
+		createPaths[fmt.Sprintf(vaultTokenRoleCreatePath, role)] = struct{}{}
+	} else {
+		createPaths["auth/token/create"] = struct{}{}
+	}
+	for _, r := range v.config.AllowedRoles {
+		createPaths[fmt.Sprintf(vaultTokenRoleCreatePath, r)] = struct{}{}
 	}
 
 	return func(operation, path string) string {
-		// Only wrap the token create operation
-		if operation != "POST" || path != createPath {
+		// Only wrap the token create operations
+		if operation != "POST" {
+			return ""
+		}
+		if _, ok := createPaths[path]; !ok {
 			return ""
 		}
 
@@ -636,6 +645,7 @@ func (v *vaultClient) parseSelfToken() error {
 	//     3) Must not have an explicit max TTL
 	//     4) Must have non-zero period
 	// 5) If not configured against a role, the token must be root
+	// 6) The same requirements as 4) for every role jobs are allowed to use
 
 	var mErr multierror.Error
 	role := v.getRole()
@@ -671,13 +681,14 @@ func (v *vaultClient) parseSelfToken() error {
 	}
 
 	// Check we have the correct capabilities
-	if err := v.validateCapabilities(role, root); err != nil {
+	roles := v.getRoles()
+	if err := v.validateCapabilities(roles, root); err != nil {
 		multierror.Append(&mErr, err)
 	}
 
-	// If given a role validate it
-	if role != "" {
-		if err := v.validateRole(role); err != nil {
+	// Validate the default role and the roles jobs may use
+	for _, r := range roles {
+		if err := v.validateRole(r); err != nil {
 			multierror.Append(&mErr, err)
 		}
 	}
@@ -694,9 +705,24 @@ func (v *vaultClient) getRole() string {
 	return v.tokenData.Role
 }
 
+// getRoles returns the set of role names tokens may be created from: the
+// default role, if any, followed by the roles jobs are allowed to use
+func (v *vaultClient) getRoles() []string {
+	var roles []string
+	if role := v.getRole(); role != "" {
+		roles = append(roles, role)
+	}
+	for _, r := range v.config.AllowedRoles {
+		if !lib.StrContains(roles, r) {
+			roles = append(roles, r)
+		}
+	}
+	return roles
+}
+
 // validateCapabilities checks that Nomad's Vault token has the correct
-// capabilities.
-func (v *vaultClient) validateCapabilities(role string, root bool) error {
+// capabilities, including on the given roles.
+func (v *vaultClient) validateCapabilities(roles []string, root bool) error {
 	// Check if the token can lookup capabilities.
 	var mErr multierror.Error
 	_, _, err := v.hasCapability(vaultCapabilitiesLookupPath, vaultCapabilitiesCapability)
@@ -740,8 +766,8 @@ func (v *vaultClient) validateCapabilities(role string, root bool) error {
 	// Verify we can revoke tokens
 	verify(vaultTokenRevokePath, vaultTokenRevokeCapability)
 
-	// If we are using a role verify the capability
-	if role != "" {
+	// If we are using roles verify the capabilities
+	for _, role := range roles {
 		// Verify we can read the role
 		verify(fmt.Sprintf(vaultRoleLookupPath, role), vaultRoleLookupCapability)
 
@@ -888,16 +914,22 @@ func (v *vaultClient) CreateToken(ctx context.Context, a *structs.Allocation, ta
 	}
 
 	// Make the request and switch depending on whether we are using a root
-	// token or a role based token
+	// token or a role based token. The task may use its own role.
 	var secret *vapi.Secret
 	var err error
-	role := v.getRole()
+	role := taskVault.Role
+	if role == "" {
+		role = v.getRole()
+	}
 	if v.tokenData.Root && role == "" {
 		req.Period = v.childTTL
 		secret, err = v.auth.Create(req)
+	} else if taskVault.EntityAlias != "" {
+		// Make the token for the entity alias using the role
+		secret, err = v.createWithEntityAlias(req, role, taskVault.EntityAlias)
 	} else {
 		// Make the token using the role
-		secret, err = v.auth.CreateWithRole(req, v.getRole())
+		secret, err = v.auth.CreateWithRole(req, role)
 	}
 
 	// Determine whether it is unrecoverable
@@ -913,6 +945,31 @@ func (v *vaultClient) CreateToken(ctx context.Context, a *structs.Allocation, ta
 	return secret, nil
 }
 
+// createWithEntityAlias creates a token from the role for the given entity
+// alias. The role must list the alias in its allowed entity aliases.
+func (v *vaultClient) createWithEntityAlias(req *vapi.TokenCreateRequest, role, alias string) (*vapi.Secret, error) {
+	body := struct {
+		*vapi.TokenCreateRequest
+		EntityAlias string `json:"entity_alias"`
+	}{
+		TokenCreateRequest: req,
+		EntityAlias:        alias,
+	}
+
+	r := v.client.NewRequest("POST", "/v1/"+fmt.Sprintf(vaultTokenRoleCreatePath, role))
+	if err := r.SetJSONBody(body); err != nil {
+		return nil, err
+	}
+
+	resp, err := v.client.RawRequest(r)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	return vapi.ParseSecret(resp.Body)
+}
+
 // LookupToken takes a Vault token and does a lookup against Vault. The call is
 // rate limited and may be canceled with passed context.
 func (v *vaultClient) LookupToken(ctx context.Context, token string) (*vapi.Secret, error) {
@@ -300,6 +300,33 @@ func TestVaultClient_ValidateToken(t *testing.T) {
 	}
 }
 
+func TestVaultClient_WrappingFn_Roles(t *testing.T) {
+	client := &vaultClient{
+		config: &config.VaultConfig{
+			Role:         "default",
+			AllowedRoles: []string{"web", "batch"},
+		},
+		tokenData: &tokenData{},
+	}
+
+	if roles := client.getRoles(); !reflect.DeepEqual(roles, []string{"default", "web", "batch"}) {
+		t.Fatalf("bad roles: %v", roles)
+	}
+
+	wrap := client.getWrappingFn()
+	for _, role := range []string{"default", "web", "batch"} {
+		if ttl := wrap("POST", "auth/token/create/"+role); ttl != vaultTokenCreateTTL {
+			t.Fatalf("token created from role %q should be wrapped", role)
+		}
+	}
+	if ttl := wrap("POST", "auth/token/create/other"); ttl != "" {
+		t.Fatalf("token created from other role should not be wrapped")
+	}
+	if ttl := wrap("GET", "auth/token/create/web"); ttl != "" {
+		t.Fatalf("only token creation should be wrapped")
+	}
+}
+
 func TestVaultClient_SetActive(t *testing.T) {
 	v := testutil.NewTestVault(t).Start()
 	defer v.Stop()
@@ -177,7 +177,12 @@ func annotateTask(diff *structs.TaskDiff, parent *structs.TaskGroupDiff) {
 	if !destructive {
 		for _, oDiff := range diff.Objects {
 			switch oDiff.Name {
-			case "LogConfig", "Service", "Constraint":
+			case "LogConfig":
+				// Log sinks are set up when the task starts
+				if len(oDiff.Objects) != 0 {
+					destructive = true
+				}
+			case "Service", "Constraint":
 				continue
 			default:
 				destructive = true
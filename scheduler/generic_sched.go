@@ -109,7 +109,8 @@ func (s *GenericScheduler) Process(eval *structs.Evaluation) error {
 	switch eval.TriggeredBy {
 	case structs.EvalTriggerJobRegister, structs.EvalTriggerNodeUpdate,
 		structs.EvalTriggerJobDeregister, structs.EvalTriggerRollingUpdate,
-		structs.EvalTriggerPeriodicJob, structs.EvalTriggerMaxPlans:
+		structs.EvalTriggerPeriodicJob, structs.EvalTriggerMaxPlans,
+		structs.EvalTriggerScaling:
 	default:
 		desc := fmt.Sprintf("scheduler cannot handle '%s' evaluation reason",
 			eval.TriggeredBy)
@@ -486,6 +487,11 @@ func (s *GenericScheduler) computePlacements(place []allocTuple) error {
 				},
 			}
 
+			// Store the network assigned to the task group
+			if option.AllocResources != nil {
+				alloc.SharedResources.Networks = option.AllocResources.Networks
+			}
+
 			// If the new allocation is replacing an older allocation then we
 			// set the record the older allocation id so that they are chained
 			if missing.Alloc != nil {
@@ -14,6 +14,10 @@ type RankedNode struct {
 	Score         float64
 	TaskResources map[string]*structs.Resources
 
+	// AllocResources are the resources shared by all the tasks of the
+	// allocation, such as the task group's network.
+	AllocResources *structs.Resources
+
 	// Allocs is used to cache the proposed allocations on the
 	// node. This can be shared between iterators that require it.
 	Proposed []*structs.Allocation
@@ -185,6 +189,29 @@ OUTER:
 		total := &structs.Resources{
 			DiskMB: iter.taskGroup.EphemeralDisk.SizeMB,
 		}
+
+		// Assign the task group's shared network
+		if len(iter.taskGroup.Networks) > 0 {
+			ask := iter.taskGroup.Networks[0].Copy()
+			offer, err := netIdx.AssignNetwork(ask)
+			if offer == nil {
+				iter.ctx.Metrics().ExhaustedNode(option.Node,
+					fmt.Sprintf("network: %s", err))
+				netIdx.Release()
+				continue OUTER
+			}
+
+			// Reserve this to prevent a task from colliding
+			netIdx.AddReserved(offer)
+
+			option.AllocResources = &structs.Resources{
+				Networks: []*structs.NetworkResource{offer},
+			}
+			total.Add(option.AllocResources)
+		} else {
+			option.AllocResources = nil
+		}
+
 		for _, task := range iter.taskGroup.Tasks {
 			taskResources := task.Resources.Copy()
 
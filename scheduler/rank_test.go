@@ -99,6 +99,89 @@ func TestBinPackIterator_NoExistingAlloc(t *testing.T) {
 	}
 }
 
+func TestBinPackIterator_GroupNetwork(t *testing.T) {
+	_, ctx := testContext(t)
+	nodes := []*RankedNode{
+		&RankedNode{
+			Node: &structs.Node{
+				Resources: &structs.Resources{
+					CPU:      2048,
+					MemoryMB: 2048,
+					Networks: []*structs.NetworkResource{
+						{
+							Device: "eth0",
+							CIDR:   "192.168.0.100/32",
+							MBits:  100,
+						},
+					},
+				},
+			},
+		},
+		&RankedNode{
+			Node: &structs.Node{
+				// Not enough bandwidth for the group network
+				Resources: &structs.Resources{
+					CPU:      2048,
+					MemoryMB: 2048,
+					Networks: []*structs.NetworkResource{
+						{
+							Device: "eth0",
+							CIDR:   "192.168.0.101/32",
+							MBits:  10,
+						},
+					},
+				},
+			},
+		},
+	}
+	static := NewStaticRankIterator(ctx, nodes)
+
+	taskGroup := &structs.TaskGroup{
+		EphemeralDisk: &structs.EphemeralDisk{},
+		Networks: []*structs.NetworkResource{
+			{
+				Mode:         structs.NetworkModeBridge,
+				MBits:        50,
+				DynamicPorts: []structs.Port{{Label: "http", To: 8080}},
+			},
+		},
+		Tasks: []*structs.Task{
+			{
+				Name: "web",
+				Resources: &structs.Resources{
+					CPU:      1024,
+					MemoryMB: 1024,
+				},
+			},
+		},
+	}
+	binp := NewBinPackIterator(ctx, static, false, 0)
+	binp.SetTaskGroup(taskGroup)
+
+	out := collectRanked(binp)
+	if len(out) != 1 || out[0] != nodes[0] {
+		t.Fatalf("Bad: %v", out)
+	}
+
+	shared := out[0].AllocResources
+	if shared == nil || len(shared.Networks) != 1 {
+		t.Fatalf("Bad: %#v", shared)
+	}
+	offer := shared.Networks[0]
+	if offer.IP != "192.168.0.100" || offer.Mode != structs.NetworkModeBridge {
+		t.Fatalf("Bad: %#v", offer)
+	}
+	port := offer.DynamicPorts[0]
+	if port.Value < structs.MinDynamicPort || port.To != 8080 {
+		t.Fatalf("Bad: %#v", port)
+	}
+
+	// The ask of the job must not be modified
+	if taskGroup.Networks[0].DynamicPorts[0].Value != 0 {
+		t.Fatalf("ask modified: %#v", taskGroup.Networks[0])
+	}
+}
+
 func TestBinPackIterator_PlannedAlloc(t *testing.T) {
 	_, ctx := testContext(t)
 	nodes := []*RankedNode{
@@ -321,6 +321,11 @@ func (s *SystemScheduler) computePlacements(place []allocTuple) error {
 				},
 			}
 
+			// Store the network assigned to the task group
+			if option.AllocResources != nil {
+				alloc.SharedResources.Networks = option.AllocResources.Networks
+			}
+
 			// If the new allocation is replacing an older allocation then we
 			// set the record the older allocation id so that they are chained
 			if missing.Alloc != nil {
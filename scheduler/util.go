@@ -347,6 +347,11 @@ func tasksUpdated(jobA, jobB *structs.Job, taskGroup string) bool {
 		return true
 	}
 
+	// Check the task group's network
+	if networkUpdated(a.Networks, b.Networks) {
+		return true
+	}
+
 	// Check each task
 	for _, at := range a.Tasks {
 		bt := b.LookupTask(at.Name)
@@ -374,6 +379,9 @@ func tasksUpdated(jobA, jobB *structs.Job, taskGroup string) bool {
 		if !reflect.DeepEqual(at.Templates, bt.Templates) {
 			return true
 		}
+		if logSinksUpdated(at.LogConfig, bt.LogConfig) {
+			return true
+		}
 
 		// Check the metadata
 		if !reflect.DeepEqual(
@@ -383,22 +391,9 @@ func tasksUpdated(jobA, jobB *structs.Job, taskGroup string) bool {
 		}
 
 		// Inspect the network to see if the dynamic ports are different
-		if len(at.Resources.Networks) != len(bt.Resources.Networks) {
+		if networkUpdated(at.Resources.Networks, bt.Resources.Networks) {
 			return true
 		}
-		for idx := range at.Resources.Networks {
-			an := at.Resources.Networks[idx]
-			bn := bt.Resources.Networks[idx]
-
-			if an.MBits != bn.MBits {
-				return true
-			}
-
-			aPorts, bPorts := networkPortMap(an), networkPortMap(bn)
-			if !reflect.DeepEqual(aPorts, bPorts) {
-				return true
-			}
-		}
 
 		// Inspect the non-network resources
 		if ar, br := at.Resources, bt.Resources; ar.CPU != br.CPU {
@@ -412,6 +407,49 @@ func tasksUpdated(jobA, jobB *structs.Job, taskGroup string) bool {
 	return false
 }
 
+// networkUpdated returns whether the two sets of network resources differ in
+// a way that requires a destructive update.
+func networkUpdated(netA, netB []*structs.NetworkResource) bool {
+	if len(netA) != len(netB) {
+		return true
+	}
+	for idx := range netA {
+		an := netA[idx]
+		bn := netB[idx]
+
+		if an.MBits != bn.MBits {
+			return true
+		}
+
+		if an.Mode != bn.Mode {
+			return true
+		}
+
+		aPorts, bPorts := networkPortMap(an), networkPortMap(bn)
+		if !reflect.DeepEqual(aPorts, bPorts) {
+			return true
+		}
+
+		aTo, bTo := networkPortToMap(an), networkPortToMap(bn)
+		if !reflect.DeepEqual(aTo, bTo) {
+			return true
+		}
+	}
+	return false
+}
+
+// networkPortToMap takes a network resource and returns a map of port labels
+// to the port they are mapped to inside the allocation's network namespace.
+func networkPortToMap(n *structs.NetworkResource) map[string]int {
+	m := make(map[string]int, len(n.DynamicPorts)+len(n.ReservedPorts))
+	for _, ports := range [][]structs.Port{n.ReservedPorts, n.DynamicPorts} {
+		for _, p := range ports {
+			m[p.Label] = p.To
+		}
+	}
+	return m
+}
+
 // networkPortMap takes a network resource and returns a map of port labels to
 // values. The value for dynamic ports is disregarded even if it is set. This
 // makes this function suitable for comparing two network resources for changes.
@@ -426,6 +464,22 @@ func networkPortMap(n *structs.NetworkResource) map[string]int {
 	return m
 }
 
+// logSinksUpdated returns whether the log sinks of a task changed. Sinks are
+// set up when the task is started so they can't be updated in-place.
+func logSinksUpdated(a, b *structs.LogConfig) bool {
+	var aSinks, bSinks []*structs.LogSink
+	if a != nil {
+		aSinks = a.Sinks
+	}
+	if b != nil {
+		bSinks = b.Sinks
+	}
+	if len(aSinks) == 0 && len(bSinks) == 0 {
+		return false
+	}
+	return !reflect.DeepEqual(aSinks, bSinks)
+}
+
 // setStatus is used to update the status of the evaluation
 func setStatus(logger *log.Logger, planner Planner,
 	eval, nextEval, spawnedBlocked *structs.Evaluation,
@@ -571,6 +571,41 @@ func TestTasksUpdated(t *testing.T) {
 	if !tasksUpdated(j1, j18, name) {
 		t.Fatal("bad")
 	}
+
+	// Add a group network
+	j19 := mock.Job()
+	j19.TaskGroups[0].Networks = []*structs.NetworkResource{
+		{
+			Mode:         structs.NetworkModeBridge,
+			MBits:        10,
+			DynamicPorts: []structs.Port{{Label: "http", To: 8080}},
+		},
+	}
+	if !tasksUpdated(j1, j19, name) {
+		t.Fatal("bad")
+	}
+
+	// Change the port the group network maps to
+	j20 := j19.Copy()
+	j20.TaskGroups[0].Networks[0].DynamicPorts[0].To = 9090
+	if !tasksUpdated(j19, j20, name) {
+		t.Fatal("bad")
+	}
+
+	// Rotation settings are updated in-place but sinks are not
+	j21 := mock.Job()
+	j21.TaskGroups[0].Tasks[0].LogConfig.MaxFiles = 20
+	if tasksUpdated(j1, j21, name) {
+		t.Fatal("bad")
+	}
+
+	j22 := mock.Job()
+	j22.TaskGroups[0].Tasks[0].LogConfig.Sinks = []*structs.LogSink{
+		{Type: structs.LogSinkTypeUnix, Address: "/run/fluentd.sock"},
+	}
+	if !tasksUpdated(j1, j22, name) {
+		t.Fatal("bad")
+	}
 }
 
 func TestEvictAndPlace_LimitLessThanAllocs(t *testing.T) {